@@ -0,0 +1,356 @@
+//! Organization-wide crypto posture from many saved reports, released under differential
+//! privacy.
+
+use std::collections::{BTreeMap, BTreeSet};
+use std::fmt::Write as _;
+
+use serde::Serialize;
+use serde_json::Value;
+#[cfg(test)]
+use sha2::{Digest, Sha256};
+
+pub const DEFAULT_EPSILON: f64 = 1.0;
+pub const DEFAULT_MIN_COUNT: u64 = 5;
+
+/// Categorized arguments counted per finding; the rest are dropped so one call site moves
+/// the parameter table by a bounded amount.
+pub const MAX_PARAMETERS_PER_FINDING: usize = 4;
+
+/// Tables the privacy budget is split across: algorithm call counts, algorithm
+/// repository counts and parameter distributions.
+const TABLES: f64 = 3.0;
+
+const UNCLASSIFIED: &str = "unclassified";
+const UNRESOLVED: &str = "unresolved";
+
+#[derive(Debug, Clone, Serialize)]
+pub struct AggregateReport {
+    /// Reports merged; the operator chose them, so the number is published exactly.
+    pub reports: usize,
+    pub epsilon: f64,
+    pub min_count: u64,
+    pub algorithms: Vec<AlgorithmUsage>,
+    pub parameters: Vec<ParameterDistribution>,
+}
+
+/// Noisy number of call sites using an algorithm and of repositories with any.
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct AlgorithmUsage {
+    pub algorithm: String,
+    pub findings: u64,
+    pub repositories: u64,
+}
+
+/// Noisy counts of the values one category of argument takes for an algorithm, e.g. the
+/// iteration counts passed to PBKDF2.
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct ParameterDistribution {
+    pub algorithm: String,
+    pub category: String,
+    pub values: Vec<ValueCount>,
+}
+
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct ValueCount {
+    pub value: String,
+    pub count: u64,
+}
+
+/// Exact counts before noise.
+#[derive(Debug, Default)]
+struct Tally {
+    findings: BTreeMap<String, u64>,
+    repositories: BTreeMap<String, u64>,
+    parameters: BTreeMap<(String, String), BTreeMap<String, u64>>,
+}
+
+impl Tally {
+    fn add_report(&mut self, report: &Value) {
+        let findings = report
+            .get("findings")
+            .and_then(Value::as_array)
+            .map(Vec::as_slice)
+            .unwrap_or_default();
+        let mut algorithms = BTreeSet::new();
+        for finding in findings {
+            let algorithm = finding
+                .get("algorithm")
+                .and_then(Value::as_str)
+                .unwrap_or(UNCLASSIFIED)
+                .to_string();
+            *self.findings.entry(algorithm.clone()).or_default() += 1;
+
+            let categories = finding
+                .get("parameter_categories")
+                .and_then(Value::as_object);
+            for (parameter, category) in categories
+                .into_iter()
+                .flatten()
+                .take(MAX_PARAMETERS_PER_FINDING)
+            {
+                let Some(category) = category.as_str() else {
+                    continue;
+                };
+                let value = finding
+                    .get("parameters")
+                    .and_then(|parameters| parameters.get(parameter))
+                    .and_then(scalar)
+                    .unwrap_or_else(|| UNRESOLVED.to_string());
+                *self
+                    .parameters
+                    .entry((algorithm.clone(), category.to_string()))
+                    .or_default()
+                    .entry(value)
+                    .or_default() += 1;
+            }
+            algorithms.insert(algorithm);
+        }
+        for algorithm in algorithms {
+            *self.repositories.entry(algorithm).or_default() += 1;
+        }
+    }
+}
+
+/// A resolved single value; lists of possible values and unresolved arguments have none.
+fn scalar(value: &Value) -> Option<String> {
+    match value {
+        Value::Number(n) => Some(n.to_string()),
+        Value::String(s) => Some(s.clone()),
+        Value::Bool(b) => Some(b.to_string()),
+        _ => None,
+    }
+}
+
+impl AggregateReport {
+    /// Merges `reports`, parsed JSON reports of one repository each, adding noise drawn
+    /// from `noise`.
+    pub fn build(reports: &[Value], epsilon: f64, min_count: u64, noise: &mut Noise) -> Self {
+        let mut tally = Tally::default();
+        for report in reports {
+            tally.add_report(report);
+        }
+
+        // A call site adds one to its algorithm's call count and at most one to its
+        // algorithm's repository count, but up to MAX_PARAMETERS_PER_FINDING parameter counts
+        let scale = TABLES / epsilon;
+        let parameter_scale = scale * MAX_PARAMETERS_PER_FINDING as f64;
+        let mut release = |count: u64, scale: f64| {
+            let noisy = (count as f64 + noise.laplace(scale)).round();
+            (noisy >= min_count.max(1) as f64).then_some(noisy as u64)
+        };
+
+        let mut algorithms: Vec<AlgorithmUsage> = tally
+            .findings
+            .iter()
+            .filter_map(|(algorithm, &count)| {
+                let findings = release(count, scale)?;
+                let repositories = tally.repositories.get(algorithm).copied().unwrap_or(0);
+                Some(AlgorithmUsage {
+                    algorithm: algorithm.clone(),
+                    findings,
+                    repositories: release(repositories, scale).unwrap_or(0),
+                })
+            })
+            .collect();
+        algorithms.sort_by(|a, b| {
+            b.findings
+                .cmp(&a.findings)
+                .then_with(|| a.algorithm.cmp(&b.algorithm))
+        });
+
+        let parameters = tally
+            .parameters
+            .iter()
+            .filter_map(|((algorithm, category), values)| {
+                let mut values: Vec<ValueCount> = values
+                    .iter()
+                    .filter_map(|(value, &count)| {
+                        Some(ValueCount {
+                            value: value.clone(),
+                            count: release(count, parameter_scale)?,
+                        })
+                    })
+                    .collect();
+                values.sort_by(|a, b| b.count.cmp(&a.count).then_with(|| a.value.cmp(&b.value)));
+                (!values.is_empty()).then(|| ParameterDistribution {
+                    algorithm: algorithm.clone(),
+                    category: category.clone(),
+                    values,
+                })
+            })
+            .collect();
+
+        AggregateReport {
+            reports: reports.len(),
+            epsilon,
+            min_count,
+            algorithms,
+            parameters,
+        }
+    }
+
+    pub fn render_text(&self) -> String {
+        let mut out = String::new();
+        let _ = writeln!(
+            out,
+            "Crypto posture across {} report(s) (epsilon {}, counts below {} withheld; all counts are approximate)",
+            self.reports, self.epsilon, self.min_count
+        );
+        if self.algorithms.is_empty() {
+            let _ = writeln!(out, "No algorithm is used often enough to report");
+            return out;
+        }
+        let _ = writeln!(out, "Algorithms:");
+        for usage in &self.algorithms {
+            let _ = write!(out, "  {}: {} call(s)", usage.algorithm, usage.findings);
+            let _ = match usage.repositories {
+                0 => writeln!(out),
+                n => writeln!(out, " in {n} repositories"),
+            };
+        }
+        if !self.parameters.is_empty() {
+            let _ = writeln!(out, "Parameters:");
+            for distribution in &self.parameters {
+                let values: Vec<String> = distribution
+                    .values
+                    .iter()
+                    .map(|v| format!("{} ({})", v.value, v.count))
+                    .collect();
+                let _ = writeln!(
+                    out,
+                    "  {} {}: {}",
+                    distribution.algorithm,
+                    distribution.category,
+                    values.join(", ")
+                );
+            }
+        }
+        out
+    }
+}
+
+/// Random numbers for the noise. Anyone who can predict the draws can subtract the
+/// noise, so release runs use the operating system's CSPRNG.
+pub enum Noise {
+    Os,
+    /// SHA-256 over a seed and a counter, so tests draw the same numbers every run.
+    #[cfg(test)]
+    Seeded {
+        seed: [u8; 32],
+        counter: u64,
+    },
+}
+
+impl Noise {
+    /// Noise from the operating system's CSPRNG. Fails when it cannot be read rather than
+    /// falling back to a predictable source.
+    pub fn from_os() -> Result<Self, getrandom::Error> {
+        getrandom::u64()?;
+        Ok(Self::Os)
+    }
+
+    #[cfg(test)]
+    pub fn from_seed(seed: u64) -> Self {
+        Self::Seeded {
+            seed: Sha256::digest(seed.to_le_bytes()).into(),
+            counter: 0,
+        }
+    }
+
+    /// Uniform in (0, 1).
+    fn uniform(&mut self) -> f64 {
+        let bits = match self {
+            // A source that was read once is not expected to fail later
+            Self::Os => getrandom::u64().expect("OS random source failed after a successful read"),
+            #[cfg(test)]
+            Self::Seeded { seed, counter } => {
+                let mut hasher = Sha256::new();
+                hasher.update(*seed);
+                hasher.update(counter.to_le_bytes());
+                *counter += 1;
+                let digest = hasher.finalize();
+                u64::from_le_bytes(digest[..8].try_into().unwrap())
+            }
+        } >> 11;
+        (bits as f64 + 0.5) / (1u64 << 53) as f64
+    }
+
+    /// A draw from the Laplace distribution centred on zero with the given scale.
+    fn laplace(&mut self, scale: f64) -> f64 {
+        let u = self.uniform() - 0.5;
+        -scale * u.signum() * (1.0 - 2.0 * u.abs()).ln()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use serde_json::json;
+
+    fn report(findings: Value) -> Value {
+        json!({"files_scanned": 1, "total_findings": 0, "total_configs": 0, "findings": findings})
+    }
+
+    fn pbkdf2(iterations: Value) -> Value {
+        json!({
+            "file": "internal/auth/password.go",
+            "algorithm": "PBKDF2",
+            "parameters": {"arg2": iterations, "arg3": 32},
+            "parameter_categories": {"arg2": "iteration-count", "arg3": "key-size"}
+        })
+    }
+
+    #[test]
+    fn test_tally_counts_repositories_once() {
+        let mut tally = Tally::default();
+        tally.add_report(&report(json!([
+            pbkdf2(json!(600000)),
+            pbkdf2(json!([4096, 600000])),
+            {"file": "a.go", "function": "Sum"}
+        ])));
+        tally.add_report(&report(json!([pbkdf2(json!(600000))])));
+
+        assert_eq!(tally.findings["PBKDF2"], 3);
+        assert_eq!(tally.findings[UNCLASSIFIED], 1);
+        assert_eq!(tally.repositories["PBKDF2"], 2);
+        let iterations = &tally.parameters[&("PBKDF2".to_string(), "iteration-count".to_string())];
+        assert_eq!(iterations["600000"], 2);
+        assert_eq!(iterations[UNRESOLVED], 1);
+    }
+
+    #[test]
+    fn test_build_withholds_rare_counts_and_locations() {
+        let common: Vec<Value> = (0..200).map(|_| pbkdf2(json!(600000))).collect();
+        let reports = vec![
+            report(Value::Array(common)),
+            report(json!([pbkdf2(json!(1000))])),
+        ];
+
+        let aggregate = AggregateReport::build(&reports, 10.0, 20, &mut Noise::from_seed(7));
+        assert_eq!(aggregate.reports, 2);
+        let pbkdf2 = &aggregate.algorithms[0];
+        assert_eq!(pbkdf2.algorithm, "PBKDF2");
+        assert!(pbkdf2.findings.abs_diff(201) < 60, "{}", pbkdf2.findings);
+
+        let iterations = aggregate
+            .parameters
+            .iter()
+            .find(|d| d.category == "iteration-count")
+            .unwrap();
+        assert_eq!(iterations.values.len(), 1);
+        assert_eq!(iterations.values[0].value, "600000");
+
+        let json = serde_json::to_string(&aggregate).unwrap();
+        assert!(!json.contains("password.go"));
+    }
+
+    #[test]
+    fn test_laplace_noise_is_centred() {
+        let mut noise = Noise::from_seed(1);
+        let draws: Vec<f64> = (0..20000).map(|_| noise.laplace(2.0)).collect();
+        let mean = draws.iter().sum::<f64>() / draws.len() as f64;
+        let mean_abs = draws.iter().map(|d| d.abs()).sum::<f64>() / draws.len() as f64;
+        assert!(mean.abs() < 0.1, "{mean}");
+        assert!((mean_abs - 2.0).abs() < 0.1, "{mean_abs}");
+    }
+}
@@ -0,0 +1,417 @@
+//! Fixture-driven tests for custom sinks and rules, in the style of Go's `analysistest`:
+//! expected findings are `// want` comments next to the calls, and golden files pin the
+//! resolved values.
+
+use std::cell::RefCell;
+use std::collections::BTreeMap;
+use std::fs;
+use std::path::{Path, PathBuf};
+use std::rc::Rc;
+
+use regex::Regex;
+use serde::Serialize;
+use walkdir::WalkDir;
+
+use crate::classifier::RulesClassifier;
+use crate::cli::{detect_language, Language};
+use crate::engine::{index_file, FileCache};
+use crate::error::{IoError, ParserError, Result};
+use crate::output::{Finding, OutputFormatter, ParameterStatus};
+use crate::policy::relative_path;
+use crate::scanner::{ScanResult, Scanner};
+
+/// Set to rewrite golden files instead of comparing against them.
+pub const UPDATE_GOLDEN_ENV: &str = "ARGFLOW_UPDATE_GOLDEN";
+
+/// Runs the scanner over fixture directories with a given set of rules.
+pub struct Harness {
+    scanner: Scanner,
+    classifier: RulesClassifier,
+}
+
+/// An expected finding parsed from a `want` comment.
+#[derive(Debug, Clone)]
+pub struct Expectation {
+    /// Path relative to the fixture directory.
+    pub file: String,
+    pub line: usize,
+    pub pattern: Regex,
+    /// Argument name and expected rendered value.
+    pub arguments: Vec<(String, String)>,
+}
+
+/// Findings of one fixture run and how they compare with the `want` comments.
+#[derive(Debug)]
+pub struct Outcome {
+    pub root: PathBuf,
+    pub findings: Vec<Finding>,
+    /// Mismatches between findings and expectations, one message each.
+    pub problems: Vec<String>,
+}
+
+/// The per-finding record kept in golden files.
+#[derive(Debug, Serialize)]
+struct GoldenFinding<'a> {
+    file: String,
+    line: usize,
+    column: usize,
+    full_name: &'a str,
+    parameters: &'a BTreeMap<String, serde_json::Value>,
+    parameter_status: &'a BTreeMap<String, ParameterStatus>,
+}
+
+impl Harness {
+    pub fn new(classifier: RulesClassifier) -> Self {
+        let scanner = Scanner::with_mappings_and_struct_fields(
+            classifier.get_mappings().clone(),
+            classifier.get_struct_fields().clone(),
+        )
+        .with_secrets(classifier.get_secrets().clone());
+        Self {
+            scanner,
+            classifier,
+        }
+    }
+
+    /// A harness with the bundled crypto preset.
+    pub fn bundled() -> Result<Self> {
+        Ok(Self::new(RulesClassifier::from_bundled()?))
+    }
+
+    /// Scans every supported source file under `dir` and checks the findings against
+    /// the `want` comments in those files.
+    pub fn run(&self, dir: &Path) -> Result<Outcome> {
+        let mut files = Vec::new();
+        for entry in WalkDir::new(dir).sort_by_file_name() {
+            let entry = entry.map_err(|_| IoError::directory_not_found(dir))?;
+            if entry.file_type().is_file() {
+                if let Some(language) = detect_language(entry.path()) {
+                    let source = fs::read_to_string(entry.path())
+                        .map_err(|e| IoError::read_error(entry.path(), e))?;
+                    files.push((entry.path().to_path_buf(), language, source));
+                }
+            }
+        }
+
+        let mut trees = Vec::new();
+        for (path, language, source) in &files {
+            trees.push(parse(path, *language, source)?);
+        }
+
+        // Go fixtures may spread a constant chain over several files, as real packages do
+        let mut index = FileCache::with_capacity(files.len().max(1));
+        for ((path, language, source), tree) in files.iter().zip(&trees) {
+            if *language == Language::Go {
+                let file_path = path.to_string_lossy().to_string();
+                index.add_file(
+                    file_path.clone(),
+                    index_file(tree, source.as_bytes(), &file_path, "go"),
+                );
+            }
+        }
+        let index = Rc::new(RefCell::new(index));
+
+        let results: Vec<ScanResult> = files
+            .iter()
+            .zip(&trees)
+            .map(|((path, language, source), tree)| {
+                let file_path = path.to_string_lossy();
+                match language {
+                    Language::Go => self.scanner.scan_tree_with_cache(
+                        tree,
+                        source.as_bytes(),
+                        &file_path,
+                        language.as_str(),
+                        Rc::clone(&index),
+                    ),
+                    _ => self.scanner.scan_tree(
+                        tree,
+                        source.as_bytes(),
+                        &file_path,
+                        language.as_str(),
+                    ),
+                }
+            })
+            .collect();
+        let findings = OutputFormatter::build_output(&results, &self.classifier).findings;
+
+        let mut expectations = Vec::new();
+        for (path, _, source) in &files {
+            let file = relative_path(&path.to_string_lossy(), dir);
+            expectations.extend(parse_expectations(&file, source));
+        }
+
+        let problems = compare(&findings, expectations, dir);
+        Ok(Outcome {
+            root: dir.to_path_buf(),
+            findings,
+            problems,
+        })
+    }
+}
+
+impl Outcome {
+    /// Panics with every mismatch if the findings do not match the `want` comments.
+    pub fn assert_ok(&self) {
+        assert!(
+            self.problems.is_empty(),
+            "{} fixture problem(s) in {}:\n  {}",
+            self.problems.len(),
+            self.root.display(),
+            self.problems.join("\n  ")
+        );
+    }
+
+    /// The golden representation of the findings: locations relative to the fixture
+    /// directory, names and the full resolution result of every argument.
+    pub fn golden(&self) -> String {
+        let records: Vec<GoldenFinding> = self
+            .findings
+            .iter()
+            .map(|f| GoldenFinding {
+                file: relative_path(&f.file, &self.root),
+                line: f.line,
+                column: f.column,
+                full_name: &f.full_name,
+                parameters: &f.parameters,
+                parameter_status: &f.parameter_status,
+            })
+            .collect();
+        let mut golden = serde_json::to_string_pretty(&records).expect("findings serialize");
+        golden.push('\n');
+        golden
+    }
+
+    /// Compares [`Outcome::golden`] with the file at `path`, or rewrites the file when
+    /// `ARGFLOW_UPDATE_GOLDEN` is set.
+    pub fn assert_golden(&self, path: &Path) {
+        let actual = self.golden();
+        if std::env::var_os(UPDATE_GOLDEN_ENV).is_some() {
+            fs::write(path, &actual)
+                .unwrap_or_else(|e| panic!("failed to write {}: {e}", path.display()));
+            return;
+        }
+        let expected = fs::read_to_string(path).unwrap_or_else(|e| {
+            panic!(
+                "failed to read {}: {e} (set {UPDATE_GOLDEN_ENV}=1 to create it)",
+                path.display()
+            )
+        });
+        assert!(
+            expected == actual,
+            "golden mismatch for {} (set {UPDATE_GOLDEN_ENV}=1 to update)\n--- expected\n{expected}\n--- actual\n{actual}",
+            path.display()
+        );
+    }
+}
+
+fn parse(path: &Path, language: Language, source: &str) -> Result<tree_sitter::Tree> {
+    let ts_language = match language {
+        Language::Go => tree_sitter_go::LANGUAGE.into(),
+        Language::Python => tree_sitter_python::LANGUAGE.into(),
+        Language::Rust => tree_sitter_rust::LANGUAGE.into(),
+        Language::Javascript => tree_sitter_javascript::LANGUAGE.into(),
+        Language::Typescript => tree_sitter_typescript::LANGUAGE_TYPESCRIPT.into(),
+    };
+    let mut parser = tree_sitter::Parser::new();
+    parser
+        .set_language(&ts_language)
+        .map_err(|_| ParserError::language_setup_failed(language.as_str()))?;
+    Ok(parser
+        .parse(source, None)
+        .ok_or_else(|| ParserError::parse_failed(path))?)
+}
+
+/// Parses the `want` comments of one file. Malformed patterns become expectations that
+/// can never match, so they surface as problems instead of being skipped.
+pub fn parse_expectations(file: &str, source: &str) -> Vec<Expectation> {
+    let mut expectations = Vec::new();
+    for (index, line) in source.lines().enumerate() {
+        let Some(annotation) = want_annotation(line) else {
+            continue;
+        };
+        for token in tokenize(annotation) {
+            match token {
+                Token::Pattern(pattern) => expectations.push(Expectation {
+                    file: file.to_string(),
+                    line: index + 1,
+                    pattern: Regex::new(&pattern).unwrap_or_else(|_| {
+                        Regex::new(&format!("^invalid pattern {}$", regex::escape(&pattern)))
+                            .expect("escaped pattern compiles")
+                    }),
+                    arguments: Vec::new(),
+                }),
+                Token::Argument(name, value) => {
+                    if let Some(last) = expectations.last_mut().filter(|e| e.line == index + 1) {
+                        last.arguments.push((name, value));
+                    }
+                }
+            }
+        }
+    }
+    expectations
+}
+
+/// Text after `// want` or `# want` on a line.
+fn want_annotation(line: &str) -> Option<&str> {
+    ["//", "#"].iter().find_map(|marker| {
+        let comment = &line[line.find(marker)? + marker.len()..];
+        comment.trim_start().strip_prefix("want ")
+    })
+}
+
+enum Token {
+    Pattern(String),
+    Argument(String, String),
+}
+
+/// Splits a `want` annotation into quoted patterns and `name=value` pairs. Patterns use
+/// double quotes or backticks; values may be quoted to include spaces.
+fn tokenize(annotation: &str) -> Vec<Token> {
+    let mut tokens = Vec::new();
+    let mut rest = annotation.trim();
+    while !rest.is_empty() {
+        if let Some((quoted, after)) = take_quoted(rest) {
+            tokens.push(Token::Pattern(quoted));
+            rest = after.trim_start();
+            continue;
+        }
+        let end = rest.find(char::is_whitespace).unwrap_or(rest.len());
+        let word = &rest[..end];
+        match word.split_once('=') {
+            Some((name, value)) if value.starts_with(['"', '`']) => {
+                let start = name.len() + 1;
+                match take_quoted(&rest[start..]) {
+                    Some((value, after)) => {
+                        tokens.push(Token::Argument(name.to_string(), value));
+                        rest = after.trim_start();
+                    }
+                    None => break,
+                }
+            }
+            Some((name, value)) => {
+                tokens.push(Token::Argument(name.to_string(), value.to_string()));
+                rest = rest[end..].trim_start();
+            }
+            None => rest = rest[end..].trim_start(),
+        }
+    }
+    tokens
+}
+
+fn take_quoted(text: &str) -> Option<(String, &str)> {
+    let quote = text.chars().next().filter(|c| *c == '"' || *c == '`')?;
+    let end = text[1..].find(quote)? + 1;
+    Some((text[1..end].to_string(), &text[end + 1..]))
+}
+
+/// Renders an argument the way `want` values are written: strings unquoted, anything
+/// else as JSON.
+fn render_argument(value: &serde_json::Value) -> String {
+    match value {
+        serde_json::Value::String(s) => s.clone(),
+        other => other.to_string(),
+    }
+}
+
+fn compare(findings: &[Finding], mut expectations: Vec<Expectation>, root: &Path) -> Vec<String> {
+    let mut problems = Vec::new();
+    for finding in findings {
+        let file = relative_path(&finding.file, root);
+        let position = expectations.iter().position(|e| {
+            e.file == file && e.line == finding.line && e.pattern.is_match(&finding.full_name)
+        });
+        let Some(position) = position else {
+            problems.push(format!(
+                "{file}:{}: unexpected finding {}",
+                finding.line, finding.full_name
+            ));
+            continue;
+        };
+        let expectation = expectations.remove(position);
+        for (name, expected) in &expectation.arguments {
+            let actual = finding.parameters.get(name).map(render_argument);
+            if actual.as_deref() != Some(expected.as_str()) {
+                problems.push(format!(
+                    "{file}:{}: {} {name} = {}, want {expected}",
+                    finding.line,
+                    finding.full_name,
+                    actual.as_deref().unwrap_or("<missing>")
+                ));
+            }
+        }
+    }
+    for expectation in expectations {
+        problems.push(format!(
+            "{}:{}: no finding matching `{}`",
+            expectation.file, expectation.line, expectation.pattern
+        ));
+    }
+    problems
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn finding(line: usize, full_name: &str, arg2: serde_json::Value) -> Finding {
+        Finding::call("/fixtures/kdf/kdf.go", line, 9, full_name).with_parameter("arg2", arg2)
+    }
+
+    #[test]
+    fn test_parse_expectations() {
+        let source = "package kdf\n\
+            \tk := pbkdf2.Key(pw, salt, n, 32, sha256.New) // want `pbkdf2\\.Key` arg2=4096 arg4=\"sha256.New\"\n\
+            h := hashlib.md5(data)  # want \"hashlib.md5\" \"sha1\"\n\
+            x := 1 // wanted, but not an annotation\n";
+        let expectations = parse_expectations("kdf.go", source);
+
+        assert_eq!(expectations.len(), 3);
+        assert_eq!(expectations[0].line, 2);
+        assert_eq!(expectations[0].pattern.as_str(), "pbkdf2\\.Key");
+        assert_eq!(
+            expectations[0].arguments,
+            vec![
+                ("arg2".to_string(), "4096".to_string()),
+                ("arg4".to_string(), "sha256.New".to_string()),
+            ]
+        );
+        assert_eq!(expectations[2].line, 3);
+        assert_eq!(expectations[2].pattern.as_str(), "sha1");
+    }
+
+    #[test]
+    fn test_compare_reports_each_mismatch() {
+        let root = Path::new("/fixtures/kdf");
+        let source = "\n\
+            // want `pbkdf2\\.Key` arg2=600000\n\
+            // want `md5`\n";
+        let expectations = parse_expectations("kdf.go", source);
+        let findings = [
+            finding(2, "golang.org/x/crypto/pbkdf2.Key", serde_json::json!(4096)),
+            finding(4, "crypto/sha1.New", serde_json::Value::Null),
+        ];
+
+        let problems = compare(&findings, expectations, root);
+        assert_eq!(
+            problems,
+            vec![
+                "kdf.go:2: golang.org/x/crypto/pbkdf2.Key arg2 = 4096, want 600000",
+                "kdf.go:4: unexpected finding crypto/sha1.New",
+                "kdf.go:3: no finding matching `md5`",
+            ]
+        );
+    }
+
+    #[test]
+    fn test_compare_accepts_matching_findings() {
+        let root = Path::new("/fixtures/kdf");
+        let expectations = parse_expectations("kdf.go", "\n// want `pbkdf2\\.Key` arg2=4096\n");
+        let findings = [finding(
+            2,
+            "golang.org/x/crypto/pbkdf2.Key",
+            serde_json::json!(4096),
+        )];
+        assert!(compare(&findings, expectations, root).is_empty());
+    }
+}
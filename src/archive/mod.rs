@@ -0,0 +1,396 @@
+//! Archive scan targets, extracted into a temporary workspace.
+
+mod zip;
+
+use std::fs::{self, File};
+use std::io::{self, BufReader, Read, Seek, SeekFrom};
+use std::path::{Component, Path, PathBuf};
+
+use flate2::read::GzDecoder;
+use tar::EntryType;
+use tempfile::TempDir;
+use tracing::debug;
+
+use crate::error::ArchiveError;
+
+/// Scan path that reads the archive from stdin, e.g. `git archive HEAD | argflow --path -`.
+pub const STDIN_PATH: &str = "-";
+
+const ARCHIVE_EXTENSIONS: [&str; 4] = [".zip", ".tar", ".tar.gz", ".tgz"];
+
+/// Largest file extracted from an archive.
+const MAX_ENTRY_SIZE: u64 = 256 << 20;
+/// Most bytes extracted from one archive, against decompression bombs.
+const MAX_TOTAL_SIZE: u64 = 2 << 30;
+
+/// Caps on extracted bytes, shared by every format. Sizes stated in headers are
+/// attacker-controlled, so both are enforced on the bytes actually written.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+struct Limits {
+    /// Largest single file.
+    entry: u64,
+    /// Largest sum of all files.
+    total: u64,
+}
+
+impl Default for Limits {
+    fn default() -> Self {
+        Self {
+            entry: MAX_ENTRY_SIZE,
+            total: MAX_TOTAL_SIZE,
+        }
+    }
+}
+
+/// The bytes written so far while unpacking one archive.
+struct Extraction {
+    limits: Limits,
+    written: u64,
+}
+
+impl Extraction {
+    fn new(limits: Limits) -> Self {
+        Self { limits, written: 0 }
+    }
+
+    /// Writes one file from `content`, failing as soon as it passes either limit.
+    /// Returns the number of bytes written.
+    fn write(
+        &mut self,
+        name: &str,
+        content: impl Read,
+        target: &Path,
+    ) -> Result<u64, ArchiveError> {
+        let remaining = self.limits.total - self.written;
+        let allowed = self.limits.entry.min(remaining);
+        if let Some(parent) = target.parent() {
+            fs::create_dir_all(parent).map_err(|e| ArchiveError::extract_error(e.to_string()))?;
+        }
+        let mut file = File::create(target)
+            .map_err(|e| ArchiveError::extract_error(format!("{name}: {e}")))?;
+        // One byte past the allowance is enough to tell that the entry is over it
+        let written = io::copy(&mut content.take(allowed + 1), &mut file)
+            .map_err(|e| ArchiveError::extract_error(format!("{name}: {e}")))?;
+        if written > allowed {
+            drop(file);
+            let _ = fs::remove_file(target);
+            return Err(if written > self.limits.entry {
+                ArchiveError::EntryTooLarge {
+                    entry: name.to_string(),
+                    limit: self.limits.entry,
+                }
+            } else {
+                ArchiveError::TooLarge {
+                    limit: self.limits.total,
+                }
+            });
+        }
+        self.written += written;
+        Ok(written)
+    }
+}
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum ArchiveFormat {
+    Zip,
+    Tar,
+    TarGz,
+}
+
+impl ArchiveFormat {
+    /// Identifies the format from the leading bytes; extensions are not trusted since
+    /// stdin has none and `git archive` output is often saved without one.
+    pub fn sniff(header: &[u8]) -> Option<Self> {
+        if header.starts_with(b"PK\x03\x04") || header.starts_with(b"PK\x05\x06") {
+            Some(Self::Zip)
+        } else if header.starts_with(&[0x1f, 0x8b]) {
+            Some(Self::TarGz)
+        } else if header.get(257..262) == Some(b"ustar") {
+            Some(Self::Tar)
+        } else {
+            None
+        }
+    }
+}
+
+/// Whether `path` names an archive to extract rather than a source file or directory.
+pub fn is_archive(path: &Path) -> bool {
+    if path.as_os_str() == STDIN_PATH {
+        return true;
+    }
+    let name = path
+        .file_name()
+        .map(|n| n.to_string_lossy().to_lowercase())
+        .unwrap_or_default();
+    path.is_file() && ARCHIVE_EXTENSIONS.iter().any(|ext| name.ends_with(ext))
+}
+
+/// An extracted archive. The files are deleted when this is dropped.
+#[derive(Debug)]
+pub struct Workspace {
+    dir: TempDir,
+    root: PathBuf,
+    files: usize,
+}
+
+impl Workspace {
+    /// Extracts the archive at `path`, or stdin for [`STDIN_PATH`].
+    pub fn extract(path: &Path) -> Result<Self, ArchiveError> {
+        let dir = tempfile::Builder::new()
+            .prefix("argflow-archive-")
+            .tempdir()
+            .map_err(|e| ArchiveError::extract_error(e.to_string()))?;
+
+        if path.as_os_str() == STDIN_PATH {
+            // Zip needs random access, so stdin is spooled to disk first
+            let spooled = dir.path().join("stdin.archive");
+            let mut file = File::create(&spooled)
+                .map_err(|e| ArchiveError::read_error(&spooled, e.to_string()))?;
+            io::copy(&mut io::stdin().lock(), &mut file)
+                .map_err(|e| ArchiveError::read_error("<stdin>", e.to_string()))?;
+            Self::extract_file(&spooled, dir, Path::new("<stdin>"))
+        } else {
+            Self::extract_file(path, dir, path)
+        }
+    }
+
+    fn extract_file(path: &Path, dir: TempDir, display: &Path) -> Result<Self, ArchiveError> {
+        let mut file =
+            File::open(path).map_err(|e| ArchiveError::read_error(display, e.to_string()))?;
+        let mut header = Vec::with_capacity(262);
+        (&mut file)
+            .take(262)
+            .read_to_end(&mut header)
+            .and_then(|_| file.seek(SeekFrom::Start(0)))
+            .map_err(|e| ArchiveError::read_error(display, e.to_string()))?;
+        let format =
+            ArchiveFormat::sniff(&header).ok_or_else(|| ArchiveError::UnsupportedFormat {
+                path: display.to_path_buf(),
+            })?;
+
+        let dest = dir.path().join("src");
+        fs::create_dir(&dest).map_err(|e| ArchiveError::extract_error(e.to_string()))?;
+        let limits = Limits::default();
+        let files = match format {
+            ArchiveFormat::Zip => {
+                zip::unpack(BufReader::new(file), &dest, limits).map_err(|e| match e {
+                    ArchiveError::CorruptZip { message, .. } => {
+                        ArchiveError::corrupt_zip(display, message)
+                    }
+                    other => other,
+                })?
+            }
+            ArchiveFormat::Tar => unpack_tar(BufReader::new(file), &dest, limits)?,
+            ArchiveFormat::TarGz => {
+                unpack_tar(GzDecoder::new(BufReader::new(file)), &dest, limits)?
+            }
+        };
+
+        let root = strip_wrappers(&dest);
+        debug!(format = ?format, files, root = %root.display(), "extracted archive");
+        Ok(Self { dir, root, files })
+    }
+
+    /// Directory to scan: the extraction directory, minus any single top-level
+    /// directory the archive wraps its contents in (`repo-<sha>/` in GitHub zipballs,
+    /// `--prefix` in `git archive`), so `go.mod` and friends sit at the root.
+    pub fn root(&self) -> &Path {
+        &self.root
+    }
+
+    /// Number of regular files extracted.
+    pub fn files(&self) -> usize {
+        self.files
+    }
+
+    /// The temporary directory holding the extraction.
+    pub fn dir(&self) -> &Path {
+        self.dir.path()
+    }
+}
+
+/// Unpacks regular files and directories. Links are skipped: source scanning does not
+/// need them and they are the usual way to write outside the destination.
+fn unpack_tar(reader: impl Read, dest: &Path, limits: Limits) -> Result<usize, ArchiveError> {
+    let mut archive = tar::Archive::new(reader);
+    let mut extraction = Extraction::new(limits);
+    let mut files = 0;
+    for entry in archive
+        .entries()
+        .map_err(|e| ArchiveError::extract_error(e.to_string()))?
+    {
+        let mut entry = entry.map_err(|e| ArchiveError::extract_error(e.to_string()))?;
+        let kind = entry.header().entry_type();
+        if !matches!(
+            kind,
+            EntryType::Regular | EntryType::Continuous | EntryType::Directory
+        ) {
+            continue;
+        }
+        let name = entry
+            .path()
+            .map_err(|e| ArchiveError::extract_error(e.to_string()))?
+            .to_string_lossy()
+            .into_owned();
+        let target = dest.join(safe_path(&name)?);
+        if kind == EntryType::Directory {
+            fs::create_dir_all(&target).map_err(|e| ArchiveError::extract_error(e.to_string()))?;
+            continue;
+        }
+        extraction.write(&name, &mut entry, &target)?;
+        files += 1;
+    }
+    Ok(files)
+}
+
+/// The relative path an entry unpacks to, rejecting absolute paths and `..`.
+fn safe_path(name: &str) -> Result<PathBuf, ArchiveError> {
+    let mut path = PathBuf::new();
+    for component in Path::new(name).components() {
+        match component {
+            Component::Normal(part) => path.push(part),
+            Component::CurDir => {}
+            _ => {
+                return Err(ArchiveError::UnsafeEntry {
+                    entry: name.to_string(),
+                })
+            }
+        }
+    }
+    Ok(path)
+}
+
+fn strip_wrappers(dest: &Path) -> PathBuf {
+    let mut root = dest.to_path_buf();
+    loop {
+        let Ok(entries) = fs::read_dir(&root) else {
+            return root;
+        };
+        let entries: Vec<_> = entries.filter_map(|e| e.ok()).collect();
+        match entries.as_slice() {
+            [only] if only.path().is_dir() => root = only.path(),
+            _ => return root,
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn tar_gz(entries: &[(&str, &str)]) -> Vec<u8> {
+        let encoder = flate2::write::GzEncoder::new(Vec::new(), flate2::Compression::fast());
+        let mut builder = tar::Builder::new(encoder);
+        for (name, content) in entries {
+            let mut header = tar::Header::new_gnu();
+            header.set_size(content.len() as u64);
+            header.set_mode(0o644);
+            header.set_cksum();
+            builder
+                .append_data(&mut header, name, content.as_bytes())
+                .unwrap();
+        }
+        builder.into_inner().unwrap().finish().unwrap()
+    }
+
+    fn write_archive(name: &str, bytes: &[u8]) -> (TempDir, PathBuf) {
+        let dir = TempDir::new().unwrap();
+        let path = dir.path().join(name);
+        fs::write(&path, bytes).unwrap();
+        (dir, path)
+    }
+
+    #[test]
+    fn test_sniff() {
+        assert_eq!(
+            ArchiveFormat::sniff(b"PK\x03\x04rest"),
+            Some(ArchiveFormat::Zip)
+        );
+        assert_eq!(
+            ArchiveFormat::sniff(&[0x1f, 0x8b, 8]),
+            Some(ArchiveFormat::TarGz)
+        );
+        let mut tar = vec![0u8; 512];
+        tar[257..262].copy_from_slice(b"ustar");
+        assert_eq!(ArchiveFormat::sniff(&tar), Some(ArchiveFormat::Tar));
+        assert_eq!(ArchiveFormat::sniff(b"package main"), None);
+    }
+
+    #[test]
+    fn test_extract_tar_gz_strips_wrapper_directory() {
+        let bytes = tar_gz(&[
+            ("app-1.2.0/go.mod", "module example.com/app\n"),
+            ("app-1.2.0/internal/kdf.go", "package internal\n"),
+        ]);
+        let (_dir, path) = write_archive("app.tar.gz", &bytes);
+        assert!(is_archive(&path));
+
+        let workspace = Workspace::extract(&path).unwrap();
+        assert_eq!(workspace.files(), 2);
+        assert!(workspace.root().ends_with("app-1.2.0"));
+        assert!(workspace.root().join("go.mod").is_file());
+        assert!(workspace.root().join("internal/kdf.go").is_file());
+
+        let extracted = workspace.dir().to_path_buf();
+        drop(workspace);
+        assert!(!extracted.exists());
+    }
+
+    fn unpack_limited(entries: &[(&str, &str)], limits: Limits) -> Result<usize, ArchiveError> {
+        let bytes = tar_gz(entries);
+        let dir = TempDir::new().unwrap();
+        unpack_tar(GzDecoder::new(bytes.as_slice()), dir.path(), limits)
+    }
+
+    #[test]
+    fn test_rejects_oversized_entry() {
+        let limits = Limits {
+            entry: 16,
+            total: 1024,
+        };
+        assert_eq!(
+            unpack_limited(&[("go.mod", "module a\n")], limits).unwrap(),
+            1
+        );
+
+        let error = unpack_limited(&[("main.go", &"x".repeat(17))], limits).unwrap_err();
+        assert!(matches!(
+            error,
+            ArchiveError::EntryTooLarge { ref entry, limit: 16 } if entry == "main.go"
+        ));
+    }
+
+    #[test]
+    fn test_rejects_oversized_total() {
+        let limits = Limits {
+            entry: 16,
+            total: 25,
+        };
+        let ten = "x".repeat(10);
+        assert_eq!(
+            unpack_limited(&[("a.go", &ten), ("b.go", &ten)], limits).unwrap(),
+            2
+        );
+
+        let error =
+            unpack_limited(&[("a.go", &ten), ("b.go", &ten), ("c.go", &ten)], limits).unwrap_err();
+        assert!(matches!(error, ArchiveError::TooLarge { limit: 25 }));
+    }
+
+    #[test]
+    fn test_rejects_unsafe_paths() {
+        assert!(safe_path("src/./main.go").is_ok());
+        assert!(safe_path("../etc/passwd").is_err());
+        assert!(safe_path("/etc/passwd").is_err());
+        assert!(safe_path("a/../../b").is_err());
+    }
+
+    #[test]
+    fn test_unsupported_format() {
+        let (_dir, path) = write_archive("notes.tar", b"not an archive");
+        assert!(matches!(
+            Workspace::extract(&path),
+            Err(ArchiveError::UnsupportedFormat { .. })
+        ));
+    }
+}
@@ -0,0 +1,372 @@
+//! Minimal zip reader: stored and deflated entries from the central directory, which
+//! covers GitHub zipballs and `git archive --format=zip`. Zip64 and encryption are
+//! rejected.
+
+use std::fs;
+use std::io::{Read, Seek, SeekFrom};
+use std::path::{Path, PathBuf};
+
+use flate2::read::DeflateDecoder;
+
+use super::{safe_path, Extraction, Limits};
+use crate::error::ArchiveError;
+
+const END_OF_CENTRAL_DIRECTORY: u32 = 0x0605_4b50;
+const CENTRAL_DIRECTORY_HEADER: u32 = 0x0201_4b50;
+const LOCAL_FILE_HEADER: u32 = 0x0403_4b50;
+
+const END_OF_CENTRAL_DIRECTORY_LEN: usize = 22;
+const MAX_COMMENT_LEN: usize = u16::MAX as usize;
+
+const METHOD_STORED: u16 = 0;
+const METHOD_DEFLATED: u16 = 8;
+const FLAG_ENCRYPTED: u16 = 0x1;
+
+const S_IFMT: u32 = 0o170_000;
+const S_IFLNK: u32 = 0o120_000;
+
+struct Entry {
+    name: String,
+    method: u16,
+    flags: u16,
+    compressed_size: u64,
+    size: u64,
+    local_header_offset: u64,
+    unix_mode: u32,
+}
+
+/// Unpacks the archive into `dest`, returning the number of files written. Entries are
+/// streamed from `archive`, which is never read into memory whole. The path in
+/// [`ArchiveError::CorruptZip`] is left empty for the caller to fill in.
+pub(super) fn unpack<R: Read + Seek>(
+    mut archive: R,
+    dest: &Path,
+    limits: Limits,
+) -> Result<usize, ArchiveError> {
+    let mut extraction = Extraction::new(limits);
+    let mut files = 0;
+    for entry in central_directory(&mut archive)? {
+        // Symlinks carry their target as content; skip them like tar links
+        if entry.unix_mode & S_IFMT == S_IFLNK {
+            continue;
+        }
+        let target = dest.join(safe_path(&entry.name)?);
+        if entry.name.ends_with('/') {
+            fs::create_dir_all(&target).map_err(|e| ArchiveError::extract_error(e.to_string()))?;
+            continue;
+        }
+        extract_entry(&mut archive, &entry, &target, &mut extraction)?;
+        files += 1;
+    }
+    Ok(files)
+}
+
+fn central_directory<R: Read + Seek>(archive: &mut R) -> Result<Vec<Entry>, ArchiveError> {
+    let len = archive
+        .seek(SeekFrom::End(0))
+        .map_err(|e| corrupt(e.to_string()))?;
+    let tail_len = len.min((END_OF_CENTRAL_DIRECTORY_LEN + MAX_COMMENT_LEN) as u64);
+    let tail = read_at(archive, len - tail_len, tail_len as usize)?;
+    let eocd = find_end_of_central_directory(&tail)
+        .ok_or_else(|| corrupt("missing end of central directory"))?;
+    let count = u16_at(&tail, eocd + 10)? as usize;
+    let size = u32_at(&tail, eocd + 12)?;
+    let offset = u32_at(&tail, eocd + 16)?;
+    if count == u16::MAX as usize || offset == u32::MAX {
+        return Err(corrupt("zip64 archives are not supported"));
+    }
+    if u64::from(offset) + u64::from(size) > len {
+        return Err(corrupt(
+            "central directory runs past the end of the archive",
+        ));
+    }
+    let bytes = read_at(archive, offset.into(), size as usize)?;
+
+    let mut entries = Vec::with_capacity(count);
+    let mut pos = 0;
+    for _ in 0..count {
+        if u32_at(&bytes, pos)? != CENTRAL_DIRECTORY_HEADER {
+            return Err(corrupt("bad central directory header"));
+        }
+        let name_len = u16_at(&bytes, pos + 28)? as usize;
+        let extra_len = u16_at(&bytes, pos + 30)? as usize;
+        let comment_len = u16_at(&bytes, pos + 32)? as usize;
+        let name = bytes
+            .get(pos + 46..pos + 46 + name_len)
+            .ok_or_else(|| corrupt("truncated entry name"))?;
+
+        let compressed_size = u32_at(&bytes, pos + 20)?;
+        let size = u32_at(&bytes, pos + 24)?;
+        let local_header_offset = u32_at(&bytes, pos + 42)?;
+        if [compressed_size, size, local_header_offset].contains(&u32::MAX) {
+            return Err(corrupt("zip64 archives are not supported"));
+        }
+
+        entries.push(Entry {
+            name: String::from_utf8_lossy(name).into_owned(),
+            flags: u16_at(&bytes, pos + 8)?,
+            method: u16_at(&bytes, pos + 10)?,
+            compressed_size: compressed_size.into(),
+            size: size.into(),
+            local_header_offset: local_header_offset.into(),
+            unix_mode: u32_at(&bytes, pos + 38)? >> 16,
+        });
+        pos += 46 + name_len + extra_len + comment_len;
+    }
+    Ok(entries)
+}
+
+fn find_end_of_central_directory(bytes: &[u8]) -> Option<usize> {
+    let last = bytes.len().checked_sub(END_OF_CENTRAL_DIRECTORY_LEN)?;
+    let first = last.saturating_sub(MAX_COMMENT_LEN);
+    (first..=last)
+        .rev()
+        .find(|&pos| u32_at(bytes, pos).ok() == Some(END_OF_CENTRAL_DIRECTORY))
+}
+
+/// Streams one entry to `target`. Stated sizes are attacker-controlled, so an entry is
+/// never inflated past the size it states, and the extraction limits apply on top.
+fn extract_entry<R: Read + Seek>(
+    archive: &mut R,
+    entry: &Entry,
+    target: &Path,
+    extraction: &mut Extraction,
+) -> Result<(), ArchiveError> {
+    if entry.flags & FLAG_ENCRYPTED != 0 {
+        return Err(corrupt(format!("{} is encrypted", entry.name)));
+    }
+    let limit = extraction.limits.entry;
+    if entry.size > limit {
+        return Err(corrupt(format!(
+            "{} states a size of {} bytes, over the {limit} byte limit",
+            entry.name, entry.size
+        )));
+    }
+    let pos = entry.local_header_offset;
+    let header = read_at(archive, pos, 30)?;
+    if u32_at(&header, 0)? != LOCAL_FILE_HEADER {
+        return Err(corrupt(format!("bad local header for {}", entry.name)));
+    }
+    // Sizes come from the central directory: local headers may defer them to a data
+    // descriptor, but the name and extra lengths are always present
+    let start = pos + 30 + u64::from(u16_at(&header, 26)?) + u64::from(u16_at(&header, 28)?);
+    archive
+        .seek(SeekFrom::Start(start))
+        .map_err(|e| corrupt(format!("{}: {e}", entry.name)))?;
+    let data = archive.by_ref().take(entry.compressed_size);
+
+    // One byte past the stated size is enough to tell that the entry lies
+    let written = match entry.method {
+        METHOD_STORED => extraction.write(&entry.name, data.take(entry.size + 1), target)?,
+        METHOD_DEFLATED => extraction.write(
+            &entry.name,
+            DeflateDecoder::new(data).take(entry.size + 1),
+            target,
+        )?,
+        method => {
+            return Err(corrupt(format!(
+                "{} uses unsupported compression method {method}",
+                entry.name
+            )))
+        }
+    };
+    if written != entry.size {
+        let _ = fs::remove_file(target);
+        return Err(corrupt(if written > entry.size {
+            format!(
+                "{} inflates past its stated size of {} bytes",
+                entry.name, entry.size
+            )
+        } else {
+            format!("truncated data for {}", entry.name)
+        }));
+    }
+    Ok(())
+}
+
+fn read_at<R: Read + Seek>(archive: &mut R, pos: u64, len: usize) -> Result<Vec<u8>, ArchiveError> {
+    let mut bytes = vec![0; len];
+    archive
+        .seek(SeekFrom::Start(pos))
+        .and_then(|_| archive.read_exact(&mut bytes))
+        .map_err(|_| corrupt("unexpected end of archive"))?;
+    Ok(bytes)
+}
+
+fn u16_at(bytes: &[u8], pos: usize) -> Result<u16, ArchiveError> {
+    bytes
+        .get(pos..pos + 2)
+        .map(|b| u16::from_le_bytes([b[0], b[1]]))
+        .ok_or_else(|| corrupt("unexpected end of archive"))
+}
+
+fn u32_at(bytes: &[u8], pos: usize) -> Result<u32, ArchiveError> {
+    bytes
+        .get(pos..pos + 4)
+        .map(|b| u32::from_le_bytes([b[0], b[1], b[2], b[3]]))
+        .ok_or_else(|| corrupt("unexpected end of archive"))
+}
+
+fn corrupt(message: impl Into<String>) -> ArchiveError {
+    ArchiveError::corrupt_zip(PathBuf::new(), message)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::io::{Cursor, Write};
+
+    use flate2::write::DeflateEncoder;
+    use flate2::Compression;
+    use tempfile::TempDir;
+
+    /// Builds a zip the way `git archive --format=zip` does: local headers, then the
+    /// central directory, then the end record.
+    fn build_zip(entries: &[(&str, &[u8], u16)]) -> Vec<u8> {
+        let mut out = Vec::new();
+        let mut central = Vec::new();
+        for (name, content, method) in entries {
+            let data = match *method {
+                METHOD_DEFLATED => {
+                    let mut encoder = DeflateEncoder::new(Vec::new(), Compression::fast());
+                    encoder.write_all(content).unwrap();
+                    encoder.finish().unwrap()
+                }
+                _ => content.to_vec(),
+            };
+            let offset = out.len() as u32;
+            out.extend(LOCAL_FILE_HEADER.to_le_bytes());
+            out.extend([0u8; 4]);
+            out.extend(method.to_le_bytes());
+            out.extend([0u8; 8]);
+            out.extend((data.len() as u32).to_le_bytes());
+            out.extend((content.len() as u32).to_le_bytes());
+            out.extend((name.len() as u16).to_le_bytes());
+            out.extend(0u16.to_le_bytes());
+            out.extend(name.as_bytes());
+            out.extend(&data);
+
+            central.extend(CENTRAL_DIRECTORY_HEADER.to_le_bytes());
+            central.extend([0u8; 6]);
+            central.extend(method.to_le_bytes());
+            central.extend([0u8; 8]);
+            central.extend((data.len() as u32).to_le_bytes());
+            central.extend((content.len() as u32).to_le_bytes());
+            central.extend((name.len() as u16).to_le_bytes());
+            central.extend([0u8; 8]);
+            central.extend((0o100_644u32 << 16).to_le_bytes());
+            central.extend(offset.to_le_bytes());
+            central.extend(name.as_bytes());
+        }
+        let central_offset = out.len() as u32;
+        out.extend(&central);
+        out.extend(END_OF_CENTRAL_DIRECTORY.to_le_bytes());
+        out.extend([0u8; 4]);
+        out.extend((entries.len() as u16).to_le_bytes());
+        out.extend((entries.len() as u16).to_le_bytes());
+        out.extend((central.len() as u32).to_le_bytes());
+        out.extend(central_offset.to_le_bytes());
+        out.extend(0u16.to_le_bytes());
+        out
+    }
+
+    #[test]
+    fn test_unpack_stored_and_deflated() {
+        let source = b"package main\n\nimport \"crypto/md5\"\n".repeat(20);
+        let bytes = build_zip(&[
+            ("repo-abc123/", b"", METHOD_STORED),
+            (
+                "repo-abc123/go.mod",
+                b"module example.com/app\n",
+                METHOD_STORED,
+            ),
+            ("repo-abc123/main.go", &source, METHOD_DEFLATED),
+        ]);
+        let dir = TempDir::new().unwrap();
+
+        assert_eq!(
+            unpack(Cursor::new(&bytes), dir.path(), Limits::default()).unwrap(),
+            2
+        );
+        assert_eq!(
+            fs::read(dir.path().join("repo-abc123/main.go")).unwrap(),
+            source
+        );
+        assert!(dir.path().join("repo-abc123/go.mod").is_file());
+    }
+
+    #[test]
+    fn test_unpack_rejects_zip_slip() {
+        let bytes = build_zip(&[("../evil.go", b"package evil\n", METHOD_STORED)]);
+        let dir = TempDir::new().unwrap();
+        assert!(matches!(
+            unpack(Cursor::new(&bytes), dir.path(), Limits::default()),
+            Err(ArchiveError::UnsafeEntry { .. })
+        ));
+    }
+
+    /// Overwrites the uncompressed size the first central directory entry states.
+    fn state_size(bytes: &mut [u8], size: u32) {
+        let eocd = find_end_of_central_directory(bytes).unwrap();
+        let central = u32_at(bytes, eocd + 16).unwrap() as usize;
+        bytes[central + 24..central + 28].copy_from_slice(&size.to_le_bytes());
+    }
+
+    #[test]
+    fn test_unpack_rejects_oversized_entries() {
+        let source = b"package main\n\nimport \"crypto/md5\"\n".repeat(20);
+        let dir = TempDir::new().unwrap();
+
+        let mut huge = build_zip(&[("main.go", &source, METHOD_DEFLATED)]);
+        state_size(&mut huge, u32::MAX - 1);
+        let error = unpack(Cursor::new(&huge), dir.path(), Limits::default()).unwrap_err();
+        assert!(error.to_string().contains("over the 268435456 byte limit"));
+
+        let mut understated = build_zip(&[("main.go", &source, METHOD_DEFLATED)]);
+        state_size(&mut understated, 16);
+        let error = unpack(Cursor::new(&understated), dir.path(), Limits::default()).unwrap_err();
+        assert!(error.to_string().contains("inflates past its stated size"));
+        assert!(!dir.path().join("main.go").exists());
+    }
+
+    #[test]
+    fn test_unpack_enforces_extraction_limits() {
+        let dir = TempDir::new().unwrap();
+        let limits = Limits {
+            entry: 16,
+            total: 25,
+        };
+        let ten = [b'x'; 10];
+
+        let large = build_zip(&[("main.go", &[b'x'; 17], METHOD_DEFLATED)]);
+        let error = unpack(Cursor::new(&large), dir.path(), limits).unwrap_err();
+        assert!(
+            error.to_string().contains("over the 16 byte limit"),
+            "{error}"
+        );
+
+        let many = build_zip(&[
+            ("a.go", &ten, METHOD_STORED),
+            ("b.go", &ten, METHOD_DEFLATED),
+            ("c.go", &ten, METHOD_STORED),
+        ]);
+        assert!(matches!(
+            unpack(Cursor::new(&many), dir.path(), limits),
+            Err(ArchiveError::TooLarge { limit: 25 })
+        ));
+    }
+
+    #[test]
+    fn test_truncated_archive() {
+        let bytes = build_zip(&[("main.go", b"package main\n", METHOD_STORED)]);
+        let dir = TempDir::new().unwrap();
+        assert!(matches!(
+            unpack(
+                Cursor::new(&bytes[..bytes.len() - 30]),
+                dir.path(),
+                Limits::default()
+            ),
+            Err(ArchiveError::CorruptZip { .. })
+        ));
+    }
+}
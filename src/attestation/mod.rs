@@ -0,0 +1,180 @@
+//! Signed in-toto attestations over scan reports.
+
+mod dsse;
+
+use std::collections::BTreeMap;
+use std::path::PathBuf;
+
+use serde::Serialize;
+use sha2::{Digest, Sha256};
+use walkdir::WalkDir;
+
+use crate::error::AttestationError;
+
+pub use dsse::{pae, Envelope, EnvelopeSignature, Signer};
+
+pub const PAYLOAD_TYPE: &str = "application/vnd.in-toto+json";
+pub const STATEMENT_TYPE: &str = "https://in-toto.io/Statement/v1";
+pub const PREDICATE_TYPE: &str = "https://github.com/smith-xyz/argflow/attestation/scan/v1";
+
+/// Extension appended to the report path for the attestation when none is given.
+pub const ATTESTATION_EXTENSION: &str = "intoto.jsonl";
+
+#[derive(Debug, Clone, Serialize)]
+pub struct Statement {
+    #[serde(rename = "_type")]
+    pub statement_type: String,
+    pub subject: Vec<Subject>,
+    #[serde(rename = "predicateType")]
+    pub predicate_type: String,
+    pub predicate: ScanPredicate,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct Subject {
+    pub name: String,
+    pub digest: BTreeMap<String, String>,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct ScanPredicate {
+    pub tool: ToolInfo,
+    /// Output format of the attested report (`json` or `cbom`).
+    pub format: String,
+    /// SHA-256 over the settings and rule files that determine the findings.
+    pub config_hash: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub commit: Option<String>,
+    /// Unix timestamp in seconds.
+    pub generated_at: i64,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct ToolInfo {
+    pub name: String,
+    pub version: String,
+}
+
+impl ToolInfo {
+    pub fn current() -> Self {
+        Self {
+            name: env!("CARGO_PKG_NAME").to_string(),
+            version: env!("CARGO_PKG_VERSION").to_string(),
+        }
+    }
+}
+
+impl Statement {
+    /// A statement whose subject is the report `content` published as `name`.
+    pub fn for_report(name: &str, content: &[u8], predicate: ScanPredicate) -> Self {
+        Self::for_digest(name, sha256_hex(content), predicate)
+    }
+
+    /// A statement whose subject is a report published as `name` with the hex SHA-256
+    /// `sha256`, for reports hashed while they were streamed to disk.
+    pub fn for_digest(name: &str, sha256: String, predicate: ScanPredicate) -> Self {
+        Self {
+            statement_type: STATEMENT_TYPE.to_string(),
+            subject: vec![Subject {
+                name: name.to_string(),
+                digest: BTreeMap::from([("sha256".to_string(), sha256)]),
+            }],
+            predicate_type: PREDICATE_TYPE.to_string(),
+            predicate,
+        }
+    }
+
+    /// Signs the statement. The result is written as a single JSON line.
+    pub fn sign(&self, signer: &Signer) -> Envelope {
+        let payload = serde_json::to_vec(self).expect("statement serializes");
+        signer.sign(PAYLOAD_TYPE, &payload)
+    }
+}
+
+/// Hashes the scan settings together with the contents of the rule files and preset
+/// directories they name, so two reports share a config hash only if the same rules
+/// were applied the same way.
+pub fn config_hash(
+    settings: &BTreeMap<&str, String>,
+    inputs: &[PathBuf],
+) -> Result<String, AttestationError> {
+    let mut hasher = Sha256::new();
+    for (key, value) in settings {
+        hasher.update(format!("{key}={value}\n"));
+    }
+    for input in inputs {
+        for entry in WalkDir::new(input).sort_by_file_name() {
+            let entry =
+                entry.map_err(|e| AttestationError::config_read_error(input, e.to_string()))?;
+            if !entry.file_type().is_file() {
+                continue;
+            }
+            let content = std::fs::read(entry.path())
+                .map_err(|e| AttestationError::config_read_error(entry.path(), e.to_string()))?;
+            hasher.update(format!("{}\n", sha256_hex(&content)));
+        }
+    }
+    Ok(hex_encode(&hasher.finalize()))
+}
+
+pub fn sha256_hex(bytes: &[u8]) -> String {
+    hex_encode(&Sha256::digest(bytes))
+}
+
+fn hex_encode(bytes: &[u8]) -> String {
+    bytes.iter().map(|b| format!("{b:02x}")).collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    fn predicate() -> ScanPredicate {
+        ScanPredicate {
+            tool: ToolInfo::current(),
+            format: "json".to_string(),
+            config_hash: "abc".to_string(),
+            commit: Some("0123456789abcdef".to_string()),
+            generated_at: 1_700_000_000,
+        }
+    }
+
+    #[test]
+    fn test_statement_subject_digest() {
+        let statement = Statement::for_report("report.json", b"{}", predicate());
+        let json = serde_json::to_value(&statement).unwrap();
+
+        assert_eq!(json["_type"], STATEMENT_TYPE);
+        assert_eq!(json["predicateType"], PREDICATE_TYPE);
+        assert_eq!(json["subject"][0]["name"], "report.json");
+        assert_eq!(
+            json["subject"][0]["digest"]["sha256"],
+            "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"
+        );
+        assert_eq!(json["predicate"]["tool"]["name"], "argflow");
+    }
+
+    #[test]
+    fn test_config_hash_covers_settings() {
+        let mut settings = BTreeMap::from([("preset", "crypto".to_string())]);
+        let first = config_hash(&settings, &[]).unwrap();
+        assert_eq!(first, config_hash(&settings, &[]).unwrap());
+
+        settings.insert("include_deps", "true".to_string());
+        assert_ne!(first, config_hash(&settings, &[]).unwrap());
+    }
+
+    #[test]
+    fn test_config_hash_covers_rule_contents() {
+        let temp_dir = TempDir::new().unwrap();
+        let rules = temp_dir.path().join("rules.json");
+        let settings = BTreeMap::new();
+
+        std::fs::write(&rules, "{}").unwrap();
+        let before = config_hash(&settings, &[temp_dir.path().to_path_buf()]).unwrap();
+        std::fs::write(&rules, "{\"rules\": []}").unwrap();
+        let after = config_hash(&settings, &[temp_dir.path().to_path_buf()]).unwrap();
+        assert_ne!(before, after);
+    }
+}
@@ -0,0 +1,321 @@
+//! Every rule argflow can apply, for `argflow rules`.
+
+use std::collections::{BTreeMap, BTreeSet};
+use std::fmt::Write;
+
+use serde::Serialize;
+use serde_json::Value;
+
+use crate::classifier::{Classification, RulesClassifier};
+use crate::policy::{Policy, Severity};
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum RuleKind {
+    /// A classification calls are mapped to.
+    Sink,
+    /// A gate policy rule.
+    Policy,
+}
+
+/// One rule and what configures it.
+#[derive(Debug, Clone, Serialize)]
+pub struct CatalogRule {
+    pub id: String,
+    pub kind: RuleKind,
+    /// What a sink rule reports matching calls as.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub classification: Option<Classification>,
+    /// Calls a sink rule matches, as `import/path.function`, lowercased as they are
+    /// matched.
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub sinks: Vec<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub severity: Option<Severity>,
+    /// Findings a policy rule applies to.
+    #[serde(rename = "match", skip_serializing_if = "Option::is_none")]
+    pub selector: Option<Value>,
+    /// Constraint settings of a policy rule by dotted path, e.g. `salt.min_length`.
+    #[serde(skip_serializing_if = "BTreeMap::is_empty")]
+    pub thresholds: BTreeMap<String, Value>,
+    /// Presets the rule ships in.
+    pub presets: Vec<String>,
+    /// Whether the rule is loaded for a scan with the current options.
+    pub enabled: bool,
+    /// Whether a violation of a policy rule fails the gate under the policy's `fail_on`.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub blocking: Option<bool>,
+}
+
+#[derive(Debug, Clone, Default, Serialize)]
+pub struct RuleCatalog {
+    pub rules: Vec<CatalogRule>,
+}
+
+impl RuleCatalog {
+    /// Adds the sink rules of `classifier`, recording `preset` as shipping them and
+    /// marking them enabled if `enabled`. Rules already listed are merged.
+    pub fn add_sinks(&mut self, classifier: &RulesClassifier, preset: Option<&str>, enabled: bool) {
+        let mut sinks: BTreeMap<&str, BTreeSet<String>> = BTreeMap::new();
+        for (import_path, functions) in classifier.get_mappings() {
+            for (function, key) in functions {
+                sinks
+                    .entry(key.as_str())
+                    .or_default()
+                    .insert(format!("{import_path}.{function}"));
+            }
+        }
+
+        let mut ids: Vec<&String> = classifier.get_classifications().keys().collect();
+        ids.sort();
+        for id in ids {
+            let position = self
+                .rules
+                .iter()
+                .position(|r| r.kind == RuleKind::Sink && &r.id == id);
+            let rule = match position {
+                Some(index) => &mut self.rules[index],
+                None => {
+                    self.rules.push(CatalogRule {
+                        id: id.clone(),
+                        kind: RuleKind::Sink,
+                        classification: classifier.get_classifications().get(id).cloned(),
+                        sinks: Vec::new(),
+                        severity: None,
+                        selector: None,
+                        thresholds: BTreeMap::new(),
+                        presets: Vec::new(),
+                        enabled: false,
+                        blocking: None,
+                    });
+                    self.rules.last_mut().unwrap()
+                }
+            };
+            for sink in sinks.get(id.as_str()).into_iter().flatten() {
+                if !rule.sinks.contains(sink) {
+                    rule.sinks.push(sink.clone());
+                }
+            }
+            rule.sinks.sort();
+            if let Some(preset) = preset.filter(|p| !rule.presets.iter().any(|r| r == p)) {
+                rule.presets.push(preset.to_string());
+            }
+            rule.enabled |= enabled;
+        }
+    }
+
+    /// Adds the rules of `policy`, including its module allow/deny list and approved
+    /// wrappers. They are all enabled; `blocking` tells whether they fail the gate.
+    pub fn add_policy(&mut self, policy: &Policy) {
+        for rule in &policy.rules {
+            let mut value = serde_json::to_value(rule).unwrap_or_default();
+            let selector = value.as_object_mut().and_then(|fields| {
+                for field in ["id", "message", "severity", "remediation_url"] {
+                    fields.remove(field);
+                }
+                fields.remove("match")
+            });
+            self.push_policy_rule(
+                &rule.id,
+                rule.severity,
+                selector.filter(has_settings),
+                value,
+                policy.fail_on,
+            );
+        }
+        if let Some(modules) = &policy.modules {
+            let mut value = serde_json::to_value(modules).unwrap_or_default();
+            if let Some(fields) = value.as_object_mut() {
+                for field in ["id", "message", "severity", "remediation_url"] {
+                    fields.remove(field);
+                }
+            }
+            let value = serde_json::json!({ "modules": value });
+            self.push_policy_rule(&modules.id, modules.severity, None, value, policy.fail_on);
+        }
+        for wrapper in &policy.wrappers {
+            let mut value = serde_json::to_value(wrapper).unwrap_or_default();
+            let selector = value.as_object_mut().and_then(|fields| {
+                for field in ["id", "message", "severity", "remediation_url"] {
+                    fields.remove(field);
+                }
+                fields.remove("match")
+            });
+            self.push_policy_rule(
+                &wrapper.id,
+                wrapper.severity,
+                selector.filter(has_settings),
+                value,
+                policy.fail_on,
+            );
+        }
+    }
+
+    fn push_policy_rule(
+        &mut self,
+        id: &str,
+        severity: Severity,
+        selector: Option<Value>,
+        constraints: Value,
+        fail_on: Severity,
+    ) {
+        let mut thresholds = BTreeMap::new();
+        flatten(String::new(), constraints, &mut thresholds);
+        self.rules.push(CatalogRule {
+            id: id.to_string(),
+            kind: RuleKind::Policy,
+            classification: None,
+            sinks: Vec::new(),
+            severity: Some(severity),
+            selector,
+            thresholds,
+            presets: Vec::new(),
+            enabled: true,
+            blocking: Some(severity >= fail_on),
+        });
+    }
+
+    pub fn render_text(&self) -> String {
+        let mut out = String::new();
+        for (title, kind) in [
+            ("Sink rules:", RuleKind::Sink),
+            ("Policy rules:", RuleKind::Policy),
+        ] {
+            let rules: Vec<_> = self.rules.iter().filter(|r| r.kind == kind).collect();
+            if rules.is_empty() {
+                continue;
+            }
+            if !out.is_empty() {
+                out.push('\n');
+            }
+            let _ = writeln!(out, "{title}");
+            for rule in rules {
+                let state = match (rule.enabled, rule.blocking) {
+                    (_, Some(true)) => "blocking",
+                    (_, Some(false)) => "advisory",
+                    (true, None) => "enabled",
+                    (false, None) => "disabled",
+                };
+                let detail = match &rule.classification {
+                    Some(c) => format!("{}/{}", c.finding_type, c.operation),
+                    None => rule.severity.map(|s| s.as_str()).unwrap_or("").to_string(),
+                };
+                let _ = writeln!(
+                    out,
+                    "  {:<32} {:<9} {:<24} {}",
+                    rule.id,
+                    state,
+                    detail,
+                    rule.presets.join(",")
+                );
+                for (path, value) in &rule.thresholds {
+                    let _ = writeln!(out, "      {path} = {value}");
+                }
+            }
+        }
+        out
+    }
+}
+
+/// Whether `value` holds anything besides nulls, `false` and empty lists.
+fn has_settings(value: &Value) -> bool {
+    match value {
+        Value::Null | Value::Bool(false) => false,
+        Value::Array(items) => !items.is_empty(),
+        Value::Object(fields) => fields.values().any(has_settings),
+        _ => true,
+    }
+}
+
+/// Collects the settings in `value` by dotted path.
+fn flatten(prefix: String, value: Value, out: &mut BTreeMap<String, Value>) {
+    match value {
+        Value::Object(fields) => {
+            for (key, value) in fields {
+                let path = if prefix.is_empty() {
+                    key
+                } else {
+                    format!("{prefix}.{key}")
+                };
+                flatten(path, value, out);
+            }
+        }
+        value if has_settings(&value) => {
+            out.insert(prefix, value);
+        }
+        _ => {}
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use serde_json::json;
+    use std::io::Write as _;
+
+    fn classifier(rules: Value) -> RulesClassifier {
+        let mut file = tempfile::Builder::new().suffix(".json").tempfile().unwrap();
+        write!(file, "{rules}").unwrap();
+        RulesClassifier::from_file(file.path()).unwrap()
+    }
+
+    #[test]
+    fn test_sink_rules_merge_presets_and_enabled() {
+        let md5 = json!({
+            "classifications": {"md5": {"findingType": "hash", "operation": "digest"}},
+            "mappings": {"crypto/md5": {"Sum": "md5"}}
+        });
+        let mut catalog = RuleCatalog::default();
+        catalog.add_sinks(&classifier(md5.clone()), Some("crypto"), false);
+        catalog.add_sinks(&classifier(md5), Some("legacy"), false);
+        catalog.add_sinks(
+            &classifier(json!({
+                "classifications": {"md5": {"findingType": "hash", "operation": "digest"}},
+                "mappings": {"crypto/md5": {"New": "md5"}}
+            })),
+            None,
+            true,
+        );
+
+        assert_eq!(catalog.rules.len(), 1);
+        let rule = &catalog.rules[0];
+        assert_eq!(rule.presets, vec!["crypto", "legacy"]);
+        assert_eq!(rule.sinks, vec!["crypto/md5.new", "crypto/md5.sum"]);
+        assert!(rule.enabled);
+    }
+
+    #[test]
+    fn test_policy_rule_thresholds() {
+        let policy: Policy = serde_json::from_value(json!({
+            "fail_on": "error",
+            "rules": [
+                {"id": "pbkdf2-iterations", "severity": "warning",
+                 "match": {"function": "pbkdf2.Key"},
+                 "parameter": {"name": "arg2", "min": 600000},
+                 "salt": {"min_length": 16}},
+                {"id": "no-md5", "match": {"algorithm": "MD5"}}
+            ],
+            "modules": {"deny": ["github.com/old/crypto-fork"]}
+        }))
+        .unwrap();
+        let mut catalog = RuleCatalog::default();
+        catalog.add_policy(&policy);
+
+        let ids: Vec<_> = catalog.rules.iter().map(|r| r.id.as_str()).collect();
+        assert_eq!(ids, vec!["pbkdf2-iterations", "no-md5", "crypto-modules"]);
+
+        let pbkdf2 = &catalog.rules[0];
+        assert_eq!(pbkdf2.blocking, Some(false));
+        assert_eq!(pbkdf2.thresholds["parameter.min"], json!(600000));
+        assert_eq!(pbkdf2.thresholds["salt.min_length"], json!(16));
+        assert!(!pbkdf2.thresholds.contains_key("parameter.require_resolved"));
+        assert_eq!(pbkdf2.selector.as_ref().unwrap()["function"], "pbkdf2.Key");
+
+        assert_eq!(catalog.rules[1].blocking, Some(true));
+        assert!(catalog.rules[1].thresholds.is_empty());
+        assert_eq!(
+            catalog.rules[2].thresholds["modules.deny"],
+            json!(["github.com/old/crypto-fork"])
+        );
+    }
+}
@@ -1,8 +1,13 @@
 mod classification;
 mod rules;
+mod values;
 
 pub use classification::Classification;
-pub use rules::{Classifier, RulesClassifier};
+pub use rules::{Classifier, RulesClassifier, VersionedRoles};
+pub use values::{
+    RoleCategories, ValueClassifier, ValueContext, ValueHeuristics, ALGORITHM_NAME,
+    ITERATION_COUNT, KEY_SIZE, NONCE_LENGTH,
+};
 
 pub use crate::error::ClassifierError;
 
@@ -55,6 +60,32 @@ mod integration_tests {
             arguments: vec![],
             raw_text: format!("{function}()"),
             language: language.to_string(),
+            enclosing_function: None,
+            failure_paths: Vec::new(),
+            crash_paths: Vec::new(),
+            zero_fallbacks: Vec::new(),
+            selection: None,
+            receiver_chain: Vec::new(),
+            receiver_construction: None,
+            secret: None,
+            salt: None,
+            key: None,
+            nonce: None,
+            key_encoding: None,
+            key_exchange: None,
+            nonce_counter: None,
+            password_storage: None,
+            secret_comparison: None,
+            iteration_tuning: None,
+            long_lived_aead: None,
+            kms: None,
+            pkcs11: None,
+            unauthenticated_mode: None,
+            random_bias: None,
+            validity: Vec::new(),
+            callback: None,
+            remediation_effort: None,
+            agility: None,
         }
     }
 
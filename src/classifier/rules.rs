@@ -1,9 +1,12 @@
+use super::values::{RoleCategories, ValueClassifier, ValueContext, ValueHeuristics};
 use super::Classification;
+use crate::discovery::languages::go::GoVersion;
 use crate::error::ClassifierError;
+use crate::scanner::DeclaredSecrets;
 use serde::Deserialize;
 use std::collections::HashMap;
 use std::fs;
-use std::path::Path;
+use std::path::{Path, PathBuf};
 use tracing::{debug, trace};
 
 pub trait Classifier: Send + Sync {
@@ -51,6 +54,10 @@ struct MappingsFile {
     struct_fields: HashMap<String, HashMap<String, String>>,
     #[serde(default)]
     constants: HashMap<String, HashMap<String, ConstantValue>>,
+    #[serde(default)]
+    parameters: ParameterRolesMap,
+    #[serde(default)]
+    parameter_versions: ParameterVersionsMap,
 }
 
 #[derive(Debug, Clone, Deserialize)]
@@ -62,15 +69,95 @@ pub struct ConstantValue {
     pub version: Option<String>,
 }
 
+/// Sinks merged into every Go preset. Method sinks are keyed by receiver type, e.g.
+/// `net/http.Server`.
+/// - `server_tls.json`: server TLS plumbing (HTTP servers, gRPC credentials), so
+///   transport-security posture is reported next to primitive usage.
+/// - `aead.json`: `cipher.AEAD` Seal/Open, so policies can check associated data.
+/// - `key_encoding.json`: private key marshaling and PEM encoding, so policies can flag
+///   keys stored or returned without encryption.
+/// - `key_exchange.json`: ECDH, X25519 and NaCl box exchanges, so policies can flag static
+///   private keys where forward secrecy is required.
+/// - `password_storage.json`: marshaling, file and SQL writes, reported only when a
+///   password flows into them, so policies can flag passwords stored in plain text.
+/// - `secret_comparison.json`: `bytes.Equal`, `reflect.DeepEqual` and the `==`/`!=`
+///   operators (as `builtin`), reported only when a MAC or key is compared, so policies
+///   can require constant-time comparison.
+/// - `cloud_kms.json`: AWS KMS, GCP Cloud KMS and Azure Key Vault encrypt, decrypt and
+///   data-key calls, so envelope encryption is told apart from local raw-key crypto.
+/// - `pkcs11.json`: `miekg/pkcs11` operations and `crypto11` key generation, so
+///   hardware-backed operations are inventoried with the rest.
+/// - `cipher_modes.json`: CBC, CTR, OFB and CFB mode constructors, so policies can flag
+///   encryption no MAC authenticates.
+/// - `key_lifetime.json`: certificate issuance, timers and JWK cache refresh, so
+///   policies can cap certificate lifetimes, rotation intervals and cache TTLs. Timers
+///   are reported only when they schedule key rotation.
+const BUILTIN_SINKS: &[(&str, &str)] = &[
+    ("server_tls.json", include_str!("server_tls.json")),
+    ("aead.json", include_str!("aead.json")),
+    ("key_encoding.json", include_str!("key_encoding.json")),
+    ("key_exchange.json", include_str!("key_exchange.json")),
+    (
+        "password_storage.json",
+        include_str!("password_storage.json"),
+    ),
+    (
+        "secret_comparison.json",
+        include_str!("secret_comparison.json"),
+    ),
+    ("cloud_kms.json", include_str!("cloud_kms.json")),
+    ("pkcs11.json", include_str!("pkcs11.json")),
+    ("cipher_modes.json", include_str!("cipher_modes.json")),
+    ("key_lifetime.json", include_str!("key_lifetime.json")),
+];
+
+/// Classification of the persistence sinks in `password_storage.json`, which declared
+/// secret sinks share.
+const PASSWORD_PERSIST: &str = "password_persist";
+
 type ImportMap = HashMap<String, HashMap<String, String>>;
 type StructFieldMap = HashMap<String, HashMap<String, String>>;
 type ConstantsMap = HashMap<String, HashMap<String, ConstantValue>>;
+/// import_path -> (function_name -> role of each positional argument)
+type ParameterRolesMap = HashMap<String, HashMap<String, Vec<String>>>;
+/// import_path -> (function_name -> roles per version range)
+type ParameterVersionsMap = HashMap<String, HashMap<String, Vec<VersionedRoles>>>;
+
+/// The roles of a sink's arguments for a range of versions of the package declaring it:
+/// Go versions (`go1.24`) for the standard library, module versions (`v0.20.0`)
+/// otherwise. `since` is inclusive and `until` exclusive; either may be left open.
+#[derive(Debug, Clone, Deserialize)]
+pub struct VersionedRoles {
+    #[serde(default)]
+    pub since: Option<String>,
+    #[serde(default)]
+    pub until: Option<String>,
+    pub roles: Vec<String>,
+}
+
+impl VersionedRoles {
+    fn contains(&self, version: GoVersion) -> bool {
+        let bound = |bound: &Option<String>| bound.as_deref().and_then(parse_version);
+        bound(&self.since).is_none_or(|since| version >= since)
+            && bound(&self.until).is_none_or(|until| version < until)
+    }
+}
+
+/// Module versions compare like Go versions once the `v` is dropped.
+fn parse_version(version: &str) -> Option<GoVersion> {
+    GoVersion::parse(version.trim().trim_start_matches('v'))
+}
 
 pub struct RulesClassifier {
     classifications: HashMap<String, Classification>,
     mappings: ImportMap,
     struct_fields: StructFieldMap,
     constants: ConstantsMap,
+    parameter_roles: ParameterRolesMap,
+    parameter_versions: ParameterVersionsMap,
+    value_roles: RoleCategories,
+    value_classifiers: Vec<Box<dyn ValueClassifier>>,
+    secrets: DeclaredSecrets,
 }
 
 impl RulesClassifier {
@@ -80,6 +167,11 @@ impl RulesClassifier {
             mappings: HashMap::new(),
             struct_fields: HashMap::new(),
             constants: HashMap::new(),
+            parameter_roles: HashMap::new(),
+            parameter_versions: HashMap::new(),
+            value_roles: RoleCategories::builtin(),
+            value_classifiers: Vec::new(),
+            secrets: DeclaredSecrets::default(),
         }
     }
 
@@ -93,7 +185,8 @@ impl RulesClassifier {
         let file: ClassificationsFile = serde_json::from_str(&content)
             .map_err(|e| ClassifierError::rules_parse_error(path, e.to_string()))?;
 
-        self.classifications = file.classifications;
+        // Presets loaded together classify the union of their keys
+        self.classifications.extend(file.classifications);
         debug!(count = self.classifications.len(), "loaded classifications");
         Ok(())
     }
@@ -128,6 +221,9 @@ impl RulesClassifier {
             }
         }
 
+        self.merge_parameter_roles(file.parameters);
+        self.merge_parameter_versions(file.parameter_versions);
+
         // Load constant values
         for (package, constants) in file.constants {
             let pkg_lower = package.to_lowercase();
@@ -192,6 +288,152 @@ impl RulesClassifier {
                 }
             }
         }
+        if let Some(parameters) = rules.parameters {
+            self.merge_parameter_roles(parameters);
+        }
+        if let Some(versions) = rules.parameter_versions {
+            self.merge_parameter_versions(versions);
+        }
+        if let Some(categories) = rules.value_categories {
+            for (category, roles) in categories {
+                for role in roles {
+                    self.value_roles.insert(&role, &category);
+                }
+            }
+        }
+        if let Some(secrets) = rules.secrets {
+            self.merge_secrets(secrets);
+        }
+        if let Some(struct_fields) = rules.struct_fields {
+            for (struct_type, fields) in struct_fields {
+                let entry = self
+                    .struct_fields
+                    .entry(struct_type.to_lowercase())
+                    .or_default();
+                for (field, key) in fields {
+                    entry.insert(field.to_lowercase(), key);
+                }
+            }
+        }
+    }
+
+    /// Maps declared sinks like the built-in persistence calls, so that calls to them are
+    /// matched; like those, they are only reported when a secret reaches them.
+    fn merge_secrets(&mut self, secrets: DeclaredSecrets) {
+        for sink in secrets.sinks.keys() {
+            let Some((owner, function)) = sink.rsplit_once('.') else {
+                continue;
+            };
+            self.mappings
+                .entry(owner.to_lowercase())
+                .or_default()
+                .entry(function.to_lowercase())
+                .or_insert_with(|| PASSWORD_PERSIST.to_string());
+        }
+        if !secrets.sinks.is_empty() {
+            self.classifications
+                .entry(PASSWORD_PERSIST.to_string())
+                .or_insert_with(|| Classification {
+                    finding_type: "password-storage".to_string(),
+                    operation: "persist".to_string(),
+                    ..Classification::default()
+                });
+        }
+        self.secrets.extend(secrets);
+    }
+
+    /// Secret sources and sinks declared under `secrets`.
+    pub fn get_secrets(&self) -> &DeclaredSecrets {
+        &self.secrets
+    }
+
+    fn merge_parameter_roles(&mut self, roles: ParameterRolesMap) {
+        for (import_path, functions) in roles {
+            let entry = self
+                .parameter_roles
+                .entry(import_path.to_lowercase())
+                .or_default();
+            for (func, roles) in functions {
+                entry.insert(func.to_lowercase(), roles);
+            }
+        }
+    }
+
+    /// Merges versioned roles. Until a version is selected, a sink takes the roles of its
+    /// open-ended range, the newest signature.
+    fn merge_parameter_versions(&mut self, versions: ParameterVersionsMap) {
+        for (import_path, functions) in versions {
+            let import_lower = import_path.to_lowercase();
+            for (func, ranges) in functions {
+                let func_lower = func.to_lowercase();
+                if let Some(newest) = ranges.iter().find(|range| range.until.is_none()) {
+                    self.parameter_roles
+                        .entry(import_lower.clone())
+                        .or_default()
+                        .insert(func_lower.clone(), newest.roles.clone());
+                }
+                self.parameter_versions
+                    .entry(import_lower.clone())
+                    .or_default()
+                    .insert(func_lower, ranges);
+            }
+        }
+    }
+
+    /// Gives versioned sinks the roles of the version in use. `version_of` returns the Go
+    /// or module version of the package an import path belongs to; sinks it has no
+    /// version for keep their newest roles. Returns the `import/path.function` sinks whose
+    /// roles changed.
+    pub fn select_parameter_versions(
+        &mut self,
+        version_of: impl Fn(&str) -> Option<String>,
+    ) -> Vec<String> {
+        let mut changed = Vec::new();
+        for (import_path, functions) in &self.parameter_versions {
+            let Some(version) = version_of(import_path).as_deref().and_then(parse_version) else {
+                continue;
+            };
+            for (func, ranges) in functions {
+                let Some(range) = ranges.iter().find(|range| range.contains(version)) else {
+                    continue;
+                };
+                let roles = self
+                    .parameter_roles
+                    .entry(import_path.clone())
+                    .or_default()
+                    .entry(func.clone())
+                    .or_default();
+                if *roles != range.roles {
+                    *roles = range.roles.clone();
+                    changed.push(format!("{import_path}.{func}"));
+                }
+            }
+        }
+        changed.sort();
+        changed
+    }
+
+    /// Positional roles of every sink that declares them, by lowercased import path and
+    /// function.
+    pub fn get_parameter_roles(&self) -> &HashMap<String, HashMap<String, Vec<String>>> {
+        &self.parameter_roles
+    }
+
+    /// Merges the built-in sink catalogs. Preset mappings for the same APIs win.
+    pub fn load_builtin_sinks(&mut self) -> Result<(), ClassifierError> {
+        for (name, content) in BUILTIN_SINKS {
+            let mut rules: UserRulesFile = serde_json::from_str(content)
+                .map_err(|e| ClassifierError::rules_parse_error(*name, e.to_string()))?;
+            if let Some(mappings) = rules.mappings.as_mut() {
+                for (import_path, functions) in mappings.iter_mut() {
+                    if let Some(existing) = self.mappings.get(&import_path.to_lowercase()) {
+                        functions.retain(|func, _| !existing.contains_key(&func.to_lowercase()));
+                    }
+                }
+            }
+            self.merge_user_rules(rules);
+        }
+        Ok(())
     }
 
     pub fn from_bundled() -> Result<Self, ClassifierError> {
@@ -203,20 +445,27 @@ impl RulesClassifier {
     }
 
     pub fn from_preset_path(preset_dir: &Path) -> Result<Self, ClassifierError> {
-        debug!(path = %preset_dir.display(), "loading classifier rules from preset");
-        let mut classifier = Self::new();
+        Self::from_preset_paths(&[preset_dir.to_path_buf()])
+    }
 
-        let classifications_path = preset_dir.join("classifications.json");
-        if classifications_path.exists() {
-            classifier.load_classifications(&classifications_path)?;
-        }
+    /// The rules of every preset in `preset_dirs` merged, as with `--preset crypto --preset tls`.
+    pub fn from_preset_paths(preset_dirs: &[PathBuf]) -> Result<Self, ClassifierError> {
+        let mut classifier = Self::new();
+        for preset_dir in preset_dirs {
+            debug!(path = %preset_dir.display(), "loading classifier rules from preset");
+            let classifications_path = preset_dir.join("classifications.json");
+            if classifications_path.exists() {
+                classifier.load_classifications(&classifications_path)?;
+            }
 
-        for lang in &["go", "python", "rust", "javascript"] {
-            let mappings_path = preset_dir.join(lang).join("mappings.json");
-            if mappings_path.exists() {
-                classifier.load_mappings(&mappings_path)?;
+            for lang in &["go", "python", "rust", "javascript"] {
+                let mappings_path = preset_dir.join(lang).join("mappings.json");
+                if mappings_path.exists() {
+                    classifier.load_mappings(&mappings_path)?;
+                }
             }
         }
+        classifier.load_builtin_sinks()?;
 
         debug!(
             classifications = classifier.classification_count(),
@@ -250,10 +499,36 @@ impl RulesClassifier {
         if mappings_path.exists() {
             classifier.load_mappings(mappings_path)?;
         }
+        if language == "go" {
+            classifier.load_builtin_sinks()?;
+        }
 
         Ok(classifier)
     }
 
+    /// Drops mappings for Go standard library packages that do not exist at `version`,
+    /// returning the import paths that were removed.
+    pub fn restrict_to_go_version(&mut self, version: GoVersion) -> Vec<String> {
+        let mut removed: Vec<String> = self
+            .mappings
+            .keys()
+            .filter(|import_path| !version.supports_package(import_path))
+            .cloned()
+            .collect();
+        removed.sort();
+
+        for import_path in &removed {
+            self.mappings.remove(import_path);
+        }
+
+        debug!(go_version = %version, removed = removed.len(), "restricted mappings to Go version");
+        removed
+    }
+
+    pub fn get_classifications(&self) -> &HashMap<String, Classification> {
+        &self.classifications
+    }
+
     pub fn classification_count(&self) -> usize {
         self.classifications.len()
     }
@@ -288,6 +563,42 @@ impl RulesClassifier {
         self.struct_fields.contains_key(&type_lower)
     }
 
+    /// Role names of a function's positional arguments, e.g. `["password", "salt",
+    /// "iterations", "keyLen", "hashFunc"]`, tried by import path and then package name
+    /// like [`Classifier::lookup_with_fallback`].
+    pub fn parameter_roles(
+        &self,
+        import_path: Option<&str>,
+        package: &str,
+        function: &str,
+    ) -> Option<&[String]> {
+        let func_lower = function.to_lowercase();
+        import_path
+            .into_iter()
+            .chain([package])
+            .find_map(|path| {
+                self.parameter_roles
+                    .get(&path.to_lowercase())?
+                    .get(&func_lower)
+            })
+            .map(Vec::as_slice)
+    }
+
+    /// Registers a classifier tried before the built-in role table and heuristics.
+    pub fn register_value_classifier(&mut self, classifier: impl ValueClassifier + 'static) {
+        self.value_classifiers.push(Box::new(classifier));
+    }
+
+    /// The semantic category of an argument: from registered classifiers, then the role
+    /// table, then heuristics over positional arguments.
+    pub fn value_category(&self, value: &ValueContext) -> Option<String> {
+        self.value_classifiers
+            .iter()
+            .find_map(|classifier| classifier.classify(value))
+            .or_else(|| self.value_roles.classify(value))
+            .or_else(|| ValueHeuristics.classify(value))
+    }
+
     pub fn lookup_constant(&self, package: &str, constant_name: &str) -> Option<&ConstantValue> {
         let pkg_lower = package.to_lowercase();
         let const_lower = constant_name.to_lowercase();
@@ -324,6 +635,12 @@ impl Classifier for RulesClassifier {
 struct UserRulesFile {
     classifications: Option<HashMap<String, Classification>>,
     mappings: Option<HashMap<String, HashMap<String, String>>>,
+    struct_fields: Option<HashMap<String, HashMap<String, String>>>,
+    parameters: Option<ParameterRolesMap>,
+    parameter_versions: Option<ParameterVersionsMap>,
+    /// category -> roles, e.g. `{"iteration-count": ["workFactor"]}`
+    value_categories: Option<HashMap<String, Vec<String>>>,
+    secrets: Option<DeclaredSecrets>,
 }
 
 #[cfg(test)]
@@ -344,6 +661,200 @@ mod tests {
         assert!(result.is_unclassified());
     }
 
+    #[test]
+    fn test_restrict_to_go_version() {
+        let mut classifier = RulesClassifier::new();
+        classifier.merge_user_rules(UserRulesFile {
+            classifications: None,
+            mappings: Some(HashMap::from([
+                (
+                    "crypto/pbkdf2".to_string(),
+                    HashMap::from([("Key".to_string(), "pbkdf2".to_string())]),
+                ),
+                (
+                    "golang.org/x/crypto/pbkdf2".to_string(),
+                    HashMap::from([("Key".to_string(), "pbkdf2".to_string())]),
+                ),
+            ])),
+            struct_fields: None,
+            parameters: None,
+            parameter_versions: None,
+            value_categories: None,
+            secrets: None,
+        });
+
+        let removed = classifier.restrict_to_go_version(GoVersion::new(1, 22, 0));
+        assert_eq!(removed, vec!["crypto/pbkdf2".to_string()]);
+        assert!(classifier
+            .get_mappings()
+            .contains_key("golang.org/x/crypto/pbkdf2"));
+
+        let mut classifier = RulesClassifier::new();
+        classifier.merge_user_rules(UserRulesFile {
+            classifications: None,
+            mappings: Some(HashMap::from([(
+                "crypto/pbkdf2".to_string(),
+                HashMap::from([("Key".to_string(), "pbkdf2".to_string())]),
+            )])),
+            struct_fields: None,
+            parameters: None,
+            parameter_versions: None,
+            value_categories: None,
+            secrets: None,
+        });
+        assert!(classifier
+            .restrict_to_go_version(GoVersion::new(1, 24, 0))
+            .is_empty());
+    }
+
+    #[test]
+    fn test_parameter_roles_by_import_path_then_package() {
+        let mut classifier = RulesClassifier::new();
+        classifier
+            .parse_user_rules_json(
+                r#"{"parameters": {
+                    "golang.org/x/crypto/pbkdf2": {
+                        "Key": ["password", "salt", "iterations", "keyLen", "hashFunc"]
+                    },
+                    "pbkdf2": {"Key": ["hashFunc", "password", "salt", "iterations", "keyLen"]}
+                }}"#,
+            )
+            .unwrap();
+
+        let roles = |import_path| classifier.parameter_roles(import_path, "pbkdf2", "key");
+        assert_eq!(
+            roles(Some("golang.org/x/crypto/pbkdf2")).map(|r| r[0].as_str()),
+            Some("password")
+        );
+        assert_eq!(
+            roles(Some("crypto/pbkdf2")).map(|r| r[0].as_str()),
+            Some("hashFunc")
+        );
+        assert_eq!(classifier.parameter_roles(None, "scrypt", "Key"), None);
+    }
+
+    #[test]
+    fn test_declared_secret_sinks_are_mapped() {
+        let mut classifier = RulesClassifier::new();
+        classifier
+            .parse_user_rules_json(
+                r#"{"secrets": {
+                    "sources": ["example.com/app/vault.Get"],
+                    "sinks": {"example.com/app/log.Logger.Info": 1}
+                }}"#,
+            )
+            .unwrap();
+
+        assert_eq!(
+            classifier
+                .lookup("example.com/app/log.logger", "info")
+                .finding_type,
+            "password-storage"
+        );
+        assert_eq!(
+            classifier.get_secrets().sources,
+            vec!["example.com/app/vault.Get".to_string()]
+        );
+    }
+
+    #[test]
+    fn test_parameter_roles_selected_by_version() {
+        let mut classifier = RulesClassifier::new();
+        classifier
+            .parse_user_rules_json(
+                r#"{"parameter_versions": {
+                    "example.com/kdf": {"Derive": [
+                        {"until": "v2.0.0", "roles": ["password", "salt"]},
+                        {"since": "v2.0.0", "roles": ["ctx", "password", "salt"]}
+                    ]}
+                }}"#,
+            )
+            .unwrap();
+        let first_role = |classifier: &RulesClassifier| {
+            classifier
+                .parameter_roles(Some("example.com/kdf"), "kdf", "Derive")
+                .map(|roles| roles[0].clone())
+        };
+        assert_eq!(first_role(&classifier).as_deref(), Some("ctx"));
+
+        let changed = classifier.select_parameter_versions(|_| Some("v1.4.2".to_string()));
+        assert_eq!(changed, vec!["example.com/kdf.derive".to_string()]);
+        assert_eq!(first_role(&classifier).as_deref(), Some("password"));
+
+        assert!(classifier.select_parameter_versions(|_| None).is_empty());
+        assert_eq!(first_role(&classifier).as_deref(), Some("password"));
+    }
+
+    #[test]
+    fn test_builtin_server_tls_sinks() {
+        let mut classifier = RulesClassifier::new();
+        classifier.merge_user_rules(UserRulesFile {
+            classifications: None,
+            mappings: Some(HashMap::from([(
+                "google.golang.org/grpc/credentials".to_string(),
+                HashMap::from([("NewTLS".to_string(), "preset_grpc_tls".to_string())]),
+            )])),
+            struct_fields: None,
+            parameters: None,
+            parameter_versions: None,
+            value_categories: None,
+            secrets: None,
+        });
+        classifier.load_builtin_sinks().unwrap();
+
+        let listen = classifier.lookup("net/http.Server", "ListenAndServeTLS");
+        assert_eq!(listen.finding_type, "protocol");
+        assert_eq!(listen.protocol_name.as_deref(), Some("TLS"));
+        assert_eq!(
+            classifier.lookup_struct_field("net/http.Server", "TLSConfig"),
+            Some("server_tls_http_config")
+        );
+        assert_eq!(
+            classifier.get_mappings()["google.golang.org/grpc/credentials"]["newtls"],
+            "preset_grpc_tls"
+        );
+        assert_eq!(
+            classifier.get_mappings()["google.golang.org/grpc/credentials"]["newservertlsfromfile"],
+            "server_tls_grpc_credentials_file"
+        );
+        assert_eq!(
+            classifier
+                .lookup("crypto/cipher.AEAD", "Seal")
+                .primitive
+                .as_deref(),
+            Some("aead")
+        );
+        let marshal = classifier.lookup("crypto/x509", "MarshalPKCS8PrivateKey");
+        assert_eq!(marshal.finding_type, "key");
+        assert_eq!(marshal.operation, "encode");
+        let exchange = classifier.lookup("crypto/ecdh.PrivateKey", "ECDH");
+        assert_eq!(exchange.finding_type, "key-agreement");
+        assert_eq!(exchange.primitive.as_deref(), Some("key-agree"));
+        let persist = classifier.lookup("database/sql.DB", "Exec");
+        assert_eq!(persist.finding_type, "password-storage");
+        let compare = classifier.lookup("builtin", "==");
+        assert_eq!(compare.finding_type, "secret-comparison");
+        let data_key = classifier.lookup(
+            "github.com/aws/aws-sdk-go-v2/service/kms.Client",
+            "GenerateDataKey",
+        );
+        assert_eq!(data_key.finding_type, "key-management");
+        assert_eq!(data_key.material_source.as_deref(), Some("kms"));
+        assert_eq!(
+            classifier
+                .parameter_roles(
+                    Some("github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azkeys.Client"),
+                    "azkeys",
+                    "Encrypt"
+                )
+                .unwrap()[1],
+            "key_name"
+        );
+        let encrypt = classifier.lookup("github.com/miekg/pkcs11.Ctx", "EncryptInit");
+        assert_eq!(encrypt.operation, "encrypt");
+        assert_eq!(encrypt.material_source.as_deref(), Some("hsm"));
+    }
+
     #[test]
     fn test_load_bundled_classifications() {
         let classifier = RulesClassifier::from_bundled();
@@ -447,4 +958,46 @@ mod tests {
         assert!(!result2.is_unclassified());
         assert!(!result3.is_unclassified());
     }
+
+    #[test]
+    fn test_value_category_order() {
+        struct Opaque;
+        impl ValueClassifier for Opaque {
+            fn classify(&self, value: &ValueContext) -> Option<String> {
+                (value.function == "Seal").then(|| "opaque".to_string())
+            }
+        }
+
+        let mut classifier = RulesClassifier::new();
+        classifier
+            .parse_user_rules_json(r#"{"value_categories": {"iteration-count": ["work_factor"]}}"#)
+            .unwrap();
+        let kdf = Classification {
+            finding_type: "kdf".to_string(),
+            ..Classification::unclassified()
+        };
+        let value = serde_json::json!(32);
+        let context = |function: &'static str, role: Option<&'static str>| ValueContext {
+            function,
+            classification: &kdf,
+            position: 3,
+            role,
+            value: &value,
+        };
+
+        let category = |function, role| classifier.value_category(&context(function, role));
+        assert_eq!(
+            category("Derive", Some("workFactor")).as_deref(),
+            Some("iteration-count")
+        );
+        assert_eq!(category("Key", Some("keyLen")).as_deref(), Some("key-size"));
+        assert_eq!(category("Key", None).as_deref(), Some("key-size"));
+        assert_eq!(category("Seal", None).as_deref(), Some("key-size"));
+
+        classifier.register_value_classifier(Opaque);
+        assert_eq!(
+            classifier.value_category(&context("Seal", None)).as_deref(),
+            Some("opaque")
+        );
+    }
 }
@@ -0,0 +1,317 @@
+//! Semantic categories of resolved argument values, such as `iteration-count` or
+//! `key-size`.
+
+use std::collections::HashMap;
+
+use super::Classification;
+
+pub const KEY_SIZE: &str = "key-size";
+pub const ITERATION_COUNT: &str = "iteration-count";
+pub const NONCE_LENGTH: &str = "nonce-length";
+pub const ALGORITHM_NAME: &str = "algorithm-name";
+
+/// One argument of a finding, as value classifiers see it.
+#[derive(Debug, Clone, Copy)]
+pub struct ValueContext<'a> {
+    /// The sink's function name, e.g. `Key` or `NewGCMWithNonceSize`.
+    pub function: &'a str,
+    pub classification: &'a Classification,
+    /// Zero-based argument position.
+    pub position: usize,
+    /// The role the sink declares for the argument, if any.
+    pub role: Option<&'a str>,
+    /// The resolved value as reported: a scalar, a list of possible values, or a
+    /// description of an unresolved argument.
+    pub value: &'a serde_json::Value,
+}
+
+/// Tags an argument with a semantic category. Classifiers are tried in order and the
+/// first category returned wins.
+pub trait ValueClassifier: Send + Sync {
+    fn classify(&self, value: &ValueContext) -> Option<String>;
+}
+
+/// Categories by declared role, e.g. `iterations` and `rounds` are iteration counts.
+/// Roles are compared ignoring case, `_` and `-`, so `keyLen` matches `key_len`.
+#[derive(Debug, Clone, Default)]
+pub struct RoleCategories {
+    roles: HashMap<String, String>,
+}
+
+impl RoleCategories {
+    /// The roles of the bundled sink catalogs and common library signatures.
+    pub fn builtin() -> Self {
+        let mut categories = Self::default();
+        let table: &[(&str, &[&str])] = &[
+            (
+                KEY_SIZE,
+                &["bits", "keyLen", "keyLength", "keySize", "keyBits", "dkLen"],
+            ),
+            (
+                ITERATION_COUNT,
+                &["iterations", "iter", "iterCount", "rounds", "cost", "time"],
+            ),
+            (
+                NONCE_LENGTH,
+                &[
+                    "nonceSize",
+                    "nonceLength",
+                    "nonceLen",
+                    "ivSize",
+                    "ivLength",
+                    "ivLen",
+                ],
+            ),
+            (
+                ALGORITHM_NAME,
+                &[
+                    "algorithm",
+                    "alg",
+                    "hash",
+                    "hashFunc",
+                    "hashName",
+                    "digest",
+                    "curve",
+                    "cipher",
+                    "mechanism",
+                ],
+            ),
+        ];
+        for (category, roles) in table {
+            for role in *roles {
+                categories.insert(role, category);
+            }
+        }
+        categories
+    }
+
+    pub fn insert(&mut self, role: &str, category: &str) {
+        self.roles.insert(normalize(role), category.to_string());
+    }
+}
+
+impl ValueClassifier for RoleCategories {
+    fn classify(&self, value: &ValueContext) -> Option<String> {
+        self.roles.get(&normalize(value.role?)).cloned()
+    }
+}
+
+fn normalize(role: &str) -> String {
+    role.chars()
+        .filter(|c| *c != '_' && *c != '-')
+        .flat_map(char::to_lowercase)
+        .collect()
+}
+
+/// Algorithm names that show up as string arguments, compared ignoring case and `-`.
+const ALGORITHM_NAMES: &[&str] = &[
+    "aes",
+    "aesgcm",
+    "aescbc",
+    "aesctr",
+    "des",
+    "3des",
+    "tripledes",
+    "rc4",
+    "chacha20",
+    "chacha20poly1305",
+    "xchacha20poly1305",
+    "md5",
+    "sha1",
+    "sha224",
+    "sha256",
+    "sha384",
+    "sha512",
+    "sha3256",
+    "sha3512",
+    "blake2b",
+    "blake2s",
+    "hmac",
+    "rsa",
+    "ecdsa",
+    "ed25519",
+    "x25519",
+    "p224",
+    "p256",
+    "p384",
+    "p521",
+    "secp256k1",
+    "hs256",
+    "hs384",
+    "hs512",
+    "rs256",
+    "rs384",
+    "rs512",
+    "es256",
+    "es384",
+    "es512",
+    "ps256",
+    "eddsa",
+    "a128gcm",
+    "a256gcm",
+];
+
+/// Common RSA and DSA modulus sizes in bits.
+const MODULUS_BITS: &[i64] = &[512, 768, 1024, 2048, 3072, 4096, 8192];
+
+/// Derived key lengths in bytes.
+const KEY_BYTES: &[i64] = &[16, 24, 32, 48, 64];
+
+/// Categories of arguments the sink declares no role for, from the call and the value:
+/// algorithm names passed as strings, key-derivation work factors and output lengths,
+/// modulus sizes of RSA key generation, and the size passed to a `...NonceSize` function.
+#[derive(Debug, Clone, Copy, Default)]
+pub struct ValueHeuristics;
+
+impl ValueClassifier for ValueHeuristics {
+    fn classify(&self, value: &ValueContext) -> Option<String> {
+        if value.role.is_some_and(|role| !role.is_empty()) {
+            return None;
+        }
+        let strings = strings(value.value);
+        if !strings.is_empty() && strings.iter().all(|s| is_algorithm_name(s)) {
+            return Some(ALGORITHM_NAME.to_string());
+        }
+        let ints = ints(value.value);
+        if ints.is_empty() {
+            return None;
+        }
+        let algorithm = value
+            .classification
+            .algorithm
+            .as_deref()
+            .unwrap_or_default()
+            .to_ascii_uppercase();
+        let category = if value.function.to_ascii_lowercase().ends_with("noncesize") {
+            NONCE_LENGTH
+        } else if value.classification.finding_type == "kdf" {
+            if ints.iter().all(|&n| n >= 1000) {
+                ITERATION_COUNT
+            } else if ints.iter().all(|n| KEY_BYTES.contains(n)) {
+                KEY_SIZE
+            } else {
+                return None;
+            }
+        } else if (algorithm.contains("RSA") || algorithm.contains("DSA"))
+            && ints.iter().all(|n| MODULUS_BITS.contains(n))
+        {
+            KEY_SIZE
+        } else {
+            return None;
+        };
+        Some(category.to_string())
+    }
+}
+
+fn is_algorithm_name(value: &str) -> bool {
+    let name: String = value
+        .trim_start_matches("crypto.")
+        .chars()
+        .filter(|c| *c != '-' && *c != '_' && *c != '/')
+        .flat_map(char::to_lowercase)
+        .collect();
+    ALGORITHM_NAMES.contains(&name.as_str())
+}
+
+fn ints(value: &serde_json::Value) -> Vec<i64> {
+    match value {
+        serde_json::Value::Number(n) => n.as_i64().into_iter().collect(),
+        serde_json::Value::Array(items) => items.iter().filter_map(|v| v.as_i64()).collect(),
+        _ => Vec::new(),
+    }
+}
+
+fn strings(value: &serde_json::Value) -> Vec<&str> {
+    match value {
+        serde_json::Value::String(s) => vec![s.as_str()],
+        serde_json::Value::Array(items) => items.iter().filter_map(|v| v.as_str()).collect(),
+        _ => Vec::new(),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use serde_json::json;
+
+    fn context<'a>(
+        function: &'a str,
+        classification: &'a Classification,
+        role: Option<&'a str>,
+        value: &'a serde_json::Value,
+    ) -> ValueContext<'a> {
+        ValueContext {
+            function,
+            classification,
+            position: 0,
+            role,
+            value,
+        }
+    }
+
+    #[test]
+    fn test_role_categories_ignore_case_and_separators() {
+        let kdf = Classification::unclassified();
+        let value = json!(32);
+        let roles = RoleCategories::builtin();
+        let classify = |role| roles.classify(&context("Key", &kdf, Some(role), &value));
+
+        assert_eq!(classify("key_len").as_deref(), Some(KEY_SIZE));
+        assert_eq!(classify("Iterations").as_deref(), Some(ITERATION_COUNT));
+        assert_eq!(classify("password"), None);
+
+        let mut roles = RoleCategories::default();
+        roles.insert("workFactor", ITERATION_COUNT);
+        assert_eq!(
+            roles
+                .classify(&context("Derive", &kdf, Some("work-factor"), &value))
+                .as_deref(),
+            Some(ITERATION_COUNT)
+        );
+    }
+
+    #[test]
+    fn test_heuristics_for_positional_arguments() {
+        let kdf = Classification {
+            algorithm: Some("PBKDF2".to_string()),
+            finding_type: "kdf".to_string(),
+            ..Classification::unclassified()
+        };
+        let rsa = Classification {
+            algorithm: Some("RSA".to_string()),
+            ..Classification::unclassified()
+        };
+        let other = Classification::unclassified();
+        let classify = |function, classification, value: serde_json::Value| {
+            ValueHeuristics.classify(&context(function, classification, None, &value))
+        };
+
+        assert_eq!(
+            classify("Key", &kdf, json!([4096, 600000])).as_deref(),
+            Some(ITERATION_COUNT)
+        );
+        assert_eq!(classify("Key", &kdf, json!(32)).as_deref(), Some(KEY_SIZE));
+        assert_eq!(classify("Key", &kdf, json!(8)), None);
+        assert_eq!(
+            classify("GenerateKey", &rsa, json!(2048)).as_deref(),
+            Some(KEY_SIZE)
+        );
+        assert_eq!(
+            classify("NewGCMWithNonceSize", &other, json!(16)).as_deref(),
+            Some(NONCE_LENGTH)
+        );
+        assert_eq!(
+            classify("New", &other, json!("SHA-256")).as_deref(),
+            Some(ALGORITHM_NAME)
+        );
+        assert_eq!(classify("New", &other, json!("hello")), None);
+        assert_eq!(classify("New", &other, json!(2048)), None);
+
+        // A declared role is left to the role table
+        let value = json!(600000);
+        assert_eq!(
+            ValueHeuristics.classify(&context("Key", &kdf, Some("password"), &value)),
+            None
+        );
+    }
+}
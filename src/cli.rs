@@ -1,13 +1,49 @@
 use anyhow::{Context as AnyhowContext, Result};
-use clap::{Parser, ValueEnum};
+use clap::{Parser, Subcommand, ValueEnum};
 use std::path::{Path, PathBuf};
 
+use crate::discovery::languages::go::GoVersion;
+use crate::engine::DEFAULT_MAX_DERIVATION_DEPTH;
+use crate::history::DEFAULT_HISTORY_DB;
+use crate::policy::Severity;
+
 #[derive(Debug, Clone, Copy, PartialEq, Eq, ValueEnum)]
 pub enum OutputFormat {
     Json,
     Cbom,
 }
 
+/// What a run is for: enforcing rules, or only cataloguing crypto usage.
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, ValueEnum)]
+pub enum ScanMode {
+    /// Policy gates, baselines and vulnerability checks are available
+    #[default]
+    Enforce,
+    /// Report-only catalog of sinks, values, locations and provenance; no pass/fail rules
+    Inventory,
+}
+
+/// Which findings to keep when a wrapper function and its calls are both mapped as sinks.
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, ValueEnum)]
+pub enum WrapperAttribution {
+    /// Report the sink call inside the wrapper only
+    DefinitionSite,
+    /// Report each call of the wrapper only
+    CallSite,
+    /// Report both, each linked to the other
+    #[default]
+    BothLinked,
+}
+
+/// Compatibility modes for projects that do not follow current tooling conventions.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, ValueEnum)]
+pub enum CompatMode {
+    /// Legacy GOPATH workspace without go.mod
+    Gopath,
+    /// Bazel or Please monorepo: load packages from the $GOPACKAGESDRIVER driver
+    Bazel,
+}
+
 #[derive(Debug, Clone, Copy, PartialEq, Eq, Hash, ValueEnum)]
 pub enum Language {
     Go,
@@ -21,9 +57,18 @@ pub enum Language {
 #[command(name = "argflow")]
 #[command(about = "Argument flow analyzer - trace where function arguments come from", long_about = None)]
 pub struct Args {
-    /// Path to file or directory to analyze
+    #[command(subcommand)]
+    pub command: Option<Command>,
+
+    /// Path to file, directory or archive (.zip, .tar, .tar.gz) to analyze; `-` reads an
+    /// archive from stdin (required unless running `trend`)
     #[arg(long, value_name = "PATH")]
-    pub path: PathBuf,
+    pub path: Option<PathBuf>,
+
+    /// What to analyze, as with --path. `argflow .` without presets, rules or a
+    /// subcommand runs the zero-config quickstart scan
+    #[arg(value_name = "TARGET", conflicts_with = "path")]
+    pub target: Option<PathBuf>,
 
     /// Preset to use (e.g., crypto, tls). Can be specified multiple times.
     #[arg(long, value_name = "PRESET")]
@@ -33,7 +78,7 @@ pub struct Args {
     #[arg(long, value_name = "FILE")]
     pub rules: Option<PathBuf>,
 
-    /// Output file path (prints to stdout if not specified)
+    /// Output file path (prints to stdout if not specified; gzip-compressed when it ends in .gz)
     #[arg(short = 'O', long, value_name = "FILE")]
     pub output_file: Option<PathBuf>,
 
@@ -49,6 +94,118 @@ pub struct Args {
     #[arg(long)]
     pub include_deps: bool,
 
+    /// File overlay in the go command's `-overlay` format: `{"Replace": {"path": "contents
+    /// file"}}`, with `""` deleting a file. Overlaid files are scanned in place of, or in
+    /// addition to, the files on disk (Go only)
+    #[arg(long, value_name = "FILE")]
+    pub overlay: Option<PathBuf>,
+
+    /// Treat a vendored or forked import path as its upstream, e.g.
+    /// internal/thirdparty/config=github.com/acme/config. Can be specified multiple times.
+    #[arg(long, value_name = "FORK=UPSTREAM", value_parser = parse_equivalence)]
+    pub import_equivalence: Vec<(String, String)>,
+
+    /// Maximum number of declarations an argument is followed through (e.g.
+    /// DefaultIterations -> PBKDF2Iterations -> literal is 2); longer chains are reported
+    /// unresolved with reason derivation-limit
+    #[arg(long, value_name = "N", default_value_t = DEFAULT_MAX_DERIVATION_DEPTH)]
+    pub max_derivation_depth: usize,
+
+    /// Resolve Go modules from the local module cache only: the go command runs with
+    /// GOPROXY=off and GOTOOLCHAIN=local and never downloads anything
+    #[arg(long, conflicts_with_all = ["goproxy", "govulncheck"])]
+    pub offline: bool,
+
+    /// Go module proxy to fetch dependencies from instead of $GOPROXY
+    #[arg(long, value_name = "URL")]
+    pub goproxy: Option<String>,
+
+    /// Go language version to target (read from go.mod if not specified)
+    #[arg(long, value_name = "VERSION", value_parser = parse_go_version)]
+    pub go_version: Option<GoVersion>,
+
+    /// Local Go vulnerability database (OSV JSON file or directory) to annotate findings with advisories
+    #[arg(long, value_name = "PATH")]
+    pub vulndb: Option<PathBuf>,
+
+    /// Run govulncheck alongside the scan and merge its results into the report (Go only)
+    #[arg(long, conflicts_with = "govulncheck_json")]
+    pub govulncheck: bool,
+
+    /// Merge results from a saved `govulncheck -json` run instead of running it
+    #[arg(long, value_name = "FILE")]
+    pub govulncheck_json: Option<PathBuf>,
+
+    /// Report the FIPS posture (BoringCrypto / Go FIPS 140-3) of a Go project
+    #[arg(long)]
+    pub fips: bool,
+
+    /// Run `go generate ./...` in the scanned tree before the scan, so constants in
+    /// generated files resolve to the values the build uses (Go only; generators write
+    /// into the tree)
+    #[arg(long)]
+    pub go_generate: bool,
+
+    /// Only run the //go:generate directives matching this regular expression
+    #[arg(long, value_name = "REGEX", requires = "go_generate")]
+    pub go_generate_run: Option<String>,
+
+    /// Report crypto settings in //go:generate directives and code generator configs
+    /// (sqlc, buf) that only exist in generator inputs (Go only)
+    #[arg(long)]
+    pub generators: bool,
+
+    /// Record the commit, author and age of each finding's line from `git blame`, and the
+    /// repository's remote, branch and commit
+    #[arg(long)]
+    pub blame: bool,
+
+    /// Show hardcoded secrets in reports instead of redacting them to their length,
+    /// entropy and fingerprint
+    #[arg(long)]
+    pub show_secrets: bool,
+
+    /// Sign the written report with this Ed25519 PKCS#8 PEM key (requires --output-file)
+    #[arg(long, value_name = "KEY")]
+    pub sign: Option<PathBuf>,
+
+    /// Where to write the signed attestation (default: <output-file>.intoto.jsonl)
+    #[arg(long, value_name = "FILE", requires = "sign")]
+    pub attestation: Option<PathBuf>,
+
+    /// Post a summary to the webhooks in this notification config (JSON or YAML)
+    #[arg(long, value_name = "FILE")]
+    pub notify: Option<PathBuf>,
+
+    /// Export traces and metrics to this OTLP/HTTP endpoint (default: $OTEL_EXPORTER_OTLP_ENDPOINT)
+    #[arg(long, value_name = "URL")]
+    pub otlp_endpoint: Option<String>,
+
+    /// Compatibility mode (gopath: load a pre-module GOPATH project; bazel: load packages
+    /// from a go/packages driver)
+    #[arg(long, value_name = "MODE")]
+    pub compat: Option<CompatMode>,
+
+    /// Scan every Go module under --path (each directory with a go.mod, such as test
+    /// fixture modules) on its own, as the go command would from its root, and report
+    /// findings per module
+    #[arg(long, conflicts_with = "compat")]
+    pub recurse_modules: bool,
+
+    /// golangci-lint config whose `argflow` custom linter settings fill in the presets,
+    /// rules file, mode, derivation depth and gate severity not given on the command line
+    #[arg(long, value_name = "FILE")]
+    pub golangci_config: Option<PathBuf>,
+
+    /// Run mode (inventory: report the crypto usage catalog only, with no pass/fail rules)
+    #[arg(long, value_name = "MODE", default_value = "enforce")]
+    pub mode: ScanMode,
+
+    /// Where to report crypto use through mapped wrapper functions (definition-site,
+    /// call-site, both-linked)
+    #[arg(long, value_name = "STRATEGY", default_value = "both-linked")]
+    pub wrapper_attribution: WrapperAttribution,
+
     /// Increase verbosity (-v info, -vv debug, -vvv trace)
     #[arg(short, long, action = clap::ArgAction::Count)]
     pub verbose: u8,
@@ -58,16 +215,665 @@ pub struct Args {
     pub quiet: bool,
 }
 
+#[derive(Subcommand, Debug)]
+pub enum Command {
+    /// Scan, then check findings against a policy and exit non-zero on blocking violations.
+    ///
+    /// Scan options go before the subcommand: `argflow --path . --preset crypto gate --policy p.yaml`
+    Gate(GateArgs),
+
+    /// Scan and store the findings in the history database.
+    Record(RecordArgs),
+
+    /// Report findings opened and fixed over recorded scans.
+    Trend(TrendArgs),
+
+    /// Maintain gate baselines.
+    Baseline(BaselineArgs),
+
+    /// Print the JSON Schema for a config file or the JSON report.
+    Schema(SchemaArgs),
+
+    /// Scan, then extract a minimal Go module reproducing one finding.
+    Repro(ReproArgs),
+
+    /// Scan, then insert `argflow:ignore` comments above policy violations.
+    ///
+    /// Annotates baselined violations when `--baseline` is given, otherwise every
+    /// violation; `--rule` narrows either set.
+    Annotate(AnnotateArgs),
+
+    /// Scan, then break findings down per `main` package (Go only).
+    Inventory(InventoryArgs),
+
+    /// Scan, then list the imported packages the sink catalogs cover and the
+    /// crypto-looking ones they do not, whose calls the report cannot show (Go only).
+    Coverage(CoverageArgs),
+
+    /// Scan, then write policy violations, or every finding without `--policy`, in the
+    /// import format of a vulnerability manager such as DefectDojo.
+    ///
+    /// Scan options go before the subcommand: `argflow --path . --preset crypto export --format defectdojo --policy p.yaml`
+    Export(ExportArgs),
+
+    /// Scan, then reconcile the findings with another tool's SARIF results, such as
+    /// gosec's or semgrep's: calls found by both, only by argflow, and only by the other.
+    ///
+    /// Scan options go before the subcommand: `argflow --path . --preset crypto compare gosec.sarif --rule 'G4*' --rule 'G5*'`
+    Compare(CompareArgs),
+
+    /// List every sink and policy rule with its thresholds, presets and whether the
+    /// current options enable it.
+    ///
+    /// Rule options go before the subcommand: `argflow --preset crypto rules --policy p.yaml --format json`
+    Rules(RulesArgs),
+
+    /// Scan as if package-level constants or variables had other values, and report the
+    /// arguments and policy violations that would change. No source is edited.
+    ///
+    /// Scan options go before the subcommand: `argflow --path . --preset crypto simulate --set config.PBKDF2Iterations=600000`
+    Simulate(SimulateArgs),
+
+    /// Scan two versions of a Go module and report crypto calls added or removed and
+    /// arguments whose values changed. Needs no --path; the versions are fetched into the
+    /// module cache.
+    ///
+    /// Scan options go before the subcommand: `argflow --preset crypto dep-diff golang.org/x/crypto v0.20.0 v0.27.0`
+    DepDiff(DepDiffArgs),
+
+    /// Scan, then report findings that deviate from a reference architecture spec:
+    /// unapproved algorithms, crypto in undeclared packages, or sinks bypassing the
+    /// approved wrapper.
+    ///
+    /// Scan options go before the subcommand: `argflow --path . --preset crypto architecture --spec arch.yaml`
+    Architecture(ArchitectureArgs),
+
+    /// Check sink catalogs against the packages they describe.
+    ///
+    /// Rule options go before the subcommand: `argflow --path . --preset crypto sinks verify`
+    Sinks(SinksArgs),
+
+    /// Try rule conditions against a saved JSON report without scanning.
+    Rule(RuleArgs),
+
+    /// Scan, then print every project constant reaching a crypto call, with its value,
+    /// declaration and the calls it reaches, as a manifest to commit and review.
+    ///
+    /// Scan options go before the subcommand: `argflow --path . --preset crypto params > argflow.params.yaml`
+    Params(ParamsArgs),
+
+    /// Merge saved JSON reports of many repositories into organization-wide algorithm
+    /// and parameter distributions, with no locations and differentially private counts.
+    Aggregate(AggregateArgs),
+}
+
+#[derive(clap::Args, Debug)]
+pub struct GateArgs {
+    /// Policy file (JSON or YAML)
+    #[arg(long, value_name = "FILE")]
+    pub policy: PathBuf,
+
+    /// Baseline of accepted violations; missing files are treated as empty
+    #[arg(long, value_name = "FILE")]
+    pub baseline: Option<PathBuf>,
+
+    /// Write all current violations to the baseline file instead of failing
+    #[arg(long, requires = "baseline")]
+    pub update_baseline: bool,
+
+    /// Only violations in files changed since this git ref can fail the gate
+    #[arg(long, value_name = "REF")]
+    pub diff_base: Option<String>,
+
+    /// Write the machine-readable gate report to this file
+    #[arg(long, value_name = "FILE")]
+    pub report: Option<PathBuf>,
+
+    /// Write one gate report per owning team from the policy's `owners` to this directory
+    #[arg(long, value_name = "DIR")]
+    pub team_reports: Option<PathBuf>,
+
+    /// Print the gate report as JSON instead of text
+    #[arg(long)]
+    pub json: bool,
+
+    /// Lowest severity that fails the gate (info, warning, error), overriding the policy's
+    /// `fail_on`
+    #[arg(long, value_name = "SEVERITY", value_parser = parse_severity)]
+    pub fail_on: Option<Severity>,
+}
+
+#[derive(clap::Args, Debug)]
+pub struct RecordArgs {
+    /// History database (SQLite)
+    #[arg(long, value_name = "FILE", default_value = DEFAULT_HISTORY_DB)]
+    pub db: PathBuf,
+
+    /// Commit SHA to record (detected with git if not specified)
+    #[arg(long, value_name = "SHA")]
+    pub commit: Option<String>,
+
+    /// Commit author credited with fixed findings (detected with git if not specified)
+    #[arg(long, value_name = "NAME")]
+    pub author: Option<String>,
+
+    /// Record policy violations under their rule ids instead of raw findings
+    #[arg(long, value_name = "FILE")]
+    pub policy: Option<PathBuf>,
+}
+
+#[derive(clap::Args, Debug)]
+pub struct TrendArgs {
+    /// History database (SQLite)
+    #[arg(long, value_name = "FILE", default_value = DEFAULT_HISTORY_DB)]
+    pub db: PathBuf,
+
+    /// Only report the most recent N scans
+    #[arg(long, value_name = "N")]
+    pub last: Option<usize>,
+
+    /// Number of leading path components that identify a team directory
+    #[arg(long, value_name = "N", default_value_t = 1)]
+    pub group_depth: usize,
+
+    /// Print the trend report as JSON instead of text
+    #[arg(long)]
+    pub json: bool,
+}
+
+#[derive(clap::Args, Debug)]
+pub struct ReproArgs {
+    /// Finding location as FILE:LINE or FILE:LINE:COLUMN (FILE relative to --path)
+    #[arg(value_name = "FINDING_ID")]
+    pub finding: String,
+
+    /// Directory to write the reproduction to (default: repro-<file>-<line>)
+    #[arg(short, long, value_name = "DIR")]
+    pub output: Option<PathBuf>,
+}
+
+#[derive(clap::Args, Debug)]
+pub struct AnnotateArgs {
+    /// Policy whose rule ids the suppressions name
+    #[arg(long, value_name = "FILE")]
+    pub policy: PathBuf,
+
+    /// Only annotate violations accepted in this baseline
+    #[arg(long, value_name = "FILE")]
+    pub baseline: Option<PathBuf>,
+
+    /// Only annotate violations of this rule (repeatable)
+    #[arg(long, value_name = "ID")]
+    pub rule: Vec<String>,
+
+    /// Reason written into each suppression
+    #[arg(long, default_value = crate::policy::PLACEHOLDER)]
+    pub reason: String,
+
+    /// Owner written into each suppression
+    #[arg(long, default_value = crate::policy::PLACEHOLDER)]
+    pub owner: String,
+
+    /// Ticket written into each suppression, e.g. SEC-123
+    #[arg(long, value_name = "ID")]
+    pub ticket: Option<String>,
+
+    /// Report what would be annotated without writing anything
+    #[arg(long)]
+    pub dry_run: bool,
+}
+
+#[derive(clap::Args, Debug)]
+pub struct InventoryArgs {
+    /// Print the inventory as JSON instead of text
+    #[arg(long)]
+    pub json: bool,
+}
+
+#[derive(clap::Args, Debug)]
+pub struct CoverageArgs {
+    /// Print the coverage report as JSON instead of text
+    #[arg(long)]
+    pub json: bool,
+}
+
+#[derive(clap::Args, Debug)]
+pub struct ExportArgs {
+    /// Import format: defectdojo (Generic Findings Import) or generic records
+    #[arg(long, value_name = "FORMAT", default_value = "defectdojo")]
+    pub format: ExportFormat,
+
+    /// Export violations of this policy instead of every finding
+    #[arg(long, value_name = "FILE")]
+    pub policy: Option<PathBuf>,
+
+    /// Baseline whose accepted violations are exported as accepted risks
+    #[arg(long, value_name = "FILE", requires = "policy")]
+    pub baseline: Option<PathBuf>,
+
+    /// Write the export to this file instead of stdout
+    #[arg(long, value_name = "FILE")]
+    pub output: Option<PathBuf>,
+}
+
+#[derive(clap::Args, Debug)]
+pub struct CompareArgs {
+    /// SARIF log of the other tool
+    #[arg(value_name = "SARIF")]
+    pub sarif: PathBuf,
+
+    /// Compare only the other tool's results of this rule, or of rules starting with a
+    /// prefix ending in `*`, e.g. `G4*` (repeatable)
+    #[arg(long = "rule", value_name = "RULE")]
+    pub rules: Vec<String>,
+
+    /// Print the comparison as JSON instead of text
+    #[arg(long)]
+    pub json: bool,
+}
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, ValueEnum)]
+pub enum ExportFormat {
+    /// DefectDojo Generic Findings Import JSON
+    Defectdojo,
+    /// argflow's vendor-neutral records, for importers with a custom mapping
+    Generic,
+}
+
+#[derive(clap::Args, Debug)]
+pub struct RulesArgs {
+    /// Output format for the rule catalog
+    #[arg(long, value_enum, default_value = "text")]
+    pub format: CatalogFormat,
+
+    /// Policy whose rules are listed after the sink rules
+    #[arg(long, value_name = "FILE")]
+    pub policy: Option<PathBuf>,
+}
+
+#[derive(clap::Args, Debug)]
+pub struct SimulateArgs {
+    /// Value to resolve a constant or package-level variable to, as PACKAGE.NAME=VALUE
+    /// with the package named by its directory (repeatable)
+    #[arg(long = "set", value_name = "PACKAGE.NAME=VALUE", required = true, value_parser = parse_assignment)]
+    pub set: Vec<(String, String)>,
+
+    /// Policy to evaluate with and without the new values
+    #[arg(long, value_name = "FILE")]
+    pub policy: Option<PathBuf>,
+
+    /// Print the simulation report as JSON instead of text
+    #[arg(long)]
+    pub json: bool,
+}
+
+#[derive(clap::Args, Debug)]
+pub struct DepDiffArgs {
+    /// Module path, e.g. golang.org/x/crypto
+    pub module: String,
+
+    /// Version to compare from
+    pub old: String,
+
+    /// Version to compare to
+    pub new: String,
+
+    /// Print the diff as JSON instead of text
+    #[arg(long)]
+    pub json: bool,
+}
+
+#[derive(clap::Args, Debug)]
+pub struct ArchitectureArgs {
+    /// Architecture spec (JSON or YAML)
+    #[arg(long, value_name = "FILE")]
+    pub spec: PathBuf,
+
+    /// Print the deviations as JSON instead of text
+    #[arg(long)]
+    pub json: bool,
+}
+
+#[derive(clap::Args, Debug)]
+pub struct SinksArgs {
+    #[command(subcommand)]
+    pub action: SinksCommand,
+}
+
+#[derive(Subcommand, Debug)]
+pub enum SinksCommand {
+    /// Parse the standard library and required module versions in use and report sinks
+    /// whose argument roles no longer fit their signatures. Exits non-zero on drift.
+    Verify(SinksVerifyArgs),
+}
+
+#[derive(clap::Args, Debug)]
+pub struct SinksVerifyArgs {
+    /// Print the report as JSON instead of text
+    #[arg(long)]
+    pub json: bool,
+}
+
+#[derive(clap::Args, Debug)]
+pub struct RuleArgs {
+    #[command(subcommand)]
+    pub action: RuleCommand,
+}
+
+#[derive(Subcommand, Debug)]
+pub enum RuleCommand {
+    /// Evaluate a CEL-style expression against every finding in a report and list the
+    /// findings it matches. Without --expr, reads one expression per line from stdin.
+    ///
+    /// `argflow rule test --expr 'primitive == "kdf" && parameters.iterations < 600000' --against findings.json`
+    Test(RuleTestArgs),
+}
+
+#[derive(clap::Args, Debug)]
+pub struct RuleTestArgs {
+    /// Expression to evaluate against each finding
+    #[arg(long, value_name = "EXPR")]
+    pub expr: Option<String>,
+
+    /// JSON report written with -O, optionally gzip-compressed
+    #[arg(long, value_name = "FILE")]
+    pub against: PathBuf,
+
+    /// Print matching findings as JSON instead of text
+    #[arg(long)]
+    pub json: bool,
+}
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, ValueEnum)]
+pub enum CatalogFormat {
+    Text,
+    Json,
+}
+
+#[derive(clap::Args, Debug)]
+pub struct ParamsArgs {
+    /// Compare with a committed manifest instead of printing one; exits non-zero when
+    /// parameters were added, removed, changed value or reach different calls
+    #[arg(long, value_name = "FILE")]
+    pub verify: Option<PathBuf>,
+
+    /// Output format for the manifest or the drift report; `html` renders a call-graph
+    /// drilldown per constant and does not apply to `--verify`
+    #[arg(long, value_enum, default_value = "yaml")]
+    pub format: ManifestFormat,
+}
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, ValueEnum)]
+pub enum ManifestFormat {
+    Yaml,
+    Json,
+    Html,
+}
+
+#[derive(clap::Args, Debug)]
+pub struct AggregateArgs {
+    /// Saved JSON reports (optionally .gz), one per repository
+    #[arg(value_name = "REPORT", required = true)]
+    pub reports: Vec<PathBuf>,
+
+    /// Privacy budget: smaller values add more noise to every count
+    #[arg(long, value_name = "EPSILON", default_value_t = crate::aggregate::DEFAULT_EPSILON, value_parser = parse_epsilon)]
+    pub epsilon: f64,
+
+    /// Withhold algorithms and parameter values whose noisy count is below N
+    #[arg(long, value_name = "N", default_value_t = crate::aggregate::DEFAULT_MIN_COUNT)]
+    pub min_count: u64,
+
+    /// Print the aggregate as JSON instead of text
+    #[arg(long)]
+    pub json: bool,
+}
+
+#[derive(clap::Args, Debug)]
+pub struct SchemaArgs {
+    /// Schema to print; lists the available schemas when omitted
+    #[arg(value_parser = crate::schema::SCHEMA_NAMES)]
+    pub name: Option<String>,
+}
+
+#[derive(clap::Args, Debug)]
+pub struct BaselineArgs {
+    #[command(subcommand)]
+    pub action: BaselineCommand,
+}
+
+#[derive(Subcommand, Debug)]
+pub enum BaselineCommand {
+    /// Scan, then rewrite a baseline and inline suppressions for renamed rules and the
+    /// current fingerprint algorithm.
+    Migrate(MigrateArgs),
+}
+
+#[derive(clap::Args, Debug)]
+pub struct MigrateArgs {
+    /// Baseline to migrate
+    #[arg(long, value_name = "FILE")]
+    pub baseline: PathBuf,
+
+    /// Policy with the current rule ids
+    #[arg(long, value_name = "FILE")]
+    pub policy: PathBuf,
+
+    /// Rule rename, as OLD=NEW (repeatable)
+    #[arg(long, value_name = "OLD=NEW", value_parser = parse_rename)]
+    pub rename: Vec<(String, String)>,
+
+    /// JSON or YAML map of old rule ids to new ones
+    #[arg(long, value_name = "FILE")]
+    pub renames: Option<PathBuf>,
+
+    /// Write the migrated baseline here instead of overwriting the original
+    #[arg(long, value_name = "FILE")]
+    pub output: Option<PathBuf>,
+
+    /// Report what would change without writing anything
+    #[arg(long)]
+    pub dry_run: bool,
+}
+
+impl MigrateArgs {
+    pub fn validate(&self) -> Result<()> {
+        for (what, path) in [("Baseline", &self.baseline), ("Policy file", &self.policy)] {
+            if !path.exists() {
+                anyhow::bail!("{what} does not exist: {}", path.display());
+            }
+        }
+        if let Some(ref renames) = self.renames {
+            if !renames.exists() {
+                anyhow::bail!("Renames file does not exist: {}", renames.display());
+            }
+        }
+        Ok(())
+    }
+}
+
+fn parse_equivalence(s: &str) -> Result<(String, String), String> {
+    parse_rename(s).map_err(|_| format!("expected FORK=UPSTREAM, got '{s}'"))
+}
+
+fn parse_assignment(s: &str) -> Result<(String, String), String> {
+    match parse_rename(s) {
+        Ok((name, value))
+            if name
+                .split_once('.')
+                .is_some_and(|(p, n)| !p.is_empty() && !n.is_empty()) =>
+        {
+            Ok((name, value))
+        }
+        _ => Err(format!("expected PACKAGE.NAME=VALUE, got '{s}'")),
+    }
+}
+
+fn parse_rename(s: &str) -> Result<(String, String), String> {
+    match s.split_once('=') {
+        Some((old, new)) if !old.trim().is_empty() && !new.trim().is_empty() => {
+            Ok((old.trim().to_string(), new.trim().to_string()))
+        }
+        _ => Err(format!("expected OLD=NEW, got '{s}'")),
+    }
+}
+
+impl GateArgs {
+    pub fn validate(&self) -> Result<()> {
+        if !self.policy.exists() {
+            anyhow::bail!("Policy file does not exist: {}", self.policy.display());
+        }
+        Ok(())
+    }
+}
+
+impl AnnotateArgs {
+    pub fn validate(&self) -> Result<()> {
+        if !self.policy.exists() {
+            anyhow::bail!("Policy file does not exist: {}", self.policy.display());
+        }
+        if let Some(ref baseline) = self.baseline {
+            if !baseline.exists() {
+                anyhow::bail!("Baseline does not exist: {}", baseline.display());
+            }
+        }
+        Ok(())
+    }
+}
+
 impl Args {
+    /// The path to scan; every command except `trend`, `schema`, `rule` and `aggregate`
+    /// needs one.
+    pub fn scan_path(&self) -> Result<&Path> {
+        self.path
+            .as_deref()
+            .or(self.target.as_deref())
+            .context("the following required argument was not provided: --path <PATH>")
+    }
+
     pub fn validate(&self) -> Result<()> {
-        validate_path(&self.path)?;
+        if let Some(
+            Command::Trend(_) | Command::Schema(_) | Command::Rule(_) | Command::Aggregate(_),
+        ) = &self.command
+        {
+            return Ok(());
+        }
+        if let Some(Command::DepDiff(_)) = &self.command {
+            if self.path.is_some() || self.target.is_some() {
+                anyhow::bail!("dep-diff scans the fetched module versions and takes no --path");
+            }
+        } else {
+            let path = self.scan_path()?;
+            if path.as_os_str() != crate::archive::STDIN_PATH {
+                validate_path(path)?;
+            }
+        }
+        if let Some(ref vulndb_path) = self.vulndb {
+            if !vulndb_path.exists() {
+                anyhow::bail!(
+                    "Vulnerability database does not exist: {}",
+                    vulndb_path.display()
+                );
+            }
+        }
+        if let Some(ref govulncheck_path) = self.govulncheck_json {
+            if !govulncheck_path.exists() {
+                anyhow::bail!(
+                    "govulncheck output does not exist: {}",
+                    govulncheck_path.display()
+                );
+            }
+        }
+        if let Some(ref key_path) = self.sign {
+            if self.output_file.is_none() {
+                anyhow::bail!(
+                    "--sign requires --output-file; the attestation covers the written report"
+                );
+            }
+            if !key_path.exists() {
+                anyhow::bail!("Signing key does not exist: {}", key_path.display());
+            }
+        }
+        if let Some(ref notify_path) = self.notify {
+            if !notify_path.exists() {
+                anyhow::bail!(
+                    "Notification config does not exist: {}",
+                    notify_path.display()
+                );
+            }
+        }
         if let Some(ref rules_path) = self.rules {
             if !rules_path.exists() {
                 anyhow::bail!("Rules file does not exist: {}", rules_path.display());
             }
         }
+        if self.mode == ScanMode::Inventory {
+            self.validate_inventory()?;
+        }
+        match &self.command {
+            Some(Command::Gate(gate)) => gate.validate()?,
+            Some(Command::Annotate(annotate)) => annotate.validate()?,
+            Some(Command::Baseline(BaselineArgs {
+                action: BaselineCommand::Migrate(migrate),
+            })) => migrate.validate()?,
+            Some(
+                Command::Record(RecordArgs {
+                    policy: Some(policy),
+                    ..
+                })
+                | Command::Simulate(SimulateArgs {
+                    policy: Some(policy),
+                    ..
+                })
+                | Command::Export(ExportArgs {
+                    policy: Some(policy),
+                    ..
+                }),
+            ) if !policy.exists() => {
+                anyhow::bail!("Policy file does not exist: {}", policy.display());
+            }
+            Some(Command::Compare(compare)) if !compare.sarif.exists() => {
+                anyhow::bail!("SARIF file does not exist: {}", compare.sarif.display());
+            }
+            Some(Command::Architecture(architecture)) if !architecture.spec.exists() => {
+                anyhow::bail!(
+                    "Architecture spec does not exist: {}",
+                    architecture.spec.display()
+                );
+            }
+            _ => {}
+        }
         Ok(())
     }
+
+    /// Inventory mode only catalogs usage, so anything that judges findings is rejected
+    /// rather than silently dropped.
+    fn validate_inventory(&self) -> Result<()> {
+        let rules = match &self.command {
+            Some(Command::Gate(_)) => Some("gate"),
+            Some(Command::Annotate(_)) => Some("annotate"),
+            Some(Command::Baseline(_)) => Some("baseline"),
+            Some(Command::Record(RecordArgs {
+                policy: Some(_), ..
+            })) => Some("record --policy"),
+            Some(Command::Simulate(SimulateArgs {
+                policy: Some(_), ..
+            })) => Some("simulate --policy"),
+            Some(Command::Export(ExportArgs {
+                policy: Some(_), ..
+            })) => Some("export --policy"),
+            _ if self.vulndb.is_some() => Some("--vulndb"),
+            _ if self.govulncheck || self.govulncheck_json.is_some() => Some("--govulncheck"),
+            _ if self.fips => Some("--fips"),
+            _ => None,
+        };
+        match rules {
+            Some(what) => anyhow::bail!(
+                "{what} evaluates rules and cannot run with --mode inventory; use --mode enforce"
+            ),
+            None => Ok(()),
+        }
+    }
 }
 
 pub fn detect_language(file_path: &Path) -> Option<Language> {
@@ -81,6 +887,22 @@ pub fn detect_language(file_path: &Path) -> Option<Language> {
     })
 }
 
+fn parse_epsilon(value: &str) -> Result<f64, String> {
+    match value.parse::<f64>() {
+        Ok(epsilon) if epsilon.is_finite() && epsilon > 0.0 => Ok(epsilon),
+        _ => Err(format!("expected a positive number, got '{value}'")),
+    }
+}
+
+fn parse_severity(value: &str) -> Result<Severity, String> {
+    serde_json::from_value(serde_json::Value::String(value.to_string()))
+        .map_err(|_| format!("expected info, warning or error, got '{value}'"))
+}
+
+fn parse_go_version(value: &str) -> Result<GoVersion, String> {
+    GoVersion::parse(value).ok_or_else(|| format!("invalid Go version: {value}"))
+}
+
 pub fn validate_path(path: &Path) -> Result<()> {
     if !path.exists() {
         anyhow::bail!("Path does not exist: {}", path.display());
@@ -130,6 +952,25 @@ impl Language {
     }
 }
 
+impl WrapperAttribution {
+    pub fn as_str(&self) -> &'static str {
+        match self {
+            WrapperAttribution::DefinitionSite => "definition-site",
+            WrapperAttribution::CallSite => "call-site",
+            WrapperAttribution::BothLinked => "both-linked",
+        }
+    }
+}
+
+impl CompatMode {
+    pub fn as_str(&self) -> &'static str {
+        match self {
+            CompatMode::Gopath => "gopath",
+            CompatMode::Bazel => "bazel",
+        }
+    }
+}
+
 impl OutputFormat {
     pub fn as_str(&self) -> &'static str {
         match self {
@@ -220,6 +1061,369 @@ mod tests {
         assert_eq!(OutputFormat::Cbom.as_str(), "cbom");
     }
 
+    #[test]
+    fn test_parse_gate_subcommand() {
+        let args = Args::try_parse_from([
+            "argflow",
+            "--path",
+            ".",
+            "--preset",
+            "crypto",
+            "gate",
+            "--policy",
+            "policy.yaml",
+            "--baseline",
+            "baseline.json",
+            "--diff-base",
+            "origin/main",
+        ])
+        .unwrap();
+
+        let Some(Command::Gate(gate)) = args.command else {
+            panic!("expected gate subcommand");
+        };
+        assert_eq!(gate.policy, PathBuf::from("policy.yaml"));
+        assert_eq!(gate.diff_base.as_deref(), Some("origin/main"));
+        assert!(!gate.update_baseline);
+    }
+
+    #[test]
+    fn test_parse_annotate_subcommand() {
+        let args = Args::try_parse_from([
+            "argflow",
+            "--path",
+            ".",
+            "annotate",
+            "--policy",
+            "policy.yaml",
+            "--rule",
+            "no-md5",
+            "--owner",
+            "@crypto",
+        ])
+        .unwrap();
+
+        let Some(Command::Annotate(annotate)) = args.command else {
+            panic!("expected annotate subcommand");
+        };
+        assert_eq!(annotate.rule, vec!["no-md5".to_string()]);
+        assert_eq!(annotate.owner, "@crypto");
+        assert_eq!(annotate.reason, crate::policy::PLACEHOLDER);
+        assert!(annotate.baseline.is_none());
+    }
+
+    #[test]
+    fn test_update_baseline_requires_baseline() {
+        let result = Args::try_parse_from([
+            "argflow",
+            "--path",
+            ".",
+            "gate",
+            "--policy",
+            "policy.yaml",
+            "--update-baseline",
+        ]);
+        assert!(result.is_err());
+    }
+
+    #[test]
+    fn test_parse_simulate_assignments() {
+        let args = Args::try_parse_from([
+            "argflow",
+            "--path",
+            ".",
+            "simulate",
+            "--set",
+            "config.PBKDF2Iterations=600000",
+            "--set",
+            "kdf.Hash=SHA-512",
+        ])
+        .unwrap();
+        let Some(Command::Simulate(simulate)) = args.command else {
+            panic!("expected simulate subcommand");
+        };
+        assert_eq!(
+            simulate.set,
+            vec![
+                ("config.PBKDF2Iterations".to_string(), "600000".to_string()),
+                ("kdf.Hash".to_string(), "SHA-512".to_string()),
+            ]
+        );
+
+        // The package must be named, and at least one value given
+        for set in [
+            "PBKDF2Iterations=600000",
+            ".Iterations=1",
+            "config.Iterations",
+        ] {
+            let result = Args::try_parse_from(["argflow", "--path", ".", "simulate", "--set", set]);
+            assert!(result.is_err(), "{set}");
+        }
+        assert!(Args::try_parse_from(["argflow", "--path", ".", "simulate"]).is_err());
+    }
+
+    #[test]
+    fn test_dep_diff_takes_no_path() {
+        let args = Args::try_parse_from([
+            "argflow",
+            "--preset",
+            "crypto",
+            "dep-diff",
+            "golang.org/x/crypto",
+            "v0.20.0",
+            "v0.27.0",
+        ])
+        .unwrap();
+        args.validate().unwrap();
+        let Some(Command::DepDiff(dep_diff)) = &args.command else {
+            panic!("expected dep-diff subcommand");
+        };
+        assert_eq!(dep_diff.module, "golang.org/x/crypto");
+        assert_eq!(
+            (dep_diff.old.as_str(), dep_diff.new.as_str()),
+            ("v0.20.0", "v0.27.0")
+        );
+
+        let args = Args::try_parse_from([
+            "argflow",
+            "--path",
+            ".",
+            "dep-diff",
+            "golang.org/x/crypto",
+            "v0.20.0",
+            "v0.27.0",
+        ])
+        .unwrap();
+        assert!(args.validate().is_err());
+    }
+
+    #[test]
+    fn test_architecture_spec_must_exist() {
+        let args = Args::try_parse_from([
+            "argflow",
+            "--path",
+            ".",
+            "architecture",
+            "--spec",
+            "missing-architecture.yaml",
+            "--json",
+        ])
+        .unwrap();
+        let Some(Command::Architecture(architecture)) = &args.command else {
+            panic!("expected architecture subcommand");
+        };
+        assert!(architecture.json);
+        assert!(args.validate().is_err());
+        assert!(Args::try_parse_from(["argflow", "--path", ".", "architecture"]).is_err());
+    }
+
+    #[test]
+    fn test_params_subcommand() {
+        let args = Args::try_parse_from([
+            "argflow",
+            "--path",
+            ".",
+            "params",
+            "--verify",
+            "argflow.params.yaml",
+        ])
+        .unwrap();
+        let Some(Command::Params(params)) = &args.command else {
+            panic!("expected params subcommand");
+        };
+        assert_eq!(
+            params.verify.as_deref(),
+            Some(Path::new("argflow.params.yaml"))
+        );
+        assert_eq!(params.format, ManifestFormat::Yaml);
+    }
+
+    #[test]
+    fn test_aggregate_takes_reports_and_positive_epsilon() {
+        let args = Args::try_parse_from([
+            "argflow",
+            "aggregate",
+            "api.json",
+            "web.json.gz",
+            "--epsilon",
+            "0.5",
+        ])
+        .unwrap();
+        assert!(args.validate().is_ok());
+        let Some(Command::Aggregate(aggregate)) = &args.command else {
+            panic!("expected aggregate subcommand");
+        };
+        assert_eq!(aggregate.reports.len(), 2);
+        assert_eq!(aggregate.epsilon, 0.5);
+        assert_eq!(aggregate.min_count, crate::aggregate::DEFAULT_MIN_COUNT);
+
+        assert!(Args::try_parse_from(["argflow", "aggregate"]).is_err());
+        assert!(
+            Args::try_parse_from(["argflow", "aggregate", "a.json", "--epsilon", "0"]).is_err()
+        );
+    }
+
+    #[test]
+    fn test_sinks_verify_subcommand() {
+        let args = Args::try_parse_from([
+            "argflow", "--path", ".", "--preset", "crypto", "sinks", "verify", "--json",
+        ])
+        .unwrap();
+        let Some(Command::Sinks(SinksArgs {
+            action: SinksCommand::Verify(verify),
+        })) = &args.command
+        else {
+            panic!("expected sinks verify subcommand");
+        };
+        assert!(verify.json);
+        assert!(Args::try_parse_from(["argflow", "--path", ".", "sinks"]).is_err());
+    }
+
+    #[test]
+    fn test_rule_test_does_not_need_path() {
+        let args = Args::try_parse_from([
+            "argflow",
+            "rule",
+            "test",
+            "--expr",
+            "algorithm == 'MD5'",
+            "--against",
+            "findings.json.gz",
+        ])
+        .unwrap();
+        assert!(args.validate().is_ok());
+        let Some(Command::Rule(RuleArgs {
+            action: RuleCommand::Test(test),
+        })) = &args.command
+        else {
+            panic!("expected rule test subcommand");
+        };
+        assert_eq!(test.expr.as_deref(), Some("algorithm == 'MD5'"));
+        assert!(Args::try_parse_from(["argflow", "rule", "test"]).is_err());
+    }
+
+    #[test]
+    fn test_trend_does_not_need_path() {
+        let args = Args::try_parse_from(["argflow", "trend", "--last", "10"]).unwrap();
+        assert!(args.validate().is_ok());
+        let Some(Command::Trend(trend)) = args.command else {
+            panic!("expected trend subcommand");
+        };
+        assert_eq!(trend.db, PathBuf::from(DEFAULT_HISTORY_DB));
+        assert_eq!(trend.last, Some(10));
+    }
+
+    #[test]
+    fn test_offline_conflicts_with_network_flags() {
+        let args = Args::try_parse_from(["argflow", "--path", ".", "--offline"]).unwrap();
+        assert!(args.offline);
+        for flag in [
+            &["--goproxy", "https://proxy.golang.org"][..],
+            &["--govulncheck"],
+        ] {
+            let mut argv = vec!["argflow", "--path", ".", "--offline"];
+            argv.extend_from_slice(flag);
+            assert!(Args::try_parse_from(argv).is_err());
+        }
+    }
+
+    #[test]
+    fn test_recurse_modules_conflicts_with_compat() {
+        let args = Args::try_parse_from(["argflow", "--path", ".", "--recurse-modules"]).unwrap();
+        assert!(args.recurse_modules);
+        assert!(Args::try_parse_from([
+            "argflow",
+            "--path",
+            ".",
+            "--recurse-modules",
+            "--compat",
+            "gopath"
+        ])
+        .is_err());
+    }
+
+    #[test]
+    fn test_scan_requires_path() {
+        let args = Args::try_parse_from(["argflow", "--preset", "crypto"]).unwrap();
+        assert!(args.validate().is_err());
+    }
+
+    #[test]
+    fn test_stdin_archive_path_is_valid() {
+        let args = Args::try_parse_from(["argflow", "--path", "-", "--language", "go"]).unwrap();
+        assert!(args.validate().is_ok());
+    }
+
+    #[test]
+    fn test_schema_does_not_require_path() {
+        let args = Args::try_parse_from(["argflow", "schema", "findings"]).unwrap();
+        assert!(args.validate().is_ok());
+        assert!(Args::try_parse_from(["argflow", "schema", "bogus"]).is_err());
+    }
+
+    #[test]
+    fn test_sign_requires_output_file() {
+        let temp_dir = TempDir::new().unwrap();
+        let key = temp_dir.path().join("argflow.key");
+        fs::write(&key, "").unwrap();
+        let path = temp_dir.path().to_str().unwrap();
+        let key = key.to_str().unwrap();
+
+        let args = Args::try_parse_from(["argflow", "--path", path, "--sign", key]).unwrap();
+        assert!(args.validate().is_err());
+
+        let args = Args::try_parse_from([
+            "argflow",
+            "--path",
+            path,
+            "--sign",
+            key,
+            "-O",
+            "report.json",
+        ])
+        .unwrap();
+        assert!(args.validate().is_ok());
+    }
+
+    #[test]
+    fn test_inventory_mode_rejects_rules() {
+        let temp_dir = TempDir::new().unwrap();
+        let policy = temp_dir.path().join("policy.yaml");
+        fs::write(&policy, "rules: []").unwrap();
+        let path = temp_dir.path().to_str().unwrap();
+        let policy = policy.to_str().unwrap();
+
+        let args =
+            Args::try_parse_from(["argflow", "--path", path, "--mode", "inventory"]).unwrap();
+        assert_eq!(args.mode, ScanMode::Inventory);
+        assert!(args.validate().is_ok());
+
+        let args = Args::try_parse_from([
+            "argflow",
+            "--path",
+            path,
+            "--mode",
+            "inventory",
+            "gate",
+            "--policy",
+            policy,
+        ])
+        .unwrap();
+        let err = args.validate().unwrap_err();
+        assert!(err.to_string().contains("gate evaluates rules"));
+
+        let args =
+            Args::try_parse_from(["argflow", "--path", path, "--mode", "inventory", "--fips"])
+                .unwrap();
+        assert!(args.validate().is_err());
+    }
+
+    #[test]
+    fn test_parse_go_version_arg() {
+        assert_eq!(parse_go_version("1.24"), Ok(GoVersion::new(1, 24, 0)));
+        assert!(parse_go_version("not-a-version").is_err());
+    }
+
     #[test]
     fn test_validate_path_file_exists() {
         let temp_dir = TempDir::new().unwrap();
@@ -248,13 +1452,39 @@ mod tests {
         fs::write(&file_path, "package main").unwrap();
 
         let args = Args {
-            path: file_path,
+            command: None,
+            path: Some(file_path),
+            target: None,
             preset: vec![],
             rules: None,
             output_file: None,
             format: OutputFormat::Json,
             language: Some(Language::Go),
             include_deps: false,
+            overlay: None,
+            import_equivalence: vec![],
+            max_derivation_depth: DEFAULT_MAX_DERIVATION_DEPTH,
+            offline: false,
+            goproxy: None,
+            go_version: None,
+            vulndb: None,
+            govulncheck: false,
+            govulncheck_json: None,
+            fips: false,
+            go_generate: false,
+            go_generate_run: None,
+            generators: false,
+            blame: false,
+            show_secrets: false,
+            sign: None,
+            attestation: None,
+            notify: None,
+            otlp_endpoint: None,
+            compat: None,
+            recurse_modules: false,
+            golangci_config: None,
+            mode: ScanMode::Enforce,
+            wrapper_attribution: WrapperAttribution::BothLinked,
             verbose: 0,
             quiet: false,
         };
@@ -262,6 +1492,22 @@ mod tests {
         assert!(args.validate().is_ok());
     }
 
+    #[test]
+    fn test_args_validate_positional_target() {
+        let temp_dir = TempDir::new().unwrap();
+        let dir = temp_dir.path().to_str().unwrap();
+
+        let args = Args::try_parse_from(["argflow", dir]).unwrap();
+        assert!(args.path.is_none());
+        assert_eq!(args.scan_path().unwrap(), temp_dir.path());
+        assert!(args.validate().is_ok());
+        assert!(crate::quickstart::applies(&args));
+
+        let missing =
+            Args::try_parse_from(["argflow", "/nonexistent/path/that/does/not/exist"]).unwrap();
+        assert!(missing.validate().is_err());
+    }
+
     #[test]
     fn test_args_validate_with_preset() {
         let temp_dir = TempDir::new().unwrap();
@@ -269,13 +1515,39 @@ mod tests {
         fs::write(&file_path, "package main").unwrap();
 
         let args = Args {
-            path: file_path,
+            command: None,
+            path: Some(file_path),
+            target: None,
             preset: vec!["crypto".to_string()],
             rules: None,
             output_file: None,
             format: OutputFormat::Json,
             language: Some(Language::Go),
             include_deps: false,
+            overlay: None,
+            import_equivalence: vec![],
+            max_derivation_depth: DEFAULT_MAX_DERIVATION_DEPTH,
+            offline: false,
+            goproxy: None,
+            go_version: None,
+            vulndb: None,
+            govulncheck: false,
+            govulncheck_json: None,
+            fips: false,
+            go_generate: false,
+            go_generate_run: None,
+            generators: false,
+            blame: false,
+            show_secrets: false,
+            sign: None,
+            attestation: None,
+            notify: None,
+            otlp_endpoint: None,
+            compat: None,
+            recurse_modules: false,
+            golangci_config: None,
+            mode: ScanMode::Enforce,
+            wrapper_attribution: WrapperAttribution::BothLinked,
             verbose: 0,
             quiet: false,
         };
@@ -286,13 +1558,39 @@ mod tests {
     #[test]
     fn test_args_validate_invalid_path() {
         let args = Args {
-            path: PathBuf::from("/nonexistent/path"),
+            command: None,
+            path: Some(PathBuf::from("/nonexistent/path")),
+            target: None,
             preset: vec![],
             rules: None,
             output_file: None,
             format: OutputFormat::Json,
             language: None,
             include_deps: false,
+            overlay: None,
+            import_equivalence: vec![],
+            max_derivation_depth: DEFAULT_MAX_DERIVATION_DEPTH,
+            offline: false,
+            goproxy: None,
+            go_version: None,
+            vulndb: None,
+            govulncheck: false,
+            govulncheck_json: None,
+            fips: false,
+            go_generate: false,
+            go_generate_run: None,
+            generators: false,
+            blame: false,
+            show_secrets: false,
+            sign: None,
+            attestation: None,
+            notify: None,
+            otlp_endpoint: None,
+            compat: None,
+            recurse_modules: false,
+            golangci_config: None,
+            mode: ScanMode::Enforce,
+            wrapper_attribution: WrapperAttribution::BothLinked,
             verbose: 0,
             quiet: false,
         };
@@ -303,13 +1601,39 @@ mod tests {
     #[test]
     fn test_verbose_flag_incremental() {
         let args = Args {
-            path: PathBuf::from("."),
+            command: None,
+            path: Some(PathBuf::from(".")),
+            target: None,
             preset: vec![],
             rules: None,
             output_file: None,
             format: OutputFormat::Json,
             language: None,
             include_deps: false,
+            overlay: None,
+            import_equivalence: vec![],
+            max_derivation_depth: DEFAULT_MAX_DERIVATION_DEPTH,
+            offline: false,
+            goproxy: None,
+            go_version: None,
+            vulndb: None,
+            govulncheck: false,
+            govulncheck_json: None,
+            fips: false,
+            go_generate: false,
+            go_generate_run: None,
+            generators: false,
+            blame: false,
+            show_secrets: false,
+            sign: None,
+            attestation: None,
+            notify: None,
+            otlp_endpoint: None,
+            compat: None,
+            recurse_modules: false,
+            golangci_config: None,
+            mode: ScanMode::Enforce,
+            wrapper_attribution: WrapperAttribution::BothLinked,
             verbose: 2,
             quiet: false,
         };
@@ -0,0 +1,29 @@
+//! Merges saved reports into organization-wide distributions.
+
+use anyhow::{Context as AnyhowContext, Result};
+use argflow::aggregate::{AggregateReport, Noise};
+use argflow::cli;
+use argflow::output::read_report_file;
+use tracing::info;
+
+/// Merges saved reports into noisy organization-wide distributions.
+pub fn run(args: &cli::AggregateArgs) -> Result<()> {
+    let reports = args
+        .reports
+        .iter()
+        .map(|path| read_report_file(path))
+        .collect::<Result<Vec<_>>>()?;
+    let mut noise = Noise::from_os().context("Failed to read the OS random source")?;
+    let aggregate = AggregateReport::build(&reports, args.epsilon, args.min_count, &mut noise);
+    info!(
+        reports = aggregate.reports,
+        algorithms = aggregate.algorithms.len(),
+        "aggregated reports"
+    );
+    if args.json {
+        println!("{}", serde_json::to_string_pretty(&aggregate)?);
+    } else {
+        print!("{}", aggregate.render_text());
+    }
+    Ok(())
+}
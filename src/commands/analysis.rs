@@ -0,0 +1,450 @@
+//! Scans files and directories into a report.
+
+use anyhow::{Context as AnyhowContext, Result};
+use argflow::classifier::RulesClassifier;
+use argflow::cli::{self, OutputFormat};
+use argflow::discovery::cache::DiscoveryCache;
+use argflow::discovery::filter::ImportFileFilter;
+use argflow::discovery::languages::go::{
+    gomod, gopath, DriverPackageLoader, GoEnv, GoImportFilter, GoMod, GoPackageLoader, GoVersion,
+    GoWorkspace, GopathPackageLoader, ModuleAttributor, PackagesDriver,
+};
+use argflow::discovery::languages::javascript::{JavaScriptImportFilter, JavaScriptPackageLoader};
+use argflow::discovery::languages::python::{PythonImportFilter, PythonPackageLoader};
+use argflow::discovery::languages::rust::{RustImportFilter, RustPackageLoader};
+use argflow::discovery::loader::PackageLoader;
+use argflow::discovery::{Overlay, SourceFile};
+use argflow::engine::{index_file, FileCache, ImportEquivalences};
+use argflow::output::{
+    summarize_packages, FileFailure, JsonOutput, ModuleScan, OutputFormatter, PackageStatus,
+};
+use argflow::scanner::{ScanResult, Scanner};
+use argflow::telemetry::Telemetry;
+use std::cell::RefCell;
+use std::collections::HashSet;
+use std::path::{Path, PathBuf};
+use std::rc::Rc;
+use tracing::{debug, info, trace, warn};
+
+pub struct ScanContext<'a> {
+    pub scanner: &'a Scanner,
+    pub classifier: &'a RulesClassifier,
+    pub output_format: OutputFormat,
+    pub output_file: Option<&'a PathBuf>,
+    pub preset_paths: &'a [PathBuf],
+    pub go_version: Option<GoVersion>,
+    pub compat: Option<cli::CompatMode>,
+    pub recurse_modules: bool,
+    pub go_env: &'a GoEnv,
+    pub import_equivalences: &'a ImportEquivalences,
+    pub overlay: &'a Overlay,
+    pub telemetry: &'a Telemetry,
+}
+
+type ScanOutput = (Vec<ScanResult>, Vec<PackageStatus>);
+
+/// Scans `path` and builds the report, with wrapper findings attributed.
+pub fn scan_report(
+    path: &Path,
+    language: cli::Language,
+    ctx: &ScanContext,
+    args: &cli::Args,
+) -> Result<JsonOutput> {
+    let (results, packages) = ctx.telemetry.phase("scan", || {
+        if path.is_dir() {
+            scan_directory(path, language, ctx, args.include_deps)
+        } else {
+            scan_file(path, language, ctx)
+        }
+    })?;
+    let mut report = build_report(&results, packages, ctx);
+    report.attribute_wrappers(args.wrapper_attribution);
+    if ctx.recurse_modules {
+        let modules: Vec<_> = gomod::find_modules(path)
+            .into_iter()
+            .map(|root| {
+                let module = GoMod::from_file(&root.join("go.mod"))
+                    .ok()
+                    .and_then(|go_mod| go_mod.module);
+                (root, module)
+            })
+            .collect();
+        report.modules = ModuleScan::summarize(path, &modules, &report.findings);
+    }
+    Ok(report)
+}
+
+fn scan_file(path: &Path, language: cli::Language, ctx: &ScanContext) -> Result<ScanOutput> {
+    debug!(file = %path.display(), "scanning file");
+
+    let source = ctx
+        .overlay
+        .read_to_string(path)
+        .context("Failed to read file")?;
+    trace!(bytes = source.len(), "read source file");
+
+    let tree = parse_source(&source, language)?;
+    trace!("parsed source into AST");
+
+    let result = ctx.scanner.scan_tree(
+        &tree,
+        source.as_bytes(),
+        &path.to_string_lossy(),
+        language.as_str(),
+    );
+
+    info!(calls = result.call_count(), "scan complete");
+
+    let packages = summarize_packages(std::slice::from_ref(&result), &[]);
+    Ok((vec![result], packages))
+}
+
+fn scan_directory(
+    path: &Path,
+    language: cli::Language,
+    ctx: &ScanContext,
+    include_deps: bool,
+) -> Result<ScanOutput> {
+    debug!(directory = %path.display(), include_deps, "scanning directory");
+
+    if ctx.preset_paths.is_empty() {
+        anyhow::bail!(
+            "Directory scanning requires a preset for import filtering. \
+             Use --preset <name> (e.g., --preset crypto). \
+             For custom rules, scan individual files instead."
+        );
+    }
+
+    match language {
+        cli::Language::Go => {
+            let filter = GoImportFilter::new(ctx.preset_paths)
+                .context("Failed to create Go import filter")?
+                .with_forks(ctx.import_equivalences.forks());
+            match ctx.compat {
+                Some(cli::CompatMode::Gopath) => {
+                    let gopath = gopath::find_gopath(path).context(
+                        "Could not locate a GOPATH workspace. Place the project under $GOPATH/src or set GOPATH",
+                    )?;
+                    info!(gopath = %gopath.display(), "loading project in GOPATH mode");
+                    let workspace = GoWorkspace::gopath(gopath.clone());
+                    let loader = GopathPackageLoader::new(gopath).with_env(ctx.go_env.clone());
+                    scan_with_loader_and_filter(
+                        path,
+                        language,
+                        ctx,
+                        include_deps,
+                        &loader,
+                        &filter,
+                        Some(&workspace),
+                    )
+                }
+                Some(cli::CompatMode::Bazel) => {
+                    let driver = PackagesDriver::from_env().context(
+                        "No packages driver configured. Set GOPACKAGESDRIVER to the build system's gopackagesdriver",
+                    )?;
+                    info!(driver = %driver.program().display(), "loading packages from driver");
+                    let loader = DriverPackageLoader::load(&driver, path)
+                        .context("Failed to load packages from the packages driver")?;
+                    let workspace = loader.workspace();
+                    scan_with_loader_and_filter(
+                        path,
+                        language,
+                        ctx,
+                        include_deps,
+                        &loader,
+                        &filter,
+                        Some(&workspace),
+                    )
+                }
+                None if ctx.recurse_modules => {
+                    scan_modules(path, language, ctx, include_deps, &filter)
+                }
+                None => {
+                    let workspace = GoWorkspace::module(path);
+                    scan_with_loader_and_filter(
+                        path,
+                        language,
+                        ctx,
+                        include_deps,
+                        &GoPackageLoader::new(ctx.go_env.clone()),
+                        &filter,
+                        workspace.as_ref(),
+                    )
+                }
+            }
+        }
+        cli::Language::Python => {
+            let loader = PythonPackageLoader;
+            let filter = PythonImportFilter::new(ctx.preset_paths)
+                .context("Failed to create Python import filter")?;
+            scan_with_loader_and_filter(path, language, ctx, include_deps, &loader, &filter, None)
+        }
+        cli::Language::Javascript | cli::Language::Typescript => {
+            let loader = JavaScriptPackageLoader;
+            let filter = JavaScriptImportFilter::new(ctx.preset_paths)
+                .context("Failed to create JavaScript import filter")?;
+            scan_with_loader_and_filter(path, language, ctx, include_deps, &loader, &filter, None)
+        }
+        cli::Language::Rust => {
+            let loader = RustPackageLoader;
+            let filter = RustImportFilter::new(ctx.preset_paths)
+                .context("Failed to create Rust import filter")?;
+            scan_with_loader_and_filter(path, language, ctx, include_deps, &loader, &filter, None)
+        }
+    }
+}
+
+/// Scans each Go module at or below `path` from its own root, leaving out the modules
+/// nested below it, as the go command does.
+fn scan_modules(
+    path: &Path,
+    language: cli::Language,
+    ctx: &ScanContext,
+    include_deps: bool,
+    filter: &dyn ImportFileFilter,
+) -> Result<ScanOutput> {
+    let roots = gomod::find_modules(path);
+    if roots.is_empty() {
+        anyhow::bail!("No go.mod found under {}", path.display());
+    }
+    info!(modules = roots.len(), "scanning nested Go modules");
+
+    let loader = GoPackageLoader::new(ctx.go_env.clone()).within_module();
+    let mut results = Vec::new();
+    let mut packages = Vec::new();
+    for root in &roots {
+        let workspace = GoWorkspace::module(root);
+        let (module_results, module_packages) = scan_with_loader_and_filter(
+            root,
+            language,
+            ctx,
+            include_deps,
+            &loader,
+            filter,
+            workspace.as_ref(),
+        )
+        .with_context(|| format!("Failed to scan module {}", root.display()))?;
+        debug!(module = %root.display(), files = module_results.len(), "scanned module");
+        results.extend(module_results);
+        packages.extend(module_packages);
+    }
+    Ok((results, packages))
+}
+
+fn scan_with_loader_and_filter(
+    path: &Path,
+    language: cli::Language,
+    ctx: &ScanContext,
+    include_deps: bool,
+    loader: &dyn PackageLoader,
+    filter: &dyn ImportFileFilter,
+    go_workspace: Option<&GoWorkspace>,
+) -> Result<ScanOutput> {
+    let mut cache = DiscoveryCache::default();
+
+    let mut all_files = ctx.telemetry.phase("discover", || -> Result<_> {
+        // Discover user code files
+        info!("discovering user code files");
+        let mut all_files = loader
+            .load_user_code(path)
+            .context("Failed to discover user code files")?;
+        info!(count = all_files.len(), "found user code files");
+
+        // Optionally include dependency files
+        if include_deps {
+            info!("discovering dependency files");
+            match loader.load_dependencies(path, &mut cache) {
+                Ok(dep_files) => {
+                    info!(count = dep_files.len(), "found dependency files");
+                    all_files.extend(dep_files);
+                }
+                Err(e) => {
+                    warn!(error = %e, "failed to load dependencies, continuing with user code only");
+                }
+            }
+        }
+        Ok(all_files)
+    })?;
+
+    let (hits, misses) = cache.dependency_stats();
+    ctx.telemetry.hit_rate(
+        "argflow.cache.hit_rate",
+        "Share of dependency lookups answered by the discovery cache",
+        hits,
+        misses,
+    );
+
+    if language == cli::Language::Go {
+        ctx.overlay.apply(path, language, "go", &mut all_files);
+    }
+    info!(total = all_files.len(), "total files to scan");
+
+    // Constants are often declared in files that never import a sink package, so the
+    // index covers every discovered file, not just the ones that pass the import filter.
+    let file_cache = (language == cli::Language::Go).then(|| {
+        let mut cache = ctx.telemetry.phase("index", || {
+            build_go_index(&all_files, go_workspace, ctx.overlay)
+        });
+        cache.set_import_equivalences(ctx.import_equivalences.clone());
+        debug!(
+            files = cache.file_count(),
+            "indexed Go files for cross-package constants"
+        );
+        Rc::new(RefCell::new(cache))
+    });
+
+    let attributor = (language == cli::Language::Go).then(|| ModuleAttributor::new(go_workspace));
+
+    info!("filtering for matching imports");
+    let matched_files: Vec<_> = ctx.telemetry.phase("filter", || {
+        all_files
+            .into_iter()
+            .filter_map(|file| {
+                filter
+                    .has_matching_imports(&ctx.overlay.source_path(&file.path)?)
+                    .ok()
+                    .and_then(|has_match| has_match.then_some(file))
+            })
+            .collect()
+    });
+    info!(
+        count = matched_files.len(),
+        "found files with matching imports"
+    );
+
+    // Every file is scanned on its own, so a broken file never aborts the run. Files with
+    // syntax errors are still matched best-effort and reported via per-package status.
+    let (scanned, failures) = ctx.telemetry.phase("match", || {
+        let mut scanned = Vec::new();
+        let mut failures = Vec::new();
+        for file in &matched_files {
+            trace!(file = %file.path.display(), "scanning file");
+            let file_path = file.path.to_string_lossy();
+            let source = match ctx.overlay.read_to_string(&file.path) {
+                Ok(source) => source,
+                Err(e) => {
+                    warn!(file = %file.path.display(), error = %e, "failed to read file");
+                    failures.push(FileFailure::new(file_path, e.to_string()));
+                    continue;
+                }
+            };
+            let tree = match parse_source(&source, language) {
+                Ok(tree) => tree,
+                Err(e) => {
+                    warn!(file = %file.path.display(), error = %e, "failed to parse file");
+                    failures.push(FileFailure::new(file_path, e.to_string()));
+                    continue;
+                }
+            };
+
+            let mut result = match &file_cache {
+                Some(cache) => ctx.scanner.scan_tree_with_cache(
+                    &tree,
+                    source.as_bytes(),
+                    &file_path,
+                    language.as_str(),
+                    Rc::clone(cache),
+                ),
+                None => {
+                    ctx.scanner
+                        .scan_tree(&tree, source.as_bytes(), &file_path, language.as_str())
+                }
+            };
+            result.module = attributor
+                .as_ref()
+                .and_then(|attributor| attributor.module_for_file(&file.path));
+            if result.has_errors() {
+                debug!(
+                    file = %file.path.display(),
+                    errors = result.errors.len(),
+                    "file has syntax errors, results are best-effort"
+                );
+            }
+            if result.call_count() > 0 {
+                debug!(
+                    file = %file.path.display(),
+                    calls = result.call_count(),
+                    "found matching calls"
+                );
+            }
+            scanned.push(result);
+        }
+        (scanned, failures)
+    });
+
+    let packages = summarize_packages(&scanned, &failures);
+    let results: Vec<ScanResult> = scanned.into_iter().filter(|r| r.call_count() > 0).collect();
+
+    let total_calls: usize = results.iter().map(|r| r.call_count()).sum();
+    info!(
+        files = results.len(),
+        calls = total_calls,
+        failed = failures.len(),
+        "scan complete"
+    );
+
+    Ok((results, packages))
+}
+
+fn build_go_index(
+    files: &[SourceFile],
+    workspace: Option<&GoWorkspace>,
+    overlay: &Overlay,
+) -> FileCache {
+    let mut cache = FileCache::with_capacity(files.len().max(1));
+    let mut registered_dirs = HashSet::new();
+
+    for file in files {
+        let Ok(source) = overlay.read_to_string(&file.path) else {
+            continue;
+        };
+        let Ok(tree) = parse_source(&source, cli::Language::Go) else {
+            continue;
+        };
+        let file_path = file.path.to_string_lossy().to_string();
+        let entry = index_file(&tree, source.as_bytes(), &file_path, "go");
+        cache.add_file(file_path, entry);
+
+        if let (Some(workspace), Some(dir)) = (workspace, file.path.parent()) {
+            if registered_dirs.insert(dir.to_path_buf()) {
+                if let Some(import_path) = workspace.import_path_for_dir(dir) {
+                    trace!(import_path, dir = %dir.display(), "registered Go package");
+                    cache.register_package(import_path, dir.to_string_lossy().to_string());
+                }
+            }
+        }
+    }
+
+    cache
+}
+
+fn parse_source(source: &str, language: cli::Language) -> Result<tree_sitter::Tree> {
+    let mut parser = tree_sitter::Parser::new();
+
+    let ts_language = match language {
+        cli::Language::Go => tree_sitter_go::LANGUAGE.into(),
+        cli::Language::Python => tree_sitter_python::LANGUAGE.into(),
+        cli::Language::Rust => tree_sitter_rust::LANGUAGE.into(),
+        cli::Language::Javascript => tree_sitter_javascript::LANGUAGE.into(),
+        cli::Language::Typescript => tree_sitter_typescript::LANGUAGE_TYPESCRIPT.into(),
+    };
+
+    parser
+        .set_language(&ts_language)
+        .context("Failed to set parser language")?;
+
+    parser
+        .parse(source, None)
+        .context("Failed to parse source code")
+}
+
+fn build_report(
+    results: &[ScanResult],
+    packages: Vec<PackageStatus>,
+    ctx: &ScanContext,
+) -> JsonOutput {
+    let mut report = OutputFormatter::build_output(results, ctx.classifier);
+    report.go_version = ctx.go_version.map(|v| v.to_string());
+    report.set_packages(packages);
+    report
+}
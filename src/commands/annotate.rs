@@ -0,0 +1,91 @@
+//! Adds suppression comments for selected violations.
+
+use anyhow::{Context as AnyhowContext, Result};
+use argflow::cli;
+use argflow::output::JsonOutput;
+use argflow::policy::{self, Baseline, GateOptions, Policy};
+use std::collections::BTreeMap;
+use std::path::Path;
+use tracing::debug;
+
+use super::scan_root;
+
+/// Inserts suppression comments above the selected violations, one per call site.
+pub fn run(root: &Path, report: &JsonOutput, args: &cli::AnnotateArgs) -> Result<()> {
+    let policy = Policy::from_file(&args.policy).context("Failed to load policy")?;
+    let baseline = args
+        .baseline
+        .as_deref()
+        .map(Baseline::from_file)
+        .transpose()
+        .context("Failed to load baseline")?;
+    if let (Some(baseline), Some(path)) = (&baseline, &args.baseline) {
+        baseline.check_version(path)?;
+    }
+
+    let root = scan_root(root);
+    let gate = policy::evaluate(
+        &policy,
+        &report.findings,
+        &GateOptions {
+            root: root.clone(),
+            baseline: baseline.as_ref(),
+            changed_files: None,
+        },
+    );
+
+    let mut by_file: BTreeMap<&str, BTreeMap<usize, Vec<String>>> = BTreeMap::new();
+    for violation in &gate.violations {
+        if baseline.is_some() && violation.status != policy::ViolationStatus::Baselined {
+            continue;
+        }
+        if !args.rule.is_empty() && !args.rule.contains(&violation.rule) {
+            continue;
+        }
+        let rules = by_file
+            .entry(&violation.file)
+            .or_default()
+            .entry(violation.line)
+            .or_default();
+        if !rules.contains(&violation.rule) {
+            rules.push(violation.rule.clone());
+        }
+    }
+
+    let mut total = 0;
+    for (file, rules_by_line) in &by_file {
+        let path = root.join(file);
+        let prefix = match cli::detect_language(&path) {
+            Some(cli::Language::Python) => "# ",
+            _ => "//",
+        };
+        let content = std::fs::read_to_string(&path)
+            .with_context(|| format!("Failed to read {}", path.display()))?;
+        let Some((annotated, changed)) = policy::insert_suppressions(
+            &content,
+            rules_by_line,
+            prefix,
+            &args.reason,
+            &args.owner,
+            args.ticket.as_deref(),
+        ) else {
+            continue;
+        };
+        if !args.dry_run {
+            std::fs::write(&path, annotated)
+                .with_context(|| format!("Failed to write {}", path.display()))?;
+        }
+        debug!(file, changed, "annotated suppressions");
+        total += changed;
+    }
+
+    let verb = if args.dry_run { "Would add" } else { "Added" };
+    println!("{verb} {total} suppression(s) in {} file(s)", by_file.len());
+    if args.reason == policy::PLACEHOLDER || args.owner == policy::PLACEHOLDER {
+        println!(
+            "Replace the {} placeholders with a reason and owner before committing",
+            policy::PLACEHOLDER
+        );
+    }
+    Ok(())
+}
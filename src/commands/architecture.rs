@@ -0,0 +1,28 @@
+//! Compares findings with a reference architecture spec.
+
+use anyhow::{Context as AnyhowContext, Result};
+use argflow::cli;
+use argflow::output::JsonOutput;
+use argflow::policy::{ArchitectureReport, ArchitectureSpec};
+use std::path::Path;
+use tracing::info;
+
+use super::scan_root;
+
+/// Compares the findings with the reference architecture spec and prints the deviations.
+pub fn run(root: &Path, report: &JsonOutput, args: &cli::ArchitectureArgs) -> Result<()> {
+    let spec =
+        ArchitectureSpec::from_file(&args.spec).context("Failed to load architecture spec")?;
+    let comparison = ArchitectureReport::compare(&spec, &report.findings, &scan_root(root));
+    info!(
+        components = spec.components.len(),
+        deviations = comparison.deviations.len(),
+        "compared with architecture spec"
+    );
+    if args.json {
+        println!("{}", serde_json::to_string_pretty(&comparison)?);
+    } else {
+        print!("{}", comparison.render_text());
+    }
+    Ok(())
+}
@@ -0,0 +1,75 @@
+//! Migrates baselines across rule renames and fingerprint versions.
+
+use anyhow::{Context as AnyhowContext, Result};
+use argflow::cli;
+use argflow::output::JsonOutput;
+use argflow::policy::{self, Baseline, Policy};
+use std::collections::BTreeSet;
+use std::path::Path;
+use tracing::debug;
+
+use super::scan_root;
+
+/// Re-keys a baseline for renamed rules and the current fingerprint algorithm, and
+/// renames rule ids in inline suppressions in the files with findings.
+pub fn migrate(root: &Path, report: &JsonOutput, args: &cli::MigrateArgs) -> Result<()> {
+    let old = Baseline::from_file(&args.baseline).context("Failed to load baseline")?;
+    let policy = Policy::from_file(&args.policy).context("Failed to load policy")?;
+
+    let mut renames = match &args.renames {
+        Some(path) => policy::load_renames(path).context("Failed to load rule renames")?,
+        None => policy::RuleRenames::new(),
+    };
+    renames.extend(args.rename.iter().cloned());
+
+    let (migrated, summary) =
+        policy::migrate_baseline(&old, &policy, &report.findings, &scan_root(root), &renames)?;
+
+    let files: BTreeSet<&str> = report.findings.iter().map(|f| f.file.as_str()).collect();
+    let mut suppressions = 0;
+    for file in files {
+        let Ok(content) = std::fs::read_to_string(file) else {
+            continue;
+        };
+        if let Some((rewritten, changed)) = policy::rename_suppressed_rules(&content, &renames) {
+            suppressions += changed;
+            if !args.dry_run {
+                std::fs::write(file, rewritten)
+                    .with_context(|| format!("Failed to rewrite suppressions in {file}"))?;
+            }
+            debug!(file, changed, "renamed rules in inline suppressions");
+        }
+    }
+
+    let output = args.output.as_ref().unwrap_or(&args.baseline);
+    if !args.dry_run {
+        migrated
+            .save(output)
+            .context("Failed to write migrated baseline")?;
+    }
+
+    let verb = if args.dry_run {
+        "Would migrate"
+    } else {
+        "Migrated"
+    };
+    println!(
+        "{verb} baseline v{} -> v{}: {} entries carried over ({} renamed), {} stale dropped, {} inline suppression(s) updated",
+        summary.from_version,
+        summary.to_version,
+        summary.migrated,
+        summary.renamed,
+        summary.stale.len(),
+        suppressions
+    );
+    for entry in &summary.stale {
+        println!(
+            "  stale: [{}] {} {}",
+            entry.rule, entry.file, entry.function
+        );
+    }
+    if !args.dry_run {
+        println!("Wrote {}", output.display());
+    }
+    Ok(())
+}
@@ -0,0 +1,35 @@
+//! Compares findings with another tool's SARIF results.
+
+use anyhow::{Context as AnyhowContext, Result};
+use argflow::cli;
+use argflow::compare::{self, Comparison};
+use argflow::output::JsonOutput;
+use std::path::Path;
+use tracing::info;
+
+use super::scan_root;
+
+/// Prints which calls argflow and the tool behind a SARIF log each report.
+pub fn run(root: &Path, report: &JsonOutput, args: &cli::CompareArgs) -> Result<()> {
+    let root = scan_root(root);
+    let content = std::fs::read_to_string(&args.sarif)
+        .with_context(|| format!("Failed to read SARIF file: {}", args.sarif.display()))?;
+    let results: Vec<_> = compare::parse_sarif(&content, &root)
+        .with_context(|| format!("Failed to parse SARIF file: {}", args.sarif.display()))?
+        .into_iter()
+        .filter(|result| compare::rule_selected(&result.rule, &args.rules))
+        .collect();
+    let comparison = Comparison::build(&report.findings, results, &root);
+    info!(
+        both = comparison.both.len(),
+        only_argflow = comparison.only_argflow.len(),
+        only_other = comparison.only_other.len(),
+        "compared findings with SARIF results"
+    );
+    if args.json {
+        println!("{}", serde_json::to_string_pretty(&comparison)?);
+    } else {
+        print!("{}", comparison.render_text());
+    }
+    Ok(())
+}
@@ -0,0 +1,36 @@
+//! Reports which imports the sink catalogs cover.
+
+use anyhow::Result;
+use argflow::cli;
+use argflow::coverage::CoverageReport;
+use argflow::output::JsonOutput;
+use std::path::Path;
+use tracing::info;
+
+use super::analysis::ScanContext;
+use super::scan_root;
+
+pub fn run(
+    root: &Path,
+    report: &JsonOutput,
+    ctx: &ScanContext,
+    args: &cli::CoverageArgs,
+) -> Result<()> {
+    let coverage = CoverageReport::build(
+        &scan_root(root),
+        ctx.classifier.get_mappings(),
+        ctx.import_equivalences,
+        &report.findings,
+    );
+    info!(
+        covered = coverage.covered.len(),
+        uncovered = coverage.uncovered.len(),
+        "compared imports with sink catalogs"
+    );
+    if args.json {
+        println!("{}", serde_json::to_string_pretty(&coverage)?);
+    } else {
+        print!("{}", coverage.render_text());
+    }
+    Ok(())
+}
@@ -0,0 +1,43 @@
+//! Compares the findings of two versions of a Go module.
+
+use anyhow::{Context as AnyhowContext, Result};
+use argflow::cli;
+use argflow::depdiff::DepDiffReport;
+use argflow::discovery::languages::go::{deps, GoEnv};
+use argflow::output::JsonOutput;
+use std::path::PathBuf;
+use tracing::info;
+
+/// Fetches both versions compared by `dep-diff` into the module cache.
+pub fn fetch_versions(args: &cli::DepDiffArgs, go_env: &GoEnv) -> Result<(PathBuf, PathBuf)> {
+    let fetch = |version: &str| {
+        let dir = deps::download_module(&args.module, version, go_env)
+            .with_context(|| format!("Failed to fetch {}@{version}", args.module))?;
+        info!(module = %args.module, version, dir = %dir.display(), "fetched module");
+        anyhow::Ok(dir)
+    };
+    Ok((fetch(&args.old)?, fetch(&args.new)?))
+}
+
+/// Compares the findings of two versions of a module and prints the changes.
+pub fn run(old: &JsonOutput, new: &JsonOutput, args: &cli::DepDiffArgs) -> Result<()> {
+    let diff = DepDiffReport::compare(
+        &args.module,
+        &args.old,
+        &args.new,
+        &old.findings,
+        &new.findings,
+    );
+    info!(
+        added = diff.added.len(),
+        removed = diff.removed.len(),
+        changed = diff.changed.len(),
+        "compared module versions"
+    );
+    if args.json {
+        println!("{}", serde_json::to_string_pretty(&diff)?);
+    } else {
+        print!("{}", diff.render_text());
+    }
+    Ok(())
+}
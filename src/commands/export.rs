@@ -0,0 +1,63 @@
+//! Exports findings in a vulnerability manager's import format.
+
+use anyhow::{Context as AnyhowContext, Result};
+use argflow::cli;
+use argflow::output::{JsonOutput, Redactions};
+use argflow::policy::{self, Baseline, GateOptions, Policy};
+use argflow::vulnmgr;
+use std::path::Path;
+use tracing::info;
+
+use super::scan_root;
+
+/// Writes violations, or every finding, in a vulnerability manager's import format.
+/// Exports findings or violations; they are evaluated and fingerprinted on the raw
+/// findings, like the gate, and redacted afterwards.
+pub fn run(
+    root: &Path,
+    report: &JsonOutput,
+    redactions: &Redactions,
+    args: &cli::ExportArgs,
+) -> Result<()> {
+    let root = scan_root(root);
+    let mut records = match &args.policy {
+        Some(policy_path) => {
+            let policy = Policy::from_file(policy_path).context("Failed to load policy")?;
+            let baseline = match &args.baseline {
+                Some(path) if path.exists() => {
+                    let baseline = Baseline::from_file(path).context("Failed to load baseline")?;
+                    baseline.check_version(path)?;
+                    Some(baseline)
+                }
+                _ => None,
+            };
+            let options = GateOptions {
+                root: root.clone(),
+                baseline: baseline.as_ref(),
+                ..GateOptions::default()
+            };
+            let gate = policy::evaluate(&policy, &report.findings, &options);
+            vulnmgr::records_from_gate(&gate, &report.findings, &root)
+        }
+        None => vulnmgr::records_from_findings(&report.findings, &root),
+    };
+    for record in &mut records {
+        record.title = redactions.apply(&record.title);
+        record.description = redactions.apply(&record.description);
+    }
+    info!(records = records.len(), format = ?args.format, "exporting findings");
+
+    let rendered = match args.format {
+        cli::ExportFormat::Defectdojo => vulnmgr::render_defectdojo(&records)?,
+        cli::ExportFormat::Generic => vulnmgr::render_generic(&records)?,
+    };
+    match &args.output {
+        Some(output) => {
+            std::fs::write(output, rendered + "\n")
+                .with_context(|| format!("Failed to write export: {}", output.display()))?;
+            info!(path = %output.display(), "wrote export");
+        }
+        None => println!("{rendered}"),
+    }
+    Ok(())
+}
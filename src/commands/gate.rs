@@ -0,0 +1,119 @@
+//! Evaluates findings against a policy.
+
+use anyhow::{Context as AnyhowContext, Result};
+use argflow::cli;
+use argflow::output::{JsonOutput, Redactions};
+use argflow::policy::{self, Baseline, GateOptions, Policy};
+use std::path::Path;
+use tracing::info;
+
+use super::{scan_root, write_output};
+
+/// Exit code when the gate finds blocking violations, distinct from 1 for tool errors.
+pub const GATE_FAILED_EXIT_CODE: i32 = 3;
+
+/// Evaluates the report against the policy and prints the outcome.
+pub fn run(
+    root: &Path,
+    report: &JsonOutput,
+    redactions: &Redactions,
+    args: &cli::GateArgs,
+) -> Result<policy::GateReport> {
+    let mut policy = Policy::from_file(&args.policy).context("Failed to load policy")?;
+    policy.fail_on = args.fail_on.unwrap_or(policy.fail_on);
+    info!(rules = policy.rules.len(), "loaded policy");
+
+    let baseline = match &args.baseline {
+        Some(path) if path.exists() && !args.update_baseline => {
+            let baseline = Baseline::from_file(path).context("Failed to load baseline")?;
+            baseline.check_version(path)?;
+            Some(baseline)
+        }
+        _ => None,
+    };
+
+    let changed = args
+        .diff_base
+        .as_deref()
+        .map(|base| policy::changed_files(root, base))
+        .transpose()
+        .context("Failed to compute changed files")?;
+    if let Some(changed) = &changed {
+        info!(files = changed.len(), "restricting gate to changed files");
+    }
+
+    let options = GateOptions {
+        root: scan_root(root),
+        baseline: baseline.as_ref(),
+        changed_files: changed.as_ref(),
+    };
+    let mut gate = policy::evaluate(&policy, &report.findings, &options);
+
+    let updated = if args.update_baseline {
+        let path = args
+            .baseline
+            .as_ref()
+            .context("--update-baseline requires --baseline")?;
+        // Tickets recorded against entries that are still violated carry over
+        let mut updated = Baseline::from_violations(&gate.violations);
+        if path.exists() {
+            let previous = Baseline::from_file(path).context("Failed to load baseline")?;
+            updated = updated.keep_tickets(&previous);
+        }
+        updated.save(path).context("Failed to write baseline")?;
+        info!(path = %path.display(), entries = updated.len(), "wrote baseline");
+        Some(updated)
+    } else {
+        None
+    };
+    let options = GateOptions {
+        baseline: updated.as_ref().or(options.baseline),
+        ..options
+    };
+    if updated.is_some() {
+        gate = policy::evaluate(&policy, &report.findings, &options);
+    }
+    gate.redact(redactions);
+
+    if let Some(path) = &args.report {
+        write_output(&serde_json::to_string_pretty(&gate)?, Some(path))?;
+    }
+    if let Some(dir) = &args.team_reports {
+        write_team_reports(dir, &gate, &options)?;
+    }
+    if args.json {
+        println!("{}", serde_json::to_string_pretty(&gate)?);
+    } else {
+        print!("{}", gate.render_text());
+    }
+
+    Ok(gate)
+}
+
+/// Writes `<team>.json` per owning team, and `unowned.json` for the rest.
+fn write_team_reports(dir: &Path, gate: &policy::GateReport, options: &GateOptions) -> Result<()> {
+    std::fs::create_dir_all(dir).with_context(|| format!("Failed to create {}", dir.display()))?;
+    for (owner, report) in gate.by_owner(options) {
+        let name = owner
+            .as_deref()
+            .map_or_else(|| "unowned".to_string(), team_file_name);
+        let path = dir.join(format!("{name}.json"));
+        write_output(&serde_json::to_string_pretty(&report)?, Some(&path))?;
+        info!(path = %path.display(), violations = report.summary.violations, "wrote team report");
+    }
+    Ok(())
+}
+
+/// File name for a team handle: `@org/payments` becomes `org-payments`.
+fn team_file_name(team: &str) -> String {
+    team.trim_start_matches('@')
+        .chars()
+        .map(|c| {
+            if c.is_ascii_alphanumeric() || c == '-' || c == '_' {
+                c
+            } else {
+                '-'
+            }
+        })
+        .collect()
+}
@@ -0,0 +1,111 @@
+//! Records scans into the history database and reports trends from it.
+
+use anyhow::{Context as AnyhowContext, Result};
+use argflow::cli;
+use argflow::history::{self, HistoryStore};
+use argflow::output::JsonOutput;
+use argflow::policy::{self, GateOptions, Policy};
+use argflow::utils::git;
+use std::path::Path;
+use tracing::{info, warn};
+
+use super::{scan_root, unix_now};
+
+pub fn record(path: &Path, report: &JsonOutput, args: &cli::RecordArgs) -> Result<()> {
+    let root = scan_root(path);
+    let mut entries = match &args.policy {
+        Some(policy_path) => {
+            let policy = Policy::from_file(policy_path).context("Failed to load policy")?;
+            let options = GateOptions {
+                root: root.clone(),
+                ..GateOptions::default()
+            };
+            history::entries_from_gate(&policy::evaluate(&policy, &report.findings, &options))
+        }
+        None => history::entries_from_findings(&report.findings, &root),
+    };
+
+    let commit = args.commit.clone().or_else(|| git::head_commit(path));
+    if commit.is_none() {
+        warn!("could not determine commit SHA; recording scan without one");
+    }
+    let author = args.author.clone().or_else(|| git::head_author(path));
+    let recorded_at = unix_now();
+
+    let mut store = HistoryStore::open(&args.db).context("Failed to open history database")?;
+    let previous = store
+        .scans(Some(1))
+        .context("Failed to read scan history")?
+        .pop();
+    let seen = store
+        .seen_fingerprints()
+        .context("Failed to read scan history")?;
+    let fixed = history::annotate(&mut entries, previous.as_ref(), &seen);
+
+    let scan_id = store
+        .record(
+            commit.as_deref(),
+            author.as_deref(),
+            recorded_at,
+            &root.to_string_lossy(),
+            &entries,
+        )
+        .context("Failed to record scan")?;
+
+    let count = |status| entries.iter().filter(|e| e.status == Some(status)).count();
+    println!(
+        "Recorded scan {scan_id} ({} entries{}) in {}: {} new, {} recurring, {} regressed, {} fixed",
+        entries.len(),
+        commit
+            .as_deref()
+            .map(|c| format!(" at {c}"))
+            .unwrap_or_default(),
+        args.db.display(),
+        count(history::DeltaStatus::New),
+        count(history::DeltaStatus::Recurring),
+        count(history::DeltaStatus::Regressed),
+        fixed.len()
+    );
+    for entry in entries
+        .iter()
+        .filter(|e| e.status != Some(history::DeltaStatus::Recurring))
+    {
+        let status = entry.status.map_or("", history::DeltaStatus::as_str);
+        println!("  {status:<9} {}:{} {}", entry.file, entry.line, entry.rule);
+    }
+    for entry in &fixed {
+        println!(
+            "  fixed     {}:{} {}{}",
+            entry.file,
+            entry.line,
+            entry.rule,
+            author
+                .as_deref()
+                .map(|a| format!(" by {a}"))
+                .unwrap_or_default()
+        );
+    }
+    Ok(())
+}
+
+pub fn trend(args: &cli::TrendArgs) -> Result<()> {
+    if !args.db.exists() {
+        anyhow::bail!(
+            "History database does not exist: {}. Record scans with `argflow record` first",
+            args.db.display()
+        );
+    }
+    let store = HistoryStore::open(&args.db).context("Failed to open history database")?;
+    let scans = store
+        .scans(args.last)
+        .context("Failed to read scan history")?;
+    info!(scans = scans.len(), "loaded scan history");
+
+    let trend = history::compute_trend(&scans, args.group_depth);
+    if args.json {
+        println!("{}", serde_json::to_string_pretty(&trend)?);
+    } else {
+        print!("{}", trend.render_text());
+    }
+    Ok(())
+}
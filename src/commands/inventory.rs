@@ -0,0 +1,25 @@
+//! Reports the findings reachable from each binary.
+
+use anyhow::Result;
+use argflow::cli;
+use argflow::inventory::Inventory;
+use argflow::output::JsonOutput;
+use std::path::Path;
+use tracing::info;
+
+use super::scan_root;
+
+/// Prints the findings reachable from each `main` package.
+pub fn run(root: &Path, report: &JsonOutput, args: &cli::InventoryArgs) -> Result<()> {
+    let inventory = Inventory::build(&scan_root(root), &report.findings);
+    info!(
+        binaries = inventory.binaries.len(),
+        "built binary inventory"
+    );
+    if args.json {
+        println!("{}", serde_json::to_string_pretty(&inventory)?);
+    } else {
+        print!("{}", inventory.render_text());
+    }
+    Ok(())
+}
@@ -0,0 +1,97 @@
+//! Subcommand handlers and the helpers they share.
+
+pub mod aggregate;
+mod analysis;
+mod annotate;
+mod architecture;
+mod baseline;
+mod compare;
+mod coverage;
+mod dep_diff;
+mod export;
+mod gate;
+pub mod history;
+mod inventory;
+mod params;
+mod quickstart;
+mod repro;
+pub mod rule;
+pub mod rules;
+pub mod scan;
+pub mod schema;
+mod simulate;
+pub mod sinks;
+
+use anyhow::{Context as AnyhowContext, Result};
+use argflow::classifier::RulesClassifier;
+use argflow::cli;
+use argflow::presets;
+use std::io::Write;
+use std::path::{Path, PathBuf};
+use std::time::{SystemTime, UNIX_EPOCH};
+use tracing::info;
+
+/// Directory that report paths are made relative to.
+pub fn scan_root(path: &Path) -> PathBuf {
+    if path.is_dir() {
+        path.to_path_buf()
+    } else {
+        path.parent().unwrap_or(path).to_path_buf()
+    }
+}
+
+pub fn unix_now() -> i64 {
+    SystemTime::now()
+        .duration_since(UNIX_EPOCH)
+        .map(|d| d.as_secs() as i64)
+        .unwrap_or_default()
+}
+
+pub fn write_output(output: &str, output_file: Option<&PathBuf>) -> Result<()> {
+    match output_file {
+        Some(path) => {
+            let mut file = std::fs::File::create(path)
+                .with_context(|| format!("Failed to create output file: {}", path.display()))?;
+            file.write_all(output.as_bytes())
+                .with_context(|| format!("Failed to write to output file: {}", path.display()))?;
+            info!(path = %path.display(), "wrote output to file");
+        }
+        None => {
+            println!("{output}");
+        }
+    }
+
+    Ok(())
+}
+
+pub fn get_preset_paths(args: &cli::Args) -> Result<Vec<PathBuf>> {
+    if args.preset.is_empty() && args.rules.is_none() {
+        anyhow::bail!(
+            "No preset or rules specified. Use --preset <name> (e.g., --preset crypto) or --rules <path>"
+        );
+    }
+
+    if !args.preset.is_empty() {
+        info!(presets = ?args.preset, "loading presets");
+        return presets::load_presets(&args.preset);
+    }
+
+    // Custom rules file - return empty preset paths (classifier loads from rules file)
+    Ok(vec![])
+}
+
+pub fn load_classifier(args: &cli::Args, preset_paths: &[PathBuf]) -> Result<RulesClassifier> {
+    if let Some(ref rules_path) = args.rules {
+        info!(rules = %rules_path.display(), "loading custom rules");
+        return RulesClassifier::from_file(rules_path)
+            .map_err(|e| anyhow::anyhow!("Failed to load custom rules: {e}"));
+    }
+
+    if !preset_paths.is_empty() {
+        return RulesClassifier::from_preset_paths(preset_paths)
+            .map_err(|e| anyhow::anyhow!("Failed to load preset: {e}"));
+    }
+
+    RulesClassifier::from_bundled()
+        .map_err(|e| anyhow::anyhow!("Failed to load classifier rules: {e}"))
+}
@@ -0,0 +1,59 @@
+//! Builds and verifies the security parameter manifest.
+
+use anyhow::{Context as AnyhowContext, Result};
+use argflow::cli;
+use argflow::output::JsonOutput;
+use argflow::params::{self, ParamsManifest};
+use std::path::Path;
+use tracing::info;
+
+use super::scan_root;
+
+/// Prints the security parameter manifest, or compares it with a committed one.
+pub fn run(root: &Path, report: &JsonOutput, args: &cli::ParamsArgs) -> Result<()> {
+    let manifest = ParamsManifest::build(&scan_root(root), report);
+    info!(
+        parameters = manifest.parameters.len(),
+        "built security parameter manifest"
+    );
+    let Some(committed_path) = &args.verify else {
+        match args.format {
+            cli::ManifestFormat::Yaml => print!("{}", serde_yaml::to_string(&manifest)?),
+            cli::ManifestFormat::Json => println!("{}", serde_json::to_string_pretty(&manifest)?),
+            cli::ManifestFormat::Html => print!("{}", params::render_html(&manifest)),
+        }
+        return Ok(());
+    };
+    if args.format == cli::ManifestFormat::Html {
+        anyhow::bail!("--verify reports drift as yaml or json, not html");
+    }
+
+    // YAML is a superset of JSON, so either format of committed manifest parses
+    let content = std::fs::read_to_string(committed_path)
+        .with_context(|| format!("Failed to read manifest: {}", committed_path.display()))?;
+    let committed: ParamsManifest = serde_yaml::from_str(&content)
+        .with_context(|| format!("Failed to parse manifest: {}", committed_path.display()))?;
+    if committed.version != params::MANIFEST_VERSION {
+        anyhow::bail!(
+            "manifest {} has version {}, expected {}; regenerate it with `argflow params`",
+            committed_path.display(),
+            committed.version,
+            params::MANIFEST_VERSION
+        );
+    }
+    let drift = manifest.drift(&committed);
+    match args.format {
+        cli::ManifestFormat::Yaml | cli::ManifestFormat::Html => {
+            print!("{}", params::render_drift(&drift))
+        }
+        cli::ManifestFormat::Json => println!("{}", serde_json::to_string_pretty(&drift)?),
+    }
+    if !drift.is_empty() {
+        anyhow::bail!(
+            "{} security parameter(s) drifted from {}",
+            drift.len(),
+            committed_path.display()
+        );
+    }
+    Ok(())
+}
@@ -0,0 +1,34 @@
+//! Summarizes a scan run without configuration.
+
+use anyhow::{Context as AnyhowContext, Result};
+use argflow::cli;
+use argflow::output::JsonOutput;
+use argflow::policy::Policy;
+use argflow::quickstart;
+use std::path::Path;
+
+use super::scan_root;
+
+/// Prints the quickstart summary of a scan run without configuration.
+pub fn run(root: &Path, report: &JsonOutput, args: &cli::Args) -> Result<()> {
+    // Inventory runs have no pass/fail rules, not even moderate ones
+    let policy = match args.mode {
+        cli::ScanMode::Enforce => {
+            Some(Policy::moderate().context("Failed to load moderate policy")?)
+        }
+        cli::ScanMode::Inventory => None,
+    };
+    let report_file = args
+        .output_file
+        .clone()
+        .unwrap_or_else(|| quickstart::REPORT_FILE.into());
+    let summary = quickstart::Summary::new(
+        report,
+        policy.as_ref(),
+        &scan_root(root),
+        args.preset.clone(),
+        report_file,
+    );
+    print!("{}", summary.render_text());
+    Ok(())
+}
@@ -0,0 +1,48 @@
+//! Writes a standalone reproduction of a finding.
+
+use anyhow::{Context as AnyhowContext, Result};
+use argflow::cli;
+use argflow::output::JsonOutput;
+use argflow::repro;
+use std::path::{Path, PathBuf};
+use tracing::warn;
+
+use super::scan_root;
+
+/// Writes a standalone Go module reproducing the selected finding.
+pub fn run(root: &Path, report: &JsonOutput, args: &cli::ReproArgs) -> Result<()> {
+    let root = scan_root(root);
+    let mut finding = repro::select(&report.findings, &args.finding, &root)
+        .with_context(|| format!("No finding at {}", args.finding))?
+        .clone();
+    // Findings from archive scans carry paths relative to the extracted root
+    if !Path::new(&finding.file).exists() {
+        finding.file = root.join(&finding.file).to_string_lossy().into_owned();
+    }
+    let reproduction = repro::extract(&finding).context("Failed to extract reproduction")?;
+
+    let dir = args.output.clone().unwrap_or_else(|| {
+        let stem = Path::new(&finding.file)
+            .file_stem()
+            .map(|s| s.to_string_lossy().into_owned())
+            .unwrap_or_default();
+        PathBuf::from(format!("repro-{stem}-{}", finding.line))
+    });
+    reproduction
+        .write(&dir, &finding)
+        .context("Failed to write reproduction")?;
+
+    for path in &reproduction.unresolved_imports {
+        warn!(
+            import = path,
+            "reproduction imports a package of the scanned module; vendor its declarations by hand"
+        );
+    }
+    println!(
+        "Wrote {} ({} at main.go:{})",
+        dir.display(),
+        finding.full_name,
+        reproduction.line
+    );
+    Ok(())
+}
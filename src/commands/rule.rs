@@ -0,0 +1,56 @@
+//! Tests rule expressions against a saved report.
+
+use anyhow::{Context as AnyhowContext, Result};
+use argflow::cli;
+use argflow::output::read_report_file;
+use argflow::policy::{Expression, ExpressionTest};
+use std::io::BufRead;
+use tracing::info;
+
+pub fn run(rule_args: &cli::RuleArgs) -> Result<()> {
+    let cli::RuleCommand::Test(test_args) = &rule_args.action;
+    let report = read_report_file(&test_args.against)?;
+    let findings = report
+        .get("findings")
+        .unwrap_or(&report)
+        .as_array()
+        .with_context(|| {
+            format!(
+                "{} is not an argflow JSON report",
+                test_args.against.display()
+            )
+        })?;
+    info!(findings = findings.len(), "loaded report");
+
+    let print = |test: &ExpressionTest| -> Result<()> {
+        if test_args.json {
+            println!("{}", serde_json::to_string_pretty(test)?);
+        } else {
+            print!("{}", test.render_text());
+        }
+        Ok(())
+    };
+    if let Some(source) = &test_args.expr {
+        let expression = Expression::parse(source).context("Failed to parse --expr")?;
+        return print(&ExpressionTest::run(&expression, findings));
+    }
+
+    // One expression per line until end of input; a bad line is reported and skipped
+    let stdin = std::io::stdin();
+    let mut line = String::new();
+    loop {
+        eprint!("> ");
+        line.clear();
+        if stdin.lock().read_line(&mut line)? == 0 {
+            return Ok(());
+        }
+        let source = line.trim();
+        if source.is_empty() {
+            continue;
+        }
+        match Expression::parse(source) {
+            Ok(expression) => print(&ExpressionTest::run(&expression, findings))?,
+            Err(e) => eprintln!("{e}"),
+        }
+    }
+}
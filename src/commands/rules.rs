@@ -0,0 +1,37 @@
+//! Lists the sinks and policy rules argflow knows about.
+
+use anyhow::{Context as AnyhowContext, Result};
+use argflow::catalog::RuleCatalog;
+use argflow::classifier::RulesClassifier;
+use argflow::cli;
+use argflow::policy::Policy;
+use argflow::presets;
+
+use super::load_classifier;
+
+pub fn run(args: &cli::Args, rules_args: &cli::RulesArgs) -> Result<()> {
+    let mut catalog = RuleCatalog::default();
+    for name in presets::list_available_presets() {
+        let classifier = RulesClassifier::from_preset_path(&presets::get_presets_dir().join(&name))
+            .map_err(|e| anyhow::anyhow!("Failed to load preset {name}: {e}"))?;
+        catalog.add_sinks(&classifier, Some(&name), false);
+    }
+
+    let preset_paths = if args.preset.is_empty() {
+        Vec::new()
+    } else {
+        presets::load_presets(&args.preset)?
+    };
+    catalog.add_sinks(&load_classifier(args, &preset_paths)?, None, true);
+
+    if let Some(policy_path) = &rules_args.policy {
+        let policy = Policy::from_file(policy_path).context("Failed to load policy")?;
+        catalog.add_policy(&policy);
+    }
+
+    match rules_args.format {
+        cli::CatalogFormat::Text => print!("{}", catalog.render_text()),
+        cli::CatalogFormat::Json => println!("{}", serde_json::to_string_pretty(&catalog)?),
+    }
+    Ok(())
+}
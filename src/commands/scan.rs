@@ -0,0 +1,668 @@
+//! The scan run and the subcommands that consume its report.
+
+use anyhow::{Context as AnyhowContext, Result};
+use argflow::archive;
+use argflow::attestation::{self, ScanPredicate, Signer, Statement, ToolInfo};
+use argflow::cli;
+use argflow::discovery::languages::go::{fips, generate, gomod, GoEnv};
+use argflow::discovery::Overlay;
+use argflow::engine::{ImportEquivalences, ValueOverrides};
+use argflow::notify::{HttpTransport, NotificationSummary, NotifyConfig};
+use argflow::output::{
+    write_report_file, CryptoOperation, DuplicateOperation, FipsPosture, JsonOutput,
+    OutputFormatter, Redactions, ScanManifest,
+};
+use argflow::policy;
+use argflow::scanner::Scanner;
+use argflow::sinks::PackageVersions;
+use argflow::telemetry::{self, OtlpConfig, Telemetry};
+use argflow::utils::git;
+use argflow::vcs::{self, GitProvider};
+use argflow::vulndb::{GovulncheckOutput, VulnDb};
+use std::collections::BTreeMap;
+use std::io::{BufWriter, Write};
+use std::path::{Path, PathBuf};
+use tracing::{debug, info, trace, warn};
+
+use super::analysis::{scan_report, ScanContext};
+use super::{
+    annotate, architecture, baseline, compare, coverage, dep_diff, export, gate, get_preset_paths,
+    history, inventory, load_classifier, params, quickstart, repro, scan_root, simulate, unix_now,
+    write_output,
+};
+
+/// Scans the project and runs the subcommand that consumes the report, if any.
+pub fn run(args: &cli::Args, zero_config: bool) -> Result<()> {
+    let go_env = GoEnv {
+        offline: args.offline,
+        proxy: args.goproxy.clone(),
+    };
+
+    // dep-diff scans the old version as the project and the new one alongside it
+    let dep_diff = match &args.command {
+        Some(cli::Command::DepDiff(dep_args)) => Some(dep_diff::fetch_versions(dep_args, &go_env)?),
+        _ => None,
+    };
+    let path = match &dep_diff {
+        Some((old, _)) => old.as_path(),
+        None => args.scan_path()?,
+    };
+    info!(path = %path.display(), "starting argflow analysis");
+
+    let otlp = OtlpConfig::resolve(args.otlp_endpoint.as_deref(), &|key| {
+        std::env::var(key).ok()
+    })
+    .context("Invalid OpenTelemetry configuration")?;
+    let telemetry = Telemetry::new(otlp.is_some());
+
+    let workspace = if archive::is_archive(path) {
+        if matches!(
+            args.command,
+            Some(cli::Command::Annotate(_) | cli::Command::Baseline(_))
+        ) {
+            anyhow::bail!(
+                "annotate and baseline migrate rewrite source files and cannot run on an archive"
+            );
+        }
+        let workspace = telemetry
+            .phase("extract", || archive::Workspace::extract(path))
+            .context("Failed to extract archive")?;
+        info!(
+            files = workspace.files(),
+            root = %workspace.root().display(),
+            "extracted archive"
+        );
+        Some(workspace)
+    } else {
+        None
+    };
+    let path = workspace.as_ref().map_or(path, |w| w.root());
+
+    let language = args
+        .language
+        .or(dep_diff.as_ref().map(|_| cli::Language::Go))
+        .or_else(|| {
+            if path.is_file() {
+                let detected = cli::detect_language(path);
+                if let Some(lang) = detected {
+                    debug!(language = lang.as_str(), "auto-detected language");
+                }
+                detected
+            } else {
+                None
+            }
+        })
+        .context("Could not detect language. Please specify --language")?;
+
+    info!(language = language.as_str(), "using language");
+    telemetry.set_attribute("argflow.language", language.as_str());
+    if !args.preset.is_empty() {
+        telemetry.set_attribute("argflow.preset", args.preset.join(","));
+    }
+
+    if (args.govulncheck || args.govulncheck_json.is_some()) && language != cli::Language::Go {
+        anyhow::bail!("govulncheck results can only be merged into Go scans");
+    }
+    if args.fips && language != cli::Language::Go {
+        anyhow::bail!("--fips is only supported for Go scans");
+    }
+    if args.generators && language != cli::Language::Go {
+        anyhow::bail!("--generators is only supported for Go scans");
+    }
+    if args.overlay.is_some() && language != cli::Language::Go {
+        anyhow::bail!("--overlay is only supported for Go scans");
+    }
+    if args.go_generate && (language != cli::Language::Go || !path.is_dir()) {
+        anyhow::bail!("--go-generate needs a Go project directory");
+    }
+    if args.recurse_modules && (language != cli::Language::Go || !path.is_dir()) {
+        anyhow::bail!("--recurse-modules needs a Go project directory");
+    }
+    if matches!(args.command, Some(cli::Command::Repro(_))) && language != cli::Language::Go {
+        anyhow::bail!("argflow repro only supports Go findings");
+    }
+    if matches!(args.command, Some(cli::Command::Inventory(_))) && language != cli::Language::Go {
+        anyhow::bail!("argflow inventory only supports Go projects");
+    }
+    if matches!(args.command, Some(cli::Command::Coverage(_))) && language != cli::Language::Go {
+        anyhow::bail!("argflow coverage only supports Go projects");
+    }
+    if dep_diff.is_some() && language != cli::Language::Go {
+        anyhow::bail!("argflow dep-diff only supports Go modules");
+    }
+
+    let (preset_paths, mut classifier) = telemetry.phase("load_rules", || -> Result<_> {
+        // Load preset paths for both classifier and filters
+        let preset_paths = get_preset_paths(args)?;
+        // Load classifier from presets or custom rules
+        let classifier = load_classifier(args, &preset_paths)?;
+        Ok((preset_paths, classifier))
+    })?;
+
+    let go_version = match language {
+        // The newer version may call APIs the older one's go directive predates
+        cli::Language::Go => args.go_version.or_else(|| {
+            gomod::detect_go_version(dep_diff.as_ref().map_or(path, |(_, new)| new.as_path()))
+        }),
+        _ => None,
+    };
+    if let Some(version) = go_version {
+        info!(go_version = %version, "targeting Go version");
+        let removed = classifier.restrict_to_go_version(version);
+        if !removed.is_empty() {
+            debug!(
+                ?removed,
+                "skipping mappings for packages newer than target Go version"
+            );
+        }
+    }
+    if language == cli::Language::Go {
+        let versions = PackageVersions::detect(path, go_version);
+        let selected = classifier.select_parameter_versions(|key| versions.version_of(key));
+        if !selected.is_empty() {
+            debug!(
+                ?selected,
+                "using parameter roles of the package versions in use"
+            );
+        }
+    }
+    debug!(
+        classifications = classifier.classification_count(),
+        mappings = classifier.mapping_count(),
+        "classifier loaded"
+    );
+
+    // Create scanner with classifier mappings and struct field detection
+    // Only calls with explicit API mappings will be detected (high precision)
+    let import_equivalences = ImportEquivalences::new(args.import_equivalence.clone());
+    let overlay = args
+        .overlay
+        .as_deref()
+        .map(Overlay::from_file)
+        .transpose()
+        .context("Failed to load overlay")?
+        .unwrap_or_default();
+    let build_scanner = || {
+        Scanner::with_mappings_and_struct_fields(
+            classifier.get_mappings().clone(),
+            classifier.get_struct_fields().clone(),
+        )
+        .with_secrets(classifier.get_secrets().clone())
+        .with_import_equivalences(import_equivalences.clone())
+        .with_max_derivation_depth(args.max_derivation_depth)
+    };
+    let scanner = build_scanner();
+    trace!("scanner initialized with classifier mappings and struct fields");
+
+    let ctx = ScanContext {
+        scanner: &scanner,
+        classifier: &classifier,
+        output_format: args.format,
+        output_file: args.output_file.as_ref(),
+        preset_paths: &preset_paths,
+        go_version,
+        compat: args.compat,
+        recurse_modules: args.recurse_modules,
+        go_env: &go_env,
+        import_equivalences: &import_equivalences,
+        overlay: &overlay,
+        telemetry: &telemetry,
+    };
+
+    if args.go_generate {
+        telemetry
+            .phase("generate", || {
+                generate::run_generators(&scan_root(path), &go_env, args.go_generate_run.as_deref())
+            })
+            .context("Failed to run generators")?;
+        info!("ran go generate before the scan");
+    }
+
+    // govulncheck loads the packages and builds its own call graph; running it while we
+    // scan overlaps the two analyses instead of doubling the wall time
+    let govulncheck = args.govulncheck.then(|| {
+        let dir = scan_root(path);
+        info!(dir = %dir.display(), "running govulncheck alongside the scan");
+        std::thread::spawn(move || GovulncheckOutput::run(&dir))
+    });
+
+    let mut report = scan_report(path, language, &ctx, args)?;
+    report.manifest = Some(scan_manifest(path, language, &ctx, args)?);
+
+    if let Some(vulndb_path) = &args.vulndb {
+        telemetry.phase("vulndb", || -> Result<()> {
+            let db = VulnDb::load(vulndb_path).context("Failed to load vulnerability database")?;
+            info!(advisories = db.len(), "loaded vulnerability database");
+            db.annotate(&mut report.findings, report.go_version.as_deref());
+            Ok(())
+        })?;
+    }
+
+    let govulncheck_output = telemetry.phase("govulncheck", || -> Result<_> {
+        Ok(match (govulncheck, &args.govulncheck_json) {
+            (Some(handle), _) => Some(
+                handle
+                    .join()
+                    .map_err(|_| anyhow::anyhow!("govulncheck thread panicked"))?
+                    .context("Failed to run govulncheck")?,
+            ),
+            (None, Some(path)) => Some(
+                GovulncheckOutput::from_file(path).context("Failed to read govulncheck output")?,
+            ),
+            (None, None) => None,
+        })
+    })?;
+    if let Some(output) = govulncheck_output {
+        output.merge_into(&mut report);
+        info!(
+            vulnerabilities = report.vulnerabilities.len(),
+            "merged govulncheck results"
+        );
+    }
+
+    if args.fips {
+        let signals = fips::detect_signals(&scan_root(path), &|key| std::env::var(key).ok());
+        let posture = FipsPosture::assess(signals, &report.findings);
+        info!(mode = posture.mode.as_str(), "assessed FIPS posture");
+        report.fips = Some(posture);
+    }
+
+    if args.blame {
+        telemetry.phase("blame", || {
+            let provider = GitProvider;
+            report.repository = provider.repository(&scan_root(path));
+            if report.repository.is_none() {
+                warn!(path = %path.display(), "--blame: not a git repository; findings are not blamed");
+                return;
+            }
+            let blamed = vcs::annotate(&mut report.findings, &provider, unix_now());
+            info!(blamed, findings = report.findings.len(), "blamed finding lines");
+        });
+    }
+
+    if args.generators {
+        report.generator_settings = generate::detect_settings(&scan_root(path));
+        info!(
+            settings = report.generator_settings.len(),
+            "read code generator inputs"
+        );
+    }
+
+    if args.mode == cli::ScanMode::Inventory {
+        report.retain_inventory();
+        info!(
+            findings = report.findings.len(),
+            "inventory mode: reporting usage only"
+        );
+    }
+
+    // Extraction paths are meaningless once the workspace is gone; report archive paths
+    if let Some(workspace) = &workspace {
+        relativize_paths(&mut report, workspace.root());
+    }
+    if dep_diff.is_some() {
+        relativize_paths(&mut report, path);
+    }
+
+    // Gates, baselines and history fingerprint the scan as it was; everything written or
+    // sent elsewhere gets hardcoded secrets redacted, including messages rendered from
+    // the raw findings
+    let mut published = report.clone();
+    let redactions = if args.show_secrets {
+        Redactions::default()
+    } else {
+        let redactions = published.redact_secrets();
+        if !redactions.is_empty() {
+            info!(
+                redacted = redactions.len(),
+                "redacted hardcoded secrets (use --show-secrets to keep them)"
+            );
+        }
+        redactions
+    };
+
+    // For subcommands the scan report is only written when explicitly requested;
+    // stdout belongs to the subcommand's own output
+    if args.command.is_none() || ctx.output_file.is_some() {
+        telemetry.phase("output", || -> Result<()> {
+            let Some(output_file) = ctx.output_file else {
+                let mut stdout = BufWriter::new(std::io::stdout().lock());
+                OutputFormatter::write(&published, ctx.output_format, &mut stdout)?;
+                writeln!(stdout)?;
+                stdout.flush()?;
+                return Ok(());
+            };
+            let digest = write_report_file(&published, ctx.output_format, output_file)?;
+            info!(path = %output_file.display(), "wrote output to file");
+            if let Some(key) = &args.sign {
+                let config_hash = report
+                    .manifest
+                    .as_ref()
+                    .map(|manifest| manifest.config_hash.clone())
+                    .unwrap_or_default();
+                write_attestation(args, key, path, &ctx, config_hash, &digest, output_file)?;
+            }
+            Ok(())
+        })?;
+    }
+
+    let gate = match &args.command {
+        Some(cli::Command::Gate(gate_args)) => {
+            Some(telemetry.phase("gate", || gate::run(path, &report, &redactions, gate_args))?)
+        }
+        Some(cli::Command::Record(record_args)) => {
+            telemetry.phase("record", || history::record(path, &report, record_args))?;
+            None
+        }
+        Some(cli::Command::Baseline(cli::BaselineArgs {
+            action: cli::BaselineCommand::Migrate(migrate_args),
+        })) => {
+            telemetry.phase("migrate", || baseline::migrate(path, &report, migrate_args))?;
+            None
+        }
+        Some(cli::Command::Repro(repro_args)) => {
+            repro::run(path, &published, repro_args)?;
+            None
+        }
+        Some(cli::Command::Inventory(inventory_args)) => {
+            inventory::run(path, &published, inventory_args)?;
+            None
+        }
+        Some(cli::Command::Coverage(coverage_args)) => {
+            coverage::run(path, &published, &ctx, coverage_args)?;
+            None
+        }
+        Some(cli::Command::Params(params_args)) => {
+            params::run(path, &published, params_args)?;
+            None
+        }
+        Some(cli::Command::Export(export_args)) => {
+            telemetry.phase("export", || {
+                export::run(path, &report, &redactions, export_args)
+            })?;
+            None
+        }
+        Some(cli::Command::Compare(compare_args)) => {
+            compare::run(path, &published, compare_args)?;
+            None
+        }
+        Some(cli::Command::Annotate(annotate_args)) => {
+            telemetry.phase("annotate", || annotate::run(path, &report, annotate_args))?;
+            None
+        }
+        Some(cli::Command::Simulate(simulate_args)) => {
+            let scanner =
+                build_scanner().with_overrides(ValueOverrides::new(simulate_args.set.clone()));
+            let ctx = ScanContext {
+                scanner: &scanner,
+                ..ctx
+            };
+            let mut simulated =
+                telemetry.phase("simulate", || scan_report(path, language, &ctx, args))?;
+            if let Some(workspace) = &workspace {
+                relativize_paths(&mut simulated, workspace.root());
+            }
+            let mut redactions = redactions.clone();
+            if !args.show_secrets {
+                redactions.extend(simulated.clone().redact_secrets());
+            }
+            simulate::run(path, &report, &simulated, &redactions, simulate_args)?;
+            None
+        }
+        Some(cli::Command::DepDiff(dep_args)) => {
+            let (_, new) = dep_diff
+                .as_ref()
+                .expect("versions are fetched for dep-diff");
+            let mut newer =
+                telemetry.phase("dep-diff", || scan_report(new, language, &ctx, args))?;
+            relativize_paths(&mut newer, new);
+            if !args.show_secrets {
+                newer.redact_secrets();
+            }
+            dep_diff::run(&published, &newer, dep_args)?;
+            None
+        }
+        Some(cli::Command::Architecture(architecture_args)) => {
+            architecture::run(path, &published, architecture_args)?;
+            None
+        }
+        Some(
+            cli::Command::Trend(_)
+            | cli::Command::Schema(_)
+            | cli::Command::Rules(_)
+            | cli::Command::Sinks(_)
+            | cli::Command::Rule(_)
+            | cli::Command::Aggregate(_),
+        ) => {
+            unreachable!(
+                "trend, schema, rules, sinks, rule and aggregate are handled before scanning"
+            )
+        }
+        None if zero_config => {
+            quickstart::run(path, &published, args)?;
+            None
+        }
+        None => None,
+    };
+
+    if let Some(config_path) = &args.notify {
+        send_notifications(config_path, path, &published, gate.as_ref())?;
+    }
+
+    if let Some(config) = &otlp {
+        record_report_metrics(&telemetry, &report);
+        if let Err(e) = telemetry::export(&telemetry, config) {
+            warn!(error = %e, "failed to export telemetry");
+        }
+    }
+
+    if gate.is_some_and(|gate| !gate.passed) {
+        std::process::exit(gate::GATE_FAILED_EXIT_CODE);
+    }
+
+    Ok(())
+}
+
+fn record_report_metrics(telemetry: &Telemetry, report: &JsonOutput) {
+    telemetry.count(
+        "argflow.packages.analyzed",
+        "Packages with at least one scanned file",
+        report.packages.len(),
+    );
+    telemetry.count(
+        "argflow.files.scanned",
+        "Files with matching crypto calls",
+        report.files_scanned,
+    );
+    telemetry.count(
+        "argflow.findings",
+        "Crypto API calls found",
+        report.total_findings,
+    );
+    telemetry.count(
+        "argflow.configs",
+        "Crypto configurations found",
+        report.total_configs,
+    );
+    telemetry.count(
+        "argflow.vulnerabilities",
+        "Merged govulncheck vulnerabilities",
+        report.vulnerabilities.len(),
+    );
+}
+
+/// Posts the run summary to the configured webhooks. Delivery failures are logged
+/// rather than returned so a webhook outage never fails the build.
+fn send_notifications(
+    config_path: &Path,
+    path: &Path,
+    report: &JsonOutput,
+    gate: Option<&policy::GateReport>,
+) -> Result<()> {
+    let config =
+        NotifyConfig::from_file(config_path).context("Failed to load notification config")?;
+    let root = scan_root(path);
+    let project = std::fs::canonicalize(&root)
+        .unwrap_or_else(|_| root.clone())
+        .file_name()
+        .map_or_else(
+            || root.display().to_string(),
+            |n| n.to_string_lossy().into_owned(),
+        );
+    let commit = git::head_commit(path);
+    let summary = match gate {
+        Some(gate) => NotificationSummary::from_gate(&project, commit, gate),
+        None => NotificationSummary::from_findings(&project, commit, &report.findings, &root),
+    };
+
+    let transport = match HttpTransport::new() {
+        Ok(transport) => transport,
+        Err(e) => {
+            warn!(error = %e, "could not create HTTP client; skipping notifications");
+            return Ok(());
+        }
+    };
+    for error in config.notify(&summary, &transport, &|key| std::env::var(key).ok()) {
+        warn!(error = %error, "failed to send notification");
+    }
+    Ok(())
+}
+
+/// Signs an in-toto statement about the written report and stores it next to the report.
+fn write_attestation(
+    args: &cli::Args,
+    key: &Path,
+    path: &Path,
+    ctx: &ScanContext,
+    config_hash: String,
+    digest: &str,
+    output_file: &Path,
+) -> Result<()> {
+    let signer = Signer::from_pem_file(key).context("Failed to load signing key")?;
+
+    let name = output_file
+        .file_name()
+        .map(|n| n.to_string_lossy().to_string())
+        .unwrap_or_default();
+    let statement = Statement::for_digest(
+        &name,
+        digest.to_string(),
+        ScanPredicate {
+            tool: ToolInfo::current(),
+            format: ctx.output_format.as_str().to_string(),
+            config_hash,
+            commit: git::head_commit(path),
+            generated_at: unix_now(),
+        },
+    );
+    let envelope = statement.sign(&signer);
+
+    let attestation_path = args.attestation.clone().unwrap_or_else(|| {
+        PathBuf::from(format!(
+            "{}.{}",
+            output_file.display(),
+            attestation::ATTESTATION_EXTENSION
+        ))
+    });
+    write_output(&serde_json::to_string(&envelope)?, Some(&attestation_path))?;
+    info!(path = %attestation_path.display(), "wrote signed attestation");
+    Ok(())
+}
+
+/// Settings that determine the findings, hashed into the config hash.
+fn scan_settings(
+    args: &cli::Args,
+    ctx: &ScanContext,
+    language: cli::Language,
+) -> BTreeMap<&'static str, String> {
+    let mut settings = BTreeMap::from([
+        ("language", language.as_str().to_string()),
+        ("presets", args.preset.join(",")),
+        ("include_deps", args.include_deps.to_string()),
+        (
+            "go_version",
+            ctx.go_version.map(|v| v.to_string()).unwrap_or_default(),
+        ),
+        (
+            "compat",
+            args.compat.map(|c| format!("{c:?}")).unwrap_or_default(),
+        ),
+        (
+            "wrapper_attribution",
+            args.wrapper_attribution.as_str().to_string(),
+        ),
+    ]);
+    // Only present when set, so hashes of runs without them are unchanged
+    if args.go_generate {
+        settings.insert(
+            "go_generate",
+            args.go_generate_run.clone().unwrap_or_default(),
+        );
+    }
+    if let Some(overlay) = &args.overlay {
+        settings.insert("overlay", overlay.display().to_string());
+    }
+    settings
+}
+
+/// Records what the scan of `path` needs to be reproduced.
+fn scan_manifest(
+    path: &Path,
+    language: cli::Language,
+    ctx: &ScanContext,
+    args: &cli::Args,
+) -> Result<ScanManifest> {
+    let requires = match language {
+        cli::Language::Go => PackageVersions::detect(path, ctx.go_version).requires,
+        _ => Vec::new(),
+    };
+    let mut catalogs = ctx.preset_paths.to_vec();
+    catalogs.extend(args.rules.clone());
+    let mut manifest = ScanManifest::new(
+        std::env::args().skip(1).collect(),
+        &scan_settings(args, ctx, language),
+        &catalogs,
+        &requires,
+    )
+    .context("Failed to hash scan configuration")?;
+    manifest.commit = git::head_commit(path);
+    if language == cli::Language::Go {
+        manifest.go_toolchain = ctx.go_env.toolchain_version(&scan_root(path));
+    }
+    Ok(manifest)
+}
+
+/// Rewrites report paths relative to `root`.
+fn relativize_paths(report: &mut JsonOutput, root: &Path) {
+    for finding in &mut report.findings {
+        finding.file = policy::relative_path(&finding.file, root);
+        let constants = finding
+            .agility
+            .iter_mut()
+            .flat_map(|a| a.constants.values_mut());
+        for constant in constants.flatten() {
+            constant.package = policy::relative_path(&constant.package, root);
+        }
+    }
+    for config in &mut report.configs {
+        config.file = policy::relative_path(&config.file, root);
+    }
+    for package in &mut report.packages {
+        package.package = policy::relative_path(&package.package, root);
+    }
+    for mismatch in &mut report.key_mismatches {
+        mismatch.file = policy::relative_path(&mismatch.file, root);
+    }
+    for overflow in &mut report.nonce_overflows {
+        overflow.file = policy::relative_path(&overflow.file, root);
+    }
+    for usage in &mut report.constant_usage {
+        usage.package = policy::relative_path(&usage.package, root);
+        for sink in &mut usage.sinks {
+            sink.file = policy::relative_path(&sink.file, root);
+        }
+    }
+    // Labels name the package, so operations are regrouped from the relative paths
+    report.operations = CryptoOperation::group(&report.findings);
+    report.duplicate_operations = DuplicateOperation::detect(&report.findings);
+}
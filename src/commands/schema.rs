@@ -0,0 +1,15 @@
+//! Prints the bundled JSON schemas.
+
+use argflow::cli;
+use argflow::schema;
+
+pub fn print(args: &cli::SchemaArgs) {
+    match args.name.as_deref().and_then(schema::find) {
+        Some(schema) => print!("{}", schema.content),
+        None => {
+            for schema in schema::SCHEMAS {
+                println!("{:<10} {}", schema.name, schema.description);
+            }
+        }
+    }
+}
@@ -0,0 +1,41 @@
+//! Shows how findings change under value overrides.
+
+use anyhow::{Context as AnyhowContext, Result};
+use argflow::cli;
+use argflow::output::{JsonOutput, Redactions};
+use argflow::policy::{GateOptions, Policy};
+use argflow::simulate::SimulationReport;
+use std::path::Path;
+use tracing::info;
+
+use super::scan_root;
+
+/// Compares the scan with one resolving the `--set` overrides and prints what changes.
+pub fn run(
+    root: &Path,
+    report: &JsonOutput,
+    simulated: &JsonOutput,
+    redactions: &Redactions,
+    args: &cli::SimulateArgs,
+) -> Result<()> {
+    let mut simulation =
+        SimulationReport::compare(&args.set, &report.findings, &simulated.findings);
+    info!(changes = simulation.changes.len(), "simulated overrides");
+
+    if let Some(path) = &args.policy {
+        let policy = Policy::from_file(path).context("Failed to load policy")?;
+        let options = GateOptions {
+            root: scan_root(root),
+            ..Default::default()
+        };
+        simulation.evaluate_policy(&policy, &report.findings, &simulated.findings, &options);
+    }
+    simulation.redact(redactions);
+
+    if args.json {
+        println!("{}", serde_json::to_string_pretty(&simulation)?);
+    } else {
+        print!("{}", simulation.render_text());
+    }
+    Ok(())
+}
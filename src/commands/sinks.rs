@@ -0,0 +1,51 @@
+//! Verifies sink argument roles against package signatures.
+
+use anyhow::Result;
+use argflow::cli;
+use argflow::discovery::languages::go::{deps, GoEnv};
+use argflow::sinks::{PackageOrigin, PackageVersions, SinkVerification};
+use tracing::{info, warn};
+
+use super::{get_preset_paths, load_classifier};
+
+/// Checks the argument roles of every loaded sink against the signatures of the package
+/// versions the module at `--path` builds against.
+pub fn run(args: &cli::Args, sinks_args: &cli::SinksArgs) -> Result<()> {
+    let cli::SinksCommand::Verify(verify_args) = &sinks_args.action;
+    let mut classifier = load_classifier(args, &get_preset_paths(args)?)?;
+    let go_env = GoEnv {
+        offline: args.offline,
+        proxy: args.goproxy.clone(),
+    };
+    let versions = PackageVersions::detect(args.scan_path()?, args.go_version);
+    classifier.select_parameter_versions(|key| versions.version_of(key));
+
+    let verification =
+        SinkVerification::verify(classifier.get_parameter_roles(), &versions, |origin| {
+            let root = match origin {
+                PackageOrigin::Stdlib => deps::goroot_src(&go_env),
+                PackageOrigin::Module(require) => {
+                    deps::download_module(&require.path, &require.version, &go_env)
+                }
+            };
+            root.inspect_err(|e| warn!(error = %e, "cannot load sink package source"))
+                .ok()
+        });
+    info!(
+        checked = verification.checked,
+        drift = verification.drift.len(),
+        "verified sink signatures"
+    );
+    if verify_args.json {
+        println!("{}", serde_json::to_string_pretty(&verification)?);
+    } else {
+        print!("{}", verification.render_text());
+    }
+    if !verification.drift.is_empty() {
+        anyhow::bail!(
+            "{} sink signature(s) drifted from the catalog",
+            verification.drift.len()
+        );
+    }
+    Ok(())
+}
@@ -0,0 +1,330 @@
+//! Reconciliation of a scan with another tool's SARIF results.
+
+use std::collections::BTreeSet;
+use std::fmt::Write as _;
+use std::path::Path;
+
+use serde::{Deserialize, Serialize};
+
+use crate::output::Finding;
+use crate::policy::relative_path;
+
+/// A result of the other tool, at the start of its first location.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct ExternalResult {
+    pub tool: String,
+    pub rule: String,
+    /// Path relative to the scan root.
+    pub file: String,
+    pub line: usize,
+    /// Last line of the result's region; equals `line` for one-line results.
+    pub end_line: usize,
+    pub message: String,
+}
+
+/// A call both tools report.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct MatchedFinding {
+    pub file: String,
+    pub line: usize,
+    pub function: String,
+    /// Rules of the other tool's results at the call.
+    pub rules: Vec<String>,
+}
+
+/// A call only argflow reports.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct UnmatchedFinding {
+    pub file: String,
+    pub line: usize,
+    pub function: String,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct Comparison {
+    /// Tools named by the SARIF runs.
+    pub tools: Vec<String>,
+    pub both: Vec<MatchedFinding>,
+    pub only_argflow: Vec<UnmatchedFinding>,
+    pub only_other: Vec<ExternalResult>,
+}
+
+#[derive(Deserialize)]
+struct SarifLog {
+    #[serde(default)]
+    runs: Vec<SarifRun>,
+}
+
+#[derive(Deserialize)]
+struct SarifRun {
+    tool: SarifTool,
+    #[serde(default)]
+    results: Vec<SarifResult>,
+}
+
+#[derive(Deserialize)]
+struct SarifTool {
+    driver: SarifDriver,
+}
+
+#[derive(Deserialize)]
+struct SarifDriver {
+    name: String,
+}
+
+#[derive(Deserialize)]
+#[serde(rename_all = "camelCase")]
+struct SarifResult {
+    rule_id: Option<String>,
+    #[serde(default)]
+    message: SarifMessage,
+    #[serde(default)]
+    locations: Vec<SarifLocation>,
+}
+
+#[derive(Default, Deserialize)]
+struct SarifMessage {
+    text: Option<String>,
+}
+
+#[derive(Deserialize)]
+#[serde(rename_all = "camelCase")]
+struct SarifLocation {
+    physical_location: Option<SarifPhysicalLocation>,
+}
+
+#[derive(Deserialize)]
+#[serde(rename_all = "camelCase")]
+struct SarifPhysicalLocation {
+    artifact_location: SarifArtifactLocation,
+    region: Option<SarifRegion>,
+}
+
+#[derive(Deserialize)]
+struct SarifArtifactLocation {
+    uri: String,
+}
+
+#[derive(Deserialize)]
+#[serde(rename_all = "camelCase")]
+struct SarifRegion {
+    start_line: Option<usize>,
+    end_line: Option<usize>,
+}
+
+/// The results of every run in a SARIF log, with paths made relative to `root`.
+/// Results without a file location, such as whole-project ones, are skipped.
+pub fn parse_sarif(content: &str, root: &Path) -> serde_json::Result<Vec<ExternalResult>> {
+    let log: SarifLog = serde_json::from_str(content)?;
+    let mut results = Vec::new();
+    for run in log.runs {
+        for result in run.results {
+            let Some(location) = result
+                .locations
+                .into_iter()
+                .find_map(|location| location.physical_location)
+            else {
+                continue;
+            };
+            let region = location.region.as_ref();
+            let line = region.and_then(|r| r.start_line).unwrap_or(1);
+            results.push(ExternalResult {
+                tool: run.tool.driver.name.clone(),
+                rule: result.rule_id.unwrap_or_default(),
+                file: sarif_path(&location.artifact_location.uri, root),
+                line,
+                end_line: region.and_then(|r| r.end_line).unwrap_or(line).max(line),
+                message: result.message.text.unwrap_or_default(),
+            });
+        }
+    }
+    Ok(results)
+}
+
+/// `uri` relative to `root`. Relative URIs are taken to be relative to the root already.
+fn sarif_path(uri: &str, root: &Path) -> String {
+    let path = uri.strip_prefix("file://").unwrap_or(uri);
+    if Path::new(path).is_absolute() {
+        relative_path(path, root)
+    } else {
+        path.trim_start_matches("./").replace('\\', "/")
+    }
+}
+
+/// Whether `rule` is selected by `patterns`: exact ids, or prefixes ending in `*` such
+/// as `G4*`. No patterns select every rule.
+pub fn rule_selected(rule: &str, patterns: &[String]) -> bool {
+    patterns.is_empty()
+        || patterns
+            .iter()
+            .any(|pattern| match pattern.strip_suffix('*') {
+                Some(prefix) => rule.starts_with(prefix),
+                None => rule == pattern,
+            })
+}
+
+impl Comparison {
+    pub fn build(findings: &[Finding], results: Vec<ExternalResult>, root: &Path) -> Self {
+        let tools: BTreeSet<String> = results.iter().map(|r| r.tool.clone()).collect();
+        let mut matched = vec![false; results.len()];
+        let mut both = Vec::new();
+        let mut only_argflow = Vec::new();
+
+        for finding in findings {
+            let file = relative_path(&finding.file, root);
+            let mut rules = BTreeSet::new();
+            for (index, result) in results.iter().enumerate() {
+                if result.file == file && (result.line..=result.end_line).contains(&finding.line) {
+                    matched[index] = true;
+                    rules.insert(result.rule.clone());
+                }
+            }
+            if rules.is_empty() {
+                only_argflow.push(UnmatchedFinding {
+                    file,
+                    line: finding.line,
+                    function: finding.full_name.clone(),
+                });
+            } else {
+                both.push(MatchedFinding {
+                    file,
+                    line: finding.line,
+                    function: finding.full_name.clone(),
+                    rules: rules.into_iter().collect(),
+                });
+            }
+        }
+
+        let only_other = results
+            .into_iter()
+            .zip(matched)
+            .filter(|(_, matched)| !matched)
+            .map(|(result, _)| result)
+            .collect();
+        Comparison {
+            tools: tools.into_iter().collect(),
+            both,
+            only_argflow,
+            only_other,
+        }
+    }
+
+    pub fn render_text(&self) -> String {
+        let other = match self.tools.as_slice() {
+            [] => "the other tool".to_string(),
+            tools => tools.join(", "),
+        };
+        let mut out = String::new();
+        let _ = writeln!(out, "{} call(s) found by both:", self.both.len());
+        for finding in &self.both {
+            let _ = writeln!(
+                out,
+                "  {}:{} {} ({})",
+                finding.file,
+                finding.line,
+                finding.function,
+                finding.rules.join(", ")
+            );
+        }
+        let _ = writeln!(
+            out,
+            "{} call(s) only argflow found:",
+            self.only_argflow.len()
+        );
+        for finding in &self.only_argflow {
+            let _ = writeln!(
+                out,
+                "  {}:{} {}",
+                finding.file, finding.line, finding.function
+            );
+        }
+        let _ = writeln!(
+            out,
+            "{} result(s) only {other} found:",
+            self.only_other.len()
+        );
+        for result in &self.only_other {
+            let _ = writeln!(
+                out,
+                "  {}:{} {}: {}",
+                result.file, result.line, result.rule, result.message
+            );
+        }
+        out
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    const GOSEC: &str = r#"{
+  "version": "2.1.0",
+  "runs": [{
+    "tool": {"driver": {"name": "gosec"}},
+    "results": [
+      {
+        "ruleId": "G401",
+        "message": {"text": "Use of weak cryptographic primitive"},
+        "locations": [{"physicalLocation": {
+          "artifactLocation": {"uri": "internal/cache/key.go"},
+          "region": {"startLine": 12, "endLine": 12}
+        }}]
+      },
+      {
+        "ruleId": "G404",
+        "message": {"text": "Use of weak random number generator (math/rand instead of crypto/rand)"},
+        "locations": [{"physicalLocation": {
+          "artifactLocation": {"uri": "./internal/token/token.go"},
+          "region": {"startLine": 30}
+        }}]
+      },
+      {
+        "ruleId": "G104",
+        "message": {"text": "Errors unhandled."},
+        "locations": [{"physicalLocation": {
+          "artifactLocation": {"uri": "main.go"},
+          "region": {"startLine": 5}
+        }}]
+      }
+    ]
+  }]
+}"#;
+
+    fn finding(file: &str, line: usize, full_name: &str) -> Finding {
+        Finding::call(&format!("/repo/{file}"), line, 2, full_name)
+    }
+
+    #[test]
+    fn test_reconcile_gosec_sarif() {
+        let root = Path::new("/repo");
+        let results: Vec<ExternalResult> = parse_sarif(GOSEC, root)
+            .unwrap()
+            .into_iter()
+            .filter(|result| rule_selected(&result.rule, &["G4*".to_string()]))
+            .collect();
+        assert_eq!(results.len(), 2);
+        assert_eq!(results[1].file, "internal/token/token.go");
+
+        let findings = [
+            finding("internal/cache/key.go", 12, "crypto/md5.Sum"),
+            finding("internal/kdf/kdf.go", 20, "golang.org/x/crypto/pbkdf2.Key"),
+        ];
+        let comparison = Comparison::build(&findings, results, root);
+        assert_eq!(comparison.tools, vec!["gosec"]);
+        assert_eq!(
+            comparison.both,
+            vec![MatchedFinding {
+                file: "internal/cache/key.go".to_string(),
+                line: 12,
+                function: "crypto/md5.Sum".to_string(),
+                rules: vec!["G401".to_string()],
+            }]
+        );
+        assert_eq!(comparison.only_argflow[0].line, 20);
+        assert_eq!(comparison.only_other[0].rule, "G404");
+        assert!(comparison
+            .render_text()
+            .contains("1 result(s) only gosec found:\n  internal/token/token.go:30 G404"));
+    }
+}
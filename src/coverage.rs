@@ -0,0 +1,274 @@
+//! Sink catalog coverage of the packages a Go tree imports.
+
+use std::collections::{BTreeMap, BTreeSet, HashMap};
+use std::fmt::Write as _;
+use std::path::Path;
+
+use serde::Serialize;
+
+use crate::discovery::languages::go::{GoWorkspace, PackageGraph};
+use crate::engine::ImportEquivalences;
+use crate::output::Finding;
+
+/// Words of an import path marking a crypto package, compared against its segments split
+/// at `/`, `-`, `_` and `.`. A segment containing `crypto` always counts.
+const CRYPTO_WORDS: &[&str] = &[
+    "aes",
+    "argon2",
+    "bcrypt",
+    "blake2b",
+    "blake3",
+    "chacha20",
+    "chacha20poly1305",
+    "cipher",
+    "cose",
+    "curve25519",
+    "ecdsa",
+    "ed25519",
+    "gpg",
+    "hkdf",
+    "hmac",
+    "hsm",
+    "jose",
+    "jwe",
+    "jwk",
+    "jws",
+    "jwt",
+    "jwx",
+    "kms",
+    "kyber",
+    "md5",
+    "mlkem",
+    "nacl",
+    "openpgp",
+    "paseto",
+    "pbkdf2",
+    "pgp",
+    "pkcs11",
+    "pkcs12",
+    "pkcs7",
+    "pkcs8",
+    "poly1305",
+    "rsa",
+    "scrypt",
+    "secretbox",
+    "sha1",
+    "sha256",
+    "sha3",
+    "sha512",
+    "ssh",
+    "tink",
+    "tls",
+    "x25519",
+    "x509",
+];
+
+#[derive(Debug, Clone, Serialize)]
+pub struct CoverageReport {
+    /// Imported packages with sink definitions.
+    pub covered: Vec<ImportCoverage>,
+    /// Imported packages that look like crypto but have no sink definitions.
+    pub uncovered: Vec<ImportCoverage>,
+}
+
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct ImportCoverage {
+    pub import_path: String,
+    /// Directories of the packages importing it, relative to the scan root.
+    pub imported_by: Vec<String>,
+    /// Functions the catalogs map in the package.
+    pub sinks: usize,
+    /// Findings of the scan in calls of the package.
+    pub findings: usize,
+}
+
+impl CoverageReport {
+    /// Compares the packages imported under `root` with the sinks in `mappings`, keyed by
+    /// import path, counting `findings` per package.
+    pub fn build(
+        root: &Path,
+        mappings: &HashMap<String, HashMap<String, String>>,
+        equivalences: &ImportEquivalences,
+        findings: &[Finding],
+    ) -> Self {
+        let root = root.canonicalize().unwrap_or_else(|_| root.to_path_buf());
+        let workspace = GoWorkspace::module(&root);
+        let graph = PackageGraph::load(&root, workspace.as_ref());
+        let local: BTreeSet<&str> = graph
+            .packages()
+            .filter_map(|package| package.import_path.as_deref())
+            .collect();
+
+        let mut importers: BTreeMap<&str, BTreeSet<String>> = BTreeMap::new();
+        for package in graph.packages() {
+            let dir = match package.dir.strip_prefix(&root) {
+                Ok(relative) if relative.as_os_str().is_empty() => ".".to_string(),
+                Ok(relative) => relative.to_string_lossy().replace('\\', "/"),
+                Err(_) => package.dir.to_string_lossy().into_owned(),
+            };
+            for import in &package.imports {
+                if !local.contains(import.as_str()) {
+                    importers
+                        .entry(import.as_str())
+                        .or_default()
+                        .insert(dir.clone());
+                }
+            }
+        }
+
+        let mut report = CoverageReport {
+            covered: Vec::new(),
+            uncovered: Vec::new(),
+        };
+        for (import_path, imported_by) in importers {
+            let canonical = equivalences.canonical(import_path);
+            let sinks = mappings.get(&canonical).map_or(0, HashMap::len);
+            let coverage = ImportCoverage {
+                import_path: import_path.to_string(),
+                imported_by: imported_by.into_iter().collect(),
+                sinks,
+                findings: findings
+                    .iter()
+                    .filter(|finding| {
+                        finding
+                            .import_path
+                            .as_deref()
+                            .is_some_and(|path| path == import_path || path == canonical)
+                    })
+                    .count(),
+            };
+            if sinks > 0 {
+                report.covered.push(coverage);
+            } else if looks_like_crypto(import_path) {
+                report.uncovered.push(coverage);
+            }
+        }
+        report
+    }
+
+    pub fn render_text(&self) -> String {
+        let mut out = String::new();
+        let _ = writeln!(
+            out,
+            "{} imported package(s) covered by sink catalogs:",
+            self.covered.len()
+        );
+        for coverage in &self.covered {
+            let _ = writeln!(
+                out,
+                "  {}: {} sink(s), {} finding(s), imported by {}",
+                coverage.import_path,
+                coverage.sinks,
+                coverage.findings,
+                coverage.imported_by.join(", ")
+            );
+        }
+        if self.uncovered.is_empty() {
+            let _ = writeln!(out, "No crypto-looking import lacks sink definitions");
+            return out;
+        }
+        let _ = writeln!(
+            out,
+            "{} crypto-looking package(s) without sink definitions; their calls are not reported:",
+            self.uncovered.len()
+        );
+        for coverage in &self.uncovered {
+            let _ = writeln!(
+                out,
+                "  {}: imported by {}",
+                coverage.import_path,
+                coverage.imported_by.join(", ")
+            );
+        }
+        out
+    }
+}
+
+fn looks_like_crypto(import_path: &str) -> bool {
+    import_path
+        .split(['/', '-', '_', '.'])
+        .map(str::to_lowercase)
+        .any(|segment| segment.contains("crypto") || CRYPTO_WORDS.contains(&segment.as_str()))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    #[test]
+    fn test_crypto_looking_paths() {
+        for path in [
+            "github.com/acme/jose-fork/jwt",
+            "github.com/acme/gocryptoutil",
+            "filippo.io/age/armor/x25519",
+            "github.com/ProtonMail/go-crypto/openpgp",
+        ] {
+            assert!(looks_like_crypto(path), "{path}");
+        }
+        for path in ["fmt", "github.com/spf13/cobra", "example.com/rsvp"] {
+            assert!(!looks_like_crypto(path), "{path}");
+        }
+    }
+
+    #[test]
+    fn test_imports_without_sinks_are_blind_spots() {
+        let dir = TempDir::new().unwrap();
+        let root = dir.path();
+        std::fs::write(root.join("go.mod"), "module example.com/app\n\ngo 1.22\n").unwrap();
+        std::fs::write(
+            root.join("main.go"),
+            "package main\n\nimport (\n\t\"crypto/aes\"\n\t\"fmt\"\n\t\"example.com/app/internal/keys\"\n\t\"github.com/acme/jose-fork/jwt\"\n)\n\nfunc main() {}\n",
+        )
+        .unwrap();
+        std::fs::create_dir_all(root.join("internal/keys")).unwrap();
+        std::fs::write(
+            root.join("internal/keys/keys.go"),
+            "package keys\n\nimport \"example.com/app/third_party/pbkdf2\"\n",
+        )
+        .unwrap();
+
+        let mappings = HashMap::from([
+            (
+                "crypto/aes".to_string(),
+                HashMap::from([("NewCipher".to_string(), "aes".to_string())]),
+            ),
+            (
+                "golang.org/x/crypto/pbkdf2".to_string(),
+                HashMap::from([("Key".to_string(), "pbkdf2".to_string())]),
+            ),
+        ]);
+        let equivalences = ImportEquivalences::new(vec![(
+            "example.com/app/third_party/pbkdf2".to_string(),
+            "golang.org/x/crypto/pbkdf2".to_string(),
+        )]);
+
+        let report = CoverageReport::build(root, &mappings, &equivalences, &[]);
+        assert_eq!(
+            report.covered,
+            vec![
+                ImportCoverage {
+                    import_path: "crypto/aes".to_string(),
+                    imported_by: vec![".".to_string()],
+                    sinks: 1,
+                    findings: 0,
+                },
+                ImportCoverage {
+                    import_path: "example.com/app/third_party/pbkdf2".to_string(),
+                    imported_by: vec!["internal/keys".to_string()],
+                    sinks: 1,
+                    findings: 0,
+                },
+            ]
+        );
+        assert_eq!(
+            report.uncovered,
+            vec![ImportCoverage {
+                import_path: "github.com/acme/jose-fork/jwt".to_string(),
+                imported_by: vec![".".to_string()],
+                sinks: 0,
+                findings: 0,
+            }]
+        );
+    }
+}
@@ -0,0 +1,270 @@
+//! Crypto usage of two versions of a dependency, for `argflow dep-diff`.
+
+use std::collections::{BTreeMap, BTreeSet};
+use std::fmt::Write as _;
+
+use serde::Serialize;
+use serde_json::Value;
+
+use crate::output::Finding;
+use crate::simulate::ParameterChange;
+
+/// A crypto call present in only one of the versions.
+#[derive(Debug, Clone, Serialize)]
+pub struct CallChange {
+    pub file: String,
+    pub line: usize,
+    pub column: usize,
+    pub function: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub algorithm: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub enclosing_function: Option<String>,
+    pub parameters: BTreeMap<String, Value>,
+}
+
+impl CallChange {
+    fn of(finding: &Finding) -> Self {
+        CallChange {
+            file: finding.file.clone(),
+            line: finding.line,
+            column: finding.column,
+            function: finding.full_name.clone(),
+            algorithm: finding.algorithm.clone(),
+            enclosing_function: finding.enclosing_function.clone(),
+            parameters: finding.parameters.clone(),
+        }
+    }
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct DepDiffReport {
+    pub module: String,
+    pub old_version: String,
+    pub new_version: String,
+    /// Algorithms only the new version uses.
+    pub algorithms_added: Vec<String>,
+    /// Algorithms only the old version uses.
+    pub algorithms_removed: Vec<String>,
+    pub added: Vec<CallChange>,
+    pub removed: Vec<CallChange>,
+    /// Arguments of calls in both versions whose values differ; locations are those of
+    /// the new version.
+    pub changed: Vec<ParameterChange>,
+}
+
+/// File, enclosing function, call and the call's position among identical keys.
+type CallKey<'a> = (&'a str, Option<&'a str>, &'a str, usize);
+
+impl DepDiffReport {
+    /// Compares the findings of the old version of `module` with those of the new one.
+    /// Paths in both must be relative to their module roots.
+    pub fn compare(
+        module: &str,
+        old_version: &str,
+        new_version: &str,
+        old: &[Finding],
+        new: &[Finding],
+    ) -> Self {
+        let (before, after) = (keyed(old), keyed(new));
+
+        let mut changed = Vec::new();
+        for (key, finding) in &after {
+            let Some(previous) = before.get(key) else {
+                continue;
+            };
+            let names: BTreeSet<&String> = previous
+                .parameters
+                .keys()
+                .chain(finding.parameters.keys())
+                .collect();
+            for name in names {
+                let was = previous.parameters.get(name).unwrap_or(&Value::Null);
+                let now = finding.parameters.get(name).unwrap_or(&Value::Null);
+                if was != now {
+                    changed.push(ParameterChange {
+                        file: finding.file.clone(),
+                        line: finding.line,
+                        column: finding.column,
+                        function: finding.full_name.clone(),
+                        parameter: name.clone(),
+                        before: was.clone(),
+                        after: now.clone(),
+                    });
+                }
+            }
+        }
+
+        let only = |a: &BTreeMap<CallKey, &Finding>, b: &BTreeMap<CallKey, &Finding>| {
+            let mut calls: Vec<&Finding> = a
+                .iter()
+                .filter(|(key, _)| !b.contains_key(*key))
+                .map(|(_, finding)| *finding)
+                .collect();
+            calls.sort_by(|x, y| x.report_order(y));
+            calls.into_iter().map(CallChange::of).collect()
+        };
+        let algorithms = |findings: &[Finding]| -> BTreeSet<String> {
+            findings
+                .iter()
+                .filter_map(|f| f.algorithm.clone())
+                .collect()
+        };
+        let (was, now) = (algorithms(old), algorithms(new));
+
+        DepDiffReport {
+            module: module.to_string(),
+            old_version: old_version.to_string(),
+            new_version: new_version.to_string(),
+            algorithms_added: now.difference(&was).cloned().collect(),
+            algorithms_removed: was.difference(&now).cloned().collect(),
+            added: only(&after, &before),
+            removed: only(&before, &after),
+            changed,
+        }
+    }
+
+    pub fn is_empty(&self) -> bool {
+        self.added.is_empty() && self.removed.is_empty() && self.changed.is_empty()
+    }
+
+    pub fn render_text(&self) -> String {
+        let mut out = String::new();
+        let _ = writeln!(
+            out,
+            "argflow dep-diff: {} {} -> {}",
+            self.module, self.old_version, self.new_version
+        );
+        if self.is_empty() {
+            let _ = writeln!(out, "\nNo crypto usage changes.");
+            return out;
+        }
+
+        for (marker, algorithms) in [
+            ("+", &self.algorithms_added),
+            ("-", &self.algorithms_removed),
+        ] {
+            if !algorithms.is_empty() {
+                let _ = writeln!(out, "\nAlgorithms {marker} {}", algorithms.join(", "));
+            }
+        }
+        for (heading, calls) in [
+            ("Added calls", &self.added),
+            ("Removed calls", &self.removed),
+        ] {
+            if calls.is_empty() {
+                continue;
+            }
+            let _ = writeln!(out, "\n{heading}:");
+            for call in calls {
+                let _ = writeln!(
+                    out,
+                    "  {}:{}:{} {}{}",
+                    call.file,
+                    call.line,
+                    call.column,
+                    call.function,
+                    call.algorithm
+                        .as_ref()
+                        .map(|a| format!(" ({a})"))
+                        .unwrap_or_default()
+                );
+            }
+        }
+        if !self.changed.is_empty() {
+            let _ = writeln!(out, "\nChanged arguments:");
+            for change in &self.changed {
+                let _ = writeln!(
+                    out,
+                    "  {}:{}:{} {} {}: {} -> {}",
+                    change.file,
+                    change.line,
+                    change.column,
+                    change.function,
+                    change.parameter,
+                    change.before,
+                    change.after
+                );
+            }
+        }
+        out
+    }
+}
+
+/// Findings by call key; the n-th identical call of a function is matched with the n-th
+/// in the other version, in line order.
+fn keyed(findings: &[Finding]) -> BTreeMap<CallKey<'_>, &Finding> {
+    let mut sorted: Vec<&Finding> = findings.iter().collect();
+    sorted.sort_by(|a, b| a.report_order(b));
+    let mut keyed = BTreeMap::new();
+    let mut seen: BTreeMap<(&str, Option<&str>, &str), usize> = BTreeMap::new();
+    for finding in sorted {
+        let call = (
+            finding.file.as_str(),
+            finding.enclosing_function.as_deref(),
+            finding.full_name.as_str(),
+        );
+        let index = seen.entry(call).or_default();
+        keyed.insert((call.0, call.1, call.2, *index), finding);
+        *index += 1;
+    }
+    keyed
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use serde_json::json;
+
+    fn call(line: usize, function: &str, algorithm: &str, parameters: Value) -> Finding {
+        Finding {
+            parameters: serde_json::from_value(parameters).unwrap(),
+            ..Finding::call("ssh/cipher.go", line, 3, function)
+                .with_algorithm(algorithm)
+                .in_function("newCipher")
+        }
+    }
+
+    #[test]
+    fn test_compare_versions() {
+        let old = [
+            call(10, "crypto/rc4.NewCipher", "RC4", json!({})),
+            call(20, "crypto/rsa.GenerateKey", "RSA", json!({"arg1": 1024})),
+        ];
+        // Everything moved down five lines; RC4 is gone, the key size grew, ChaCha20 is new
+        let new = [
+            call(25, "crypto/rsa.GenerateKey", "RSA", json!({"arg1": 2048})),
+            call(
+                31,
+                "golang.org/x/crypto/chacha20poly1305.New",
+                "ChaCha20-Poly1305",
+                json!({}),
+            ),
+        ];
+
+        let report =
+            DepDiffReport::compare("golang.org/x/crypto", "v0.20.0", "v0.27.0", &old, &new);
+        assert_eq!(report.algorithms_added, vec!["ChaCha20-Poly1305"]);
+        assert_eq!(report.algorithms_removed, vec!["RC4"]);
+        assert_eq!(report.added.len(), 1);
+        assert_eq!(report.added[0].line, 31);
+        assert_eq!(report.removed[0].function, "crypto/rc4.NewCipher");
+        assert_eq!(report.changed.len(), 1);
+        assert_eq!(report.changed[0].line, 25);
+        assert_eq!(report.changed[0].before, json!(1024));
+        assert_eq!(report.changed[0].after, json!(2048));
+
+        let text = report.render_text();
+        assert!(text.contains("Algorithms - RC4"), "{text}");
+        assert!(text.contains("arg1: 1024 -> 2048"), "{text}");
+    }
+
+    #[test]
+    fn test_moved_calls_are_unchanged() {
+        let old = [call(10, "crypto/sha256.Sum256", "SHA-256", json!({}))];
+        let new = [call(40, "crypto/sha256.Sum256", "SHA-256", json!({}))];
+        let report = DepDiffReport::compare("example.com/m", "v1.0.0", "v1.1.0", &old, &new);
+        assert!(report.is_empty());
+        assert!(report.render_text().contains("No crypto usage changes."));
+    }
+}
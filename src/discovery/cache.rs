@@ -1,6 +1,8 @@
 use std::collections::{HashMap, HashSet};
 use std::fs;
+use std::io::Write;
 use std::path::{Path, PathBuf};
+use std::sync::atomic::{AtomicU64, Ordering};
 use std::time::{Duration, SystemTime};
 
 use crate::cli::Language;
@@ -20,11 +22,18 @@ pub struct DiscoveryCache {
     file_hash_cache: HashMap<PathBuf, String>,
     detection_cache: HashMap<PathBuf, CacheEntry<Vec<Language>>>,
     cache_dir: PathBuf,
+    hits: AtomicU64,
+    misses: AtomicU64,
 }
 
 impl DiscoveryCache {
     pub fn new() -> Result<Self, CacheError> {
-        let cache_dir = Self::get_cache_dir()?;
+        Self::in_dir(Self::get_cache_dir()?)
+    }
+
+    /// A cache persisted under `cache_dir` instead of the user cache directory, so an
+    /// embedding service can give each analysis its own.
+    pub fn in_dir(cache_dir: PathBuf) -> Result<Self, CacheError> {
         fs::create_dir_all(&cache_dir).map_err(CacheError::Io)?;
 
         let mut cache = Self {
@@ -33,6 +42,8 @@ impl DiscoveryCache {
             file_hash_cache: HashMap::new(),
             detection_cache: HashMap::new(),
             cache_dir,
+            hits: AtomicU64::new(0),
+            misses: AtomicU64::new(0),
         };
 
         cache.load_from_disk()?;
@@ -40,6 +51,12 @@ impl DiscoveryCache {
     }
 
     fn get_cache_dir() -> Result<PathBuf, CacheError> {
+        if cfg!(windows) {
+            if let Some(local) = std::env::var_os("LOCALAPPDATA") {
+                return Ok(PathBuf::from(local).join("argflow").join("cache"));
+            }
+        }
+
         let home = std::env::var("HOME")
             .or_else(|_| std::env::var("USERPROFILE"))
             .map_err(|_| CacheError::CacheDir("Could not determine home directory".to_string()))?;
@@ -50,13 +67,28 @@ impl DiscoveryCache {
     }
 
     pub fn get_dependencies(&self, key: &str) -> Option<Vec<PathBuf>> {
-        self.dependency_cache.get(key).and_then(|entry| {
+        let found = self.dependency_cache.get(key).and_then(|entry| {
             if entry.expires_at > SystemTime::now() {
                 Some(entry.value.clone())
             } else {
                 None
             }
-        })
+        });
+        let counter = if found.is_some() {
+            &self.hits
+        } else {
+            &self.misses
+        };
+        counter.fetch_add(1, Ordering::Relaxed);
+        found
+    }
+
+    /// Dependency lookups answered from the cache, and those that were not.
+    pub fn dependency_stats(&self) -> (u64, u64) {
+        (
+            self.hits.load(Ordering::Relaxed),
+            self.misses.load(Ordering::Relaxed),
+        )
     }
 
     pub fn set_dependencies(&mut self, key: String, files: Vec<PathBuf>) {
@@ -163,7 +195,12 @@ impl DiscoveryCache {
         let content = serde_json::to_string_pretty(&cache_data)
             .map_err(|e| CacheError::Serialize(e.to_string()))?;
 
-        fs::write(&cache_file, content).map_err(CacheError::Io)?;
+        // Analyses sharing the directory may save at the same time; writing a temporary
+        // file and renaming it over the cache means readers never see a partial write
+        let mut temp = tempfile::NamedTempFile::new_in(&self.cache_dir).map_err(CacheError::Io)?;
+        temp.write_all(content.as_bytes()).map_err(CacheError::Io)?;
+        temp.persist(&cache_file)
+            .map_err(|e| CacheError::Io(e.error))?;
 
         Ok(())
     }
@@ -188,13 +225,17 @@ impl DiscoveryCache {
 }
 
 impl Default for DiscoveryCache {
+    /// Falls back to an empty cache under the temporary directory when the user cache
+    /// directory cannot be created, e.g. on a read-only home.
     fn default() -> Self {
         Self::new().unwrap_or_else(|_| Self {
             dependency_cache: HashMap::new(),
             stdlib_cache: HashMap::new(),
             file_hash_cache: HashMap::new(),
             detection_cache: HashMap::new(),
-            cache_dir: PathBuf::from("/tmp"),
+            cache_dir: std::env::temp_dir().join("argflow"),
+            hits: AtomicU64::new(0),
+            misses: AtomicU64::new(0),
         })
     }
 }
@@ -231,8 +272,10 @@ mod tests {
         let key = "test_project:go".to_string();
         let files: Vec<PathBuf> = vec![];
 
+        assert_eq!(cache.get_dependencies(&key), None);
         cache.set_dependencies(key.clone(), files.clone());
         assert_eq!(cache.get_dependencies(&key), Some(files));
+        assert_eq!(cache.dependency_stats(), (1, 1));
     }
 
     #[test]
@@ -245,4 +288,29 @@ mod tests {
         cache.set_stdlib(Language::Go, packages.clone());
         assert_eq!(cache.get_stdlib(Language::Go), Some(packages));
     }
+    #[test]
+    fn test_concurrent_saves_leave_a_whole_file() {
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        let dir = temp_dir.path().to_path_buf();
+        let threads: Vec<_> = (0..8)
+            .map(|_| {
+                let dir = dir.clone();
+                std::thread::spawn(move || {
+                    let cache = DiscoveryCache::in_dir(dir).unwrap();
+                    for _ in 0..20 {
+                        cache.save_to_disk().unwrap();
+                    }
+                })
+            })
+            .collect();
+        for thread in threads {
+            thread.join().unwrap();
+        }
+
+        let content = fs::read_to_string(dir.join("discovery-cache.json")).unwrap();
+        let saved: serde_json::Value = serde_json::from_str(&content).unwrap();
+        assert_eq!(saved["version"], "1.0");
+        // Only the cache file is left behind
+        assert_eq!(fs::read_dir(&dir).unwrap().count(), 1);
+    }
 }
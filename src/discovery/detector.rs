@@ -3,6 +3,7 @@ use std::path::Path;
 
 use crate::cli::Language;
 use crate::discovery::cache::{CacheError, DiscoveryCache};
+use crate::discovery::utils::canonical_path;
 
 pub struct LanguageDetector {
     cache: DiscoveryCache,
@@ -16,7 +17,7 @@ impl LanguageDetector {
     }
 
     pub fn detect(&mut self, root: &Path) -> Vec<Language> {
-        let root_path = root.canonicalize().unwrap_or_else(|_| root.to_path_buf());
+        let root_path = canonical_path(root);
 
         if let Some(cached) = self.cache.get_detection(&root_path) {
             return cached;
@@ -0,0 +1,252 @@
+use std::env;
+use std::fs;
+use std::path::{Component, Path, PathBuf};
+
+use crate::discovery::utils::canonical_path;
+use crate::scanner::ModuleRef;
+
+use super::config::{GOMODCACHE_ENV, GO_MODULE_CACHE_DIR, VENDOR_DIR, VENDOR_MODULES_FILE};
+use super::workspace::GoWorkspace;
+
+/// Works out which module, and which version of it, a scanned Go file belongs to.
+///
+/// Files in the module cache carry their version in the path (`.../pkg/mod/path@version/...`,
+/// or below `$GOMODCACHE` when it is set), vendored files are looked up in `vendor/modules.txt`, and anything else inside the
+/// workspace belongs to the main module, which has no version.
+#[derive(Debug, Default)]
+pub struct ModuleAttributor {
+    main_module: Option<(PathBuf, String)>,
+    vendored: Vec<(String, Option<String>)>,
+    module_cache: Option<PathBuf>,
+}
+
+impl ModuleAttributor {
+    pub fn new(workspace: Option<&GoWorkspace>) -> Self {
+        let module_cache = env::var_os(GOMODCACHE_ENV)
+            .filter(|dir| !dir.is_empty())
+            .map(|dir| canonical_path(Path::new(&dir)));
+        let Some(GoWorkspace::Module { root, module_path }) = workspace else {
+            return Self {
+                module_cache,
+                ..Self::default()
+            };
+        };
+
+        let vendored = fs::read_to_string(root.join(VENDOR_DIR).join(VENDOR_MODULES_FILE))
+            .map(|content| parse_vendor_modules(&content))
+            .unwrap_or_default();
+
+        Self {
+            main_module: Some((root.clone(), module_path.clone())),
+            vendored,
+            module_cache,
+        }
+    }
+
+    pub fn module_for_file(&self, path: &Path) -> Option<ModuleRef> {
+        if let Some((module, version)) = self.module_cache_entry(path) {
+            return Some(golang_module(module, Some(version)));
+        }
+
+        if let Some(import_path) = vendored_import_path(path) {
+            // Longest module path wins so nested modules are attributed correctly
+            let (module, version) = self
+                .vendored
+                .iter()
+                .filter(|(module, _)| is_within_module(&import_path, module))
+                .max_by_key(|(module, _)| module.len())?;
+            return Some(golang_module(module.clone(), version.clone()));
+        }
+
+        let (root, module) = self.main_module.as_ref()?;
+        let path = canonical_path(path);
+        path.starts_with(root)
+            .then(|| golang_module(module.clone(), None))
+    }
+}
+
+impl ModuleAttributor {
+    fn module_cache_entry(&self, path: &Path) -> Option<(String, String)> {
+        let in_cache = self.module_cache.as_ref().and_then(|cache| {
+            let path = canonical_path(path);
+            let relative = path.strip_prefix(cache).ok()?;
+            module_entry(&normal_components(relative))
+        });
+        in_cache.or_else(|| module_cache_entry(path))
+    }
+}
+
+/// `pkg:golang/<module>@<version>`; the version is omitted when unknown.
+pub fn golang_purl(module: &str, version: Option<&str>) -> String {
+    match version {
+        Some(version) => format!("pkg:golang/{module}@{version}"),
+        None => format!("pkg:golang/{module}"),
+    }
+}
+
+fn golang_module(path: String, version: Option<String>) -> ModuleRef {
+    ModuleRef {
+        purl: golang_purl(&path, version.as_deref()),
+        path,
+        version,
+    }
+}
+
+/// `$GOMODCACHE/github.com/!azure/sdk@v1.2.0/auth/key.go` -> (`github.com/Azure/sdk`, `v1.2.0`)
+fn module_cache_entry(path: &Path) -> Option<(String, String)> {
+    let parts = normal_components(path);
+    let cache_idx = parts
+        .windows(2)
+        .rposition(|pair| pair[0] == "pkg" && pair[1] == GO_MODULE_CACHE_DIR)?;
+    module_entry(&parts[cache_idx + 2..])
+}
+
+/// `github.com/!azure/sdk@v1.2.0/auth` -> (`github.com/Azure/sdk`, `v1.2.0`)
+fn module_entry(parts: &[String]) -> Option<(String, String)> {
+    let mut module_parts = Vec::new();
+    for part in parts {
+        if let Some((last, version)) = part.split_once('@') {
+            module_parts.push(last.to_string());
+            return Some((
+                unescape_module_path(&module_parts.join("/")),
+                unescape_module_path(version),
+            ));
+        }
+        module_parts.push(part.clone());
+    }
+    None
+}
+
+/// `.../vendor/golang.org/x/crypto/pbkdf2/pbkdf2.go` -> `golang.org/x/crypto/pbkdf2`
+fn vendored_import_path(path: &Path) -> Option<String> {
+    let parts = normal_components(path.parent()?);
+    let vendor_idx = parts.iter().rposition(|part| part == VENDOR_DIR)?;
+    let import_path = parts[vendor_idx + 1..].join("/");
+    (!import_path.is_empty()).then_some(import_path)
+}
+
+fn is_within_module(import_path: &str, module: &str) -> bool {
+    import_path == module
+        || import_path
+            .strip_prefix(module)
+            .is_some_and(|rest| rest.starts_with('/'))
+}
+
+/// Reads `# module version` lines from `vendor/modules.txt`.
+///
+/// Replaced modules are listed as `# old v1 => new v2`; the vendored code is the replacement's,
+/// so its version is used. Replacements by a local directory have no version.
+fn parse_vendor_modules(content: &str) -> Vec<(String, Option<String>)> {
+    content
+        .lines()
+        .filter_map(|line| line.strip_prefix("# "))
+        .filter_map(|line| {
+            let fields: Vec<&str> = line.split_whitespace().collect();
+            let version = match fields.as_slice() {
+                [_, "=>", _] | [_, _, "=>", _] => None,
+                [_, "=>", _, version] | [_, _, "=>", _, version] => Some(version.to_string()),
+                [_, version] => Some(version.to_string()),
+                _ => return None,
+            };
+            Some((fields[0].to_string(), version))
+        })
+        .collect()
+}
+
+/// The module cache escapes upper-case letters as `!` followed by the lower-case letter.
+fn unescape_module_path(escaped: &str) -> String {
+    let mut unescaped = String::with_capacity(escaped.len());
+    let mut chars = escaped.chars();
+    while let Some(c) = chars.next() {
+        match c {
+            '!' => unescaped.extend(chars.next().map(|next| next.to_ascii_uppercase())),
+            _ => unescaped.push(c),
+        }
+    }
+    unescaped
+}
+
+fn normal_components(path: &Path) -> Vec<String> {
+    path.components()
+        .filter_map(|c| match c {
+            Component::Normal(name) => Some(name.to_string_lossy().to_string()),
+            _ => None,
+        })
+        .collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    #[test]
+    fn test_module_cache_attribution() {
+        let attributor = ModuleAttributor::default();
+        let module = attributor
+            .module_for_file(Path::new(
+                "/home/dev/go/pkg/mod/github.com/!azure/azure-sdk@v1.2.0/auth/key.go",
+            ))
+            .unwrap();
+
+        assert_eq!(module.path, "github.com/Azure/azure-sdk");
+        assert_eq!(module.version.as_deref(), Some("v1.2.0"));
+        assert_eq!(module.purl, "pkg:golang/github.com/Azure/azure-sdk@v1.2.0");
+    }
+
+    #[test]
+    fn test_custom_module_cache_attribution() {
+        let temp_dir = TempDir::new().unwrap();
+        let cache = temp_dir.path();
+        let file = cache.join("golang.org/x/crypto@v0.21.0/pbkdf2/pbkdf2.go");
+        fs::create_dir_all(file.parent().unwrap()).unwrap();
+        fs::write(&file, "package pbkdf2\n").unwrap();
+
+        let attributor = ModuleAttributor {
+            module_cache: Some(canonical_path(cache)),
+            ..ModuleAttributor::default()
+        };
+        let module = attributor.module_for_file(&file).unwrap();
+        assert_eq!(module.path, "golang.org/x/crypto");
+        assert_eq!(module.version.as_deref(), Some("v0.21.0"));
+    }
+
+    #[test]
+    fn test_vendored_and_main_module_attribution() {
+        let temp_dir = TempDir::new().unwrap();
+        let root = temp_dir.path();
+        fs::write(root.join("go.mod"), "module github.com/example/app\n").unwrap();
+        fs::create_dir_all(root.join("vendor")).unwrap();
+        fs::write(
+            root.join("vendor/modules.txt"),
+            "# golang.org/x/crypto v0.21.0\n## explicit; go 1.18\ngolang.org/x/crypto/pbkdf2\n\
+             # github.com/old/lib v1.0.0 => github.com/new/lib v1.4.2\n",
+        )
+        .unwrap();
+
+        let workspace = GoWorkspace::module(root).unwrap();
+        let attributor = ModuleAttributor::new(Some(&workspace));
+
+        let vendored = attributor
+            .module_for_file(&root.join("vendor/golang.org/x/crypto/pbkdf2/pbkdf2.go"))
+            .unwrap();
+        assert_eq!(vendored.purl, "pkg:golang/golang.org/x/crypto@v0.21.0");
+
+        let replaced = attributor
+            .module_for_file(&root.join("vendor/github.com/old/lib/lib.go"))
+            .unwrap();
+        assert_eq!(replaced.version.as_deref(), Some("v1.4.2"));
+
+        let own = attributor
+            .module_for_file(&root.join("internal/keys.go"))
+            .unwrap();
+        assert_eq!(own.path, "github.com/example/app");
+        assert_eq!(own.version, None);
+        assert_eq!(own.purl, "pkg:golang/github.com/example/app");
+
+        assert_eq!(
+            attributor.module_for_file(Path::new("/usr/local/go/src/crypto/aes/aes.go")),
+            None
+        );
+    }
+}
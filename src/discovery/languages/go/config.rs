@@ -9,11 +9,69 @@ pub const INTERNAL_PREFIX: &str = "internal/";
 
 pub const GO_LIST_STD_ARGS: &[&str] = &["list", "std"];
 
-pub const GO_LIST_DEPS_ARGS: &[&str] = &["list", "-deps", "-f"];
+// `-e` keeps packages that fail to load (commonly cgo packages without a C toolchain)
+// in the listing so their Go files are still scanned.
+pub const GO_LIST_DEPS_ARGS: &[&str] = &["list", "-e", "-deps", "-f"];
 pub const GO_LIST_IMPORT_PATH_TEMPLATE: &str = "{{.ImportPath}}";
 pub const GO_LIST_PACKAGE_PATTERN: &str = "./...";
 
-pub const GO_LIST_DIR_ARGS: &[&str] = &["list", "-f"];
+pub const GO_LIST_DIR_ARGS: &[&str] = &["list", "-e", "-f"];
 pub const GO_LIST_DIR_TEMPLATE: &str = "{{.Dir}}";
 
+pub const GO_MOD_DOWNLOAD_ARGS: &[&str] = &["mod", "download", "-json"];
+
+pub const GO_ENV_GOROOT_ARGS: &[&str] = &["env", "GOROOT"];
+
+pub const GO_GENERATE_COMMAND: &str = "generate";
+pub const GO_GENERATE_RUN_FLAG: &str = "-run";
+
 pub const MAX_FILE_SIZE: u64 = 10 * 1024 * 1024;
+
+pub const GO_MOD_FILE: &str = "go.mod";
+
+pub const GO111MODULE_ENV: &str = "GO111MODULE";
+pub const GOPATH_ENV: &str = "GOPATH";
+pub const GOPATH_SRC_DIR: &str = "src";
+pub const GOPACKAGESDRIVER_ENV: &str = "GOPACKAGESDRIVER";
+pub const GOFLAGS_ENV: &str = "GOFLAGS";
+pub const GOPROXY_ENV: &str = "GOPROXY";
+pub const GOPROXY_OFF: &str = "off";
+pub const GOTOOLCHAIN_ENV: &str = "GOTOOLCHAIN";
+pub const GOTOOLCHAIN_LOCAL: &str = "local";
+pub const GO_MOD_FLAG: &str = "-mod";
+pub const GO_MOD_READONLY: &str = "readonly";
+/// `NeedName | NeedFiles | NeedImports | NeedDeps` from `golang.org/x/tools/go/packages`.
+pub const DRIVER_LOAD_MODE: u32 = 1 | 2 | 8 | 16;
+pub const VENDOR_DIR: &str = "vendor";
+pub const VENDOR_MODULES_FILE: &str = "modules.txt";
+pub const GO_MODULE_CACHE_DIR: &str = "mod";
+pub const GOMODCACHE_ENV: &str = "GOMODCACHE";
+
+/// Build files at the project root searched for `GOEXPERIMENT`/`GOFIPS140` settings.
+pub const FIPS_BUILD_FILES: &[&str] = &[
+    "Makefile",
+    "Dockerfile",
+    ".goreleaser.yml",
+    ".goreleaser.yaml",
+    "build.sh",
+];
+
+/// Code generator configs read for crypto settings, and the generator each belongs to.
+pub const GENERATOR_CONFIGS: &[(&str, &str)] = &[
+    ("sqlc.yaml", "sqlc"),
+    ("sqlc.yml", "sqlc"),
+    ("sqlc.json", "sqlc"),
+    ("buf.gen.yaml", "buf"),
+    ("buf.gen.yml", "buf"),
+];
+
+/// Standard library packages that only exist from a given Go release onward.
+/// Mappings for these import paths are dropped when the module targets an older version.
+pub const VERSIONED_STDLIB_PACKAGES: &[(&str, &str)] = &[
+    ("crypto/ecdh", "1.20"),
+    ("math/rand/v2", "1.22"),
+    ("crypto/hkdf", "1.24"),
+    ("crypto/mlkem", "1.24"),
+    ("crypto/pbkdf2", "1.24"),
+    ("crypto/sha3", "1.24"),
+];
@@ -1,6 +1,5 @@
 use std::collections::HashSet;
 use std::path::{Path, PathBuf};
-use std::process::Command;
 use std::str;
 use std::sync::OnceLock;
 
@@ -9,7 +8,10 @@ use crate::discovery::loader::LoadError;
 use crate::discovery::utils::walk_source_files;
 
 use super::config::*;
+use super::toolchain::GoEnv;
 
+// The standard library's packages are a fact of the installed toolchain, not of any one
+// analysis, so concurrent analyses share them.
 static STDLIB_CACHE: OnceLock<HashSet<String>> = OnceLock::new();
 
 fn get_stdlib_packages() -> &'static HashSet<String> {
@@ -21,7 +23,9 @@ fn get_stdlib_packages() -> &'static HashSet<String> {
 }
 
 fn query_go_stdlib() -> Result<HashSet<String>, LoadError> {
-    let output = Command::new(GO_COMMAND)
+    // Listing the standard library never reaches a module proxy
+    let output = GoEnv::default()
+        .command(true)
         .args(GO_LIST_STD_ARGS)
         .output()
         .map_err(|e| LoadError::PackageManager(format!("Failed to run 'go list std': {e}")))?;
@@ -68,6 +72,7 @@ fn is_known_stdlib_package(package_path: &str) -> bool {
 
 pub fn scan_dependencies_using_go_tooling(
     project_root: &Path,
+    go_env: &GoEnv,
     _cache: &mut DiscoveryCache,
 ) -> Result<Vec<(PathBuf, bool)>, LoadError> {
     let go_mod_path = project_root.join("go.mod");
@@ -76,19 +81,97 @@ pub fn scan_dependencies_using_go_tooling(
         return Ok(vec![]);
     }
 
-    let dependency_packages = get_dependency_packages(project_root)?;
+    let dependency_packages = get_dependency_packages(project_root, go_env, &[])?;
 
     if dependency_packages.is_empty() {
         return Ok(vec![]);
     }
 
-    resolve_package_paths_to_files(project_root, &dependency_packages)
+    resolve_package_paths_to_files(project_root, &dependency_packages, go_env, &[])
 }
 
-fn get_dependency_packages(project_root: &Path) -> Result<Vec<String>, LoadError> {
-    let output = Command::new(GO_COMMAND)
+/// Dependency discovery for pre-module projects: runs the same `go list` queries with
+/// module mode disabled, resolving imports against `gopath`.
+pub fn scan_dependencies_in_gopath(
+    project_root: &Path,
+    gopath: &Path,
+    go_env: &GoEnv,
+) -> Result<Vec<(PathBuf, bool)>, LoadError> {
+    let gopath = gopath.to_string_lossy();
+    let env = [(GO111MODULE_ENV, "off"), (GOPATH_ENV, gopath.as_ref())];
+
+    let dependency_packages = get_dependency_packages(project_root, go_env, &env)?;
+
+    if dependency_packages.is_empty() {
+        return Ok(vec![]);
+    }
+
+    resolve_package_paths_to_files(project_root, &dependency_packages, go_env, &env)
+}
+
+/// Downloads `module@version` into the module cache, or finds it there, and returns the
+/// directory of its source. `go_env` decides whether and where it may be fetched from.
+pub fn download_module(module: &str, version: &str, go_env: &GoEnv) -> Result<PathBuf, LoadError> {
+    let target = format!("{module}@{version}");
+    // Outside any module, so the download does not depend on the working directory
+    let output = go_env
+        .command(true)
+        .args(GO_MOD_DOWNLOAD_ARGS)
+        .arg(&target)
+        .current_dir(std::env::temp_dir())
+        .output()
+        .map_err(|e| LoadError::PackageManager(format!("Failed to run 'go mod download': {e}")))?;
+
+    // `-json` reports failures in the `Error` field and exits non-zero
+    let info: serde_json::Value = serde_json::from_slice(&output.stdout).unwrap_or_default();
+    if let Some(error) = info["Error"].as_str() {
+        return Err(LoadError::PackageManager(format!(
+            "go mod download {target} failed: {error}"
+        )));
+    }
+    if !output.status.success() {
+        let stderr = String::from_utf8_lossy(&output.stderr);
+        return Err(LoadError::PackageManager(format!(
+            "go mod download {target} failed: {}",
+            stderr.trim()
+        )));
+    }
+    info["Dir"].as_str().map(PathBuf::from).ok_or_else(|| {
+        LoadError::PackageManager(format!("go mod download {target} gave no directory"))
+    })
+}
+
+/// The standard library source root of the installed toolchain, `$(go env GOROOT)/src`.
+pub fn goroot_src(go_env: &GoEnv) -> Result<PathBuf, LoadError> {
+    let output = go_env
+        .command(false)
+        .args(GO_ENV_GOROOT_ARGS)
+        .output()
+        .map_err(|e| LoadError::PackageManager(format!("Failed to run 'go env GOROOT': {e}")))?;
+    let goroot = str::from_utf8(&output.stdout).unwrap_or_default().trim();
+    if !output.status.success() || goroot.is_empty() {
+        return Err(LoadError::PackageManager(
+            "go env GOROOT failed".to_string(),
+        ));
+    }
+    Ok(Path::new(goroot).join("src"))
+}
+
+/// Whether `env` leaves module mode on.
+fn module_mode(env: &[(&str, &str)]) -> bool {
+    !env.contains(&(GO111MODULE_ENV, "off"))
+}
+
+fn get_dependency_packages(
+    project_root: &Path,
+    go_env: &GoEnv,
+    env: &[(&str, &str)],
+) -> Result<Vec<String>, LoadError> {
+    let output = go_env
+        .command(module_mode(env))
         .args(GO_LIST_DEPS_ARGS)
         .args([GO_LIST_IMPORT_PATH_TEMPLATE, GO_LIST_PACKAGE_PATTERN])
+        .envs(env.iter().copied())
         .current_dir(project_root)
         .output()
         .map_err(|e| LoadError::PackageManager(format!("Failed to run 'go list': {e}")))?;
@@ -120,6 +203,8 @@ fn get_dependency_packages(project_root: &Path) -> Result<Vec<String>, LoadError
 fn resolve_package_paths_to_files(
     project_root: &Path,
     packages: &[String],
+    go_env: &GoEnv,
+    env: &[(&str, &str)],
 ) -> Result<Vec<(PathBuf, bool)>, LoadError> {
     let mut files = Vec::new();
     let mut processed = HashSet::new();
@@ -131,7 +216,7 @@ fn resolve_package_paths_to_files(
         processed.insert(package_path.to_string());
 
         let is_stdlib = is_stdlib_package(package_path);
-        if let Some(package_files) = get_package_files(project_root, package_path)? {
+        if let Some(package_files) = get_package_files(project_root, package_path, go_env, env)? {
             for file in package_files {
                 files.push((file, is_stdlib));
             }
@@ -144,10 +229,14 @@ fn resolve_package_paths_to_files(
 fn get_package_files(
     project_root: &Path,
     package_path: &str,
+    go_env: &GoEnv,
+    env: &[(&str, &str)],
 ) -> Result<Option<Vec<PathBuf>>, LoadError> {
-    let output = Command::new(GO_COMMAND)
+    let output = go_env
+        .command(module_mode(env))
         .args(GO_LIST_DIR_ARGS)
         .args([GO_LIST_DIR_TEMPLATE, package_path])
+        .envs(env.iter().copied())
         .current_dir(project_root)
         .output()
         .map_err(|e| {
@@ -0,0 +1,286 @@
+use std::collections::BTreeSet;
+use std::env;
+use std::io::Write;
+use std::path::{Path, PathBuf};
+use std::process::{Command, Stdio};
+
+use serde::Deserialize;
+
+use crate::cli::Language;
+use crate::discovery::cache::DiscoveryCache;
+use crate::discovery::loader::{LoadError, PackageLoader};
+use crate::discovery::{SourceFile, SourceType};
+
+use super::config::{DRIVER_LOAD_MODE, GOPACKAGESDRIVER_ENV, GO_LIST_PACKAGE_PATTERN};
+use super::loader::get_file_metadata;
+use super::workspace::GoWorkspace;
+
+/// An external `go/packages` driver, such as the `gopackagesdriver` of rules_go or
+/// Please, that reports the package graph of a build system without a `go.mod` layout.
+pub struct PackagesDriver {
+    program: PathBuf,
+}
+
+impl PackagesDriver {
+    pub fn new(program: PathBuf) -> Self {
+        Self { program }
+    }
+
+    /// The driver named by `$GOPACKAGESDRIVER`, unless it is unset or `off`.
+    pub fn from_env() -> Option<Self> {
+        env::var_os(GOPACKAGESDRIVER_ENV)
+            .filter(|value| !value.is_empty() && value != "off")
+            .map(|value| Self::new(PathBuf::from(value)))
+    }
+
+    pub fn program(&self) -> &Path {
+        &self.program
+    }
+
+    /// Lists every package under `root` and its dependencies. The request is written to
+    /// the driver's stdin and the patterns are passed as arguments, as `go/packages` does.
+    pub fn list(&self, root: &Path) -> Result<DriverResponse, LoadError> {
+        let request = serde_json::json!({
+            "Mode": DRIVER_LOAD_MODE,
+            "Env": [],
+            "BuildFlags": [],
+            "Tests": false,
+            "Overlay": {},
+        });
+        let failed = |e: &dyn std::fmt::Display| {
+            LoadError::PackageManager(format!(
+                "Failed to run packages driver {}: {e}",
+                self.program.display()
+            ))
+        };
+
+        let mut child = Command::new(&self.program)
+            .arg(GO_LIST_PACKAGE_PATTERN)
+            .current_dir(root)
+            .stdin(Stdio::piped())
+            .stdout(Stdio::piped())
+            .stderr(Stdio::piped())
+            .spawn()
+            .map_err(|e| failed(&e))?;
+        if let Some(mut stdin) = child.stdin.take() {
+            stdin
+                .write_all(request.to_string().as_bytes())
+                .map_err(|e| failed(&e))?;
+        }
+        let output = child.wait_with_output().map_err(|e| failed(&e))?;
+        if !output.status.success() {
+            let stderr = String::from_utf8_lossy(&output.stderr);
+            return Err(failed(&stderr.trim()));
+        }
+
+        let response: DriverResponse =
+            serde_json::from_slice(&output.stdout).map_err(|e| failed(&e))?;
+        if response.not_handled {
+            return Err(failed(&"the driver did not handle the request"));
+        }
+        Ok(response)
+    }
+}
+
+/// The `DriverResponse` of the `go/packages` driver protocol. Only the fields argflow
+/// uses are read.
+#[derive(Debug, Clone, Default, Deserialize)]
+pub struct DriverResponse {
+    #[serde(rename = "NotHandled", default)]
+    pub not_handled: bool,
+    /// IDs of the packages matching the requested patterns.
+    #[serde(rename = "Roots", default)]
+    pub roots: Vec<String>,
+    #[serde(rename = "Packages", default)]
+    pub packages: Vec<DriverPackage>,
+}
+
+#[derive(Debug, Clone, Default, Deserialize)]
+pub struct DriverPackage {
+    /// Build-system identifier, e.g. a Bazel label; equal to the import path for `go list`.
+    #[serde(rename = "ID")]
+    pub id: String,
+    #[serde(rename = "Name", default)]
+    pub name: String,
+    #[serde(rename = "PkgPath", default)]
+    pub pkg_path: String,
+    #[serde(rename = "GoFiles", default)]
+    pub go_files: Vec<PathBuf>,
+}
+
+impl DriverPackage {
+    /// rules_go labels its standard library packages `@io_bazel_rules_go//stdlib:...`;
+    /// other drivers use the import path as the ID, and standard library paths have no
+    /// dot in their first element.
+    fn is_stdlib(&self) -> bool {
+        self.id.contains("//stdlib")
+            || (self.id == self.pkg_path
+                && !self
+                    .pkg_path
+                    .split('/')
+                    .next()
+                    .is_some_and(|first| first.contains('.')))
+    }
+}
+
+/// Loader for Bazel and Please monorepos, where a `go/packages` driver rather than
+/// `go.mod` knows the package graph.
+///
+/// User code is the driver's root packages; dependencies are every other package it
+/// reports, including generated files that only exist in the build output tree.
+pub struct DriverPackageLoader {
+    response: DriverResponse,
+}
+
+impl DriverPackageLoader {
+    /// Runs `driver` over `root`.
+    pub fn load(driver: &PackagesDriver, root: &Path) -> Result<Self, LoadError> {
+        Ok(Self::from_response(driver.list(root)?))
+    }
+
+    pub fn from_response(response: DriverResponse) -> Self {
+        Self { response }
+    }
+
+    /// Import paths of the reported package directories.
+    pub fn workspace(&self) -> GoWorkspace {
+        GoWorkspace::packages(self.response.packages.iter().flat_map(|package| {
+            package
+                .go_files
+                .iter()
+                .filter_map(|file| file.parent())
+                .map(|dir| (dir.to_path_buf(), package.pkg_path.clone()))
+        }))
+    }
+
+    fn files(&self, roots: bool) -> Vec<SourceFile> {
+        let root_ids: BTreeSet<_> = self.response.roots.iter().collect();
+        let mut seen = BTreeSet::new();
+        let mut files = Vec::new();
+        for package in &self.response.packages {
+            if root_ids.contains(&package.id) != roots {
+                continue;
+            }
+            let source_type = if roots {
+                SourceType::UserCode
+            } else if package.is_stdlib() {
+                SourceType::Stdlib
+            } else {
+                SourceType::Dependency {
+                    package: package.pkg_path.clone(),
+                    version: None,
+                }
+            };
+            for path in &package.go_files {
+                if !seen.insert(path.clone()) {
+                    continue;
+                }
+                files.push(SourceFile {
+                    path: path.clone(),
+                    language: Language::Go,
+                    source_type: source_type.clone(),
+                    package: Some(package.pkg_path.clone()),
+                    metadata: get_file_metadata(path),
+                });
+            }
+        }
+        files
+    }
+}
+
+impl PackageLoader for DriverPackageLoader {
+    fn load_user_code(&self, _root: &Path) -> Result<Vec<SourceFile>, LoadError> {
+        Ok(self.files(true))
+    }
+
+    fn load_dependencies(
+        &self,
+        _root: &Path,
+        _cache: &mut DiscoveryCache,
+    ) -> Result<Vec<SourceFile>, LoadError> {
+        Ok(self.files(false))
+    }
+
+    fn language(&self) -> Language {
+        Language::Go
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    const RESPONSE: &str = r#"{
+        "Compiler": "gc",
+        "Arch": "amd64",
+        "Roots": ["@//services/auth:auth"],
+        "Packages": [
+            {
+                "ID": "@//services/auth:auth",
+                "Name": "auth",
+                "PkgPath": "corp.example/services/auth",
+                "GoFiles": ["/repo/services/auth/token.go", "/repo/services/auth/kdf.go"],
+                "CompiledGoFiles": ["/repo/services/auth/token.go", "/repo/services/auth/kdf.go"],
+                "Imports": {
+                    "crypto/sha256": "@io_bazel_rules_go//stdlib:crypto/sha256",
+                    "golang.org/x/crypto/pbkdf2": "@org_golang_x_crypto//pbkdf2:pbkdf2"
+                }
+            },
+            {
+                "ID": "@io_bazel_rules_go//stdlib:crypto/sha256",
+                "Name": "sha256",
+                "PkgPath": "crypto/sha256",
+                "GoFiles": ["/goroot/src/crypto/sha256/sha256.go"]
+            },
+            {
+                "ID": "@org_golang_x_crypto//pbkdf2:pbkdf2",
+                "Name": "pbkdf2",
+                "PkgPath": "golang.org/x/crypto/pbkdf2",
+                "GoFiles": ["/cache/external/org_golang_x_crypto/pbkdf2/pbkdf2.go"]
+            }
+        ]
+    }"#;
+
+    #[test]
+    fn test_driver_response_splits_user_code_and_dependencies() {
+        let response: DriverResponse = serde_json::from_str(RESPONSE).unwrap();
+        let loader = DriverPackageLoader::from_response(response);
+
+        let user: Vec<_> = loader
+            .load_user_code(Path::new("/repo"))
+            .unwrap()
+            .into_iter()
+            .map(|f| f.path)
+            .collect();
+        assert_eq!(
+            user,
+            vec![
+                PathBuf::from("/repo/services/auth/token.go"),
+                PathBuf::from("/repo/services/auth/kdf.go"),
+            ]
+        );
+
+        let deps = loader
+            .load_dependencies(Path::new("/repo"), &mut DiscoveryCache::default())
+            .unwrap();
+        assert!(matches!(deps[0].source_type, SourceType::Stdlib));
+        assert!(matches!(
+            &deps[1].source_type,
+            SourceType::Dependency { package, .. } if package == "golang.org/x/crypto/pbkdf2"
+        ));
+
+        let workspace = loader.workspace();
+        assert_eq!(
+            workspace.import_path_for_dir(Path::new("/repo/services/auth")),
+            Some("corp.example/services/auth".to_string())
+        );
+        assert_eq!(workspace.import_path_for_dir(Path::new("/repo")), None);
+    }
+
+    #[test]
+    fn test_not_handled_response() {
+        let response: DriverResponse = serde_json::from_str(r#"{"NotHandled": true}"#).unwrap();
+        assert!(response.not_handled);
+        assert!(response.packages.is_empty());
+    }
+}
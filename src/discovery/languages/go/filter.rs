@@ -23,6 +23,14 @@ impl GoImportFilter {
         Ok(Self { import_patterns })
     }
 
+    /// Also passes files importing these forks, whose calls are reported under the
+    /// upstream path they were declared equivalent to.
+    pub fn with_forks<'a>(mut self, forks: impl IntoIterator<Item = &'a str>) -> Self {
+        self.import_patterns
+            .extend(forks.into_iter().map(str::to_string));
+        self
+    }
+
     pub fn from_bundled() -> Result<Self, FilterError> {
         let preset_dir = Path::new(env!("CARGO_MANIFEST_DIR"))
             .join("presets")
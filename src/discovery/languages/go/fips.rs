@@ -0,0 +1,239 @@
+use std::fs;
+use std::path::Path;
+
+use tracing::debug;
+
+use crate::discovery::utils::walk_source_files;
+use crate::output::{FipsMode, FipsSignal, FipsSignalKind};
+
+use super::config::{EXCLUDED_DIRS, FILE_EXTENSIONS, FIPS_BUILD_FILES, VENDOR_DIR};
+use super::gomod::{find_go_mod, GoMod};
+
+const FIPS140_SETTING: &str = "fips140";
+const BORINGCRYPTO_EXPERIMENT: &str = "boringcrypto";
+const FIPSONLY_PACKAGE: &str = "crypto/tls/fipsonly";
+const FIPS140_PACKAGE: &str = "crypto/fips140";
+
+/// Collects FIPS signals from go.mod, build files and Go sources under `root`, and from
+/// the build environment as returned by `env`.
+pub fn detect_signals(root: &Path, env: &dyn Fn(&str) -> Option<String>) -> Vec<FipsSignal> {
+    let mut signals = Vec::new();
+    let display = |path: &Path| {
+        path.strip_prefix(root)
+            .unwrap_or(path)
+            .to_string_lossy()
+            .to_string()
+    };
+
+    if let Some(go_mod_path) = find_go_mod(root) {
+        if let Ok(go_mod) = GoMod::from_file(&go_mod_path) {
+            for (key, value) in &go_mod.godebug {
+                if key == FIPS140_SETTING {
+                    signals.push(godebug_signal(display(&go_mod_path), value));
+                }
+            }
+        }
+    }
+
+    if let Some(experiments) = env("GOEXPERIMENT") {
+        if experiments
+            .split(',')
+            .any(|e| e.trim() == BORINGCRYPTO_EXPERIMENT)
+        {
+            signals.push(goexperiment_signal("$GOEXPERIMENT".to_string()));
+        }
+    }
+    if let Some(version) = env("GOFIPS140").filter(|v| !v.is_empty() && v != "off") {
+        signals.push(gofips140_signal("$GOFIPS140".to_string(), &version));
+    }
+    if let Some(godebug) = env("GODEBUG") {
+        for setting in godebug.split(',') {
+            if let Some(value) = setting.trim().strip_prefix("fips140=") {
+                signals.push(godebug_signal("$GODEBUG".to_string(), value));
+            }
+        }
+    }
+
+    for name in FIPS_BUILD_FILES {
+        let path = root.join(name);
+        let Ok(content) = fs::read_to_string(&path) else {
+            continue;
+        };
+        for (index, line) in content.lines().enumerate() {
+            let source = format!("{}:{}", display(&path), index + 1);
+            if line.contains("GOEXPERIMENT=boringcrypto") {
+                signals.push(goexperiment_signal(source));
+            } else if let Some(version) = assignment(line, "GOFIPS140=") {
+                if version != "off" {
+                    signals.push(gofips140_signal(source, version));
+                }
+            }
+        }
+    }
+
+    let excluded: Vec<&str> = EXCLUDED_DIRS.iter().copied().chain([VENDOR_DIR]).collect();
+    let files = walk_source_files(root, FILE_EXTENSIONS[0], &excluded, false).unwrap_or_default();
+    for path in files {
+        let Ok(content) = fs::read_to_string(&path) else {
+            continue;
+        };
+        for (index, line) in content.lines().enumerate() {
+            let source = format!("{}:{}", display(&path), index + 1);
+            if let Some(signal) = source_signal(line.trim(), source) {
+                signals.push(signal);
+            }
+        }
+    }
+
+    debug!(count = signals.len(), "detected FIPS signals");
+    signals
+}
+
+fn source_signal(line: &str, source: String) -> Option<FipsSignal> {
+    if let Some(constraint) = line.strip_prefix("//go:build ") {
+        let mentions_boring = constraint
+            .split(|c: char| !c.is_alphanumeric() && c != '.' && c != '_')
+            .any(|tag| tag == BORINGCRYPTO_EXPERIMENT || tag == "goexperiment.boringcrypto");
+        return mentions_boring.then(|| FipsSignal {
+            kind: FipsSignalKind::BuildConstraint,
+            source,
+            detail: format!("conditional code for BoringCrypto builds ({constraint})"),
+            enables: None,
+        });
+    }
+    if let Some(value) = line
+        .strip_prefix("//go:debug ")
+        .and_then(|setting| setting.trim().strip_prefix("fips140="))
+    {
+        return Some(godebug_signal(source, value));
+    }
+
+    let imported = line
+        .trim_end_matches(';')
+        .rsplit(char::is_whitespace)
+        .next()?;
+    match imported.trim_matches('"') {
+        FIPSONLY_PACKAGE if imported.starts_with('"') => Some(FipsSignal {
+            kind: FipsSignalKind::FipsonlyImport,
+            source,
+            detail: "restricts TLS to FIPS-approved settings".to_string(),
+            enables: None,
+        }),
+        FIPS140_PACKAGE if imported.starts_with('"') => Some(FipsSignal {
+            kind: FipsSignalKind::Fips140Import,
+            source,
+            detail: "checks FIPS 140-3 mode at runtime".to_string(),
+            enables: None,
+        }),
+        _ => None,
+    }
+}
+
+fn godebug_signal(source: String, value: &str) -> FipsSignal {
+    let enables = match value.trim() {
+        "on" | "debug" => Some(FipsMode::Fips140On),
+        "only" => Some(FipsMode::Fips140Only),
+        _ => None,
+    };
+    FipsSignal {
+        kind: FipsSignalKind::Godebug,
+        source,
+        detail: format!("fips140={}", value.trim()),
+        enables,
+    }
+}
+
+fn goexperiment_signal(source: String) -> FipsSignal {
+    FipsSignal {
+        kind: FipsSignalKind::Goexperiment,
+        source,
+        detail: "GOEXPERIMENT=boringcrypto".to_string(),
+        enables: Some(FipsMode::Boringcrypto),
+    }
+}
+
+/// `GOFIPS140` selects the module version and makes `fips140=on` the default.
+fn gofips140_signal(source: String, version: &str) -> FipsSignal {
+    FipsSignal {
+        kind: FipsSignalKind::Gofips140,
+        source,
+        detail: format!("GOFIPS140={version}"),
+        enables: Some(FipsMode::Fips140On),
+    }
+}
+
+/// The value assigned to `prefix` in a shell, make or YAML line, e.g. `GOFIPS140=latest`.
+fn assignment<'a>(line: &'a str, prefix: &str) -> Option<&'a str> {
+    let start = line.find(prefix)? + prefix.len();
+    line[start..]
+        .split(|c: char| c.is_whitespace() || c == '"' || c == '\'')
+        .next()
+        .filter(|value| !value.is_empty())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    fn no_env(_: &str) -> Option<String> {
+        None
+    }
+
+    #[test]
+    fn test_detects_go_mod_and_source_signals() {
+        let temp_dir = TempDir::new().unwrap();
+        let root = temp_dir.path();
+        fs::write(
+            root.join("go.mod"),
+            "module example.com/app\n\ngo 1.24\n\ngodebug fips140=only\n",
+        )
+        .unwrap();
+        fs::write(
+            root.join("main.go"),
+            "package main\n\nimport (\n\t\"crypto/fips140\"\n\t_ \"crypto/tls/fipsonly\"\n)\n",
+        )
+        .unwrap();
+        fs::write(
+            root.join("boring.go"),
+            "//go:build boringcrypto\n\npackage main\n",
+        )
+        .unwrap();
+
+        let signals = detect_signals(root, &no_env);
+        let kinds: Vec<_> = signals
+            .iter()
+            .map(|s| (s.kind, s.source.as_str()))
+            .collect();
+        assert_eq!(
+            kinds,
+            vec![
+                (FipsSignalKind::Godebug, "go.mod"),
+                (FipsSignalKind::BuildConstraint, "boring.go:1"),
+                (FipsSignalKind::Fips140Import, "main.go:4"),
+                (FipsSignalKind::FipsonlyImport, "main.go:5"),
+            ]
+        );
+        assert_eq!(signals[0].enables, Some(FipsMode::Fips140Only));
+    }
+
+    #[test]
+    fn test_detects_environment_and_build_files() {
+        let temp_dir = TempDir::new().unwrap();
+        let root = temp_dir.path();
+        fs::write(
+            root.join("Makefile"),
+            "build:\n\tGOFIPS140=v1.0.0 go build ./...\n",
+        )
+        .unwrap();
+
+        let env = |key: &str| (key == "GOEXPERIMENT").then(|| "arenas,boringcrypto".to_string());
+        let signals = detect_signals(root, &env);
+
+        assert_eq!(signals.len(), 2);
+        assert_eq!(signals[0].kind, FipsSignalKind::Goexperiment);
+        assert_eq!(signals[0].enables, Some(FipsMode::Boringcrypto));
+        assert_eq!(signals[1].source, "Makefile:2");
+        assert_eq!(signals[1].detail, "GOFIPS140=v1.0.0");
+    }
+}
@@ -0,0 +1,404 @@
+//! Crypto parameters set in `//go:generate` directives and code generator configs rather
+//! than Go source.
+
+use std::fs;
+use std::path::Path;
+use std::str;
+
+use serde_json::Value;
+use tracing::debug;
+use walkdir::WalkDir;
+
+use crate::discovery::loader::LoadError;
+use crate::discovery::utils::walk_source_files;
+use crate::output::{GeneratorSetting, GeneratorSettingKind};
+
+use super::config::{
+    EXCLUDED_DIRS, FILE_EXTENSIONS, GENERATOR_CONFIGS, GO_GENERATE_COMMAND, GO_GENERATE_RUN_FLAG,
+    GO_LIST_PACKAGE_PATTERN, VENDOR_DIR,
+};
+use super::toolchain::GoEnv;
+
+const GENERATE_DIRECTIVE: &str = "//go:generate ";
+
+/// Runs the `//go:generate` directives of every package under `root`, or only those
+/// matching the regular expression `run`, so generated files exist before the scan.
+/// Generators write into the tree like `go generate` always does.
+pub fn run_generators(root: &Path, go_env: &GoEnv, run: Option<&str>) -> Result<(), LoadError> {
+    let output = go_env
+        .command(true)
+        .args(generate_args(run))
+        .current_dir(root)
+        .output()
+        .map_err(|e| LoadError::PackageManager(format!("Failed to run 'go generate': {e}")))?;
+    if !output.status.success() {
+        let stderr = str::from_utf8(&output.stderr).unwrap_or("Unknown error");
+        return Err(LoadError::PackageManager(format!(
+            "go generate failed: {}",
+            stderr.trim()
+        )));
+    }
+    debug!(root = %root.display(), "ran go generate");
+    Ok(())
+}
+
+fn generate_args(run: Option<&str>) -> Vec<&str> {
+    let mut args = vec![GO_GENERATE_COMMAND];
+    if let Some(run) = run {
+        args.extend([GO_GENERATE_RUN_FLAG, run]);
+    }
+    args.push(GO_LIST_PACKAGE_PATTERN);
+    args
+}
+
+/// Collects generator settings from `//go:generate` directives in Go sources and from
+/// generator configs under `root`.
+pub fn detect_settings(root: &Path) -> Vec<GeneratorSetting> {
+    let display = |path: &Path| {
+        path.strip_prefix(root)
+            .unwrap_or(path)
+            .to_string_lossy()
+            .to_string()
+    };
+    let mut settings = Vec::new();
+
+    let excluded: Vec<&str> = EXCLUDED_DIRS.iter().copied().chain([VENDOR_DIR]).collect();
+    let files = walk_source_files(root, FILE_EXTENSIONS[0], &excluded, false).unwrap_or_default();
+    for path in files {
+        let Ok(content) = fs::read_to_string(&path) else {
+            continue;
+        };
+        for (index, line) in content.lines().enumerate() {
+            let Some(command) = line.trim().strip_prefix(GENERATE_DIRECTIVE) else {
+                continue;
+            };
+            let source = format!("{}:{}", display(&path), index + 1);
+            settings.extend(directive_settings(command, &source));
+        }
+    }
+
+    let configs = WalkDir::new(root)
+        .sort_by_file_name()
+        .into_iter()
+        .filter_entry(|entry| {
+            let name = entry.file_name().to_string_lossy();
+            entry.depth() == 0
+                || !entry.file_type().is_dir()
+                || !(excluded.contains(&name.as_ref()) || name.starts_with('.'))
+        })
+        .filter_map(|entry| entry.ok())
+        .filter(|entry| entry.file_type().is_file());
+    for entry in configs {
+        let name = entry.file_name().to_string_lossy();
+        let Some((_, generator)) = GENERATOR_CONFIGS.iter().find(|(file, _)| *file == name) else {
+            continue;
+        };
+        let Ok(content) = fs::read_to_string(entry.path()) else {
+            continue;
+        };
+        settings.extend(config_settings(&content, generator, &display(entry.path())));
+    }
+
+    debug!(count = settings.len(), "detected generator settings");
+    settings
+}
+
+/// Settings passed on the command line of a `//go:generate` directive.
+fn directive_settings(command: &str, source: &str) -> Vec<GeneratorSetting> {
+    let args = split_command(command);
+    let Some(generator) = generator_name(&args) else {
+        return Vec::new();
+    };
+
+    let mut pairs = Vec::new();
+    let mut rest = args.iter().skip(1).peekable();
+    while let Some(arg) = rest.next() {
+        let Some(flag) = arg.strip_prefix('-') else {
+            collect_pairs("", arg, &mut pairs);
+            continue;
+        };
+        let flag = flag.trim_start_matches('-');
+        match flag.split_once('=') {
+            Some((name, value)) => collect_pairs(name, value, &mut pairs),
+            // `-tls-min-version 1.2`; other flags may be booleans followed by arguments
+            None if GeneratorSettingKind::classify(flag).is_some() => {
+                let value = rest.next_if(|next| !next.starts_with('-'));
+                let value = value.map_or("true", String::as_str);
+                collect_pairs(flag, value, &mut pairs);
+            }
+            None => {}
+        }
+    }
+
+    settings(pairs, &generator, |_, _| source.to_string())
+}
+
+/// Settings in a generator config. YAML and JSON configs are both read as YAML; lines
+/// are those of the first entry naming the setting.
+fn config_settings(content: &str, generator: &str, file: &str) -> Vec<GeneratorSetting> {
+    let Ok(value) = serde_yaml::from_str::<Value>(content) else {
+        return Vec::new();
+    };
+    let mut pairs = Vec::new();
+    collect_config_pairs("", &value, &mut pairs);
+
+    settings(pairs, generator, |name, value| {
+        let line = content
+            .lines()
+            .position(|line| line.contains(name) && line.contains(value))
+            .or_else(|| content.lines().position(|line| line.contains(name)))
+            .map_or(1, |index| index + 1);
+        format!("{file}:{line}")
+    })
+}
+
+fn settings(
+    pairs: Vec<(String, String)>,
+    generator: &str,
+    source: impl Fn(&str, &str) -> String,
+) -> Vec<GeneratorSetting> {
+    pairs
+        .into_iter()
+        .filter_map(|(name, value)| {
+            let kind = GeneratorSettingKind::classify(&name)?;
+            Some(GeneratorSetting {
+                generator: generator.to_string(),
+                source: source(&name, &value),
+                name,
+                value,
+                kind,
+            })
+        })
+        .collect()
+}
+
+fn collect_config_pairs(key: &str, value: &Value, pairs: &mut Vec<(String, String)>) {
+    match value {
+        Value::Object(mapping) => {
+            for (name, value) in mapping {
+                collect_config_pairs(name, value, pairs);
+            }
+        }
+        Value::Array(items) => {
+            for item in items {
+                collect_config_pairs(key, item, pairs);
+            }
+        }
+        Value::String(text) => collect_pairs(key, text, pairs),
+        Value::Bool(flag) => collect_pairs(key, &flag.to_string(), pairs),
+        Value::Number(number) => collect_pairs(key, &number.to_string(), pairs),
+        _ => {}
+    }
+}
+
+/// `name=value`, expanding plugin option lists (`--go-grpc_opt=a=b,c=d`, buf's `opt:`)
+/// and the query parameters of URLs and DSNs (`postgres://...?sslmode=disable`).
+fn collect_pairs(name: &str, value: &str, pairs: &mut Vec<(String, String)>) {
+    if is_options_name(name) || (name.is_empty() && !value.contains('?')) {
+        for option in value.split(',') {
+            if let Some((name, value)) = option.split_once('=') {
+                if !name.is_empty() {
+                    collect_pairs(name, value, pairs);
+                }
+            }
+        }
+        return;
+    }
+    if let Some((_, query)) = value.split_once('?') {
+        for parameter in query.split('&') {
+            if let Some((name, value)) = parameter.split_once('=') {
+                pairs.push((name.to_string(), value.to_string()));
+            }
+        }
+    }
+    if !name.is_empty() {
+        pairs.push((name.to_string(), value.to_string()));
+    }
+}
+
+fn is_options_name(name: &str) -> bool {
+    name == "opt" || name == "options" || name.ends_with("_opt")
+}
+
+/// The command a directive runs: `protoc` for `protoc ...`, the tool's last path element
+/// for `go run github.com/sqlc-dev/sqlc/cmd/sqlc@v1.25.0 ...`.
+fn generator_name(args: &[String]) -> Option<String> {
+    let program = match args {
+        [go, run, tool, ..] if go == "go" && run == "run" => tool,
+        [program, ..] => program,
+        [] => return None,
+    };
+    let name = program.rsplit(['/', '\\']).next()?;
+    let name = name.split('@').next()?;
+    Some(name.trim_end_matches(".exe").to_string())
+}
+
+/// Splits a directive into words the way `go generate` does: at spaces, with
+/// double-quoted strings kept whole and unquoted.
+fn split_command(command: &str) -> Vec<String> {
+    let mut words = Vec::new();
+    let mut word = String::new();
+    let mut quoted = false;
+    let mut chars = command.chars();
+    while let Some(c) = chars.next() {
+        match c {
+            '"' => quoted = !quoted,
+            '\\' if quoted => word.extend(chars.next()),
+            c if c.is_whitespace() && !quoted => {
+                if !word.is_empty() {
+                    words.push(std::mem::take(&mut word));
+                }
+            }
+            c => word.push(c),
+        }
+    }
+    if !word.is_empty() {
+        words.push(word);
+    }
+    words
+}
+
+impl GeneratorSettingKind {
+    /// The kind of setting `name` controls, if it is crypto-relevant.
+    pub fn classify(name: &str) -> Option<Self> {
+        let name = name.to_ascii_lowercase().replace('-', "_");
+        let has = |word: &str| name.contains(word);
+        let kind = if has("cipher") {
+            GeneratorSettingKind::CipherSuite
+        } else if ["min_version", "max_version", "minversion", "maxversion"]
+            .iter()
+            .any(|w| has(w))
+            || has("tls_version")
+        {
+            GeneratorSettingKind::TlsVersion
+        } else if matches!(name.as_str(), "sslmode" | "tls" | "ssl" | "use_tls")
+            || [
+                "insecure",
+                "skip_verify",
+                "skipverify",
+                "tls_mode",
+                "ssl_mode",
+            ]
+            .iter()
+            .any(|w| has(w))
+        {
+            GeneratorSettingKind::TlsMode
+        } else if name == "ca"
+            || ["cert", "ca_file", "root_ca", "key_file", "keyfile"]
+                .iter()
+                .any(|w| has(w))
+        {
+            GeneratorSettingKind::Certificate
+        } else if name == "bits"
+            || ["key_size", "keysize", "key_bits", "key_length", "keylen"]
+                .iter()
+                .any(|w| has(w))
+        {
+            GeneratorSettingKind::KeySize
+        } else if name == "alg"
+            || ["hash", "digest", "algorithm", "signing_method"]
+                .iter()
+                .any(|w| has(w))
+        {
+            GeneratorSettingKind::Algorithm
+        } else {
+            return None;
+        };
+        Some(kind)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    fn summary(settings: &[GeneratorSetting]) -> Vec<(&str, &str, &str, &str)> {
+        settings
+            .iter()
+            .map(|s| {
+                (
+                    s.generator.as_str(),
+                    s.source.as_str(),
+                    s.name.as_str(),
+                    s.value.as_str(),
+                )
+            })
+            .collect()
+    }
+
+    #[test]
+    fn test_directive_settings() {
+        let settings = directive_settings(
+            r#"protoc --go-grpc_opt=require_unimplemented_servers=false,tls_min_version=1.0 --insecure --go_out=. "api/v1/service.proto""#,
+            "api/gen.go:3",
+        );
+        assert_eq!(
+            summary(&settings),
+            vec![
+                ("protoc", "api/gen.go:3", "tls_min_version", "1.0"),
+                ("protoc", "api/gen.go:3", "insecure", "true"),
+            ]
+        );
+        assert_eq!(settings[0].kind, GeneratorSettingKind::TlsVersion);
+
+        let settings = directive_settings(
+            "go run github.com/acme/dbgen/cmd/dbgen@v1.4.0 -dsn postgres://app@db/app?sslmode=disable -hash-alg sha1",
+            "db/gen.go:1",
+        );
+        assert_eq!(
+            summary(&settings),
+            vec![
+                ("dbgen", "db/gen.go:1", "sslmode", "disable"),
+                ("dbgen", "db/gen.go:1", "hash-alg", "sha1"),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_config_settings() {
+        let temp_dir = TempDir::new().unwrap();
+        let root = temp_dir.path();
+        fs::create_dir_all(root.join("db")).unwrap();
+        fs::write(
+            root.join("db/sqlc.yaml"),
+            "version: \"2\"\nsql:\n  - engine: postgresql\n    database:\n      uri: postgresql://app@localhost:5432/app?sslmode=disable\n    gen:\n      go:\n        package: db\n",
+        )
+        .unwrap();
+        fs::write(
+            root.join("buf.gen.yaml"),
+            "version: v1\nplugins:\n  - plugin: go-grpc\n    out: gen\n    opt:\n      - paths=source_relative\n      - tls_cipher_suites=TLS_RSA_WITH_RC4_128_SHA\n",
+        )
+        .unwrap();
+        fs::write(
+            root.join("gen.go"),
+            "package app\n\n//go:generate sqlc generate -f db/sqlc.yaml\n",
+        )
+        .unwrap();
+
+        let settings = detect_settings(root);
+        assert_eq!(
+            summary(&settings),
+            vec![
+                (
+                    "buf",
+                    "buf.gen.yaml:7",
+                    "tls_cipher_suites",
+                    "TLS_RSA_WITH_RC4_128_SHA"
+                ),
+                ("sqlc", "db/sqlc.yaml:5", "sslmode", "disable"),
+            ]
+        );
+        assert_eq!(settings[0].kind, GeneratorSettingKind::CipherSuite);
+        assert_eq!(settings[1].kind, GeneratorSettingKind::TlsMode);
+    }
+
+    #[test]
+    fn test_generate_args() {
+        assert_eq!(generate_args(None), vec!["generate", "./..."]);
+        assert_eq!(
+            generate_args(Some("stringer|consttab")),
+            vec!["generate", "-run", "stringer|consttab", "./..."]
+        );
+    }
+}
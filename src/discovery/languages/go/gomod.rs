@@ -0,0 +1,363 @@
+use std::cmp::Ordering;
+use std::fmt;
+use std::fs;
+use std::path::{Path, PathBuf};
+
+use walkdir::WalkDir;
+
+use super::config::{EXCLUDED_DIRS, GO_MOD_FILE, VENDOR_DIR, VERSIONED_STDLIB_PACKAGES};
+
+/// A Go language version as written in a `go` directive (e.g. `1.22`, `1.24.1`, `go1.21rc2`).
+///
+/// Pre-release suffixes are dropped: a `1.24rc1` toolchain already ships the 1.24 APIs.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash)]
+pub struct GoVersion {
+    pub major: u32,
+    pub minor: u32,
+    pub patch: u32,
+}
+
+impl GoVersion {
+    pub fn new(major: u32, minor: u32, patch: u32) -> Self {
+        Self {
+            major,
+            minor,
+            patch,
+        }
+    }
+
+    pub fn parse(input: &str) -> Option<Self> {
+        let trimmed = input.trim();
+        let trimmed = trimmed.strip_prefix("go").unwrap_or(trimmed);
+
+        let mut parts = trimmed.splitn(3, '.');
+        let major = parse_leading_number(parts.next()?)?;
+        let minor = parts.next().and_then(parse_leading_number).unwrap_or(0);
+        let patch = parts.next().and_then(parse_leading_number).unwrap_or(0);
+
+        Some(Self::new(major, minor, patch))
+    }
+
+    /// True if `import_path` is available at this language version.
+    ///
+    /// Packages that are not in the version table are always considered available.
+    pub fn supports_package(&self, import_path: &str) -> bool {
+        match introduced_in(import_path) {
+            Some(introduced) => *self >= introduced,
+            None => true,
+        }
+    }
+}
+
+fn parse_leading_number(s: &str) -> Option<u32> {
+    let digits: String = s.chars().take_while(|c| c.is_ascii_digit()).collect();
+    digits.parse().ok()
+}
+
+impl Ord for GoVersion {
+    fn cmp(&self, other: &Self) -> Ordering {
+        (self.major, self.minor, self.patch).cmp(&(other.major, other.minor, other.patch))
+    }
+}
+
+impl PartialOrd for GoVersion {
+    fn partial_cmp(&self, other: &Self) -> Option<Ordering> {
+        Some(self.cmp(other))
+    }
+}
+
+impl fmt::Display for GoVersion {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        if self.patch == 0 {
+            write!(f, "{}.{}", self.major, self.minor)
+        } else {
+            write!(f, "{}.{}.{}", self.major, self.minor, self.patch)
+        }
+    }
+}
+
+/// Returns the Go version that introduced a standard library package, if it is version-gated.
+pub fn introduced_in(import_path: &str) -> Option<GoVersion> {
+    VERSIONED_STDLIB_PACKAGES
+        .iter()
+        .find(|(path, _)| *path == import_path)
+        .and_then(|(_, version)| GoVersion::parse(version))
+}
+
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Require {
+    pub path: String,
+    pub version: String,
+}
+
+/// The subset of a `go.mod` file that argflow cares about.
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct GoMod {
+    pub module: Option<String>,
+    pub go_version: Option<GoVersion>,
+    pub requires: Vec<Require>,
+    /// `godebug` settings (Go 1.23+) as `(key, value)` pairs.
+    pub godebug: Vec<(String, String)>,
+}
+
+impl GoMod {
+    pub fn parse(content: &str) -> Self {
+        let mut go_mod = GoMod::default();
+        let mut in_require_block = false;
+        let mut in_godebug_block = false;
+
+        for raw_line in content.lines() {
+            let line = strip_comment(raw_line).trim();
+            if line.is_empty() {
+                continue;
+            }
+
+            if in_require_block {
+                if line == ")" {
+                    in_require_block = false;
+                } else if let Some(require) = parse_require(line) {
+                    go_mod.requires.push(require);
+                }
+                continue;
+            }
+            if in_godebug_block {
+                if line == ")" {
+                    in_godebug_block = false;
+                } else if let Some(setting) = parse_godebug(line) {
+                    go_mod.godebug.push(setting);
+                }
+                continue;
+            }
+
+            let (directive, rest) = match line.split_once(char::is_whitespace) {
+                Some((directive, rest)) => (directive, rest.trim()),
+                None => (line, ""),
+            };
+
+            match directive {
+                "module" => go_mod.module = Some(rest.trim_matches('"').to_string()),
+                "go" => go_mod.go_version = GoVersion::parse(rest),
+                "require" if rest == "(" => in_require_block = true,
+                "require" => {
+                    if let Some(require) = parse_require(rest) {
+                        go_mod.requires.push(require);
+                    }
+                }
+                "godebug" if rest == "(" => in_godebug_block = true,
+                "godebug" => go_mod.godebug.extend(parse_godebug(rest)),
+                _ => {}
+            }
+        }
+
+        go_mod
+    }
+
+    pub fn from_file(path: &Path) -> std::io::Result<Self> {
+        Ok(Self::parse(&fs::read_to_string(path)?))
+    }
+}
+
+fn strip_comment(line: &str) -> &str {
+    match line.find("//") {
+        Some(idx) => &line[..idx],
+        None => line,
+    }
+}
+
+fn parse_require(line: &str) -> Option<Require> {
+    let mut parts = line.split_whitespace();
+    let path = parts.next()?.trim_matches('"');
+    let version = parts.next()?;
+    Some(Require {
+        path: path.to_string(),
+        version: version.to_string(),
+    })
+}
+
+fn parse_godebug(line: &str) -> Option<(String, String)> {
+    let (key, value) = line.split_once('=')?;
+    Some((key.trim().to_string(), value.trim().to_string()))
+}
+
+/// Finds the nearest `go.mod` at or above `start`.
+pub fn find_go_mod(start: &Path) -> Option<PathBuf> {
+    let dir = if start.is_file() {
+        start.parent()?
+    } else {
+        start
+    };
+    dir.ancestors()
+        .map(|ancestor| ancestor.join(GO_MOD_FILE))
+        .find(|candidate| candidate.is_file())
+}
+
+/// Every module root at or below `root`: directories holding a `go.mod`, in path order.
+/// Vendored, hidden and `testdata` directories are skipped, as the go command does.
+pub fn find_modules(root: &Path) -> Vec<PathBuf> {
+    WalkDir::new(root)
+        .sort_by_file_name()
+        .into_iter()
+        .filter_entry(|entry| {
+            let name = entry.file_name().to_string_lossy();
+            entry.depth() == 0
+                || !entry.file_type().is_dir()
+                || !(name.starts_with('.')
+                    || name == VENDOR_DIR
+                    || EXCLUDED_DIRS.contains(&name.as_ref()))
+        })
+        .filter_map(Result::ok)
+        .filter(|entry| entry.file_type().is_dir() && entry.path().join(GO_MOD_FILE).is_file())
+        .map(|entry| entry.into_path())
+        .collect()
+}
+
+/// Whether `path` lies in a module nested below `root`, i.e. a directory between them
+/// holds its own `go.mod`.
+pub fn in_nested_module(root: &Path, path: &Path) -> bool {
+    path.parent()
+        .into_iter()
+        .flat_map(Path::ancestors)
+        .take_while(|dir| *dir != root && dir.starts_with(root))
+        .any(|dir| dir.join(GO_MOD_FILE).is_file())
+}
+
+/// Reads the `go` directive from the nearest `go.mod` at or above `start`.
+pub fn detect_go_version(start: &Path) -> Option<GoVersion> {
+    let path = find_go_mod(start)?;
+    GoMod::from_file(&path).ok()?.go_version
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    #[test]
+    fn test_parse_go_version() {
+        assert_eq!(GoVersion::parse("1.22"), Some(GoVersion::new(1, 22, 0)));
+        assert_eq!(GoVersion::parse("1.24.1"), Some(GoVersion::new(1, 24, 1)));
+        assert_eq!(
+            GoVersion::parse("go1.21rc2"),
+            Some(GoVersion::new(1, 21, 0))
+        );
+        assert_eq!(GoVersion::parse("latest"), None);
+    }
+
+    #[test]
+    fn test_go_version_ordering() {
+        assert!(GoVersion::new(1, 24, 0) > GoVersion::new(1, 22, 5));
+        assert!(GoVersion::new(1, 21, 0) < GoVersion::new(1, 21, 1));
+    }
+
+    #[test]
+    fn test_go_version_display() {
+        assert_eq!(GoVersion::new(1, 24, 0).to_string(), "1.24");
+        assert_eq!(GoVersion::new(1, 24, 3).to_string(), "1.24.3");
+    }
+
+    #[test]
+    fn test_supports_package() {
+        let go121 = GoVersion::new(1, 21, 0);
+        let go124 = GoVersion::new(1, 24, 0);
+
+        assert!(!go121.supports_package("crypto/pbkdf2"));
+        assert!(go124.supports_package("crypto/pbkdf2"));
+        assert!(!go121.supports_package("math/rand/v2"));
+        assert!(go121.supports_package("crypto/sha256"));
+        assert!(go121.supports_package("golang.org/x/crypto/pbkdf2"));
+    }
+
+    #[test]
+    fn test_parse_go_mod() {
+        let go_mod = GoMod::parse(
+            r#"module example.com/app // trailing comment
+
+go 1.24
+
+require github.com/go-jose/go-jose/v4 v4.0.5
+
+require (
+	golang.org/x/crypto v0.31.0
+	// indirect dependencies
+	golang.org/x/sys v0.28.0 // indirect
+)
+"#,
+        );
+
+        assert_eq!(go_mod.module.as_deref(), Some("example.com/app"));
+        assert_eq!(go_mod.go_version, Some(GoVersion::new(1, 24, 0)));
+        assert_eq!(go_mod.requires.len(), 3);
+        assert_eq!(go_mod.requires[1].path, "golang.org/x/crypto");
+        assert_eq!(go_mod.requires[2].version, "v0.28.0");
+    }
+
+    #[test]
+    fn test_parse_godebug() {
+        let go_mod = GoMod::parse(
+            "module example.com/app\n\ngo 1.24\n\ngodebug fips140=on\n\ngodebug (\n\tdefault=go1.21\n\ttlsrsakex=1\n)\n",
+        );
+
+        assert_eq!(
+            go_mod.godebug,
+            vec![
+                ("fips140".to_string(), "on".to_string()),
+                ("default".to_string(), "go1.21".to_string()),
+                ("tlsrsakex".to_string(), "1".to_string()),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_detect_go_version_from_subdirectory() {
+        let temp_dir = TempDir::new().unwrap();
+        fs::write(
+            temp_dir.path().join("go.mod"),
+            "module example.com/app\n\ngo 1.22\n",
+        )
+        .unwrap();
+        let pkg_dir = temp_dir.path().join("pkg").join("hash");
+        fs::create_dir_all(&pkg_dir).unwrap();
+        let file = pkg_dir.join("hash.go");
+        fs::write(&file, "package hash").unwrap();
+
+        assert_eq!(detect_go_version(&file), Some(GoVersion::new(1, 22, 0)));
+        assert_eq!(detect_go_version(&pkg_dir), Some(GoVersion::new(1, 22, 0)));
+    }
+
+    #[test]
+    fn test_find_modules_and_nested_files() {
+        let temp_dir = TempDir::new().unwrap();
+        let root = temp_dir.path();
+        for (dir, module) in [
+            ("", "example.com/app"),
+            ("tests/fixtures/kdf", "example.com/fixtures/kdf"),
+            ("tests/fixtures/tls", "example.com/fixtures/tls"),
+            ("vendor/example.com/dep", "example.com/dep"),
+            ("internal/testdata/broken", "example.com/broken"),
+        ] {
+            let dir = root.join(dir);
+            fs::create_dir_all(&dir).unwrap();
+            fs::write(dir.join("go.mod"), format!("module {module}\n")).unwrap();
+        }
+
+        let modules: Vec<PathBuf> = find_modules(root)
+            .iter()
+            .map(|dir| dir.strip_prefix(root).unwrap().to_path_buf())
+            .collect();
+        assert_eq!(
+            modules,
+            vec![
+                PathBuf::new(),
+                PathBuf::from("tests/fixtures/kdf"),
+                PathBuf::from("tests/fixtures/tls"),
+            ]
+        );
+
+        assert!(in_nested_module(
+            root,
+            &root.join("tests/fixtures/kdf/pkg/kdf.go")
+        ));
+        assert!(!in_nested_module(root, &root.join("internal/kdf/kdf.go")));
+        assert!(!in_nested_module(root, &root.join("main.go")));
+    }
+}
@@ -0,0 +1,138 @@
+use std::env;
+use std::path::{Path, PathBuf};
+
+use crate::cli::Language;
+use crate::discovery::cache::DiscoveryCache;
+use crate::discovery::loader::{LoadError, PackageLoader};
+use crate::discovery::utils::canonical_path;
+use crate::discovery::{SourceFile, SourceType};
+
+use super::config::{GOPATH_ENV, GOPATH_SRC_DIR};
+use super::deps;
+use super::loader::{find_all_vendor_dirs, get_file_metadata, scan_vendor, GoPackageLoader};
+use super::toolchain::GoEnv;
+
+/// Finds the GOPATH workspace that contains `root`.
+///
+/// A `.../src/...` ancestor of the scan root wins, since that is the tree the project
+/// actually lives in; otherwise the first entry of `$GOPATH` is used.
+pub fn find_gopath(root: &Path) -> Option<PathBuf> {
+    let root = canonical_path(root);
+    let from_layout = root
+        .ancestors()
+        .find(|ancestor| {
+            ancestor
+                .file_name()
+                .is_some_and(|name| name == GOPATH_SRC_DIR)
+        })
+        .and_then(|src| src.parent())
+        .map(Path::to_path_buf);
+
+    from_layout.or_else(|| {
+        env::var_os(GOPATH_ENV)
+            .and_then(|value| env::split_paths(&value).next())
+            .filter(|path| !path.as_os_str().is_empty())
+    })
+}
+
+/// Loader for projects that predate Go modules and live in a GOPATH workspace.
+///
+/// User code is discovered the same way as in module mode; dependencies come from
+/// vendor directories or from `$GOPATH/src` via `go list` with modules disabled.
+pub struct GopathPackageLoader {
+    gopath: PathBuf,
+    env: GoEnv,
+}
+
+impl GopathPackageLoader {
+    pub fn new(gopath: PathBuf) -> Self {
+        Self {
+            gopath,
+            env: GoEnv::default(),
+        }
+    }
+
+    pub fn with_env(mut self, env: GoEnv) -> Self {
+        self.env = env;
+        self
+    }
+
+    pub fn gopath(&self) -> &Path {
+        &self.gopath
+    }
+}
+
+impl PackageLoader for GopathPackageLoader {
+    fn load_user_code(&self, root: &Path) -> Result<Vec<SourceFile>, LoadError> {
+        GoPackageLoader::new(self.env.clone()).load_user_code(root)
+    }
+
+    fn load_dependencies(
+        &self,
+        root: &Path,
+        cache: &mut DiscoveryCache,
+    ) -> Result<Vec<SourceFile>, LoadError> {
+        let cache_key = format!("{}:go-gopath", root.display());
+        if let Some(cached_paths) = cache.get_dependencies(&cache_key) {
+            return Ok(cached_paths.into_iter().map(dependency_file).collect());
+        }
+
+        let vendor_dirs = find_all_vendor_dirs(root)?;
+        let mut all_files = Vec::new();
+        if !vendor_dirs.is_empty() {
+            for vendor_path in vendor_dirs {
+                all_files.extend(scan_vendor(&vendor_path)?.into_iter().map(dependency_file));
+            }
+        } else {
+            for (path, is_stdlib) in
+                deps::scan_dependencies_in_gopath(root, &self.gopath, &self.env)?
+            {
+                let mut file = dependency_file(path);
+                if is_stdlib {
+                    file.source_type = SourceType::Stdlib;
+                }
+                all_files.push(file);
+            }
+        }
+
+        let paths_for_cache: Vec<_> = all_files.iter().map(|f| f.path.clone()).collect();
+        cache.set_dependencies(cache_key, paths_for_cache);
+
+        Ok(all_files)
+    }
+
+    fn language(&self) -> Language {
+        Language::Go
+    }
+}
+
+fn dependency_file(path: PathBuf) -> SourceFile {
+    let metadata = get_file_metadata(&path);
+    SourceFile {
+        path,
+        language: Language::Go,
+        source_type: SourceType::Dependency {
+            package: "unknown".to_string(),
+            version: None,
+        },
+        package: None,
+        metadata,
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::fs;
+    use tempfile::TempDir;
+
+    #[test]
+    fn test_find_gopath_from_layout() {
+        let temp_dir = TempDir::new().unwrap();
+        let project = temp_dir.path().join("src/github.com/acme/legacy");
+        fs::create_dir_all(&project).unwrap();
+
+        let gopath = find_gopath(&project).unwrap();
+        assert_eq!(gopath, temp_dir.path().canonicalize().unwrap());
+    }
+}
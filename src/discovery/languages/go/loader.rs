@@ -4,13 +4,41 @@ use std::path::{Path, PathBuf};
 use crate::cli::Language;
 use crate::discovery::cache::DiscoveryCache;
 use crate::discovery::loader::{LoadError, PackageLoader};
-use crate::discovery::utils::walk_source_files;
+use crate::discovery::utils::{path_key, walk_source_files};
 use crate::discovery::{FileMetadata, SourceFile, SourceType};
 
 use super::config::*;
 use super::deps;
+use super::gomod::in_nested_module;
+use super::toolchain::GoEnv;
+
+/// Loader for Go modules. Dependencies come from vendor directories or `go list`, run
+/// with the loader's own [`GoEnv`].
+#[derive(Debug, Clone, Default)]
+pub struct GoPackageLoader {
+    env: GoEnv,
+    within_module: bool,
+}
+
+impl GoPackageLoader {
+    pub fn new(env: GoEnv) -> Self {
+        Self {
+            env,
+            within_module: false,
+        }
+    }
 
-pub struct GoPackageLoader;
+    /// Leaves out files of modules nested below the scanned root, which the go command
+    /// treats as separate modules.
+    pub fn within_module(mut self) -> Self {
+        self.within_module = true;
+        self
+    }
+
+    pub fn env(&self) -> &GoEnv {
+        &self.env
+    }
+}
 
 impl PackageLoader for GoPackageLoader {
     fn load_user_code(&self, root: &Path) -> Result<Vec<SourceFile>, LoadError> {
@@ -31,6 +59,7 @@ impl PackageLoader for GoPackageLoader {
         let paths = walk_source_files(root, FILE_EXTENSIONS[0], EXCLUDED_DIRS, false)?;
         Ok(paths
             .into_iter()
+            .filter(|path| !(self.within_module && in_nested_module(root, path)))
             .map(|path| SourceFile {
                 path: path.clone(),
                 language: Language::Go,
@@ -46,7 +75,7 @@ impl PackageLoader for GoPackageLoader {
         root: &Path,
         cache: &mut DiscoveryCache,
     ) -> Result<Vec<SourceFile>, LoadError> {
-        let cache_key = format!("{}:go", root.display());
+        let cache_key = format!("{}:go", path_key(root));
 
         if let Some(cached_paths) = cache.get_dependencies(&cache_key) {
             return Ok(cached_paths
@@ -88,7 +117,7 @@ impl PackageLoader for GoPackageLoader {
                 }
             }
         } else {
-            let dep_results = deps::scan_dependencies_using_go_tooling(root, cache)?;
+            let dep_results = deps::scan_dependencies_using_go_tooling(root, &self.env, cache)?;
             for (path, is_stdlib) in dep_results {
                 let metadata = get_file_metadata(&path);
                 all_files.push(SourceFile {
@@ -119,7 +148,7 @@ impl PackageLoader for GoPackageLoader {
     }
 }
 
-fn get_file_metadata(path: &PathBuf) -> FileMetadata {
+pub(super) fn get_file_metadata(path: &PathBuf) -> FileMetadata {
     fs::metadata(path)
         .ok()
         .map(|m| FileMetadata {
@@ -134,7 +163,7 @@ fn get_file_metadata(path: &PathBuf) -> FileMetadata {
         })
 }
 
-fn find_all_vendor_dirs(root: &Path) -> Result<Vec<PathBuf>, LoadError> {
+pub(super) fn find_all_vendor_dirs(root: &Path) -> Result<Vec<PathBuf>, LoadError> {
     let mut vendor_dirs = Vec::new();
 
     fn walk_for_vendor(dir: &Path, vendor_dirs: &mut Vec<PathBuf>) -> std::io::Result<()> {
@@ -171,6 +200,6 @@ fn find_all_vendor_dirs(root: &Path) -> Result<Vec<PathBuf>, LoadError> {
     Ok(vendor_dirs)
 }
 
-fn scan_vendor(vendor_path: &Path) -> Result<Vec<PathBuf>, LoadError> {
+pub(super) fn scan_vendor(vendor_path: &Path) -> Result<Vec<PathBuf>, LoadError> {
     walk_source_files(vendor_path, FILE_EXTENSIONS[0], &[], true)
 }
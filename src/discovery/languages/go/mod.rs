@@ -5,19 +5,35 @@ use crate::discovery::filter::ImportFileFilter;
 use crate::discovery::languages::LanguageModule;
 use crate::discovery::loader::PackageLoader;
 
+pub mod attribution;
 pub mod config;
 pub mod deps;
+pub mod driver;
 pub mod filter;
+pub mod fips;
+pub mod generate;
+pub mod gomod;
+pub mod gopath;
 pub mod loader;
+pub mod packages;
+pub mod toolchain;
+pub mod workspace;
 
+pub use attribution::ModuleAttributor;
+pub use driver::{DriverPackageLoader, PackagesDriver};
 pub use filter::GoImportFilter;
+pub use gomod::{GoMod, GoVersion};
+pub use gopath::GopathPackageLoader;
 pub use loader::GoPackageLoader;
+pub use packages::{GoPackage, PackageGraph};
+pub use toolchain::GoEnv;
+pub use workspace::GoWorkspace;
 
 pub struct GoModule;
 
 impl LanguageModule for GoModule {
     fn create_loader(&self) -> Box<dyn PackageLoader> {
-        Box::new(GoPackageLoader)
+        Box::new(GoPackageLoader::default())
     }
 
     fn create_filter(&self) -> Box<dyn ImportFileFilter> {
@@ -0,0 +1,277 @@
+use std::collections::{BTreeMap, BTreeSet, VecDeque};
+use std::fs;
+use std::path::{Path, PathBuf};
+
+use walkdir::WalkDir;
+
+use crate::discovery::utils::canonical_path;
+
+use super::config::{EXCLUDED_DIRS, MAX_FILE_SIZE};
+use super::workspace::GoWorkspace;
+
+/// A Go package in the scanned tree and the packages its non-test files import.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct GoPackage {
+    pub dir: PathBuf,
+    pub name: String,
+    pub import_path: Option<String>,
+    pub imports: BTreeSet<String>,
+}
+
+impl GoPackage {
+    pub fn is_main(&self) -> bool {
+        self.name == "main"
+    }
+}
+
+/// Package-level import graph of the tree under a root, built from import declarations
+/// without invoking the Go toolchain. Build constraints are ignored, so a package counts
+/// as imported if any of its files imports it.
+#[derive(Debug, Default)]
+pub struct PackageGraph {
+    /// Keyed by canonical package directory.
+    packages: BTreeMap<PathBuf, GoPackage>,
+    by_import_path: BTreeMap<String, PathBuf>,
+}
+
+impl PackageGraph {
+    pub fn load(root: &Path, workspace: Option<&GoWorkspace>) -> Self {
+        let mut files_by_dir: BTreeMap<PathBuf, Vec<PathBuf>> = BTreeMap::new();
+        let walker = WalkDir::new(root).into_iter().filter_entry(|entry| {
+            let name = entry.file_name().to_string_lossy();
+            entry.depth() == 0
+                || !entry.file_type().is_dir()
+                || !(EXCLUDED_DIRS.contains(&name.as_ref())
+                    || name.starts_with('.')
+                    || name.starts_with('_'))
+        });
+        for entry in walker.filter_map(|e| e.ok()) {
+            let path = entry.path();
+            let name = entry.file_name().to_string_lossy();
+            if entry.file_type().is_file() && name.ends_with(".go") && !name.ends_with("_test.go") {
+                let dir = path.parent().unwrap_or(root);
+                let dir = canonical_path(dir);
+                files_by_dir
+                    .entry(dir)
+                    .or_default()
+                    .push(path.to_path_buf());
+            }
+        }
+
+        let mut graph = Self::default();
+        for (dir, files) in files_by_dir {
+            let mut name = None;
+            let mut imports = BTreeSet::new();
+            for file in files {
+                let too_large = fs::metadata(&file).map_or(true, |m| m.len() > MAX_FILE_SIZE);
+                let Some(content) = (!too_large)
+                    .then(|| fs::read_to_string(&file).ok())
+                    .flatten()
+                else {
+                    continue;
+                };
+                let (package, file_imports) = parse_header(&content);
+                name = name.or(package);
+                imports.extend(file_imports);
+            }
+            let Some(name) = name else { continue };
+            let import_path = workspace.and_then(|w| w.import_path_for_dir(&dir));
+            if let Some(import_path) = &import_path {
+                graph
+                    .by_import_path
+                    .insert(import_path.clone(), dir.clone());
+            }
+            graph.packages.insert(
+                dir.clone(),
+                GoPackage {
+                    dir,
+                    name,
+                    import_path,
+                    imports,
+                },
+            );
+        }
+        graph
+    }
+
+    pub fn packages(&self) -> impl Iterator<Item = &GoPackage> {
+        self.packages.values()
+    }
+
+    pub fn main_packages(&self) -> impl Iterator<Item = &GoPackage> {
+        self.packages().filter(|p| p.is_main())
+    }
+
+    /// Directories of every package in the tree reachable from `dir` through imports,
+    /// including `dir` itself.
+    pub fn reachable_from(&self, dir: &Path) -> BTreeSet<PathBuf> {
+        let mut reachable = BTreeSet::new();
+        let mut queue = VecDeque::from([dir.to_path_buf()]);
+        while let Some(dir) = queue.pop_front() {
+            let Some(package) = self.packages.get(&dir) else {
+                continue;
+            };
+            if !reachable.insert(dir) {
+                continue;
+            }
+            queue.extend(
+                package
+                    .imports
+                    .iter()
+                    .filter_map(|import| self.by_import_path.get(import).cloned()),
+            );
+        }
+        reachable
+    }
+}
+
+/// The package clause and import paths of a Go file. Only the file header is read:
+/// parsing stops at the first declaration after the imports.
+fn parse_header(content: &str) -> (Option<String>, Vec<String>) {
+    let mut package = None;
+    let mut imports = Vec::new();
+    let mut in_block = false;
+    let mut in_comment = false;
+
+    for line in content.lines() {
+        let mut line = line.trim();
+        if in_comment {
+            match line.find("*/") {
+                Some(end) => {
+                    in_comment = false;
+                    line = line[end + 2..].trim();
+                }
+                None => continue,
+            }
+        }
+        if let Some(start) = line.find("/*") {
+            if !line[start..].contains("*/") {
+                in_comment = true;
+            }
+            line = line[..start].trim();
+        }
+        if let Some(start) = line.find("//") {
+            line = line[..start].trim();
+        }
+        if line.is_empty() {
+            continue;
+        }
+
+        if in_block {
+            if line.starts_with(')') {
+                in_block = false;
+            } else if let Some(path) = quoted(line) {
+                imports.push(path);
+            }
+        } else if let Some(name) = line.strip_prefix("package ") {
+            package = Some(name.trim().to_string());
+        } else if let Some(spec) = line.strip_prefix("import") {
+            let spec = spec.trim();
+            if spec.starts_with('(') {
+                in_block = !spec.contains(')');
+                imports.extend(spec.trim_start_matches('(').split(';').filter_map(quoted));
+            } else if let Some(path) = quoted(spec) {
+                imports.push(path);
+            }
+        } else if package.is_some() {
+            break;
+        }
+    }
+    (package, imports)
+}
+
+fn quoted(spec: &str) -> Option<String> {
+    let start = spec.find(['"', '`'])?;
+    let quote = spec[start..].chars().next()?;
+    let end = spec[start + 1..].find(quote)? + start + 1;
+    Some(spec[start + 1..end].to_string())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    #[test]
+    fn test_parse_header() {
+        let content = "// Copyright notice\n\
+            /* multi\n   line */\n\
+            package main // the entrypoint\n\n\
+            import \"fmt\"\n\
+            import kdf \"example.com/app/internal/kdf\"\n\
+            import (\n\
+            \t\"crypto/sha256\"\n\
+            \t_ \"crypto/tls/fipsonly\" // FIPS only\n\
+            )\n\n\
+            func main() {}\n\
+            import \"never\"\n";
+
+        let (package, imports) = parse_header(content);
+        assert_eq!(package.as_deref(), Some("main"));
+        assert_eq!(
+            imports,
+            vec![
+                "fmt",
+                "example.com/app/internal/kdf",
+                "crypto/sha256",
+                "crypto/tls/fipsonly",
+            ]
+        );
+    }
+
+    #[test]
+    fn test_reachable_from_main_packages() {
+        let dir = TempDir::new().unwrap();
+        let files = [
+            ("go.mod", "module example.com/app\n"),
+            (
+                "cmd/server/main.go",
+                "package main\n\nimport \"example.com/app/internal/tlsconf\"\n",
+            ),
+            (
+                "cmd/keygen/main.go",
+                "package main\n\nimport \"example.com/app/internal/kdf\"\n",
+            ),
+            (
+                "internal/tlsconf/tls.go",
+                "package tlsconf\n\nimport (\n\t\"crypto/tls\"\n\t\"example.com/app/internal/kdf\"\n)\n",
+            ),
+            ("internal/kdf/kdf.go", "package kdf\n"),
+            ("internal/unused/unused.go", "package unused\n"),
+            ("internal/kdf/kdf_test.go", "package kdf\n\nimport \"example.com/app/internal/unused\"\n"),
+        ];
+        for (path, content) in files {
+            let path = dir.path().join(path);
+            fs::create_dir_all(path.parent().unwrap()).unwrap();
+            fs::write(path, content).unwrap();
+        }
+
+        let root = dir.path().canonicalize().unwrap();
+        let workspace = GoWorkspace::module(&root).unwrap();
+        let graph = PackageGraph::load(&root, Some(&workspace));
+
+        let mains: Vec<_> = graph
+            .main_packages()
+            .filter_map(|p| p.import_path.clone())
+            .collect();
+        assert_eq!(
+            mains,
+            vec!["example.com/app/cmd/keygen", "example.com/app/cmd/server"]
+        );
+
+        let reachable = graph.reachable_from(&root.join("cmd/server"));
+        let names: Vec<_> = reachable
+            .iter()
+            .map(|d| {
+                d.strip_prefix(&root)
+                    .unwrap()
+                    .to_string_lossy()
+                    .into_owned()
+            })
+            .collect();
+        assert_eq!(
+            names,
+            vec!["cmd/server", "internal/kdf", "internal/tlsconf"]
+        );
+    }
+}
@@ -0,0 +1,125 @@
+//! Environment for the `go` commands run during discovery, keeping them offline or on a
+//! given proxy and off `go.mod`.
+
+use std::env;
+use std::path::Path;
+use std::process::Command;
+
+use super::config::{
+    GOFLAGS_ENV, GOPROXY_ENV, GOPROXY_OFF, GOTOOLCHAIN_ENV, GOTOOLCHAIN_LOCAL, GO_COMMAND,
+    GO_MOD_FLAG, GO_MOD_READONLY,
+};
+
+/// How discovery's `go` commands may reach modules.
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct GoEnv {
+    /// Resolve modules from the local module cache only.
+    pub offline: bool,
+    /// Module proxy to use instead of `$GOPROXY`.
+    pub proxy: Option<String>,
+}
+
+impl GoEnv {
+    /// A `go` command with this environment; `module_mode` is false for commands run
+    /// with `GO111MODULE=off`.
+    pub fn command(&self, module_mode: bool) -> Command {
+        let goflags = env::var(GOFLAGS_ENV).ok();
+        let mut command = Command::new(GO_COMMAND);
+        command.envs(self.vars(module_mode, goflags.as_deref()));
+        command
+    }
+
+    /// Version of the toolchain `go` commands run in `dir` use, e.g. `go1.22.5`, or
+    /// `None` without a working `go` command.
+    pub fn toolchain_version(&self, dir: &Path) -> Option<String> {
+        let output = self
+            .command(false)
+            .args(["env", "GOVERSION"])
+            .current_dir(dir)
+            .output()
+            .ok()?;
+        let version = String::from_utf8(output.stdout).ok()?.trim().to_string();
+        (output.status.success() && !version.is_empty()).then_some(version)
+    }
+
+    /// Variables to set on a `go` command. `goflags` is the inherited `$GOFLAGS`.
+    pub fn vars(&self, module_mode: bool, goflags: Option<&str>) -> Vec<(&'static str, String)> {
+        let mut vars = Vec::new();
+        if self.offline {
+            vars.push((GOPROXY_ENV, GOPROXY_OFF.to_string()));
+            vars.push((GOTOOLCHAIN_ENV, GOTOOLCHAIN_LOCAL.to_string()));
+        } else if let Some(proxy) = &self.proxy {
+            vars.push((GOPROXY_ENV, proxy.clone()));
+        }
+
+        // `-mod` is rejected outside module mode
+        let goflags = goflags.unwrap_or_default().trim();
+        if module_mode
+            && !goflags
+                .split_whitespace()
+                .any(|f| f.trim_start_matches('-').starts_with("mod="))
+        {
+            let readonly = format!("{GO_MOD_FLAG}={GO_MOD_READONLY}");
+            let flags = if goflags.is_empty() {
+                readonly
+            } else {
+                format!("{goflags} {readonly}")
+            };
+            vars.push((GOFLAGS_ENV, flags));
+        }
+        vars
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_offline_env() {
+        let offline = GoEnv {
+            offline: true,
+            proxy: None,
+        };
+        assert_eq!(
+            offline.vars(true, Some("-tags=integration")),
+            vec![
+                ("GOPROXY", "off".to_string()),
+                ("GOTOOLCHAIN", "local".to_string()),
+                ("GOFLAGS", "-tags=integration -mod=readonly".to_string()),
+            ]
+        );
+
+        // `-modcacherw` is a different flag
+        assert_eq!(
+            offline.vars(true, Some("-modcacherw"))[2],
+            ("GOFLAGS", "-modcacherw -mod=readonly".to_string())
+        );
+
+        // An explicit `-mod`, e.g. `-mod=vendor`, is kept, and GOPATH mode gets none
+        assert_eq!(
+            offline.vars(true, Some("-mod=vendor")),
+            vec![
+                ("GOPROXY", "off".to_string()),
+                ("GOTOOLCHAIN", "local".to_string()),
+            ]
+        );
+        assert_eq!(offline.vars(false, None).len(), 2);
+    }
+
+    #[test]
+    fn test_proxy_env() {
+        let proxied = GoEnv {
+            offline: false,
+            proxy: Some("https://goproxy.corp.example".to_string()),
+        };
+        assert_eq!(
+            proxied.vars(true, None),
+            vec![
+                ("GOPROXY", "https://goproxy.corp.example".to_string()),
+                ("GOFLAGS", "-mod=readonly".to_string()),
+            ]
+        );
+        assert!(GoEnv::default().vars(false, None).is_empty());
+    }
+}
@@ -0,0 +1,145 @@
+use std::collections::BTreeMap;
+use std::path::{Component, Path, PathBuf};
+
+use crate::discovery::utils::canonical_path;
+
+use super::config::{GOPATH_SRC_DIR, VENDOR_DIR};
+use super::gomod::{find_go_mod, GoMod};
+
+/// Maps package directories to Go import paths for the tree being scanned.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum GoWorkspace {
+    /// A module rooted at the directory containing `go.mod`.
+    Module { root: PathBuf, module_path: String },
+    /// A GOPATH workspace: import paths are relative to `$GOPATH/src`.
+    Gopath { gopath: PathBuf },
+    /// Import paths reported per directory by a build system, e.g. a Bazel monorepo.
+    Packages {
+        import_paths: BTreeMap<PathBuf, String>,
+    },
+}
+
+impl GoWorkspace {
+    /// Locates the module that owns `start` via its nearest `go.mod`.
+    pub fn module(start: &Path) -> Option<Self> {
+        let go_mod_path = find_go_mod(start)?;
+        let module_path = GoMod::from_file(&go_mod_path).ok()?.module?;
+        let root = go_mod_path.parent()?;
+        Some(GoWorkspace::Module {
+            root: canonical_path(root),
+            module_path,
+        })
+    }
+
+    pub fn gopath(gopath: PathBuf) -> Self {
+        GoWorkspace::Gopath {
+            gopath: canonical_path(&gopath),
+        }
+    }
+
+    pub fn packages(import_paths: impl IntoIterator<Item = (PathBuf, String)>) -> Self {
+        GoWorkspace::Packages {
+            import_paths: import_paths
+                .into_iter()
+                .map(|(dir, import_path)| (canonical_path(&dir), import_path))
+                .collect(),
+        }
+    }
+
+    /// The import path of the package in `dir`, if the directory belongs to this workspace.
+    pub fn import_path_for_dir(&self, dir: &Path) -> Option<String> {
+        let dir = canonical_path(dir);
+
+        if let Some(vendored) = vendored_import_path(&dir) {
+            return Some(vendored);
+        }
+
+        match self {
+            GoWorkspace::Module { root, module_path } => {
+                let relative = dir.strip_prefix(root).ok()?;
+                Some(join_import_path(module_path, relative))
+            }
+            GoWorkspace::Gopath { gopath } => {
+                let relative = dir.strip_prefix(gopath.join(GOPATH_SRC_DIR)).ok()?;
+                let import_path = join_import_path("", relative);
+                (!import_path.is_empty()).then_some(import_path)
+            }
+            GoWorkspace::Packages { import_paths } => import_paths.get(&dir).cloned(),
+        }
+    }
+}
+
+/// `.../vendor/github.com/x/y` -> `github.com/x/y`
+fn vendored_import_path(dir: &Path) -> Option<String> {
+    let components: Vec<_> = dir.components().collect();
+    let vendor_idx = components
+        .iter()
+        .rposition(|c| matches!(c, Component::Normal(name) if *name == VENDOR_DIR))?;
+    let relative: PathBuf = components[vendor_idx + 1..].iter().collect();
+    let import_path = join_import_path("", &relative);
+    (!import_path.is_empty()).then_some(import_path)
+}
+
+fn join_import_path(prefix: &str, relative: &Path) -> String {
+    let mut parts: Vec<String> = Vec::new();
+    if !prefix.is_empty() {
+        parts.push(prefix.to_string());
+    }
+    parts.extend(relative.components().filter_map(|c| match c {
+        Component::Normal(name) => Some(name.to_string_lossy().to_string()),
+        _ => None,
+    }));
+    parts.join("/")
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::fs;
+    use tempfile::TempDir;
+
+    #[test]
+    fn test_module_import_paths() {
+        let temp_dir = TempDir::new().unwrap();
+        fs::write(
+            temp_dir.path().join("go.mod"),
+            "module github.com/example/app\n\ngo 1.22\n",
+        )
+        .unwrap();
+        let config_dir = temp_dir.path().join("internal/config");
+        fs::create_dir_all(&config_dir).unwrap();
+
+        let workspace = GoWorkspace::module(temp_dir.path()).unwrap();
+        assert_eq!(
+            workspace.import_path_for_dir(temp_dir.path()),
+            Some("github.com/example/app".to_string())
+        );
+        assert_eq!(
+            workspace.import_path_for_dir(&config_dir),
+            Some("github.com/example/app/internal/config".to_string())
+        );
+    }
+
+    #[test]
+    fn test_gopath_import_paths() {
+        let temp_dir = TempDir::new().unwrap();
+        let pkg_dir = temp_dir.path().join("src/github.com/acme/legacy/crypto");
+        fs::create_dir_all(&pkg_dir).unwrap();
+
+        let workspace = GoWorkspace::gopath(temp_dir.path().to_path_buf());
+        assert_eq!(
+            workspace.import_path_for_dir(&pkg_dir),
+            Some("github.com/acme/legacy/crypto".to_string())
+        );
+        assert_eq!(workspace.import_path_for_dir(temp_dir.path()), None);
+    }
+
+    #[test]
+    fn test_vendored_import_paths() {
+        let workspace = GoWorkspace::gopath(PathBuf::from("/nonexistent/gopath"));
+        assert_eq!(
+            workspace.import_path_for_dir(Path::new("/work/app/vendor/golang.org/x/crypto/pbkdf2")),
+            Some("golang.org/x/crypto/pbkdf2".to_string())
+        );
+    }
+}
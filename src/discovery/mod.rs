@@ -3,6 +3,7 @@ pub mod detector;
 pub mod filter;
 pub mod languages;
 pub mod loader;
+pub mod overlay;
 pub mod utils;
 
 pub use cache::DiscoveryCache;
@@ -10,6 +11,7 @@ pub use detector::LanguageDetector;
 pub use filter::ImportFileFilter;
 pub use languages::{GoImportFilter, GoPackageLoader, LanguageModule, LanguageRegistry};
 pub use loader::PackageLoader;
+pub use overlay::Overlay;
 pub use utils::walk_source_files;
 
 use std::path::PathBuf;
@@ -0,0 +1,211 @@
+//! File overlays in the format of the go command's `-overlay` flag.
+
+use std::collections::BTreeMap;
+use std::fs;
+use std::io;
+use std::path::{Path, PathBuf};
+
+use serde::Deserialize;
+
+use crate::cli::Language;
+
+use super::loader::LoadError;
+use super::utils::canonical_path;
+use super::{FileMetadata, SourceFile, SourceType};
+
+#[derive(Debug, Deserialize)]
+struct OverlayJson {
+    #[serde(rename = "Replace", default)]
+    replace: BTreeMap<PathBuf, String>,
+}
+
+/// Overlaid paths and their replacements; `None` deletes the file.
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct Overlay {
+    replace: BTreeMap<PathBuf, Option<PathBuf>>,
+}
+
+impl Overlay {
+    pub fn from_file(path: &Path) -> Result<Self, LoadError> {
+        let content = fs::read_to_string(path).map_err(|e| {
+            LoadError::InvalidPath(format!("Failed to read overlay {}: {e}", path.display()))
+        })?;
+        let base = std::env::current_dir()?;
+        Self::parse(&content, &base)
+            .map_err(|e| LoadError::InvalidPath(format!("Invalid overlay {}: {e}", path.display())))
+    }
+
+    /// Parses overlay JSON, resolving relative paths against `base`.
+    pub fn parse(content: &str, base: &Path) -> Result<Self, serde_json::Error> {
+        let overlay: OverlayJson = serde_json::from_str(content)?;
+        Ok(Overlay {
+            replace: overlay
+                .replace
+                .into_iter()
+                .map(|(path, replacement)| {
+                    let replacement = (!replacement.is_empty()).then(|| base.join(replacement));
+                    (normalize(&base.join(path)), replacement)
+                })
+                .collect(),
+        })
+    }
+
+    pub fn is_empty(&self) -> bool {
+        self.replace.is_empty()
+    }
+
+    /// The file holding the contents of `path`, or `None` when the overlay deletes it.
+    pub fn source_path(&self, path: &Path) -> Option<PathBuf> {
+        if self.is_empty() {
+            return Some(path.to_path_buf());
+        }
+        match self.replace.get(&absolute(path)) {
+            Some(replacement) => replacement.clone(),
+            None => Some(path.to_path_buf()),
+        }
+    }
+
+    /// The contents of `path` with the overlay applied.
+    pub fn read_to_string(&self, path: &Path) -> io::Result<String> {
+        match self.source_path(path) {
+            Some(source) => fs::read_to_string(source),
+            None => Err(io::Error::new(
+                io::ErrorKind::NotFound,
+                format!("{} is deleted by the overlay", path.display()),
+            )),
+        }
+    }
+
+    /// Removes the files the overlay deletes from `files`, and adds the overlaid files
+    /// with `extension` under `root` that were not discovered on disk.
+    pub fn apply(
+        &self,
+        root: &Path,
+        language: Language,
+        extension: &str,
+        files: &mut Vec<SourceFile>,
+    ) {
+        if self.is_empty() {
+            return;
+        }
+        files.retain(|file| self.source_path(&file.path).is_some());
+
+        let root = canonical_path(&std::env::current_dir().unwrap_or_default().join(root));
+        let discovered: Vec<PathBuf> = files.iter().map(|file| absolute(&file.path)).collect();
+        for (path, replacement) in &self.replace {
+            let Some(replacement) = replacement else {
+                continue;
+            };
+            if !path.starts_with(&root)
+                || path.extension().map_or(true, |e| e != extension)
+                || discovered.contains(path)
+            {
+                continue;
+            }
+            let metadata = fs::metadata(replacement).ok();
+            files.push(SourceFile {
+                path: path.clone(),
+                language,
+                source_type: SourceType::UserCode,
+                package: None,
+                metadata: FileMetadata {
+                    size: metadata.as_ref().map_or(0, |m| m.len()),
+                    modified: metadata.and_then(|m| m.modified().ok()),
+                    hash: None,
+                },
+            });
+        }
+    }
+}
+
+/// `path` resolved against the working directory, like the overlay's own paths.
+fn absolute(path: &Path) -> PathBuf {
+    let cwd = std::env::current_dir().unwrap_or_default();
+    normalize(&cwd.join(path))
+}
+
+/// `path` with its directory canonicalized, so overlaid files that do not exist yet
+/// still compare equal to discovered paths through symlinks.
+fn normalize(path: &Path) -> PathBuf {
+    match (path.parent(), path.file_name()) {
+        (Some(dir), Some(name)) if !dir.as_os_str().is_empty() => canonical_path(dir).join(name),
+        _ => path.to_path_buf(),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    fn source_file(path: PathBuf) -> SourceFile {
+        SourceFile {
+            path,
+            language: Language::Go,
+            source_type: SourceType::UserCode,
+            package: None,
+            metadata: FileMetadata {
+                size: 0,
+                modified: None,
+                hash: None,
+            },
+        }
+    }
+
+    #[test]
+    fn test_overlay_replaces_deletes_and_adds_files() {
+        let dir = TempDir::new().unwrap();
+        let root = dir.path().join("app");
+        fs::create_dir(&root).unwrap();
+        fs::write(root.join("config.go"), "package app\n\nconst KeyLen = 16\n").unwrap();
+        fs::write(root.join("old.go"), "package app\n").unwrap();
+        fs::write(
+            dir.path().join("buffer.go"),
+            "package app\n\nconst KeyLen = 32\n",
+        )
+        .unwrap();
+        fs::write(
+            dir.path().join("gen.go"),
+            "package app\n\nconst Rounds = 4\n",
+        )
+        .unwrap();
+
+        let overlay = Overlay::parse(
+            r#"{"Replace": {
+                "app/config.go": "buffer.go",
+                "app/old.go": "",
+                "app/zz_generated.go": "gen.go",
+                "elsewhere/other.go": "gen.go"
+            }}"#,
+            dir.path(),
+        )
+        .unwrap();
+
+        assert_eq!(
+            overlay.read_to_string(&root.join("config.go")).unwrap(),
+            "package app\n\nconst KeyLen = 32\n"
+        );
+        assert!(overlay.read_to_string(&root.join("old.go")).is_err());
+
+        let mut files = vec![
+            source_file(root.join("config.go")),
+            source_file(root.join("old.go")),
+        ];
+        overlay.apply(&root, Language::Go, "go", &mut files);
+        let paths: Vec<_> = files
+            .iter()
+            .map(|file| {
+                file.path
+                    .file_name()
+                    .unwrap()
+                    .to_string_lossy()
+                    .into_owned()
+            })
+            .collect();
+        assert_eq!(paths, vec!["config.go", "zz_generated.go"]);
+        assert_eq!(
+            overlay.read_to_string(&files[1].path).unwrap(),
+            "package app\n\nconst Rounds = 4\n"
+        );
+    }
+}
@@ -15,17 +15,21 @@ pub fn walk_source_files(
 ) -> Result<Vec<PathBuf>, LoadError> {
     let mut files = Vec::new();
 
-    for entry in WalkDir::new(root).into_iter().filter_entry(|e| {
-        let name = e.file_name().to_string_lossy();
-        if e.file_type().is_dir() {
-            if exclude_hidden && name.starts_with('.') {
-                return false;
+    for entry in WalkDir::new(root)
+        .sort_by_file_name()
+        .into_iter()
+        .filter_entry(|e| {
+            let name = e.file_name().to_string_lossy();
+            if e.file_type().is_dir() {
+                if exclude_hidden && name.starts_with('.') {
+                    return false;
+                }
+                !excluded_dirs.contains(&name.as_ref())
+            } else {
+                true
             }
-            !excluded_dirs.contains(&name.as_ref())
-        } else {
-            true
-        }
-    }) {
+        })
+    {
         let entry = entry.map_err(|e| LoadError::DirectoryScanError {
             path: root.to_path_buf(),
             source: e,
@@ -59,6 +63,48 @@ pub fn walk_source_files(
     Ok(files)
 }
 
+/// Whether the platform's default filesystem ignores case (NTFS, APFS).
+const CASE_INSENSITIVE_FS: bool = cfg!(any(windows, target_os = "macos"));
+
+/// `path` resolved like `Path::canonicalize`, or `path` itself when it cannot be resolved.
+/// The `\\?\` prefix canonicalize adds on Windows is removed, so the result compares
+/// equal to paths reported by `go list` and build drivers.
+pub fn canonical_path(path: &Path) -> PathBuf {
+    let canonical = path.canonicalize().unwrap_or_else(|_| path.to_path_buf());
+    strip_verbatim_prefix(canonical)
+}
+
+/// `\\?\C:\src` -> `C:\src`, `\\?\UNC\server\share` -> `\\server\share`
+fn strip_verbatim_prefix(path: PathBuf) -> PathBuf {
+    let plain = {
+        let text = path.to_string_lossy();
+        match text.strip_prefix(r"\\?\UNC\") {
+            Some(unc) => Some(PathBuf::from(format!(r"\\{unc}"))),
+            None => text
+                .strip_prefix(r"\\?\")
+                .filter(|rest| rest.as_bytes().get(1) == Some(&b':'))
+                .map(PathBuf::from),
+        }
+    };
+    plain.unwrap_or(path)
+}
+
+/// A key naming `path` however it is spelled: canonical, with `/` separators on Windows,
+/// and lower-cased where the filesystem ignores case.
+pub fn path_key(path: &Path) -> String {
+    let key = canonical_path(path).to_string_lossy().into_owned();
+    let key = if cfg!(windows) {
+        key.replace('\\', "/")
+    } else {
+        key
+    };
+    if CASE_INSENSITIVE_FS {
+        key.to_lowercase()
+    } else {
+        key
+    }
+}
+
 #[derive(Debug, Deserialize)]
 struct MappingsFile {
     mappings: std::collections::HashMap<String, std::collections::HashMap<String, String>>,
@@ -108,6 +154,41 @@ mod tests {
     use std::io::Write;
     use tempfile::TempDir;
 
+    #[test]
+    fn test_strip_verbatim_prefix() {
+        assert_eq!(
+            strip_verbatim_prefix(PathBuf::from(r"\\?\C:\src\app")),
+            PathBuf::from(r"C:\src\app")
+        );
+        assert_eq!(
+            strip_verbatim_prefix(PathBuf::from(r"\\?\UNC\build\share\app")),
+            PathBuf::from(r"\\build\share\app")
+        );
+        // Other verbatim forms have no plain spelling
+        assert_eq!(
+            strip_verbatim_prefix(PathBuf::from(r"\\?\Volume{1234}\app")),
+            PathBuf::from(r"\\?\Volume{1234}\app")
+        );
+        assert_eq!(
+            strip_verbatim_prefix(PathBuf::from("/src/app")),
+            PathBuf::from("/src/app")
+        );
+    }
+
+    #[test]
+    fn test_path_key_resolves_spellings() {
+        let temp_dir = TempDir::new().unwrap();
+        let root = temp_dir.path();
+        fs::create_dir_all(root.join("app")).unwrap();
+
+        assert_eq!(
+            path_key(&root.join("app")),
+            path_key(&root.join("app").join("..").join("app"))
+        );
+        // A path that does not exist keys as written
+        assert_eq!(path_key(Path::new("/no/such/dir")), "/no/such/dir");
+    }
+
     #[test]
     fn test_walk_source_files_finds_files_with_extension() {
         let temp_dir = TempDir::new().unwrap();
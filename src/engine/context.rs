@@ -1,16 +1,22 @@
-use std::cell::RefCell;
+use std::cell::{Cell, RefCell};
 use std::collections::{HashMap, HashSet};
 use std::path::Path;
 use std::rc::Rc;
+use std::sync::Arc;
 use tree_sitter::{Node, Tree};
 
 use super::file_cache::{FileCache, FunctionInfo};
 use super::lang_features;
 use super::node_types::{Language, NodeCategory, NodeTypes};
+use super::overrides::ValueOverrides;
 use super::scope::{Scope, ScopeEntry};
 
 const MAX_CACHE_SIZE: usize = 10_000;
 
+/// How many declarations a value is followed through by default, e.g.
+/// `DefaultIterations` -> `PBKDF2Iterations` -> literal is two.
+pub const DEFAULT_MAX_DERIVATION_DEPTH: usize = 32;
+
 pub struct Context<'a> {
     tree: &'a Tree,
     source_code: &'a [u8],
@@ -20,8 +26,15 @@ pub struct Context<'a> {
     scopes: RefCell<Vec<Scope>>,
     constants: RefCell<HashMap<String, ScopeEntry>>,
     file_cache: Option<Rc<RefCell<FileCache>>>,
+    /// Package alias -> import path for the file being analyzed
+    imports: HashMap<String, String>,
     value_cache: RefCell<HashMap<usize, crate::Value>>,
     visited_nodes: RefCell<HashSet<usize>>,
+    /// Declarations followed on the way to the value being resolved.
+    derivation_depth: Cell<usize>,
+    max_derivation_depth: usize,
+    /// Values substituted for package-level names, from `argflow simulate --set`.
+    overrides: Arc<ValueOverrides>,
 }
 
 impl<'a> Context<'a> {
@@ -41,8 +54,12 @@ impl<'a> Context<'a> {
             scopes: RefCell::new(Vec::new()),
             constants: RefCell::new(HashMap::new()),
             file_cache: None,
+            imports: HashMap::new(),
             value_cache: RefCell::new(HashMap::new()),
             visited_nodes: RefCell::new(HashSet::new()),
+            derivation_depth: Cell::new(0),
+            max_derivation_depth: DEFAULT_MAX_DERIVATION_DEPTH,
+            overrides: Arc::default(),
         }
     }
 
@@ -63,11 +80,46 @@ impl<'a> Context<'a> {
             scopes: RefCell::new(Vec::new()),
             constants: RefCell::new(HashMap::new()),
             file_cache: Some(file_cache),
+            imports: HashMap::new(),
             value_cache: RefCell::new(HashMap::new()),
             visited_nodes: RefCell::new(HashSet::new()),
+            derivation_depth: Cell::new(0),
+            max_derivation_depth: DEFAULT_MAX_DERIVATION_DEPTH,
+            overrides: Arc::default(),
         }
     }
 
+    pub fn with_imports(mut self, imports: HashMap<String, String>) -> Self {
+        self.imports = imports;
+        self
+    }
+
+    pub fn with_max_derivation_depth(mut self, depth: usize) -> Self {
+        self.max_derivation_depth = depth;
+        self
+    }
+
+    pub fn with_overrides(mut self, overrides: Arc<ValueOverrides>) -> Self {
+        self.overrides = overrides;
+        self
+    }
+
+    /// Steps into a declaration; `false` if that would exceed the derivation depth
+    /// limit, in which case the value should not be followed.
+    pub fn enter_derivation(&self) -> bool {
+        let depth = self.derivation_depth.get();
+        if depth >= self.max_derivation_depth {
+            return false;
+        }
+        self.derivation_depth.set(depth + 1);
+        true
+    }
+
+    pub fn exit_derivation(&self) {
+        self.derivation_depth
+            .set(self.derivation_depth.get().saturating_sub(1));
+    }
+
     pub fn tree(&self) -> &Tree {
         self.tree
     }
@@ -245,6 +297,47 @@ impl<'a> Context<'a> {
         }
     }
 
+    /// Resolves `package.name` through the file's imports to a constant in another package.
+    pub fn find_imported_constant(&self, package: &str, name: &str) -> Option<crate::Value> {
+        let import_path = self.imports.get(package)?;
+        let cache = self.file_cache.as_ref()?;
+        let cache = cache.borrow();
+        cache.find_package_constant(import_path, name)
+    }
+
+    /// Directory of the package the file imports as `package`, when it is in the index.
+    pub fn imported_package_dir(&self, package: &str) -> Option<String> {
+        let import_path = self.imports.get(package)?;
+        let cache = self.file_cache.as_ref()?;
+        let cache = cache.borrow();
+        cache.package_dir_for(import_path).map(str::to_string)
+    }
+
+    /// The value overriding the package-level `name` of the package the file imports as
+    /// `package`, or of the file's own package.
+    pub fn value_override(&self, package: Option<&str>, name: &str) -> Option<crate::Value> {
+        if self.overrides.is_empty() {
+            return None;
+        }
+        let package_dir = match package {
+            Some(alias) => self.imported_package_dir(alias).unwrap_or_else(|| {
+                self.imports
+                    .get(alias)
+                    .cloned()
+                    .unwrap_or_else(|| alias.to_string())
+            }),
+            None => self.package_dir()?,
+        };
+        self.overrides.get(&package_dir, name)
+    }
+
+    /// The value stored with `context.WithValue` under the package-level key `name`.
+    pub fn find_context_value(&self, name: &str) -> Option<crate::Value> {
+        let cache = self.file_cache.as_ref()?;
+        let cache = cache.borrow();
+        cache.find_context_value(name, self.package_dir().as_deref())
+    }
+
     pub fn find_cross_file_function(&self, name: &str) -> Option<FunctionInfo> {
         let cache = self.file_cache.as_ref()?;
         let cache = cache.borrow();
@@ -518,6 +611,7 @@ mod tests {
             CachedFileEntry {
                 constants,
                 functions: HashMap::new(),
+                context_values: HashMap::new(),
             },
         );
 
@@ -541,6 +635,45 @@ mod tests {
         assert_eq!(value.int_values, vec![42]);
     }
 
+    #[test]
+    fn test_context_find_imported_constant() {
+        let source = b"package main";
+        let tree = parse_go_source("package main");
+
+        let mut file_cache = FileCache::new();
+        let mut constants = HashMap::new();
+        constants.insert("KeySize".to_string(), crate::Value::resolved_int(32));
+        file_cache.add_file(
+            "/app/config/config.go".to_string(),
+            CachedFileEntry {
+                constants,
+                functions: HashMap::new(),
+                context_values: HashMap::new(),
+            },
+        );
+        file_cache.register_package(
+            "example.com/app/config".to_string(),
+            "/app/config".to_string(),
+        );
+
+        let ctx = Context::with_file_cache(
+            &tree,
+            source,
+            "/app/main.go".to_string(),
+            "go".to_string(),
+            HashMap::new(),
+            Rc::new(RefCell::new(file_cache)),
+        )
+        .with_imports(HashMap::from([(
+            "cfg".to_string(),
+            "example.com/app/config".to_string(),
+        )]));
+
+        let value = ctx.find_imported_constant("cfg", "KeySize").unwrap();
+        assert_eq!(value.int_values, vec![32]);
+        assert!(ctx.find_imported_constant("config", "KeySize").is_none());
+    }
+
     #[test]
     fn test_context_without_file_cache() {
         let source = b"package main";
@@ -0,0 +1,112 @@
+//! Import paths declared to name the same package, such as a vendored fork and its
+//! upstream.
+
+/// Fork/upstream import path pairs. A fork may be given relative to its module
+/// (`internal/thirdparty/config`); it then matches any import path ending in it.
+#[derive(Debug, Clone, Default)]
+pub struct ImportEquivalences {
+    pairs: Vec<(String, String)>,
+}
+
+impl ImportEquivalences {
+    pub fn new(pairs: Vec<(String, String)>) -> Self {
+        let pairs = pairs
+            .into_iter()
+            .map(|(fork, upstream)| {
+                (
+                    fork.trim_matches('/').to_string(),
+                    upstream.trim_matches('/').to_string(),
+                )
+            })
+            .collect();
+        Self { pairs }
+    }
+
+    /// Declared fork paths, as given.
+    pub fn forks(&self) -> impl Iterator<Item = &str> {
+        self.pairs.iter().map(|(fork, _)| fork.as_str())
+    }
+
+    /// The upstream path for `import_path` if it is, or is below, a declared fork:
+    /// `example.com/app/internal/thirdparty/config/v2` becomes `github.com/acme/config/v2`.
+    pub fn canonical(&self, import_path: &str) -> String {
+        self.pairs
+            .iter()
+            .find_map(|(fork, upstream)| {
+                fork_subpath(fork, import_path).map(|rest| format!("{upstream}{rest}"))
+            })
+            .unwrap_or_else(|| import_path.to_string())
+    }
+
+    /// Whether `candidate` is a fork declared equivalent to `import_path`, at the same
+    /// subpackage.
+    pub fn is_fork_of(&self, candidate: &str, import_path: &str) -> bool {
+        self.pairs.iter().any(|(fork, upstream)| {
+            let Some(rest) = import_path.strip_prefix(upstream.as_str()) else {
+                return false;
+            };
+            (rest.is_empty() || rest.starts_with('/'))
+                && fork_subpath(fork, candidate) == Some(rest)
+        })
+    }
+}
+
+/// The part of `import_path` below `fork` (empty or starting with `/`), if `import_path`
+/// is `fork`, ends in `/fork`, or is a subpackage of either.
+fn fork_subpath<'a>(fork: &str, import_path: &'a str) -> Option<&'a str> {
+    if fork.is_empty() {
+        return None;
+    }
+    let mut start = 0;
+    while let Some(offset) = import_path[start..].find(fork) {
+        let at = start + offset;
+        let end = at + fork.len();
+        let rest = &import_path[end..];
+        let bounded_left = at == 0 || import_path.as_bytes()[at - 1] == b'/';
+        if bounded_left && (rest.is_empty() || rest.starts_with('/')) {
+            return Some(rest);
+        }
+        start = at + import_path[at..].chars().next().map_or(1, char::len_utf8);
+    }
+    None
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn equivalences() -> ImportEquivalences {
+        ImportEquivalences::new(vec![(
+            "internal/thirdparty/config".to_string(),
+            "github.com/acme/config".to_string(),
+        )])
+    }
+
+    #[test]
+    fn test_canonical_maps_fork_to_upstream() {
+        let eq = equivalences();
+        assert_eq!(
+            eq.canonical("example.com/app/internal/thirdparty/config"),
+            "github.com/acme/config"
+        );
+        assert_eq!(
+            eq.canonical("example.com/app/internal/thirdparty/config/v2"),
+            "github.com/acme/config/v2"
+        );
+        assert_eq!(
+            eq.canonical("example.com/app/internal/thirdparty/configx"),
+            "example.com/app/internal/thirdparty/configx"
+        );
+        assert_eq!(eq.canonical("crypto/sha256"), "crypto/sha256");
+    }
+
+    #[test]
+    fn test_is_fork_of_matches_same_subpackage() {
+        let eq = equivalences();
+        let fork = "example.com/app/internal/thirdparty/config";
+        assert!(eq.is_fork_of(fork, "github.com/acme/config"));
+        assert!(eq.is_fork_of(&format!("{fork}/v2"), "github.com/acme/config/v2"));
+        assert!(!eq.is_fork_of(fork, "github.com/acme/config/v2"));
+        assert!(!eq.is_fork_of(fork, "github.com/acme/configs"));
+    }
+}
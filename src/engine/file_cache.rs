@@ -1,12 +1,16 @@
 use std::collections::HashMap;
 use std::path::Path;
 
+use super::equivalence::ImportEquivalences;
+
 const MAX_FILE_CACHE_SIZE: usize = 100;
 
 #[derive(Debug, Clone)]
 pub struct CachedFileEntry {
     pub constants: HashMap<String, crate::Value>,
     pub functions: HashMap<String, FunctionInfo>,
+    /// Values stored with `context.WithValue`, by the name of their package-level key.
+    pub context_values: HashMap<String, crate::Value>,
 }
 
 #[derive(Debug, Clone)]
@@ -16,10 +20,21 @@ pub struct FunctionInfo {
     pub end_byte: usize,
 }
 
-#[derive(Debug, Default)]
+#[derive(Debug)]
 pub struct FileCache {
     entries: HashMap<String, CachedFileEntry>,
     load_order: Vec<String>,
+    /// Import path -> package directory, for resolving `pkg.Constant` across packages
+    packages: HashMap<String, String>,
+    /// Forks declared equivalent to upstream paths, consulted when a path is not registered
+    equivalences: ImportEquivalences,
+    capacity: usize,
+}
+
+impl Default for FileCache {
+    fn default() -> Self {
+        Self::with_capacity(MAX_FILE_CACHE_SIZE)
+    }
 }
 
 impl FileCache {
@@ -27,8 +42,20 @@ impl FileCache {
         Self::default()
     }
 
+    /// Creates a cache holding up to `capacity` files. Project-wide indexes need
+    /// more room than the default, which is sized for on-demand lookups.
+    pub fn with_capacity(capacity: usize) -> Self {
+        Self {
+            entries: HashMap::new(),
+            load_order: Vec::new(),
+            packages: HashMap::new(),
+            equivalences: ImportEquivalences::default(),
+            capacity,
+        }
+    }
+
     pub fn add_file(&mut self, file_path: String, entry: CachedFileEntry) {
-        if self.entries.len() >= MAX_FILE_CACHE_SIZE && !self.entries.contains_key(&file_path) {
+        if self.entries.len() >= self.capacity && !self.entries.contains_key(&file_path) {
             if let Some(oldest) = self.load_order.first().cloned() {
                 self.entries.remove(&oldest);
                 self.load_order.remove(0);
@@ -46,34 +73,74 @@ impl FileCache {
     }
 
     pub fn find_constant(&self, name: &str) -> Option<crate::Value> {
-        for entry in self.entries.values() {
-            if let Some(value) = entry.constants.get(name) {
-                return Some(value.clone());
-            }
-        }
-        None
+        self.entries
+            .values()
+            .find_map(|entry| entry.constants.get(name).cloned())
     }
 
     pub fn find_constant_in_package(&self, name: &str, package_dir: &str) -> Option<crate::Value> {
-        for (path, entry) in &self.entries {
-            if let Some(parent) = Path::new(path).parent() {
-                if parent.to_string_lossy() == package_dir {
-                    if let Some(value) = entry.constants.get(name) {
-                        return Some(value.clone());
-                    }
-                }
+        self.entries.iter().find_map(|(path, entry)| {
+            let parent = Path::new(path).parent()?;
+            if parent.to_string_lossy() != package_dir {
+                return None;
             }
+            entry.constants.get(name).cloned()
+        })
+    }
+
+    /// Values stored under the context key `name`, preferring stores in `package_dir`.
+    pub fn find_context_value(
+        &self,
+        name: &str,
+        package_dir: Option<&str>,
+    ) -> Option<crate::Value> {
+        let stored = |same_package: bool| -> Vec<crate::Value> {
+            self.entries
+                .iter()
+                .filter(|(path, _)| {
+                    !same_package
+                        || Path::new(path).parent().map(|p| p.to_string_lossy())
+                            == package_dir.map(Into::into)
+                })
+                .filter_map(|(_, entry)| entry.context_values.get(name).cloned())
+                .collect()
+        };
+        let mut values = stored(true);
+        if values.is_empty() {
+            values = stored(false);
         }
-        None
+        (!values.is_empty()).then(|| crate::Value::merge(values))
     }
 
-    pub fn find_function(&self, name: &str) -> Option<&FunctionInfo> {
-        for entry in self.entries.values() {
-            if let Some(info) = entry.functions.get(name) {
-                return Some(info);
-            }
+    pub fn register_package(&mut self, import_path: String, package_dir: String) {
+        self.packages.insert(import_path, package_dir);
+    }
+
+    pub fn set_import_equivalences(&mut self, equivalences: ImportEquivalences) {
+        self.equivalences = equivalences;
+    }
+
+    /// Directory of the package at `import_path`, or of a fork declared equivalent to it.
+    pub fn package_dir_for(&self, import_path: &str) -> Option<&str> {
+        if let Some(dir) = self.packages.get(import_path) {
+            return Some(dir.as_str());
         }
-        None
+        self.packages
+            .iter()
+            .find(|(path, _)| self.equivalences.is_fork_of(path, import_path))
+            .map(|(_, dir)| dir.as_str())
+    }
+
+    /// Looks up an exported constant of another package by its import path.
+    pub fn find_package_constant(&self, import_path: &str, name: &str) -> Option<crate::Value> {
+        let package_dir = self.package_dir_for(import_path)?;
+        self.find_constant_in_package(name, package_dir)
+    }
+
+    pub fn find_function(&self, name: &str) -> Option<&FunctionInfo> {
+        self.entries
+            .values()
+            .find_map(|entry| entry.functions.get(name))
     }
 
     pub fn file_count(&self) -> usize {
@@ -83,6 +150,7 @@ impl FileCache {
     pub fn clear(&mut self) {
         self.entries.clear();
         self.load_order.clear();
+        self.packages.clear();
     }
 }
 
@@ -90,6 +158,109 @@ impl FileCache {
 mod tests {
     use super::*;
 
+    #[test]
+    fn test_file_cache_find_package_constant() {
+        let mut cache = FileCache::new();
+
+        let mut constants = HashMap::new();
+        constants.insert(
+            "PBKDF2Iterations".to_string(),
+            crate::Value::resolved_int(600000),
+        );
+        cache.add_file(
+            "/src/app/config/constants.go".to_string(),
+            CachedFileEntry {
+                constants,
+                functions: HashMap::new(),
+                context_values: HashMap::new(),
+            },
+        );
+        cache.register_package(
+            "example.com/app/config".to_string(),
+            "/src/app/config".to_string(),
+        );
+
+        let found = cache.find_package_constant("example.com/app/config", "PBKDF2Iterations");
+        assert_eq!(found.unwrap().int_values, vec![600000]);
+        assert!(cache
+            .find_package_constant("example.com/app/other", "PBKDF2Iterations")
+            .is_none());
+    }
+
+    #[test]
+    fn test_find_package_constant_through_fork() {
+        let mut cache = FileCache::new();
+        cache.add_file(
+            "/src/app/internal/thirdparty/config/constants.go".to_string(),
+            CachedFileEntry {
+                constants: HashMap::from([(
+                    "PBKDF2Iterations".to_string(),
+                    crate::Value::resolved_int(600000),
+                )]),
+                functions: HashMap::new(),
+                context_values: HashMap::new(),
+            },
+        );
+        cache.register_package(
+            "example.com/app/internal/thirdparty/config".to_string(),
+            "/src/app/internal/thirdparty/config".to_string(),
+        );
+        assert!(cache
+            .find_package_constant("github.com/acme/config", "PBKDF2Iterations")
+            .is_none());
+
+        cache.set_import_equivalences(ImportEquivalences::new(vec![(
+            "internal/thirdparty/config".to_string(),
+            "github.com/acme/config".to_string(),
+        )]));
+        let found = cache.find_package_constant("github.com/acme/config", "PBKDF2Iterations");
+        assert_eq!(found.unwrap().int_values, vec![600000]);
+    }
+
+    #[test]
+    fn test_find_context_value_prefers_same_package() {
+        let mut cache = FileCache::new();
+        for (path, iterations) in [
+            ("/src/app/auth/ctx.go", 600000),
+            ("/src/app/legacy/ctx.go", 1000),
+        ] {
+            cache.add_file(
+                path.to_string(),
+                CachedFileEntry {
+                    constants: HashMap::new(),
+                    functions: HashMap::new(),
+                    context_values: HashMap::from([(
+                        "iterationsKey".to_string(),
+                        crate::Value::resolved_int(iterations),
+                    )]),
+                },
+            );
+        }
+
+        let found = cache.find_context_value("iterationsKey", Some("/src/app/auth"));
+        assert_eq!(found.unwrap().int_values, vec![600000]);
+        let found = cache.find_context_value("iterationsKey", Some("/src/app/handlers"));
+        assert_eq!(found.unwrap().int_values, vec![1000, 600000]);
+        assert!(cache.find_context_value("saltKey", None).is_none());
+    }
+
+    #[test]
+    fn test_file_cache_capacity() {
+        let mut cache = FileCache::with_capacity(1);
+        for path in ["/a.go", "/b.go"] {
+            cache.add_file(
+                path.to_string(),
+                CachedFileEntry {
+                    constants: HashMap::new(),
+                    functions: HashMap::new(),
+                    context_values: HashMap::new(),
+                },
+            );
+        }
+        assert_eq!(cache.file_count(), 1);
+        assert!(cache.get_file("/b.go").is_some());
+    }
+
     #[test]
     fn test_file_cache_add_and_find_constant() {
         let mut cache = FileCache::new();
@@ -102,6 +273,7 @@ mod tests {
             CachedFileEntry {
                 constants,
                 functions: HashMap::new(),
+                context_values: HashMap::new(),
             },
         );
 
@@ -125,6 +297,7 @@ mod tests {
             CachedFileEntry {
                 constants: constants1,
                 functions: HashMap::new(),
+                context_values: HashMap::new(),
             },
         );
 
@@ -133,6 +306,7 @@ mod tests {
             CachedFileEntry {
                 constants: constants2,
                 functions: HashMap::new(),
+                context_values: HashMap::new(),
             },
         );
 
@@ -162,6 +336,7 @@ mod tests {
             CachedFileEntry {
                 constants: HashMap::new(),
                 functions,
+                context_values: HashMap::new(),
             },
         );
 
@@ -185,6 +360,7 @@ mod tests {
                 CachedFileEntry {
                     constants,
                     functions: HashMap::new(),
+                    context_values: HashMap::new(),
                 },
             );
         }
@@ -208,6 +384,7 @@ mod tests {
             CachedFileEntry {
                 constants,
                 functions: HashMap::new(),
+                context_values: HashMap::new(),
             },
         );
 
@@ -0,0 +1,269 @@
+use std::collections::HashMap;
+use tree_sitter::{Node, Tree};
+
+use super::file_cache::{CachedFileEntry, FunctionInfo};
+use super::{Context, Resolver};
+
+/// Builds a [`CachedFileEntry`] for a file: its package-level constants that resolve to
+/// concrete values, its top-level functions, and the values it stores in contexts under
+/// package-level keys.
+///
+/// Only Go is indexed today; other languages yield an empty entry.
+pub fn index_file(tree: &Tree, source: &[u8], file_path: &str, language: &str) -> CachedFileEntry {
+    let mut entry = CachedFileEntry {
+        constants: HashMap::new(),
+        functions: HashMap::new(),
+        context_values: HashMap::new(),
+    };
+
+    if language != "go" {
+        return entry;
+    }
+
+    let ctx = Context::new(
+        tree,
+        source,
+        file_path.to_string(),
+        language.to_string(),
+        HashMap::new(),
+    );
+    let resolver = Resolver::new();
+
+    let root = tree.root_node();
+    let mut cursor = root.walk();
+    for child in root.children(&mut cursor) {
+        match child.kind() {
+            "const_declaration" | "var_declaration" => {
+                index_go_declaration(child, &ctx, &resolver, &mut entry);
+            }
+            "function_declaration" => {
+                if let Some(name) = child.child_by_field_name("name") {
+                    entry.functions.insert(
+                        ctx.get_node_text(&name),
+                        FunctionInfo {
+                            file_path: file_path.to_string(),
+                            start_byte: child.start_byte(),
+                            end_byte: child.end_byte(),
+                        },
+                    );
+                }
+            }
+            _ => {}
+        }
+    }
+    index_go_context_values(root, &ctx, &resolver, &mut entry);
+
+    entry
+}
+
+/// Records `context.WithValue(parent, key, value)` calls whose key is a package-level
+/// sentinel and whose value resolves, so `ctx.Value(key)` reads elsewhere can be paired.
+fn index_go_context_values<'a>(
+    node: Node<'a>,
+    ctx: &Context<'a>,
+    resolver: &Resolver,
+    entry: &mut CachedFileEntry,
+) {
+    if node.kind() == "call_expression" && is_go_with_value(node, ctx) {
+        let args = node
+            .child_by_field_name("arguments")
+            .map(|args| ctx.get_named_children(&args))
+            .unwrap_or_default();
+        if let [_, key, value] = args.as_slice() {
+            if let Some(name) = go_context_key(*key, ctx) {
+                let value = resolver.resolve(value, ctx);
+                if value.is_resolved {
+                    let merged = match entry.context_values.remove(&name) {
+                        Some(stored) => crate::Value::merge(vec![stored, value]),
+                        None => value,
+                    };
+                    entry.context_values.insert(name, merged);
+                }
+            }
+        }
+    }
+
+    let mut cursor = node.walk();
+    for child in node.named_children(&mut cursor) {
+        index_go_context_values(child, ctx, resolver, entry);
+    }
+}
+
+fn is_go_with_value<'a>(call: Node<'a>, ctx: &Context<'a>) -> bool {
+    call.child_by_field_name("function")
+        .is_some_and(|function| ctx.get_node_text(&function) == "context.WithValue")
+}
+
+/// Name of a context key that is a package-level sentinel: `ctxKeyIterations`, or
+/// `auth.IterationsKey` from another package. Keys declared in the enclosing function
+/// (parameters, locals) may differ per call and are not paired.
+pub(crate) fn go_context_key<'a>(key: Node<'a>, ctx: &Context<'a>) -> Option<String> {
+    match key.kind() {
+        "identifier" => {
+            let name = ctx.get_node_text(&key);
+            (!declared_in_function(key, &name, ctx)).then_some(name)
+        }
+        "selector_expression" => {
+            let operand = key.child_by_field_name("operand")?;
+            if operand.kind() != "identifier" {
+                return None;
+            }
+            Some(ctx.get_node_text(&key.child_by_field_name("field")?))
+        }
+        _ => None,
+    }
+}
+
+fn declared_in_function<'a>(node: Node<'a>, name: &str, ctx: &Context<'a>) -> bool {
+    let mut current = node.parent();
+    while let Some(parent) = current {
+        if matches!(
+            parent.kind(),
+            "function_declaration" | "method_declaration" | "func_literal"
+        ) {
+            return declares(parent, name, ctx);
+        }
+        current = parent.parent();
+    }
+    false
+}
+
+fn declares<'a>(node: Node<'a>, name: &str, ctx: &Context<'a>) -> bool {
+    let declared = match node.kind() {
+        "parameter_declaration" | "var_spec" | "const_spec" => {
+            let mut cursor = node.walk();
+            let found = node
+                .children_by_field_name("name", &mut cursor)
+                .any(|n| ctx.get_node_text(&n) == name);
+            found
+        }
+        "short_var_declaration" | "range_clause" => node
+            .child_by_field_name("left")
+            .map(|left| ctx.get_named_children(&left))
+            .unwrap_or_default()
+            .iter()
+            .any(|n| ctx.get_node_text(n) == name),
+        _ => false,
+    };
+    if declared {
+        return true;
+    }
+    let mut cursor = node.walk();
+    let found = node
+        .named_children(&mut cursor)
+        .any(|child| declares(child, name, ctx));
+    found
+}
+
+fn index_go_declaration<'a>(
+    decl: Node<'a>,
+    ctx: &Context<'a>,
+    resolver: &Resolver,
+    entry: &mut CachedFileEntry,
+) {
+    let mut specs = Vec::new();
+    collect_go_specs(decl, &mut specs);
+
+    for spec in specs {
+        let mut cursor = spec.walk();
+        let names: Vec<_> = spec.children_by_field_name("name", &mut cursor).collect();
+        let Some(value_list) = spec.child_by_field_name("value") else {
+            continue;
+        };
+        let values = ctx.get_named_children(&value_list);
+        if names.len() != values.len() {
+            continue;
+        }
+
+        for (name, value_node) in names.iter().zip(values) {
+            let value = resolver.resolve(&value_node, ctx);
+            if value.is_resolved {
+                entry.constants.insert(ctx.get_node_text(name), value);
+            }
+        }
+    }
+}
+
+fn collect_go_specs<'a>(node: Node<'a>, specs: &mut Vec<Node<'a>>) {
+    let mut cursor = node.walk();
+    for child in node.children(&mut cursor) {
+        match child.kind() {
+            "const_spec" | "var_spec" => specs.push(child),
+            "var_spec_list" => collect_go_specs(child, specs),
+            _ => {}
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn parse_go(source: &str) -> Tree {
+        let mut parser = tree_sitter::Parser::new();
+        parser
+            .set_language(&tree_sitter_go::LANGUAGE.into())
+            .unwrap();
+        parser.parse(source, None).unwrap()
+    }
+
+    #[test]
+    fn test_index_go_constants() {
+        let source = r#"package config
+
+const (
+	PBKDF2Iterations = 600000
+	KeySize, SaltSize = 32, 16
+	Algorithm = "sha256"
+)
+
+var DefaultRounds = PBKDF2Iterations / 2
+
+func Load() {}
+"#;
+        let tree = parse_go(source);
+        let entry = index_file(&tree, source.as_bytes(), "/app/config/config.go", "go");
+
+        assert_eq!(entry.constants["PBKDF2Iterations"].int_values, vec![600000]);
+        assert_eq!(entry.constants["KeySize"].int_values, vec![32]);
+        assert_eq!(entry.constants["SaltSize"].int_values, vec![16]);
+        assert_eq!(entry.constants["Algorithm"].string_values, vec!["sha256"]);
+        assert!(entry.functions.contains_key("Load"));
+    }
+
+    #[test]
+    fn test_index_go_context_values() {
+        let source = r#"package auth
+
+import "context"
+
+type ctxKey int
+
+const iterationsKey ctxKey = 0
+
+func WithIterations(ctx context.Context) context.Context {
+	return context.WithValue(ctx, iterationsKey, 600000)
+}
+
+func WithLocalKey(ctx context.Context, key ctxKey) context.Context {
+	return context.WithValue(ctx, key, 1000)
+}
+"#;
+        let tree = parse_go(source);
+        let entry = index_file(&tree, source.as_bytes(), "/app/auth/ctx.go", "go");
+
+        assert_eq!(
+            entry.context_values["iterationsKey"].int_values,
+            vec![600000]
+        );
+        assert!(!entry.context_values.contains_key("key"));
+    }
+
+    #[test]
+    fn test_index_skips_unresolved_values() {
+        let source = "package config\n\nvar Rounds = loadRounds()\n";
+        let tree = parse_go(source);
+        let entry = index_file(&tree, source.as_bytes(), "/app/config/config.go", "go");
+        assert!(entry.constants.is_empty());
+    }
+}
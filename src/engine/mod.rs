@@ -1,17 +1,25 @@
 pub mod context;
+pub mod equivalence;
 pub mod file_cache;
+pub mod file_index;
 pub mod lang_features;
 pub mod node_types;
 pub mod operators;
+pub mod overrides;
+pub mod resolution;
 pub mod scope;
 pub mod sources;
 pub mod strategies;
 pub mod value;
 
-pub use context::Context;
+pub use context::{Context, DEFAULT_MAX_DERIVATION_DEPTH};
+pub use equivalence::ImportEquivalences;
 pub use file_cache::{CachedFileEntry, FileCache, FunctionInfo};
+pub use file_index::index_file;
 pub use node_types::{Language, NodeCategory, NodeTypes};
 pub use operators::{BinaryOp, UnaryOp};
+pub use overrides::ValueOverrides;
+pub use resolution::{ResolutionStatus, UnknownReason};
 pub use scope::{Scope, ScopeEntry};
 pub use sources::UnresolvedSource;
 pub use value::Value;
@@ -76,8 +84,10 @@ impl Resolver {
             return Value::unextractable(UnresolvedSource::CycleDetected);
         }
 
-        // Unwrap parenthesized expressions - they're just wrappers
-        if ctx.is_node_category(node.kind(), NodeCategory::ParenthesizedExpression) {
+        // Unwrap parenthesized expressions and type assertions - they're just wrappers
+        if ctx.is_node_category(node.kind(), NodeCategory::ParenthesizedExpression)
+            || ctx.is_node_category(node.kind(), NodeCategory::TypeAssertion)
+        {
             if let Some(inner) = node.named_child(0) {
                 return self.resolve_with_depth(&inner, ctx, depth);
             }
@@ -57,6 +57,7 @@ pub enum NodeCategory {
     ArrayLiteral,
     StructLiteral,
     ParenthesizedExpression,
+    TypeAssertion,
     FunctionDeclaration,
     VariableDeclaration,
     ConstantDeclaration,
@@ -104,6 +105,7 @@ impl NodeTypes {
             NodeCategory::ArrayLiteral => self.array_literal_types(),
             NodeCategory::StructLiteral => self.struct_literal_types(),
             NodeCategory::ParenthesizedExpression => self.parenthesized_expression_types(),
+            NodeCategory::TypeAssertion => self.type_assertion_types(),
             NodeCategory::FunctionDeclaration => self.function_declaration_types(),
             NodeCategory::VariableDeclaration => self.variable_declaration_types(),
             NodeCategory::ConstantDeclaration => self.constant_declaration_types(),
@@ -305,6 +307,22 @@ impl NodeTypes {
         ["parenthesized_expression"].into_iter().collect()
     }
 
+    fn type_assertion_types(&self) -> HashSet<&'static str> {
+        // Expressions that only restate the type of their operand, e.g. `v.(int)`
+        match self.language {
+            Language::Go => ["type_assertion_expression"].into_iter().collect(),
+            Language::TypeScript => ["as_expression", "non_null_expression"]
+                .into_iter()
+                .collect(),
+            Language::Python
+            | Language::Rust
+            | Language::JavaScript
+            | Language::C
+            | Language::Cpp
+            | Language::Java => HashSet::new(),
+        }
+    }
+
     fn function_declaration_types(&self) -> HashSet<&'static str> {
         match self.language {
             Language::Go => ["function_declaration", "method_declaration"]
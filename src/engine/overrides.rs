@@ -0,0 +1,86 @@
+//! Values substituted for package-level constants and variables, for `argflow simulate`.
+
+use std::collections::HashMap;
+use std::path::Path;
+
+use super::Value;
+
+/// `package.Name` to the value it resolves to instead of its declaration.
+#[derive(Debug, Clone, Default)]
+pub struct ValueOverrides {
+    values: HashMap<String, Value>,
+}
+
+impl ValueOverrides {
+    /// Overrides from `package.Name` and value text pairs. Integers (`600_000`, `0x20`)
+    /// resolve as integers; anything else as a string, without surrounding quotes.
+    pub fn new(assignments: Vec<(String, String)>) -> Self {
+        let values = assignments
+            .into_iter()
+            .map(|(name, value)| (name, parse_value(&value)))
+            .collect();
+        Self { values }
+    }
+
+    pub fn is_empty(&self) -> bool {
+        self.values.is_empty()
+    }
+
+    /// The value set for `name` in the package whose files are in `package_dir`.
+    pub fn get(&self, package_dir: &str, name: &str) -> Option<Value> {
+        let package = Path::new(package_dir).file_name()?.to_string_lossy();
+        self.values.get(&format!("{package}.{name}")).cloned()
+    }
+}
+
+fn parse_value(text: &str) -> Value {
+    let text = text.trim();
+    let digits = text.replace('_', "");
+    let int = match digits
+        .strip_prefix("0x")
+        .or_else(|| digits.strip_prefix("0X"))
+    {
+        Some(hex) => i64::from_str_radix(hex, 16).ok(),
+        None => digits.parse().ok(),
+    };
+    match int {
+        Some(value) => Value::resolved_int(value),
+        None => {
+            let unquoted = text
+                .strip_prefix('"')
+                .and_then(|t| t.strip_suffix('"'))
+                .unwrap_or(text);
+            Value::resolved_string(unquoted.to_string())
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_overrides_by_package_directory() {
+        let overrides = ValueOverrides::new(vec![
+            ("config.PBKDF2Iterations".to_string(), "600_000".to_string()),
+            ("config.KeySize".to_string(), "0x20".to_string()),
+            ("kdf.Hash".to_string(), "\"SHA-512\"".to_string()),
+        ]);
+
+        let iterations = overrides
+            .get("/src/app/config", "PBKDF2Iterations")
+            .unwrap();
+        assert_eq!(iterations.int_values, vec![600000]);
+        assert_eq!(
+            overrides.get("config", "KeySize").unwrap().int_values,
+            vec![32]
+        );
+        assert_eq!(
+            overrides.get("internal/kdf", "Hash").unwrap().string_values,
+            vec!["SHA-512"]
+        );
+
+        assert!(overrides.get("/src/app/auth", "PBKDF2Iterations").is_none());
+        assert!(overrides.get("", "PBKDF2Iterations").is_none());
+    }
+}
@@ -0,0 +1,188 @@
+use serde::Serialize;
+
+use super::sources::UnresolvedSource;
+use super::value::Value;
+
+/// How far an argument value could be resolved.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "lowercase")]
+pub enum ResolutionStatus {
+    /// A single concrete value.
+    Resolved,
+    /// A finite set of possible concrete values (e.g. from branches).
+    Range,
+    /// An expression over names we could not reduce further.
+    Symbolic,
+    /// Nothing useful could be determined; see [`UnknownReason`].
+    Unknown,
+}
+
+/// Machine-readable reason an argument could not be resolved.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum UnknownReason {
+    /// The value comes from a call we could not follow.
+    DynamicCall,
+    /// The value is received from a channel.
+    ChannelValue,
+    /// The value is produced through reflection.
+    Reflection,
+    /// The value enters from outside the analyzed code (parameters, config, runtime).
+    ExternalInput,
+    /// The value is derived through more constants than the derivation depth limit.
+    DerivationLimit,
+    /// The construct is not supported by the resolver.
+    UnsupportedConstruct,
+}
+
+impl ResolutionStatus {
+    pub fn of(value: &Value) -> Self {
+        if value.is_resolved {
+            let count = value.int_values.len() + value.string_values.len();
+            return if count > 1 {
+                ResolutionStatus::Range
+            } else {
+                ResolutionStatus::Resolved
+            };
+        }
+
+        if !value.expression.is_empty() && UnknownReason::for_source(&value.source).is_none() {
+            return ResolutionStatus::Symbolic;
+        }
+
+        ResolutionStatus::Unknown
+    }
+
+    pub fn as_str(&self) -> &'static str {
+        match self {
+            ResolutionStatus::Resolved => "resolved",
+            ResolutionStatus::Range => "range",
+            ResolutionStatus::Symbolic => "symbolic",
+            ResolutionStatus::Unknown => "unknown",
+        }
+    }
+}
+
+impl UnknownReason {
+    /// Maps an unresolved source to a reason. Returns `None` for partially
+    /// resolved values, which are reported as symbolic rather than unknown.
+    pub fn for_source(source: &str) -> Option<Self> {
+        let reason = match source {
+            s if s == UnresolvedSource::PartiallyResolved.as_str() => return None,
+            s if s == UnresolvedSource::FunctionNotFound.as_str()
+                || s == UnresolvedSource::ExternalDependency.as_str() =>
+            {
+                UnknownReason::DynamicCall
+            }
+            s if s == UnresolvedSource::ChannelReceive.as_str() => UnknownReason::ChannelValue,
+            s if s == UnresolvedSource::Reflection.as_str() => UnknownReason::Reflection,
+            s if s == UnresolvedSource::DerivationLimit.as_str() => UnknownReason::DerivationLimit,
+            s if s == UnresolvedSource::FunctionParameter.as_str()
+                || s == UnresolvedSource::ConfigValue.as_str()
+                || s == UnresolvedSource::RuntimeValue.as_str() =>
+            {
+                UnknownReason::ExternalInput
+            }
+            _ => UnknownReason::UnsupportedConstruct,
+        };
+        Some(reason)
+    }
+
+    /// The reason for a value, or `None` if the value is not unknown.
+    pub fn of(value: &Value) -> Option<Self> {
+        match ResolutionStatus::of(value) {
+            ResolutionStatus::Unknown => {
+                Some(Self::for_source(&value.source).unwrap_or(UnknownReason::UnsupportedConstruct))
+            }
+            _ => None,
+        }
+    }
+
+    pub fn as_str(&self) -> &'static str {
+        match self {
+            UnknownReason::DynamicCall => "dynamic-call",
+            UnknownReason::ChannelValue => "channel-value",
+            UnknownReason::Reflection => "reflection",
+            UnknownReason::ExternalInput => "external-input",
+            UnknownReason::DerivationLimit => "derivation-limit",
+            UnknownReason::UnsupportedConstruct => "unsupported-construct",
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_status_of_resolved_values() {
+        assert_eq!(
+            ResolutionStatus::of(&Value::resolved_int(10000)),
+            ResolutionStatus::Resolved
+        );
+        assert_eq!(
+            ResolutionStatus::of(&Value::resolved_ints(vec![16, 32])),
+            ResolutionStatus::Range
+        );
+        assert_eq!(UnknownReason::of(&Value::resolved_int(1)), None);
+    }
+
+    #[test]
+    fn test_status_of_partial_expression() {
+        let value = Value::partial_expression("config.Iterations");
+        assert_eq!(ResolutionStatus::of(&value), ResolutionStatus::Symbolic);
+        assert_eq!(UnknownReason::of(&value), None);
+    }
+
+    #[test]
+    fn test_unknown_reasons() {
+        let cases = [
+            (
+                UnresolvedSource::FunctionParameter,
+                UnknownReason::ExternalInput,
+            ),
+            (
+                UnresolvedSource::FunctionNotFound,
+                UnknownReason::DynamicCall,
+            ),
+            (
+                UnresolvedSource::ChannelReceive,
+                UnknownReason::ChannelValue,
+            ),
+            (UnresolvedSource::Reflection, UnknownReason::Reflection),
+            (
+                UnresolvedSource::DerivationLimit,
+                UnknownReason::DerivationLimit,
+            ),
+            (
+                UnresolvedSource::CycleDetected,
+                UnknownReason::UnsupportedConstruct,
+            ),
+        ];
+        for (source, reason) in cases {
+            let value = Value::unextractable(source);
+            assert_eq!(ResolutionStatus::of(&value), ResolutionStatus::Unknown);
+            assert_eq!(UnknownReason::of(&value), Some(reason));
+        }
+    }
+
+    #[test]
+    fn test_unknown_with_expression_keeps_reason() {
+        let mut value = Value::unextractable(UnresolvedSource::ChannelReceive);
+        value.expression = "<-keys".to_string();
+        assert_eq!(ResolutionStatus::of(&value), ResolutionStatus::Unknown);
+        assert_eq!(UnknownReason::of(&value), Some(UnknownReason::ChannelValue));
+    }
+
+    #[test]
+    fn test_serialized_names() {
+        assert_eq!(
+            serde_json::to_value(UnknownReason::UnsupportedConstruct).unwrap(),
+            "unsupported-construct"
+        );
+        assert_eq!(
+            serde_json::to_value(ResolutionStatus::Symbolic).unwrap(),
+            "symbolic"
+        );
+    }
+}
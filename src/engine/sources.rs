@@ -7,10 +7,14 @@ pub enum UnresolvedSource {
     ExternalDependency,
     IdentifierNotFound,
     CycleDetected,
+    DerivationLimit,
     NotImplemented,
     PartiallyResolved,
     MixedResolution,
     MixedTypes,
+    CgoValue,
+    ChannelReceive,
+    Reflection,
     Unknown,
 }
 
@@ -24,10 +28,14 @@ impl UnresolvedSource {
             Self::ExternalDependency => "external_dependency",
             Self::IdentifierNotFound => "identifier_not_found",
             Self::CycleDetected => "cycle_detected",
+            Self::DerivationLimit => "derivation_limit",
             Self::NotImplemented => "not_implemented",
             Self::PartiallyResolved => "partially_resolved",
             Self::MixedResolution => "mixed_resolution",
             Self::MixedTypes => "mixed_types",
+            Self::CgoValue => "cgo_value",
+            Self::ChannelReceive => "channel_receive",
+            Self::Reflection => "reflection",
             Self::Unknown => "unknown",
         }
     }
@@ -59,6 +67,7 @@ mod tests {
             "function_parameter"
         );
         assert_eq!(UnresolvedSource::CycleDetected.as_str(), "cycle_detected");
+        assert_eq!(UnresolvedSource::CgoValue.as_str(), "cgo_value");
     }
 
     #[test]
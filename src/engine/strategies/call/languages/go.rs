@@ -1,3 +1,4 @@
+use crate::engine::file_index::go_context_key;
 use crate::engine::{Context, Value};
 use tree_sitter::Node;
 
@@ -28,3 +29,59 @@ pub fn extract_return<'a>(
 
     Some(strategy.resolve_multiple_values(&children, ctx))
 }
+
+/// The value a `ctx.Value(key)` read gets, when `key` is a package-level sentinel that a
+/// `context.WithValue` call in the module stores a resolvable value under.
+pub fn context_value<'a>(node: &Node<'a>, ctx: &Context<'a>) -> Option<Value> {
+    let function = node.child_by_field_name("function")?;
+    if function.kind() != "selector_expression"
+        || ctx.get_field_text(&function, "field").as_deref() != Some("Value")
+    {
+        return None;
+    }
+
+    let args = node.child_by_field_name("arguments")?;
+    let key = match ctx.get_named_children(&args).as_slice() {
+        [key] => *key,
+        _ => return None,
+    };
+    ctx.find_context_value(&go_context_key(key, ctx)?)
+}
+
+/// C scalar types whose cgo conversions (e.g. `C.int(16)`) keep the Go-side value intact.
+const CGO_SCALAR_TYPES: &[&str] = &[
+    "char",
+    "schar",
+    "uchar",
+    "short",
+    "ushort",
+    "int",
+    "uint",
+    "long",
+    "ulong",
+    "longlong",
+    "ulonglong",
+    "float",
+    "double",
+    "size_t",
+];
+
+/// Returns the single argument of a cgo scalar conversion such as `C.int(keyLen)`.
+pub fn cgo_conversion_argument<'a>(
+    func_name: &str,
+    node: &Node<'a>,
+    _ctx: &Context<'a>,
+) -> Option<Node<'a>> {
+    let c_type = func_name.strip_prefix("C.")?;
+    if !CGO_SCALAR_TYPES.contains(&c_type) {
+        return None;
+    }
+
+    let args = node.child_by_field_name("arguments")?;
+    let mut cursor = args.walk();
+    let named: Vec<_> = args.named_children(&mut cursor).collect();
+    match named.as_slice() {
+        [arg] => Some(*arg),
+        _ => None,
+    }
+}
@@ -6,6 +6,8 @@ pub mod python;
 pub mod rust;
 
 pub use c::extract_return as c_extract_return;
+pub use go::cgo_conversion_argument as go_cgo_conversion_argument;
+pub use go::context_value as go_context_value;
 pub use go::extract_return as go_extract_return;
 pub use java::extract_return as java_extract_return;
 pub use javascript::extract_return as js_extract_return;
@@ -1,4 +1,4 @@
-use crate::engine::{Context, Language, NodeCategory, Strategy, UnresolvedSource, Value};
+use crate::engine::{Context, Language, NodeCategory, Resolver, Strategy, UnresolvedSource, Value};
 use tree_sitter::Node;
 
 mod languages;
@@ -245,6 +245,28 @@ impl CallStrategy {
         Value::partial_expression(ctx.get_node_text(&node))
     }
 
+    /// Values read through reflection (`reflect.ValueOf(x).Int()`, `getattr(obj, name)()`).
+    fn is_reflective_call(func_name: &str) -> bool {
+        func_name.starts_with("reflect.")
+            || func_name.contains(").Interface")
+            || func_name.starts_with("getattr(")
+    }
+
+    /// Calls into cgo cannot be followed. Scalar conversions like `C.int(n)` pass the Go
+    /// argument through; anything else is reported as a cgo value.
+    fn resolve_cgo_call<'a>(&self, func_name: &str, node: &Node<'a>, ctx: &Context<'a>) -> Value {
+        if let Some(arg) = languages::go_cgo_conversion_argument(func_name, node, ctx) {
+            let value = Resolver::new().resolve(&arg, ctx);
+            if value.is_resolved {
+                return value;
+            }
+        }
+
+        let mut value = Value::unextractable(UnresolvedSource::CgoValue);
+        value.expression = ctx.get_node_text(node);
+        value
+    }
+
     fn merge_return_values(&self, values: Vec<Value>) -> Value {
         if values.is_empty() {
             return Value::unextractable(UnresolvedSource::NotImplemented);
@@ -314,6 +336,24 @@ impl Strategy for CallStrategy {
             None => return Value::unextractable(UnresolvedSource::Unknown),
         };
 
+        if func_name.starts_with("C.")
+            && ctx.node_types().map(|nt| nt.language()) == Some(Language::Go)
+        {
+            return self.resolve_cgo_call(&func_name, node, ctx);
+        }
+
+        if ctx.node_types().map(|nt| nt.language()) == Some(Language::Go) {
+            if let Some(value) = languages::go_context_value(node, ctx) {
+                return value;
+            }
+        }
+
+        if Self::is_reflective_call(&func_name) {
+            let mut value = Value::unextractable(UnresolvedSource::Reflection);
+            value.expression = ctx.get_node_text(node);
+            return value;
+        }
+
         let simple_name = func_name.split('.').next_back().unwrap_or(&func_name);
 
         let func_decl =
@@ -479,6 +519,70 @@ mod tests {
         }
     }
 
+    #[test]
+    fn test_go_cgo_scalar_conversion() {
+        let source = r#"
+package main
+
+import "C"
+
+func main() {
+    x := C.int(16)
+}"#;
+        let tree = parse_go(source);
+        let ctx = create_go_context(&tree, source.as_bytes());
+        let strategy = CallStrategy::new();
+
+        let call_node = find_call_by_name(tree.root_node(), "C.int", &ctx).unwrap();
+        let value = strategy.resolve(&call_node, &ctx);
+
+        assert!(value.is_resolved);
+        assert_eq!(value.int_values, vec![16]);
+    }
+
+    #[test]
+    fn test_go_reflection_call() {
+        let source = r#"
+package main
+
+import "reflect"
+
+func main() {
+    x := reflect.ValueOf(cfg).FieldByName("Iterations").Int()
+}"#;
+        let tree = parse_go(source);
+        let ctx = create_go_context(&tree, source.as_bytes());
+        let strategy = CallStrategy::new();
+
+        let call_node = find_first_call(tree.root_node(), &ctx).unwrap();
+        let value = strategy.resolve(&call_node, &ctx);
+
+        assert!(!value.is_resolved);
+        assert_eq!(value.source, "reflection");
+    }
+
+    #[test]
+    fn test_go_cgo_function_call() {
+        let source = r#"
+package main
+
+import "C"
+
+func main() {
+    x := C.key_length()
+}"#;
+        let tree = parse_go(source);
+        let ctx = create_go_context(&tree, source.as_bytes());
+        let strategy = CallStrategy::new();
+
+        let call_node = find_call_by_name(tree.root_node(), "C.key_length", &ctx).unwrap();
+        let value = strategy.resolve(&call_node, &ctx);
+
+        assert!(!value.is_resolved);
+        assert_eq!(value.source, "cgo_value");
+        assert_eq!(value.expression, "C.key_length()");
+    }
+
     // =========================================================================
     // Go - Simple Return Tests
     // =========================================================================
@@ -618,15 +618,18 @@ impl IdentifierStrategy {
     }
 
     fn resolve_value_node<'a>(&self, node: Node<'a>, ctx: &Context<'a>) -> Value {
-        // Use resolver if available for full strategy chain
-        if let Some(ref resolver) = self.resolver {
-            return resolver.resolve(&node, ctx);
+        if !ctx.enter_derivation() {
+            return Value::unextractable(UnresolvedSource::DerivationLimit);
         }
 
-        // Create a new resolver for full strategy chain resolution
-        // This enables proper chaining through composite/array strategies
-        let resolver = Resolver::new();
-        resolver.resolve(&node, ctx)
+        // Use resolver if available for full strategy chain, otherwise create a new
+        // one; this enables proper chaining through composite/array strategies
+        let value = match self.resolver {
+            Some(ref resolver) => resolver.resolve(&node, ctx),
+            None => Resolver::new().resolve(&node, ctx),
+        };
+        ctx.exit_derivation();
+        value
     }
 }
 
@@ -659,6 +662,10 @@ impl Strategy for IdentifierStrategy {
             }
         }
 
+        if let Some(value) = ctx.value_override(None, &name) {
+            return value;
+        }
+
         let root = ctx.tree().root_node();
         if let Some(value_node) = self.find_file_level_constant(&name, root, use_position, ctx) {
             return self.resolve_value_node(value_node, ctx);
@@ -893,6 +900,31 @@ func test() {
         assert_eq!(value.int_values, vec![100000]);
     }
 
+    #[test]
+    fn test_go_derivation_depth_limit() {
+        let source = r#"
+package main
+
+const PBKDF2Iterations = 100000
+const DefaultIterations = PBKDF2Iterations
+
+func test() {
+    use(DefaultIterations)
+}"#;
+        let tree = parse_go(source);
+        let strategy = IdentifierStrategy::new();
+
+        let ctx = create_go_context(&tree, source.as_bytes()).with_max_derivation_depth(2);
+        let node =
+            find_last_identifier_by_name(tree.root_node(), "DefaultIterations", &ctx).unwrap();
+        assert_eq!(strategy.resolve(&node, &ctx).int_values, vec![100000]);
+
+        let ctx = create_go_context(&tree, source.as_bytes()).with_max_derivation_depth(1);
+        let value = strategy.resolve(&node, &ctx);
+        assert!(!value.is_resolved);
+        assert_eq!(value.source, "derivation_limit");
+    }
+
     #[test]
     fn test_go_file_level_var() {
         let source = r#"
@@ -49,6 +49,12 @@ impl SelectorStrategy {
         false
     }
 
+    fn is_cgo_reference<'a>(&self, object: &Node<'a>, ctx: &Context<'a>) -> bool {
+        ctx.node_types().map(|nt| nt.language()) == Some(Language::Go)
+            && object.kind() == "identifier"
+            && ctx.get_node_text(object) == "C"
+    }
+
     fn looks_like_package_name<'a>(&self, name: &str, ctx: &Context<'a>) -> bool {
         let lang = ctx.node_types().map(|nt| nt.language());
 
@@ -79,13 +85,23 @@ impl SelectorStrategy {
         field_name: &str,
         ctx: &Context<'a>,
     ) -> Value {
+        let package_name = ctx.get_node_text(_package);
+
+        if let Some(value) = ctx.value_override(Some(&package_name), field_name) {
+            return value;
+        }
+
+        // Constant exported by an imported package
+        if let Some(value) = ctx.find_imported_constant(&package_name, field_name) {
+            return value;
+        }
+
         // Try to find cross-file constant with this name
         if let Some(value) = ctx.find_cross_file_constant(field_name) {
             return value;
         }
 
         // Return partial expression preserving the selector
-        let package_name = ctx.get_node_text(_package);
         Value::partial_expression(format!("{package_name}.{field_name}"))
     }
 
@@ -154,6 +170,13 @@ impl Strategy for SelectorStrategy {
             None => return Value::unextractable(UnresolvedSource::Unknown),
         };
 
+        // cgo pseudo-package: C.FOO refers to C declarations we cannot see
+        if self.is_cgo_reference(&object, ctx) {
+            let mut value = Value::unextractable(UnresolvedSource::CgoValue);
+            value.expression = format!("C.{field_name}");
+            return value;
+        }
+
         // Check if this looks like a package-qualified constant (pkg.Constant)
         if self.is_package_identifier(&object, ctx) {
             // Check if the field name looks like a constant (starts with uppercase in Go)
@@ -345,6 +368,24 @@ func main() {
         assert_eq!(value.expression, "cfg.Iterations");
     }
 
+    #[test]
+    fn test_go_cgo_constant() {
+        let source = r#"
+package main
+import "C"
+func main() { use(C.KEY_LEN) }
+"#;
+        let tree = parse_go(source);
+        let ctx = create_go_context(&tree, source.as_bytes());
+        let strategy = SelectorStrategy::new();
+
+        let node = find_first_node_of_kind(tree.root_node(), "selector_expression").unwrap();
+        let value = strategy.resolve(&node, &ctx);
+        assert!(!value.is_resolved);
+        assert_eq!(value.source, "cgo_value");
+        assert_eq!(value.expression, "C.KEY_LEN");
+    }
+
     #[test]
     fn test_go_chained_selector() {
         let source = r#"
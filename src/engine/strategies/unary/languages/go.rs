@@ -8,7 +8,11 @@ pub fn get_unary<'a>(_node: &Node<'a>, _ctx: &Context<'a>) -> Option<(String, No
     for child in _node.children(&mut cursor) {
         if !child.is_named() {
             let op_text = _ctx.get_node_text(&child);
-            if UnaryOp::parse(&op_text).is_some() || op_text == "&" || op_text == "*" {
+            if UnaryOp::parse(&op_text).is_some()
+                || op_text == "&"
+                || op_text == "*"
+                || op_text == "<-"
+            {
                 return Some((op_text, operand));
             }
         }
@@ -114,6 +114,13 @@ impl Strategy for UnaryStrategy {
             return Value::partial_expression(format!("{op_text}{operand_text}"));
         }
 
+        // Channel receive: the value only exists at runtime
+        if op_text == "<-" {
+            let mut value = Value::unextractable(UnresolvedSource::ChannelReceive);
+            value.expression = format!("<-{}", ctx.get_node_text(&operand));
+            return value;
+        }
+
         if op_text == "not" {
             let operand_value = self.resolve_operand(&operand, ctx);
             return Value::unary_op("!", &operand_value);
@@ -256,6 +263,21 @@ mod tests {
         assert_eq!(value.int_values, vec![-10000]);
     }
 
+    #[test]
+    fn test_go_channel_receive() {
+        let source = "package main\nfunc main() { x := <-keySizes }";
+        let tree = parse_go(source);
+        let ctx = create_go_context(&tree, source.as_bytes());
+        let strategy = UnaryStrategy::new();
+
+        let node = find_first_node_of_kind(tree.root_node(), "unary_expression").unwrap();
+        let value = strategy.resolve(&node, &ctx);
+
+        assert!(!value.is_resolved);
+        assert_eq!(value.source, "channel_receive");
+        assert_eq!(value.expression, "<-keySizes");
+    }
+
     #[test]
     fn test_go_bitwise_not() {
         let source = "package main\nconst x = ^255";
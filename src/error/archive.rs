@@ -0,0 +1,48 @@
+use std::path::PathBuf;
+use thiserror::Error;
+
+#[derive(Error, Debug)]
+pub enum ArchiveError {
+    #[error("failed to read archive '{path}': {message}")]
+    ReadError { path: PathBuf, message: String },
+
+    #[error("'{path}' is not a zip, tar or tar.gz archive")]
+    UnsupportedFormat { path: PathBuf },
+
+    #[error("archive entry '{entry}' escapes the extraction directory")]
+    UnsafeEntry { entry: String },
+
+    #[error("corrupt zip archive '{path}': {message}")]
+    CorruptZip { path: PathBuf, message: String },
+
+    #[error("archive entry '{entry}' is larger than the {limit} byte limit")]
+    EntryTooLarge { entry: String, limit: u64 },
+
+    #[error("archive extracts to more than the {limit} byte limit")]
+    TooLarge { limit: u64 },
+
+    #[error("failed to extract archive: {message}")]
+    ExtractError { message: String },
+}
+
+impl ArchiveError {
+    pub fn read_error(path: impl Into<PathBuf>, message: impl Into<String>) -> Self {
+        Self::ReadError {
+            path: path.into(),
+            message: message.into(),
+        }
+    }
+
+    pub fn corrupt_zip(path: impl Into<PathBuf>, message: impl Into<String>) -> Self {
+        Self::CorruptZip {
+            path: path.into(),
+            message: message.into(),
+        }
+    }
+
+    pub fn extract_error(message: impl Into<String>) -> Self {
+        Self::ExtractError {
+            message: message.into(),
+        }
+    }
+}
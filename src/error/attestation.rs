@@ -0,0 +1,37 @@
+use std::path::PathBuf;
+use thiserror::Error;
+
+#[derive(Error, Debug)]
+pub enum AttestationError {
+    #[error("failed to read signing key '{path}': {message}")]
+    KeyReadError { path: PathBuf, message: String },
+
+    #[error("invalid signing key '{path}': {message}")]
+    InvalidKey { path: PathBuf, message: String },
+
+    #[error("failed to hash configuration input '{path}': {message}")]
+    ConfigReadError { path: PathBuf, message: String },
+}
+
+impl AttestationError {
+    pub fn key_read_error(path: impl Into<PathBuf>, message: impl Into<String>) -> Self {
+        Self::KeyReadError {
+            path: path.into(),
+            message: message.into(),
+        }
+    }
+
+    pub fn invalid_key(path: impl Into<PathBuf>, message: impl Into<String>) -> Self {
+        Self::InvalidKey {
+            path: path.into(),
+            message: message.into(),
+        }
+    }
+
+    pub fn config_read_error(path: impl Into<PathBuf>, message: impl Into<String>) -> Self {
+        Self::ConfigReadError {
+            path: path.into(),
+            message: message.into(),
+        }
+    }
+}
@@ -0,0 +1,31 @@
+use std::path::PathBuf;
+use thiserror::Error;
+
+#[derive(Error, Debug)]
+pub enum HistoryError {
+    #[error("failed to open history database '{path}': {message}")]
+    OpenError { path: PathBuf, message: String },
+
+    #[error("history database error: {message}")]
+    DatabaseError { message: String },
+
+    #[error("history database schema version {found} is newer than supported version {supported}")]
+    UnsupportedSchema { found: i64, supported: i64 },
+}
+
+impl HistoryError {
+    pub fn open_error(path: impl Into<PathBuf>, message: impl Into<String>) -> Self {
+        Self::OpenError {
+            path: path.into(),
+            message: message.into(),
+        }
+    }
+}
+
+impl From<rusqlite::Error> for HistoryError {
+    fn from(err: rusqlite::Error) -> Self {
+        Self::DatabaseError {
+            message: err.to_string(),
+        }
+    }
+}
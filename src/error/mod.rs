@@ -1,12 +1,28 @@
+mod archive;
+mod attestation;
 mod classifier;
+mod history;
 mod io;
+mod notify;
 mod parser;
+mod policy;
 mod query;
+mod repro;
+mod telemetry;
+mod vulndb;
 
+pub use archive::ArchiveError;
+pub use attestation::AttestationError;
 pub use classifier::ClassifierError;
+pub use history::HistoryError;
 pub use io::IoError;
+pub use notify::NotifyError;
 pub use parser::ParserError;
+pub use policy::PolicyError;
 pub use query::QueryError;
+pub use repro::ReproError;
+pub use telemetry::TelemetryError;
+pub use vulndb::VulnDbError;
 
 use thiserror::Error;
 
@@ -23,6 +39,30 @@ pub enum Error {
 
     #[error(transparent)]
     Query(#[from] QueryError),
+
+    #[error(transparent)]
+    Policy(#[from] PolicyError),
+
+    #[error(transparent)]
+    History(#[from] HistoryError),
+
+    #[error(transparent)]
+    VulnDb(#[from] VulnDbError),
+
+    #[error(transparent)]
+    Attestation(#[from] AttestationError),
+
+    #[error(transparent)]
+    Notify(#[from] NotifyError),
+
+    #[error(transparent)]
+    Repro(#[from] ReproError),
+
+    #[error(transparent)]
+    Telemetry(#[from] TelemetryError),
+
+    #[error(transparent)]
+    Archive(#[from] ArchiveError),
 }
 
 pub type Result<T> = std::result::Result<T, Error>;
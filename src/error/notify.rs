@@ -0,0 +1,54 @@
+use std::path::PathBuf;
+use thiserror::Error;
+
+#[derive(Error, Debug)]
+pub enum NotifyError {
+    #[error("failed to read notification config '{path}': {message}")]
+    ConfigReadError { path: PathBuf, message: String },
+
+    #[error("failed to parse notification config '{path}': {message}")]
+    ConfigParseError { path: PathBuf, message: String },
+
+    #[error("unsupported notification config format: {format} (expected json or yaml)")]
+    UnsupportedFormat { format: String },
+
+    #[error("failed to notify {target}: {message}")]
+    SendError { target: String, message: String },
+}
+
+impl NotifyError {
+    pub fn config_read_error(path: impl Into<PathBuf>, message: impl Into<String>) -> Self {
+        Self::ConfigReadError {
+            path: path.into(),
+            message: message.into(),
+        }
+    }
+
+    pub fn config_parse_error(path: impl Into<PathBuf>, message: impl Into<String>) -> Self {
+        Self::ConfigParseError {
+            path: path.into(),
+            message: message.into(),
+        }
+    }
+
+    pub fn send_error(target: impl Into<String>, message: impl Into<String>) -> Self {
+        Self::SendError {
+            target: target.into(),
+            message: message.into(),
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_send_error_display() {
+        let err = NotifyError::send_error("hooks.slack.com", "HTTP 404");
+        assert_eq!(
+            err.to_string(),
+            "failed to notify hooks.slack.com: HTTP 404"
+        );
+    }
+}
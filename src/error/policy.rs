@@ -0,0 +1,144 @@
+use std::path::PathBuf;
+use thiserror::Error;
+
+#[derive(Error, Debug)]
+pub enum PolicyError {
+    #[error("failed to read policy file '{path}': {message}")]
+    PolicyFileReadError { path: PathBuf, message: String },
+
+    #[error("failed to parse policy file '{path}': {message}")]
+    PolicyParseError { path: PathBuf, message: String },
+
+    #[error("unsupported policy format: {format} (expected json or yaml)")]
+    UnsupportedFormat { format: String },
+
+    #[error("invalid policy rule '{rule}': {message}")]
+    InvalidRule { rule: String, message: String },
+
+    #[error("failed to read baseline '{path}': {message}")]
+    BaselineReadError { path: PathBuf, message: String },
+
+    #[error("failed to write baseline '{path}': {message}")]
+    BaselineWriteError { path: PathBuf, message: String },
+
+    #[error("failed to list files changed since '{base}': {message}")]
+    DiffError { base: String, message: String },
+
+    #[error("baseline '{path}' uses fingerprint version {found}, expected {expected}; run `argflow baseline migrate`")]
+    BaselineVersionMismatch {
+        path: PathBuf,
+        found: u32,
+        expected: u32,
+    },
+
+    #[error("cannot migrate baseline fingerprint version {found} (supported: 1 to {supported})")]
+    UnsupportedBaselineVersion { found: u32, supported: u32 },
+
+    #[error("failed to read rule renames '{path}': {message}")]
+    RenamesReadError { path: PathBuf, message: String },
+
+    #[error("failed to load message catalog '{path}': {message}")]
+    MessagesError { path: PathBuf, message: String },
+
+    #[error("failed to load architecture spec '{path}': {message}")]
+    ArchitectureError { path: PathBuf, message: String },
+
+    #[error("invalid expression at offset {offset}: {message}")]
+    InvalidExpression { offset: usize, message: String },
+
+    #[error("failed to load base policy '{base}': {message}")]
+    ExtendsError { base: String, message: String },
+}
+
+impl PolicyError {
+    pub fn policy_file_read_error(path: impl Into<PathBuf>, message: impl Into<String>) -> Self {
+        Self::PolicyFileReadError {
+            path: path.into(),
+            message: message.into(),
+        }
+    }
+
+    pub fn policy_parse_error(path: impl Into<PathBuf>, message: impl Into<String>) -> Self {
+        Self::PolicyParseError {
+            path: path.into(),
+            message: message.into(),
+        }
+    }
+
+    pub fn extends_error(base: impl Into<String>, message: impl Into<String>) -> Self {
+        Self::ExtendsError {
+            base: base.into(),
+            message: message.into(),
+        }
+    }
+
+    pub fn invalid_rule(rule: impl Into<String>, message: impl Into<String>) -> Self {
+        Self::InvalidRule {
+            rule: rule.into(),
+            message: message.into(),
+        }
+    }
+
+    pub fn baseline_read_error(path: impl Into<PathBuf>, message: impl Into<String>) -> Self {
+        Self::BaselineReadError {
+            path: path.into(),
+            message: message.into(),
+        }
+    }
+
+    pub fn baseline_write_error(path: impl Into<PathBuf>, message: impl Into<String>) -> Self {
+        Self::BaselineWriteError {
+            path: path.into(),
+            message: message.into(),
+        }
+    }
+
+    pub fn diff_error(base: impl Into<String>, message: impl Into<String>) -> Self {
+        Self::DiffError {
+            base: base.into(),
+            message: message.into(),
+        }
+    }
+
+    pub fn renames_read_error(path: impl Into<PathBuf>, message: impl Into<String>) -> Self {
+        Self::RenamesReadError {
+            path: path.into(),
+            message: message.into(),
+        }
+    }
+
+    pub fn messages_error(path: impl Into<PathBuf>, message: impl Into<String>) -> Self {
+        Self::MessagesError {
+            path: path.into(),
+            message: message.into(),
+        }
+    }
+
+    pub fn architecture_error(path: impl Into<PathBuf>, message: impl Into<String>) -> Self {
+        Self::ArchitectureError {
+            path: path.into(),
+            message: message.into(),
+        }
+    }
+
+    pub fn invalid_expression(offset: usize, message: impl Into<String>) -> Self {
+        Self::InvalidExpression {
+            offset,
+            message: message.into(),
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_invalid_rule_display() {
+        let err = PolicyError::invalid_rule("min-iterations", "parameter constraint has no bounds");
+        assert_eq!(
+            err.to_string(),
+            "invalid policy rule 'min-iterations': parameter constraint has no bounds"
+        );
+    }
+}
@@ -0,0 +1,33 @@
+use std::path::PathBuf;
+use thiserror::Error;
+
+#[derive(Error, Debug)]
+pub enum ReproError {
+    #[error("failed to read '{path}': {message}")]
+    ReadError { path: PathBuf, message: String },
+
+    #[error("failed to parse Go source '{path}'")]
+    ParseError { path: PathBuf },
+
+    #[error("no top-level declaration encloses {path}:{line}")]
+    NoEnclosingDeclaration { path: PathBuf, line: usize },
+
+    #[error("failed to write '{path}': {message}")]
+    WriteError { path: PathBuf, message: String },
+}
+
+impl ReproError {
+    pub fn read_error(path: impl Into<PathBuf>, message: impl Into<String>) -> Self {
+        Self::ReadError {
+            path: path.into(),
+            message: message.into(),
+        }
+    }
+
+    pub fn write_error(path: impl Into<PathBuf>, message: impl Into<String>) -> Self {
+        Self::WriteError {
+            path: path.into(),
+            message: message.into(),
+        }
+    }
+}
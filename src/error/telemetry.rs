@@ -0,0 +1,19 @@
+use thiserror::Error;
+
+#[derive(Error, Debug)]
+pub enum TelemetryError {
+    #[error("invalid OTLP header '{header}' (expected key=value)")]
+    InvalidHeader { header: String },
+
+    #[error("failed to export telemetry to {endpoint}: {message}")]
+    ExportError { endpoint: String, message: String },
+}
+
+impl TelemetryError {
+    pub fn export_error(endpoint: impl Into<String>, message: impl Into<String>) -> Self {
+        Self::ExportError {
+            endpoint: endpoint.into(),
+            message: message.into(),
+        }
+    }
+}
@@ -0,0 +1,37 @@
+use std::path::PathBuf;
+use thiserror::Error;
+
+#[derive(Error, Debug)]
+pub enum VulnDbError {
+    #[error("failed to read vulnerability database '{path}': {message}")]
+    ReadError { path: PathBuf, message: String },
+
+    #[error("failed to parse advisory '{path}': {message}")]
+    ParseError { path: PathBuf, message: String },
+
+    #[error("govulncheck failed in '{path}': {message}")]
+    GovulncheckError { path: PathBuf, message: String },
+}
+
+impl VulnDbError {
+    pub fn read_error(path: impl Into<PathBuf>, message: impl Into<String>) -> Self {
+        Self::ReadError {
+            path: path.into(),
+            message: message.into(),
+        }
+    }
+
+    pub fn parse_error(path: impl Into<PathBuf>, message: impl Into<String>) -> Self {
+        Self::ParseError {
+            path: path.into(),
+            message: message.into(),
+        }
+    }
+
+    pub fn govulncheck_error(path: impl Into<PathBuf>, message: impl Into<String>) -> Self {
+        Self::GovulncheckError {
+            path: path.into(),
+            message: message.into(),
+        }
+    }
+}
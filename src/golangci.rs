@@ -0,0 +1,160 @@
+//! Scan settings from the `argflow` entry of a golangci-lint configuration, which fill in
+//! the options the command line leaves at their defaults.
+
+use std::path::{Path, PathBuf};
+
+use anyhow::{Context as AnyhowContext, Result};
+use serde::Deserialize;
+
+use crate::cli::{Args, Command, ScanMode};
+use crate::engine::DEFAULT_MAX_DERIVATION_DEPTH;
+use crate::policy::Severity;
+
+/// Name of the custom linter entry holding argflow's settings.
+pub const LINTER_NAME: &str = "argflow";
+
+/// The `settings` of the `argflow` custom linter.
+#[derive(Debug, Clone, Default, PartialEq, Deserialize)]
+#[serde(rename_all = "kebab-case", deny_unknown_fields)]
+pub struct LinterSettings {
+    /// Presets to scan with, like `--preset`.
+    #[serde(default)]
+    pub sink_packs: Vec<String>,
+    /// Custom rules file, like `--rules`.
+    #[serde(default)]
+    pub rules: Option<PathBuf>,
+    /// Run mode, like `--mode`.
+    #[serde(default)]
+    pub profile: Option<LinterProfile>,
+    /// Like `--max-derivation-depth`.
+    #[serde(default)]
+    pub max_derivation_depth: Option<usize>,
+    /// Lowest violation severity that fails `gate`, overriding the policy's `fail_on`.
+    #[serde(default)]
+    pub fail_on: Option<Severity>,
+}
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum LinterProfile {
+    Enforce,
+    Inventory,
+}
+
+impl LinterSettings {
+    /// Reads the `argflow` entry of the golangci-lint configuration at `path`; a
+    /// configuration without one yields the defaults.
+    pub fn from_file(path: &Path) -> Result<Self> {
+        let content = std::fs::read_to_string(path)
+            .with_context(|| format!("Failed to read golangci-lint config: {}", path.display()))?;
+        let mut settings = Self::parse(&content)
+            .with_context(|| format!("Failed to parse golangci-lint config: {}", path.display()))?;
+        let dir = path.parent().unwrap_or(Path::new(""));
+        settings.rules = settings.rules.map(|rules| dir.join(rules));
+        Ok(settings)
+    }
+
+    pub fn parse(content: &str) -> Result<Self> {
+        let config: serde_yaml::Value = serde_yaml::from_str(content)?;
+        let entry = [
+            &config["linters"]["settings"]["custom"][LINTER_NAME],
+            &config["linters-settings"]["custom"][LINTER_NAME],
+        ]
+        .into_iter()
+        .find(|entry| !entry.is_null());
+        match entry.map(|entry| &entry["settings"]) {
+            Some(settings) if !settings.is_null() => Ok(serde_yaml::from_value(settings.clone())?),
+            _ => Ok(Self::default()),
+        }
+    }
+
+    /// Fills in the options of `args` left at their defaults.
+    pub fn apply(&self, args: &mut Args) {
+        if args.preset.is_empty() {
+            args.preset = self.sink_packs.clone();
+        }
+        if args.rules.is_none() {
+            args.rules = self.rules.clone();
+        }
+        if let Some(profile) = self.profile.filter(|_| args.mode == ScanMode::default()) {
+            args.mode = match profile {
+                LinterProfile::Enforce => ScanMode::Enforce,
+                LinterProfile::Inventory => ScanMode::Inventory,
+            };
+        }
+        if let Some(depth) = self
+            .max_derivation_depth
+            .filter(|_| args.max_derivation_depth == DEFAULT_MAX_DERIVATION_DEPTH)
+        {
+            args.max_derivation_depth = depth;
+        }
+        if let Some(Command::Gate(gate)) = &mut args.command {
+            gate.fail_on = gate.fail_on.or(self.fail_on);
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use clap::Parser;
+
+    #[test]
+    fn test_settings_fill_in_default_options() {
+        let settings = LinterSettings::parse(
+            r#"
+version: "2"
+linters:
+  enable: [argflow]
+  settings:
+    custom:
+      argflow:
+        type: module
+        settings:
+          sink-packs: [crypto, tls]
+          profile: inventory
+          max-derivation-depth: 3
+          fail-on: warning
+"#,
+        )
+        .unwrap();
+        assert_eq!(settings.sink_packs, vec!["crypto", "tls"]);
+
+        let mut args = Args::try_parse_from([
+            "argflow",
+            "--path",
+            ".",
+            "--max-derivation-depth",
+            "8",
+            "gate",
+            "--policy",
+            "p.yaml",
+        ])
+        .unwrap();
+        settings.apply(&mut args);
+        assert_eq!(args.preset, vec!["crypto", "tls"]);
+        assert_eq!(args.mode, ScanMode::Inventory);
+        // The command line wins over the settings
+        assert_eq!(args.max_derivation_depth, 8);
+        let Some(Command::Gate(gate)) = &args.command else {
+            panic!("expected gate");
+        };
+        assert_eq!(gate.fail_on, Some(Severity::Warning));
+    }
+
+    #[test]
+    fn test_v1_layout_and_missing_entry() {
+        let settings = LinterSettings::parse(
+            "linters-settings:\n  custom:\n    argflow:\n      settings:\n        rules: sinks.json\n",
+        )
+        .unwrap();
+        assert_eq!(settings.rules, Some(PathBuf::from("sinks.json")));
+
+        let settings = LinterSettings::parse("linters:\n  enable: [govet]\n").unwrap();
+        assert_eq!(settings, LinterSettings::default());
+        assert!(LinterSettings::parse(
+            "linters-settings:\n  custom:\n    argflow:\n      settings:\n        presets: [crypto]\n"
+        )
+        .is_err());
+    }
+}
@@ -0,0 +1,118 @@
+use std::collections::HashSet;
+
+use serde::Serialize;
+
+use super::{HistoryEntry, StoredScan};
+
+/// How an entry relates to the scans recorded before it.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum DeltaStatus {
+    /// Never recorded before.
+    New,
+    /// Present in the previous scan.
+    Recurring,
+    /// Fixed in an earlier scan, absent from the previous one, and back now.
+    Regressed,
+}
+
+impl DeltaStatus {
+    pub fn as_str(self) -> &'static str {
+        match self {
+            DeltaStatus::New => "new",
+            DeltaStatus::Recurring => "recurring",
+            DeltaStatus::Regressed => "regressed",
+        }
+    }
+
+    pub fn parse(value: &str) -> Option<Self> {
+        match value {
+            "new" => Some(DeltaStatus::New),
+            "recurring" => Some(DeltaStatus::Recurring),
+            "regressed" => Some(DeltaStatus::Regressed),
+            _ => None,
+        }
+    }
+}
+
+/// Sets the status of each of `entries` against the `previous` scan and every fingerprint
+/// `seen` in the history, and returns the entries of `previous` that are gone: the
+/// findings the scan being recorded fixed.
+pub fn annotate(
+    entries: &mut [HistoryEntry],
+    previous: Option<&StoredScan>,
+    seen: &HashSet<String>,
+) -> Vec<HistoryEntry> {
+    let before: HashSet<&str> = previous
+        .into_iter()
+        .flat_map(|s| s.fingerprints())
+        .collect();
+    for entry in entries.iter_mut() {
+        entry.status = Some(if before.contains(entry.fingerprint.as_str()) {
+            DeltaStatus::Recurring
+        } else if seen.contains(&entry.fingerprint) {
+            DeltaStatus::Regressed
+        } else {
+            DeltaStatus::New
+        });
+    }
+
+    let after: HashSet<&str> = entries.iter().map(|e| e.fingerprint.as_str()).collect();
+    previous
+        .map(|scan| {
+            scan.entries
+                .iter()
+                .filter(|e| !after.contains(e.fingerprint.as_str()))
+                .map(|e| HistoryEntry {
+                    status: None,
+                    ..e.clone()
+                })
+                .collect()
+        })
+        .unwrap_or_default()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn entry(fingerprint: &str) -> HistoryEntry {
+        HistoryEntry {
+            fingerprint: fingerprint.to_string(),
+            rule: "no-md5".to_string(),
+            file: "auth/hash.go".to_string(),
+            line: 3,
+            status: None,
+        }
+    }
+
+    #[test]
+    fn test_annotate_new_recurring_regressed_and_fixed() {
+        let previous = StoredScan {
+            entries: vec![entry("a"), entry("b")],
+            ..StoredScan::default()
+        };
+        let seen: HashSet<String> = ["a", "b", "c"].map(String::from).into();
+        let mut entries = vec![entry("a"), entry("c"), entry("d")];
+
+        let fixed = annotate(&mut entries, Some(&previous), &seen);
+
+        let statuses: Vec<_> = entries.iter().map(|e| e.status.unwrap()).collect();
+        assert_eq!(
+            statuses,
+            vec![
+                DeltaStatus::Recurring,
+                DeltaStatus::Regressed,
+                DeltaStatus::New
+            ]
+        );
+        assert_eq!(fixed, vec![entry("b")]);
+    }
+
+    #[test]
+    fn test_first_scan_is_all_new() {
+        let mut entries = vec![entry("a")];
+        assert!(annotate(&mut entries, None, &HashSet::new()).is_empty());
+        assert_eq!(entries[0].status, Some(DeltaStatus::New));
+    }
+}
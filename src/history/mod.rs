@@ -0,0 +1,79 @@
+//! Scan history for trend reporting.
+
+mod delta;
+mod store;
+mod trend;
+
+use std::path::Path;
+
+use serde::Serialize;
+
+use crate::output::Finding;
+use crate::policy::{fingerprint, relative_path, GateReport};
+
+pub use delta::{annotate, DeltaStatus};
+pub use store::HistoryStore;
+pub use trend::{compute_trend, AuthorBurnDown, GroupTrend, ScanTrend, TrendReport};
+
+pub const DEFAULT_HISTORY_DB: &str = ".argflow/history.db";
+
+/// One finding (or policy violation) as stored in the history.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct HistoryEntry {
+    pub fingerprint: String,
+    /// Policy rule id, or the call name when no policy was applied.
+    pub rule: String,
+    /// Path relative to the scan root.
+    pub file: String,
+    pub line: usize,
+    /// Set once the entry is compared against the history.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub status: Option<DeltaStatus>,
+}
+
+#[derive(Debug, Clone, Default)]
+pub struct StoredScan {
+    pub id: i64,
+    pub commit: Option<String>,
+    pub author: Option<String>,
+    pub recorded_at: i64,
+    pub entries: Vec<HistoryEntry>,
+}
+
+impl StoredScan {
+    fn fingerprints(&self) -> impl Iterator<Item = &str> {
+        self.entries.iter().map(|e| e.fingerprint.as_str())
+    }
+}
+
+/// Entries for every finding, grouped under the called function.
+pub fn entries_from_findings(findings: &[Finding], root: &Path) -> Vec<HistoryEntry> {
+    findings
+        .iter()
+        .map(|finding| {
+            let file = relative_path(&finding.file, root);
+            HistoryEntry {
+                fingerprint: fingerprint(&finding.full_name, &file, finding),
+                rule: finding.full_name.clone(),
+                file,
+                line: finding.line,
+                status: None,
+            }
+        })
+        .collect()
+}
+
+/// Entries for every policy violation, grouped under the rule id.
+pub fn entries_from_gate(report: &GateReport) -> Vec<HistoryEntry> {
+    report
+        .violations
+        .iter()
+        .map(|violation| HistoryEntry {
+            fingerprint: violation.fingerprint.clone(),
+            rule: violation.rule.clone(),
+            file: violation.file.clone(),
+            line: violation.line,
+            status: None,
+        })
+        .collect()
+}
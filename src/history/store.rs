@@ -0,0 +1,231 @@
+use std::collections::HashSet;
+use std::path::Path;
+
+use rusqlite::{params, Connection};
+use tracing::debug;
+
+use crate::error::HistoryError;
+
+use super::{DeltaStatus, HistoryEntry, StoredScan};
+
+const SCHEMA_VERSION: i64 = 2;
+
+const SCHEMA: &str = "
+CREATE TABLE IF NOT EXISTS scans (
+    id INTEGER PRIMARY KEY,
+    commit_sha TEXT,
+    author TEXT,
+    recorded_at INTEGER NOT NULL,
+    root TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS findings (
+    scan_id INTEGER NOT NULL REFERENCES scans(id) ON DELETE CASCADE,
+    fingerprint TEXT NOT NULL,
+    rule TEXT NOT NULL,
+    file TEXT NOT NULL,
+    line INTEGER NOT NULL,
+    status TEXT
+);
+CREATE INDEX IF NOT EXISTS findings_by_scan ON findings(scan_id);
+";
+
+/// Version 2 added commit authors and delta statuses.
+const MIGRATE_V1: &str = "
+ALTER TABLE scans ADD COLUMN author TEXT;
+ALTER TABLE findings ADD COLUMN status TEXT;
+";
+
+/// SQLite-backed record of past scans.
+pub struct HistoryStore {
+    conn: Connection,
+}
+
+impl HistoryStore {
+    /// Opens (creating if needed) the history database at `path`.
+    pub fn open(path: &Path) -> Result<Self, HistoryError> {
+        if let Some(parent) = path.parent().filter(|p| !p.as_os_str().is_empty()) {
+            std::fs::create_dir_all(parent)
+                .map_err(|e| HistoryError::open_error(path, e.to_string()))?;
+        }
+        let conn =
+            Connection::open(path).map_err(|e| HistoryError::open_error(path, e.to_string()))?;
+        Self::with_connection(conn)
+    }
+
+    pub fn open_in_memory() -> Result<Self, HistoryError> {
+        Self::with_connection(Connection::open_in_memory()?)
+    }
+
+    fn with_connection(conn: Connection) -> Result<Self, HistoryError> {
+        let version: i64 = conn.query_row("PRAGMA user_version", [], |row| row.get(0))?;
+        if version > SCHEMA_VERSION {
+            return Err(HistoryError::UnsupportedSchema {
+                found: version,
+                supported: SCHEMA_VERSION,
+            });
+        }
+        if version == 1 {
+            conn.execute_batch(MIGRATE_V1)?;
+        }
+        conn.execute_batch(SCHEMA)?;
+        conn.pragma_update(None, "user_version", SCHEMA_VERSION)?;
+        Ok(Self { conn })
+    }
+
+    /// Stores one scan and its entries. Returns the new scan id.
+    pub fn record(
+        &mut self,
+        commit: Option<&str>,
+        author: Option<&str>,
+        recorded_at: i64,
+        root: &str,
+        entries: &[HistoryEntry],
+    ) -> Result<i64, HistoryError> {
+        let tx = self.conn.transaction()?;
+        tx.execute(
+            "INSERT INTO scans (commit_sha, author, recorded_at, root) VALUES (?1, ?2, ?3, ?4)",
+            params![commit, author, recorded_at, root],
+        )?;
+        let scan_id = tx.last_insert_rowid();
+        {
+            let mut insert = tx.prepare(
+                "INSERT INTO findings (scan_id, fingerprint, rule, file, line, status) VALUES (?1, ?2, ?3, ?4, ?5, ?6)",
+            )?;
+            for entry in entries {
+                insert.execute(params![
+                    scan_id,
+                    entry.fingerprint,
+                    entry.rule,
+                    entry.file,
+                    entry.line,
+                    entry.status.map(DeltaStatus::as_str)
+                ])?;
+            }
+        }
+        tx.commit()?;
+        debug!(scan_id, entries = entries.len(), "recorded scan");
+        Ok(scan_id)
+    }
+
+    /// Recorded scans, oldest first. `last` keeps only the most recent scans.
+    pub fn scans(&self, last: Option<usize>) -> Result<Vec<StoredScan>, HistoryError> {
+        let mut statement = self.conn.prepare(
+            "SELECT id, commit_sha, author, recorded_at FROM scans ORDER BY recorded_at, id",
+        )?;
+        let mut scans = statement
+            .query_map([], |row| {
+                Ok(StoredScan {
+                    id: row.get(0)?,
+                    commit: row.get(1)?,
+                    author: row.get(2)?,
+                    recorded_at: row.get(3)?,
+                    entries: Vec::new(),
+                })
+            })?
+            .collect::<Result<Vec<_>, _>>()?;
+
+        if let Some(last) = last {
+            let skip = scans.len().saturating_sub(last);
+            scans.drain(..skip);
+        }
+
+        let mut entries = self.conn.prepare(
+            "SELECT fingerprint, rule, file, line, status FROM findings WHERE scan_id = ?1 ORDER BY file, line",
+        )?;
+        for scan in &mut scans {
+            scan.entries = entries
+                .query_map(params![scan.id], |row| {
+                    let status: Option<String> = row.get(4)?;
+                    Ok(HistoryEntry {
+                        fingerprint: row.get(0)?,
+                        rule: row.get(1)?,
+                        file: row.get(2)?,
+                        line: row.get(3)?,
+                        status: status.as_deref().and_then(DeltaStatus::parse),
+                    })
+                })?
+                .collect::<Result<Vec<_>, _>>()?;
+        }
+
+        Ok(scans)
+    }
+
+    /// Every fingerprint recorded in any scan.
+    pub fn seen_fingerprints(&self) -> Result<HashSet<String>, HistoryError> {
+        let mut statement = self
+            .conn
+            .prepare("SELECT DISTINCT fingerprint FROM findings")?;
+        let seen = statement
+            .query_map([], |row| row.get(0))?
+            .collect::<Result<HashSet<String>, _>>()?;
+        Ok(seen)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn entry(fingerprint: &str) -> HistoryEntry {
+        HistoryEntry {
+            fingerprint: fingerprint.to_string(),
+            rule: "crypto/md5.Sum".to_string(),
+            file: "pkg/sum.go".to_string(),
+            line: 12,
+            status: None,
+        }
+    }
+
+    #[test]
+    fn test_record_and_read_back() {
+        let mut store = HistoryStore::open_in_memory().unwrap();
+        store
+            .record(
+                Some("abc123"),
+                Some("Ana <ana@example.com>"),
+                100,
+                "/work/app",
+                &[entry("a"), entry("b")],
+            )
+            .unwrap();
+        store
+            .record(Some("def456"), None, 200, "/work/app", &[entry("b")])
+            .unwrap();
+
+        let scans = store.scans(None).unwrap();
+        assert_eq!(scans.len(), 2);
+        assert_eq!(scans[0].commit.as_deref(), Some("abc123"));
+        assert_eq!(scans[0].author.as_deref(), Some("Ana <ana@example.com>"));
+        assert_eq!(scans[0].entries.len(), 2);
+        assert_eq!(store.seen_fingerprints().unwrap().len(), 2);
+
+        let latest = store.scans(Some(1)).unwrap();
+        assert_eq!(latest.len(), 1);
+        assert_eq!(latest[0].commit.as_deref(), Some("def456"));
+    }
+
+    #[test]
+    fn test_statuses_round_trip_and_v1_migrates() {
+        let conn = Connection::open_in_memory().unwrap();
+        conn.execute_batch(
+            "CREATE TABLE scans (id INTEGER PRIMARY KEY, commit_sha TEXT, recorded_at INTEGER NOT NULL, root TEXT NOT NULL);
+             CREATE TABLE findings (scan_id INTEGER NOT NULL, fingerprint TEXT NOT NULL, rule TEXT NOT NULL, file TEXT NOT NULL, line INTEGER NOT NULL);
+             INSERT INTO scans VALUES (1, 'abc123', 100, '/work/app');
+             INSERT INTO findings VALUES (1, 'a', 'crypto/md5.Sum', 'pkg/sum.go', 12);
+             PRAGMA user_version = 1;",
+        )
+        .unwrap();
+        let mut store = HistoryStore::with_connection(conn).unwrap();
+
+        let mut regressed = entry("a");
+        regressed.status = Some(DeltaStatus::Regressed);
+        store
+            .record(Some("def456"), None, 200, "/work/app", &[regressed])
+            .unwrap();
+
+        let scans = store.scans(None).unwrap();
+        assert_eq!(scans[0].author, None);
+        assert_eq!(scans[0].entries[0].status, None);
+        assert_eq!(scans[1].entries[0].status, Some(DeltaStatus::Regressed));
+    }
+}
@@ -0,0 +1,283 @@
+use std::collections::{BTreeMap, HashSet};
+use std::fmt::Write as _;
+
+use serde::Serialize;
+
+use super::{DeltaStatus, StoredScan};
+
+const UNKNOWN_AUTHOR: &str = "unknown";
+
+/// Findings opened and fixed between consecutive scans.
+#[derive(Debug, Clone, Serialize)]
+pub struct TrendReport {
+    pub scans: Vec<ScanTrend>,
+    pub by_rule: Vec<GroupTrend>,
+    pub by_directory: Vec<GroupTrend>,
+    /// Fixed findings per author of the scan that no longer had them, most first.
+    pub burn_down: Vec<AuthorBurnDown>,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct ScanTrend {
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub commit: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub author: Option<String>,
+    /// Unix timestamp in seconds.
+    pub recorded_at: i64,
+    pub total: usize,
+    pub opened: usize,
+    /// Opened findings that had been fixed before, as recorded.
+    pub regressed: usize,
+    pub fixed: usize,
+}
+
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize)]
+pub struct AuthorBurnDown {
+    pub author: String,
+    pub fixed: usize,
+    /// Commits that fixed at least one finding.
+    pub commits: Vec<String>,
+}
+
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize)]
+pub struct GroupTrend {
+    pub key: String,
+    pub opened: usize,
+    pub fixed: usize,
+    /// Findings in the group as of the latest scan.
+    pub open: usize,
+}
+
+/// Computes the trend over `scans`, which must be ordered oldest first.
+///
+/// Everything in the first scan counts as opened. Directories are the first
+/// `group_depth` components of each finding's path. Scans recorded without an author
+/// are credited to `unknown`.
+pub fn compute_trend(scans: &[StoredScan], group_depth: usize) -> TrendReport {
+    let mut by_rule: BTreeMap<String, GroupTrend> = BTreeMap::new();
+    let mut by_directory: BTreeMap<String, GroupTrend> = BTreeMap::new();
+    let mut burn_down: BTreeMap<&str, AuthorBurnDown> = BTreeMap::new();
+    let mut scan_trends = Vec::new();
+    let empty = StoredScan::default();
+
+    for (i, scan) in scans.iter().enumerate() {
+        let previous = if i == 0 { &empty } else { &scans[i - 1] };
+        let before: HashSet<&str> = previous.fingerprints().collect();
+        let after: HashSet<&str> = scan.fingerprints().collect();
+
+        let opened: Vec<_> = scan
+            .entries
+            .iter()
+            .filter(|e| !before.contains(e.fingerprint.as_str()))
+            .collect();
+        let fixed: Vec<_> = previous
+            .entries
+            .iter()
+            .filter(|e| !after.contains(e.fingerprint.as_str()))
+            .collect();
+
+        for entry in &opened {
+            group(&mut by_rule, &entry.rule).opened += 1;
+            group(&mut by_directory, &directory(&entry.file, group_depth)).opened += 1;
+        }
+        for entry in &fixed {
+            group(&mut by_rule, &entry.rule).fixed += 1;
+            group(&mut by_directory, &directory(&entry.file, group_depth)).fixed += 1;
+        }
+
+        if !fixed.is_empty() {
+            let author = scan.author.as_deref().unwrap_or(UNKNOWN_AUTHOR);
+            let credit = burn_down.entry(author).or_insert_with(|| AuthorBurnDown {
+                author: author.to_string(),
+                ..AuthorBurnDown::default()
+            });
+            credit.fixed += fixed.len();
+            credit.commits.extend(scan.commit.clone());
+        }
+
+        scan_trends.push(ScanTrend {
+            commit: scan.commit.clone(),
+            author: scan.author.clone(),
+            recorded_at: scan.recorded_at,
+            total: scan.entries.len(),
+            opened: opened.len(),
+            regressed: opened
+                .iter()
+                .filter(|e| e.status == Some(DeltaStatus::Regressed))
+                .count(),
+            fixed: fixed.len(),
+        });
+    }
+
+    if let Some(latest) = scans.last() {
+        for entry in &latest.entries {
+            group(&mut by_rule, &entry.rule).open += 1;
+            group(&mut by_directory, &directory(&entry.file, group_depth)).open += 1;
+        }
+    }
+
+    let mut burn_down: Vec<_> = burn_down.into_values().collect();
+    burn_down.sort_by(|a, b| b.fixed.cmp(&a.fixed));
+
+    TrendReport {
+        scans: scan_trends,
+        by_rule: by_rule.into_values().collect(),
+        by_directory: by_directory.into_values().collect(),
+        burn_down,
+    }
+}
+
+impl TrendReport {
+    pub fn render_text(&self) -> String {
+        let mut out = String::new();
+        let _ = writeln!(out, "Scans:");
+        for scan in &self.scans {
+            let commit = scan
+                .commit
+                .as_deref()
+                .map_or("-", |c| &c[..c.len().min(12)]);
+            let _ = writeln!(
+                out,
+                "  {:<12} {:>12}  total {:>5}  +{:<5} -{:<5} regressed {}",
+                commit, scan.recorded_at, scan.total, scan.opened, scan.fixed, scan.regressed
+            );
+        }
+        for (title, groups) in [
+            ("By rule:", &self.by_rule),
+            ("By directory:", &self.by_directory),
+        ] {
+            let _ = writeln!(out, "\n{title}");
+            for g in groups {
+                let _ = writeln!(
+                    out,
+                    "  {:<48} open {:>5}  +{:<5} -{:<5}",
+                    g.key, g.open, g.opened, g.fixed
+                );
+            }
+        }
+        if !self.burn_down.is_empty() {
+            let _ = writeln!(out, "\nFixed by:");
+            for credit in &self.burn_down {
+                let _ = writeln!(
+                    out,
+                    "  {:<48} -{:<5} in {} commit(s)",
+                    credit.author,
+                    credit.fixed,
+                    credit.commits.len()
+                );
+            }
+        }
+        out
+    }
+}
+
+fn group<'a>(groups: &'a mut BTreeMap<String, GroupTrend>, key: &str) -> &'a mut GroupTrend {
+    groups.entry(key.to_string()).or_insert_with(|| GroupTrend {
+        key: key.to_string(),
+        ..GroupTrend::default()
+    })
+}
+
+fn directory(file: &str, depth: usize) -> String {
+    let parts: Vec<&str> = file.split('/').collect();
+    // The last component is the file name itself
+    let dirs = &parts[..parts.len().saturating_sub(1)];
+    if dirs.is_empty() {
+        return ".".to_string();
+    }
+    dirs[..dirs.len().min(depth.max(1))].join("/")
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::history::HistoryEntry;
+
+    fn scan(id: i64, entries: &[(&str, &str, &str)]) -> StoredScan {
+        StoredScan {
+            id,
+            commit: Some(format!("c{id}")),
+            author: Some(format!("dev{}", id % 2)),
+            recorded_at: id * 100,
+            entries: entries
+                .iter()
+                .map(|(fingerprint, rule, file)| HistoryEntry {
+                    fingerprint: fingerprint.to_string(),
+                    rule: rule.to_string(),
+                    file: file.to_string(),
+                    line: 1,
+                    status: None,
+                })
+                .collect(),
+        }
+    }
+
+    #[test]
+    fn test_opened_and_fixed_between_scans() {
+        let scans = [
+            scan(
+                1,
+                &[
+                    ("a", "no-md5", "billing/sum.go"),
+                    ("b", "no-md5", "auth/hash.go"),
+                ],
+            ),
+            scan(
+                2,
+                &[
+                    ("b", "no-md5", "auth/hash.go"),
+                    ("c", "pbkdf2", "auth/kdf/kdf.go"),
+                ],
+            ),
+        ];
+        let report = compute_trend(&scans, 1);
+
+        assert_eq!(report.scans[0].opened, 2);
+        assert_eq!(report.scans[1].opened, 1);
+        assert_eq!(report.scans[1].fixed, 1);
+        assert_eq!(
+            report.burn_down,
+            vec![AuthorBurnDown {
+                author: "dev0".to_string(),
+                fixed: 1,
+                commits: vec!["c2".to_string()],
+            }]
+        );
+
+        let md5 = report.by_rule.iter().find(|g| g.key == "no-md5").unwrap();
+        assert_eq!((md5.opened, md5.fixed, md5.open), (2, 1, 1));
+
+        let auth = report
+            .by_directory
+            .iter()
+            .find(|g| g.key == "auth")
+            .unwrap();
+        assert_eq!((auth.opened, auth.fixed, auth.open), (2, 0, 2));
+        let billing = report
+            .by_directory
+            .iter()
+            .find(|g| g.key == "billing")
+            .unwrap();
+        assert_eq!((billing.opened, billing.fixed, billing.open), (1, 1, 0));
+    }
+
+    #[test]
+    fn test_regressed_counts_recorded_status() {
+        let mut returned = scan(3, &[("a", "no-md5", "billing/sum.go")]);
+        returned.entries[0].status = Some(DeltaStatus::Regressed);
+        let scans = [scan(2, &[]), returned];
+
+        let report = compute_trend(&scans, 1);
+        assert_eq!((report.scans[1].opened, report.scans[1].regressed), (1, 1));
+        assert!(report.burn_down.is_empty());
+    }
+
+    #[test]
+    fn test_directory_depth() {
+        assert_eq!(directory("auth/kdf/kdf.go", 1), "auth");
+        assert_eq!(directory("auth/kdf/kdf.go", 2), "auth/kdf");
+        assert_eq!(directory("auth/kdf/kdf.go", 5), "auth/kdf");
+        assert_eq!(directory("main.go", 1), ".");
+    }
+}
@@ -0,0 +1,461 @@
+//! Per-binary crypto inventory for Go repositories with several `main` packages.
+
+use std::collections::{BTreeMap, BTreeSet};
+use std::fmt::Write as _;
+use std::path::{Path, PathBuf};
+
+use serde::Serialize;
+
+use crate::discovery::languages::go::{GoWorkspace, PackageGraph};
+use crate::output::Finding;
+use crate::policy::relative_path;
+
+#[derive(Debug, Clone, Serialize)]
+pub struct Inventory {
+    pub binaries: Vec<BinaryInventory>,
+    /// Findings in packages that no binary imports, e.g. unused libraries.
+    pub unreachable_findings: usize,
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub divergent_parameters: Vec<ParameterDivergence>,
+}
+
+/// One argument of an algorithm that packages pass different resolved values for.
+#[derive(Debug, Clone, Serialize)]
+pub struct ParameterDivergence {
+    pub algorithm: String,
+    pub parameter: String,
+    /// Package directory relative to the scan root to the distinct values it passes.
+    pub packages: BTreeMap<String, BTreeSet<String>>,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct BinaryInventory {
+    /// Directory of the `main` package relative to the scan root; `.` for the root.
+    pub binary: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub import_path: Option<String>,
+    /// Packages in the tree the binary reaches, including its own.
+    pub packages: usize,
+    pub findings: usize,
+    /// Calls encrypting, decrypting or generating data keys through a cloud KMS.
+    pub kms_calls: usize,
+    /// Cipher and MAC calls under a raw key the process holds.
+    pub local_key_calls: usize,
+    pub algorithms: Vec<AlgorithmUsage>,
+}
+
+/// One algorithm used by a binary, with every distinct resolved argument value.
+#[derive(Debug, Clone, Serialize)]
+pub struct AlgorithmUsage {
+    pub algorithm: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub primitive: Option<String>,
+    pub calls: usize,
+    pub functions: BTreeSet<String>,
+    /// Argument name to the distinct resolved values seen for it.
+    pub parameters: BTreeMap<String, BTreeSet<String>>,
+    /// KMS keys the calls name, with their key spec when set, e.g. `alias/app (AES_256)`.
+    #[serde(skip_serializing_if = "BTreeSet::is_empty")]
+    pub kms_keys: BTreeSet<String>,
+}
+
+impl Inventory {
+    /// Attributes `findings` to the `main` packages under `root`.
+    pub fn build(root: &Path, findings: &[Finding]) -> Self {
+        let root = root.canonicalize().unwrap_or_else(|_| root.to_path_buf());
+        let workspace = GoWorkspace::module(&root);
+        let graph = PackageGraph::load(&root, workspace.as_ref());
+
+        let finding_dirs: Vec<PathBuf> = findings
+            .iter()
+            .map(|f| package_dir(&f.file, &root))
+            .collect();
+        let mut reached = vec![false; findings.len()];
+
+        let mut binaries = Vec::new();
+        for main in graph.main_packages() {
+            let reachable = graph.reachable_from(&main.dir);
+            let mut algorithms: BTreeMap<String, AlgorithmUsage> = BTreeMap::new();
+            let mut count = 0;
+            let mut kms_calls = 0;
+            let mut local_key_calls = 0;
+            for (index, finding) in findings.iter().enumerate() {
+                if !reachable.contains(&finding_dirs[index]) {
+                    continue;
+                }
+                reached[index] = true;
+                count += 1;
+                if finding.kms.is_some() {
+                    kms_calls += 1;
+                } else if finding.key.is_some() {
+                    local_key_calls += 1;
+                }
+                add_usage(&mut algorithms, finding);
+            }
+
+            binaries.push(BinaryInventory {
+                binary: if main.dir == root {
+                    ".".to_string()
+                } else {
+                    relative_path(&main.dir.to_string_lossy(), &root)
+                },
+                import_path: main.import_path.clone(),
+                packages: reachable.len(),
+                findings: count,
+                kms_calls,
+                local_key_calls,
+                algorithms: algorithms.into_values().collect(),
+            });
+        }
+
+        Self {
+            binaries,
+            unreachable_findings: reached.iter().filter(|r| !**r).count(),
+            divergent_parameters: divergences(findings, &finding_dirs, &root),
+        }
+    }
+
+    pub fn render_text(&self) -> String {
+        let mut out = String::new();
+        if self.binaries.is_empty() {
+            let _ = writeln!(out, "No main packages found");
+        }
+        for binary in &self.binaries {
+            let _ = writeln!(
+                out,
+                "{}: {} crypto call(s) across {} package(s)",
+                binary.binary, binary.findings, binary.packages
+            );
+            if binary.kms_calls > 0 {
+                let _ = writeln!(
+                    out,
+                    "  key custody: {} KMS envelope call(s), {} local-key call(s)",
+                    binary.kms_calls, binary.local_key_calls
+                );
+            }
+            for usage in &binary.algorithms {
+                let primitive = usage
+                    .primitive
+                    .as_deref()
+                    .map(|p| format!(" [{p}]"))
+                    .unwrap_or_default();
+                let _ = writeln!(
+                    out,
+                    "  {}{primitive}: {} call(s) via {}",
+                    usage.algorithm,
+                    usage.calls,
+                    usage
+                        .functions
+                        .iter()
+                        .cloned()
+                        .collect::<Vec<_>>()
+                        .join(", ")
+                );
+                for (name, values) in &usage.parameters {
+                    let values: Vec<_> = values.iter().cloned().collect();
+                    let _ = writeln!(out, "    {name}: {}", values.join(", "));
+                }
+                if !usage.kms_keys.is_empty() {
+                    let keys: Vec<_> = usage.kms_keys.iter().cloned().collect();
+                    let _ = writeln!(out, "    kms keys: {}", keys.join(", "));
+                }
+            }
+        }
+        for divergence in &self.divergent_parameters {
+            let packages: Vec<_> = divergence
+                .packages
+                .iter()
+                .map(|(package, values)| {
+                    let values: Vec<_> = values.iter().cloned().collect();
+                    format!("{package} = {}", values.join(", "))
+                })
+                .collect();
+            let _ = writeln!(
+                out,
+                "divergent {} {}: {}",
+                divergence.algorithm,
+                divergence.parameter,
+                packages.join("; ")
+            );
+        }
+        if self.unreachable_findings > 0 {
+            let _ = writeln!(
+                out,
+                "{} finding(s) in packages no binary imports",
+                self.unreachable_findings
+            );
+        }
+        out
+    }
+}
+
+/// Parameters of the same algorithm that differ between packages. Packages that pass the
+/// same values agree; the parameter diverges once two packages' value sets differ.
+fn divergences(findings: &[Finding], dirs: &[PathBuf], root: &Path) -> Vec<ParameterDivergence> {
+    let mut seen: BTreeMap<(String, String), BTreeMap<String, BTreeSet<String>>> = BTreeMap::new();
+    for (finding, dir) in findings.iter().zip(dirs) {
+        let package = if dir == root {
+            ".".to_string()
+        } else {
+            relative_path(&dir.to_string_lossy(), root)
+        };
+        for (name, value) in &finding.parameters {
+            let Some(rendered) = render_value(value) else {
+                continue;
+            };
+            seen.entry((algorithm(finding), name.clone()))
+                .or_default()
+                .entry(package.clone())
+                .or_default()
+                .insert(rendered);
+        }
+    }
+
+    seen.into_iter()
+        .filter(|(_, packages)| {
+            let mut sets = packages.values();
+            let first = sets.next();
+            sets.any(|values| Some(values) != first)
+        })
+        .map(|((algorithm, parameter), packages)| ParameterDivergence {
+            algorithm,
+            parameter,
+            packages,
+        })
+        .collect()
+}
+
+fn algorithm(finding: &Finding) -> String {
+    finding
+        .algorithm
+        .clone()
+        .unwrap_or_else(|| finding.full_name.clone())
+}
+
+/// Resolved argument value as reported; `None` for arguments that did not resolve.
+fn render_value(value: &serde_json::Value) -> Option<String> {
+    match value {
+        serde_json::Value::Null => None,
+        serde_json::Value::String(s) => Some(s.clone()),
+        other => Some(other.to_string()),
+    }
+}
+
+fn add_usage(algorithms: &mut BTreeMap<String, AlgorithmUsage>, finding: &Finding) {
+    let algorithm = algorithm(finding);
+    let usage = algorithms
+        .entry(algorithm.clone())
+        .or_insert_with(|| AlgorithmUsage {
+            algorithm,
+            primitive: finding.primitive.clone(),
+            calls: 0,
+            functions: BTreeSet::new(),
+            parameters: BTreeMap::new(),
+            kms_keys: BTreeSet::new(),
+        });
+    usage.calls += 1;
+    usage.functions.insert(finding.full_name.clone());
+    if let Some(key_id) = finding.kms.as_ref().and_then(|kms| kms.key_id.as_ref()) {
+        let spec = finding.kms.as_ref().and_then(|kms| kms.key_spec.as_ref());
+        usage.kms_keys.insert(match spec {
+            Some(spec) => format!("{key_id} ({spec})"),
+            None => key_id.clone(),
+        });
+    }
+    for (name, value) in &finding.parameters {
+        let Some(rendered) = render_value(value) else {
+            continue;
+        };
+        usage
+            .parameters
+            .entry(name.clone())
+            .or_default()
+            .insert(rendered);
+    }
+}
+
+/// Canonical directory of `file`; relative paths that do not resolve from the working
+/// directory (archive scans report them relative to the root) are taken from `root`.
+fn package_dir(file: &str, root: &Path) -> PathBuf {
+    let path = Path::new(file);
+    let path = if path.exists() {
+        path.to_path_buf()
+    } else {
+        root.join(path)
+    };
+    let dir = path.parent().unwrap_or(root);
+    dir.canonicalize().unwrap_or_else(|_| dir.to_path_buf())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::scanner::{ByteOrigin, ByteSource, KmsOperation, KmsProvider};
+    use std::fs;
+    use tempfile::TempDir;
+
+    fn finding(file: &Path, algorithm: &str, full_name: &str, arg: serde_json::Value) -> Finding {
+        Finding {
+            primitive: Some("kdf".to_string()),
+            ..Finding::call(&file.to_string_lossy(), 3, 2, full_name)
+                .with_algorithm(algorithm)
+                .with_parameter("arg2", arg)
+        }
+    }
+
+    #[test]
+    fn test_findings_attributed_per_binary() {
+        let dir = TempDir::new().unwrap();
+        for (path, content) in [
+            ("go.mod", "module example.com/app\n"),
+            (
+                "cmd/server/main.go",
+                "package main\n\nimport \"example.com/app/internal/kdf\"\n",
+            ),
+            ("cmd/cli/main.go", "package main\n"),
+            ("internal/kdf/kdf.go", "package kdf\n"),
+            ("internal/legacy/md5.go", "package legacy\n"),
+        ] {
+            let path = dir.path().join(path);
+            fs::create_dir_all(path.parent().unwrap()).unwrap();
+            fs::write(path, content).unwrap();
+        }
+        let root = dir.path().canonicalize().unwrap();
+        let findings = [
+            finding(
+                &root.join("internal/kdf/kdf.go"),
+                "PBKDF2",
+                "golang.org/x/crypto/pbkdf2.Key",
+                serde_json::json!(4096),
+            ),
+            finding(
+                &root.join("internal/kdf/kdf.go"),
+                "PBKDF2",
+                "golang.org/x/crypto/pbkdf2.Key",
+                serde_json::Value::Null,
+            ),
+            finding(
+                &root.join("internal/legacy/md5.go"),
+                "MD5",
+                "crypto/md5.Sum",
+                serde_json::Value::Null,
+            ),
+        ];
+
+        let inventory = Inventory::build(&root, &findings);
+        assert_eq!(inventory.unreachable_findings, 1);
+        let binaries: Vec<_> = inventory
+            .binaries
+            .iter()
+            .map(|b| (b.binary.as_str(), b.findings))
+            .collect();
+        assert_eq!(binaries, vec![("cmd/cli", 0), ("cmd/server", 2)]);
+
+        let server = &inventory.binaries[1];
+        assert_eq!(server.packages, 2);
+        assert_eq!(server.algorithms.len(), 1);
+        assert_eq!(server.algorithms[0].calls, 2);
+        assert_eq!(
+            server.algorithms[0].parameters["arg2"],
+            BTreeSet::from(["4096".to_string()])
+        );
+        assert!(inventory
+            .render_text()
+            .contains("cmd/server: 2 crypto call(s) across 2 package(s)\n  PBKDF2 [kdf]"));
+        assert!(inventory.divergent_parameters.is_empty());
+    }
+
+    #[test]
+    fn test_divergent_parameters_across_packages() {
+        let dir = TempDir::new().unwrap();
+        for path in [
+            "go.mod",
+            "pkg/kdf/pbkdf2.go",
+            "config/constants.go",
+            "auth/kdf.go",
+        ] {
+            let path = dir.path().join(path);
+            fs::create_dir_all(path.parent().unwrap()).unwrap();
+            fs::write(path, "package x\n").unwrap();
+        }
+        let root = dir.path().canonicalize().unwrap();
+        let pbkdf2 = |file: &str, iterations: i64| {
+            finding(
+                &root.join(file),
+                "PBKDF2",
+                "golang.org/x/crypto/pbkdf2.Key",
+                serde_json::json!(iterations),
+            )
+        };
+        let findings = [
+            pbkdf2("pkg/kdf/pbkdf2.go", 100000),
+            pbkdf2("config/constants.go", 10000),
+            pbkdf2("auth/kdf.go", 100000),
+        ];
+
+        let inventory = Inventory::build(&root, &findings);
+        assert_eq!(inventory.divergent_parameters.len(), 1);
+        let divergence = &inventory.divergent_parameters[0];
+        assert_eq!(
+            (divergence.algorithm.as_str(), divergence.parameter.as_str()),
+            ("PBKDF2", "arg2")
+        );
+        assert_eq!(
+            divergence.packages.keys().collect::<Vec<_>>(),
+            vec!["auth", "config", "pkg/kdf"]
+        );
+        assert!(inventory
+            .render_text()
+            .contains("divergent PBKDF2 arg2: auth = 100000; config = 10000; pkg/kdf = 100000"));
+
+        let agreeing = Inventory::build(&root, &[findings[0].clone(), findings[2].clone()]);
+        assert!(agreeing.divergent_parameters.is_empty());
+    }
+
+    #[test]
+    fn test_kms_envelope_calls_counted_apart_from_local_keys() {
+        let dir = TempDir::new().unwrap();
+        for path in ["go.mod", "main.go"] {
+            fs::write(dir.path().join(path), "package main\n").unwrap();
+        }
+        let root = dir.path().canonicalize().unwrap();
+        let main = root.join("main.go");
+        let mut data_key = finding(
+            &main,
+            "AWS-KMS",
+            "github.com/aws/aws-sdk-go-v2/service/kms.Client.GenerateDataKey",
+            serde_json::Value::Null,
+        );
+        data_key.primitive = Some("kms".to_string());
+        data_key.kms = Some(KmsOperation {
+            provider: KmsProvider::Aws,
+            key_id: Some("alias/payments".to_string()),
+            key_spec: Some("AES_256".to_string()),
+        });
+        let mut cipher = finding(
+            &main,
+            "AES",
+            "crypto/aes.NewCipher",
+            serde_json::Value::Null,
+        );
+        cipher.key = Some(ByteSource {
+            origin: ByteOrigin::Stored,
+            length: None,
+            available: None,
+            length_from: None,
+            minimum: None,
+            expression: "cfg.Key".to_string(),
+        });
+
+        let inventory = Inventory::build(&root, &[data_key, cipher]);
+        let binary = &inventory.binaries[0];
+        assert_eq!((binary.kms_calls, binary.local_key_calls), (1, 1));
+        assert_eq!(
+            binary.algorithms[1].kms_keys,
+            BTreeSet::from(["alias/payments (AES_256)".to_string()])
+        );
+        let text = inventory.render_text();
+        assert!(text.contains("key custody: 1 KMS envelope call(s), 1 local-key call(s)"));
+        assert!(text.contains("    kms keys: alias/payments (AES_256)"));
+    }
+}
@@ -3,33 +3,106 @@
 /// Argument flow analyzer - traces where function arguments come from across
 /// multi-language codebases using Tree-sitter for parsing and a resolution
 /// engine that works across multiple languages.
+pub mod aggregate;
+pub mod analysistest;
+pub mod archive;
+pub mod attestation;
+pub mod catalog;
 pub mod classifier;
 pub mod cli;
+pub mod compare;
+pub mod coverage;
+pub mod depdiff;
 pub mod discovery;
 pub mod engine;
 pub mod error;
+pub mod golangci;
+pub mod history;
+pub mod inventory;
 pub mod logging;
 pub mod mappings;
+pub mod notify;
 pub mod output;
+pub mod params;
+pub mod policy;
 pub mod presets;
 pub mod query;
+pub mod quickstart;
+pub mod repro;
 pub mod scanner;
+pub mod schema;
+pub mod simulate;
+pub mod sinks;
+pub mod telemetry;
 pub mod utils;
+pub mod vcs;
+pub mod vulndb;
+pub mod vulnmgr;
 
 pub use classifier::{
     classify_call, Classification, ClassifiedCall, Classifier, ClassifierError, RulesClassifier,
 };
 pub use engine::{Context, Resolver, Value};
-pub use error::{Error, IoError, ParserError, QueryError};
+pub use error::{
+    ArchiveError, AttestationError, Error, HistoryError, IoError, NotifyError, ParserError,
+    PolicyError, QueryError, ReproError, TelemetryError, VulnDbError,
+};
 pub use logging::Verbosity;
-pub use output::{ConfigFinding, Finding, JsonOutput, OutputFormatter};
+pub use output::{
+    AnalysisStatus, ConfigFinding, Finding, JsonOutput, OutputFormatter, PackageStatus,
+};
 pub use presets::{load_preset, load_presets, PresetMetadata};
-pub use scanner::{CallMatcher, ImportMap, PatternMatcher, ScanResult, Scanner};
+pub use scanner::{CallMatcher, ImportMap, ModuleRef, PatternMatcher, ScanResult, Scanner};
 
 #[cfg(test)]
 mod tests {
+    use super::*;
+    use discovery::languages::go::{GoEnv, GoPackageLoader};
+
     #[test]
     fn it_works() {
         assert_eq!(2 + 2, 4);
     }
+
+    fn assert_send_sync<T: Send + Sync>() {}
+    fn assert_send<T: Send>() {}
+
+    /// Services embedding argflow run analyses of several repositories on parallel
+    /// threads; everything an analysis holds must be movable to and shareable between them.
+    #[test]
+    fn test_analysis_types_are_thread_safe() {
+        assert_send_sync::<Scanner>();
+        assert_send_sync::<RulesClassifier>();
+        assert_send_sync::<GoPackageLoader>();
+        assert_send_sync::<discovery::cache::DiscoveryCache>();
+        assert_send_sync::<JsonOutput>();
+        // A file cache belongs to one analysis and moves with it
+        assert_send::<engine::FileCache>();
+    }
+
+    #[test]
+    fn test_loaders_keep_their_own_go_env() {
+        let threads: Vec<_> = [None, Some("https://proxy.a.example"), Some("off")]
+            .into_iter()
+            .map(|proxy| {
+                std::thread::spawn(move || {
+                    let loader = GoPackageLoader::new(GoEnv {
+                        offline: proxy == Some("off"),
+                        proxy: proxy.map(str::to_string),
+                    });
+                    let command = loader.env().command(true);
+                    let goproxy = command
+                        .get_envs()
+                        .find(|(key, _)| *key == "GOPROXY")
+                        .and_then(|(_, value)| value)
+                        .map(|value| value.to_string_lossy().into_owned());
+                    (proxy, goproxy)
+                })
+            })
+            .collect();
+        for thread in threads {
+            let (proxy, goproxy) = thread.join().unwrap();
+            assert_eq!(goproxy.as_deref(), proxy);
+        }
+    }
 }
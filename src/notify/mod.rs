@@ -0,0 +1,353 @@
+//! Scan notifications to Slack, Teams or generic JSON webhooks.
+
+mod webhook;
+
+use std::fs;
+use std::path::Path;
+
+use serde::{Deserialize, Serialize};
+use tracing::debug;
+
+use crate::error::NotifyError;
+use crate::output::Finding;
+use crate::policy::{relative_path, GateReport, ViolationStatus};
+
+pub use webhook::{payload, HttpTransport, Transport};
+
+/// Findings listed individually in a notification; the rest are summarized by count.
+pub const MAX_LISTED_FINDINGS: usize = 10;
+
+#[derive(Debug, Clone, Default, Deserialize)]
+pub struct NotifyConfig {
+    #[serde(default)]
+    pub webhooks: Vec<WebhookConfig>,
+}
+
+#[derive(Debug, Clone, Deserialize)]
+pub struct WebhookConfig {
+    pub url: String,
+    #[serde(default)]
+    pub kind: WebhookKind,
+    /// Message text with `{{placeholder}}` fields; see [`NotificationSummary::field`].
+    pub template: Option<String>,
+    /// Notify only when at least this many findings are new (default 1).
+    #[serde(default = "default_min_new")]
+    pub min_new: usize,
+    /// Notify only when the gate failed. Has no effect outside `argflow gate`.
+    #[serde(default)]
+    pub only_on_failure: bool,
+}
+
+fn default_min_new() -> usize {
+    1
+}
+
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum WebhookKind {
+    Slack,
+    Teams,
+    /// POSTs the summary as JSON, or the rendered template when one is set.
+    #[default]
+    Generic,
+}
+
+/// What a notification reports about one run.
+#[derive(Debug, Clone, Default, Serialize)]
+pub struct NotificationSummary {
+    pub project: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub commit: Option<String>,
+    pub total_findings: usize,
+    /// Gate violations not in the baseline, or every finding when no gate ran.
+    pub new_findings: usize,
+    /// Gate verdict; `None` for plain scans.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub passed: Option<bool>,
+    pub blocking: usize,
+    /// Up to [`MAX_LISTED_FINDINGS`] new findings as `name file:line`.
+    pub findings: Vec<String>,
+}
+
+impl NotifyConfig {
+    pub fn from_file(path: &Path) -> Result<Self, NotifyError> {
+        let content = fs::read_to_string(path)
+            .map_err(|e| NotifyError::config_read_error(path, e.to_string()))?;
+
+        let extension = path.extension().and_then(|e| e.to_str()).unwrap_or("");
+        match extension {
+            "json" => serde_json::from_str(&content)
+                .map_err(|e| NotifyError::config_parse_error(path, e.to_string())),
+            "yaml" | "yml" => serde_yaml::from_str(&content)
+                .map_err(|e| NotifyError::config_parse_error(path, e.to_string())),
+            _ => Err(NotifyError::UnsupportedFormat {
+                format: extension.to_string(),
+            }),
+        }
+    }
+
+    /// Sends the summary to every webhook whose thresholds it meets. Failures are
+    /// collected rather than aborting, so one broken webhook does not hide the others.
+    pub fn notify(
+        &self,
+        summary: &NotificationSummary,
+        transport: &dyn Transport,
+        env: &dyn Fn(&str) -> Option<String>,
+    ) -> Vec<NotifyError> {
+        let mut errors = Vec::new();
+        for webhook in &self.webhooks {
+            if !webhook.should_notify(summary) {
+                debug!(kind = ?webhook.kind, "below notification threshold, skipping webhook");
+                continue;
+            }
+            let url = expand_env(&webhook.url, env);
+            let (content_type, body) = payload(webhook, summary);
+            if let Err(message) = transport.post(&url, content_type, &body) {
+                errors.push(NotifyError::send_error(redact_url(&url), message));
+            }
+        }
+        errors
+    }
+}
+
+impl WebhookConfig {
+    pub fn should_notify(&self, summary: &NotificationSummary) -> bool {
+        if self.only_on_failure && summary.passed != Some(false) {
+            return false;
+        }
+        summary.new_findings >= self.min_new
+    }
+}
+
+impl NotificationSummary {
+    /// Summary of a plain scan, where every finding counts as new.
+    pub fn from_findings(
+        project: &str,
+        commit: Option<String>,
+        findings: &[Finding],
+        root: &Path,
+    ) -> Self {
+        Self {
+            project: project.to_string(),
+            commit,
+            total_findings: findings.len(),
+            new_findings: findings.len(),
+            passed: None,
+            blocking: 0,
+            findings: findings
+                .iter()
+                .take(MAX_LISTED_FINDINGS)
+                .map(|f| {
+                    format!(
+                        "{} {}:{}",
+                        f.full_name,
+                        relative_path(&f.file, root),
+                        f.line
+                    )
+                })
+                .collect(),
+        }
+    }
+
+    /// Summary of a gate run, counting only violations that are not baselined.
+    pub fn from_gate(project: &str, commit: Option<String>, gate: &GateReport) -> Self {
+        let new: Vec<_> = gate
+            .violations
+            .iter()
+            .filter(|v| v.status == ViolationStatus::New)
+            .collect();
+        Self {
+            project: project.to_string(),
+            commit,
+            total_findings: gate.summary.violations,
+            new_findings: new.len(),
+            passed: Some(gate.passed),
+            blocking: gate.summary.blocking,
+            findings: new
+                .iter()
+                .take(MAX_LISTED_FINDINGS)
+                .map(|v| {
+                    let line = format!("[{}] {} {}:{}", v.rule, v.function, v.file, v.line);
+                    match &v.remediation_url {
+                        Some(url) => format!("{line} {url}"),
+                        None => line,
+                    }
+                })
+                .collect(),
+        }
+    }
+
+    /// Value of a template placeholder: `project`, `commit`, `total`, `new`, `blocking`,
+    /// `status` or `findings` (one per line, with a `...and N more` line if truncated).
+    pub fn field(&self, name: &str) -> Option<String> {
+        Some(match name {
+            "project" => self.project.clone(),
+            "commit" => self
+                .commit
+                .as_deref()
+                .map_or("unknown", |c| &c[..c.len().min(12)])
+                .to_string(),
+            "total" => self.total_findings.to_string(),
+            "new" => self.new_findings.to_string(),
+            "blocking" => self.blocking.to_string(),
+            "status" => match self.passed {
+                Some(true) => "passed",
+                Some(false) => "failed",
+                None => "scanned",
+            }
+            .to_string(),
+            "findings" => {
+                let mut lines = self.findings.clone();
+                if self.new_findings > self.findings.len() {
+                    lines.push(format!(
+                        "...and {} more",
+                        self.new_findings - self.findings.len()
+                    ));
+                }
+                lines.join("\n")
+            }
+            _ => return None,
+        })
+    }
+
+    /// Replaces `{{field}}` placeholders; unknown placeholders are left as written.
+    pub fn render(&self, template: &str) -> String {
+        let mut out = String::new();
+        let mut rest = template;
+        while let Some(start) = rest.find("{{") {
+            out.push_str(&rest[..start]);
+            let after = &rest[start + 2..];
+            match after.find("}}") {
+                Some(end) => {
+                    let name = after[..end].trim();
+                    match self.field(name) {
+                        Some(value) => out.push_str(&value),
+                        None => out.push_str(&rest[start..start + 2 + end + 2]),
+                    }
+                    rest = &after[end + 2..];
+                }
+                None => {
+                    out.push_str(&rest[start..]);
+                    rest = "";
+                }
+            }
+        }
+        out.push_str(rest);
+        out
+    }
+}
+
+/// Expands `${NAME}` references using `env`; unset variables expand to nothing.
+fn expand_env(value: &str, env: &dyn Fn(&str) -> Option<String>) -> String {
+    let mut out = String::new();
+    let mut rest = value;
+    while let Some(start) = rest.find("${") {
+        let Some(end) = rest[start..].find('}') else {
+            break;
+        };
+        out.push_str(&rest[..start]);
+        out.push_str(&env(&rest[start + 2..start + end]).unwrap_or_default());
+        rest = &rest[start + end + 1..];
+    }
+    out.push_str(rest);
+    out
+}
+
+/// Scheme and host only; webhook paths usually embed the secret token.
+fn redact_url(url: &str) -> String {
+    let (scheme, rest) = url.split_once("://").unwrap_or(("", url));
+    let host = rest.split('/').next().unwrap_or(rest);
+    if scheme.is_empty() {
+        host.to_string()
+    } else {
+        format!("{scheme}://{host}")
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::cell::RefCell;
+
+    #[derive(Default)]
+    struct Recorder {
+        posts: RefCell<Vec<(String, String)>>,
+    }
+
+    impl Transport for Recorder {
+        fn post(&self, url: &str, _content_type: &str, body: &str) -> Result<(), String> {
+            self.posts
+                .borrow_mut()
+                .push((url.to_string(), body.to_string()));
+            Ok(())
+        }
+    }
+
+    fn summary(new_findings: usize, passed: Option<bool>) -> NotificationSummary {
+        NotificationSummary {
+            project: "app".to_string(),
+            commit: Some("0123456789abcdef".to_string()),
+            total_findings: 5,
+            new_findings,
+            passed,
+            blocking: 1,
+            findings: vec!["[no-md5] crypto/md5.Sum sum.go:10".to_string()],
+        }
+    }
+
+    fn webhook(template: Option<&str>) -> WebhookConfig {
+        WebhookConfig {
+            url: "https://hooks.slack.com/services/${SLACK_TOKEN}".to_string(),
+            kind: WebhookKind::Slack,
+            template: template.map(str::to_string),
+            min_new: 1,
+            only_on_failure: false,
+        }
+    }
+
+    #[test]
+    fn test_render_template() {
+        let text = summary(3, Some(false))
+            .render("{{project}}@{{commit}} {{status}}: {{new}} new {{unknown}}\n{{findings}}");
+        assert_eq!(
+            text,
+            "app@0123456789ab failed: 3 new {{unknown}}\n[no-md5] crypto/md5.Sum sum.go:10\n...and 2 more"
+        );
+    }
+
+    #[test]
+    fn test_thresholds() {
+        let mut hook = webhook(None);
+        assert!(hook.should_notify(&summary(1, None)));
+        assert!(!hook.should_notify(&summary(0, None)));
+
+        hook.only_on_failure = true;
+        assert!(!hook.should_notify(&summary(1, Some(true))));
+        assert!(hook.should_notify(&summary(1, Some(false))));
+    }
+
+    #[test]
+    fn test_notify_expands_env_and_skips_quiet_runs() {
+        let config = NotifyConfig {
+            webhooks: vec![webhook(Some("{{new}} new findings"))],
+        };
+        let recorder = Recorder::default();
+        let env = |key: &str| (key == "SLACK_TOKEN").then(|| "T000/B000/XXX".to_string());
+
+        assert!(config.notify(&summary(0, None), &recorder, &env).is_empty());
+        assert!(recorder.posts.borrow().is_empty());
+
+        assert!(config.notify(&summary(2, None), &recorder, &env).is_empty());
+        let posts = recorder.posts.borrow();
+        assert_eq!(posts[0].0, "https://hooks.slack.com/services/T000/B000/XXX");
+        assert_eq!(posts[0].1, r#"{"text":"2 new findings"}"#);
+    }
+
+    #[test]
+    fn test_redact_url() {
+        assert_eq!(
+            redact_url("https://hooks.slack.com/services/T000/B000/XXX"),
+            "https://hooks.slack.com"
+        );
+    }
+}
@@ -0,0 +1,135 @@
+use std::time::Duration;
+
+use serde_json::json;
+
+use super::{NotificationSummary, WebhookConfig, WebhookKind};
+
+const REQUEST_TIMEOUT: Duration = Duration::from_secs(10);
+
+const JSON_CONTENT_TYPE: &str = "application/json";
+const TEXT_CONTENT_TYPE: &str = "text/plain; charset=utf-8";
+
+const DEFAULT_TEMPLATE: &str =
+    "argflow {{status}} {{project}} at {{commit}}: {{new}} new of {{total}} findings ({{blocking}} blocking)\n{{findings}}";
+
+/// Sends a request body to a webhook URL.
+pub trait Transport {
+    fn post(&self, url: &str, content_type: &str, body: &str) -> Result<(), String>;
+}
+
+/// Blocking HTTP transport.
+pub struct HttpTransport {
+    client: reqwest::blocking::Client,
+}
+
+impl HttpTransport {
+    pub fn new() -> Result<Self, String> {
+        let client = reqwest::blocking::Client::builder()
+            .timeout(REQUEST_TIMEOUT)
+            .user_agent(concat!("argflow/", env!("CARGO_PKG_VERSION")))
+            .build()
+            .map_err(|e| e.to_string())?;
+        Ok(Self { client })
+    }
+}
+
+impl Transport for HttpTransport {
+    fn post(&self, url: &str, content_type: &str, body: &str) -> Result<(), String> {
+        let response = self
+            .client
+            .post(url)
+            .header(reqwest::header::CONTENT_TYPE, content_type)
+            .body(body.to_string())
+            .send()
+            // reqwest errors include the URL, which carries the webhook secret
+            .map_err(|e| e.without_url().to_string())?;
+        let status = response.status();
+        if !status.is_success() {
+            return Err(format!("HTTP {status}"));
+        }
+        Ok(())
+    }
+}
+
+/// Content type and body for one webhook.
+///
+/// Slack and Teams incoming webhooks both accept `{"text": ...}`. Generic webhooks get
+/// the summary as JSON, or the rendered template when one is configured.
+pub fn payload(webhook: &WebhookConfig, summary: &NotificationSummary) -> (&'static str, String) {
+    match (webhook.kind, &webhook.template) {
+        (WebhookKind::Slack | WebhookKind::Teams, template) => {
+            let text = summary.render(template.as_deref().unwrap_or(DEFAULT_TEMPLATE));
+            (JSON_CONTENT_TYPE, json!({ "text": text }).to_string())
+        }
+        (WebhookKind::Generic, None) => (
+            JSON_CONTENT_TYPE,
+            serde_json::to_string(summary).unwrap_or_default(),
+        ),
+        (WebhookKind::Generic, Some(template)) => {
+            let body = summary.render(template);
+            let content_type = if serde_json::from_str::<serde_json::Value>(&body).is_ok() {
+                JSON_CONTENT_TYPE
+            } else {
+                TEXT_CONTENT_TYPE
+            };
+            (content_type, body)
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn webhook(kind: WebhookKind, template: Option<&str>) -> WebhookConfig {
+        WebhookConfig {
+            url: "https://example.com/hook".to_string(),
+            kind,
+            template: template.map(str::to_string),
+            min_new: 1,
+            only_on_failure: false,
+        }
+    }
+
+    fn summary() -> NotificationSummary {
+        NotificationSummary {
+            project: "app".to_string(),
+            total_findings: 2,
+            new_findings: 2,
+            ..NotificationSummary::default()
+        }
+    }
+
+    #[test]
+    fn test_generic_payloads() {
+        let (content_type, body) = payload(&webhook(WebhookKind::Generic, None), &summary());
+        assert_eq!(content_type, JSON_CONTENT_TYPE);
+        assert_eq!(
+            serde_json::from_str::<serde_json::Value>(&body).unwrap()["new_findings"],
+            2
+        );
+
+        let (content_type, body) = payload(
+            &webhook(WebhookKind::Generic, Some(r#"{"count": {{new}}}"#)),
+            &summary(),
+        );
+        assert_eq!(content_type, JSON_CONTENT_TYPE);
+        assert_eq!(body, r#"{"count": 2}"#);
+
+        let (content_type, _) = payload(
+            &webhook(WebhookKind::Generic, Some("{{new}} new")),
+            &summary(),
+        );
+        assert_eq!(content_type, TEXT_CONTENT_TYPE);
+    }
+
+    #[test]
+    fn test_teams_uses_default_template() {
+        let (_, body) = payload(&webhook(WebhookKind::Teams, None), &summary());
+        let text = serde_json::from_str::<serde_json::Value>(&body).unwrap()["text"]
+            .as_str()
+            .unwrap()
+            .to_string();
+        assert!(text.starts_with("argflow scanned app at unknown: 2 new of 2 findings"));
+    }
+}
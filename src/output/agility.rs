@@ -0,0 +1,134 @@
+use serde::Serialize;
+use std::collections::BTreeMap;
+use std::path::Path;
+
+use crate::scanner::{AgilityClass, ConstantRef};
+
+use super::Finding;
+
+/// How the algorithm and tunable parameters of one finding are chosen.
+#[derive(Debug, Clone, Serialize)]
+pub struct FindingAgility {
+    pub algorithm: AgilityClass,
+    /// Argument name to its class; data arguments are left out.
+    #[serde(skip_serializing_if = "BTreeMap::is_empty")]
+    pub parameters: BTreeMap<String, AgilityClass>,
+    /// Argument name to the project constants it reads.
+    #[serde(skip_serializing_if = "BTreeMap::is_empty")]
+    pub constants: BTreeMap<String, Vec<ConstantRef>>,
+}
+
+/// Crypto-agility scorecard of one package: how many algorithm and parameter choices
+/// are hardcoded, compile-time constants, or configurable at runtime.
+#[derive(Debug, Clone, Serialize)]
+pub struct PackageAgility {
+    /// Directory of the package's files.
+    pub package: String,
+    pub findings: usize,
+    pub hardcoded_literal: usize,
+    pub compile_time_constant: usize,
+    pub runtime_configurable: usize,
+    /// 0-100: runtime-configurable choices count fully, constants half, literals not at all.
+    pub score: u8,
+}
+
+impl PackageAgility {
+    /// One scorecard per package with classified findings, in package order.
+    pub fn scorecard(findings: &[Finding]) -> Vec<PackageAgility> {
+        let mut packages: BTreeMap<String, PackageAgility> = BTreeMap::new();
+        for finding in findings {
+            let Some(agility) = &finding.agility else {
+                continue;
+            };
+            let package = Path::new(&finding.file)
+                .parent()
+                .map(|dir| dir.to_string_lossy().into_owned())
+                .unwrap_or_default();
+            let card = packages
+                .entry(package.clone())
+                .or_insert_with(|| PackageAgility {
+                    package,
+                    findings: 0,
+                    hardcoded_literal: 0,
+                    compile_time_constant: 0,
+                    runtime_configurable: 0,
+                    score: 0,
+                });
+            card.findings += 1;
+            for class in std::iter::once(&agility.algorithm).chain(agility.parameters.values()) {
+                match class {
+                    AgilityClass::HardcodedLiteral => card.hardcoded_literal += 1,
+                    AgilityClass::CompileTimeConstant => card.compile_time_constant += 1,
+                    AgilityClass::RuntimeConfigurable => card.runtime_configurable += 1,
+                }
+            }
+        }
+
+        packages
+            .into_values()
+            .map(|mut card| {
+                let total =
+                    card.hardcoded_literal + card.compile_time_constant + card.runtime_configurable;
+                let points = 2 * card.runtime_configurable + card.compile_time_constant;
+                card.score = (points * 100 / (2 * total.max(1))) as u8;
+                card
+            })
+            .collect()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn finding(file: &str, algorithm: AgilityClass, parameters: &[AgilityClass]) -> Finding {
+        Finding {
+            agility: Some(FindingAgility {
+                algorithm,
+                parameters: parameters
+                    .iter()
+                    .enumerate()
+                    .map(|(i, class)| (format!("arg{i}"), *class))
+                    .collect(),
+                constants: BTreeMap::new(),
+            }),
+            ..Finding::call(file, 1, 1, "pbkdf2.Key")
+                .with_package("pbkdf2")
+                .with_import_path("golang.org/x/crypto/pbkdf2")
+                .with_algorithm("PBKDF2")
+        }
+    }
+
+    #[test]
+    fn test_scorecard_per_package() {
+        use AgilityClass::*;
+        let findings = [
+            finding(
+                "auth/kdf.go",
+                HardcodedLiteral,
+                &[HardcodedLiteral, HardcodedLiteral],
+            ),
+            finding("auth/token.go", HardcodedLiteral, &[CompileTimeConstant]),
+            finding("config/kdf.go", RuntimeConfigurable, &[RuntimeConfigurable]),
+        ];
+
+        let cards = PackageAgility::scorecard(&findings);
+        let summary: Vec<_> = cards
+            .iter()
+            .map(|c| {
+                (
+                    c.package.as_str(),
+                    c.findings,
+                    c.hardcoded_literal,
+                    c.compile_time_constant,
+                    c.runtime_configurable,
+                    c.score,
+                )
+            })
+            .collect();
+        assert_eq!(
+            summary,
+            vec![("auth", 2, 4, 1, 0, 10), ("config", 1, 0, 0, 2, 100)]
+        );
+    }
+}
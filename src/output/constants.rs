@@ -0,0 +1,142 @@
+use serde::Serialize;
+use std::collections::BTreeMap;
+use std::path::Path;
+
+use crate::scanner::ConstantRef;
+
+use super::Finding;
+
+/// One call a project constant reaches.
+#[derive(Debug, Clone, Serialize)]
+pub struct ConstantSink {
+    pub file: String,
+    pub line: usize,
+    pub column: usize,
+    pub function: String,
+    /// The argument reading the constant, e.g. `arg2`.
+    pub parameter: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub algorithm: Option<String>,
+}
+
+/// A named constant of the project and every crypto call it reaches, so a change to
+/// the constant can be reviewed against all of them.
+#[derive(Debug, Clone, Serialize)]
+pub struct ConstantUsage {
+    /// `package.Name`, the package named by its directory, e.g. `config.PBKDF2Iterations`.
+    pub constant: String,
+    /// Directory of the package declaring the constant.
+    pub package: String,
+    pub sinks: Vec<ConstantSink>,
+}
+
+impl ConstantUsage {
+    /// One entry per constant read by an argument of `findings`, in constant order.
+    pub fn index(findings: &[Finding]) -> Vec<ConstantUsage> {
+        let mut constants: BTreeMap<&ConstantRef, Vec<ConstantSink>> = BTreeMap::new();
+        for finding in findings {
+            let Some(agility) = &finding.agility else {
+                continue;
+            };
+            for (parameter, refs) in &agility.constants {
+                for constant in refs {
+                    constants.entry(constant).or_default().push(ConstantSink {
+                        file: finding.file.clone(),
+                        line: finding.line,
+                        column: finding.column,
+                        function: finding.full_name.clone(),
+                        parameter: parameter.clone(),
+                        algorithm: finding.algorithm.clone(),
+                    });
+                }
+            }
+        }
+
+        constants
+            .into_iter()
+            .map(|(constant, sinks)| {
+                let package = Path::new(&constant.package)
+                    .file_name()
+                    .map(|name| name.to_string_lossy().into_owned())
+                    .unwrap_or_default();
+                ConstantUsage {
+                    constant: format!("{package}.{}", constant.name),
+                    package: constant.package.clone(),
+                    sinks,
+                }
+            })
+            .collect()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::output::FindingAgility;
+    use crate::scanner::AgilityClass;
+
+    fn call(file: &str, line: usize, function: &str, constants: &[(&str, &str)]) -> Finding {
+        Finding {
+            agility: Some(FindingAgility {
+                algorithm: AgilityClass::HardcodedLiteral,
+                parameters: BTreeMap::new(),
+                constants: constants
+                    .iter()
+                    .map(|(parameter, name)| {
+                        (
+                            parameter.to_string(),
+                            vec![ConstantRef {
+                                package: "/src/app/config".to_string(),
+                                name: name.to_string(),
+                            }],
+                        )
+                    })
+                    .collect(),
+            }),
+            ..Finding::call(
+                file,
+                line,
+                5,
+                &format!("golang.org/x/crypto/pbkdf2.{function}"),
+            )
+            .with_algorithm("PBKDF2")
+        }
+    }
+
+    #[test]
+    fn test_index_groups_sinks_by_constant() {
+        let usages = ConstantUsage::index(&[
+            call(
+                "/src/app/auth/login.go",
+                20,
+                "Key",
+                &[("arg2", "PBKDF2Iterations"), ("arg3", "KeyLen")],
+            ),
+            call(
+                "/src/app/auth/reset.go",
+                41,
+                "Key",
+                &[("arg2", "PBKDF2Iterations")],
+            ),
+            call("/src/app/auth/legacy.go", 9, "Key", &[]),
+        ]);
+
+        let constants: Vec<_> = usages.iter().map(|u| u.constant.as_str()).collect();
+        assert_eq!(constants, vec!["config.KeyLen", "config.PBKDF2Iterations"]);
+
+        let iterations = &usages[1];
+        assert_eq!(iterations.package, "/src/app/config");
+        let sinks: Vec<_> = iterations
+            .sinks
+            .iter()
+            .map(|s| (s.file.as_str(), s.line, s.parameter.as_str()))
+            .collect();
+        assert_eq!(
+            sinks,
+            vec![
+                ("/src/app/auth/login.go", 20, "arg2"),
+                ("/src/app/auth/reset.go", 41, "arg2"),
+            ]
+        );
+    }
+}
@@ -0,0 +1,196 @@
+use serde::Serialize;
+use std::collections::{BTreeMap, BTreeSet};
+
+use super::{CryptoOperation, Finding, WrapperRole};
+
+/// Several functions implementing the same operation with the same algorithms, e.g.
+/// three AES-GCM encryption helpers: candidates for consolidation onto one vetted wrapper.
+#[derive(Debug, Clone, Serialize)]
+pub struct DuplicateOperation {
+    pub purpose: String,
+    /// The algorithms every implementation uses, sorted.
+    pub algorithms: Vec<String>,
+    pub implementations: Vec<Implementation>,
+    /// Sink arguments the implementations pass different resolved values for, e.g.
+    /// `golang.org/x/crypto/pbkdf2.Key arg2`.
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub differing_parameters: Vec<String>,
+    pub message: String,
+}
+
+/// One function implementing a duplicated operation.
+#[derive(Debug, Clone, Serialize)]
+pub struct Implementation {
+    pub package: String,
+    pub file: String,
+    pub function: String,
+    /// Line of the function's first crypto call.
+    pub line: usize,
+    /// `<sink> <argument>` to the resolved value the function passes.
+    #[serde(skip_serializing_if = "BTreeMap::is_empty")]
+    pub parameters: BTreeMap<String, serde_json::Value>,
+}
+
+impl DuplicateOperation {
+    /// Operations among `findings` implemented by more than one function. Mocks and calls
+    /// of a mapped wrapper are not implementations; operations of unknown purpose or
+    /// without algorithms are not compared.
+    pub fn detect(findings: &[Finding]) -> Vec<DuplicateOperation> {
+        let implementations: Vec<Finding> = findings
+            .iter()
+            .filter(|f| f.mock.is_none())
+            .filter(|f| {
+                f.wrapper
+                    .as_ref()
+                    .is_none_or(|link| link.role != WrapperRole::CallSite)
+            })
+            .cloned()
+            .collect();
+
+        let mut groups: BTreeMap<(String, Vec<String>), Vec<Implementation>> = BTreeMap::new();
+        for operation in CryptoOperation::group(&implementations) {
+            if operation.purpose == "crypto" || operation.algorithms.is_empty() {
+                continue;
+            }
+            let mut algorithms = operation.algorithms.clone();
+            algorithms.sort();
+            let parameters = implementations
+                .iter()
+                .filter(|f| {
+                    f.file == operation.file
+                        && f.enclosing_function.as_deref() == Some(operation.function.as_str())
+                })
+                .flat_map(|f| {
+                    f.parameters
+                        .iter()
+                        .filter(|(_, value)| !value.is_null())
+                        .map(|(name, value)| (format!("{} {name}", f.full_name), value.clone()))
+                })
+                .collect();
+            groups
+                .entry((operation.purpose.clone(), algorithms))
+                .or_default()
+                .push(Implementation {
+                    package: operation.package,
+                    file: operation.file,
+                    function: operation.function,
+                    line: operation.steps.first().map_or(0, |step| step.line),
+                    parameters,
+                });
+        }
+
+        groups
+            .into_iter()
+            .filter(|(_, implementations)| implementations.len() > 1)
+            .map(|((purpose, algorithms), implementations)| {
+                let differing_parameters = differing(&implementations);
+                let message = format!(
+                    "{} functions implement {} {purpose} separately{}; consolidate them onto one vetted wrapper",
+                    implementations.len(),
+                    algorithms.join(" + "),
+                    if differing_parameters.is_empty() {
+                        String::new()
+                    } else {
+                        format!(" with different {}", differing_parameters.join(", "))
+                    },
+                );
+                DuplicateOperation {
+                    purpose,
+                    algorithms,
+                    implementations,
+                    differing_parameters,
+                    message,
+                }
+            })
+            .collect()
+    }
+}
+
+/// Parameters set by at least two implementations to different values.
+fn differing(implementations: &[Implementation]) -> Vec<String> {
+    let mut values: BTreeMap<&str, BTreeSet<String>> = BTreeMap::new();
+    for implementation in implementations {
+        for (name, value) in &implementation.parameters {
+            values.entry(name).or_default().insert(value.to_string());
+        }
+    }
+    values
+        .into_iter()
+        .filter(|(_, values)| values.len() > 1)
+        .map(|(name, _)| name.to_string())
+        .collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn call(file: &str, function: &str, sink: &str, algorithm: &str, arg: i64) -> Finding {
+        Finding::call(file, 5, 2, sink)
+            .with_algorithm(algorithm)
+            .with_operation("encrypt")
+            .with_parameter("arg1", serde_json::json!(arg))
+            .in_function(function)
+    }
+
+    fn helper(file: &str, function: &str, nonce_size: i64) -> [Finding; 2] {
+        [
+            call(file, function, "crypto/aes.NewCipher", "AES", 32),
+            call(
+                file,
+                function,
+                "crypto/cipher.NewGCMWithNonceSize",
+                "AES-GCM",
+                nonce_size,
+            ),
+        ]
+    }
+
+    #[test]
+    fn test_same_operation_in_several_functions() {
+        let mut findings = Vec::new();
+        findings.extend(helper("pkg/auth/token.go", "sealToken", 12));
+        findings.extend(helper("pkg/store/blob.go", "encryptBlob", 16));
+        findings.extend(helper("internal/crypt/crypt.go", "Encrypt", 12));
+        let mut mock = helper("mocks/crypt.go", "Encrypt", 12);
+        for finding in &mut mock {
+            finding.mock = Some("mockgen".to_string());
+        }
+        findings.extend(mock);
+
+        let duplicates = DuplicateOperation::detect(&findings);
+        assert_eq!(duplicates.len(), 1);
+        let duplicate = &duplicates[0];
+        assert_eq!(duplicate.purpose, "encryption");
+        assert_eq!(duplicate.algorithms, vec!["AES", "AES-GCM"]);
+        let functions: Vec<_> = duplicate
+            .implementations
+            .iter()
+            .map(|i| i.function.as_str())
+            .collect();
+        assert_eq!(functions, vec!["Encrypt", "sealToken", "encryptBlob"]);
+        assert_eq!(
+            duplicate.differing_parameters,
+            vec!["crypto/cipher.NewGCMWithNonceSize arg1"]
+        );
+        assert!(duplicate
+            .message
+            .starts_with("3 functions implement AES + AES-GCM encryption separately"));
+    }
+
+    #[test]
+    fn test_single_implementation_is_not_a_duplicate() {
+        let mut findings = helper("pkg/auth/token.go", "sealToken", 12).to_vec();
+        let mut hash = call(
+            "pkg/auth/token.go",
+            "digest",
+            "crypto/sha256.New",
+            "SHA-256",
+            0,
+        );
+        hash.operation = Some("digest".to_string());
+        findings.push(hash.clone());
+        findings.push(Finding { line: 7, ..hash });
+        assert!(DuplicateOperation::detect(&findings).is_empty());
+    }
+}
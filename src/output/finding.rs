@@ -1,11 +1,21 @@
 use serde::Serialize;
-use std::collections::HashMap;
+use std::cmp::Ordering;
+use std::collections::{BTreeMap, BTreeSet};
+use std::path::Path;
 
-use crate::classifier::RulesClassifier;
-use crate::engine::Value;
-use crate::scanner::{ConfigFinding as ScannerConfigFinding, Finding as ScannerFinding};
+use crate::classifier::{RulesClassifier, ValueContext};
+use crate::engine::{ResolutionStatus, UnknownReason, UnresolvedSource, Value};
+use crate::scanner::{
+    ByteSource, CallbackRegistration, ConfigFinding as ScannerConfigFinding, ConstantRef,
+    CrashPath, FailurePath, Finding as ScannerFinding, IterationTuning, KeyEncoding, KeyExchange,
+    KmsOperation, LongLivedAead, NonceCounter, PasswordStorage, Pkcs11Operation, RandomBias,
+    RemediationEffort, SecretComparison, UnauthenticatedMode, ValidityPeriod, ZeroFallback,
+};
+use crate::vcs::Blame;
 
-#[derive(Debug, Clone, Serialize)]
+use super::{AlgorithmSelection, FindingAgility, WrapperLink};
+
+#[derive(Debug, Clone, Default, Serialize)]
 pub struct Finding {
     pub file: String,
     pub line: usize,
@@ -24,8 +34,201 @@ pub struct Finding {
     pub operation: Option<String>,
     #[serde(skip_serializing_if = "Option::is_none")]
     pub primitive: Option<String>,
-    pub parameters: HashMap<String, serde_json::Value>,
+    pub parameters: BTreeMap<String, serde_json::Value>,
+    pub parameter_status: BTreeMap<String, ParameterStatus>,
+    /// Semantic category of each categorized argument, e.g. `iterations: iteration-count`,
+    /// so rules can be written against categories instead of individual sinks.
+    #[serde(skip_serializing_if = "BTreeMap::is_empty")]
+    pub parameter_categories: BTreeMap<String, String>,
     pub raw_text: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub enclosing_function: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub build_constraint: Option<String>,
+    /// Generator of the mock file the finding is in, e.g. `mockgen`. Policies skip these
+    /// findings.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub mock: Option<String>,
+    /// Module that owns the file, for joining findings against SBOMs.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub module: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub module_version: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub purl: Option<String>,
+    /// Known crypto-related advisories for the called API or the owning module version.
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub advisories: Vec<AdvisoryRef>,
+    /// Per-configuration sites when the same call is compiled under several build constraints.
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub configurations: Vec<BuildVariant>,
+    /// `return nil, nil` and `panic` paths of the enclosing `(T, error)` constructor.
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub failure_paths: Vec<FailurePath>,
+    /// `panic` and `log.Fatal` calls of the enclosing function, outside package `main`.
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub crash_paths: Vec<CrashPath>,
+    /// Arguments that are a loader's zero value when its error is ignored or swallowed.
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub zero_fallbacks: Vec<ZeroFallback>,
+    /// Every algorithm the registry function returning this call can select.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub selection: Option<AlgorithmSelection>,
+    /// Calls that constructed the receiver of a method call, e.g. the AEAD behind `Seal`.
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub receiver_chain: Vec<String>,
+    /// Arguments or fields of the constructor that built the receiver of a method call.
+    #[serde(skip_serializing_if = "BTreeMap::is_empty")]
+    pub receiver_parameters: BTreeMap<String, serde_json::Value>,
+    /// Where the password or input key material comes from, for key-derivation calls.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub secret: Option<ByteSource>,
+    /// Where the salt comes from, for key-derivation calls.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub salt: Option<ByteSource>,
+    /// Where the key comes from, for cipher and MAC constructors.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub key: Option<ByteSource>,
+    /// Where the nonce comes from, for AEAD `Seal` and `Open` calls.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub nonce: Option<ByteSource>,
+    /// Where an encoded private key goes and whether it is encrypted.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub key_encoding: Option<KeyEncoding>,
+    /// Whether the private key of a key exchange is generated per exchange or reused.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub key_exchange: Option<KeyExchange>,
+    /// The incremented counter an AEAD nonce is encoded from, and whether it is bounded.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub nonce_counter: Option<NonceCounter>,
+    /// A password reaching a fast hash, or serialized or stored as is.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub password_storage: Option<PasswordStorage>,
+    /// A MAC or key compared with a call or operator that does not run in constant time.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub secret_comparison: Option<SecretComparison>,
+    /// A KDF work factor calibrated by timing derivations at runtime, and the floor it is
+    /// raised to, if any.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub iteration_tuning: Option<IterationTuning>,
+    /// A package-level or singleton AEAD this `Seal` call shares with every other message
+    /// the process seals, with no path that rotates its key.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub long_lived_aead: Option<LongLivedAead>,
+    /// The cloud KMS key a KMS client call encrypts, decrypts or generates a data key under:
+    /// envelope encryption rather than crypto with a key the process holds.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub kms: Option<KmsOperation>,
+    /// The mechanisms and key template attributes of a PKCS#11 call into an HSM.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub pkcs11: Option<Pkcs11Operation>,
+    /// The mode, cipher and key size of CBC, CTR, OFB or CFB encryption whose ciphertext no
+    /// MAC covers.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub unauthenticated_mode: Option<UnauthenticatedMode>,
+    /// A random number reduced to a range with bias, drawn with a bound taken from another
+    /// random value, or drawn from `math/rand` for a secret.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub random_bias: Option<RandomBias>,
+    /// Certificate lifetimes, key rotation intervals and key cache TTLs the call sets,
+    /// resolved to seconds.
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub validity: Vec<ValidityPeriod>,
+    /// The HTTP handler, cron job or worker-pool task registration that makes the call
+    /// reachable although nothing calls its function by name.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub callback: Option<CallbackRegistration>,
+    /// Estimated work to replace the call, for planning crypto-agility changes.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub remediation_effort: Option<RemediationEffort>,
+    /// Whether the algorithm and tunable parameters are hardcoded, constants or configurable.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub agility: Option<FindingAgility>,
+    /// The same crypto use reported through a wrapper function and its calls.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub wrapper: Option<WrapperLink>,
+    /// The last change to the call's line, with `--blame`.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub blame: Option<Blame>,
+}
+
+/// One build configuration of a finding that was merged across build-constrained files.
+#[derive(Debug, Clone, Serialize)]
+pub struct BuildVariant {
+    pub build_constraint: String,
+    pub file: String,
+    pub line: usize,
+    pub column: usize,
+    pub parameters: BTreeMap<String, serde_json::Value>,
+    /// Kept to tell literal secrets from resolved names when redacting.
+    #[serde(skip)]
+    pub parameter_status: BTreeMap<String, ParameterStatus>,
+}
+
+/// A vulnerability advisory related to a finding.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct AdvisoryRef {
+    pub id: String,
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub aliases: Vec<String>,
+    pub summary: String,
+    /// First version that fixes the advisory, if one exists.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub fixed: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub url: Option<String>,
+    pub relation: AdvisoryMatch,
+}
+
+/// Why an advisory is attached to a finding.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "lowercase")]
+pub enum AdvisoryMatch {
+    /// The finding calls a package or symbol the advisory names.
+    Api,
+    /// The finding is in an affected version of the advisory's module.
+    Module,
+    /// govulncheck found a call path to the vulnerable symbol through this call site.
+    Reachable,
+}
+
+/// A vulnerability govulncheck reported for the scanned module.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct Vulnerability {
+    pub id: String,
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub aliases: Vec<String>,
+    pub summary: String,
+    pub module: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub version: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub fixed: Option<String>,
+    /// Vulnerable symbol the code calls, when govulncheck analyzed at symbol level.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub symbol: Option<String>,
+    /// Call path from the vulnerable symbol up to the entry point, as `function (file:line)`.
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub trace: Vec<String>,
+    /// Whether the advisory matched crypto-related packages or keywords.
+    pub crypto: bool,
+}
+
+/// Resolution status of a single argument, with a reason when it is unknown.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct ParameterStatus {
+    pub status: ResolutionStatus,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub reason: Option<UnknownReason>,
+}
+
+impl ParameterStatus {
+    pub fn of(value: &Value) -> Self {
+        Self {
+            status: ResolutionStatus::of(value),
+            reason: UnknownReason::of(value),
+        }
+    }
 }
 
 #[derive(Debug, Clone, Serialize)]
@@ -47,6 +250,9 @@ pub struct ConfigFinding {
 pub struct ConfigFieldValue {
     pub field_name: String,
     pub value: serde_json::Value,
+    pub status: ResolutionStatus,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub reason: Option<UnknownReason>,
     #[serde(skip_serializing_if = "Option::is_none")]
     pub classification_key: Option<String>,
 }
@@ -55,17 +261,91 @@ impl Finding {
     pub fn from_scanner_finding(call: &ScannerFinding, classifier: &RulesClassifier) -> Self {
         let classification = crate::classifier::classify_call(call, classifier);
 
-        let parameters = call
+        // Arguments are named by the roles the sink declares, positionally otherwise
+        let roles = classifier
+            .parameter_roles(
+                call.import_path.as_deref(),
+                call.package.as_deref().unwrap_or(""),
+                &call.function_name,
+            )
+            .unwrap_or_default();
+        let name = |i: usize| match roles.get(i) {
+            Some(role) if !role.is_empty() => role.clone(),
+            _ => format!("arg{i}"),
+        };
+
+        let parameters: BTreeMap<String, serde_json::Value> = call
+            .arguments
+            .iter()
+            .enumerate()
+            .map(|(i, v)| (name(i), value_to_json(v)))
+            .collect();
+
+        let parameter_status = call
             .arguments
             .iter()
             .enumerate()
-            .map(|(i, v)| {
-                let name = format!("arg{i}");
-                let param_value = value_to_json(v);
-                (name, param_value)
+            .map(|(i, v)| (name(i), ParameterStatus::of(v)))
+            .collect();
+
+        let parameter_categories = (0..call.arguments.len())
+            .filter_map(|i| {
+                let value = parameters.get(&name(i))?;
+                let category = classifier.value_category(&ValueContext {
+                    function: &call.function_name,
+                    classification: &classification,
+                    position: i,
+                    role: roles.get(i).map(String::as_str).filter(|r| !r.is_empty()),
+                    value,
+                })?;
+                Some((name(i), category))
             })
             .collect();
 
+        // Constructor arguments are named the same way; literal fields keep their names
+        let receiver_parameters = call
+            .receiver_construction
+            .iter()
+            .flat_map(|built| {
+                let (import_path, function) = built.name.rsplit_once('.').unwrap_or(("", ""));
+                let package = import_path.rsplit('/').next().unwrap_or_default();
+                let roles = classifier
+                    .parameter_roles(Some(import_path), package, function)
+                    .unwrap_or_default();
+                built
+                    .arguments
+                    .iter()
+                    .enumerate()
+                    .map(move |(i, (field, v))| {
+                        let name = match (field, roles.get(i)) {
+                            (Some(field), _) => field.clone(),
+                            (None, Some(role)) if !role.is_empty() => role.clone(),
+                            _ => format!("arg{i}"),
+                        };
+                        (name, value_to_json(v))
+                    })
+            })
+            .collect();
+
+        let agility = call.agility.as_ref().map(|agility| {
+            let mut constants: BTreeMap<String, Vec<ConstantRef>> = BTreeMap::new();
+            for (i, constant) in &agility.constants {
+                constants
+                    .entry(name(*i))
+                    .or_default()
+                    .push(constant.clone());
+            }
+            FindingAgility {
+                algorithm: agility.algorithm,
+                parameters: agility
+                    .arguments
+                    .iter()
+                    .map(|(i, class)| (name(*i), *class))
+                    .collect(),
+                constants,
+            }
+        });
+
         Finding {
             file: call.file_path.clone(),
             line: call.line,
@@ -74,7 +354,12 @@ impl Finding {
             package: call.package.clone(),
             import_path: call.import_path.clone(),
             full_name: call.full_name(),
-            algorithm: classification.algorithm,
+            // A PKCS#11 call names its algorithm by mechanism, not by function
+            algorithm: call
+                .pkcs11
+                .as_ref()
+                .and_then(|pkcs11| pkcs11.algorithm.clone())
+                .or(classification.algorithm),
             finding_type: if classification.finding_type.is_empty() {
                 None
             } else {
@@ -87,12 +372,219 @@ impl Finding {
             },
             primitive: classification.primitive,
             parameters,
+            parameter_status,
+            parameter_categories,
             raw_text: call.raw_text.clone(),
+            enclosing_function: call.enclosing_function.clone(),
+            build_constraint: None,
+            mock: None,
+            module: None,
+            module_version: None,
+            purl: None,
+            advisories: Vec::new(),
+            configurations: Vec::new(),
+            failure_paths: call.failure_paths.clone(),
+            crash_paths: call.crash_paths.clone(),
+            zero_fallbacks: call
+                .zero_fallbacks
+                .iter()
+                .map(|fallback| ZeroFallback {
+                    argument: name(fallback.position),
+                    ..fallback.clone()
+                })
+                .collect(),
+            selection: call.selection.as_ref().map(AlgorithmSelection::from),
+            receiver_chain: call.receiver_chain.clone(),
+            receiver_parameters,
+            secret: call.secret.clone(),
+            salt: call.salt.clone(),
+            key: call.key.clone(),
+            nonce: call.nonce.clone(),
+            key_encoding: call.key_encoding.clone(),
+            key_exchange: call.key_exchange.clone(),
+            nonce_counter: call.nonce_counter.clone(),
+            password_storage: call.password_storage.clone(),
+            secret_comparison: call.secret_comparison.clone(),
+            iteration_tuning: call.iteration_tuning.clone(),
+            long_lived_aead: call.long_lived_aead.clone(),
+            kms: call.kms.clone(),
+            pkcs11: call.pkcs11.clone(),
+            unauthenticated_mode: call.unauthenticated_mode.clone(),
+            random_bias: call.random_bias.clone(),
+            validity: call.validity.clone(),
+            callback: call.callback.clone(),
+            remediation_effort: call.remediation_effort,
+            agility,
+            wrapper: None,
+            blame: None,
+        }
+    }
+}
+
+impl Finding {
+    /// Stable report order: module, package, file, position, call name, then the
+    /// classification the call matched.
+    pub fn report_order(&self, other: &Self) -> Ordering {
+        self.module
+            .cmp(&other.module)
+            .then_with(|| package_dir(&self.file).cmp(package_dir(&other.file)))
+            .then_with(|| self.file.cmp(&other.file))
+            .then_with(|| self.line.cmp(&other.line))
+            .then_with(|| self.column.cmp(&other.column))
+            .then_with(|| self.full_name.cmp(&other.full_name))
+            .then_with(|| self.finding_type.cmp(&other.finding_type))
+            .then_with(|| self.algorithm.cmp(&other.algorithm))
+    }
+}
+
+#[cfg(test)]
+impl Finding {
+    /// A call to `full_name` at `file:line:column`, named after the last segment of
+    /// `full_name`. Tests set the other fields they need with the `with_*` methods or
+    /// struct update syntax.
+    pub fn call(file: &str, line: usize, column: usize, full_name: &str) -> Self {
+        Finding {
+            file: file.to_string(),
+            line,
+            column,
+            function: full_name
+                .rsplit('.')
+                .next()
+                .unwrap_or(full_name)
+                .to_string(),
+            full_name: full_name.to_string(),
+            ..Default::default()
+        }
+    }
+
+    pub fn with_import_path(mut self, import_path: &str) -> Self {
+        self.import_path = Some(import_path.to_string());
+        self
+    }
+
+    pub fn with_package(mut self, package: &str) -> Self {
+        self.package = Some(package.to_string());
+        self
+    }
+
+    pub fn with_algorithm(mut self, algorithm: &str) -> Self {
+        self.algorithm = Some(algorithm.to_string());
+        self
+    }
+
+    pub fn with_operation(mut self, operation: &str) -> Self {
+        self.operation = Some(operation.to_string());
+        self
+    }
+
+    pub fn with_parameter(mut self, name: &str, value: serde_json::Value) -> Self {
+        self.parameters.insert(name.to_string(), value);
+        self
+    }
+
+    pub fn with_raw_text(mut self, raw_text: &str) -> Self {
+        self.raw_text = raw_text.to_string();
+        self
+    }
+
+    pub fn in_function(mut self, function: &str) -> Self {
+        self.enclosing_function = Some(function.to_string());
+        self
+    }
+}
+
+impl Finding {
+    fn as_build_variant(&self) -> Option<BuildVariant> {
+        Some(BuildVariant {
+            build_constraint: self.build_constraint.clone()?,
+            file: self.file.clone(),
+            line: self.line,
+            column: self.column,
+            parameters: self.parameters.clone(),
+            parameter_status: self.parameter_status.clone(),
+        })
+    }
+}
+
+/// Collapses findings for the same call site compiled under different build constraints
+/// (e.g. `keys_linux.go` and `keys_windows.go`) into one finding with per-configuration values.
+///
+/// Sites are matched by package, enclosing function, callee and their position among
+/// identical calls in that function. Expects `findings` in report order.
+pub fn merge_build_variants(findings: Vec<Finding>) -> Vec<Finding> {
+    let mut occurrences: BTreeMap<(&str, Option<&str>, &str), usize> = BTreeMap::new();
+    let mut sites: BTreeMap<(&Path, Option<&str>, &str, usize), Vec<usize>> = BTreeMap::new();
+
+    for (idx, finding) in findings.iter().enumerate() {
+        if finding.build_constraint.is_none() {
+            continue;
         }
+        let function = finding.enclosing_function.as_deref();
+        let occurrence = occurrences
+            .entry((&finding.file, function, &finding.full_name))
+            .or_default();
+        sites
+            .entry((
+                package_dir(&finding.file),
+                function,
+                &finding.full_name,
+                *occurrence,
+            ))
+            .or_default()
+            .push(idx);
+        *occurrence += 1;
     }
+
+    // Primary finding index -> all members of its site, primary first
+    let groups: BTreeMap<usize, Vec<usize>> = sites
+        .into_values()
+        .filter(|members| {
+            let files: BTreeSet<_> = members.iter().map(|&i| &findings[i].file).collect();
+            members.len() > 1 && files.len() == members.len()
+        })
+        .map(|members| (members[0], members))
+        .collect();
+
+    let absorbed: BTreeSet<usize> = groups
+        .values()
+        .flat_map(|members| members.iter().skip(1).copied())
+        .collect();
+    let mut configurations: BTreeMap<usize, Vec<BuildVariant>> = groups
+        .iter()
+        .map(|(&primary, members)| {
+            let variants = members
+                .iter()
+                .filter_map(|&i| findings[i].as_build_variant())
+                .collect();
+            (primary, variants)
+        })
+        .collect();
+
+    findings
+        .into_iter()
+        .enumerate()
+        .filter(|(idx, _)| !absorbed.contains(idx))
+        .map(|(idx, mut finding)| {
+            if let Some(variants) = configurations.remove(&idx) {
+                finding.build_constraint = None;
+                finding.configurations = variants;
+            }
+            finding
+        })
+        .collect()
 }
 
 impl ConfigFinding {
+    /// Stable report order: package, file, position, then struct type.
+    pub fn report_order(&self, other: &Self) -> Ordering {
+        package_dir(&self.file)
+            .cmp(package_dir(&other.file))
+            .then_with(|| self.file.cmp(&other.file))
+            .then_with(|| self.line.cmp(&other.line))
+            .then_with(|| self.column.cmp(&other.column))
+            .then_with(|| self.full_type.cmp(&other.full_type))
+    }
+
     pub fn from_scanner_config(config: &ScannerConfigFinding) -> Self {
         let fields = config
             .fields
@@ -100,6 +592,8 @@ impl ConfigFinding {
             .map(|f| ConfigFieldValue {
                 field_name: f.field_name.clone(),
                 value: value_to_json(&f.value),
+                status: ResolutionStatus::of(&f.value),
+                reason: UnknownReason::of(&f.value),
                 classification_key: f.classification_key.clone(),
             })
             .collect();
@@ -118,6 +612,10 @@ impl ConfigFinding {
     }
 }
 
+fn package_dir(file: &str) -> &Path {
+    Path::new(file).parent().unwrap_or(Path::new(""))
+}
+
 fn value_to_json(value: &Value) -> serde_json::Value {
     // Resolved: return direct value
     if !value.int_values.is_empty() {
@@ -146,9 +644,16 @@ fn value_to_json(value: &Value) -> serde_json::Value {
         }
     // Partial: return value with source
     } else if !value.expression.is_empty() {
+        let source = if value.source.is_empty()
+            || value.source == UnresolvedSource::PartiallyResolved.as_str()
+        {
+            "partial_expression"
+        } else {
+            value.source.as_str()
+        };
         serde_json::json!({
             "value": value.expression,
-            "source": "partial_expression"
+            "source": source
         })
     // Unresolved: return source only
     } else if !value.source.is_empty() {
@@ -0,0 +1,264 @@
+use serde::Serialize;
+use std::collections::BTreeMap;
+
+use super::Finding;
+
+/// Which validated crypto implementation a build would use.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, PartialOrd, Ord, Serialize)]
+#[serde(rename_all = "snake_case")]
+pub enum FipsMode {
+    /// No FIPS configuration was found; the standard Go implementations are used.
+    None,
+    /// Built with `GOEXPERIMENT=boringcrypto`, backed by BoringSSL's validated module.
+    Boringcrypto,
+    /// Go 1.24+ native FIPS 140-3 module with `fips140=on`.
+    Fips140On,
+    /// As `fips140_on`, but non-approved algorithms return errors or panic.
+    Fips140Only,
+}
+
+impl FipsMode {
+    pub fn as_str(&self) -> &'static str {
+        match self {
+            FipsMode::None => "none",
+            FipsMode::Boringcrypto => "boringcrypto",
+            FipsMode::Fips140On => "fips140_on",
+            FipsMode::Fips140Only => "fips140_only",
+        }
+    }
+}
+
+/// Evidence of a FIPS configuration in the build or the code.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct FipsSignal {
+    pub kind: FipsSignalKind,
+    /// Where the signal was found: `file:line`, a file, or `$VARIABLE`.
+    pub source: String,
+    pub detail: String,
+    /// The FIPS mode the signal turns on, if it turns one on rather than just hinting at it.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub enables: Option<FipsMode>,
+}
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "snake_case")]
+pub enum FipsSignalKind {
+    /// `GOEXPERIMENT=boringcrypto` in the environment or a build file.
+    Goexperiment,
+    /// `//go:build boringcrypto` constraint on a file.
+    BuildConstraint,
+    /// Blank import of `crypto/tls/fipsonly`.
+    FipsonlyImport,
+    /// `godebug fips140=...` in go.mod, `//go:debug fips140=...`, or `GODEBUG`.
+    Godebug,
+    /// `GOFIPS140` selecting a module version at build time.
+    Gofips140,
+    /// Import of `crypto/fips140`, usually to check `fips140.Enabled()` at runtime.
+    Fips140Import,
+}
+
+/// Whether the calls into a package would be served by the validated module.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, PartialOrd, Ord, Serialize)]
+#[serde(rename_all = "snake_case")]
+pub enum FipsStatus {
+    Validated,
+    /// Standard library, but not an approved algorithm (e.g. MD5, DES, RC4).
+    NotApproved,
+    /// Outside the validated module, e.g. `golang.org/x/crypto` or third-party code.
+    OutsideModule,
+}
+
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct FipsCodePath {
+    pub import_path: String,
+    pub status: FipsStatus,
+    pub findings: usize,
+}
+
+/// The overall FIPS posture of a scanned Go project.
+#[derive(Debug, Clone, Serialize)]
+pub struct FipsPosture {
+    pub mode: FipsMode,
+    pub summary: String,
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub signals: Vec<FipsSignal>,
+    pub validated_findings: usize,
+    pub unvalidated_findings: usize,
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub code_paths: Vec<FipsCodePath>,
+}
+
+/// Standard library packages served by the Go Cryptographic Module (Go 1.24+).
+const FIPS140_PACKAGES: &[&str] = &[
+    "crypto/aes",
+    "crypto/cipher",
+    "crypto/ecdh",
+    "crypto/ecdsa",
+    "crypto/ed25519",
+    "crypto/hkdf",
+    "crypto/hmac",
+    "crypto/mlkem",
+    "crypto/pbkdf2",
+    "crypto/rand",
+    "crypto/rsa",
+    "crypto/sha256",
+    "crypto/sha3",
+    "crypto/sha512",
+    "crypto/tls",
+    "crypto/x509",
+];
+
+/// Standard library packages backed by BoringCrypto under `GOEXPERIMENT=boringcrypto`.
+const BORINGCRYPTO_PACKAGES: &[&str] = &[
+    "crypto/aes",
+    "crypto/cipher",
+    "crypto/ecdh",
+    "crypto/ecdsa",
+    "crypto/hmac",
+    "crypto/rand",
+    "crypto/rsa",
+    "crypto/sha1",
+    "crypto/sha256",
+    "crypto/sha512",
+    "crypto/tls",
+    "crypto/x509",
+];
+
+impl FipsPosture {
+    /// Derives the posture from the detected signals and classifies each finding's package.
+    ///
+    /// With no mode enabled, packages are classified as they would be under `fips140=on`
+    /// so the report shows what enabling it would cover.
+    pub fn assess(signals: Vec<FipsSignal>, findings: &[Finding]) -> Self {
+        let mode = signals
+            .iter()
+            .filter_map(|s| s.enables)
+            .max()
+            .unwrap_or(FipsMode::None);
+        let validated_packages = match mode {
+            FipsMode::Boringcrypto => BORINGCRYPTO_PACKAGES,
+            _ => FIPS140_PACKAGES,
+        };
+
+        let mut counts: BTreeMap<&str, usize> = BTreeMap::new();
+        for finding in findings {
+            if let Some(import_path) = finding.import_path.as_deref() {
+                *counts.entry(import_path).or_default() += 1;
+            }
+        }
+
+        let code_paths: Vec<FipsCodePath> = counts
+            .into_iter()
+            .map(|(import_path, findings)| FipsCodePath {
+                import_path: import_path.to_string(),
+                status: package_status(import_path, validated_packages),
+                findings,
+            })
+            .collect();
+        let validated_findings = code_paths
+            .iter()
+            .filter(|p| p.status == FipsStatus::Validated)
+            .map(|p| p.findings)
+            .sum();
+        let unvalidated_findings = code_paths
+            .iter()
+            .filter(|p| p.status != FipsStatus::Validated)
+            .map(|p| p.findings)
+            .sum();
+
+        Self {
+            summary: summary(mode, validated_findings, unvalidated_findings),
+            mode,
+            signals,
+            validated_findings,
+            unvalidated_findings,
+            code_paths,
+        }
+    }
+}
+
+fn package_status(import_path: &str, validated_packages: &[&str]) -> FipsStatus {
+    if validated_packages.contains(&import_path) {
+        FipsStatus::Validated
+    } else if import_path.starts_with("crypto/") {
+        FipsStatus::NotApproved
+    } else {
+        FipsStatus::OutsideModule
+    }
+}
+
+fn summary(mode: FipsMode, validated: usize, unvalidated: usize) -> String {
+    let coverage = format!(
+        "{validated} of {} findings call packages in the validated module",
+        validated + unvalidated
+    );
+    match mode {
+        FipsMode::None => format!("No FIPS mode is enabled; with fips140=on, {coverage}"),
+        FipsMode::Boringcrypto => format!("Built with BoringCrypto; {coverage}"),
+        FipsMode::Fips140On => format!("Go FIPS 140-3 mode is on; {coverage}"),
+        FipsMode::Fips140Only => format!(
+            "Go FIPS 140-3 mode is enforced (fips140=only); {coverage}, the rest fail at runtime"
+        ),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn finding(import_path: &str) -> Finding {
+        Finding::call("main.go", 1, 1, &format!("{import_path}.New")).with_import_path(import_path)
+    }
+
+    fn signal(enables: Option<FipsMode>) -> FipsSignal {
+        FipsSignal {
+            kind: FipsSignalKind::Godebug,
+            source: "go.mod".to_string(),
+            detail: "fips140=only".to_string(),
+            enables,
+        }
+    }
+
+    #[test]
+    fn test_classifies_code_paths() {
+        let findings = [
+            finding("crypto/sha256"),
+            finding("crypto/sha256"),
+            finding("crypto/md5"),
+            finding("golang.org/x/crypto/chacha20poly1305"),
+        ];
+        let posture = FipsPosture::assess(vec![signal(Some(FipsMode::Fips140Only))], &findings);
+
+        assert_eq!(posture.mode, FipsMode::Fips140Only);
+        assert_eq!(posture.validated_findings, 2);
+        assert_eq!(posture.unvalidated_findings, 2);
+        let status = |path: &str| {
+            posture
+                .code_paths
+                .iter()
+                .find(|p| p.import_path == path)
+                .unwrap()
+                .status
+        };
+        assert_eq!(status("crypto/sha256"), FipsStatus::Validated);
+        assert_eq!(status("crypto/md5"), FipsStatus::NotApproved);
+        assert_eq!(
+            status("golang.org/x/crypto/chacha20poly1305"),
+            FipsStatus::OutsideModule
+        );
+    }
+
+    #[test]
+    fn test_mode_depends_on_enabling_signals() {
+        let posture = FipsPosture::assess(vec![signal(None)], &[finding("crypto/sha1")]);
+        assert_eq!(posture.mode, FipsMode::None);
+        assert_eq!(posture.unvalidated_findings, 1);
+
+        let boring = FipsPosture::assess(
+            vec![signal(Some(FipsMode::Boringcrypto))],
+            &[finding("crypto/sha1")],
+        );
+        assert_eq!(boring.mode, FipsMode::Boringcrypto);
+        assert_eq!(boring.validated_findings, 1);
+    }
+}
@@ -1,20 +1,106 @@
 use anyhow::Result;
 use serde::Serialize;
+use std::io::Write;
 
 use crate::classifier::RulesClassifier;
 use crate::cli::OutputFormat;
 use crate::scanner::ScanResult;
+use crate::vcs::Repository;
 
-use super::{ConfigFinding, Finding};
+use super::{
+    attribute_wrappers, collect_selection_options, link_wrappers, merge_build_variants,
+    AnalysisStatus, ConfigFinding, ConstantUsage, CryptoOperation, DuplicateOperation, Finding,
+    FipsPosture, GeneratorSetting, KeyMismatch, ModuleScan, NonceOverflow, PackageAgility,
+    PackageStatus, ScanManifest, Vulnerability,
+};
+use crate::cli::WrapperAttribution;
 
-#[derive(Debug, Serialize)]
+#[derive(Debug, Clone, Serialize)]
 pub struct JsonOutput {
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub go_version: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub analysis_status: Option<AnalysisStatus>,
     pub files_scanned: usize,
     pub total_findings: usize,
     pub total_configs: usize,
     pub findings: Vec<Finding>,
     #[serde(skip_serializing_if = "Vec::is_empty")]
     pub configs: Vec<ConfigFinding>,
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub packages: Vec<PackageStatus>,
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub vulnerabilities: Vec<Vulnerability>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub fips: Option<FipsPosture>,
+    /// Keys sliced down or sized differently from what the consuming call uses.
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub key_mismatches: Vec<KeyMismatch>,
+    /// AEAD nonce counters with no bound check or key rotation before they wrap.
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub nonce_overflows: Vec<NonceOverflow>,
+    /// Per-package share of crypto choices that can change without a code edit.
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub agility: Vec<PackageAgility>,
+    /// Project constants and every crypto call they reach.
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub constant_usage: Vec<ConstantUsage>,
+    /// Findings made together by one function, grouped into a single operation.
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub operations: Vec<CryptoOperation>,
+    /// Operations implemented separately by several functions with the same algorithms.
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub duplicate_operations: Vec<DuplicateOperation>,
+    /// Crypto settings in `//go:generate` directives and code generator configs.
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub generator_settings: Vec<GeneratorSetting>,
+    /// Go modules scanned with `--recurse-modules` and their finding counts.
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub modules: Vec<ModuleScan>,
+    /// What the run needs to be reproduced: tool version, settings, catalogs, toolchain.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub manifest: Option<ScanManifest>,
+    /// The repository the scanned tree is checked out from, with `--blame`.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub repository: Option<Repository>,
+}
+
+impl JsonOutput {
+    /// Drops judgments (advisories, vulnerabilities, FIPS posture, key mismatches, nonce
+    /// overflows, duplicate operations and constructor failure paths), leaving the usage
+    /// catalog: sinks, argument values, locations and provenance.
+    pub fn retain_inventory(&mut self) {
+        self.vulnerabilities.clear();
+        self.fips = None;
+        self.key_mismatches.clear();
+        self.nonce_overflows.clear();
+        self.duplicate_operations.clear();
+        for finding in &mut self.findings {
+            finding.advisories.clear();
+            finding.failure_paths.clear();
+            finding.crash_paths.clear();
+            finding.zero_fallbacks.clear();
+        }
+    }
+
+    /// Keeps the definition, the call sites, or both of findings linked through a wrapper,
+    /// and recomputes what is derived from the findings.
+    pub fn attribute_wrappers(&mut self, strategy: WrapperAttribution) {
+        attribute_wrappers(&mut self.findings, strategy);
+        self.total_findings = self.findings.len();
+        self.key_mismatches = KeyMismatch::detect(&self.findings);
+        self.nonce_overflows = NonceOverflow::detect(&self.findings);
+        self.agility = PackageAgility::scorecard(&self.findings);
+        self.constant_usage = ConstantUsage::index(&self.findings);
+        self.operations = CryptoOperation::group(&self.findings);
+        self.duplicate_operations = DuplicateOperation::detect(&self.findings);
+    }
+
+    /// Attaches per-package analysis status and the rolled-up status for the run.
+    pub fn set_packages(&mut self, packages: Vec<PackageStatus>) {
+        self.analysis_status = Some(AnalysisStatus::overall(&packages));
+        self.packages = packages;
+    }
 }
 
 pub struct OutputFormatter;
@@ -26,7 +112,10 @@ impl OutputFormatter {
         format: OutputFormat,
     ) -> Result<String> {
         let output = Self::build_output(results, classifier);
+        Self::render(&output, format)
+    }
 
+    pub fn render(output: &JsonOutput, format: OutputFormat) -> Result<String> {
         match format {
             OutputFormat::Json => Ok(serde_json::to_string_pretty(&output)?),
             OutputFormat::Cbom => {
@@ -36,30 +125,273 @@ impl OutputFormatter {
         }
     }
 
+    /// Serializes `output` straight into `writer`, so a large report is never held in
+    /// memory as one document.
+    pub fn write(output: &JsonOutput, format: OutputFormat, writer: impl Write) -> Result<()> {
+        match format {
+            OutputFormat::Json => serde_json::to_writer_pretty(writer, output)?,
+            OutputFormat::Cbom => {
+                tracing::warn!("CBOM output not yet implemented, using JSON");
+                serde_json::to_writer_pretty(writer, output)?
+            }
+        }
+        Ok(())
+    }
+
     pub fn build_output(results: &[ScanResult], classifier: &RulesClassifier) -> JsonOutput {
-        let findings: Vec<Finding> = results
+        let mut findings: Vec<Finding> = results
             .iter()
             .flat_map(|r| {
-                r.calls
-                    .iter()
-                    .map(|call| Finding::from_scanner_finding(call, classifier))
+                r.calls.iter().map(|call| {
+                    let mut finding = Finding::from_scanner_finding(call, classifier);
+                    finding.build_constraint = r.build_constraint.clone();
+                    finding.mock = r.mock.clone();
+                    if let Some(module) = &r.module {
+                        finding.module = Some(module.path.clone());
+                        finding.module_version = module.version.clone();
+                        finding.purl = Some(module.purl.clone());
+                    }
+                    finding
+                })
             })
             .collect();
 
-        let configs: Vec<ConfigFinding> = results
+        let mut configs: Vec<ConfigFinding> = results
             .iter()
             .flat_map(|r| r.configs.iter().map(ConfigFinding::from_scanner_config))
             .collect();
 
+        // Input order depends on directory walk and scheduling; sort so reports diff cleanly.
+        findings.sort_by(Finding::report_order);
+        configs.sort_by(ConfigFinding::report_order);
+        let mut findings = merge_build_variants(findings);
+        collect_selection_options(&mut findings);
+        link_wrappers(&mut findings);
+        let key_mismatches = KeyMismatch::detect(&findings);
+        let nonce_overflows = NonceOverflow::detect(&findings);
+        let agility = PackageAgility::scorecard(&findings);
+        let constant_usage = ConstantUsage::index(&findings);
+        let operations = CryptoOperation::group(&findings);
+        let duplicate_operations = DuplicateOperation::detect(&findings);
+
         let total_findings = findings.len();
         let total_configs = configs.len();
 
         JsonOutput {
+            go_version: None,
+            analysis_status: None,
             files_scanned: results.len(),
             total_findings,
             total_configs,
             findings,
             configs,
+            packages: Vec::new(),
+            vulnerabilities: Vec::new(),
+            fips: None,
+            key_mismatches,
+            nonce_overflows,
+            agility,
+            constant_usage,
+            operations,
+            duplicate_operations,
+            generator_settings: Vec::new(),
+            modules: Vec::new(),
+            manifest: None,
+            repository: None,
         }
     }
 }
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::policy::Policy;
+    use crate::scanner::{ErrorHandling, Finding as ScannerFinding, Scanner};
+    use std::collections::HashMap;
+    use tree_sitter::Parser;
+
+    fn scan_result(file: &str, calls: &[(usize, &str)]) -> ScanResult {
+        scan_result_in(file, None, calls)
+    }
+
+    fn scan_result_in(file: &str, enclosing: Option<&str>, calls: &[(usize, &str)]) -> ScanResult {
+        let mut result = ScanResult::new(file.to_string());
+        for (line, function) in calls {
+            result.add_call(ScannerFinding {
+                file_path: file.to_string(),
+                line: *line,
+                column: 1,
+                function_name: function.to_string(),
+                package: Some("sha256".to_string()),
+                import_path: Some("crypto/sha256".to_string()),
+                arguments: vec![],
+                raw_text: format!("sha256.{function}()"),
+                language: "go".to_string(),
+                enclosing_function: enclosing.map(|f| f.to_string()),
+                failure_paths: Vec::new(),
+                crash_paths: Vec::new(),
+                zero_fallbacks: Vec::new(),
+                selection: None,
+                receiver_chain: Vec::new(),
+                receiver_construction: None,
+                secret: None,
+                salt: None,
+                key: None,
+                nonce: None,
+                key_encoding: None,
+                key_exchange: None,
+                nonce_counter: None,
+                password_storage: None,
+                secret_comparison: None,
+                iteration_tuning: None,
+                long_lived_aead: None,
+                kms: None,
+                pkcs11: None,
+                unauthenticated_mode: None,
+                random_bias: None,
+                validity: Vec::new(),
+                callback: None,
+                remediation_effort: None,
+                agility: None,
+            });
+        }
+        result
+    }
+
+    #[test]
+    fn test_build_output_orders_findings() {
+        let classifier = RulesClassifier::new();
+        let results = vec![
+            scan_result("app/pkg/b.go", &[(9, "Sum256"), (3, "New")]),
+            scan_result("app/main.go", &[(20, "New")]),
+            scan_result("app/pkg/a.go", &[(5, "Sum256"), (5, "New")]),
+        ];
+
+        let output = OutputFormatter::build_output(&results, &classifier);
+        let order: Vec<_> = output
+            .findings
+            .iter()
+            .map(|f| format!("{}:{}:{}", f.file, f.line, f.function))
+            .collect();
+
+        assert_eq!(
+            order,
+            vec![
+                "app/main.go:20:New",
+                "app/pkg/a.go:5:New",
+                "app/pkg/a.go:5:Sum256",
+                "app/pkg/b.go:3:New",
+                "app/pkg/b.go:9:Sum256",
+            ]
+        );
+
+        let mut reversed = results;
+        reversed.reverse();
+        let again = OutputFormatter::build_output(&reversed, &classifier);
+        assert_eq!(
+            serde_json::to_string(&output).unwrap(),
+            serde_json::to_string(&again).unwrap()
+        );
+    }
+
+    #[test]
+    fn test_report_order_across_modules() {
+        let finding = |module: &str, file: &str, line: usize, finding_type: &str| Finding {
+            finding_type: Some(finding_type.to_string()),
+            module: Some(module.to_string()),
+            ..Finding::call(file, line, 0, "crypto/sha256.New")
+        };
+        let mut findings = vec![
+            finding("example.com/zeta", "a/main.go", 3, "hash"),
+            finding("example.com/alpha", "z/pkg/b.go", 7, "hash"),
+            finding("example.com/alpha", "z/pkg/b.go", 7, "digest"),
+            finding("example.com/alpha", "z/main.go", 40, "hash"),
+        ];
+        findings.sort_by(Finding::report_order);
+
+        let order: Vec<_> = findings
+            .iter()
+            .map(|f| {
+                format!(
+                    "{} {}:{} {}",
+                    f.module.as_deref().unwrap(),
+                    f.file,
+                    f.line,
+                    f.finding_type.as_deref().unwrap()
+                )
+            })
+            .collect();
+        assert_eq!(
+            order,
+            vec![
+                "example.com/alpha z/main.go:40 hash",
+                "example.com/alpha z/pkg/b.go:7 digest",
+                "example.com/alpha z/pkg/b.go:7 hash",
+                "example.com/zeta a/main.go:3 hash",
+            ]
+        );
+    }
+
+    #[test]
+    fn test_build_output_merges_build_variants() {
+        let classifier = RulesClassifier::new();
+        let mut linux = scan_result_in("app/keys/keys_linux.go", Some("newKey"), &[(7, "New")]);
+        linux.build_constraint = Some("linux".to_string());
+        let mut windows = scan_result_in("app/keys/keys_windows.go", Some("newKey"), &[(9, "New")]);
+        windows.build_constraint = Some("windows".to_string());
+        let common = scan_result_in("app/keys/keys.go", Some("newKey"), &[(4, "New")]);
+
+        let output = OutputFormatter::build_output(&[windows, common, linux], &classifier);
+
+        assert_eq!(output.total_findings, 2);
+        let merged = output
+            .findings
+            .iter()
+            .find(|f| !f.configurations.is_empty())
+            .unwrap();
+        assert_eq!(merged.file, "app/keys/keys_linux.go");
+        assert!(merged.build_constraint.is_none());
+        let builds: Vec<_> = merged
+            .configurations
+            .iter()
+            .map(|c| c.build_constraint.as_str())
+            .collect();
+        assert_eq!(builds, vec!["linux", "windows"]);
+    }
+
+    #[test]
+    fn test_build_output_reports_zero_fallbacks() {
+        let source = "package kdf\n\nimport (\n\t\"crypto/sha256\"\n\t\"strconv\"\n\n\t\"golang.org/x/crypto/pbkdf2\"\n)\n\nfunc Derive(pw, salt []byte, iter string) []byte {\n\tn, _ := strconv.Atoi(iter)\n\treturn pbkdf2.Key(pw, salt, n, 32, sha256.New)\n}\n";
+        let mut parser = Parser::new();
+        parser
+            .set_language(&tree_sitter_go::LANGUAGE.into())
+            .unwrap();
+        let tree = parser.parse(source, None).unwrap();
+        let mappings = HashMap::from([(
+            "golang.org/x/crypto/pbkdf2".to_string(),
+            HashMap::from([("key".to_string(), "pbkdf2".to_string())]),
+        )]);
+        let result =
+            Scanner::with_mappings(mappings).scan_tree(&tree, source.as_bytes(), "kdf.go", "go");
+
+        let output = OutputFormatter::build_output(&[result], &RulesClassifier::new());
+
+        let fallbacks = &output.findings[0].zero_fallbacks;
+        assert_eq!(fallbacks.len(), 1);
+        assert_eq!(fallbacks[0].argument, "arg2");
+        assert_eq!(fallbacks[0].expression, "n");
+        assert_eq!(fallbacks[0].loader, "strconv.Atoi(iter)");
+        assert_eq!(fallbacks[0].line, 11);
+        assert_eq!(fallbacks[0].handling, ErrorHandling::Ignored);
+        assert_eq!(fallbacks[0].zero.as_deref(), Some("0"));
+
+        let policy: Policy = serde_json::from_str(
+            r#"{"rules": [{"id": "kdf-iterations", "parameter": {"name": "arg2", "min": 600000}}]}"#,
+        )
+        .unwrap();
+        assert_eq!(
+            policy.rules[0].check(&output.findings[0]).as_deref(),
+            Some("arg2 is 0 when strconv.Atoi(iter) fails (line 11): the error is discarded and the zero value is used")
+        );
+    }
+}
@@ -0,0 +1,32 @@
+use serde::Serialize;
+
+/// What a code generator setting controls.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum GeneratorSettingKind {
+    /// Whether TLS is used or verified, e.g. `sslmode`, `insecure`.
+    TlsMode,
+    /// Minimum or maximum TLS version.
+    TlsVersion,
+    CipherSuite,
+    /// Hash, signing or key derivation algorithm.
+    Algorithm,
+    KeySize,
+    /// Certificate, CA or private key file.
+    Certificate,
+}
+
+/// A crypto-relevant parameter that only exists in the inputs of a code generator: a
+/// `//go:generate` directive or a generator config such as `sqlc.yaml` or `buf.gen.yaml`.
+/// The generated Go code bakes the value in, so it is reported where it can be changed.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct GeneratorSetting {
+    /// The generator, e.g. `protoc`, `sqlc`, `buf`.
+    pub generator: String,
+    /// `file:line` of the directive or config entry, relative to the scan root.
+    pub source: String,
+    /// The flag, option or key as written, e.g. `sslmode`.
+    pub name: String,
+    pub value: String,
+    pub kind: GeneratorSettingKind,
+}
@@ -0,0 +1,136 @@
+use serde::Serialize;
+
+use crate::scanner::key_sizes;
+
+use super::Finding;
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum KeyMismatchKind {
+    /// A longer key is sliced down before use, e.g. 32 bytes to AES-128.
+    Truncated,
+    /// The key size is not one the consuming call accepts.
+    UnsupportedSize,
+}
+
+/// A key whose generated or available size differs from the size its consuming call uses.
+#[derive(Debug, Clone, Serialize)]
+pub struct KeyMismatch {
+    pub file: String,
+    pub line: usize,
+    pub column: usize,
+    pub function: String,
+    pub kind: KeyMismatchKind,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub available_bytes: Option<usize>,
+    pub used_bytes: usize,
+    /// Sizes the consuming call accepts; empty means any.
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub accepted_bytes: Vec<usize>,
+    pub expression: String,
+    pub message: String,
+}
+
+impl KeyMismatch {
+    /// Mismatches among `findings` whose key was traced to a known size.
+    pub fn detect(findings: &[Finding]) -> Vec<KeyMismatch> {
+        findings.iter().filter_map(Self::of).collect()
+    }
+
+    fn of(finding: &Finding) -> Option<KeyMismatch> {
+        let key = finding.key.as_ref()?;
+        let used = key.length?;
+        let callee = format!("{}.{}", finding.import_path.as_deref()?, finding.function);
+        let accepted = key_sizes(&callee).unwrap_or_default();
+
+        let (kind, message) = match key.available {
+            Some(available) if available > used => (
+                KeyMismatchKind::Truncated,
+                format!(
+                    "{available}-byte key truncated to {used} bytes ({}-bit) for {callee}",
+                    used * 8
+                ),
+            ),
+            _ if !accepted.is_empty() && !accepted.contains(&used) => (
+                KeyMismatchKind::UnsupportedSize,
+                format!(
+                    "{used}-byte key, {callee} accepts {} bytes",
+                    accepted
+                        .iter()
+                        .map(usize::to_string)
+                        .collect::<Vec<_>>()
+                        .join(", ")
+                ),
+            ),
+            _ => return None,
+        };
+
+        Some(KeyMismatch {
+            file: finding.file.clone(),
+            line: finding.line,
+            column: finding.column,
+            function: finding.full_name.clone(),
+            kind,
+            available_bytes: key.available,
+            used_bytes: used,
+            accepted_bytes: accepted.to_vec(),
+            expression: key.expression.clone(),
+            message,
+        })
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::scanner::{ByteOrigin, ByteSource};
+
+    fn cipher(
+        import_path: &str,
+        function: &str,
+        length: usize,
+        available: Option<usize>,
+    ) -> Finding {
+        Finding {
+            key: Some(ByteSource {
+                origin: ByteOrigin::Random,
+                length: Some(length),
+                available,
+                length_from: None,
+                minimum: None,
+                expression: "key".to_string(),
+            }),
+            ..Finding::call("seal.go", 7, 2, &format!("{import_path}.{function}"))
+                .with_import_path(import_path)
+        }
+    }
+
+    #[test]
+    fn test_detect_truncated_and_unsupported_keys() {
+        let findings = [
+            cipher("crypto/aes", "NewCipher", 16, Some(32)),
+            cipher("golang.org/x/crypto/chacha20poly1305", "New", 16, None),
+            cipher("crypto/aes", "NewCipher", 32, None),
+            cipher("crypto/hmac", "New", 20, None),
+        ];
+
+        let mismatches = KeyMismatch::detect(&findings);
+        let summary: Vec<_> = mismatches
+            .iter()
+            .map(|m| (m.kind, m.message.as_str()))
+            .collect();
+        assert_eq!(
+            summary,
+            vec![
+                (
+                    KeyMismatchKind::Truncated,
+                    "32-byte key truncated to 16 bytes (128-bit) for crypto/aes.NewCipher"
+                ),
+                (
+                    KeyMismatchKind::UnsupportedSize,
+                    "16-byte key, golang.org/x/crypto/chacha20poly1305.New accepts 32 bytes"
+                ),
+            ]
+        );
+    }
+}
@@ -0,0 +1,147 @@
+//! Reproducibility manifest embedded in scan reports.
+
+use std::collections::BTreeMap;
+use std::path::{Path, PathBuf};
+
+use serde::Serialize;
+
+use crate::attestation::{config_hash, ToolInfo};
+use crate::discovery::languages::go::gomod::Require;
+use crate::error::AttestationError;
+use crate::presets::load_preset_metadata;
+
+#[derive(Debug, Clone, Serialize)]
+pub struct ScanManifest {
+    pub tool: ToolInfo,
+    /// Command-line arguments of the run, without the program name.
+    pub arguments: Vec<String>,
+    /// Effective scan settings, including defaults the command line left implicit.
+    pub settings: BTreeMap<String, String>,
+    /// Same hash as the attestation's `config_hash`, over `settings` and the catalogs.
+    pub config_hash: String,
+    /// Commit checked out in the scanned repository.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub commit: Option<String>,
+    /// `go env GOVERSION` of the toolchain discovery ran with.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub go_toolchain: Option<String>,
+    /// Sink catalogs: presets and custom rule files.
+    pub catalogs: Vec<CatalogVersion>,
+    /// Module requirements of the scanned `go.mod`, by module path.
+    #[serde(skip_serializing_if = "BTreeMap::is_empty")]
+    pub modules: BTreeMap<String, String>,
+}
+
+/// A preset directory or rule file and the digest of its contents.
+#[derive(Debug, Clone, Serialize)]
+pub struct CatalogVersion {
+    pub name: String,
+    /// Version declared by a preset's `preset.json`.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub version: Option<String>,
+    /// Hex SHA-256 over the catalog's files, as hashed into `config_hash`.
+    pub sha256: String,
+}
+
+impl CatalogVersion {
+    /// Reads the preset directory or rule file at `path`.
+    pub fn read(path: &Path) -> Result<Self, AttestationError> {
+        let name = path
+            .file_name()
+            .map(|name| name.to_string_lossy().into_owned())
+            .unwrap_or_else(|| path.display().to_string());
+        let version = path
+            .is_dir()
+            .then(|| load_preset_metadata(path).ok())
+            .flatten()
+            .map(|metadata| metadata.version);
+        Ok(CatalogVersion {
+            name,
+            version,
+            sha256: config_hash(&BTreeMap::new(), &[path.to_path_buf()])?,
+        })
+    }
+}
+
+impl ScanManifest {
+    /// A manifest for a run with `arguments` and `settings`, applying the catalogs at
+    /// `catalogs` to a project requiring `requires`.
+    pub fn new(
+        arguments: Vec<String>,
+        settings: &BTreeMap<&str, String>,
+        catalogs: &[PathBuf],
+        requires: &[Require],
+    ) -> Result<Self, AttestationError> {
+        Ok(ScanManifest {
+            tool: ToolInfo::current(),
+            arguments,
+            settings: settings
+                .iter()
+                .map(|(key, value)| (key.to_string(), value.clone()))
+                .collect(),
+            config_hash: config_hash(settings, catalogs)?,
+            commit: None,
+            go_toolchain: None,
+            catalogs: catalogs
+                .iter()
+                .map(|path| CatalogVersion::read(path))
+                .collect::<Result<_, _>>()?,
+            modules: requires
+                .iter()
+                .map(|require| (require.path.clone(), require.version.clone()))
+                .collect(),
+        })
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    #[test]
+    fn test_manifest_records_catalog_versions() {
+        let dir = TempDir::new().unwrap();
+        let preset = dir.path().join("crypto");
+        std::fs::create_dir(&preset).unwrap();
+        std::fs::write(preset.join("classifications.json"), "{}").unwrap();
+        std::fs::write(
+            preset.join("preset.json"),
+            r#"{"name": "crypto", "version": "2.1.0", "description": "", "languages": ["go"]}"#,
+        )
+        .unwrap();
+        let rules = dir.path().join("rules.json");
+        std::fs::write(&rules, "{}").unwrap();
+
+        let settings = BTreeMap::from([("language", "go".to_string())]);
+        let catalogs = [preset.clone(), rules.clone()];
+        let requires = [Require {
+            path: "golang.org/x/crypto".to_string(),
+            version: "v0.21.0".to_string(),
+        }];
+        let manifest = ScanManifest::new(
+            vec!["--preset".to_string(), "crypto".to_string()],
+            &settings,
+            &catalogs,
+            &requires,
+        )
+        .unwrap();
+
+        assert_eq!(manifest.settings["language"], "go");
+        assert_eq!(
+            manifest.config_hash,
+            config_hash(&settings, &catalogs).unwrap()
+        );
+        assert_eq!(manifest.catalogs[0].name, "crypto");
+        assert_eq!(manifest.catalogs[0].version.as_deref(), Some("2.1.0"));
+        assert_eq!(manifest.catalogs[1].name, "rules.json");
+        assert_eq!(manifest.catalogs[1].version, None);
+        assert_eq!(manifest.modules["golang.org/x/crypto"], "v0.21.0");
+
+        // A changed rule file changes its digest and the config hash
+        std::fs::write(&rules, r#"{"rules": []}"#).unwrap();
+        let changed = ScanManifest::new(Vec::new(), &settings, &catalogs, &requires).unwrap();
+        assert_ne!(changed.catalogs[1].sha256, manifest.catalogs[1].sha256);
+        assert_ne!(changed.config_hash, manifest.config_hash);
+    }
+}
@@ -1,5 +1,38 @@
+mod agility;
+mod constants;
+mod duplicates;
 mod finding;
+mod fips;
 mod formatter;
+mod generators;
+mod keys;
+mod manifest;
+mod modules;
+mod nonces;
+mod operations;
+mod redaction;
+mod report_file;
+mod selection;
+mod status;
+mod wrappers;
 
-pub use finding::{ConfigFieldValue, ConfigFinding, Finding};
+pub use agility::{FindingAgility, PackageAgility};
+pub use constants::{ConstantSink, ConstantUsage};
+pub use duplicates::{DuplicateOperation, Implementation};
+pub use finding::{
+    merge_build_variants, AdvisoryMatch, AdvisoryRef, BuildVariant, ConfigFieldValue,
+    ConfigFinding, Finding, ParameterStatus, Vulnerability,
+};
+pub use fips::{FipsCodePath, FipsMode, FipsPosture, FipsSignal, FipsSignalKind, FipsStatus};
 pub use formatter::{JsonOutput, OutputFormatter};
+pub use generators::{GeneratorSetting, GeneratorSettingKind};
+pub use keys::{KeyMismatch, KeyMismatchKind};
+pub use manifest::{CatalogVersion, ScanManifest};
+pub use modules::ModuleScan;
+pub use nonces::NonceOverflow;
+pub use operations::{CryptoOperation, OperationStep};
+pub use redaction::{RedactedSecret, Redactions};
+pub use report_file::{read_report_file, write_report_file};
+pub use selection::{collect_selection_options, AlgorithmSelection, SelectionOption};
+pub use status::{summarize_packages, AnalysisStatus, FileFailure, PackageStatus};
+pub use wrappers::{attribute_wrappers, link_wrappers, WrapperLink, WrapperRole, WrapperSite};
@@ -0,0 +1,101 @@
+use std::path::{Path, PathBuf};
+
+use serde::Serialize;
+
+use super::Finding;
+
+/// One Go module of a `--recurse-modules` scan and the number of findings in it.
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct ModuleScan {
+    /// Module root relative to the scan root, `.` for the root itself.
+    pub dir: String,
+    /// Module path declared by the module's `go.mod`.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub module: Option<String>,
+    pub findings: usize,
+}
+
+impl ModuleScan {
+    /// Counts `findings` per module in `modules`, given as root directory and module path.
+    /// A finding belongs to the deepest root containing its file.
+    pub fn summarize(
+        root: &Path,
+        modules: &[(PathBuf, Option<String>)],
+        findings: &[Finding],
+    ) -> Vec<ModuleScan> {
+        let mut counts = vec![0; modules.len()];
+        for finding in findings {
+            let file = Path::new(&finding.file);
+            let owner = modules
+                .iter()
+                .enumerate()
+                .filter(|(_, (dir, _))| file.starts_with(dir))
+                .max_by_key(|(_, (dir, _))| dir.components().count());
+            if let Some((i, _)) = owner {
+                counts[i] += 1;
+            }
+        }
+        modules
+            .iter()
+            .zip(counts)
+            .map(|((dir, module), findings)| ModuleScan {
+                dir: match dir.strip_prefix(root) {
+                    Ok(relative) if relative.as_os_str().is_empty() => ".".to_string(),
+                    Ok(relative) => relative.to_string_lossy().replace('\\', "/"),
+                    Err(_) => dir.to_string_lossy().into_owned(),
+                },
+                module: module.clone(),
+                findings,
+            })
+            .collect()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn finding(file: &str) -> Finding {
+        Finding::call(file, 1, 1, "golang.org/x/crypto/pbkdf2.Key")
+    }
+
+    #[test]
+    fn test_findings_count_toward_deepest_module() {
+        let root = Path::new("repo");
+        let modules = vec![
+            (root.to_path_buf(), Some("example.com/app".to_string())),
+            (
+                root.join("tests/fixtures/kdf"),
+                Some("example.com/fixtures/kdf".to_string()),
+            ),
+            (root.join("tests/fixtures/tls"), None),
+        ];
+        let findings = [
+            finding("repo/internal/kdf/kdf.go"),
+            finding("repo/tests/fixtures/kdf/main.go"),
+            finding("repo/tests/fixtures/kdf/pkg/derive.go"),
+        ];
+
+        let summary = ModuleScan::summarize(root, &modules, &findings);
+        assert_eq!(
+            summary,
+            vec![
+                ModuleScan {
+                    dir: ".".to_string(),
+                    module: Some("example.com/app".to_string()),
+                    findings: 1,
+                },
+                ModuleScan {
+                    dir: "tests/fixtures/kdf".to_string(),
+                    module: Some("example.com/fixtures/kdf".to_string()),
+                    findings: 2,
+                },
+                ModuleScan {
+                    dir: "tests/fixtures/tls".to_string(),
+                    module: None,
+                    findings: 0,
+                },
+            ]
+        );
+    }
+}
@@ -0,0 +1,76 @@
+use serde::Serialize;
+
+use super::Finding;
+
+/// An AEAD nonce encoded from a counter that nothing bounds or resets before it wraps.
+#[derive(Debug, Clone, Serialize)]
+pub struct NonceOverflow {
+    pub file: String,
+    pub line: usize,
+    pub column: usize,
+    pub function: String,
+    pub counter: String,
+    pub counter_bits: u32,
+    /// Line of the counter's declaration in `file`.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub declaration_line: Option<usize>,
+    pub message: String,
+}
+
+impl NonceOverflow {
+    /// Counter nonces among `findings` with no bound check or key rotation.
+    pub fn detect(findings: &[Finding]) -> Vec<NonceOverflow> {
+        findings.iter().filter_map(Self::of).collect()
+    }
+
+    fn of(finding: &Finding) -> Option<NonceOverflow> {
+        let nonce = finding.nonce_counter.as_ref().filter(|n| !n.bounded)?;
+        let declared = nonce
+            .declaration_line
+            .map(|line| format!(" (declared at line {line})"))
+            .unwrap_or_default();
+        Some(NonceOverflow {
+            file: finding.file.clone(),
+            line: finding.line,
+            column: finding.column,
+            function: finding.full_name.clone(),
+            counter: nonce.counter.clone(),
+            counter_bits: nonce.bits,
+            declaration_line: nonce.declaration_line,
+            message: format!(
+                "nonce counter {}{declared} repeats after 2^{} messages; check it or rotate the key before it wraps",
+                nonce.counter, nonce.bits
+            ),
+        })
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::scanner::NonceCounter;
+
+    fn seal(bounded: bool) -> Finding {
+        Finding {
+            nonce_counter: Some(NonceCounter {
+                counter: "s.seq".to_string(),
+                bits: 32,
+                declaration_line: Some(11),
+                declaration: Some("seq uint32".to_string()),
+                bounded,
+            }),
+            ..Finding::call("channel.go", 18, 12, "crypto/cipher.AEAD.Seal")
+                .with_import_path("crypto/cipher.AEAD")
+        }
+    }
+
+    #[test]
+    fn test_detect_unbounded_counters() {
+        let overflows = NonceOverflow::detect(&[seal(false), seal(true)]);
+        assert_eq!(overflows.len(), 1);
+        assert_eq!(
+            overflows[0].message,
+            "nonce counter s.seq (declared at line 11) repeats after 2^32 messages; check it or rotate the key before it wraps"
+        );
+    }
+}
@@ -0,0 +1,175 @@
+use serde::Serialize;
+use std::collections::BTreeMap;
+use std::path::Path;
+
+use super::Finding;
+
+/// What an operation is for, by the first matching step classification: an operation
+/// that derives a key and encrypts with it is an encryption.
+const PURPOSES: &[(&[&str], &str)] = &[
+    (&["encrypt", "decrypt", "aead", "cipher"], "encryption"),
+    (&["sign", "signature"], "signing"),
+    (&["verify"], "signature verification"),
+    (&["agree", "key-agree", "kem"], "key agreement"),
+    (&["mac", "hmac"], "message authentication"),
+    (&["keyderive", "derive", "kdf"], "key derivation"),
+    (&["keygen", "generate"], "key generation"),
+    (&["digest", "hash"], "hashing"),
+    (&["compare"], "secret comparison"),
+];
+
+/// One finding of an operation.
+#[derive(Debug, Clone, Serialize)]
+pub struct OperationStep {
+    pub line: usize,
+    pub column: usize,
+    pub function: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub algorithm: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub operation: Option<String>,
+}
+
+/// Crypto calls made together by one function, e.g. a key derivation, a cipher
+/// construction and a nonce read that make up token encryption, reported as one unit.
+#[derive(Debug, Clone, Serialize)]
+pub struct CryptoOperation {
+    /// `<purpose> in <package>`, e.g. `encryption in pkg/auth`.
+    pub label: String,
+    pub purpose: String,
+    /// Directory of the package's files.
+    pub package: String,
+    pub file: String,
+    /// The function making the calls.
+    pub function: String,
+    /// Distinct algorithms of the steps, in step order.
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub algorithms: Vec<String>,
+    pub steps: Vec<OperationStep>,
+}
+
+impl CryptoOperation {
+    /// Groups findings sharing a file and enclosing function; functions with a single
+    /// crypto call are not operations. In report order of the first step.
+    pub fn group(findings: &[Finding]) -> Vec<CryptoOperation> {
+        let mut functions: BTreeMap<(&str, &str), Vec<&Finding>> = BTreeMap::new();
+        for finding in findings {
+            if let Some(function) = &finding.enclosing_function {
+                functions
+                    .entry((finding.file.as_str(), function.as_str()))
+                    .or_default()
+                    .push(finding);
+            }
+        }
+
+        let mut operations: Vec<(&Finding, CryptoOperation)> = functions
+            .into_iter()
+            .filter(|(_, steps)| steps.len() > 1)
+            .map(|((file, function), mut steps)| {
+                steps.sort_by(|a, b| a.report_order(b));
+                (steps[0], Self::from_steps(file, function, &steps))
+            })
+            .collect();
+        operations.sort_by(|(a, _), (b, _)| a.report_order(b));
+        operations.into_iter().map(|(_, op)| op).collect()
+    }
+
+    fn from_steps(file: &str, function: &str, steps: &[&Finding]) -> CryptoOperation {
+        let package = Path::new(file)
+            .parent()
+            .map(|dir| dir.to_string_lossy().into_owned())
+            .unwrap_or_default();
+        let purpose = purpose(steps).to_string();
+        let mut algorithms: Vec<String> = Vec::new();
+        for algorithm in steps.iter().filter_map(|f| f.algorithm.as_ref()) {
+            if !algorithms.contains(algorithm) {
+                algorithms.push(algorithm.clone());
+            }
+        }
+
+        CryptoOperation {
+            label: if package.is_empty() {
+                purpose.clone()
+            } else {
+                format!("{purpose} in {package}")
+            },
+            purpose,
+            package,
+            file: file.to_string(),
+            function: function.to_string(),
+            algorithms,
+            steps: steps
+                .iter()
+                .map(|f| OperationStep {
+                    line: f.line,
+                    column: f.column,
+                    function: f.full_name.clone(),
+                    algorithm: f.algorithm.clone(),
+                    operation: f.operation.clone(),
+                })
+                .collect(),
+        }
+    }
+}
+
+/// The first purpose any step's operation or primitive names; `crypto` when none do.
+fn purpose(steps: &[&Finding]) -> &'static str {
+    let classes: Vec<String> = steps
+        .iter()
+        .flat_map(|f| [&f.operation, &f.primitive])
+        .flatten()
+        .map(|class| class.to_ascii_lowercase())
+        .collect();
+    PURPOSES
+        .iter()
+        .find(|(words, _)| {
+            classes
+                .iter()
+                .any(|class| words.iter().any(|word| class == word))
+        })
+        .map_or("crypto", |(_, purpose)| purpose)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn call(file: &str, line: usize, function: &str, operation: &str) -> Finding {
+        Finding::call(file, line, 2, function)
+            .with_algorithm(&function.split('.').next().unwrap().to_uppercase())
+            .with_operation(operation)
+            .in_function("sealToken")
+    }
+
+    #[test]
+    fn test_groups_calls_of_one_function() {
+        let mut lone = call("pkg/auth/hash.go", 4, "sha256.Sum256", "digest");
+        lone.enclosing_function = Some("checksum".to_string());
+        let findings = vec![
+            call("pkg/auth/token.go", 12, "aes.NewCipher", "encrypt"),
+            call("pkg/auth/token.go", 9, "hkdf.Key", "keyderive"),
+            call("pkg/auth/token.go", 14, "cipher.NewGCM", "encrypt"),
+            lone,
+        ];
+
+        let operations = CryptoOperation::group(&findings);
+        assert_eq!(operations.len(), 1);
+        let operation = &operations[0];
+        assert_eq!(operation.label, "encryption in pkg/auth");
+        assert_eq!(operation.function, "sealToken");
+        assert_eq!(operation.algorithms, vec!["HKDF", "AES", "CIPHER"]);
+        let lines: Vec<_> = operation.steps.iter().map(|s| s.line).collect();
+        assert_eq!(lines, vec![9, 12, 14]);
+    }
+
+    #[test]
+    fn test_purpose_falls_back_to_crypto() {
+        let findings = [
+            call("main.go", 1, "rand.Read", "random"),
+            call("main.go", 2, "base64.Encode", "encode"),
+        ];
+        let operations = CryptoOperation::group(&findings);
+        assert_eq!(operations[0].label, "crypto");
+        assert_eq!(operations[0].package, "");
+    }
+}
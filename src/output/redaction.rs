@@ -0,0 +1,382 @@
+//! Redaction of hardcoded secrets in written reports, replacing each value with its
+//! length, entropy and a fingerprint.
+
+use std::collections::BTreeMap;
+
+use serde::Serialize;
+use sha2::{Digest, Sha256};
+
+use crate::engine::ResolutionStatus;
+use crate::scanner::{secret_argument, ByteOrigin, ByteSource};
+use crate::utils::unquote_string;
+
+use super::{Finding, JsonOutput, ParameterStatus};
+
+/// Argument roles holding secret material, compared case-insensitively.
+const SECRET_ROLES: &[&str] = &["password", "passphrase", "secret", "ikm", "seed", "key"];
+
+/// Hex digits of the SHA-256 digest kept as a fingerprint.
+const FINGERPRINT_DIGITS: usize = 12;
+
+/// What is reported in place of a secret value.
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct RedactedSecret {
+    pub redacted: bool,
+    /// Length in bytes.
+    pub length: usize,
+    /// Shannon entropy in bits per byte.
+    pub entropy: f64,
+    /// `sha256:` and the first digits of the value's SHA-256 digest.
+    pub fingerprint: String,
+}
+
+impl RedactedSecret {
+    pub fn of(secret: &str) -> Self {
+        let digest = Sha256::digest(secret.as_bytes());
+        let hex: String = digest.iter().map(|b| format!("{b:02x}")).collect();
+        RedactedSecret {
+            redacted: true,
+            length: secret.len(),
+            entropy: (shannon_entropy(secret.as_bytes()) * 100.0).round() / 100.0,
+            fingerprint: format!("sha256:{}", &hex[..FINGERPRINT_DIGITS]),
+        }
+    }
+
+    /// The secret's stand-in in call text and expressions.
+    fn marker(&self) -> String {
+        format!("[redacted {}]", self.fingerprint)
+    }
+}
+
+/// The secrets redacted from a scan, for redacting what else is derived from it.
+#[derive(Debug, Clone, Default)]
+pub struct Redactions {
+    secrets: Vec<(String, RedactedSecret)>,
+}
+
+impl Redactions {
+    /// How many values were redacted.
+    pub fn len(&self) -> usize {
+        self.secrets.len()
+    }
+
+    pub fn is_empty(&self) -> bool {
+        self.secrets.is_empty()
+    }
+
+    pub fn extend(&mut self, other: Redactions) {
+        self.secrets.extend(other.secrets);
+    }
+
+    /// Replaces every secret in `text` with its marker, longest secrets first so a quoted
+    /// literal becomes one marker rather than a marker in quotes.
+    pub fn apply(&self, text: &str) -> String {
+        let mut secrets: Vec<_> = self
+            .secrets
+            .iter()
+            .filter(|(secret, _)| !secret.is_empty())
+            .collect();
+        secrets.sort_by_key(|(secret, _)| std::cmp::Reverse(secret.len()));
+        secrets
+            .into_iter()
+            .fold(text.to_string(), |text, (secret, redacted)| {
+                text.replace(secret.as_str(), &redacted.marker())
+            })
+    }
+
+    /// Redacts an argument value: a string that is a secret is replaced like a redacted
+    /// parameter, and secrets inside other strings by their marker.
+    pub fn redact_value(&self, value: &mut serde_json::Value) {
+        match value {
+            serde_json::Value::String(text) => {
+                match self.secrets.iter().find(|(secret, _)| secret == text) {
+                    Some((_, redacted)) => {
+                        *value = serde_json::to_value(redacted).unwrap_or_default()
+                    }
+                    None => *text = self.apply(text),
+                }
+            }
+            serde_json::Value::Array(values) => {
+                for value in values {
+                    self.redact_value(value);
+                }
+            }
+            _ => {}
+        }
+    }
+}
+
+impl JsonOutput {
+    /// Redacts hardcoded secrets in every finding, and the same secrets wherever the
+    /// report repeats them: key mismatches and duplicate operations.
+    pub fn redact_secrets(&mut self) -> Redactions {
+        let mut redactions = Redactions::default();
+        for finding in &mut self.findings {
+            redactions.extend(finding.redact_secrets());
+        }
+        if redactions.is_empty() {
+            return redactions;
+        }
+
+        for mismatch in &mut self.key_mismatches {
+            mismatch.expression = redactions.apply(&mismatch.expression);
+            mismatch.message = redactions.apply(&mismatch.message);
+        }
+        let implementations = self
+            .duplicate_operations
+            .iter_mut()
+            .flat_map(|duplicate| &mut duplicate.implementations);
+        for implementation in implementations {
+            implementation
+                .parameters
+                .values_mut()
+                .for_each(|value| redactions.redact_value(value));
+        }
+        redactions
+    }
+}
+
+impl Finding {
+    /// Replaces the literal values of secret arguments, in the parameters, the build
+    /// variants, the call text and the traced byte sources.
+    pub fn redact_secrets(&mut self) -> Redactions {
+        let positional = secret_argument(&self.full_name).map(|i| format!("arg{i}"));
+        let is_secret = |name: &String| positional.as_ref() == Some(name) || is_secret_role(name);
+        let mut secrets = Vec::new();
+
+        for (name, value) in &mut self.parameters {
+            if is_secret(name) && is_literal(self.parameter_status.get(name)) {
+                redact_value(value, &mut secrets);
+            }
+        }
+        for variant in &mut self.configurations {
+            for (name, value) in &mut variant.parameters {
+                if is_secret(name) && is_literal(variant.parameter_status.get(name)) {
+                    redact_value(value, &mut secrets);
+                }
+            }
+        }
+        for (name, value) in &mut self.receiver_parameters {
+            if is_secret_role(name) {
+                redact_value(value, &mut secrets);
+            }
+        }
+        for source in [&mut self.secret, &mut self.key].into_iter().flatten() {
+            redact_source(source, &mut secrets);
+        }
+
+        let redactions = Redactions { secrets };
+        self.raw_text = redactions.apply(&self.raw_text);
+        redactions
+    }
+}
+
+/// `password`, `Key`, `hmacKey`, `privateKey`; not `keyLen`.
+fn is_secret_role(name: &str) -> bool {
+    let name = name.to_ascii_lowercase();
+    SECRET_ROLES.iter().any(|role| name == *role)
+        || name.ends_with("key")
+        || name.contains("password")
+        || name.contains("secret")
+}
+
+/// Whether the argument resolved to values written in the source.
+fn is_literal(status: Option<&ParameterStatus>) -> bool {
+    status.is_some_and(|s| {
+        matches!(
+            s.status,
+            ResolutionStatus::Resolved | ResolutionStatus::Range
+        )
+    })
+}
+
+fn redact_value(value: &mut serde_json::Value, secrets: &mut Vec<(String, RedactedSecret)>) {
+    match value {
+        serde_json::Value::String(secret) => {
+            let redacted = RedactedSecret::of(secret);
+            secrets.push((std::mem::take(secret), redacted.clone()));
+            *value = serde_json::to_value(redacted).unwrap_or_default();
+        }
+        serde_json::Value::Array(values) => {
+            for value in values {
+                redact_value(value, secrets);
+            }
+        }
+        _ => {}
+    }
+}
+
+/// A byte source that ended at a literal shows the literal as its expression.
+fn redact_source(source: &mut ByteSource, secrets: &mut Vec<(String, RedactedSecret)>) {
+    if source.origin != ByteOrigin::Literal || source.expression.is_empty() {
+        return;
+    }
+    // A string literal fingerprints as its contents, like the resolved parameter
+    let expression = std::mem::take(&mut source.expression);
+    let redacted = RedactedSecret::of(&unquote_string(&expression));
+    source.expression = redacted.marker();
+    secrets.push((expression, redacted));
+}
+
+fn shannon_entropy(bytes: &[u8]) -> f64 {
+    let mut counts: BTreeMap<u8, usize> = BTreeMap::new();
+    for byte in bytes {
+        *counts.entry(*byte).or_default() += 1;
+    }
+    let total = bytes.len() as f64;
+    counts
+        .values()
+        .map(|&count| {
+            let p = count as f64 / total;
+            -p * p.log2()
+        })
+        .sum()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::engine::Value;
+    use crate::output::{BuildVariant, KeyMismatch};
+    use serde_json::json;
+
+    fn pbkdf2_with_password(password: &str) -> Finding {
+        let resolved = ParameterStatus::of(&Value::resolved_string(password.to_string()));
+        Finding {
+            parameter_status: BTreeMap::from([("arg0".to_string(), resolved)]),
+            raw_text: format!("pbkdf2.Key([]byte(\"{password}\"), salt, 4096, 32, sha256.New)"),
+            secret: Some(ByteSource {
+                origin: ByteOrigin::Literal,
+                length: Some(password.len()),
+                available: None,
+                length_from: None,
+                minimum: None,
+                expression: format!("\"{password}\""),
+            }),
+            ..Finding::call("auth/login.go", 12, 9, "golang.org/x/crypto/pbkdf2.Key")
+                .with_package("pbkdf2")
+                .with_import_path("golang.org/x/crypto/pbkdf2")
+                .with_algorithm("PBKDF2")
+                .with_parameter("arg0", json!(password))
+                .with_parameter("arg2", json!(4096))
+        }
+    }
+
+    #[test]
+    fn test_redacts_hardcoded_password() {
+        let mut finding = pbkdf2_with_password("hunter2hunter2");
+        assert_eq!(finding.redact_secrets().len(), 2);
+
+        let redacted = &finding.parameters["arg0"];
+        assert_eq!(redacted["redacted"], json!(true));
+        assert_eq!(redacted["length"], json!(14));
+        let fingerprint = redacted["fingerprint"].as_str().unwrap();
+        assert!(fingerprint.starts_with("sha256:"));
+        assert_eq!(finding.parameters["arg2"], json!(4096));
+
+        let report = serde_json::to_string(&finding).unwrap();
+        assert!(!report.contains("hunter2"), "{report}");
+        assert!(finding
+            .raw_text
+            .contains(&format!("[redacted {fingerprint}]")));
+
+        // The traced literal keeps its length and fingerprints like the parameter
+        let secret = finding.secret.as_ref().unwrap();
+        assert_eq!(secret.length, Some(14));
+        assert_eq!(secret.expression, format!("[redacted {fingerprint}]"));
+    }
+
+    fn aes_with_key(key: &str) -> Finding {
+        let resolved = ParameterStatus::of(&Value::resolved_string(key.to_string()));
+        Finding {
+            parameter_status: BTreeMap::from([("arg0".to_string(), resolved.clone())]),
+            raw_text: format!("aes.NewCipher([]byte(\"{key}\"))"),
+            key: Some(ByteSource {
+                origin: ByteOrigin::Literal,
+                length: Some(key.len()),
+                available: None,
+                length_from: None,
+                minimum: None,
+                expression: format!("[]byte(\"{key}\")"),
+            }),
+            configurations: vec![BuildVariant {
+                build_constraint: "linux".to_string(),
+                file: "store/seal_linux.go".to_string(),
+                line: 30,
+                column: 14,
+                parameters: BTreeMap::from([("arg0".to_string(), json!(key))]),
+                parameter_status: BTreeMap::from([("arg0".to_string(), resolved)]),
+            }],
+            ..Finding::call("store/seal.go", 30, 14, "crypto/aes.NewCipher")
+                .with_package("aes")
+                .with_import_path("crypto/aes")
+                .with_parameter("arg0", json!(key))
+        }
+    }
+
+    fn report(findings: Vec<Finding>) -> JsonOutput {
+        JsonOutput {
+            go_version: None,
+            analysis_status: None,
+            files_scanned: 1,
+            total_findings: findings.len(),
+            total_configs: 0,
+            key_mismatches: KeyMismatch::detect(&findings),
+            findings,
+            configs: Vec::new(),
+            packages: Vec::new(),
+            vulnerabilities: Vec::new(),
+            fips: None,
+            nonce_overflows: Vec::new(),
+            agility: Vec::new(),
+            constant_usage: Vec::new(),
+            operations: Vec::new(),
+            duplicate_operations: Vec::new(),
+            generator_settings: Vec::new(),
+            modules: Vec::new(),
+            manifest: None,
+            repository: None,
+        }
+    }
+
+    #[test]
+    fn test_redacts_every_section() {
+        let mut report = report(vec![aes_with_key("sixteen-byte-key-x")]);
+        assert_eq!(report.key_mismatches.len(), 1);
+
+        let redactions = report.redact_secrets();
+        assert!(!redactions.is_empty());
+        let written = serde_json::to_string(&report).unwrap();
+        assert!(!written.contains("sixteen-byte-key-x"), "{written}");
+        assert_eq!(
+            report.findings[0].configurations[0].parameters["arg0"]["redacted"],
+            json!(true)
+        );
+        assert!(report.key_mismatches[0]
+            .expression
+            .starts_with("[redacted sha256:"));
+
+        // Messages rendered from the raw finding, as gate violations are
+        let message = "secret (\"sixteen-byte-key-x\") and salt (\"pepper\") are constants";
+        let redacted = redactions.apply(message);
+        assert!(!redacted.contains("sixteen-byte-key-x"), "{redacted}");
+        assert!(redacted.contains("[redacted sha256:"), "{redacted}");
+        assert!(redacted.contains("\"pepper\""));
+
+        let mut value = json!(["sixteen-byte-key-x", "other"]);
+        redactions.redact_value(&mut value);
+        assert_eq!(value[0]["redacted"], json!(true));
+        assert_eq!(value[1], json!("other"));
+    }
+
+    #[test]
+    fn test_secret_roles_and_entropy() {
+        assert!(is_secret_role("password"));
+        assert!(is_secret_role("hmacKey"));
+        assert!(!is_secret_role("keyLen"));
+        assert!(!is_secret_role("iterations"));
+
+        assert_eq!(RedactedSecret::of("aaaa").entropy, 0.0);
+        assert_eq!(RedactedSecret::of("abcd").entropy, 2.0);
+    }
+}
@@ -0,0 +1,127 @@
+//! Report files streamed to disk as they are serialized, gzip-compressed for `.gz` paths.
+
+use anyhow::{Context, Result};
+use flate2::read::GzDecoder;
+use flate2::write::GzEncoder;
+use flate2::Compression;
+use sha2::{Digest, Sha256};
+use std::fs::File;
+use std::io::{self, BufReader, BufWriter, Write};
+use std::path::Path;
+
+use super::{JsonOutput, OutputFormatter};
+use crate::cli::OutputFormat;
+
+const GZIP_EXTENSION: &str = "gz";
+
+/// Writes `output` to `path`, gzip-compressed when the path ends in `.gz`, and returns the
+/// hex SHA-256 of the file's contents.
+pub fn write_report_file(output: &JsonOutput, format: OutputFormat, path: &Path) -> Result<String> {
+    let file = File::create(path)
+        .with_context(|| format!("Failed to create output file: {}", path.display()))?;
+    let mut file = HashingWriter::new(BufWriter::new(file));
+    let written = if is_gzip(path) {
+        let mut encoder = BufWriter::new(GzEncoder::new(&mut file, Compression::default()));
+        OutputFormatter::write(output, format, &mut encoder).and_then(|()| {
+            let encoder = encoder
+                .into_inner()
+                .map_err(io::IntoInnerError::into_error)?;
+            encoder.finish()?;
+            Ok(())
+        })
+    } else {
+        let mut buffered = BufWriter::new(&mut file);
+        OutputFormatter::write(output, format, &mut buffered).and_then(|()| Ok(buffered.flush()?))
+    };
+    written
+        .and_then(|()| Ok(file.flush()?))
+        .with_context(|| format!("Failed to write to output file: {}", path.display()))?;
+    Ok(file.hex_digest())
+}
+
+/// Reads a JSON report written to `path`, decompressing it when the path ends in `.gz`.
+pub fn read_report_file(path: &Path) -> Result<serde_json::Value> {
+    let file =
+        File::open(path).with_context(|| format!("Failed to open report: {}", path.display()))?;
+    let reader = BufReader::new(file);
+    let report = if is_gzip(path) {
+        serde_json::from_reader(GzDecoder::new(reader))
+    } else {
+        serde_json::from_reader(reader)
+    };
+    report.with_context(|| format!("Failed to parse report: {}", path.display()))
+}
+
+fn is_gzip(path: &Path) -> bool {
+    path.extension()
+        .is_some_and(|extension| extension.eq_ignore_ascii_case(GZIP_EXTENSION))
+}
+
+/// Passes bytes through to `inner`, hashing them on the way.
+struct HashingWriter<W> {
+    inner: W,
+    hasher: Sha256,
+}
+
+impl<W: Write> HashingWriter<W> {
+    fn new(inner: W) -> Self {
+        Self {
+            inner,
+            hasher: Sha256::new(),
+        }
+    }
+
+    fn hex_digest(self) -> String {
+        format!("{:x}", self.hasher.finalize())
+    }
+}
+
+impl<W: Write> Write for HashingWriter<W> {
+    fn write(&mut self, buf: &[u8]) -> io::Result<usize> {
+        let written = self.inner.write(buf)?;
+        self.hasher.update(&buf[..written]);
+        Ok(written)
+    }
+
+    fn flush(&mut self) -> io::Result<()> {
+        self.inner.flush()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::attestation::sha256_hex;
+    use crate::classifier::RulesClassifier;
+    use std::io::Read;
+
+    #[test]
+    fn test_plain_and_gzip_reports() {
+        let dir = tempfile::tempdir().unwrap();
+        let output = OutputFormatter::build_output(&[], &RulesClassifier::new());
+        let expected = serde_json::to_value(&output).unwrap();
+
+        let plain = dir.path().join("report.json");
+        let digest = write_report_file(&output, OutputFormat::Json, &plain).unwrap();
+        let bytes = std::fs::read(&plain).unwrap();
+        assert_eq!(digest, sha256_hex(&bytes));
+        assert_eq!(
+            serde_json::from_slice::<serde_json::Value>(&bytes).unwrap(),
+            expected
+        );
+
+        let compressed = dir.path().join("report.json.gz");
+        let digest = write_report_file(&output, OutputFormat::Json, &compressed).unwrap();
+        let bytes = std::fs::read(&compressed).unwrap();
+        assert_eq!(digest, sha256_hex(&bytes));
+        let mut json = String::new();
+        GzDecoder::new(bytes.as_slice())
+            .read_to_string(&mut json)
+            .unwrap();
+        assert_eq!(
+            serde_json::from_str::<serde_json::Value>(&json).unwrap(),
+            expected
+        );
+        assert_eq!(read_report_file(&compressed).unwrap(), expected);
+    }
+}
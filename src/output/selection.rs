@@ -0,0 +1,141 @@
+use serde::Serialize;
+
+use crate::scanner::Selection;
+
+use super::Finding;
+
+/// Every algorithm a registry function can hand out, seen from one of its cases.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct AlgorithmSelection {
+    /// The dispatcher, e.g. `GetHasher`.
+    pub function: String,
+    /// The parameter switched on, e.g. `algorithm`.
+    pub selector: String,
+    /// Labels of the case returning this finding; `default` for the default clause.
+    pub cases: Vec<String>,
+    /// All cases of the dispatcher in source order, with the algorithm each selects.
+    pub options: Vec<SelectionOption>,
+}
+
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct SelectionOption {
+    pub case: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub algorithm: Option<String>,
+    pub line: usize,
+}
+
+impl From<&Selection> for AlgorithmSelection {
+    fn from(selection: &Selection) -> Self {
+        Self {
+            function: selection.function.clone(),
+            selector: selection.selector.clone(),
+            cases: selection.cases.clone(),
+            options: Vec::new(),
+        }
+    }
+}
+
+impl AlgorithmSelection {
+    /// Algorithms of the options, deduplicated in source order. Unclassified cases are
+    /// left out.
+    pub fn algorithms(&self) -> Vec<&str> {
+        let mut algorithms: Vec<&str> = Vec::new();
+        for algorithm in self.options.iter().filter_map(|o| o.algorithm.as_deref()) {
+            if !algorithms.contains(&algorithm) {
+                algorithms.push(algorithm);
+            }
+        }
+        algorithms
+    }
+
+    fn same_registry(&self, other: &AlgorithmSelection) -> bool {
+        self.function == other.function && self.selector == other.selector
+    }
+}
+
+/// Fills the options of every selection from the findings of its dispatcher's other
+/// cases in the same file.
+pub fn collect_selection_options(findings: &mut [Finding]) {
+    let registry: Vec<(String, AlgorithmSelection, usize, Option<String>)> = findings
+        .iter()
+        .filter_map(|f| {
+            let selection = f.selection.as_ref()?;
+            Some((
+                f.file.clone(),
+                selection.clone(),
+                f.line,
+                f.algorithm.clone(),
+            ))
+        })
+        .collect();
+
+    for finding in findings.iter_mut() {
+        let file = finding.file.clone();
+        let Some(selection) = finding.selection.as_mut() else {
+            continue;
+        };
+        let mut options: Vec<SelectionOption> = registry
+            .iter()
+            .filter(|(other_file, other, _, _)| {
+                *other_file == file && selection.same_registry(other)
+            })
+            .flat_map(|(_, other, line, algorithm)| {
+                other.cases.iter().map(|case| SelectionOption {
+                    case: case.clone(),
+                    algorithm: algorithm.clone(),
+                    line: *line,
+                })
+            })
+            .collect();
+        options.sort_by_key(|option| option.line);
+        options.dedup();
+        selection.options = options;
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn hasher(line: usize, algorithm: &str, case: &str) -> Finding {
+        Finding {
+            selection: Some(AlgorithmSelection {
+                function: "GetHasher".to_string(),
+                selector: "algorithm".to_string(),
+                cases: vec![case.to_string()],
+                options: Vec::new(),
+            }),
+            ..Finding::call("pkg/hash/sha.go", line, 10, "New")
+                .with_algorithm(algorithm)
+                .in_function("GetHasher")
+        }
+    }
+
+    #[test]
+    fn test_every_case_sees_all_options() {
+        let mut findings = vec![
+            hasher(31, "SHA-256", "default"),
+            hasher(27, "SHA-256", "sha256"),
+            hasher(29, "SHA-512", "sha512"),
+        ];
+        collect_selection_options(&mut findings);
+
+        let selection = findings[0].selection.as_ref().unwrap();
+        let cases: Vec<_> = selection
+            .options
+            .iter()
+            .map(|o| (o.case.as_str(), o.algorithm.as_deref().unwrap()))
+            .collect();
+        assert_eq!(
+            cases,
+            vec![
+                ("sha256", "SHA-256"),
+                ("sha512", "SHA-512"),
+                ("default", "SHA-256")
+            ]
+        );
+        assert_eq!(selection.algorithms(), vec!["SHA-256", "SHA-512"]);
+        assert_eq!(findings[1].selection.as_ref().unwrap().options.len(), 3);
+    }
+}
@@ -0,0 +1,184 @@
+use serde::Serialize;
+use std::collections::BTreeMap;
+use std::path::Path;
+
+use crate::scanner::ScanResult;
+
+/// How completely a package could be analyzed.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, PartialOrd, Ord, Serialize)]
+#[serde(rename_all = "lowercase")]
+pub enum AnalysisStatus {
+    /// Every file parsed cleanly.
+    Ok,
+    /// Some files had syntax errors or could not be read; findings are best-effort.
+    Partial,
+    /// No file in the package could be analyzed.
+    Failed,
+}
+
+impl AnalysisStatus {
+    pub fn as_str(&self) -> &'static str {
+        match self {
+            AnalysisStatus::Ok => "ok",
+            AnalysisStatus::Partial => "partial",
+            AnalysisStatus::Failed => "failed",
+        }
+    }
+
+    /// Rolls package statuses up into a single status for the whole run.
+    pub fn overall(packages: &[PackageStatus]) -> Self {
+        if packages.iter().all(|p| p.status == AnalysisStatus::Ok) {
+            AnalysisStatus::Ok
+        } else if packages.iter().all(|p| p.status == AnalysisStatus::Failed) {
+            AnalysisStatus::Failed
+        } else {
+            AnalysisStatus::Partial
+        }
+    }
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct PackageStatus {
+    pub package: String,
+    pub status: AnalysisStatus,
+    pub files_analyzed: usize,
+    pub files_failed: usize,
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub errors: Vec<String>,
+}
+
+/// A file that was selected for scanning but could not be read or parsed at all.
+#[derive(Debug, Clone)]
+pub struct FileFailure {
+    pub file_path: String,
+    pub error: String,
+}
+
+impl FileFailure {
+    pub fn new(file_path: impl Into<String>, error: impl Into<String>) -> Self {
+        Self {
+            file_path: file_path.into(),
+            error: error.into(),
+        }
+    }
+}
+
+#[derive(Default)]
+struct PackageAccumulator {
+    files_analyzed: usize,
+    files_with_errors: usize,
+    files_failed: usize,
+    errors: Vec<String>,
+}
+
+/// Groups scan results by package (source directory) and derives an analysis status for each.
+pub fn summarize_packages(results: &[ScanResult], failures: &[FileFailure]) -> Vec<PackageStatus> {
+    let mut packages: BTreeMap<String, PackageAccumulator> = BTreeMap::new();
+
+    for result in results {
+        let entry = packages.entry(package_of(&result.file_path)).or_default();
+        entry.files_analyzed += 1;
+        if result.has_errors() {
+            entry.files_with_errors += 1;
+            entry.errors.extend(
+                result
+                    .errors
+                    .iter()
+                    .map(|e| format!("{}: {e}", result.file_path)),
+            );
+        }
+    }
+
+    for failure in failures {
+        let entry = packages.entry(package_of(&failure.file_path)).or_default();
+        entry.files_failed += 1;
+        entry
+            .errors
+            .push(format!("{}: {}", failure.file_path, failure.error));
+    }
+
+    packages
+        .into_iter()
+        .map(|(package, acc)| {
+            let status = if acc.files_analyzed == 0 {
+                AnalysisStatus::Failed
+            } else if acc.files_failed > 0 || acc.files_with_errors > 0 {
+                AnalysisStatus::Partial
+            } else {
+                AnalysisStatus::Ok
+            };
+            PackageStatus {
+                package,
+                status,
+                files_analyzed: acc.files_analyzed,
+                files_failed: acc.files_failed,
+                errors: acc.errors,
+            }
+        })
+        .collect()
+}
+
+fn package_of(file_path: &str) -> String {
+    Path::new(file_path)
+        .parent()
+        .map(|p| p.to_string_lossy().to_string())
+        .unwrap_or_default()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn result(path: &str, errors: &[&str]) -> ScanResult {
+        let mut result = ScanResult::new(path.to_string());
+        for error in errors {
+            result.add_error(error.to_string());
+        }
+        result
+    }
+
+    #[test]
+    fn test_summarize_clean_package() {
+        let packages = summarize_packages(
+            &[result("app/pkg/a.go", &[]), result("app/pkg/b.go", &[])],
+            &[],
+        );
+        assert_eq!(packages.len(), 1);
+        assert_eq!(packages[0].package, "app/pkg");
+        assert_eq!(packages[0].status, AnalysisStatus::Ok);
+        assert_eq!(packages[0].files_analyzed, 2);
+    }
+
+    #[test]
+    fn test_summarize_syntax_error_is_partial() {
+        let packages = summarize_packages(
+            &[
+                result("app/pkg/a.go", &["syntax error at line 3"]),
+                result("app/other/b.go", &[]),
+            ],
+            &[],
+        );
+        assert_eq!(packages[0].package, "app/other");
+        assert_eq!(packages[0].status, AnalysisStatus::Ok);
+        assert_eq!(packages[1].status, AnalysisStatus::Partial);
+        assert_eq!(
+            packages[1].errors,
+            vec!["app/pkg/a.go: syntax error at line 3".to_string()]
+        );
+        assert_eq!(AnalysisStatus::overall(&packages), AnalysisStatus::Partial);
+    }
+
+    #[test]
+    fn test_summarize_unreadable_package_is_failed() {
+        let packages =
+            summarize_packages(&[], &[FileFailure::new("app/broken/a.go", "invalid UTF-8")]);
+        assert_eq!(packages[0].status, AnalysisStatus::Failed);
+        assert_eq!(packages[0].files_failed, 1);
+        assert_eq!(AnalysisStatus::overall(&packages), AnalysisStatus::Failed);
+    }
+
+    #[test]
+    fn test_overall_with_no_packages_is_ok() {
+        assert_eq!(AnalysisStatus::overall(&[]), AnalysisStatus::Ok);
+    }
+}
@@ -0,0 +1,235 @@
+use serde::Serialize;
+use std::collections::BTreeMap;
+use std::path::Path;
+
+use crate::cli::WrapperAttribution;
+
+use super::Finding;
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum WrapperRole {
+    /// The sink call inside a wrapper function.
+    Definition,
+    /// A call of the wrapper, itself mapped as a sink.
+    CallSite,
+}
+
+/// Another finding reporting the same crypto use through a wrapper.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct WrapperSite {
+    pub file: String,
+    pub line: usize,
+    pub column: usize,
+    pub function: String,
+}
+
+/// Links a finding inside a wrapper function to the findings at the wrapper's calls.
+#[derive(Debug, Clone, Serialize)]
+pub struct WrapperLink {
+    pub role: WrapperRole,
+    /// The call sites of a definition, or the definition of a call site.
+    pub sites: Vec<WrapperSite>,
+}
+
+impl From<&Finding> for WrapperSite {
+    fn from(finding: &Finding) -> Self {
+        Self {
+            file: finding.file.clone(),
+            line: finding.line,
+            column: finding.column,
+            function: finding.full_name.clone(),
+        }
+    }
+}
+
+/// Links findings made inside a wrapper function to the findings at its calls, when custom
+/// rules map the wrapper as a sink too.
+///
+/// A call of `kdf.Derive` is linked to the findings whose enclosing function is `Derive`
+/// in a package directory named `kdf`; an unqualified call to the findings in its own
+/// directory. Expects `findings` in report order.
+///
+/// Generated mocks are left out on both sides: a mock implementing the wrapper's
+/// interface is not what production calls reach.
+pub fn link_wrappers(findings: &mut [Finding]) {
+    let mut definitions: BTreeMap<(&Path, &str), Vec<usize>> = BTreeMap::new();
+    for (idx, finding) in findings.iter().enumerate() {
+        if finding.mock.is_some() {
+            continue;
+        }
+        if let Some(function) = &finding.enclosing_function {
+            definitions
+                .entry((package_dir(&finding.file), function))
+                .or_default()
+                .push(idx);
+        }
+    }
+
+    let mut links: BTreeMap<usize, Vec<usize>> = BTreeMap::new();
+    for (idx, call) in findings.iter().enumerate() {
+        if call.mock.is_some() {
+            continue;
+        }
+        let caller_dir = package_dir(&call.file);
+        for (&(dir, function), members) in &definitions {
+            if function != call.function {
+                continue;
+            }
+            let same_package = match &call.import_path {
+                Some(import_path) => dir
+                    .file_name()
+                    .is_some_and(|name| import_path.rsplit('/').next() == name.to_str()),
+                None => dir == caller_dir,
+            };
+            if same_package {
+                for &definition in members.iter().filter(|&&member| member != idx) {
+                    links.entry(definition).or_default().push(idx);
+                }
+            }
+        }
+    }
+
+    let mut call_sites: BTreeMap<usize, Vec<WrapperSite>> = BTreeMap::new();
+    for (&definition, calls) in &links {
+        for &call in calls {
+            call_sites
+                .entry(call)
+                .or_default()
+                .push(WrapperSite::from(&findings[definition]));
+        }
+    }
+    let definitions: Vec<_> = links
+        .into_iter()
+        .map(|(definition, calls)| {
+            let sites = calls
+                .iter()
+                .map(|&c| WrapperSite::from(&findings[c]))
+                .collect();
+            (definition, sites)
+        })
+        .collect();
+
+    for (definition, sites) in definitions {
+        findings[definition].wrapper = Some(WrapperLink {
+            role: WrapperRole::Definition,
+            sites,
+        });
+    }
+    for (call, sites) in call_sites {
+        // A wrapper calling another wrapper is reported where it is called
+        if findings[call].wrapper.is_none() {
+            findings[call].wrapper = Some(WrapperLink {
+                role: WrapperRole::CallSite,
+                sites,
+            });
+        }
+    }
+}
+
+/// Keeps one side of each linked pair: the definition, the call sites, or both.
+pub fn attribute_wrappers(findings: &mut Vec<Finding>, strategy: WrapperAttribution) {
+    let dropped = match strategy {
+        WrapperAttribution::BothLinked => return,
+        WrapperAttribution::DefinitionSite => WrapperRole::CallSite,
+        WrapperAttribution::CallSite => WrapperRole::Definition,
+    };
+    findings.retain(|finding| {
+        finding
+            .wrapper
+            .as_ref()
+            .is_none_or(|link| link.role != dropped)
+    });
+}
+
+fn package_dir(file: &str) -> &Path {
+    Path::new(file).parent().unwrap_or(Path::new(""))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn finding(file: &str, line: usize, function: &str, import_path: &str) -> Finding {
+        Finding::call(file, line, 2, &format!("{import_path}.{function}"))
+            .with_import_path(import_path)
+    }
+
+    fn findings() -> Vec<Finding> {
+        let mut derive = finding(
+            "internal/kdf/kdf.go",
+            12,
+            "Key",
+            "golang.org/x/crypto/pbkdf2",
+        );
+        derive.enclosing_function = Some("Derive".to_string());
+        vec![
+            finding("api/login.go", 30, "Derive", "example.com/app/internal/kdf"),
+            finding(
+                "api/signup.go",
+                18,
+                "Derive",
+                "example.com/app/internal/kdf",
+            ),
+            finding(
+                "api/token.go",
+                7,
+                "Derive",
+                "example.com/app/internal/other",
+            ),
+            derive,
+        ]
+    }
+
+    #[test]
+    fn test_links_definition_and_call_sites() {
+        let mut findings = findings();
+        link_wrappers(&mut findings);
+
+        let definition = findings[3].wrapper.as_ref().unwrap();
+        assert_eq!(definition.role, WrapperRole::Definition);
+        let lines: Vec<_> = definition.sites.iter().map(|s| s.line).collect();
+        assert_eq!(lines, vec![30, 18]);
+
+        let call = findings[0].wrapper.as_ref().unwrap();
+        assert_eq!(call.role, WrapperRole::CallSite);
+        assert_eq!(
+            call.sites,
+            vec![WrapperSite {
+                file: "internal/kdf/kdf.go".to_string(),
+                line: 12,
+                column: 2,
+                function: "golang.org/x/crypto/pbkdf2.Key".to_string(),
+            }]
+        );
+        assert!(findings[2].wrapper.is_none());
+    }
+
+    #[test]
+    fn test_mocks_are_not_linked() {
+        let mut mock = finding("internal/kdf/mock_kdf.go", 40, "Sum", "crypto/md5");
+        mock.enclosing_function = Some("Derive".to_string());
+        mock.mock = Some("mockgen".to_string());
+        let mut findings = findings();
+        findings.push(mock);
+        link_wrappers(&mut findings);
+
+        assert!(findings[4].wrapper.is_none());
+        let call = findings[0].wrapper.as_ref().unwrap();
+        let lines: Vec<_> = call.sites.iter().map(|s| s.line).collect();
+        assert_eq!(lines, vec![12]);
+    }
+
+    #[test]
+    fn test_attribution_strategies() {
+        let kept = |strategy| {
+            let mut findings = findings();
+            link_wrappers(&mut findings);
+            attribute_wrappers(&mut findings, strategy);
+            findings.iter().map(|f| f.line).collect::<Vec<_>>()
+        };
+        assert_eq!(kept(WrapperAttribution::BothLinked), vec![30, 18, 7, 12]);
+        assert_eq!(kept(WrapperAttribution::DefinitionSite), vec![7, 12]);
+        assert_eq!(kept(WrapperAttribution::CallSite), vec![30, 18, 7]);
+    }
+}
@@ -0,0 +1,757 @@
+//! Security parameter manifests for `argflow params`: the project constants reaching
+//! crypto calls, reviewed and verified like a lockfile.
+
+use std::collections::{BTreeMap, BTreeSet, VecDeque};
+use std::fmt::Write as _;
+use std::path::Path;
+
+use serde::{Deserialize, Serialize};
+use tree_sitter::{Node, Parser};
+
+use crate::output::JsonOutput;
+use crate::policy::relative_path;
+
+pub const MANIFEST_VERSION: u32 = 1;
+
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct ParamsManifest {
+    pub version: u32,
+    pub parameters: Vec<SecurityParameter>,
+}
+
+/// A project constant and the crypto calls it reaches.
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct SecurityParameter {
+    /// `package.Name`, e.g. `config.PBKDF2Iterations`.
+    pub name: String,
+    /// The resolved value the calls receive, when they all receive the same one.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub value: Option<serde_json::Value>,
+    /// The declaration's source expression, e.g. `600_000` or `BaseIterations * 2`.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub expression: Option<String>,
+    /// `file:line` of the declaration, relative to the scan root.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub defined_at: Option<String>,
+    pub sinks: Vec<ParameterSink>,
+}
+
+#[derive(Debug, Clone, PartialEq, Eq, PartialOrd, Ord, Serialize, Deserialize)]
+pub struct ParameterSink {
+    pub file: String,
+    pub line: usize,
+    pub function: String,
+    /// The argument reading the constant, e.g. `arg2`.
+    pub parameter: String,
+}
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum DriftKind {
+    Added,
+    Removed,
+    ValueChanged,
+    SinksChanged,
+}
+
+/// One difference between the committed manifest and the code.
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct ParameterDrift {
+    pub name: String,
+    pub kind: DriftKind,
+    /// What the committed manifest records, e.g. the old value or the sinks no longer reached.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub expected: Option<String>,
+    /// What the code has now.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub actual: Option<String>,
+}
+
+impl ParamsManifest {
+    /// The parameters of `report`, a scan of `root`.
+    pub fn build(root: &Path, report: &JsonOutput) -> Self {
+        let mut declarations = Declarations::default();
+        let parameters = report
+            .constant_usage
+            .iter()
+            .map(|usage| {
+                let name = usage
+                    .constant
+                    .rsplit_once('.')
+                    .map_or(usage.constant.as_str(), |(_, name)| name);
+                let declaration = declarations.find(&usage.package, name);
+
+                let values: Vec<&serde_json::Value> = usage
+                    .sinks
+                    .iter()
+                    .filter_map(|sink| {
+                        report
+                            .findings
+                            .iter()
+                            .find(|f| {
+                                f.file == sink.file
+                                    && f.line == sink.line
+                                    && f.column == sink.column
+                                    && f.full_name == sink.function
+                            })?
+                            .parameters
+                            .get(&sink.parameter)
+                    })
+                    .collect();
+                let value = values
+                    .first()
+                    .filter(|first| values.iter().all(|value| value == *first))
+                    .map(|value| (*value).clone());
+
+                let mut sinks: Vec<ParameterSink> = usage
+                    .sinks
+                    .iter()
+                    .map(|sink| ParameterSink {
+                        file: relative_path(&sink.file, root),
+                        line: sink.line,
+                        function: sink.function.clone(),
+                        parameter: sink.parameter.clone(),
+                    })
+                    .collect();
+                sinks.sort();
+                sinks.dedup();
+
+                SecurityParameter {
+                    name: usage.constant.clone(),
+                    value,
+                    expression: declaration.as_ref().map(|d| d.expression.clone()),
+                    defined_at: declaration
+                        .map(|d| format!("{}:{}", relative_path(&d.file, root), d.line)),
+                    sinks,
+                }
+            })
+            .collect();
+        Self {
+            version: MANIFEST_VERSION,
+            parameters,
+        }
+    }
+
+    /// Differences from `committed` to `self`, in parameter name order.
+    pub fn drift(&self, committed: &ParamsManifest) -> Vec<ParameterDrift> {
+        let current: BTreeMap<&str, &SecurityParameter> = self
+            .parameters
+            .iter()
+            .map(|p| (p.name.as_str(), p))
+            .collect();
+        let expected: BTreeMap<&str, &SecurityParameter> = committed
+            .parameters
+            .iter()
+            .map(|p| (p.name.as_str(), p))
+            .collect();
+        let names: BTreeSet<&str> = current.keys().chain(expected.keys()).copied().collect();
+
+        let mut drift = Vec::new();
+        for name in names {
+            let entry = |kind, from: Option<String>, to: Option<String>| ParameterDrift {
+                name: name.to_string(),
+                kind,
+                expected: from,
+                actual: to,
+            };
+            match (expected.get(name), current.get(name)) {
+                (None, Some(now)) => drift.push(entry(DriftKind::Added, None, describe(*now))),
+                (Some(then), None) => drift.push(entry(DriftKind::Removed, describe(*then), None)),
+                (Some(then), Some(now)) => {
+                    if then.value != now.value
+                        || then.value.is_none() && then.expression != now.expression
+                    {
+                        drift.push(entry(
+                            DriftKind::ValueChanged,
+                            describe(*then),
+                            describe(*now),
+                        ));
+                    }
+                    let sites = |p: &SecurityParameter| -> BTreeSet<String> {
+                        p.sinks
+                            .iter()
+                            .map(|s| format!("{} {} {}", s.file, s.function, s.parameter))
+                            .collect()
+                    };
+                    let (before, after) = (sites(*then), sites(*now));
+                    if before != after {
+                        let join = |sites: Vec<&String>| {
+                            (!sites.is_empty())
+                                .then(|| sites.into_iter().cloned().collect::<Vec<_>>().join(", "))
+                        };
+                        drift.push(entry(
+                            DriftKind::SinksChanged,
+                            join(before.difference(&after).collect()),
+                            join(after.difference(&before).collect()),
+                        ));
+                    }
+                }
+                (None, None) => {}
+            }
+        }
+        drift
+    }
+}
+
+/// The value of `parameter` for drift messages: the resolved value, else the expression.
+fn describe(parameter: &SecurityParameter) -> Option<String> {
+    match (&parameter.value, &parameter.expression) {
+        (Some(value), _) => Some(value.to_string()),
+        (None, Some(expression)) => Some(expression.clone()),
+        (None, None) => None,
+    }
+}
+
+pub fn render_drift(drift: &[ParameterDrift]) -> String {
+    let mut out = String::new();
+    if drift.is_empty() {
+        let _ = writeln!(out, "Security parameters match the manifest");
+        return out;
+    }
+    let _ = writeln!(out, "{} security parameter(s) drifted:", drift.len());
+    for entry in drift {
+        let expected = entry.expected.as_deref().unwrap_or("-");
+        let actual = entry.actual.as_deref().unwrap_or("-");
+        let _ = match entry.kind {
+            DriftKind::Added => writeln!(out, "  {}: added ({actual})", entry.name),
+            DriftKind::Removed => writeln!(out, "  {}: removed (was {expected})", entry.name),
+            DriftKind::ValueChanged => {
+                writeln!(out, "  {}: {expected} -> {actual}", entry.name)
+            }
+            DriftKind::SinksChanged => writeln!(
+                out,
+                "  {}: no longer reaches [{expected}], now reaches [{actual}]",
+                entry.name
+            ),
+        };
+    }
+    out
+}
+
+const NODE_WIDTH: usize = 300;
+const NODE_HEIGHT: usize = 44;
+const COLUMN_GAP: usize = 60;
+const ROW_GAP: usize = 16;
+
+const HTML_HEAD: &str = r#"<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>argflow security parameters</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+details { margin: 0.5em 0; }
+summary { cursor: pointer; }
+svg { display: block; margin: 1em 0; font-family: monospace; font-size: 12px; }
+rect { fill: #eef3fb; stroke: #5b7db1; }
+rect.selected { fill: #fdf1d6; stroke: #c08a1e; }
+rect.sink { fill: #f4f4f4; stroke: #777; }
+line { stroke: #888; marker-end: url(#arrow); }
+</style>
+<script>
+// Open the drilldown a derivation node links to
+function openTarget() {
+  const target = document.getElementById(decodeURIComponent(location.hash.slice(1)));
+  if (target) target.open = true;
+}
+window.addEventListener("hashchange", openTarget);
+window.addEventListener("DOMContentLoaded", openTarget);
+</script>
+</head>
+<body>
+"#;
+
+/// A page listing every parameter of `manifest`. Clicking one opens a graph of the
+/// parameters its declaration derives from and the calls it reaches.
+pub fn render_html(manifest: &ParamsManifest) -> String {
+    let mut out = String::from(HTML_HEAD);
+    let _ = writeln!(out, "<h1>Security parameters</h1>");
+    if manifest.parameters.is_empty() {
+        let _ = writeln!(out, "<p>No constant reaches a crypto call.</p>");
+    }
+    for parameter in &manifest.parameters {
+        let _ = writeln!(out, "<details id=\"{}\">", anchor(&parameter.name));
+        let _ = write!(out, "<summary><code>{}</code>", escape(&parameter.name));
+        if let Some(value) = describe(parameter) {
+            let _ = write!(out, " = <code>{}</code>", escape(&value));
+        }
+        let _ = writeln!(out, " ({} call(s))</summary>", parameter.sinks.len());
+        match (&parameter.defined_at, &parameter.expression) {
+            (Some(defined_at), Some(expression)) => {
+                let _ = writeln!(
+                    out,
+                    "<p>Declared at {} as <code>{}</code></p>",
+                    escape(defined_at),
+                    escape(expression)
+                );
+            }
+            (Some(defined_at), None) => {
+                let _ = writeln!(out, "<p>Declared at {}</p>", escape(defined_at));
+            }
+            _ => {}
+        }
+        out.push_str(&DerivationGraph::of(manifest, parameter).to_svg());
+        let _ = writeln!(out, "</details>");
+    }
+    out.push_str("</body>\n</html>\n");
+    out
+}
+
+/// The parameters a parameter derives from, transitively, and the calls it reaches.
+struct DerivationGraph<'a> {
+    /// Parameters by distance, farthest derivation first and the parameter itself last.
+    columns: Vec<Vec<&'a SecurityParameter>>,
+    /// `(from, to)` parameter names: `to`'s declaration mentions `from`.
+    edges: Vec<(&'a str, &'a str)>,
+    sinks: &'a [ParameterSink],
+}
+
+impl<'a> DerivationGraph<'a> {
+    fn of(manifest: &'a ParamsManifest, parameter: &'a SecurityParameter) -> Self {
+        let mut distance: BTreeMap<&str, usize> = BTreeMap::from([(parameter.name.as_str(), 0)]);
+        let mut order = vec![parameter];
+        let mut edges = Vec::new();
+        let mut queue = VecDeque::from([parameter]);
+        while let Some(current) = queue.pop_front() {
+            let next = distance[current.name.as_str()] + 1;
+            for source in derived_from(manifest, current) {
+                edges.push((source.name.as_str(), current.name.as_str()));
+                if !distance.contains_key(source.name.as_str()) {
+                    distance.insert(&source.name, next);
+                    order.push(source);
+                    queue.push_back(source);
+                }
+            }
+        }
+
+        let farthest = distance.values().copied().max().unwrap_or(0);
+        let mut columns = vec![Vec::new(); farthest + 1];
+        for node in order {
+            columns[farthest - distance[node.name.as_str()]].push(node);
+        }
+        DerivationGraph {
+            columns,
+            edges,
+            sinks: &parameter.sinks,
+        }
+    }
+
+    fn to_svg(&self) -> String {
+        let mut positions: BTreeMap<&str, (usize, usize)> = BTreeMap::new();
+        for (column, parameters) in self.columns.iter().enumerate() {
+            for (row, parameter) in parameters.iter().enumerate() {
+                positions.insert(&parameter.name, node_position(column, row));
+            }
+        }
+        let rows = self
+            .columns
+            .iter()
+            .map(Vec::len)
+            .chain([self.sinks.len()])
+            .max()
+            .unwrap_or(1)
+            .max(1);
+        let width = (self.columns.len() + 1) * (NODE_WIDTH + COLUMN_GAP) - COLUMN_GAP;
+        let height = rows * (NODE_HEIGHT + ROW_GAP) - ROW_GAP;
+
+        let mut out = String::new();
+        let _ = writeln!(
+            out,
+            "<svg width=\"{width}\" height=\"{height}\" viewBox=\"0 0 {width} {height}\">"
+        );
+        let _ = writeln!(
+            out,
+            "<defs><marker id=\"arrow\" viewBox=\"0 0 10 10\" refX=\"10\" refY=\"5\" \
+             markerWidth=\"6\" markerHeight=\"6\" orient=\"auto\">\
+             <path d=\"M0,0 L10,5 L0,10 z\" fill=\"#888\"/></marker></defs>"
+        );
+        let edge = |out: &mut String, (x1, y1): (usize, usize), (x2, y2): (usize, usize)| {
+            let _ = writeln!(
+                out,
+                "<line x1=\"{}\" y1=\"{}\" x2=\"{x2}\" y2=\"{}\"/>",
+                x1 + NODE_WIDTH,
+                y1 + NODE_HEIGHT / 2,
+                y2 + NODE_HEIGHT / 2
+            );
+        };
+        for (from, to) in &self.edges {
+            edge(&mut out, positions[from], positions[to]);
+        }
+        let selected = self.columns.len() - 1;
+        for row in 0..self.sinks.len() {
+            edge(
+                &mut out,
+                node_position(selected, 0),
+                node_position(selected + 1, row),
+            );
+        }
+
+        for (column, parameters) in self.columns.iter().enumerate() {
+            for (row, parameter) in parameters.iter().enumerate() {
+                let class = if column == selected {
+                    "selected"
+                } else {
+                    "derivation"
+                };
+                let _ = write!(out, "<a href=\"#{}\">", anchor(&parameter.name));
+                node(
+                    &mut out,
+                    node_position(column, row),
+                    class,
+                    &parameter.name,
+                    &describe(parameter).unwrap_or_default(),
+                );
+                let _ = writeln!(out, "</a>");
+            }
+        }
+        for (row, sink) in self.sinks.iter().enumerate() {
+            node(
+                &mut out,
+                node_position(selected + 1, row),
+                "sink",
+                &sink.function,
+                &format!("{}:{} {}", sink.file, sink.line, sink.parameter),
+            );
+        }
+        out.push_str("</svg>\n");
+        out
+    }
+}
+
+fn node_position(column: usize, row: usize) -> (usize, usize) {
+    (
+        column * (NODE_WIDTH + COLUMN_GAP),
+        row * (NODE_HEIGHT + ROW_GAP),
+    )
+}
+
+fn node(out: &mut String, (x, y): (usize, usize), class: &str, title: &str, detail: &str) {
+    let _ = write!(
+        out,
+        "<rect class=\"{class}\" x=\"{x}\" y=\"{y}\" width=\"{NODE_WIDTH}\" height=\"{NODE_HEIGHT}\" rx=\"4\"/>\
+         <text x=\"{}\" y=\"{}\">{}</text><text x=\"{}\" y=\"{}\">{}</text>",
+        x + 8,
+        y + 18,
+        escape(title),
+        x + 8,
+        y + 34,
+        escape(detail)
+    );
+}
+
+/// The other parameters of `manifest` that `parameter`'s declaration mentions, by their
+/// qualified name or, within its package, their plain name.
+fn derived_from<'a>(
+    manifest: &'a ParamsManifest,
+    parameter: &SecurityParameter,
+) -> Vec<&'a SecurityParameter> {
+    let Some(expression) = &parameter.expression else {
+        return Vec::new();
+    };
+    let package = |name: &str| {
+        name.rsplit_once('.')
+            .map(|(package, _)| package.to_string())
+    };
+    let identifiers: BTreeSet<&str> = expression
+        .split(|c: char| !(c.is_alphanumeric() || c == '_' || c == '.'))
+        .filter(|token| !token.is_empty())
+        .collect();
+    manifest
+        .parameters
+        .iter()
+        .filter(|other| other.name != parameter.name)
+        .filter(|other| {
+            identifiers.contains(other.name.as_str())
+                || (package(&other.name) == package(&parameter.name)
+                    && other
+                        .name
+                        .rsplit_once('.')
+                        .is_some_and(|(_, name)| identifiers.contains(name)))
+        })
+        .collect()
+}
+
+/// The element id of `name`'s drilldown.
+fn anchor(name: &str) -> String {
+    let id: String = name
+        .chars()
+        .map(|c| if c.is_alphanumeric() { c } else { '-' })
+        .collect();
+    format!("param-{id}")
+}
+
+fn escape(text: &str) -> String {
+    text.replace('&', "&amp;")
+        .replace('<', "&lt;")
+        .replace('>', "&gt;")
+        .replace('"', "&quot;")
+}
+
+struct Declaration {
+    file: String,
+    line: usize,
+    expression: String,
+}
+
+/// Package-level `const` and `var` declarations, parsed once per package directory.
+#[derive(Default)]
+struct Declarations {
+    packages: BTreeMap<String, Vec<(String, String, usize, String)>>,
+}
+
+impl Declarations {
+    fn find(&mut self, package: &str, name: &str) -> Option<Declaration> {
+        let specs = self
+            .packages
+            .entry(package.to_string())
+            .or_insert_with(|| package_specs(package));
+        specs
+            .iter()
+            .find(|(spec_name, ..)| spec_name == name)
+            .map(|(_, file, line, expression)| Declaration {
+                file: file.clone(),
+                line: *line,
+                expression: expression.clone(),
+            })
+    }
+}
+
+/// `(name, file, line, value)` of every package-level constant and variable with a value
+/// in the non-test Go files of `package`.
+fn package_specs(package: &str) -> Vec<(String, String, usize, String)> {
+    let dir = if package.is_empty() { "." } else { package };
+    let Ok(entries) = std::fs::read_dir(dir) else {
+        return Vec::new();
+    };
+    let mut files: Vec<_> = entries
+        .filter_map(|entry| entry.ok().map(|e| e.path()))
+        .filter(|path| {
+            path.extension().is_some_and(|e| e == "go")
+                && !path.to_string_lossy().ends_with("_test.go")
+        })
+        .collect();
+    files.sort();
+
+    let mut parser = Parser::new();
+    if parser
+        .set_language(&tree_sitter_go::LANGUAGE.into())
+        .is_err()
+    {
+        return Vec::new();
+    }
+    let mut specs = Vec::new();
+    for path in files {
+        let Ok(source) = std::fs::read_to_string(&path) else {
+            continue;
+        };
+        let Some(tree) = parser.parse(&source, None) else {
+            continue;
+        };
+        let root = tree.root_node();
+        let mut cursor = root.walk();
+        for declaration in root
+            .named_children(&mut cursor)
+            .filter(|n| matches!(n.kind(), "const_declaration" | "var_declaration"))
+        {
+            collect_specs(declaration, &source, &path, &mut specs);
+        }
+    }
+    specs
+}
+
+fn collect_specs(
+    node: Node<'_>,
+    source: &str,
+    path: &Path,
+    specs: &mut Vec<(String, String, usize, String)>,
+) {
+    if matches!(node.kind(), "const_spec" | "var_spec") {
+        let Some(values) = node.child_by_field_name("value") else {
+            return;
+        };
+        let mut cursor = node.walk();
+        for (index, name) in node.children_by_field_name("name", &mut cursor).enumerate() {
+            let Some(value) = values.named_child(index) else {
+                continue;
+            };
+            specs.push((
+                source[name.byte_range()].to_string(),
+                path.to_string_lossy().into_owned(),
+                name.start_position().row + 1,
+                source[value.byte_range()].to_string(),
+            ));
+        }
+        return;
+    }
+    let mut cursor = node.walk();
+    for child in node.named_children(&mut cursor) {
+        collect_specs(child, source, path, specs);
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn parameter(name: &str, value: i64, sinks: &[(&str, &str)]) -> SecurityParameter {
+        SecurityParameter {
+            name: name.to_string(),
+            value: Some(value.into()),
+            expression: Some(value.to_string()),
+            defined_at: Some("internal/config/crypto.go:5".to_string()),
+            sinks: sinks
+                .iter()
+                .map(|(file, parameter)| ParameterSink {
+                    file: file.to_string(),
+                    line: 10,
+                    function: "golang.org/x/crypto/pbkdf2.Key".to_string(),
+                    parameter: parameter.to_string(),
+                })
+                .collect(),
+        }
+    }
+
+    fn manifest(parameters: Vec<SecurityParameter>) -> ParamsManifest {
+        ParamsManifest {
+            version: MANIFEST_VERSION,
+            parameters,
+        }
+    }
+
+    #[test]
+    fn test_drift() {
+        let committed = manifest(vec![
+            parameter("config.Iterations", 600000, &[("auth/kdf.go", "arg2")]),
+            parameter("config.KeyLen", 32, &[("auth/kdf.go", "arg3")]),
+            parameter("config.SaltLen", 16, &[("auth/salt.go", "arg0")]),
+        ]);
+        let mut moved = parameter("config.SaltLen", 16, &[("auth/salt.go", "arg0")]);
+        moved.sinks[0].line = 99;
+        let current = manifest(vec![
+            parameter("config.Iterations", 10000, &[("auth/kdf.go", "arg2")]),
+            parameter(
+                "config.KeyLen",
+                32,
+                &[("auth/kdf.go", "arg3"), ("auth/jwt.go", "arg3")],
+            ),
+            parameter("config.MinRSABits", 2048, &[("auth/rsa.go", "arg1")]),
+            moved,
+        ]);
+
+        assert!(committed.drift(&committed).is_empty());
+        let drift = current.drift(&committed);
+        assert_eq!(
+            drift,
+            vec![
+                ParameterDrift {
+                    name: "config.Iterations".to_string(),
+                    kind: DriftKind::ValueChanged,
+                    expected: Some("600000".to_string()),
+                    actual: Some("10000".to_string()),
+                },
+                ParameterDrift {
+                    name: "config.KeyLen".to_string(),
+                    kind: DriftKind::SinksChanged,
+                    expected: None,
+                    actual: Some("auth/jwt.go golang.org/x/crypto/pbkdf2.Key arg3".to_string()),
+                },
+                ParameterDrift {
+                    name: "config.MinRSABits".to_string(),
+                    kind: DriftKind::Added,
+                    expected: None,
+                    actual: Some("2048".to_string()),
+                },
+            ]
+        );
+        assert_eq!(
+            render_drift(&drift),
+            "3 security parameter(s) drifted:\n  \
+             config.Iterations: 600000 -> 10000\n  \
+             config.KeyLen: no longer reaches [-], now reaches [auth/jwt.go golang.org/x/crypto/pbkdf2.Key arg3]\n  \
+             config.MinRSABits: added (2048)\n"
+        );
+    }
+
+    #[test]
+    fn test_manifest_yaml_round_trip() {
+        let committed = manifest(vec![parameter(
+            "config.Iterations",
+            600000,
+            &[("auth/kdf.go", "arg2")],
+        )]);
+        let yaml = serde_yaml::to_string(&committed).unwrap();
+        assert!(yaml.contains("name: config.Iterations"));
+        let parsed: ParamsManifest = serde_yaml::from_str(&yaml).unwrap();
+        assert_eq!(parsed, committed);
+    }
+
+    #[test]
+    fn test_package_specs() {
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::write(
+            dir.path().join("crypto.go"),
+            "package config\n\nconst (\n\tBaseIterations = 300_000\n\tIterations, KeyLen = BaseIterations * 2, 32\n)\n\nvar SaltLen = 16\n",
+        )
+        .unwrap();
+        std::fs::write(
+            dir.path().join("crypto_test.go"),
+            "package config\n\nconst Iterations = 1\n",
+        )
+        .unwrap();
+        let package = dir.path().to_string_lossy().into_owned();
+        let specs: Vec<_> = package_specs(&package)
+            .into_iter()
+            .map(|(name, _, line, value)| (name, line, value))
+            .collect();
+        assert_eq!(
+            specs,
+            vec![
+                ("BaseIterations".to_string(), 4, "300_000".to_string()),
+                (
+                    "Iterations".to_string(),
+                    5,
+                    "BaseIterations * 2".to_string()
+                ),
+                ("KeyLen".to_string(), 5, "32".to_string()),
+                ("SaltLen".to_string(), 8, "16".to_string()),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_html_drilldown_follows_derivations() {
+        let mut base = parameter("config.BaseIterations", 300000, &[]);
+        base.expression = Some("300_000".to_string());
+        let mut iterations = parameter(
+            "config.Iterations",
+            600000,
+            &[("auth/kdf.go", "arg2"), ("auth/jwt.go", "arg2")],
+        );
+        iterations.expression = Some("BaseIterations * 2".to_string());
+        let mut other = parameter("cache.BaseIterations", 1, &[]);
+        other.expression = Some("1".to_string());
+        let manifest = manifest(vec![base, iterations, other]);
+
+        let graph = DerivationGraph::of(&manifest, &manifest.parameters[1]);
+        let columns: Vec<Vec<&str>> = graph
+            .columns
+            .iter()
+            .map(|column| column.iter().map(|p| p.name.as_str()).collect())
+            .collect();
+        assert_eq!(
+            columns,
+            vec![vec!["config.BaseIterations"], vec!["config.Iterations"]]
+        );
+        assert_eq!(
+            graph.edges,
+            vec![("config.BaseIterations", "config.Iterations")]
+        );
+        assert_eq!(graph.sinks.len(), 2);
+
+        let html = render_html(&manifest);
+        assert!(html.contains("<details id=\"param-config-Iterations\">"));
+        assert!(html.contains("<a href=\"#param-config-BaseIterations\">"));
+        assert!(html.contains("auth/jwt.go:10 arg2"));
+        assert!(html.contains("BaseIterations * 2"));
+    }
+}
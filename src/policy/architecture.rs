@@ -0,0 +1,396 @@
+//! Reference architecture specs, for `argflow architecture`.
+
+use std::fmt::Write as _;
+use std::fs;
+use std::path::Path;
+
+use serde::{Deserialize, Serialize};
+use tracing::debug;
+
+use crate::error::PolicyError;
+use crate::output::Finding;
+
+use super::gate::relative_path;
+use super::rules::{in_directory, FindingSelector};
+
+/// The expected crypto architecture of a project.
+#[derive(Debug, Clone, Default, Deserialize, Serialize)]
+pub struct ArchitectureSpec {
+    pub components: Vec<Component>,
+}
+
+/// A part of the project allowed to perform crypto, e.g.
+/// `{"name": "vault", "paths": ["pkg/vault"], "algorithms": ["AES"]}`.
+#[derive(Debug, Clone, Deserialize, Serialize)]
+pub struct Component {
+    pub name: String,
+    /// Directories the component covers, matched like a rule's `match.paths`. A finding
+    /// belongs to the first component covering its file.
+    pub paths: Vec<String>,
+    /// Which of the component's findings the expectations apply to; others are only
+    /// checked for being in an expected package.
+    #[serde(rename = "match", default)]
+    pub selector: FindingSelector,
+    /// Approved algorithms; empty approves any.
+    #[serde(default)]
+    pub algorithms: Vec<String>,
+    /// The approved wrapper as `import/path.Function`. Sinks must be calls of it, be
+    /// made inside it, or take a key it returned.
+    #[serde(default)]
+    pub wrapper: Option<String>,
+}
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum DeviationKind {
+    /// An algorithm the finding's component does not approve.
+    UnexpectedAlgorithm,
+    /// Crypto in a directory no component covers.
+    UnexpectedPackage,
+    /// A sink called directly where the component requires its wrapper.
+    WrapperBypass,
+}
+
+impl DeviationKind {
+    pub fn as_str(&self) -> &'static str {
+        match self {
+            DeviationKind::UnexpectedAlgorithm => "unexpected-algorithm",
+            DeviationKind::UnexpectedPackage => "unexpected-package",
+            DeviationKind::WrapperBypass => "wrapper-bypass",
+        }
+    }
+}
+
+/// A finding the spec does not describe.
+#[derive(Debug, Clone, Serialize)]
+pub struct Deviation {
+    pub kind: DeviationKind,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub component: Option<String>,
+    pub file: String,
+    pub line: usize,
+    pub column: usize,
+    pub function: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub algorithm: Option<String>,
+    pub message: String,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct ArchitectureReport {
+    /// Findings compared against the spec; generated mocks are left out.
+    pub checked: usize,
+    pub deviations: Vec<Deviation>,
+}
+
+impl ArchitectureSpec {
+    pub fn from_file(path: &Path) -> Result<Self, PolicyError> {
+        debug!(path = %path.display(), "loading architecture spec");
+        let content = fs::read_to_string(path)
+            .map_err(|e| PolicyError::architecture_error(path, e.to_string()))?;
+
+        let extension = path.extension().and_then(|e| e.to_str()).unwrap_or("");
+        let spec: ArchitectureSpec = match extension {
+            "json" => serde_json::from_str(&content)
+                .map_err(|e| PolicyError::architecture_error(path, e.to_string()))?,
+            "yaml" | "yml" => serde_yaml::from_str(&content)
+                .map_err(|e| PolicyError::architecture_error(path, e.to_string()))?,
+            _ => {
+                return Err(PolicyError::UnsupportedFormat {
+                    format: extension.to_string(),
+                })
+            }
+        };
+        spec.validate()
+            .map_err(|message| PolicyError::architecture_error(path, message))?;
+        Ok(spec)
+    }
+
+    fn validate(&self) -> Result<(), String> {
+        if self.components.is_empty() {
+            return Err("spec declares no components".to_string());
+        }
+        for component in &self.components {
+            if component.paths.is_empty() {
+                return Err(format!("component '{}' covers no paths", component.name));
+            }
+            if !component.selector.paths.is_empty() {
+                return Err(format!(
+                    "component '{}' restricts match.paths; list directories under paths",
+                    component.name
+                ));
+            }
+            if let Some(wrapper) = &component.wrapper {
+                if Wrapper::parse(wrapper).is_none() {
+                    return Err(format!(
+                        "component '{}' has wrapper '{wrapper}', expected import/path.Function",
+                        component.name
+                    ));
+                }
+            }
+        }
+        Ok(())
+    }
+
+    /// The component covering `file`.
+    fn component(&self, file: &str) -> Option<&Component> {
+        self.components
+            .iter()
+            .find(|component| component.paths.iter().any(|dir| in_directory(file, dir)))
+    }
+}
+
+/// An approved wrapper split into its package name and function.
+pub(super) struct Wrapper<'a> {
+    pub(super) full_name: &'a str,
+    package: &'a str,
+    function: &'a str,
+}
+
+impl<'a> Wrapper<'a> {
+    pub(super) fn parse(full_name: &'a str) -> Option<Self> {
+        let (import_path, function) = full_name.rsplit_once('.')?;
+        let package = import_path.rsplit('/').next()?;
+        (!package.is_empty() && !function.is_empty()).then_some(Wrapper {
+            full_name,
+            package,
+            function,
+        })
+    }
+
+    /// Whether `finding` calls the wrapper, is made inside it, or takes a key from it.
+    fn covers(&self, finding: &Finding) -> bool {
+        finding.full_name == self.full_name
+            || self.contains(finding)
+            || finding.key.as_ref().is_some_and(|key| {
+                key.expression
+                    .contains(&format!("{}.{}(", self.package, self.function))
+            })
+    }
+
+    /// Whether `finding` is made inside the wrapper's function body.
+    pub(super) fn contains(&self, finding: &Finding) -> bool {
+        finding.enclosing_function.as_deref() == Some(self.function)
+            && in_directory(&finding.file, self.package)
+    }
+}
+
+impl ArchitectureReport {
+    /// Compares `findings` against `spec`. File paths in `unexpected-package` messages are
+    /// relative to `root`.
+    pub fn compare(spec: &ArchitectureSpec, findings: &[Finding], root: &Path) -> Self {
+        let wrappers: Vec<_> = spec
+            .components
+            .iter()
+            .filter_map(|component| component.wrapper.as_deref().and_then(Wrapper::parse))
+            .collect();
+
+        let mut checked = 0;
+        let mut deviations = Vec::new();
+        for finding in findings.iter().filter(|f| f.mock.is_none()) {
+            checked += 1;
+            let deviation = |kind, component: Option<&Component>, message| Deviation {
+                kind,
+                component: component.map(|c| c.name.clone()),
+                file: finding.file.clone(),
+                line: finding.line,
+                column: finding.column,
+                function: finding.full_name.clone(),
+                algorithm: finding.algorithm.clone(),
+                message,
+            };
+
+            let Some(component) = spec.component(&finding.file) else {
+                // Calls of an approved wrapper, and the sinks inside it, may live anywhere
+                if wrappers
+                    .iter()
+                    .any(|w| finding.full_name == w.full_name || w.contains(finding))
+                {
+                    continue;
+                }
+                let relative = relative_path(&finding.file, root);
+                let package = relative.rsplit_once('/').map_or(".", |(dir, _)| dir);
+                deviations.push(deviation(
+                    DeviationKind::UnexpectedPackage,
+                    None,
+                    format!(
+                        "{} performs crypto in {package}, which no component covers",
+                        finding.full_name
+                    ),
+                ));
+                continue;
+            };
+            if !component.selector.matches(finding) {
+                continue;
+            }
+
+            if let Some(algorithm) = finding.algorithm.as_deref().filter(|algorithm| {
+                !component.algorithms.is_empty()
+                    && !component
+                        .algorithms
+                        .iter()
+                        .any(|a| a.eq_ignore_ascii_case(algorithm))
+            }) {
+                deviations.push(deviation(
+                    DeviationKind::UnexpectedAlgorithm,
+                    Some(component),
+                    format!(
+                        "{algorithm} is not approved for {} (approved: {})",
+                        component.name,
+                        component.algorithms.join(", ")
+                    ),
+                ));
+            }
+            if let Some(wrapper) = component.wrapper.as_deref().and_then(Wrapper::parse) {
+                if !wrapper.covers(finding) {
+                    deviations.push(deviation(
+                        DeviationKind::WrapperBypass,
+                        Some(component),
+                        format!(
+                            "{} is called directly; {} must go through {}",
+                            finding.full_name, component.name, wrapper.full_name
+                        ),
+                    ));
+                }
+            }
+        }
+
+        ArchitectureReport {
+            checked,
+            deviations,
+        }
+    }
+
+    pub fn render_text(&self) -> String {
+        let mut out = String::new();
+        let _ = writeln!(
+            out,
+            "argflow architecture: {} finding(s) checked, {} deviation(s)",
+            self.checked,
+            self.deviations.len()
+        );
+        for deviation in &self.deviations {
+            let component = deviation
+                .component
+                .as_ref()
+                .map(|name| format!(" [{name}]"))
+                .unwrap_or_default();
+            let _ = writeln!(
+                out,
+                "  {}{component} {}:{}:{}: {}",
+                deviation.kind.as_str(),
+                deviation.file,
+                deviation.line,
+                deviation.column,
+                deviation.message
+            );
+        }
+        out
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::scanner::{ByteOrigin, ByteSource};
+    use serde_json::json;
+
+    fn finding(file: &str, full_name: &str, algorithm: &str) -> Finding {
+        let (import_path, _) = full_name.rsplit_once('.').unwrap();
+        Finding {
+            algorithm: Some(algorithm.to_string()).filter(|a| !a.is_empty()),
+            ..Finding::call(file, 12, 9, full_name)
+                .with_import_path(import_path)
+                .with_operation("encrypt")
+        }
+    }
+
+    fn spec() -> ArchitectureSpec {
+        serde_json::from_value(json!({
+            "components": [{
+                "name": "vault",
+                "paths": ["pkg/vault"],
+                "match": {"operation": "encrypt"},
+                "algorithms": ["AES"],
+                "wrapper": "example.com/app/internal/kms.Unwrap"
+            }]
+        }))
+        .unwrap()
+    }
+
+    #[test]
+    fn test_deviations_from_spec() {
+        let mut through_kms = finding("/app/pkg/vault/seal.go", "crypto/aes.NewCipher", "AES");
+        through_kms.key = Some(ByteSource {
+            origin: ByteOrigin::Untraced,
+            length: None,
+            available: None,
+            length_from: None,
+            minimum: None,
+            expression: "kms.Unwrap(ctx, blob)".to_string(),
+        });
+        let mut in_kms = finding("/app/internal/kms/unwrap.go", "crypto/aes.NewCipher", "AES");
+        in_kms.enclosing_function = Some("Unwrap".to_string());
+        let mut hashing = finding("/app/pkg/vault/id.go", "crypto/sha256.Sum256", "SHA-256");
+        hashing.operation = Some("hash".to_string());
+        let mut mock = finding("/app/pkg/mocks/cipher.go", "crypto/des.NewCipher", "DES");
+        mock.mock = Some("mockgen".to_string());
+        let findings = [
+            through_kms,
+            in_kms,
+            hashing,
+            mock,
+            finding("/app/pkg/vault/legacy.go", "crypto/des.NewCipher", "DES"),
+            finding("/app/pkg/api/token.go", "crypto/hmac.New", "HMAC"),
+            finding(
+                "/app/pkg/api/secret.go",
+                "example.com/app/internal/kms.Unwrap",
+                "",
+            ),
+        ];
+
+        let report = ArchitectureReport::compare(&spec(), &findings, Path::new("/app"));
+        assert_eq!(report.checked, 6);
+        let deviations: Vec<_> = report
+            .deviations
+            .iter()
+            .map(|d| (d.kind, d.file.as_str(), d.message.as_str()))
+            .collect();
+        assert_eq!(
+            deviations,
+            vec![
+                (
+                    DeviationKind::UnexpectedAlgorithm,
+                    "/app/pkg/vault/legacy.go",
+                    "DES is not approved for vault (approved: AES)"
+                ),
+                (
+                    DeviationKind::WrapperBypass,
+                    "/app/pkg/vault/legacy.go",
+                    "crypto/des.NewCipher is called directly; vault must go through example.com/app/internal/kms.Unwrap"
+                ),
+                (
+                    DeviationKind::UnexpectedPackage,
+                    "/app/pkg/api/token.go",
+                    "crypto/hmac.New performs crypto in pkg/api, which no component covers"
+                ),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_invalid_specs_are_rejected() {
+        for spec in [
+            json!({"components": []}),
+            json!({"components": [{"name": "vault", "paths": []}]}),
+            json!({"components": [{"name": "vault", "paths": ["pkg/vault"],
+                                   "match": {"paths": ["pkg"]}}]}),
+            json!({"components": [{"name": "vault", "paths": ["pkg/vault"],
+                                   "wrapper": "Unwrap"}]}),
+        ] {
+            let spec: ArchitectureSpec = serde_json::from_value(spec).unwrap();
+            assert!(spec.validate().is_err());
+        }
+        assert!(spec().validate().is_ok());
+    }
+}
@@ -0,0 +1,200 @@
+use std::collections::HashSet;
+use std::fs;
+use std::path::Path;
+
+use serde::{Deserialize, Serialize};
+
+use crate::error::PolicyError;
+
+use super::gate::Violation;
+
+pub const BASELINE_VERSION: u32 = 1;
+
+/// Violations that were accepted at some point and no longer fail the gate.
+///
+/// Entries are keyed by fingerprint, which ignores line numbers so unrelated edits
+/// that move code around do not resurface baselined violations.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct Baseline {
+    pub version: u32,
+    pub entries: Vec<BaselineEntry>,
+    #[serde(skip)]
+    fingerprints: HashSet<String>,
+}
+
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct BaselineEntry {
+    pub fingerprint: String,
+    pub rule: String,
+    pub file: String,
+    pub function: String,
+    /// Ticket tracking the accepted violation, required by a policy's `exceptions`.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub ticket: Option<String>,
+}
+
+impl Default for Baseline {
+    fn default() -> Self {
+        Self::new(Vec::new())
+    }
+}
+
+impl Baseline {
+    pub fn new(mut entries: Vec<BaselineEntry>) -> Self {
+        entries.sort_by(|a, b| {
+            (&a.file, &a.rule, &a.fingerprint).cmp(&(&b.file, &b.rule, &b.fingerprint))
+        });
+        entries.dedup_by(|a, b| a.fingerprint == b.fingerprint);
+        let fingerprints = entries.iter().map(|e| e.fingerprint.clone()).collect();
+        Self {
+            version: BASELINE_VERSION,
+            entries,
+            fingerprints,
+        }
+    }
+
+    /// Baselines every violation in `violations`, whatever its current status.
+    pub fn from_violations(violations: &[Violation]) -> Self {
+        Self::new(
+            violations
+                .iter()
+                .map(|v| BaselineEntry {
+                    fingerprint: v.fingerprint.clone(),
+                    rule: v.rule.clone(),
+                    file: v.file.clone(),
+                    function: v.function.clone(),
+                    ticket: None,
+                })
+                .collect(),
+        )
+    }
+
+    pub fn from_file(path: &Path) -> Result<Self, PolicyError> {
+        let content = fs::read_to_string(path)
+            .map_err(|e| PolicyError::baseline_read_error(path, e.to_string()))?;
+        let baseline: Baseline = serde_json::from_str(&content)
+            .map_err(|e| PolicyError::baseline_read_error(path, e.to_string()))?;
+        // Keep the version read from disk so older baselines can be detected and migrated
+        Ok(Self {
+            version: baseline.version,
+            ..Self::new(baseline.entries)
+        })
+    }
+
+    /// Fails if the baseline's fingerprints were computed by a different argflow version.
+    pub fn check_version(&self, path: &Path) -> Result<(), PolicyError> {
+        if self.version == BASELINE_VERSION {
+            return Ok(());
+        }
+        Err(PolicyError::BaselineVersionMismatch {
+            path: path.to_path_buf(),
+            found: self.version,
+            expected: BASELINE_VERSION,
+        })
+    }
+
+    pub fn save(&self, path: &Path) -> Result<(), PolicyError> {
+        let content = serde_json::to_string_pretty(self)
+            .map_err(|e| PolicyError::baseline_write_error(path, e.to_string()))?;
+        fs::write(path, content + "\n")
+            .map_err(|e| PolicyError::baseline_write_error(path, e.to_string()))
+    }
+
+    pub fn contains(&self, fingerprint: &str) -> bool {
+        self.fingerprints.contains(fingerprint)
+    }
+
+    pub fn entry(&self, fingerprint: &str) -> Option<&BaselineEntry> {
+        if !self.contains(fingerprint) {
+            return None;
+        }
+        self.entries.iter().find(|e| e.fingerprint == fingerprint)
+    }
+
+    /// Copies the tickets of `previous` onto entries with the same fingerprint that
+    /// have none, so regenerating a baseline keeps them.
+    pub fn keep_tickets(mut self, previous: &Baseline) -> Self {
+        for entry in self.entries.iter_mut().filter(|e| e.ticket.is_none()) {
+            entry.ticket = previous
+                .entry(&entry.fingerprint)
+                .and_then(|e| e.ticket.clone());
+        }
+        self
+    }
+
+    pub fn len(&self) -> usize {
+        self.entries.len()
+    }
+
+    pub fn is_empty(&self) -> bool {
+        self.entries.is_empty()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    fn entry(fingerprint: &str, file: &str) -> BaselineEntry {
+        BaselineEntry {
+            fingerprint: fingerprint.to_string(),
+            rule: "no-md5".to_string(),
+            file: file.to_string(),
+            function: "crypto/md5.Sum".to_string(),
+            ticket: None,
+        }
+    }
+
+    #[test]
+    fn test_baseline_round_trip() {
+        let temp_dir = TempDir::new().unwrap();
+        let path = temp_dir.path().join("argflow-baseline.json");
+
+        let baseline = Baseline::new(vec![
+            entry("b", "z.go"),
+            entry("a", "a.go"),
+            entry("a", "a.go"),
+        ]);
+        baseline.save(&path).unwrap();
+
+        let loaded = Baseline::from_file(&path).unwrap();
+        assert_eq!(loaded.len(), 2);
+        assert_eq!(loaded.entries[0].file, "a.go");
+        assert!(loaded.contains("a"));
+        assert!(loaded.contains("b"));
+        assert!(!loaded.contains("c"));
+        assert!(loaded.check_version(&path).is_ok());
+    }
+
+    #[test]
+    fn test_keep_tickets() {
+        let previous = Baseline::new(vec![BaselineEntry {
+            ticket: Some("SEC-42".to_string()),
+            ..entry("a", "a.go")
+        }]);
+        let updated =
+            Baseline::new(vec![entry("a", "a.go"), entry("b", "b.go")]).keep_tickets(&previous);
+
+        assert_eq!(
+            updated.entry("a").unwrap().ticket.as_deref(),
+            Some("SEC-42")
+        );
+        assert_eq!(updated.entry("b").unwrap().ticket, None);
+        assert!(updated.entry("c").is_none());
+    }
+
+    #[test]
+    fn test_old_version_is_preserved_and_rejected() {
+        let temp_dir = TempDir::new().unwrap();
+        let path = temp_dir.path().join("argflow-baseline.json");
+        fs::write(&path, r#"{"version": 0, "entries": []}"#).unwrap();
+
+        let loaded = Baseline::from_file(&path).unwrap();
+        assert_eq!(loaded.version, 0);
+        assert!(matches!(
+            loaded.check_version(&path),
+            Err(PolicyError::BaselineVersionMismatch { found: 0, .. })
+        ));
+    }
+}
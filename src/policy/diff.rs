@@ -0,0 +1,31 @@
+use std::collections::HashSet;
+use std::path::{Path, PathBuf};
+
+use crate::error::PolicyError;
+use crate::utils::git::run_git;
+
+/// Files changed between `base` and the working tree of the repository containing `root`,
+/// as canonical absolute paths.
+pub fn changed_files(root: &Path, base: &str) -> Result<HashSet<PathBuf>, PolicyError> {
+    let git = |args: &[&str]| run_git(root, args).map_err(|e| PolicyError::diff_error(base, e));
+
+    let toplevel = git(&["rev-parse", "--show-toplevel"])?;
+    let toplevel = PathBuf::from(toplevel.trim());
+
+    let merge_base = git(&["merge-base", base, "HEAD"])?;
+    let names = git(&[
+        "diff",
+        "--name-only",
+        "--diff-filter=ACMR",
+        merge_base.trim(),
+    ])?;
+
+    Ok(names
+        .lines()
+        .filter(|line| !line.is_empty())
+        .map(|line| {
+            let path = toplevel.join(line);
+            path.canonicalize().unwrap_or(path)
+        })
+        .collect())
+}
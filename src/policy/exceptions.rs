@@ -0,0 +1,126 @@
+//! Accepted violations and the tickets tracking them.
+
+use regex::Regex;
+use serde::{Deserialize, Serialize};
+
+use crate::error::PolicyError;
+
+/// Id invalid `exceptions` settings are reported under.
+const EXCEPTIONS_ID: &str = "exceptions";
+
+/// What an inline suppression or baseline entry must carry to be honored.
+#[derive(Debug, Clone, Default, Deserialize, Serialize)]
+pub struct ExceptionPolicy {
+    /// Every exception must name a ticket.
+    #[serde(default)]
+    pub require_ticket: bool,
+    /// Regex a ticket must match, e.g. `^SEC-[0-9]+$`. Tickets that do not match are
+    /// rejected even when `require_ticket` is off.
+    #[serde(default)]
+    pub ticket_pattern: Option<String>,
+}
+
+impl ExceptionPolicy {
+    pub fn validate(&self) -> Result<(), PolicyError> {
+        if let Some(pattern) = &self.ticket_pattern {
+            Regex::new(pattern).map_err(|e| {
+                PolicyError::invalid_rule(EXCEPTIONS_ID, format!("invalid ticket_pattern: {e}"))
+            })?;
+        }
+        Ok(())
+    }
+}
+
+/// Ticket requirements of a policy, with the pattern compiled once per evaluation.
+#[derive(Debug, Default)]
+pub(super) struct TicketCheck {
+    required: bool,
+    pattern: Option<Regex>,
+}
+
+impl TicketCheck {
+    pub(super) fn new(policy: Option<&ExceptionPolicy>) -> Self {
+        Self {
+            required: policy.is_some_and(|p| p.require_ticket),
+            pattern: policy
+                .and_then(|p| p.ticket_pattern.as_deref())
+                .and_then(|pattern| Regex::new(pattern).ok()),
+        }
+    }
+
+    /// Why an exception naming `ticket` is not honored, or `None` if it is.
+    pub(super) fn problem(&self, ticket: Option<&str>) -> Option<String> {
+        match (ticket, &self.pattern) {
+            (None, _) if self.required => Some("no ticket".to_string()),
+            (Some(ticket), Some(pattern)) if !pattern.is_match(ticket) => Some(format!(
+                "ticket {ticket} does not match {}",
+                pattern.as_str()
+            )),
+            _ => None,
+        }
+    }
+}
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum ExceptionKind {
+    /// An `argflow:ignore` comment.
+    Inline,
+    /// A baseline entry.
+    Baseline,
+}
+
+/// A violation accepted by a suppression or baseline entry.
+#[derive(Debug, Clone, Serialize)]
+pub struct Exception {
+    pub kind: ExceptionKind,
+    pub rule: String,
+    /// Path relative to the scan root.
+    pub file: String,
+    pub line: usize,
+    pub function: String,
+    pub fingerprint: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub ticket: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub reason: Option<String>,
+    /// Owner named by an inline suppression.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub owner: Option<String>,
+    /// Why the exception is not honored; the violation is then treated as if it were
+    /// not accepted.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub problem: Option<String>,
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_ticket_check() {
+        let policy = ExceptionPolicy {
+            require_ticket: true,
+            ticket_pattern: Some("^SEC-[0-9]+$".to_string()),
+        };
+        let check = TicketCheck::new(Some(&policy));
+        assert_eq!(check.problem(Some("SEC-42")), None);
+        assert_eq!(check.problem(None).as_deref(), Some("no ticket"));
+        assert_eq!(
+            check.problem(Some("later")).as_deref(),
+            Some("ticket later does not match ^SEC-[0-9]+$")
+        );
+
+        // Without a policy any exception is honored
+        assert_eq!(TicketCheck::new(None).problem(None), None);
+    }
+
+    #[test]
+    fn test_invalid_ticket_pattern() {
+        let policy = ExceptionPolicy {
+            require_ticket: false,
+            ticket_pattern: Some("SEC-(".to_string()),
+        };
+        assert!(policy.validate().is_err());
+    }
+}
@@ -0,0 +1,708 @@
+//! CEL-style expressions over the findings of a report, for rule `condition`s and
+//! `argflow rule test`.
+
+use std::cmp::Ordering;
+use std::fmt::Write as _;
+
+use regex::Regex;
+use serde::{Deserialize, Serialize};
+use serde_json::Value;
+
+use crate::error::PolicyError;
+
+/// Operators and punctuation, longest first so `<=` is not read as `<`.
+const SYMBOLS: &[&str] = &[
+    "&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "-", ".", "(", ")", "[", "]", ",",
+];
+
+/// A parsed expression. Policies write it as its source string.
+#[derive(Debug, Clone, Deserialize, Serialize)]
+#[serde(try_from = "String", into = "String")]
+pub struct Expression {
+    source: String,
+    root: Expr,
+}
+
+#[derive(Debug, Clone)]
+enum Expr {
+    Literal(Value),
+    List(Vec<Expr>),
+    Name(String),
+    Field(Box<Expr>, String),
+    Index(Box<Expr>, Box<Expr>),
+    Not(Box<Expr>),
+    Negate(Box<Expr>),
+    Binary(Operator, Box<Expr>, Box<Expr>),
+    Has(Box<Expr>),
+    Size(Box<Expr>),
+    Method(Box<Expr>, Method),
+}
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+enum Operator {
+    And,
+    Or,
+    Equal,
+    NotEqual,
+    Less,
+    LessEqual,
+    Greater,
+    GreaterEqual,
+    In,
+}
+
+#[derive(Debug, Clone)]
+enum Method {
+    StartsWith(Box<Expr>),
+    EndsWith(Box<Expr>),
+    Contains(Box<Expr>),
+    Matches(Regex),
+    /// `list.exists(x, predicate)`: the variable and the predicate.
+    Exists(String, Box<Expr>),
+    All(String, Box<Expr>),
+}
+
+#[derive(Debug, Clone, PartialEq)]
+enum Token {
+    Number(Value),
+    String(String),
+    Name(String),
+    Symbol(&'static str),
+}
+
+impl Expression {
+    pub fn parse(source: &str) -> Result<Self, PolicyError> {
+        let mut parser = Parser {
+            tokens: tokenize(source)?,
+            position: 0,
+            end: source.len(),
+        };
+        let root = parser.or()?;
+        if parser.position < parser.tokens.len() {
+            return Err(parser.error("unexpected token"));
+        }
+        Ok(Self {
+            source: source.to_string(),
+            root,
+        })
+    }
+
+    pub fn source(&self) -> &str {
+        &self.source
+    }
+
+    /// Whether the expression evaluates to `true` for `finding`.
+    pub fn matches(&self, finding: &Value) -> bool {
+        evaluate(&self.root, finding, &mut Vec::new()) == Value::Bool(true)
+    }
+}
+
+impl TryFrom<String> for Expression {
+    type Error = PolicyError;
+
+    fn try_from(source: String) -> Result<Self, Self::Error> {
+        Self::parse(&source)
+    }
+}
+
+impl From<Expression> for String {
+    fn from(expression: Expression) -> Self {
+        expression.source
+    }
+}
+
+/// The findings of a report an expression matches.
+#[derive(Debug, Clone, Serialize)]
+pub struct ExpressionTest {
+    pub expression: String,
+    /// Findings the expression was evaluated against.
+    pub checked: usize,
+    pub matches: Vec<Value>,
+}
+
+impl ExpressionTest {
+    pub fn run(expression: &Expression, findings: &[Value]) -> Self {
+        Self {
+            expression: expression.source().to_string(),
+            checked: findings.len(),
+            matches: findings
+                .iter()
+                .filter(|finding| expression.matches(finding))
+                .cloned()
+                .collect(),
+        }
+    }
+
+    pub fn render_text(&self) -> String {
+        let mut out = String::new();
+        for finding in &self.matches {
+            let field = |name: &str| finding.get(name).and_then(Value::as_str).unwrap_or("?");
+            let line = finding.get("line").and_then(Value::as_u64).unwrap_or(0);
+            let _ = write!(out, "{}:{line} {}", field("file"), field("function"));
+            if let Some(algorithm) = finding.get("algorithm").and_then(Value::as_str) {
+                let _ = write!(out, " ({algorithm})");
+            }
+            out.push('\n');
+        }
+        let _ = writeln!(
+            out,
+            "{} of {} finding(s) match",
+            self.matches.len(),
+            self.checked
+        );
+        out
+    }
+}
+
+fn tokenize(source: &str) -> Result<Vec<(usize, Token)>, PolicyError> {
+    let mut tokens = Vec::new();
+    let mut chars = source.char_indices().peekable();
+    while let Some(&(offset, c)) = chars.peek() {
+        if c.is_whitespace() {
+            chars.next();
+        } else if c.is_ascii_digit() {
+            let mut end = offset;
+            while let Some(&(i, d)) = chars
+                .peek()
+                .filter(|(_, d)| d.is_ascii_digit() || *d == '.')
+            {
+                end = i + d.len_utf8();
+                chars.next();
+            }
+            let text = &source[offset..end];
+            let number = if text.contains('.') {
+                text.parse::<f64>()
+                    .ok()
+                    .and_then(serde_json::Number::from_f64)
+                    .map(Value::Number)
+            } else {
+                text.parse::<i64>().ok().map(Value::from)
+            };
+            let number = number.ok_or_else(|| {
+                PolicyError::invalid_expression(offset, format!("malformed number '{text}'"))
+            })?;
+            tokens.push((offset, Token::Number(number)));
+        } else if c.is_alphabetic() || c == '_' {
+            let mut end = offset;
+            while let Some(&(i, d)) = chars
+                .peek()
+                .filter(|(_, d)| d.is_alphanumeric() || *d == '_')
+            {
+                end = i + d.len_utf8();
+                chars.next();
+            }
+            tokens.push((offset, Token::Name(source[offset..end].to_string())));
+        } else if c == '"' || c == '\'' {
+            chars.next();
+            let mut text = String::new();
+            loop {
+                match chars.next() {
+                    Some((_, d)) if d == c => break,
+                    Some((_, '\\')) => match chars.next() {
+                        Some((_, 'n')) => text.push('\n'),
+                        Some((_, 't')) => text.push('\t'),
+                        Some((_, escaped)) if escaped == c || escaped == '\\' => text.push(escaped),
+                        // Kept for patterns such as `'^md5\.'`
+                        Some((_, escaped)) => {
+                            text.push('\\');
+                            text.push(escaped);
+                        }
+                        None => {
+                            return Err(PolicyError::invalid_expression(
+                                offset,
+                                "unterminated string",
+                            ))
+                        }
+                    },
+                    Some((_, d)) => text.push(d),
+                    None => {
+                        return Err(PolicyError::invalid_expression(
+                            offset,
+                            "unterminated string",
+                        ))
+                    }
+                }
+            }
+            tokens.push((offset, Token::String(text)));
+        } else {
+            let symbol = SYMBOLS
+                .iter()
+                .find(|symbol| source[offset..].starts_with(**symbol))
+                .ok_or_else(|| {
+                    PolicyError::invalid_expression(offset, format!("unexpected character '{c}'"))
+                })?;
+            for _ in 0..symbol.len() {
+                chars.next();
+            }
+            tokens.push((offset, Token::Symbol(*symbol)));
+        }
+    }
+    Ok(tokens)
+}
+
+struct Parser {
+    tokens: Vec<(usize, Token)>,
+    position: usize,
+    /// Offset reported for errors at the end of the input.
+    end: usize,
+}
+
+impl Parser {
+    fn peek(&self) -> Option<&Token> {
+        self.tokens.get(self.position).map(|(_, token)| token)
+    }
+
+    fn next(&mut self) -> Option<Token> {
+        let token = self.peek().cloned();
+        self.position += 1;
+        token
+    }
+
+    fn error(&self, message: &str) -> PolicyError {
+        let offset = self
+            .tokens
+            .get(self.position)
+            .map_or(self.end, |(offset, _)| *offset);
+        PolicyError::invalid_expression(offset, message)
+    }
+
+    fn eat(&mut self, symbol: &str) -> bool {
+        let found = matches!(self.peek(), Some(Token::Symbol(s)) if *s == symbol);
+        if found {
+            self.position += 1;
+        }
+        found
+    }
+
+    fn expect(&mut self, symbol: &str) -> Result<(), PolicyError> {
+        if self.eat(symbol) {
+            Ok(())
+        } else {
+            Err(self.error(&format!("expected '{symbol}'")))
+        }
+    }
+
+    fn or(&mut self) -> Result<Expr, PolicyError> {
+        let mut left = self.and()?;
+        while self.eat("||") {
+            left = Expr::Binary(Operator::Or, Box::new(left), Box::new(self.and()?));
+        }
+        Ok(left)
+    }
+
+    fn and(&mut self) -> Result<Expr, PolicyError> {
+        let mut left = self.comparison()?;
+        while self.eat("&&") {
+            left = Expr::Binary(Operator::And, Box::new(left), Box::new(self.comparison()?));
+        }
+        Ok(left)
+    }
+
+    fn comparison(&mut self) -> Result<Expr, PolicyError> {
+        let left = self.unary()?;
+        let operator = match self.peek() {
+            Some(Token::Symbol("==")) => Operator::Equal,
+            Some(Token::Symbol("!=")) => Operator::NotEqual,
+            Some(Token::Symbol("<")) => Operator::Less,
+            Some(Token::Symbol("<=")) => Operator::LessEqual,
+            Some(Token::Symbol(">")) => Operator::Greater,
+            Some(Token::Symbol(">=")) => Operator::GreaterEqual,
+            Some(Token::Name(name)) if name == "in" => Operator::In,
+            _ => return Ok(left),
+        };
+        self.position += 1;
+        let right = self.unary()?;
+        Ok(Expr::Binary(operator, Box::new(left), Box::new(right)))
+    }
+
+    fn unary(&mut self) -> Result<Expr, PolicyError> {
+        if self.eat("!") {
+            return Ok(Expr::Not(Box::new(self.unary()?)));
+        }
+        if self.eat("-") {
+            return Ok(Expr::Negate(Box::new(self.unary()?)));
+        }
+        self.postfix()
+    }
+
+    fn postfix(&mut self) -> Result<Expr, PolicyError> {
+        let mut expr = self.primary()?;
+        loop {
+            if self.eat(".") {
+                let Some(Token::Name(name)) = self.next() else {
+                    self.position -= 1;
+                    return Err(self.error("expected a field or method name after '.'"));
+                };
+                expr = if self.eat("(") {
+                    let arguments = self.arguments()?;
+                    Expr::Method(Box::new(expr), self.method(&name, arguments)?)
+                } else {
+                    Expr::Field(Box::new(expr), name)
+                };
+            } else if self.eat("[") {
+                let index = self.or()?;
+                self.expect("]")?;
+                expr = Expr::Index(Box::new(expr), Box::new(index));
+            } else {
+                return Ok(expr);
+            }
+        }
+    }
+
+    fn primary(&mut self) -> Result<Expr, PolicyError> {
+        match self.next() {
+            Some(Token::Number(number)) => Ok(Expr::Literal(number)),
+            Some(Token::String(text)) => Ok(Expr::Literal(Value::String(text))),
+            Some(Token::Name(name)) => match name.as_str() {
+                "true" => Ok(Expr::Literal(Value::Bool(true))),
+                "false" => Ok(Expr::Literal(Value::Bool(false))),
+                "null" => Ok(Expr::Literal(Value::Null)),
+                "has" | "size" if self.eat("(") => {
+                    let [argument]: [Expr; 1] = self
+                        .arguments()?
+                        .try_into()
+                        .map_err(|_| self.error(&format!("{name}() takes one argument")))?;
+                    Ok(match name.as_str() {
+                        "has" => Expr::Has(Box::new(argument)),
+                        _ => Expr::Size(Box::new(argument)),
+                    })
+                }
+                _ => Ok(Expr::Name(name)),
+            },
+            Some(Token::Symbol("(")) => {
+                let expr = self.or()?;
+                self.expect(")")?;
+                Ok(expr)
+            }
+            Some(Token::Symbol("[")) => {
+                let mut items = Vec::new();
+                if !self.eat("]") {
+                    loop {
+                        items.push(self.or()?);
+                        if self.eat("]") {
+                            break;
+                        }
+                        self.expect(",")?;
+                    }
+                }
+                Ok(Expr::List(items))
+            }
+            _ => {
+                self.position -= 1;
+                Err(self.error("expected a value"))
+            }
+        }
+    }
+
+    /// Arguments of a call whose `(` has been read.
+    fn arguments(&mut self) -> Result<Vec<Expr>, PolicyError> {
+        let mut arguments = Vec::new();
+        if self.eat(")") {
+            return Ok(arguments);
+        }
+        loop {
+            arguments.push(self.or()?);
+            if self.eat(")") {
+                return Ok(arguments);
+            }
+            self.expect(",")?;
+        }
+    }
+
+    fn method(&self, name: &str, arguments: Vec<Expr>) -> Result<Method, PolicyError> {
+        match name {
+            "startsWith" | "endsWith" | "contains" | "matches" => {
+                let [argument]: [Expr; 1] = arguments
+                    .try_into()
+                    .map_err(|_| self.error(&format!("{name}() takes one argument")))?;
+                Ok(match (name, argument) {
+                    ("startsWith", argument) => Method::StartsWith(Box::new(argument)),
+                    ("endsWith", argument) => Method::EndsWith(Box::new(argument)),
+                    ("contains", argument) => Method::Contains(Box::new(argument)),
+                    (_, Expr::Literal(Value::String(pattern))) => Method::Matches(
+                        Regex::new(&pattern)
+                            .map_err(|e| self.error(&format!("invalid pattern: {e}")))?,
+                    ),
+                    _ => return Err(self.error("matches() takes a string literal")),
+                })
+            }
+            "exists" | "all" => {
+                let [variable, predicate]: [Expr; 2] = arguments.try_into().map_err(|_| {
+                    self.error(&format!("{name}() takes a variable and a predicate"))
+                })?;
+                let Expr::Name(variable) = variable else {
+                    return Err(self.error(&format!("{name}() takes a variable name first")));
+                };
+                Ok(match name {
+                    "exists" => Method::Exists(variable, Box::new(predicate)),
+                    _ => Method::All(variable, Box::new(predicate)),
+                })
+            }
+            _ => Err(self.error(&format!("unknown method '{name}'"))),
+        }
+    }
+}
+
+fn evaluate(expr: &Expr, finding: &Value, bindings: &mut Vec<(String, Value)>) -> Value {
+    match expr {
+        Expr::Literal(value) => value.clone(),
+        Expr::List(items) => Value::Array(
+            items
+                .iter()
+                .map(|item| evaluate(item, finding, bindings))
+                .collect(),
+        ),
+        Expr::Name(name) => bindings
+            .iter()
+            .rev()
+            .find(|(bound, _)| bound == name)
+            .map(|(_, value)| value.clone())
+            .or_else(|| finding.get(name).cloned())
+            .unwrap_or(Value::Null),
+        Expr::Field(object, name) => evaluate(object, finding, bindings)
+            .get(name)
+            .cloned()
+            .unwrap_or(Value::Null),
+        Expr::Index(object, index) => {
+            let object = evaluate(object, finding, bindings);
+            let found = match evaluate(index, finding, bindings) {
+                Value::String(key) => object.get(key.as_str()).cloned(),
+                Value::Number(n) => n.as_u64().and_then(|i| object.get(i as usize)).cloned(),
+                _ => None,
+            };
+            found.unwrap_or(Value::Null)
+        }
+        Expr::Not(operand) => match evaluate(operand, finding, bindings) {
+            Value::Bool(b) => Value::Bool(!b),
+            _ => Value::Null,
+        },
+        Expr::Negate(operand) => match evaluate(operand, finding, bindings) {
+            Value::Number(n) => match n.as_i64() {
+                Some(i) => Value::from(-i),
+                None => n
+                    .as_f64()
+                    .and_then(|f| serde_json::Number::from_f64(-f))
+                    .map_or(Value::Null, Value::Number),
+            },
+            _ => Value::Null,
+        },
+        Expr::Binary(Operator::And, left, right) => {
+            let left = evaluate(left, finding, bindings);
+            if left == Value::Bool(false) {
+                return left;
+            }
+            match (left, evaluate(right, finding, bindings)) {
+                (_, Value::Bool(false)) => Value::Bool(false),
+                (Value::Bool(true), Value::Bool(true)) => Value::Bool(true),
+                _ => Value::Null,
+            }
+        }
+        Expr::Binary(Operator::Or, left, right) => {
+            let left = evaluate(left, finding, bindings);
+            if left == Value::Bool(true) {
+                return left;
+            }
+            match (left, evaluate(right, finding, bindings)) {
+                (_, Value::Bool(true)) => Value::Bool(true),
+                (Value::Bool(false), Value::Bool(false)) => Value::Bool(false),
+                _ => Value::Null,
+            }
+        }
+        Expr::Binary(operator, left, right) => {
+            let left = evaluate(left, finding, bindings);
+            let right = evaluate(right, finding, bindings);
+            let ordering = || compare(&left, &right);
+            let result = match operator {
+                Operator::Equal => Some(equal(&left, &right)),
+                Operator::NotEqual => Some(!equal(&left, &right)),
+                Operator::Less => ordering().map(Ordering::is_lt),
+                Operator::LessEqual => ordering().map(Ordering::is_le),
+                Operator::Greater => ordering().map(Ordering::is_gt),
+                Operator::GreaterEqual => ordering().map(Ordering::is_ge),
+                Operator::In => match &right {
+                    Value::Array(items) => Some(items.iter().any(|item| equal(&left, item))),
+                    Value::Object(map) => left.as_str().map(|key| map.contains_key(key)),
+                    _ => None,
+                },
+                Operator::And | Operator::Or => unreachable!("handled above"),
+            };
+            result.map_or(Value::Null, Value::Bool)
+        }
+        Expr::Has(operand) => Value::Bool(!evaluate(operand, finding, bindings).is_null()),
+        Expr::Size(operand) => match evaluate(operand, finding, bindings) {
+            Value::String(s) => Value::from(s.chars().count()),
+            Value::Array(items) => Value::from(items.len()),
+            Value::Object(map) => Value::from(map.len()),
+            _ => Value::Null,
+        },
+        Expr::Method(receiver, method) => {
+            let receiver = evaluate(receiver, finding, bindings);
+            match method {
+                Method::StartsWith(argument)
+                | Method::EndsWith(argument)
+                | Method::Contains(argument) => {
+                    let argument = evaluate(argument, finding, bindings);
+                    let (Some(text), Some(part)) = (receiver.as_str(), argument.as_str()) else {
+                        return Value::Null;
+                    };
+                    Value::Bool(match method {
+                        Method::StartsWith(_) => text.starts_with(part),
+                        Method::EndsWith(_) => text.ends_with(part),
+                        _ => text.contains(part),
+                    })
+                }
+                Method::Matches(pattern) => receiver
+                    .as_str()
+                    .map_or(Value::Null, |text| Value::Bool(pattern.is_match(text))),
+                Method::Exists(variable, predicate) | Method::All(variable, predicate) => {
+                    let items: Vec<Value> = match receiver {
+                        Value::Array(items) => items,
+                        Value::Object(map) => {
+                            map.into_iter().map(|(key, _)| Value::String(key)).collect()
+                        }
+                        _ => return Value::Null,
+                    };
+                    let exists = matches!(method, Method::Exists(..));
+                    for item in items {
+                        bindings.push((variable.clone(), item));
+                        let holds = evaluate(predicate, finding, bindings) == Value::Bool(true);
+                        bindings.pop();
+                        if holds == exists {
+                            return Value::Bool(exists);
+                        }
+                    }
+                    Value::Bool(!exists)
+                }
+            }
+        }
+    }
+}
+
+/// Equality with numbers compared by value, so `1 == 1.0`.
+fn equal(left: &Value, right: &Value) -> bool {
+    match (left, right) {
+        (Value::Number(a), Value::Number(b)) => a.as_f64() == b.as_f64(),
+        _ => left == right,
+    }
+}
+
+/// Order of two numbers or two strings; other pairs are not ordered.
+fn compare(left: &Value, right: &Value) -> Option<Ordering> {
+    match (left, right) {
+        (Value::Number(a), Value::Number(b)) => a.as_f64()?.partial_cmp(&b.as_f64()?),
+        (Value::String(a), Value::String(b)) => Some(a.cmp(b)),
+        _ => None,
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use serde_json::json;
+
+    fn findings() -> Vec<Value> {
+        vec![
+            json!({
+                "file": "internal/auth/kdf.go",
+                "line": 14,
+                "function": "pbkdf2.Key",
+                "algorithm": "PBKDF2",
+                "primitive": "kdf",
+                "parameters": {"iterations": 10000, "keyLen": 32},
+            }),
+            json!({
+                "file": "internal/auth/kdf.go",
+                "line": 30,
+                "function": "pbkdf2.Key",
+                "algorithm": "PBKDF2",
+                "primitive": "kdf",
+                "parameters": {"iterations": 600000, "keyLen": 32},
+            }),
+            json!({
+                "file": "cmd/server/tls.go",
+                "line": 8,
+                "function": "md5.Sum",
+                "algorithm": "MD5",
+                "primitive": "hash",
+                "parameters": {},
+                "failure_paths": [{"kind": "panic", "line": 9, "text": "panic(err)"}],
+            }),
+            json!({
+                "file": "internal/auth/kdf.go",
+                "line": 44,
+                "function": "pbkdf2.Key",
+                "algorithm": "PBKDF2",
+                "primitive": "kdf",
+                "parameters": {"iterations": "unresolved"},
+            }),
+        ]
+    }
+
+    fn lines(source: &str) -> Vec<u64> {
+        let expression = Expression::parse(source).unwrap();
+        ExpressionTest::run(&expression, &findings())
+            .matches
+            .iter()
+            .map(|finding| finding["line"].as_u64().unwrap())
+            .collect()
+    }
+
+    #[test]
+    fn test_comparisons_and_missing_fields() {
+        assert_eq!(
+            lines(r#"primitive == "kdf" && parameters.iterations < 600000"#),
+            vec![14]
+        );
+        assert_eq!(lines("parameters['iterations'] >= 600000"), vec![30]);
+        assert_eq!(lines("!(parameters.iterations < 600000)"), vec![30]);
+        assert_eq!(
+            lines(r#"algorithm in ["MD5", "SHA1"] || line == 44"#),
+            vec![8, 44]
+        );
+        assert_eq!(
+            lines("has(failure_paths) && size(parameters) == 0"),
+            vec![8]
+        );
+        assert_eq!(lines("-line < -40"), vec![44]);
+    }
+
+    #[test]
+    fn test_string_methods_and_macros() {
+        assert_eq!(
+            lines(r#"file.startsWith("internal/") && parameters.keyLen == 32.0"#),
+            vec![14, 30]
+        );
+        assert_eq!(lines(r#"function.matches('^md5\.')"#), vec![8]);
+        assert_eq!(
+            lines(r#"failure_paths.exists(p, p.kind == "panic")"#),
+            vec![8]
+        );
+        assert_eq!(
+            lines(r#"parameters.all(name, name.endsWith("s")) && primitive == "kdf""#),
+            vec![44]
+        );
+    }
+
+    #[test]
+    fn test_parse_errors() {
+        let error = Expression::parse("primitive == ").unwrap_err();
+        assert_eq!(
+            error.to_string(),
+            "invalid expression at offset 13: expected a value"
+        );
+        assert!(Expression::parse("file.lower()").is_err());
+        assert!(Expression::parse("file.matches(pattern)").is_err());
+        assert!(Expression::parse(r#"file == "open"#).is_err());
+        assert!(Expression::parse("line == 1 1").is_err());
+    }
+
+    #[test]
+    fn test_render_text() {
+        let expression = Expression::parse(r#"algorithm == "MD5""#).unwrap();
+        let test = ExpressionTest::run(&expression, &findings());
+        assert_eq!(
+            test.render_text(),
+            "cmd/server/tls.go:8 md5.Sum (MD5)\n1 of 4 finding(s) match\n"
+        );
+    }
+}
@@ -0,0 +1,295 @@
+//! Base policies a policy file extends, fetched by URL, OCI reference or path and
+//! optionally pinned by digest.
+
+use std::fs;
+use std::path::{Path, PathBuf};
+use std::process::Command;
+use std::time::Duration;
+
+use serde::Deserialize;
+use serde_json::Value;
+use tracing::{debug, warn};
+
+use crate::attestation::sha256_hex;
+use crate::error::PolicyError;
+
+const ORAS_COMMAND: &str = "oras";
+const OCI_PREFIX: &str = "oci://";
+const FETCH_TIMEOUT: Duration = Duration::from_secs(30);
+
+/// Bases deeper than this are taken for a cycle.
+const MAX_DEPTH: usize = 8;
+
+/// The `extends` entry: a source, or a source pinned to the SHA-256 of its content.
+#[derive(Debug, Clone, PartialEq, Eq, Deserialize)]
+#[serde(untagged)]
+pub enum PolicyBase {
+    Source(String),
+    Pinned {
+        source: String,
+        #[serde(default)]
+        sha256: Option<String>,
+    },
+}
+
+impl PolicyBase {
+    pub fn source(&self) -> &str {
+        match self {
+            PolicyBase::Source(source) | PolicyBase::Pinned { source, .. } => source,
+        }
+    }
+
+    pub fn sha256(&self) -> Option<&str> {
+        match self {
+            PolicyBase::Source(_) => None,
+            PolicyBase::Pinned { sha256, .. } => sha256.as_deref(),
+        }
+    }
+}
+
+/// `policy` merged over the chain of bases it extends. `dir` resolves relative sources.
+pub(super) fn resolve(policy: Value, dir: &Path) -> Result<Value, PolicyError> {
+    resolve_at(policy, Some(dir), 0)
+}
+
+fn resolve_at(mut policy: Value, dir: Option<&Path>, depth: usize) -> Result<Value, PolicyError> {
+    let Some(extends) = policy.as_object_mut().and_then(|p| p.remove("extends")) else {
+        return Ok(policy);
+    };
+    let base: PolicyBase = serde_json::from_value(extends)
+        .map_err(|e| PolicyError::extends_error("extends", e.to_string()))?;
+    let source = base.source();
+    if depth == MAX_DEPTH {
+        return Err(PolicyError::extends_error(
+            source,
+            format!("more than {MAX_DEPTH} policies extend each other"),
+        ));
+    }
+    if source.starts_with("http://") && base.sha256().is_none() {
+        return Err(PolicyError::extends_error(
+            source,
+            "a base fetched over plain http must be pinned with sha256",
+        ));
+    }
+
+    debug!(source, "loading base policy");
+    let (content, base_dir) = fetch(source, dir)?;
+    match base.sha256() {
+        Some(expected) => {
+            let expected = expected.trim_start_matches("sha256:");
+            let actual = sha256_hex(&content);
+            if !actual.eq_ignore_ascii_case(expected) {
+                return Err(PolicyError::extends_error(
+                    source,
+                    format!("checksum mismatch: pinned sha256 {expected}, fetched {actual}"),
+                ));
+            }
+        }
+        None if base_dir.is_none() => {
+            warn!(source, "base policy is not pinned with sha256");
+        }
+        None => {}
+    }
+
+    let parsed: Value = serde_yaml::from_slice(&content)
+        .map_err(|e| PolicyError::extends_error(source, e.to_string()))?;
+    let parsed = resolve_at(parsed, base_dir.as_deref(), depth + 1)?;
+    Ok(merge(parsed, policy))
+}
+
+/// The content of `source`, and the directory its own relative sources resolve against.
+fn fetch(source: &str, dir: Option<&Path>) -> Result<(Vec<u8>, Option<PathBuf>), PolicyError> {
+    if let Some(reference) = source.strip_prefix(OCI_PREFIX) {
+        return pull_artifact(reference)
+            .map(|content| (content, None))
+            .map_err(|message| PolicyError::extends_error(source, message));
+    }
+    if source.starts_with("https://") || source.starts_with("http://") {
+        return download(source)
+            .map(|content| (content, None))
+            .map_err(|message| PolicyError::extends_error(source, message));
+    }
+
+    let path = match dir {
+        Some(dir) => dir.join(source),
+        None if Path::new(source).is_absolute() => PathBuf::from(source),
+        None => {
+            return Err(PolicyError::extends_error(
+                source,
+                "a remote base cannot extend a relative path",
+            ))
+        }
+    };
+    let content = fs::read(&path).map_err(|e| PolicyError::extends_error(source, e.to_string()))?;
+    Ok((content, path.parent().map(Path::to_path_buf)))
+}
+
+fn download(url: &str) -> Result<Vec<u8>, String> {
+    let client = reqwest::blocking::Client::builder()
+        .timeout(FETCH_TIMEOUT)
+        .user_agent(concat!("argflow/", env!("CARGO_PKG_VERSION")))
+        .build()
+        .map_err(|e| e.to_string())?;
+    let response = client
+        .get(url)
+        .send()
+        .map_err(|e| e.without_url().to_string())?;
+    let status = response.status();
+    if !status.is_success() {
+        return Err(format!("HTTP {status}"));
+    }
+    response
+        .bytes()
+        .map(|bytes| bytes.to_vec())
+        .map_err(|e| e.without_url().to_string())
+}
+
+/// Pulls the artifact at `reference` with `oras` and reads the one policy file in it.
+fn pull_artifact(reference: &str) -> Result<Vec<u8>, String> {
+    let dir = tempfile::tempdir().map_err(|e| e.to_string())?;
+    let output = Command::new(ORAS_COMMAND)
+        .args(["pull", reference, "--output"])
+        .arg(dir.path())
+        .output()
+        .map_err(|e| format!("failed to run {ORAS_COMMAND}: {e}"))?;
+    if !output.status.success() {
+        return Err(format!(
+            "{ORAS_COMMAND} pull failed: {}",
+            String::from_utf8_lossy(&output.stderr).trim()
+        ));
+    }
+
+    let mut policies: Vec<PathBuf> = fs::read_dir(dir.path())
+        .map_err(|e| e.to_string())?
+        .filter_map(|entry| entry.ok().map(|entry| entry.path()))
+        .filter(|path| {
+            path.extension()
+                .is_some_and(|e| e == "yaml" || e == "yml" || e == "json")
+        })
+        .collect();
+    match policies.len() {
+        1 => fs::read(policies.remove(0)).map_err(|e| e.to_string()),
+        0 => Err("artifact holds no .yaml or .json policy".to_string()),
+        n => Err(format!("artifact holds {n} policies, expected one")),
+    }
+}
+
+/// `local` over `base`: objects merge key by key, lists whose entries all have an `id`
+/// merge entry by entry, and any other value of `local` replaces the base's.
+fn merge(base: Value, local: Value) -> Value {
+    match (base, local) {
+        (Value::Object(mut base), Value::Object(local)) => {
+            for (key, value) in local {
+                let merged = match base.remove(&key) {
+                    Some(inherited) => merge(inherited, value),
+                    None => value,
+                };
+                base.insert(key, merged);
+            }
+            Value::Object(base)
+        }
+        (Value::Array(mut base), Value::Array(local))
+            if base
+                .iter()
+                .chain(&local)
+                .all(|entry| entry.get("id").is_some()) =>
+        {
+            for entry in local {
+                match base
+                    .iter()
+                    .position(|inherited| inherited["id"] == entry["id"])
+                {
+                    Some(index) => {
+                        let inherited = std::mem::take(&mut base[index]);
+                        base[index] = merge(inherited, entry);
+                    }
+                    None => base.push(entry),
+                }
+            }
+            Value::Array(base)
+        }
+        (_, local) => local,
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use serde_json::json;
+    use tempfile::TempDir;
+
+    const BASE: &str = r#"
+fail_on: warning
+rules:
+  - id: no-md5
+    match: { algorithm: md5 }
+  - id: weak-kdf
+    match: { primitive: kdf }
+    parameter: { category: iteration-count, min: 600000 }
+exceptions:
+  require_ticket: true
+  ticket_pattern: "SEC-[0-9]+"
+"#;
+
+    #[test]
+    fn test_local_policy_overrides_its_base() {
+        let dir = TempDir::new().unwrap();
+        fs::write(dir.path().join("base.yaml"), BASE).unwrap();
+        let local = json!({
+            "extends": "base.yaml",
+            "fail_on": "error",
+            "rules": [
+                {"id": "weak-kdf", "severity": "warning"},
+                {"id": "no-des", "match": {"algorithm": "des"}}
+            ],
+            "exceptions": {"ticket_pattern": "CRYPTO-[0-9]+"}
+        });
+
+        let merged = resolve(local, dir.path()).unwrap();
+        assert_eq!(
+            merged,
+            json!({
+                "fail_on": "error",
+                "rules": [
+                    {"id": "no-md5", "match": {"algorithm": "md5"}},
+                    {
+                        "id": "weak-kdf",
+                        "severity": "warning",
+                        "match": {"primitive": "kdf"},
+                        "parameter": {"category": "iteration-count", "min": 600000}
+                    },
+                    {"id": "no-des", "match": {"algorithm": "des"}}
+                ],
+                "exceptions": {"require_ticket": true, "ticket_pattern": "CRYPTO-[0-9]+"}
+            })
+        );
+    }
+
+    #[test]
+    fn test_pinned_base_must_match_its_checksum() {
+        let dir = TempDir::new().unwrap();
+        fs::write(dir.path().join("base.yaml"), BASE).unwrap();
+        let pin = sha256_hex(BASE.as_bytes());
+
+        let pinned = json!({"extends": {"source": "base.yaml", "sha256": pin}});
+        assert_eq!(resolve(pinned, dir.path()).unwrap()["fail_on"], "warning");
+
+        let stale = json!({"extends": {"source": "base.yaml", "sha256": "0".repeat(64)}});
+        let err = resolve(stale, dir.path()).unwrap_err().to_string();
+        assert!(err.contains("checksum mismatch"), "{err}");
+
+        let unpinned = json!({"extends": "http://security.example.com/base.yaml"});
+        assert!(resolve(unpinned, dir.path()).is_err());
+    }
+
+    #[test]
+    fn test_extends_cycle_is_rejected() {
+        let dir = TempDir::new().unwrap();
+        fs::write(dir.path().join("a.yaml"), "extends: b.yaml\n").unwrap();
+        fs::write(dir.path().join("b.yaml"), "extends: a.yaml\n").unwrap();
+        let err = resolve(json!({"extends": "a.yaml"}), dir.path())
+            .unwrap_err()
+            .to_string();
+        assert!(err.contains("extend each other"), "{err}");
+    }
+}
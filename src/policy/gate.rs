@@ -0,0 +1,739 @@
+use std::collections::{BTreeMap, HashMap, HashSet};
+use std::fmt::Write as _;
+use std::path::{Component, Path, PathBuf};
+
+use serde::Serialize;
+
+use crate::output::{Finding, Redactions};
+
+use super::baseline::Baseline;
+use super::exceptions::{Exception, ExceptionKind, TicketCheck};
+use super::owners::owner_of;
+use super::rules::{Policy, Severity};
+use super::suppression::{parse_suppression, Suppression};
+
+/// Whether a violation counts against the gate.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum ViolationStatus {
+    /// Not in the baseline and inside the diff: blocks if severe enough.
+    New,
+    /// Accepted in the baseline.
+    Baselined,
+    /// Accepted by an inline `argflow:ignore` comment.
+    Suppressed,
+    /// In a file the change under review did not touch.
+    OutsideDiff,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct Violation {
+    pub rule: String,
+    pub severity: Severity,
+    pub message: String,
+    /// Path relative to the scan root.
+    pub file: String,
+    pub line: usize,
+    pub column: usize,
+    pub function: String,
+    /// Team owning the file, from the policy's ownership areas.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub owner: Option<String>,
+    /// The organization's remediation guide for the rule, from the policy.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub remediation_url: Option<String>,
+    pub fingerprint: String,
+    pub status: ViolationStatus,
+    pub blocking: bool,
+    /// Flagged because a constrained argument could not be resolved.
+    #[serde(skip)]
+    pub unresolved: bool,
+}
+
+#[derive(Debug, Clone, Default, Serialize)]
+pub struct GateSummary {
+    pub violations: usize,
+    pub blocking: usize,
+    pub new: usize,
+    pub baselined: usize,
+    pub suppressed: usize,
+    pub outside_diff: usize,
+}
+
+/// Machine-readable result of `argflow gate`.
+#[derive(Debug, Clone, Serialize)]
+pub struct GateReport {
+    pub passed: bool,
+    pub fail_on: Severity,
+    pub summary: GateSummary,
+    pub violations: Vec<Violation>,
+    /// Every suppression and baseline entry matching a violation, for audit review.
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub exceptions: Vec<Exception>,
+    pub next_steps: Vec<String>,
+}
+
+/// Inputs that decide which violations block, beyond the policy itself.
+#[derive(Debug, Default)]
+pub struct GateOptions<'a> {
+    /// Scan root; reported paths and fingerprints are relative to it.
+    pub root: PathBuf,
+    pub baseline: Option<&'a Baseline>,
+    /// Only violations in these files can block. `None` means every file is in scope.
+    pub changed_files: Option<&'a HashSet<PathBuf>>,
+}
+
+/// Checks every finding against every policy rule.
+pub fn evaluate(policy: &Policy, findings: &[Finding], options: &GateOptions) -> GateReport {
+    let tickets = TicketCheck::new(policy.exceptions.as_ref());
+    let mut sources: HashMap<&str, Vec<String>> = HashMap::new();
+    let mut violations = Vec::new();
+    let mut exceptions = Vec::new();
+
+    for finding in findings {
+        let file = relative_path(&finding.file, &options.root);
+        let area = owner_of(&policy.owners, &file);
+        for (rule, rule_severity, message) in policy.violations(finding) {
+            let fingerprint = fingerprint(rule, &file, finding);
+
+            // Inline suppressions are checked first, then the baseline; an exception
+            // whose ticket the policy rejects is reported but not honored
+            let mut accepted = None;
+            let mut found = Vec::new();
+            let lines = sources.entry(&finding.file).or_insert_with(|| {
+                std::fs::read_to_string(&finding.file)
+                    .map(|content| content.lines().map(str::to_string).collect())
+                    .unwrap_or_default()
+            });
+            if let Some(suppression) =
+                suppression_at(lines, finding.line).filter(|s| s.rules.iter().any(|r| r == rule))
+            {
+                let problem = tickets.problem(suppression.ticket.as_deref());
+                if problem.is_none() {
+                    accepted = Some(ViolationStatus::Suppressed);
+                }
+                found.push((ExceptionKind::Inline, suppression, problem));
+            }
+            if let Some(entry) = options.baseline.and_then(|b| b.entry(&fingerprint)) {
+                let problem = tickets.problem(entry.ticket.as_deref());
+                if problem.is_none() {
+                    accepted = accepted.or(Some(ViolationStatus::Baselined));
+                }
+                let suppression = Suppression {
+                    ticket: entry.ticket.clone(),
+                    ..Suppression::default()
+                };
+                found.push((ExceptionKind::Baseline, suppression, problem));
+            }
+
+            let status = match accepted {
+                Some(status) => status,
+                None if options
+                    .changed_files
+                    .is_some_and(|changed| !changed.contains(&absolute_path(&finding.file))) =>
+                {
+                    ViolationStatus::OutsideDiff
+                }
+                None => ViolationStatus::New,
+            };
+
+            for (kind, suppression, problem) in found {
+                exceptions.push(Exception {
+                    kind,
+                    rule: rule.to_string(),
+                    file: file.clone(),
+                    line: finding.line,
+                    function: finding.full_name.clone(),
+                    fingerprint: fingerprint.clone(),
+                    ticket: suppression.ticket,
+                    reason: suppression.reason,
+                    owner: suppression.owner,
+                    problem,
+                });
+            }
+
+            let severity = area
+                .and_then(|area| area.severity_for(rule))
+                .unwrap_or(rule_severity);
+            violations.push(Violation {
+                rule: rule.to_string(),
+                severity,
+                message,
+                file: file.clone(),
+                line: finding.line,
+                column: finding.column,
+                function: finding.full_name.clone(),
+                owner: area.map(|area| area.team.clone()),
+                remediation_url: policy.remediation_url(rule, finding),
+                fingerprint,
+                status,
+                blocking: status == ViolationStatus::New && severity >= policy.fail_on,
+                unresolved: policy
+                    .rules
+                    .iter()
+                    .any(|r| r.id == rule && r.flags_unresolved(finding)),
+            });
+        }
+    }
+
+    report(violations, exceptions, policy.fail_on, options)
+}
+
+/// The suppression at the end of line `line` (1-based) of `lines` or on the line above.
+fn suppression_at(lines: &[String], line: usize) -> Option<Suppression> {
+    let index = line.checked_sub(1)?;
+    [Some(index), index.checked_sub(1)]
+        .into_iter()
+        .flatten()
+        .filter_map(|i| lines.get(i))
+        .find_map(|text| parse_suppression(text))
+}
+
+fn report(
+    violations: Vec<Violation>,
+    exceptions: Vec<Exception>,
+    fail_on: Severity,
+    options: &GateOptions,
+) -> GateReport {
+    let summary = GateSummary {
+        violations: violations.len(),
+        blocking: violations.iter().filter(|v| v.blocking).count(),
+        new: count(&violations, ViolationStatus::New),
+        baselined: count(&violations, ViolationStatus::Baselined),
+        suppressed: count(&violations, ViolationStatus::Suppressed),
+        outside_diff: count(&violations, ViolationStatus::OutsideDiff),
+    };
+
+    GateReport {
+        passed: summary.blocking == 0,
+        fail_on,
+        next_steps: next_steps(&summary, &violations, &exceptions, options),
+        summary,
+        violations,
+        exceptions,
+    }
+}
+
+impl Violation {
+    /// Redacts hardcoded secrets from the message, which renders argument values and
+    /// traced expressions of the raw finding.
+    pub fn redact(&mut self, redactions: &Redactions) {
+        self.message = redactions.apply(&self.message);
+    }
+}
+
+impl GateReport {
+    /// Redacts the secrets redacted from the published findings out of every message.
+    pub fn redact(&mut self, redactions: &Redactions) {
+        for violation in &mut self.violations {
+            violation.redact(redactions);
+        }
+    }
+
+    /// One report per owning team, with its own verdict and summary. Violations in files
+    /// no area owns are keyed by `None`.
+    pub fn by_owner(&self, options: &GateOptions) -> BTreeMap<Option<String>, GateReport> {
+        let mut grouped: BTreeMap<Option<String>, Vec<Violation>> = BTreeMap::new();
+        for violation in &self.violations {
+            grouped
+                .entry(violation.owner.clone())
+                .or_default()
+                .push(violation.clone());
+        }
+        grouped
+            .into_iter()
+            .map(|(owner, violations)| {
+                let fingerprints: HashSet<&str> =
+                    violations.iter().map(|v| v.fingerprint.as_str()).collect();
+                let exceptions = self
+                    .exceptions
+                    .iter()
+                    .filter(|e| fingerprints.contains(e.fingerprint.as_str()))
+                    .cloned()
+                    .collect();
+                (owner, report(violations, exceptions, self.fail_on, options))
+            })
+            .collect()
+    }
+
+    /// Human-readable summary for CI logs. Only new violations are listed individually.
+    pub fn render_text(&self) -> String {
+        let mut out = String::new();
+        let verdict = if self.passed { "PASSED" } else { "FAILED" };
+        let _ = writeln!(
+            out,
+            "argflow gate: {verdict} ({} blocking, {} new, {} baselined, {} suppressed, {} outside diff)",
+            self.summary.blocking,
+            self.summary.new,
+            self.summary.baselined,
+            self.summary.suppressed,
+            self.summary.outside_diff
+        );
+
+        let listed: Vec<_> = self
+            .violations
+            .iter()
+            .filter(|v| v.status == ViolationStatus::New)
+            .collect();
+        if !listed.is_empty() {
+            out.push('\n');
+        }
+        for violation in listed {
+            let marker = if violation.blocking { "x" } else { "!" };
+            let owner = violation
+                .owner
+                .as_deref()
+                .map(|owner| format!(" ({owner})"))
+                .unwrap_or_default();
+            let _ = writeln!(
+                out,
+                "  {marker} [{}] {}: {}:{}:{} {}{owner}",
+                violation.severity.as_str(),
+                violation.rule,
+                violation.file,
+                violation.line,
+                violation.column,
+                violation.function
+            );
+            let _ = writeln!(out, "      {}", violation.message);
+            if let Some(url) = &violation.remediation_url {
+                let _ = writeln!(out, "      see {url}");
+            }
+        }
+
+        if !self.exceptions.is_empty() {
+            out.push_str("\nExceptions:\n");
+        }
+        for exception in &self.exceptions {
+            let kind = match exception.kind {
+                ExceptionKind::Inline => "inline",
+                ExceptionKind::Baseline => "baseline",
+            };
+            let _ = writeln!(
+                out,
+                "  {kind} {}: {}:{} {} ticket={}",
+                exception.rule,
+                exception.file,
+                exception.line,
+                exception.function,
+                exception.ticket.as_deref().unwrap_or("none")
+            );
+            if let Some(problem) = &exception.problem {
+                let _ = writeln!(out, "      not honored: {problem}");
+            }
+        }
+
+        if !self.next_steps.is_empty() {
+            out.push_str("\nNext steps:\n");
+            for step in &self.next_steps {
+                let _ = writeln!(out, "  - {step}");
+            }
+        }
+        out
+    }
+}
+
+fn count(violations: &[Violation], status: ViolationStatus) -> usize {
+    violations.iter().filter(|v| v.status == status).count()
+}
+
+fn next_steps(
+    summary: &GateSummary,
+    violations: &[Violation],
+    exceptions: &[Exception],
+    options: &GateOptions,
+) -> Vec<String> {
+    let mut steps = Vec::new();
+    let rejected = exceptions.iter().filter(|e| e.problem.is_some()).count();
+    if rejected > 0 {
+        steps.push(format!(
+            "{rejected} exception(s) lack a valid ticket and are not honored; add ticket=<id> to the suppression or \"ticket\" to the baseline entry"
+        ));
+    }
+    if summary.blocking > 0 {
+        steps.push(format!(
+            "Fix the {} blocking violation(s) above, or accept them by re-running with --update-baseline and committing the baseline",
+            summary.blocking
+        ));
+    }
+    if violations
+        .iter()
+        .any(|v| v.status == ViolationStatus::New && v.unresolved)
+    {
+        steps.push(
+            "Arguments that could not be resolved are flagged by require_resolved rules; pass them as constants so they can be checked".to_string(),
+        );
+    }
+    if summary.new > summary.blocking {
+        steps.push(format!(
+            "{} non-blocking violation(s) are below the fail_on severity; review them before raising it",
+            summary.new - summary.blocking
+        ));
+    }
+    if options.baseline.is_none() && summary.violations > 0 && summary.blocking == 0 {
+        steps.push(
+            "No baseline in use; create one with --update-baseline to track accepted violations"
+                .to_string(),
+        );
+    }
+    steps
+}
+
+/// Fingerprint as computed by baselines of `version`, or `None` for unknown versions.
+///
+/// When the fingerprint algorithm changes, the previous algorithm stays here under its
+/// version so `argflow baseline migrate` can still match old baseline entries.
+pub fn fingerprint_for_version(
+    version: u32,
+    rule: &str,
+    relative_file: &str,
+    finding: &Finding,
+) -> Option<String> {
+    match version {
+        1 => Some(fingerprint(rule, relative_file, finding)),
+        _ => None,
+    }
+}
+
+/// Stable identity of a violation: rule, file, enclosing function, call and call text.
+/// Line numbers are deliberately left out.
+pub fn fingerprint(rule: &str, relative_file: &str, finding: &Finding) -> String {
+    let raw_text: String = finding.raw_text.split_whitespace().collect();
+    let parts = [
+        rule,
+        relative_file,
+        finding.enclosing_function.as_deref().unwrap_or(""),
+        &finding.full_name,
+        &raw_text,
+    ];
+
+    // FNV-1a: stable across Rust releases, unlike the std hasher
+    let mut hash: u64 = 0xcbf2_9ce4_8422_2325;
+    for part in parts {
+        for byte in part.bytes().chain(std::iter::once(0)) {
+            hash ^= u64::from(byte);
+            hash = hash.wrapping_mul(0x0000_0100_0000_01b3);
+        }
+    }
+    format!("{hash:016x}")
+}
+
+fn absolute_path(file: &str) -> PathBuf {
+    let path = Path::new(file);
+    path.canonicalize().unwrap_or_else(|_| path.to_path_buf())
+}
+
+/// `file` relative to `root`, with `/` separators so fingerprints match across platforms.
+pub fn relative_path(file: &str, root: &Path) -> String {
+    let path = absolute_path(file);
+    let root = root.canonicalize().unwrap_or_else(|_| root.to_path_buf());
+    let relative = match path.strip_prefix(&root) {
+        Ok(relative) if !relative.as_os_str().is_empty() => relative,
+        _ => path.as_path(),
+    };
+
+    relative
+        .components()
+        .filter_map(|c| match c {
+            Component::Normal(name) => Some(name.to_string_lossy().to_string()),
+            _ => None,
+        })
+        .collect::<Vec<_>>()
+        .join("/")
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::policy::{ExceptionPolicy, MODULE_RULE, WRAPPER_RULE};
+    use tempfile::TempDir;
+
+    fn md5_finding(file: &str, line: usize) -> Finding {
+        Finding::call(file, line, 5, "crypto/md5.Sum")
+            .with_package("md5")
+            .with_import_path("crypto/md5")
+            .with_algorithm("MD5")
+            .with_raw_text("md5.Sum(data)")
+            .in_function("Checksum")
+    }
+
+    fn policy() -> Policy {
+        serde_json::from_str(r#"{"rules": [{"id": "no-md5", "match": {"algorithm": "MD5"}}]}"#)
+            .unwrap()
+    }
+
+    fn options(baseline: Option<&Baseline>) -> GateOptions<'_> {
+        GateOptions {
+            root: PathBuf::from("/work/app"),
+            baseline,
+            changed_files: None,
+        }
+    }
+
+    #[test]
+    fn test_gate_fails_on_new_violation() {
+        let findings = [md5_finding("/work/app/pkg/sum.go", 12)];
+        let report = evaluate(&policy(), &findings, &options(None));
+
+        assert!(!report.passed);
+        assert_eq!(report.summary.blocking, 1);
+        assert_eq!(report.violations[0].file, "pkg/sum.go");
+        assert!(report.render_text().starts_with("argflow gate: FAILED"));
+        assert!(!report.next_steps.is_empty());
+    }
+
+    #[test]
+    fn test_rule_condition_narrows_matching_findings() {
+        let policy: Policy = serde_json::from_str(
+            r#"{"rules": [{
+                "id": "no-md5",
+                "match": {"algorithm": "MD5"},
+                "condition": "!file.contains(\"/testdata/\") && enclosing_function.startsWith(\"Check\")"
+            }]}"#,
+        )
+        .unwrap();
+        let mut helper = md5_finding("/work/app/pkg/sum.go", 30);
+        helper.enclosing_function = Some("digest".to_string());
+        let findings = [
+            md5_finding("/work/app/pkg/sum.go", 12),
+            md5_finding("/work/app/pkg/testdata/sum.go", 12),
+            helper,
+        ];
+
+        let report = evaluate(&policy, &findings, &options(None));
+
+        assert!(!report.passed);
+        let flagged: Vec<_> = report
+            .violations
+            .iter()
+            .map(|v| format!("{}:{}", v.file, v.line))
+            .collect();
+        assert_eq!(flagged, vec!["pkg/sum.go:12"]);
+
+        let invalid = serde_json::from_str::<Policy>(
+            r#"{"rules": [{"id": "no-md5", "condition": "algorithm =="}]}"#,
+        );
+        assert!(invalid.is_err());
+    }
+
+    #[test]
+    fn test_baselined_violation_survives_line_moves() {
+        let before = [md5_finding("/work/app/pkg/sum.go", 12)];
+        let first = evaluate(&policy(), &before, &options(None));
+        let baseline = Baseline::from_violations(&first.violations);
+
+        let after = [md5_finding("/work/app/pkg/sum.go", 40)];
+        let report = evaluate(&policy(), &after, &options(Some(&baseline)));
+
+        assert!(report.passed);
+        assert_eq!(report.violations[0].status, ViolationStatus::Baselined);
+    }
+
+    #[test]
+    fn test_suppressions_need_a_valid_ticket() {
+        let dir = TempDir::new().unwrap();
+        let file = dir.path().join("sum.go");
+        std::fs::write(
+            &file,
+            "package sum\n\n//argflow:ignore no-md5 reason=legacy ticket=SEC-7\nsum := md5.Sum(a)\n\n//argflow:ignore no-md5 reason=legacy\nsum = md5.Sum(b)\n",
+        )
+        .unwrap();
+        let file = file.to_string_lossy();
+        let findings = [md5_finding(&file, 4), md5_finding(&file, 7)];
+
+        // Without an exceptions section both suppressions are honored
+        let report = evaluate(&policy(), &findings, &options(None));
+        assert!(report.passed);
+        assert_eq!(report.summary.suppressed, 2);
+        assert_eq!(report.exceptions.len(), 2);
+
+        let mut strict = policy();
+        strict.exceptions = Some(ExceptionPolicy {
+            require_ticket: true,
+            ticket_pattern: Some("^SEC-[0-9]+$".to_string()),
+        });
+        let report = evaluate(&strict, &findings, &options(None));
+        assert!(!report.passed);
+        let statuses: Vec<_> = report.violations.iter().map(|v| v.status).collect();
+        assert_eq!(
+            statuses,
+            vec![ViolationStatus::Suppressed, ViolationStatus::New]
+        );
+        let tickets: Vec<_> = report
+            .exceptions
+            .iter()
+            .map(|e| (e.kind, e.ticket.as_deref(), e.problem.as_deref()))
+            .collect();
+        assert_eq!(
+            tickets,
+            vec![
+                (ExceptionKind::Inline, Some("SEC-7"), None),
+                (ExceptionKind::Inline, None, Some("no ticket")),
+            ]
+        );
+        let text = report.render_text();
+        assert!(text.contains("Exceptions:"));
+        assert!(text.contains("not honored: no ticket"));
+    }
+
+    #[test]
+    fn test_violations_outside_diff_do_not_block() {
+        let changed = HashSet::from([PathBuf::from("/work/app/pkg/other.go")]);
+        let findings = [md5_finding("/work/app/pkg/sum.go", 12)];
+        let report = evaluate(
+            &policy(),
+            &findings,
+            &GateOptions {
+                root: PathBuf::from("/work/app"),
+                baseline: None,
+                changed_files: Some(&changed),
+            },
+        );
+
+        assert!(report.passed);
+        assert_eq!(report.violations[0].status, ViolationStatus::OutsideDiff);
+    }
+
+    #[test]
+    fn test_severity_below_fail_on_does_not_block() {
+        let policy: Policy = serde_json::from_str(
+            r#"{"fail_on": "error", "rules": [{"id": "no-md5", "severity": "warning", "match": {"algorithm": "MD5"}}]}"#,
+        )
+        .unwrap();
+        let findings = [md5_finding("/work/app/pkg/sum.go", 12)];
+        let report = evaluate(&policy, &findings, &options(None));
+
+        assert!(report.passed);
+        assert_eq!(report.summary.new, 1);
+        assert!(!report.violations[0].blocking);
+    }
+
+    #[test]
+    fn test_module_policy_reports_calls_through_denied_providers() {
+        let policy: Policy = serde_json::from_str(
+            r#"{"modules": {"severity": "warning", "allow": ["std", "golang.org/x/crypto"]}}"#,
+        )
+        .unwrap();
+        let mut fork = md5_finding("/work/app/pkg/sum.go", 12);
+        fork.import_path = Some("github.com/old/md5".to_string());
+        fork.full_name = "github.com/old/md5.Sum".to_string();
+        let findings = [md5_finding("/work/app/pkg/sum.go", 10), fork];
+        let report = evaluate(&policy, &findings, &options(None));
+
+        assert!(report.passed);
+        assert_eq!(report.violations.len(), 1);
+        let violation = &report.violations[0];
+        assert_eq!(violation.rule, MODULE_RULE);
+        assert_eq!(violation.severity, Severity::Warning);
+        assert_eq!(violation.line, 12);
+        assert_eq!(
+            violation.message,
+            "github.com/old/md5.Sum calls into github.com/old/md5, which is not an allowed crypto provider"
+        );
+    }
+
+    #[test]
+    fn test_violations_link_to_remediation_guide() {
+        let findings = [md5_finding("/work/app/pkg/sum.go", 12)];
+        let report = evaluate(&policy(), &findings, &options(None));
+        assert_eq!(report.violations[0].remediation_url, None);
+
+        let policy: Policy = serde_json::from_str(
+            r#"{"remediation_url": "https://wiki.example.com/crypto/{rule}?alg={algorithm}",
+                "rules": [{"id": "no-md5", "match": {"algorithm": "MD5"}},
+                          {"id": "no-sha1", "match": {"algorithm": "SHA1"},
+                           "remediation_url": "https://wiki.example.com/hashing"}]}"#,
+        )
+        .unwrap();
+        policy.validate().unwrap();
+        let report = evaluate(&policy, &findings, &options(None));
+        assert_eq!(
+            report.violations[0].remediation_url.as_deref(),
+            Some("https://wiki.example.com/crypto/no-md5?alg=MD5")
+        );
+        assert!(report
+            .render_text()
+            .contains("see https://wiki.example.com/crypto/no-md5?alg=MD5"));
+
+        let mut sha1 = md5_finding("/work/app/pkg/sum.go", 20);
+        sha1.algorithm = Some("SHA1".to_string());
+        let report = evaluate(&policy, &[sha1], &options(None));
+        assert_eq!(
+            report.violations[0].remediation_url.as_deref(),
+            Some("https://wiki.example.com/hashing")
+        );
+
+        let invalid: Policy =
+            serde_json::from_str(r#"{"remediation_url": "https://wiki.example.com/{ticket}"}"#)
+                .unwrap();
+        assert!(invalid.validate().is_err());
+    }
+
+    #[test]
+    fn test_wrapper_policy_reports_direct_calls() {
+        let policy: Policy = serde_json::from_str(
+            r#"{"wrappers": [{"wrapper": "example.com/app/internal/hashutil.Checksum",
+                              "match": {"algorithm": "MD5"},
+                              "allow": ["legacy"]}]}"#,
+        )
+        .unwrap();
+        let mut direct = md5_finding("/work/app/pkg/sum.go", 12);
+        direct.enclosing_function = Some("Sum".to_string());
+        let findings = [
+            md5_finding("/work/app/internal/hashutil/sum.go", 10),
+            md5_finding("/work/app/legacy/sum.go", 10),
+            direct,
+        ];
+        let report = evaluate(&policy, &findings, &options(None));
+
+        assert!(!report.passed);
+        assert_eq!(report.violations.len(), 1);
+        let violation = &report.violations[0];
+        assert_eq!(violation.rule, WRAPPER_RULE);
+        assert_eq!(violation.line, 12);
+        assert_eq!(
+            violation.message,
+            "crypto/md5.Sum is called directly instead of through example.com/app/internal/hashutil.Checksum"
+        );
+    }
+
+    #[test]
+    fn test_ownership_tags_and_adjusts_severity() {
+        let policy: Policy = serde_json::from_str(
+            r#"{
+                "rules": [{"id": "no-md5", "match": {"algorithm": "MD5"}}],
+                "owners": [
+                    {"team": "@platform", "paths": ["/pkg/"]},
+                    {"team": "@legacy", "paths": ["/pkg/compat/"], "severity": {"no-md5": "warning"}}
+                ]
+            }"#,
+        )
+        .unwrap();
+        let findings = [
+            md5_finding("/work/app/pkg/sum.go", 12),
+            md5_finding("/work/app/pkg/compat/sum.go", 8),
+            md5_finding("/work/app/cmd/main.go", 3),
+        ];
+        let report = evaluate(&policy, &findings, &options(None));
+
+        let owners: Vec<_> = report
+            .violations
+            .iter()
+            .map(|v| (v.owner.as_deref(), v.severity, v.blocking))
+            .collect();
+        assert_eq!(
+            owners,
+            vec![
+                (Some("@platform"), Severity::Error, true),
+                (Some("@legacy"), Severity::Warning, false),
+                (None, Severity::Error, true),
+            ]
+        );
+
+        let teams = report.by_owner(&options(None));
+        assert_eq!(teams.len(), 3);
+        assert!(teams[&Some("@legacy".to_string())].passed);
+        assert!(!teams[&Some("@platform".to_string())].passed);
+        assert_eq!(teams[&None].summary.violations, 1);
+    }
+}
@@ -0,0 +1,350 @@
+//! Violation message catalog, overridable by id.
+
+use std::collections::BTreeMap;
+use std::fs;
+use std::path::Path;
+
+use tracing::debug;
+
+use crate::error::PolicyError;
+
+/// Built-in templates by message id.
+pub const BUILTIN_MESSAGES: &[(&str, &str)] = &[
+    ("with-rule-message", "{message} ({detail})"),
+    ("not-allowed", "{function} is not allowed"),
+    ("parameter-nil", "{parameter} is nil"),
+    (
+        "parameter-nil-receiver",
+        "{parameter} is nil; receiver built by {chain}",
+    ),
+    ("parameter-unresolved", "{parameter} could not be resolved"),
+    (
+        "parameter-zero-fallback",
+        "{parameter} is {value} when {loader} fails (line {line}): the error {handling} and the zero value is used",
+    ),
+    ("parameter-below-min", "{parameter} is {value}, minimum is {min}"),
+    ("parameter-above-max", "{parameter} is {value}, maximum is {max}"),
+    (
+        "parameter-not-allowed",
+        "{parameter} is \"{value}\", allowed: {allowed}",
+    ),
+    ("salt-empty", "salt is empty ({expression})"),
+    ("salt-literal", "salt is a hard-coded literal ({expression})"),
+    (
+        "salt-unfilled",
+        "salt buffer {expression} is never filled from crypto/rand",
+    ),
+    ("salt-untraced", "salt origin could not be traced ({expression})"),
+    (
+        "salt-too-short",
+        "salt is {length} bytes ({expression}), minimum is {min}",
+    ),
+    (
+        "nonce-literal",
+        "nonce is a hard-coded literal ({expression}); it repeats on every message",
+    ),
+    (
+        "nonce-too-short",
+        "nonce is {length} bytes ({expression}), minimum is {min}",
+    ),
+    (
+        "key-too-short",
+        "key is {bits}-bit ({expression}), minimum is {min}-bit",
+    ),
+    (
+        "derivation-static",
+        "secret ({secret}) and salt ({salt}) are constants; the derived key is hardcoded",
+    ),
+    (
+        "tuning-without-floor",
+        "{variable} is tuned from {clock} with no floor; a fast or loaded machine can pick fewer than {min}",
+    ),
+    (
+        "tuning-floor-too-low",
+        "{variable} is tuned from {clock} with a floor of {floor}, minimum is {min}",
+    ),
+    (
+        "key-written-unencrypted",
+        "private key written to a file without encryption ({expression})",
+    ),
+    (
+        "key-returned-unencrypted",
+        "private key returned without encryption ({expression})",
+    ),
+    (
+        "key-sent-unencrypted",
+        "private key sent in an HTTP response without encryption ({expression})",
+    ),
+    (
+        "static-key-exchange",
+        "static private key {key} is reused across key exchanges; generate an ephemeral key per exchange",
+    ),
+    (
+        "static-key-exchange-from",
+        "static private key {key} ({origin}) is reused across key exchanges; generate an ephemeral key per exchange",
+    ),
+    (
+        "password-fast-hash",
+        "password {password} is hashed with {function}, a fast hash; use a password KDF such as argon2id, scrypt or bcrypt",
+    ),
+    (
+        "password-plaintext",
+        "password {password} is stored in plain text through {function} ({expression})",
+    ),
+    (
+        "secret-comparison",
+        "{operand} holds {material} and is compared with {function}, which is not constant time; use subtle.ConstantTimeCompare or hmac.Equal",
+    ),
+    (
+        "secret-comparison-from",
+        "{operand} holds {material} (from {provenance}) and is compared with {function}, which is not constant time; use subtle.ConstantTimeCompare or hmac.Equal",
+    ),
+    ("material-mac", "a MAC"),
+    ("material-key", "key material"),
+    ("material-declared", "a declared secret"),
+    (
+        "aead-key-not-rotated",
+        "{aead} seals every message under one key and nothing rotates it; add a rekey path before 2^32 messages",
+    ),
+    (
+        "aead-key-not-rotated-at",
+        "{aead} (constructed at line {line}) seals every message under one key and nothing rotates it; add a rekey path before 2^32 messages",
+    ),
+    (
+        "unauthenticated-encryption",
+        "{mode} encryption with no MAC over the ciphertext; add an HMAC (encrypt-then-MAC) or use an AEAD such as AES-GCM",
+    ),
+    ("unnamed-constructor", "constructor"),
+    (
+        "failure-nil-without-error",
+        "{constructor} returns nil without an error at line {line} ({text}); callers get a nil value",
+    ),
+    (
+        "failure-panic",
+        "{constructor} panics at line {line} ({text}) instead of returning an error",
+    ),
+    ("unnamed-function", "function"),
+    (
+        "crash-panic",
+        "{function} panics at line {line} ({text}) instead of returning an error; a transient failure takes the service down",
+    ),
+    (
+        "crash-fatal",
+        "{function} exits the process at line {line} ({text}) instead of returning an error; a transient failure takes the service down",
+    ),
+    ("selection-option", "{algorithm} (case {case})"),
+    (
+        "selection-disallowed",
+        "{function}({selector}) can select {options}; allowed: {allowed}",
+    ),
+    (
+        "module-not-allowed",
+        "{function} calls into {provider}, which is not an allowed crypto provider",
+    ),
+    (
+        "module-not-allowed-at",
+        "{function} calls into {provider}, which is not an allowed crypto provider, imported at line {line}",
+    ),
+    (
+        "random-modulo-bias",
+        "{expression} reduces a random value with %, which favors the low end of the range; use rand.Int(rand.Reader, max) for a uniform value",
+    ),
+    (
+        "random-bound-from-random",
+        "{function} draws below {expression}, which comes from another random value and skews the result toward small numbers",
+    ),
+    (
+        "random-predictable-secret",
+        "{function} from math/rand produces {expression}, which is predictable; use crypto/rand for secrets",
+    ),
+    (
+        "validity-too-long",
+        "{function} sets a {kind} of {actual} ({expression}), longer than the {limit} maximum",
+    ),
+    (
+        "wrapper-bypass",
+        "{function} is called directly instead of through {wrapper}",
+    ),
+];
+
+/// Message templates, the built-in ones unless a catalog file replaced them.
+#[derive(Debug, Clone, Default)]
+pub struct MessageCatalog {
+    overrides: BTreeMap<String, String>,
+}
+
+impl MessageCatalog {
+    /// Loads a JSON or YAML map of message ids to templates.
+    pub fn from_file(path: &Path) -> Result<Self, PolicyError> {
+        debug!(path = %path.display(), "loading message catalog");
+        let content = fs::read_to_string(path)
+            .map_err(|e| PolicyError::messages_error(path, e.to_string()))?;
+
+        let extension = path.extension().and_then(|e| e.to_str()).unwrap_or("");
+        let overrides: BTreeMap<String, String> = match extension {
+            "json" => serde_json::from_str(&content)
+                .map_err(|e| PolicyError::messages_error(path, e.to_string()))?,
+            "yaml" | "yml" => serde_yaml::from_str(&content)
+                .map_err(|e| PolicyError::messages_error(path, e.to_string()))?,
+            _ => {
+                return Err(PolicyError::UnsupportedFormat {
+                    format: extension.to_string(),
+                })
+            }
+        };
+
+        let catalog = MessageCatalog { overrides };
+        catalog
+            .validate()
+            .map_err(|message| PolicyError::messages_error(path, message))?;
+        Ok(catalog)
+    }
+
+    /// The template of `id` with its placeholders filled from `args`.
+    pub fn render(&self, id: &str, args: &[(&str, &str)]) -> String {
+        let template = self
+            .overrides
+            .get(id)
+            .map(String::as_str)
+            .or_else(|| builtin(id))
+            .unwrap_or(id);
+
+        let mut out = String::with_capacity(template.len());
+        let mut rest = template;
+        while let Some(open) = rest.find('{') {
+            out.push_str(&rest[..open]);
+            let after = &rest[open + 1..];
+            let value = after.find('}').and_then(|close| {
+                let name = &after[..close];
+                args.iter()
+                    .find(|(arg, _)| *arg == name)
+                    .map(|(_, value)| (*value, close))
+            });
+            match value {
+                Some((value, close)) => {
+                    out.push_str(value);
+                    rest = &after[close + 1..];
+                }
+                None => {
+                    out.push('{');
+                    rest = after;
+                }
+            }
+        }
+        out.push_str(rest);
+        out
+    }
+
+    fn validate(&self) -> Result<(), String> {
+        for (id, template) in &self.overrides {
+            let Some(original) = builtin(id) else {
+                return Err(format!("unknown message id '{id}'"));
+            };
+            let known = placeholders(original);
+            if let Some(unknown) = placeholders(template)
+                .into_iter()
+                .find(|name| !known.contains(name))
+            {
+                return Err(format!(
+                    "message '{id}' uses unknown placeholder {{{unknown}}}; available: {}",
+                    known
+                        .iter()
+                        .map(|name| format!("{{{name}}}"))
+                        .collect::<Vec<_>>()
+                        .join(", ")
+                ));
+            }
+        }
+        Ok(())
+    }
+}
+
+fn builtin(id: &str) -> Option<&'static str> {
+    BUILTIN_MESSAGES
+        .iter()
+        .find(|(builtin, _)| *builtin == id)
+        .map(|(_, template)| *template)
+}
+
+/// Names of the `{placeholder}`s in `template`.
+fn placeholders(template: &str) -> Vec<&str> {
+    template
+        .split('{')
+        .skip(1)
+        .filter_map(|part| part.split_once('}').map(|(name, _)| name))
+        .filter(|name| !name.is_empty() && name.chars().all(|c| c.is_ascii_lowercase() || c == '_'))
+        .collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::io::Write;
+
+    #[test]
+    fn test_render_builtin_and_override() {
+        let args = [
+            ("parameter", "iterations"),
+            ("value", "1000"),
+            ("min", "600000"),
+        ];
+        let builtin = MessageCatalog::default();
+        assert_eq!(
+            builtin.render("parameter-below-min", &args),
+            "iterations is 1000, minimum is 600000"
+        );
+
+        let catalog = MessageCatalog {
+            overrides: BTreeMap::from([(
+                "parameter-below-min".to_string(),
+                "{parameter} ist {value}, Minimum ist {min} {unbekannt}".to_string(),
+            )]),
+        };
+        assert_eq!(
+            catalog.render("parameter-below-min", &args),
+            "iterations ist 1000, Minimum ist 600000 {unbekannt}"
+        );
+        // Values are not expanded again
+        assert_eq!(
+            builtin.render("parameter-unresolved", &[("parameter", "{min}")]),
+            "{min} could not be resolved"
+        );
+    }
+
+    #[test]
+    fn test_catalog_file_is_validated() {
+        let dir = tempfile::tempdir().unwrap();
+        let write = |name: &str, content: &str| {
+            let path = dir.path().join(name);
+            fs::File::create(&path)
+                .unwrap()
+                .write_all(content.as_bytes())
+                .unwrap();
+            path
+        };
+
+        let path = write(
+            "de.json",
+            r#"{"salt-empty": "Salt ist leer ({expression})", "material-mac": "ein MAC"}"#,
+        );
+        let catalog = MessageCatalog::from_file(&path).unwrap();
+        assert_eq!(
+            catalog.render("salt-empty", &[("expression", "nil")]),
+            "Salt ist leer (nil)"
+        );
+        assert_eq!(catalog.render("material-key", &[]), "key material");
+
+        let unknown = write("unknown.json", r#"{"salt-emtpy": "leer"}"#);
+        let err = MessageCatalog::from_file(&unknown).unwrap_err().to_string();
+        assert!(err.contains("unknown message id 'salt-emtpy'"), "{err}");
+
+        let placeholder = write("placeholder.json", r#"{"salt-empty": "leer: {salt}"}"#);
+        let err = MessageCatalog::from_file(&placeholder)
+            .unwrap_err()
+            .to_string();
+        assert!(
+            err.contains("unknown placeholder {salt}; available: {expression}"),
+            "{err}"
+        );
+    }
+}
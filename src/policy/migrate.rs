@@ -0,0 +1,232 @@
+use std::collections::{BTreeMap, HashSet};
+use std::path::Path;
+
+use serde::Serialize;
+
+use crate::error::PolicyError;
+use crate::output::Finding;
+
+use super::baseline::{Baseline, BaselineEntry, BASELINE_VERSION};
+use super::gate::{fingerprint, fingerprint_for_version, relative_path};
+use super::rules::Policy;
+
+/// Old rule id to new rule id.
+pub type RuleRenames = BTreeMap<String, String>;
+
+/// Outcome of rewriting a baseline for renamed rules and the current fingerprint version.
+#[derive(Debug, Clone, Default, Serialize)]
+pub struct MigrationSummary {
+    pub from_version: u32,
+    pub to_version: u32,
+    /// Entries that still match a current violation and were carried over.
+    pub migrated: usize,
+    /// Carried-over entries whose rule id changed.
+    pub renamed: usize,
+    /// Entries that match no current violation; the code they accepted is gone.
+    pub stale: Vec<BaselineEntry>,
+}
+
+/// Reads renames from a JSON or YAML map of old id to new id.
+pub fn load_renames(path: &Path) -> Result<RuleRenames, PolicyError> {
+    let content = std::fs::read_to_string(path)
+        .map_err(|e| PolicyError::renames_read_error(path, e.to_string()))?;
+    // YAML is a superset of JSON, so one parser covers both
+    serde_yaml::from_str(&content).map_err(|e| PolicyError::renames_read_error(path, e.to_string()))
+}
+
+/// Rewrites `old` against the current findings.
+///
+/// Old fingerprints cannot be converted on their own since they hash the rule id and
+/// the call text, so each current violation is fingerprinted the way the old baseline
+/// would have (old rule id, old algorithm). Matches are re-keyed under the new rule id
+/// and the current algorithm; entries with no match are reported as stale.
+pub fn migrate_baseline(
+    old: &Baseline,
+    policy: &Policy,
+    findings: &[Finding],
+    root: &Path,
+    renames: &RuleRenames,
+) -> Result<(Baseline, MigrationSummary), PolicyError> {
+    if old.version > BASELINE_VERSION || old.version == 0 {
+        return Err(PolicyError::UnsupportedBaselineVersion {
+            found: old.version,
+            supported: BASELINE_VERSION,
+        });
+    }
+
+    let mut previous_ids: BTreeMap<&str, Vec<&str>> = BTreeMap::new();
+    for (old_id, new_id) in renames {
+        previous_ids.entry(new_id).or_default().push(old_id);
+    }
+
+    let mut entries = Vec::new();
+    let mut matched = HashSet::new();
+    let mut renamed = 0;
+
+    for finding in findings {
+        let file = relative_path(&finding.file, root);
+        for (rule, _, _) in policy.violations(finding) {
+            let candidates = previous_ids
+                .get(rule)
+                .into_iter()
+                .flatten()
+                .copied()
+                .chain(std::iter::once(rule));
+
+            for old_id in candidates {
+                let Some(old_fingerprint) =
+                    fingerprint_for_version(old.version, old_id, &file, finding)
+                else {
+                    continue;
+                };
+                if !old.contains(&old_fingerprint) {
+                    continue;
+                }
+                matched.insert(old_fingerprint.clone());
+                if old_id != rule {
+                    renamed += 1;
+                }
+                entries.push(BaselineEntry {
+                    fingerprint: fingerprint(rule, &file, finding),
+                    rule: rule.to_string(),
+                    file: file.clone(),
+                    function: finding.full_name.clone(),
+                    ticket: old.entry(&old_fingerprint).and_then(|e| e.ticket.clone()),
+                });
+                break;
+            }
+        }
+    }
+
+    let stale = old
+        .entries
+        .iter()
+        .filter(|e| !matched.contains(&e.fingerprint))
+        .cloned()
+        .collect();
+    let migrated = Baseline::new(entries);
+    let summary = MigrationSummary {
+        from_version: old.version,
+        to_version: BASELINE_VERSION,
+        migrated: migrated.len(),
+        renamed,
+        stale,
+    };
+    Ok((migrated, summary))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::policy::{FindingSelector, MessageCatalog, PolicyRule, Severity};
+
+    fn finding() -> Finding {
+        Finding::call("/work/app/sum.go", 10, 2, "crypto/md5.Sum")
+            .with_package("md5")
+            .with_import_path("crypto/md5")
+            .with_algorithm("MD5")
+            .with_raw_text("md5.Sum(data)")
+            .in_function("checksum")
+    }
+
+    fn policy(rule_id: &str) -> Policy {
+        Policy {
+            rules: vec![PolicyRule {
+                id: rule_id.to_string(),
+                message: None,
+                severity: Severity::Error,
+                remediation_url: None,
+                selector: FindingSelector {
+                    algorithm: Some("MD5".to_string()),
+                    ..FindingSelector::default()
+                },
+                condition: None,
+                parameter: None,
+                salt: None,
+                key: None,
+                nonce: None,
+                key_encoding: None,
+                key_exchange: None,
+                password_storage: None,
+                secret_comparison: None,
+                derivation: None,
+                aead_key: None,
+                encryption_mode: None,
+                failure: None,
+                crash: None,
+                selection: None,
+                random: None,
+                validity: None,
+            }],
+            fail_on: Severity::Error,
+            owners: Vec::new(),
+            modules: None,
+            wrappers: Vec::new(),
+            exceptions: None,
+            messages: None,
+            remediation_url: None,
+            catalog: MessageCatalog::default(),
+        }
+    }
+
+    fn baseline_for(rule_id: &str, findings: &[Finding]) -> Baseline {
+        let root = Path::new("/work/app");
+        Baseline::new(
+            findings
+                .iter()
+                .map(|f| {
+                    let file = relative_path(&f.file, root);
+                    BaselineEntry {
+                        fingerprint: fingerprint(rule_id, &file, f),
+                        rule: rule_id.to_string(),
+                        file,
+                        function: f.full_name.clone(),
+                        ticket: None,
+                    }
+                })
+                .collect(),
+        )
+    }
+
+    #[test]
+    fn test_migrates_renamed_rule() {
+        let findings = [finding()];
+        let old = baseline_for("no-md5", &findings);
+        let renames = RuleRenames::from([("no-md5".to_string(), "weak-hash".to_string())]);
+
+        let (migrated, summary) = migrate_baseline(
+            &old,
+            &policy("weak-hash"),
+            &findings,
+            Path::new("/work/app"),
+            &renames,
+        )
+        .unwrap();
+
+        assert_eq!(summary.migrated, 1);
+        assert_eq!(summary.renamed, 1);
+        assert!(summary.stale.is_empty());
+        assert_eq!(migrated.entries[0].rule, "weak-hash");
+        assert!(migrated.contains(&fingerprint("weak-hash", "sum.go", &findings[0])));
+    }
+
+    #[test]
+    fn test_reports_stale_entries() {
+        let mut gone = finding();
+        gone.raw_text = "md5.Sum(other)".to_string();
+        let old = baseline_for("no-md5", &[finding(), gone]);
+
+        let (migrated, summary) = migrate_baseline(
+            &old,
+            &policy("no-md5"),
+            &[finding()],
+            Path::new("/work/app"),
+            &RuleRenames::new(),
+        )
+        .unwrap();
+
+        assert_eq!(migrated.len(), 1);
+        assert_eq!(summary.renamed, 0);
+        assert_eq!(summary.stale.len(), 1);
+    }
+}
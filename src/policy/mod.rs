@@ -0,0 +1,43 @@
+//! Policy evaluation for CI gating.
+
+mod architecture;
+mod baseline;
+mod diff;
+mod exceptions;
+mod expression;
+mod extends;
+mod gate;
+mod messages;
+mod migrate;
+mod modules;
+mod owners;
+mod rules;
+mod suppression;
+mod wrappers;
+
+pub use architecture::{ArchitectureReport, ArchitectureSpec, Component, Deviation, DeviationKind};
+pub use baseline::{Baseline, BaselineEntry, BASELINE_VERSION};
+pub use diff::changed_files;
+pub use exceptions::{Exception, ExceptionKind, ExceptionPolicy};
+pub use expression::{Expression, ExpressionTest};
+pub use extends::PolicyBase;
+pub use gate::{
+    evaluate, fingerprint, fingerprint_for_version, relative_path, GateOptions, GateReport,
+    GateSummary, Violation, ViolationStatus,
+};
+pub use messages::{MessageCatalog, BUILTIN_MESSAGES};
+pub use migrate::{load_renames, migrate_baseline, MigrationSummary, RuleRenames};
+pub use modules::{ModulePolicy, MODULE_RULE, STDLIB_PROVIDER};
+pub use owners::{owner_of, OwnershipArea, ALL_RULES};
+pub use rules::{
+    AeadKeyConstraint, CrashConstraint, DerivationConstraint, EncryptionModeConstraint,
+    FailureConstraint, FindingSelector, KeyConstraint, KeyEncodingConstraint,
+    KeyExchangeConstraint, NonceConstraint, ParameterConstraint, Policy, PolicyRule,
+    RandomConstraint, SaltConstraint, SecretComparisonConstraint, SelectionConstraint, Severity,
+    ValidityConstraint, ValidityProfile,
+};
+pub use suppression::{
+    insert_suppressions, parse_suppression, rename_suppressed_rules, Suppression, PLACEHOLDER,
+    SUPPRESSION_MARKER,
+};
+pub use wrappers::{WrapperPolicy, WRAPPER_RULE};
@@ -0,0 +1,185 @@
+//! Crypto providers: which libraries findings may call into.
+
+use std::fs;
+
+use serde::{Deserialize, Serialize};
+
+use crate::output::Finding;
+
+use super::messages::MessageCatalog;
+use super::rules::Severity;
+
+/// Rule id of module violations unless the policy names one.
+pub const MODULE_RULE: &str = "crypto-modules";
+
+/// Provider name of standard library packages.
+pub const STDLIB_PROVIDER: &str = "std";
+
+/// Crypto libraries findings may go through, e.g.
+/// `{"allow": ["std", "golang.org/x/crypto"], "deny": ["github.com/old/crypto-fork"]}`.
+#[derive(Debug, Clone, Deserialize, Serialize)]
+pub struct ModulePolicy {
+    #[serde(default = "default_rule")]
+    pub id: String,
+    #[serde(default)]
+    pub message: Option<String>,
+    #[serde(default)]
+    pub severity: Severity,
+    /// Remediation guide link, a template like the policy's `remediation_url`.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub remediation_url: Option<String>,
+    #[serde(default)]
+    pub allow: Vec<String>,
+    #[serde(default)]
+    pub deny: Vec<String>,
+}
+
+fn default_rule() -> String {
+    MODULE_RULE.to_string()
+}
+
+impl ModulePolicy {
+    /// Returns why `finding` goes through a banned provider, with the import site, or `None`.
+    pub fn check(&self, finding: &Finding) -> Option<String> {
+        self.check_with(finding, &MessageCatalog::default())
+    }
+
+    /// Like `check`, with messages from `messages`.
+    pub fn check_with(&self, finding: &Finding, messages: &MessageCatalog) -> Option<String> {
+        let package = package_path(finding.import_path.as_deref()?);
+        let provider = if is_stdlib(package) {
+            STDLIB_PROVIDER
+        } else {
+            package
+        };
+        let denied = self.deny.iter().any(|entry| covers(entry, provider))
+            || (!self.allow.is_empty() && !self.allow.iter().any(|entry| covers(entry, provider)));
+        if !denied {
+            return None;
+        }
+
+        let args = [
+            ("function", finding.full_name.as_str()),
+            ("provider", provider),
+        ];
+        let detail = match import_line(&finding.file, package) {
+            Some(line) => {
+                let line = line.to_string();
+                let mut args = args.to_vec();
+                args.push(("line", &line));
+                messages.render("module-not-allowed-at", &args)
+            }
+            None => messages.render("module-not-allowed", &args),
+        };
+        Some(match &self.message {
+            Some(message) => messages.render(
+                "with-rule-message",
+                &[("message", message), ("detail", &detail)],
+            ),
+            None => detail,
+        })
+    }
+}
+
+/// `crypto/ecdh.PrivateKey` -> `crypto/ecdh`: method sinks are keyed by receiver type.
+fn package_path(import_path: &str) -> &str {
+    let last = import_path.rfind('/').map_or(0, |slash| slash + 1);
+    match import_path[last..].rfind('.') {
+        // `gopkg.in/yaml.v3` is a package; `yaml.v3.Node` is a type in it
+        Some(dot) if import_path[last + dot + 1..].starts_with(char::is_uppercase) => {
+            &import_path[..last + dot]
+        }
+        _ => import_path,
+    }
+}
+
+fn is_stdlib(package: &str) -> bool {
+    !package
+        .split('/')
+        .next()
+        .is_some_and(|first| first.contains('.'))
+}
+
+/// Whether the module `entry` is `provider` or contains it.
+fn covers(entry: &str, provider: &str) -> bool {
+    let entry = entry.trim_end_matches('/');
+    provider == entry
+        || provider
+            .strip_prefix(entry)
+            .is_some_and(|rest| rest.starts_with('/'))
+}
+
+/// Line of the import spec for `package` in `file`.
+fn import_line(file: &str, package: &str) -> Option<usize> {
+    let content = fs::read_to_string(file).ok()?;
+    let quoted = format!("\"{package}\"");
+    content
+        .lines()
+        .position(|line| line.contains(&quoted))
+        .map(|index| index + 1)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    fn finding(file: &str, import_path: &str, function: &str) -> Finding {
+        Finding::call(file, 9, 5, &format!("{import_path}.{function}"))
+            .with_import_path(import_path)
+    }
+
+    #[test]
+    fn test_allow_and_deny_lists() {
+        let policy: ModulePolicy = serde_json::from_str(
+            r#"{"allow": ["std", "golang.org/x/crypto", "github.com/old"],
+                "deny": ["github.com/old/crypto-fork"]}"#,
+        )
+        .unwrap();
+        assert_eq!(policy.id, MODULE_RULE);
+
+        for allowed in [
+            "crypto/sha256",
+            "crypto/ecdh.PrivateKey",
+            "golang.org/x/crypto/pbkdf2",
+            "github.com/old/tools",
+        ] {
+            assert_eq!(
+                policy.check(&finding("a.go", allowed, "New")),
+                None,
+                "{allowed}"
+            );
+        }
+        for denied in [
+            "github.com/old/crypto-fork/sha3",
+            "golang.org/x/cryptography",
+            "gopkg.in/square/go-jose.v2.Encrypter",
+        ] {
+            assert!(
+                policy.check(&finding("a.go", denied, "New")).is_some(),
+                "{denied}"
+            );
+        }
+    }
+
+    #[test]
+    fn test_violation_reports_import_site() {
+        let dir = TempDir::new().unwrap();
+        let file = dir.path().join("hash.go");
+        fs::write(
+            &file,
+            "package hash\n\nimport (\n\t\"crypto/sha256\"\n\tsha3 \"github.com/old/crypto-fork/sha3\"\n)\n",
+        )
+        .unwrap();
+        let file = file.to_string_lossy();
+
+        let policy: ModulePolicy =
+            serde_json::from_str(r#"{"deny": ["github.com/old/crypto-fork"]}"#).unwrap();
+        assert_eq!(
+            policy
+                .check(&finding(&file, "github.com/old/crypto-fork/sha3", "New256"))
+                .unwrap(),
+            "github.com/old/crypto-fork/sha3.New256 calls into github.com/old/crypto-fork/sha3, which is not an allowed crypto provider, imported at line 5"
+        );
+    }
+}
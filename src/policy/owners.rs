@@ -0,0 +1,145 @@
+//! Ownership areas: CODEOWNERS-style path patterns mapped to the team that owns them.
+
+use std::collections::BTreeMap;
+
+use regex::Regex;
+use serde::Deserialize;
+
+use super::rules::Severity;
+
+/// Key in [`OwnershipArea::severity`] that applies to every rule.
+pub const ALL_RULES: &str = "*";
+
+/// Files owned by one team, and how that team weighs violations in them.
+#[derive(Debug, Clone, Deserialize)]
+pub struct OwnershipArea {
+    /// Team handle, e.g. `@org/payments`.
+    pub team: String,
+    pub paths: Vec<String>,
+    /// Severity by rule id for violations in this area; `*` applies to every rule.
+    #[serde(default)]
+    pub severity: BTreeMap<String, Severity>,
+}
+
+impl OwnershipArea {
+    /// Whether `file`, relative to the scan root with `/` separators, is in this area.
+    pub fn owns(&self, file: &str) -> bool {
+        self.paths
+            .iter()
+            .any(|pattern| pattern_matches(pattern, file))
+    }
+
+    /// Severity of `rule` in this area, if the area overrides it.
+    pub fn severity_for(&self, rule: &str) -> Option<Severity> {
+        self.severity
+            .get(rule)
+            .or_else(|| self.severity.get(ALL_RULES))
+            .copied()
+    }
+}
+
+/// The area owning `file`: the last one with a matching pattern.
+pub fn owner_of<'a>(areas: &'a [OwnershipArea], file: &str) -> Option<&'a OwnershipArea> {
+    areas.iter().rev().find(|area| area.owns(file))
+}
+
+fn pattern_matches(pattern: &str, file: &str) -> bool {
+    pattern_regex(pattern).is_some_and(|regex| regex.is_match(file))
+}
+
+fn pattern_regex(pattern: &str) -> Option<Regex> {
+    let trimmed = pattern.trim().trim_end_matches('/');
+    // As in gitignore, a slash anywhere but the end anchors the pattern to the root
+    let anchored = trimmed.contains('/');
+    let trimmed = trimmed.trim_start_matches('/');
+    if trimmed.is_empty() {
+        return None;
+    }
+
+    let mut regex = String::from(if anchored { "^" } else { "^(?:.*/)?" });
+    let mut chars = trimmed.chars().peekable();
+    while let Some(c) = chars.next() {
+        match c {
+            '*' if chars.peek() == Some(&'*') => {
+                chars.next();
+                if chars.peek() == Some(&'/') {
+                    chars.next();
+                    regex.push_str("(?:.*/)?");
+                } else {
+                    regex.push_str(".*");
+                }
+            }
+            '*' => regex.push_str("[^/]*"),
+            '?' => regex.push_str("[^/]"),
+            c => regex.push_str(&regex::escape(&c.to_string())),
+        }
+    }
+    regex.push_str("(?:/.*)?$");
+    Regex::new(&regex).ok()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn area(team: &str, paths: &[&str]) -> OwnershipArea {
+        OwnershipArea {
+            team: team.to_string(),
+            paths: paths.iter().map(|p| p.to_string()).collect(),
+            severity: BTreeMap::new(),
+        }
+    }
+
+    #[test]
+    fn test_codeowners_patterns() {
+        assert!(pattern_matches(
+            "/internal/payments/",
+            "internal/payments/card.go"
+        ));
+        assert!(!pattern_matches(
+            "/internal/payments/",
+            "vendor/internal/payments/card.go"
+        ));
+        assert!(pattern_matches("payments", "services/payments/v2/card.go"));
+        assert!(pattern_matches("*.pb.go", "api/v1/token.pb.go"));
+        assert!(!pattern_matches("*.pb.go", "api/v1/token.go"));
+        assert!(pattern_matches(
+            "services/*/crypto",
+            "services/billing/crypto/seal.go"
+        ));
+        assert!(!pattern_matches(
+            "services/*/crypto",
+            "services/a/b/crypto/seal.go"
+        ));
+        assert!(pattern_matches(
+            "services/**/crypto",
+            "services/a/b/crypto/seal.go"
+        ));
+    }
+
+    #[test]
+    fn test_last_matching_area_wins() {
+        let areas = [
+            area("@platform", &["/services/"]),
+            area("@payments", &["/services/billing/"]),
+        ];
+        let owner = |file| owner_of(&areas, file).map(|a| a.team.as_str());
+
+        assert_eq!(owner("services/billing/seal.go"), Some("@payments"));
+        assert_eq!(owner("services/auth/token.go"), Some("@platform"));
+        assert_eq!(owner("cmd/main.go"), None);
+    }
+
+    #[test]
+    fn test_severity_override() {
+        let mut payments = area("@payments", &["/billing/"]);
+        payments.severity = BTreeMap::from([
+            ("no-md5".to_string(), Severity::Warning),
+            (ALL_RULES.to_string(), Severity::Error),
+        ]);
+
+        assert_eq!(payments.severity_for("no-md5"), Some(Severity::Warning));
+        assert_eq!(payments.severity_for("kdf-salt"), Some(Severity::Error));
+        assert_eq!(area("@auth", &["/auth/"]).severity_for("no-md5"), None);
+    }
+}
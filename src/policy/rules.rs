@@ -0,0 +1,2367 @@
+use std::fs;
+use std::path::{Path, PathBuf};
+
+use serde::{Deserialize, Serialize};
+use tracing::debug;
+
+use crate::error::PolicyError;
+use crate::output::Finding;
+use crate::scanner::{
+    BiasKind, ByteOrigin, ByteSource, CrashKind, FailureKind, KeyDestination, KeyLifetime,
+    PasswordStorageKind, SecretMaterial, ValidityKind,
+};
+
+use super::exceptions::ExceptionPolicy;
+use super::expression::Expression;
+use super::extends;
+use super::messages::MessageCatalog;
+use super::modules::ModulePolicy;
+use super::owners::OwnershipArea;
+use super::wrappers::WrapperPolicy;
+
+/// Thresholds of the zero-config quickstart scan, see `Policy::moderate`.
+const MODERATE_POLICY: &str = include_str!("moderate.yaml");
+
+/// How serious a policy violation is.
+#[derive(
+    Debug, Clone, Copy, Default, PartialEq, Eq, PartialOrd, Ord, Hash, Serialize, Deserialize,
+)]
+#[serde(rename_all = "lowercase")]
+pub enum Severity {
+    Info,
+    Warning,
+    #[default]
+    Error,
+}
+
+impl Severity {
+    pub fn as_str(&self) -> &'static str {
+        match self {
+            Severity::Info => "info",
+            Severity::Warning => "warning",
+            Severity::Error => "error",
+        }
+    }
+}
+
+/// A set of rules findings are checked against.
+#[derive(Debug, Clone, Default, Deserialize)]
+pub struct Policy {
+    #[serde(default)]
+    pub rules: Vec<PolicyRule>,
+    /// Lowest severity that fails the gate.
+    #[serde(default)]
+    pub fail_on: Severity,
+    /// Teams owning parts of the tree, CODEOWNERS-style; the last matching area wins.
+    #[serde(default)]
+    pub owners: Vec<OwnershipArea>,
+    /// Crypto libraries findings may call into.
+    #[serde(default)]
+    pub modules: Option<ModulePolicy>,
+    /// Approved wrappers that must stand between the code and the primitives they wrap.
+    #[serde(default)]
+    pub wrappers: Vec<WrapperPolicy>,
+    /// What suppressions and baseline entries must record to be honored.
+    #[serde(default)]
+    pub exceptions: Option<ExceptionPolicy>,
+    /// Message catalog replacing the built-in violation messages, relative to the policy.
+    #[serde(default)]
+    pub messages: Option<PathBuf>,
+    /// Remediation guide link for rules that set none, with `{rule}` and `{algorithm}`
+    /// placeholders, e.g. `https://wiki.example.com/crypto/{rule}`.
+    #[serde(default)]
+    pub remediation_url: Option<String>,
+    /// The catalog `messages` names, loaded by `from_file`.
+    #[serde(skip)]
+    pub catalog: MessageCatalog,
+}
+
+/// A single rule: which findings it applies to and, optionally, what their arguments must satisfy.
+///
+/// A rule without a `parameter`, `salt` or `key` constraint flags every matching finding
+/// (e.g. "no MD5").
+#[derive(Debug, Clone, Deserialize, Serialize)]
+pub struct PolicyRule {
+    pub id: String,
+    #[serde(default)]
+    pub message: Option<String>,
+    #[serde(default)]
+    pub severity: Severity,
+    /// Remediation guide link, a template like the policy's `remediation_url`.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub remediation_url: Option<String>,
+    #[serde(rename = "match", default)]
+    pub selector: FindingSelector,
+    /// CEL-style condition over the finding as the report writes it, e.g.
+    /// `algorithm == "MD5" && !file.contains("/testdata/")`, for what `match` cannot
+    /// express. The rule only applies to findings it matches.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub condition: Option<Expression>,
+    #[serde(default)]
+    pub parameter: Option<ParameterConstraint>,
+    #[serde(default)]
+    pub salt: Option<SaltConstraint>,
+    #[serde(default)]
+    pub nonce: Option<NonceConstraint>,
+    #[serde(default)]
+    pub key: Option<KeyConstraint>,
+    #[serde(default)]
+    pub key_encoding: Option<KeyEncodingConstraint>,
+    #[serde(default)]
+    pub key_exchange: Option<KeyExchangeConstraint>,
+    #[serde(default)]
+    pub password_storage: Option<PasswordStorageConstraint>,
+    #[serde(default)]
+    pub secret_comparison: Option<SecretComparisonConstraint>,
+    #[serde(default)]
+    pub derivation: Option<DerivationConstraint>,
+    #[serde(default)]
+    pub aead_key: Option<AeadKeyConstraint>,
+    #[serde(default)]
+    pub encryption_mode: Option<EncryptionModeConstraint>,
+    #[serde(default)]
+    pub failure: Option<FailureConstraint>,
+    #[serde(default)]
+    pub crash: Option<CrashConstraint>,
+    #[serde(default)]
+    pub selection: Option<SelectionConstraint>,
+    #[serde(default)]
+    pub random: Option<RandomConstraint>,
+    #[serde(default)]
+    pub validity: Option<ValidityConstraint>,
+}
+
+/// Finding attributes a rule applies to. Every field that is set must match.
+#[derive(Debug, Clone, Default, Deserialize, Serialize)]
+pub struct FindingSelector {
+    pub algorithm: Option<String>,
+    pub function: Option<String>,
+    pub primitive: Option<String>,
+    pub finding_type: Option<String>,
+    pub operation: Option<String>,
+    /// Directories the calling file must be in, as path segments relative to any
+    /// ancestor, e.g. `internal/token`. Empty means anywhere.
+    #[serde(default)]
+    pub paths: Vec<String>,
+}
+
+/// Bounds on one argument of a finding, e.g. `{"name": "arg2", "min": 600000}`, or on
+/// every argument of a value category, e.g. `{"category": "iteration-count", "min": 600000}`.
+#[derive(Debug, Clone, Deserialize, Serialize)]
+pub struct ParameterConstraint {
+    #[serde(default)]
+    pub name: String,
+    /// Semantic category of the arguments to check, whatever sink they belong to.
+    pub category: Option<String>,
+    pub min: Option<i64>,
+    pub max: Option<i64>,
+    #[serde(default)]
+    pub allowed: Vec<String>,
+    /// Treat arguments that could not be resolved as violations.
+    #[serde(default)]
+    pub require_resolved: bool,
+    /// Treat a literal `nil`/`None`/`null` argument as a violation, e.g. AEAD associated data.
+    #[serde(default)]
+    pub non_nil: bool,
+}
+
+/// Requirements on the salt of key-derivation findings, e.g. `{"min_length": 16}`.
+///
+/// Empty, literal and never-filled salts always violate. Salts read from `crypto/rand`
+/// or from storage (a struct field or decoded value) comply if long enough.
+#[derive(Debug, Clone, Default, Deserialize, Serialize)]
+pub struct SaltConstraint {
+    /// Minimum salt length in bytes, checked when the length is known. Defaults to the
+    /// KDF's own minimum, 16 bytes for PBKDF2, scrypt and Argon2.
+    pub min_length: Option<usize>,
+    /// Treat salts whose origin could not be traced (e.g. parameters) as violations.
+    #[serde(default)]
+    pub require_traced: bool,
+}
+
+/// Requirements on the nonce of AEAD `Seal` and `Open` findings, e.g. `{"min_length": 12}`.
+///
+/// Hard-coded nonces always violate: the same nonce under the same key repeats on every
+/// message.
+#[derive(Debug, Clone, Default, Deserialize, Serialize)]
+pub struct NonceConstraint {
+    /// Minimum nonce length in bytes, checked when the length is known. Defaults to the
+    /// AEAD's nonce size: 12 bytes for GCM and ChaCha20-Poly1305, 24 for XChaCha20-Poly1305.
+    pub min_length: Option<usize>,
+}
+
+/// Requirements on the traced key of cipher and MAC findings, e.g. `{"min_bits": 256}`.
+#[derive(Debug, Clone, Default, Deserialize, Serialize)]
+pub struct KeyConstraint {
+    /// Minimum key size the consuming call receives, checked when the size is known.
+    pub min_bits: Option<usize>,
+}
+
+/// Requirements on the inputs of key-derivation findings, e.g. `{"forbid_static": true}`.
+#[derive(Debug, Clone, Default, Deserialize, Serialize)]
+pub struct DerivationConstraint {
+    /// Flag calls whose secret and salt are both literals or constants: the derived key
+    /// is the same on every run, a hardcoded key in disguise.
+    #[serde(default)]
+    pub forbid_static: bool,
+    /// Flag work factors tuned from wall-clock timing at startup that are not raised to
+    /// at least this value afterwards. Floors named by a constant are not checked.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub tuning_floor: Option<i64>,
+}
+
+/// Requirements on private keys encoded by `x509.Marshal*PrivateKey` and `pem.Encode`,
+/// e.g. `{"require_encryption": true}`.
+#[derive(Debug, Clone, Default, Deserialize, Serialize)]
+pub struct KeyEncodingConstraint {
+    /// Flag keys written to a file, returned, or sent in an HTTP response without a
+    /// passphrase.
+    #[serde(default)]
+    pub require_encryption: bool,
+}
+
+/// Requirements on the private key of ECDH, X25519 and NaCl box exchanges, e.g.
+/// `{"require_ephemeral": true}`.
+#[derive(Debug, Clone, Default, Deserialize, Serialize)]
+pub struct KeyExchangeConstraint {
+    /// Flag exchanges whose private key is held in a package-level variable and reused
+    /// by every session, which gives up forward secrecy.
+    #[serde(default)]
+    pub require_ephemeral: bool,
+}
+
+/// How passwords may not be stored, e.g. `{"forbid_fast_hash": true, "forbid_plaintext": true}`.
+#[derive(Debug, Clone, Default, Deserialize, Serialize)]
+pub struct PasswordStorageConstraint {
+    /// Flag passwords hashed with SHA-256, MD5 and other one-shot hashes instead of a KDF.
+    #[serde(default)]
+    pub forbid_fast_hash: bool,
+    /// Flag passwords marshaled, written to a file or stored in a database as is.
+    #[serde(default)]
+    pub forbid_plaintext: bool,
+}
+
+/// How MACs and key material may be compared, e.g. `{"require_constant_time": true}`.
+#[derive(Debug, Clone, Default, Deserialize, Serialize)]
+pub struct SecretComparisonConstraint {
+    /// Flag `bytes.Equal`, `reflect.DeepEqual` and `==` on secrets, which return at the
+    /// first differing byte.
+    #[serde(default)]
+    pub require_constant_time: bool,
+}
+
+/// Requirements on the key behind AEAD `Seal` calls, e.g. `{"require_rotation": true}`.
+#[derive(Debug, Clone, Default, Deserialize, Serialize)]
+pub struct AeadKeyConstraint {
+    /// Flag calls sealing with an AEAD held in a package-level variable or singleton
+    /// that no function other than its constructor ever replaces.
+    #[serde(default)]
+    pub require_rotation: bool,
+}
+
+/// Requirements on CBC, CTR, OFB and CFB encryption, e.g. `{"require_authentication": true}`.
+#[derive(Debug, Clone, Default, Deserialize, Serialize)]
+pub struct EncryptionModeConstraint {
+    /// Flag modes whose ciphertext no HMAC or Poly1305 MAC covers in the same function.
+    #[serde(default)]
+    pub require_authentication: bool,
+}
+
+/// Failure paths forbidden in the `(T, error)` constructor around a finding, e.g.
+/// `{"nil_without_error": true, "panic": true}`.
+#[derive(Debug, Clone, Default, Deserialize, Serialize)]
+pub struct FailureConstraint {
+    /// `return nil, nil`: callers get a nil block or AEAD and no error to check.
+    #[serde(default)]
+    pub nil_without_error: bool,
+    /// `panic(...)` instead of returning the error.
+    #[serde(default)]
+    pub panic: bool,
+}
+
+/// Panics and fatal exits forbidden in library code around a finding, e.g.
+/// `{"panic": true, "fatal": true}`. Files of package `main` are never flagged.
+#[derive(Debug, Clone, Default, Deserialize, Serialize)]
+pub struct CrashConstraint {
+    /// `panic(...)` or `log.Panic*`: a transient entropy or key failure unwinds the caller.
+    #[serde(default)]
+    pub panic: bool,
+    /// `log.Fatal*`: exits the whole process, skipping deferred calls.
+    #[serde(default)]
+    pub fatal: bool,
+}
+
+/// How random numbers may be drawn, e.g. `{"forbid_bias": true, "forbid_predictable": true}`.
+#[derive(Debug, Clone, Default, Deserialize, Serialize)]
+pub struct RandomConstraint {
+    /// Flag random values reduced with `%` by a modulus that is not a power of two, and
+    /// draws whose bound comes from another random value.
+    #[serde(default)]
+    pub forbid_bias: bool,
+    /// Flag `math/rand` values that end up in tokens, passwords, keys and other secrets.
+    #[serde(default)]
+    pub forbid_predictable: bool,
+}
+
+/// Maximum certificate lifetimes, key rotation intervals and key cache TTLs, e.g.
+/// `{"profile": "cabf"}` or `{"max_certificate_lifetime": "90d", "max_cache_ttl": "12h"}`.
+///
+/// Limits are durations in `s`, `m`, `h` or `d`. A profile supplies the limits the
+/// constraint does not set itself.
+#[derive(Debug, Clone, Default, Deserialize, Serialize)]
+pub struct ValidityConstraint {
+    #[serde(default)]
+    pub profile: Option<ValidityProfile>,
+    #[serde(default)]
+    pub max_certificate_lifetime: Option<String>,
+    #[serde(default)]
+    pub max_rotation_interval: Option<String>,
+    #[serde(default)]
+    pub max_cache_ttl: Option<String>,
+}
+
+/// Published validity limits a rule can adopt by name.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Deserialize, Serialize)]
+pub enum ValidityProfile {
+    /// CA/Browser Forum Baseline Requirements: TLS certificates valid at most 398 days.
+    #[serde(rename = "cabf")]
+    Cabf,
+    /// The CA/Browser Forum's 2029 limit of 47 days (ballot SC-081).
+    #[serde(rename = "cabf-2029")]
+    Cabf2029,
+    /// NIST SP 800-57 Part 1: symmetric keys used to protect data for at most two years.
+    #[serde(rename = "nist-sp800-57")]
+    NistSp80057,
+}
+
+/// Algorithms a registry function may select among, e.g. `{"allowed": ["SHA-256"]}`.
+///
+/// A dispatcher's selector is not constant, so every case it registers must comply. The
+/// violation is reported once, on the first case that selects a disallowed algorithm.
+#[derive(Debug, Clone, Default, Deserialize, Serialize)]
+pub struct SelectionConstraint {
+    pub allowed: Vec<String>,
+}
+
+impl Policy {
+    pub fn from_file(path: &Path) -> Result<Self, PolicyError> {
+        debug!(path = %path.display(), "loading policy");
+        let content = fs::read_to_string(path)
+            .map_err(|e| PolicyError::policy_file_read_error(path, e.to_string()))?;
+
+        let extension = path.extension().and_then(|e| e.to_str()).unwrap_or("");
+        let value: serde_json::Value = match extension {
+            "json" => serde_json::from_str(&content)
+                .map_err(|e| PolicyError::policy_parse_error(path, e.to_string()))?,
+            "yaml" | "yml" => serde_yaml::from_str(&content)
+                .map_err(|e| PolicyError::policy_parse_error(path, e.to_string()))?,
+            _ => {
+                return Err(PolicyError::UnsupportedFormat {
+                    format: extension.to_string(),
+                })
+            }
+        };
+        let dir = path.parent().unwrap_or(Path::new(""));
+        let value = extends::resolve(value, dir)?;
+        let mut policy: Policy = serde_json::from_value(value)
+            .map_err(|e| PolicyError::policy_parse_error(path, e.to_string()))?;
+
+        policy.validate()?;
+        if let Some(messages) = &policy.messages {
+            policy.catalog = MessageCatalog::from_file(&dir.join(messages))?;
+        }
+        Ok(policy)
+    }
+
+    /// The bundled moderate thresholds: broken hashes and ciphers, RSA keys under 2048
+    /// bits, PBKDF2 under 100k iterations, hard-coded salts and nonces, and predictable
+    /// or timing-unsafe secrets. The quickstart scan checks findings against them.
+    pub fn moderate() -> Result<Self, PolicyError> {
+        let policy: Policy = serde_yaml::from_str(MODERATE_POLICY)
+            .map_err(|e| PolicyError::policy_parse_error("moderate.yaml", e.to_string()))?;
+        policy.validate()?;
+        Ok(policy)
+    }
+
+    /// Every rule `finding` violates, the module and wrapper policies included, as
+    /// `(id, severity, message)`.
+    ///
+    /// Findings in generated mocks violate nothing: test doubles never run in production.
+    pub fn violations<'a>(
+        &'a self,
+        finding: &'a Finding,
+    ) -> impl Iterator<Item = (&'a str, Severity, String)> + 'a {
+        let checked = finding.mock.is_none();
+        let rules = self
+            .rules
+            .iter()
+            .filter(move |_| checked)
+            .filter_map(|rule| {
+                rule.check_with(finding, &self.catalog)
+                    .map(|message| (rule.id.as_str(), rule.severity, message))
+            });
+        let modules = self
+            .modules
+            .iter()
+            .filter(move |_| checked)
+            .filter_map(|modules| {
+                modules
+                    .check_with(finding, &self.catalog)
+                    .map(|message| (modules.id.as_str(), modules.severity, message))
+            });
+        let wrappers = self
+            .wrappers
+            .iter()
+            .filter(move |_| checked)
+            .filter_map(|wrapper| {
+                wrapper
+                    .check_with(finding, &self.catalog)
+                    .map(|message| (wrapper.id.as_str(), wrapper.severity, message))
+            });
+        rules.chain(modules).chain(wrappers)
+    }
+
+    /// The remediation guide link for violations of `rule` by `finding`: the rule's
+    /// template, or the policy's, with `{rule}` and `{algorithm}` filled in.
+    pub fn remediation_url(&self, rule: &str, finding: &Finding) -> Option<String> {
+        let template = self
+            .rules
+            .iter()
+            .filter(|r| r.id == rule)
+            .map(|r| &r.remediation_url)
+            .chain(
+                self.modules
+                    .iter()
+                    .filter(|m| m.id == rule)
+                    .map(|m| &m.remediation_url),
+            )
+            .chain(
+                self.wrappers
+                    .iter()
+                    .filter(|w| w.id == rule)
+                    .map(|w| &w.remediation_url),
+            )
+            .find_map(Option::as_ref)
+            .or(self.remediation_url.as_ref())?;
+        let algorithm = finding.algorithm.as_deref().unwrap_or_default();
+        Some(
+            template
+                .replace("{rule}", &url_encode(rule))
+                .replace("{algorithm}", &url_encode(algorithm)),
+        )
+    }
+
+    pub fn validate(&self) -> Result<(), PolicyError> {
+        if let Some(template) = &self.remediation_url {
+            validate_url_template(template)
+                .map_err(|message| PolicyError::invalid_rule("remediation_url", message))?;
+        }
+        let templates = self
+            .rules
+            .iter()
+            .map(|r| (&r.id, &r.remediation_url))
+            .chain(self.modules.iter().map(|m| (&m.id, &m.remediation_url)))
+            .chain(self.wrappers.iter().map(|w| (&w.id, &w.remediation_url)))
+            .filter_map(|(id, template)| Some((id, template.as_ref()?)));
+        for (id, template) in templates {
+            validate_url_template(template)
+                .map_err(|message| PolicyError::invalid_rule(id, message))?;
+        }
+        if let Some(exceptions) = &self.exceptions {
+            exceptions.validate()?;
+        }
+        if let Some(modules) = &self.modules {
+            if modules.allow.is_empty() && modules.deny.is_empty() {
+                return Err(PolicyError::invalid_rule(
+                    &modules.id,
+                    "module policy allows and denies nothing",
+                ));
+            }
+        }
+        for wrapper in &self.wrappers {
+            wrapper
+                .validate()
+                .map_err(|message| PolicyError::invalid_rule(&wrapper.id, message))?;
+        }
+        for rule in &self.rules {
+            if let Some(constraint) = &rule.parameter {
+                if constraint.min.is_none()
+                    && constraint.max.is_none()
+                    && constraint.allowed.is_empty()
+                    && !constraint.require_resolved
+                    && !constraint.non_nil
+                {
+                    return Err(PolicyError::invalid_rule(
+                        &rule.id,
+                        "parameter constraint has no bounds",
+                    ));
+                }
+                if constraint.name.is_empty() == constraint.category.is_none() {
+                    return Err(PolicyError::invalid_rule(
+                        &rule.id,
+                        "parameter constraint needs either a name or a category",
+                    ));
+                }
+            }
+            if rule
+                .selection
+                .as_ref()
+                .is_some_and(|c| c.allowed.is_empty())
+            {
+                return Err(PolicyError::invalid_rule(
+                    &rule.id,
+                    "selection constraint allows nothing",
+                ));
+            }
+            if rule
+                .key_encoding
+                .as_ref()
+                .is_some_and(|c| !c.require_encryption)
+            {
+                return Err(PolicyError::invalid_rule(
+                    &rule.id,
+                    "key encoding constraint requires nothing",
+                ));
+            }
+            if rule
+                .key_exchange
+                .as_ref()
+                .is_some_and(|c| !c.require_ephemeral)
+            {
+                return Err(PolicyError::invalid_rule(
+                    &rule.id,
+                    "key exchange constraint requires nothing",
+                ));
+            }
+            if rule
+                .password_storage
+                .as_ref()
+                .is_some_and(|c| !c.forbid_fast_hash && !c.forbid_plaintext)
+            {
+                return Err(PolicyError::invalid_rule(
+                    &rule.id,
+                    "password storage constraint forbids nothing",
+                ));
+            }
+            if rule
+                .secret_comparison
+                .as_ref()
+                .is_some_and(|c| !c.require_constant_time)
+            {
+                return Err(PolicyError::invalid_rule(
+                    &rule.id,
+                    "secret comparison constraint requires nothing",
+                ));
+            }
+            if rule
+                .derivation
+                .as_ref()
+                .is_some_and(|c| !c.forbid_static && c.tuning_floor.is_none())
+            {
+                return Err(PolicyError::invalid_rule(
+                    &rule.id,
+                    "derivation constraint forbids nothing",
+                ));
+            }
+            if rule.aead_key.as_ref().is_some_and(|c| !c.require_rotation) {
+                return Err(PolicyError::invalid_rule(
+                    &rule.id,
+                    "AEAD key constraint requires nothing",
+                ));
+            }
+            if rule
+                .encryption_mode
+                .as_ref()
+                .is_some_and(|c| !c.require_authentication)
+            {
+                return Err(PolicyError::invalid_rule(
+                    &rule.id,
+                    "encryption mode constraint requires nothing",
+                ));
+            }
+            if let Some(constraint) = &rule.failure {
+                if !constraint.nil_without_error && !constraint.panic {
+                    return Err(PolicyError::invalid_rule(
+                        &rule.id,
+                        "failure constraint forbids nothing",
+                    ));
+                }
+            }
+            if rule.crash.as_ref().is_some_and(|c| !c.panic && !c.fatal) {
+                return Err(PolicyError::invalid_rule(
+                    &rule.id,
+                    "crash constraint forbids nothing",
+                ));
+            }
+            if rule
+                .random
+                .as_ref()
+                .is_some_and(|c| !c.forbid_bias && !c.forbid_predictable)
+            {
+                return Err(PolicyError::invalid_rule(
+                    &rule.id,
+                    "random constraint forbids nothing",
+                ));
+            }
+            if let Some(constraint) = &rule.validity {
+                constraint
+                    .validate()
+                    .map_err(|message| PolicyError::invalid_rule(&rule.id, message))?;
+            }
+        }
+        Ok(())
+    }
+}
+
+/// Placeholders a remediation URL template may use.
+const URL_PLACEHOLDERS: &[&str] = &["rule", "algorithm"];
+
+fn validate_url_template(template: &str) -> Result<(), String> {
+    let mut rest = template;
+    while let Some(start) = rest.find('{') {
+        let after = &rest[start + 1..];
+        let end = after
+            .find('}')
+            .ok_or_else(|| format!("remediation_url '{template}' has an unclosed placeholder"))?;
+        let name = &after[..end];
+        if !URL_PLACEHOLDERS.contains(&name) {
+            return Err(format!(
+                "remediation_url uses unknown placeholder {{{name}}}; available: {{rule}}, {{algorithm}}"
+            ));
+        }
+        rest = &after[end + 1..];
+    }
+    Ok(())
+}
+
+/// Percent-encodes everything but RFC 3986 unreserved characters, so `AES/GCM` stays
+/// one path segment.
+fn url_encode(value: &str) -> String {
+    let mut out = String::new();
+    for byte in value.bytes() {
+        if byte.is_ascii_alphanumeric() || matches!(byte, b'-' | b'_' | b'.' | b'~') {
+            out.push(byte as char);
+        } else {
+            out.push_str(&format!("%{byte:02X}"));
+        }
+    }
+    out
+}
+
+impl PolicyRule {
+    /// Returns why `finding` violates this rule, or `None` if it complies or does not apply.
+    pub fn check(&self, finding: &Finding) -> Option<String> {
+        self.check_with(finding, &MessageCatalog::default())
+    }
+
+    /// Like `check`, with messages from `messages`.
+    pub fn check_with(&self, finding: &Finding, messages: &MessageCatalog) -> Option<String> {
+        if !self.applies_to(finding) {
+            return None;
+        }
+
+        let detail = if self.parameter.is_none()
+            && self.salt.is_none()
+            && self.nonce.is_none()
+            && self.key.is_none()
+            && self.key_encoding.is_none()
+            && self.key_exchange.is_none()
+            && self.password_storage.is_none()
+            && self.secret_comparison.is_none()
+            && self.derivation.is_none()
+            && self.aead_key.is_none()
+            && self.encryption_mode.is_none()
+            && self.failure.is_none()
+            && self.crash.is_none()
+            && self.selection.is_none()
+            && self.random.is_none()
+            && self.validity.is_none()
+        {
+            messages.render("not-allowed", &[("function", &finding.full_name)])
+        } else {
+            let parameter = self
+                .parameter
+                .as_ref()
+                .and_then(|c| c.check(finding, messages));
+            let salt = || self.salt.as_ref().and_then(|c| c.check(finding, messages));
+            let nonce = || self.nonce.as_ref().and_then(|c| c.check(finding, messages));
+            let key = || self.key.as_ref().and_then(|c| c.check(finding, messages));
+            let key_encoding = || {
+                self.key_encoding
+                    .as_ref()
+                    .and_then(|c| c.check(finding, messages))
+            };
+            let key_exchange = || {
+                self.key_exchange
+                    .as_ref()
+                    .and_then(|c| c.check(finding, messages))
+            };
+            let password_storage = || {
+                self.password_storage
+                    .as_ref()
+                    .and_then(|c| c.check(finding, messages))
+            };
+            let secret_comparison = || {
+                self.secret_comparison
+                    .as_ref()
+                    .and_then(|c| c.check(finding, messages))
+            };
+            let derivation = || {
+                self.derivation
+                    .as_ref()
+                    .and_then(|c| c.check(finding, messages))
+            };
+            let aead_key = || {
+                self.aead_key
+                    .as_ref()
+                    .and_then(|c| c.check(finding, messages))
+            };
+            let encryption_mode = || {
+                self.encryption_mode
+                    .as_ref()
+                    .and_then(|c| c.check(finding, messages))
+            };
+            let failure = || {
+                self.failure
+                    .as_ref()
+                    .and_then(|c| c.check(finding, messages))
+            };
+            let crash = || self.crash.as_ref().and_then(|c| c.check(finding, messages));
+            let selection = || {
+                self.selection
+                    .as_ref()
+                    .and_then(|c| c.check(finding, messages))
+            };
+            let random = || {
+                self.random
+                    .as_ref()
+                    .and_then(|c| c.check(finding, messages))
+            };
+            let validity = || {
+                self.validity
+                    .as_ref()
+                    .and_then(|c| c.check(finding, messages))
+            };
+            parameter
+                .or_else(salt)
+                .or_else(nonce)
+                .or_else(key)
+                .or_else(key_encoding)
+                .or_else(key_exchange)
+                .or_else(password_storage)
+                .or_else(secret_comparison)
+                .or_else(derivation)
+                .or_else(aead_key)
+                .or_else(encryption_mode)
+                .or_else(failure)
+                .or_else(crash)
+                .or_else(selection)
+                .or_else(random)
+                .or_else(validity)?
+        };
+
+        Some(match &self.message {
+            Some(message) => messages.render(
+                "with-rule-message",
+                &[("message", message), ("detail", &detail)],
+            ),
+            None => detail,
+        })
+    }
+
+    /// Whether the rule flags `finding` because a constrained argument could not be
+    /// resolved.
+    pub fn flags_unresolved(&self, finding: &Finding) -> bool {
+        self.applies_to(finding)
+            && self
+                .parameter
+                .as_ref()
+                .is_some_and(|c| c.require_resolved && c.is_unresolved(finding))
+    }
+
+    /// Whether `finding` matches the rule's selector and condition.
+    fn applies_to(&self, finding: &Finding) -> bool {
+        self.selector.matches(finding)
+            && self.condition.as_ref().is_none_or(|condition| {
+                serde_json::to_value(finding).is_ok_and(|report| condition.matches(&report))
+            })
+    }
+}
+
+impl FindingSelector {
+    pub fn matches(&self, finding: &Finding) -> bool {
+        fn field_matches(expected: &Option<String>, actual: Option<&str>) -> bool {
+            match expected {
+                None => true,
+                Some(expected) => {
+                    actual.is_some_and(|actual| actual.eq_ignore_ascii_case(expected))
+                }
+            }
+        }
+
+        field_matches(&self.algorithm, finding.algorithm.as_deref())
+            && field_matches(&self.function, Some(&finding.full_name))
+            && field_matches(&self.primitive, finding.primitive.as_deref())
+            && field_matches(&self.finding_type, finding.finding_type.as_deref())
+            && field_matches(&self.operation, finding.operation.as_deref())
+            && (self.paths.is_empty() || self.paths.iter().any(|p| in_directory(&finding.file, p)))
+    }
+}
+
+/// Whether `file` sits in a directory whose path ends with the segments of `dir`, at any
+/// depth below it: `internal/token` matches `/src/app/internal/token/v2/seal.go`.
+pub(super) fn in_directory(file: &str, dir: &str) -> bool {
+    let file = file.replace('\\', "/");
+    let parent = file.rsplit_once('/').map_or("", |(parent, _)| parent);
+    let dir = dir.trim_matches('/');
+    !dir.is_empty() && format!("/{parent}/").contains(&format!("/{dir}/"))
+}
+
+impl ParameterConstraint {
+    fn check(&self, finding: &Finding, messages: &MessageCatalog) -> Option<String> {
+        self.arguments(finding)
+            .into_iter()
+            .find_map(|name| self.check_argument(finding, name, messages))
+    }
+
+    /// The arguments of `finding` the constraint applies to: the named one, or those of
+    /// its category.
+    fn arguments<'a>(&'a self, finding: &'a Finding) -> Vec<&'a str> {
+        match &self.category {
+            Some(category) => finding
+                .parameter_categories
+                .iter()
+                .filter(|(_, c)| c.eq_ignore_ascii_case(category))
+                .map(|(name, _)| name.as_str())
+                .collect(),
+            None => vec![self.name.as_str()],
+        }
+    }
+
+    fn check_argument(
+        &self,
+        finding: &Finding,
+        name: &str,
+        messages: &MessageCatalog,
+    ) -> Option<String> {
+        let value = finding.parameters.get(name)?;
+
+        if self.non_nil && is_nil(value) {
+            return Some(match finding.receiver_chain.as_slice() {
+                [] => messages.render("parameter-nil", &[("parameter", name)]),
+                chain => messages.render(
+                    "parameter-nil-receiver",
+                    &[("parameter", name), ("chain", &chain.join(" -> "))],
+                ),
+            });
+        }
+
+        if let Some(fallback) = finding
+            .zero_fallbacks
+            .iter()
+            .find(|fallback| fallback.argument == name)
+            .filter(|fallback| self.rejects_zero(fallback.zero.as_deref()))
+        {
+            return Some(messages.render(
+                "parameter-zero-fallback",
+                &[
+                    ("parameter", name),
+                    ("value", fallback.zero.as_deref().unwrap_or("zero")),
+                    ("loader", &fallback.loader),
+                    ("line", &fallback.line.to_string()),
+                    ("handling", fallback.handling.describe()),
+                ],
+            ));
+        }
+
+        let (ints, strings) = possible_values(value);
+        if ints.is_empty() && strings.is_empty() {
+            return self
+                .require_resolved
+                .then(|| messages.render("parameter-unresolved", &[("parameter", name)]));
+        }
+
+        // Every possible value must comply, so a single failing branch is a violation
+        if let Some(min) = self.min {
+            if let Some(bad) = ints.iter().find(|&&v| v < min) {
+                return Some(messages.render(
+                    "parameter-below-min",
+                    &[
+                        ("parameter", name),
+                        ("value", &bad.to_string()),
+                        ("min", &min.to_string()),
+                    ],
+                ));
+            }
+        }
+        if let Some(max) = self.max {
+            if let Some(bad) = ints.iter().find(|&&v| v > max) {
+                return Some(messages.render(
+                    "parameter-above-max",
+                    &[
+                        ("parameter", name),
+                        ("value", &bad.to_string()),
+                        ("max", &max.to_string()),
+                    ],
+                ));
+            }
+        }
+        if !self.allowed.is_empty() {
+            if let Some(bad) = strings
+                .iter()
+                .find(|s| !self.allowed.iter().any(|a| a.eq_ignore_ascii_case(s)))
+            {
+                return Some(messages.render(
+                    "parameter-not-allowed",
+                    &[
+                        ("parameter", name),
+                        ("value", bad),
+                        ("allowed", &self.allowed.join(", ")),
+                    ],
+                ));
+            }
+        }
+        None
+    }
+
+    /// Whether the zero value an argument falls back to violates the bounds. A zero of
+    /// unknown type is taken for `0` by numeric bounds.
+    fn rejects_zero(&self, zero: Option<&str>) -> bool {
+        match zero {
+            Some("\"\"") => !self.allowed.is_empty() && !self.allowed.iter().any(|a| a.is_empty()),
+            Some("0") | None => {
+                self.min.is_some_and(|min| min > 0) || self.max.is_some_and(|max| max < 0)
+            }
+            Some(_) => false,
+        }
+    }
+
+    /// Whether a constrained argument of `finding` has no resolved value.
+    fn is_unresolved(&self, finding: &Finding) -> bool {
+        self.arguments(finding).into_iter().any(|name| {
+            finding.parameters.get(name).is_some_and(|value| {
+                let (ints, strings) = possible_values(value);
+                ints.is_empty() && strings.is_empty()
+            })
+        })
+    }
+}
+
+/// The integer and string values an argument may take.
+fn possible_values(value: &serde_json::Value) -> (Vec<i64>, Vec<&str>) {
+    let ints = match value {
+        serde_json::Value::Number(n) => n.as_i64().into_iter().collect(),
+        serde_json::Value::Array(items) => items.iter().filter_map(|v| v.as_i64()).collect(),
+        _ => Vec::new(),
+    };
+    let strings = match value {
+        serde_json::Value::String(s) => vec![s.as_str()],
+        serde_json::Value::Array(items) => items.iter().filter_map(|v| v.as_str()).collect(),
+        _ => Vec::new(),
+    };
+    (ints, strings)
+}
+
+impl SaltConstraint {
+    fn check(&self, finding: &Finding, messages: &MessageCatalog) -> Option<String> {
+        let salt = finding.salt.as_ref()?;
+        let expression = [("expression", salt.expression.as_str())];
+        match salt.origin {
+            ByteOrigin::Empty => return Some(messages.render("salt-empty", &expression)),
+            ByteOrigin::Literal => return Some(messages.render("salt-literal", &expression)),
+            ByteOrigin::Unfilled => return Some(messages.render("salt-unfilled", &expression)),
+            ByteOrigin::Untraced if self.require_traced => {
+                return Some(messages.render("salt-untraced", &expression))
+            }
+            ByteOrigin::Random | ByteOrigin::Stored | ByteOrigin::Untraced => {}
+        }
+        match (self.min_length.or(salt.minimum), salt.length) {
+            (Some(min), Some(length)) if length < min => Some(messages.render(
+                "salt-too-short",
+                &[
+                    ("length", &length.to_string()),
+                    ("expression", length_source(salt)),
+                    ("min", &min.to_string()),
+                ],
+            )),
+            _ => None,
+        }
+    }
+}
+
+impl NonceConstraint {
+    fn check(&self, finding: &Finding, messages: &MessageCatalog) -> Option<String> {
+        let nonce = finding.nonce.as_ref()?;
+        if nonce.origin == ByteOrigin::Literal {
+            return Some(messages.render(
+                "nonce-literal",
+                &[("expression", nonce.expression.as_str())],
+            ));
+        }
+        let min = self.min_length.or(nonce.minimum)?;
+        let length = nonce.length?;
+        (length < min).then(|| {
+            messages.render(
+                "nonce-too-short",
+                &[
+                    ("length", &length.to_string()),
+                    ("expression", length_source(nonce)),
+                    ("min", &min.to_string()),
+                ],
+            )
+        })
+    }
+}
+
+/// Where a traced buffer's length came from: the constant or call it was resolved from,
+/// or the expression the trace ended at.
+fn length_source(source: &ByteSource) -> &str {
+    source.length_from.as_deref().unwrap_or(&source.expression)
+}
+
+impl KeyConstraint {
+    fn check(&self, finding: &Finding, messages: &MessageCatalog) -> Option<String> {
+        let key = finding.key.as_ref()?;
+        let bits = key.length? * 8;
+        let min = self.min_bits?;
+        (bits < min).then(|| {
+            messages.render(
+                "key-too-short",
+                &[
+                    ("bits", &bits.to_string()),
+                    ("expression", &key.expression),
+                    ("min", &min.to_string()),
+                ],
+            )
+        })
+    }
+}
+
+impl DerivationConstraint {
+    fn check(&self, finding: &Finding, messages: &MessageCatalog) -> Option<String> {
+        self.check_static(finding, messages)
+            .or_else(|| self.check_tuning(finding, messages))
+    }
+
+    fn check_static(&self, finding: &Finding, messages: &MessageCatalog) -> Option<String> {
+        let is_static =
+            |source: &ByteSource| matches!(source.origin, ByteOrigin::Literal | ByteOrigin::Empty);
+        let secret = finding.secret.as_ref().filter(|s| is_static(s))?;
+        let salt = finding.salt.as_ref().filter(|s| is_static(s))?;
+        self.forbid_static.then(|| {
+            messages.render(
+                "derivation-static",
+                &[("secret", &secret.expression), ("salt", &salt.expression)],
+            )
+        })
+    }
+
+    fn check_tuning(&self, finding: &Finding, messages: &MessageCatalog) -> Option<String> {
+        let minimum = self.tuning_floor?;
+        let tuning = finding.iteration_tuning.as_ref()?;
+        let min = minimum.to_string();
+        let Some(floor) = &tuning.floor else {
+            return Some(messages.render(
+                "tuning-without-floor",
+                &[
+                    ("variable", &tuning.variable),
+                    ("clock", &tuning.clock),
+                    ("min", &min),
+                ],
+            ));
+        };
+        let value: i64 = floor.replace('_', "").parse().ok()?;
+        (value < minimum).then(|| {
+            messages.render(
+                "tuning-floor-too-low",
+                &[
+                    ("variable", &tuning.variable),
+                    ("clock", &tuning.clock),
+                    ("floor", floor),
+                    ("min", &min),
+                ],
+            )
+        })
+    }
+}
+
+impl KeyEncodingConstraint {
+    fn check(&self, finding: &Finding, messages: &MessageCatalog) -> Option<String> {
+        let encoding = finding.key_encoding.as_ref()?;
+        if !self.require_encryption || encoding.encrypted {
+            return None;
+        }
+        let id = match encoding.destination {
+            KeyDestination::File => "key-written-unencrypted",
+            KeyDestination::Returned => "key-returned-unencrypted",
+            KeyDestination::Response => "key-sent-unencrypted",
+            KeyDestination::Pem | KeyDestination::Untraced => return None,
+        };
+        Some(messages.render(id, &[("expression", &encoding.expression)]))
+    }
+}
+
+impl KeyExchangeConstraint {
+    fn check(&self, finding: &Finding, messages: &MessageCatalog) -> Option<String> {
+        let exchange = finding.key_exchange.as_ref()?;
+        if !self.require_ephemeral || exchange.lifetime != KeyLifetime::Static {
+            return None;
+        }
+        let key = ("key", exchange.private_key.as_str());
+        Some(match &exchange.origin {
+            Some(origin) => messages.render("static-key-exchange-from", &[key, ("origin", origin)]),
+            None => messages.render("static-key-exchange", &[key]),
+        })
+    }
+}
+
+impl PasswordStorageConstraint {
+    fn check(&self, finding: &Finding, messages: &MessageCatalog) -> Option<String> {
+        let storage = finding.password_storage.as_ref()?;
+        let password = ("password", storage.password.as_str());
+        let function = ("function", finding.full_name.as_str());
+        match storage.kind {
+            PasswordStorageKind::FastHash if self.forbid_fast_hash => {
+                Some(messages.render("password-fast-hash", &[password, function]))
+            }
+            PasswordStorageKind::Plaintext if self.forbid_plaintext => Some(messages.render(
+                "password-plaintext",
+                &[password, function, ("expression", &storage.expression)],
+            )),
+            _ => None,
+        }
+    }
+}
+
+impl SecretComparisonConstraint {
+    fn check(&self, finding: &Finding, messages: &MessageCatalog) -> Option<String> {
+        let comparison = finding
+            .secret_comparison
+            .as_ref()
+            .filter(|_| self.require_constant_time)?;
+        let material = messages.render(
+            match comparison.material {
+                SecretMaterial::Mac => "material-mac",
+                SecretMaterial::Key => "material-key",
+                SecretMaterial::Declared => "material-declared",
+            },
+            &[],
+        );
+        let args = [
+            ("operand", comparison.operand.as_str()),
+            ("material", &material),
+            ("function", &finding.full_name),
+        ];
+        Some(match comparison.provenance.get(1..) {
+            Some(chain) if !chain.is_empty() => {
+                let provenance = chain.join(" <- ");
+                let mut args = args.to_vec();
+                args.push(("provenance", &provenance));
+                messages.render("secret-comparison-from", &args)
+            }
+            _ => messages.render("secret-comparison", &args),
+        })
+    }
+}
+
+impl AeadKeyConstraint {
+    fn check(&self, finding: &Finding, messages: &MessageCatalog) -> Option<String> {
+        let aead = finding
+            .long_lived_aead
+            .as_ref()
+            .filter(|_| self.require_rotation)?;
+        let name = ("aead", aead.aead.as_str());
+        Some(match aead.construction_line {
+            Some(line) => messages.render(
+                "aead-key-not-rotated-at",
+                &[name, ("line", &line.to_string())],
+            ),
+            None => messages.render("aead-key-not-rotated", &[name]),
+        })
+    }
+}
+
+impl EncryptionModeConstraint {
+    fn check(&self, finding: &Finding, messages: &MessageCatalog) -> Option<String> {
+        let mode = finding
+            .unauthenticated_mode
+            .as_ref()
+            .filter(|_| self.require_authentication)?;
+        let cipher = match (&mode.cipher, mode.key_size) {
+            (Some(cipher), Some(bits)) => format!("{cipher}-{bits}-{}", mode.mode),
+            (Some(cipher), None) => format!("{cipher}-{}", mode.mode),
+            _ => mode.mode.clone(),
+        };
+        Some(messages.render("unauthenticated-encryption", &[("mode", &cipher)]))
+    }
+}
+
+impl FailureConstraint {
+    fn check(&self, finding: &Finding, messages: &MessageCatalog) -> Option<String> {
+        let path = finding.failure_paths.iter().find(|path| match path.kind {
+            FailureKind::NilWithoutError => self.nil_without_error,
+            FailureKind::Panic => self.panic,
+        })?;
+        let constructor = match &finding.enclosing_function {
+            Some(function) => function.clone(),
+            None => messages.render("unnamed-constructor", &[]),
+        };
+        let id = match path.kind {
+            FailureKind::NilWithoutError => "failure-nil-without-error",
+            FailureKind::Panic => "failure-panic",
+        };
+        Some(messages.render(
+            id,
+            &[
+                ("constructor", &constructor),
+                ("line", &path.line.to_string()),
+                ("text", &path.text),
+            ],
+        ))
+    }
+}
+
+impl CrashConstraint {
+    fn check(&self, finding: &Finding, messages: &MessageCatalog) -> Option<String> {
+        let path = finding.crash_paths.iter().find(|path| match path.kind {
+            CrashKind::Panic => self.panic,
+            CrashKind::Fatal => self.fatal,
+        })?;
+        let function = match &finding.enclosing_function {
+            Some(function) => function.clone(),
+            None => messages.render("unnamed-function", &[]),
+        };
+        let id = match path.kind {
+            CrashKind::Panic => "crash-panic",
+            CrashKind::Fatal => "crash-fatal",
+        };
+        Some(messages.render(
+            id,
+            &[
+                ("function", &function),
+                ("line", &path.line.to_string()),
+                ("text", &path.text),
+            ],
+        ))
+    }
+}
+
+impl RandomConstraint {
+    fn check(&self, finding: &Finding, messages: &MessageCatalog) -> Option<String> {
+        let bias = finding.random_bias.as_ref()?;
+        let (id, forbidden) = match bias.kind {
+            BiasKind::Modulo => ("random-modulo-bias", self.forbid_bias),
+            BiasKind::RandomBound => ("random-bound-from-random", self.forbid_bias),
+            BiasKind::PredictableSecret => ("random-predictable-secret", self.forbid_predictable),
+        };
+        forbidden.then(|| {
+            messages.render(
+                id,
+                &[
+                    ("function", &finding.full_name),
+                    ("expression", &bias.expression),
+                ],
+            )
+        })
+    }
+}
+
+const SECONDS_PER_DAY: u64 = 86_400;
+
+impl ValidityProfile {
+    fn limit(self, kind: ValidityKind) -> Option<u64> {
+        match (self, kind) {
+            (ValidityProfile::Cabf, ValidityKind::CertificateLifetime) => {
+                Some(398 * SECONDS_PER_DAY)
+            }
+            (ValidityProfile::Cabf2029, ValidityKind::CertificateLifetime) => {
+                Some(47 * SECONDS_PER_DAY)
+            }
+            (ValidityProfile::NistSp80057, ValidityKind::RotationInterval) => {
+                Some(730 * SECONDS_PER_DAY)
+            }
+            _ => None,
+        }
+    }
+}
+
+impl ValidityConstraint {
+    fn check(&self, finding: &Finding, messages: &MessageCatalog) -> Option<String> {
+        finding.validity.iter().find_map(|period| {
+            let limit = self.limit(period.kind)?;
+            (period.seconds > limit).then(|| {
+                messages.render(
+                    "validity-too-long",
+                    &[
+                        ("function", &finding.full_name),
+                        ("kind", period.kind.as_str()),
+                        ("actual", &format_duration(period.seconds)),
+                        ("expression", &period.expression),
+                        ("limit", &format_duration(limit)),
+                    ],
+                )
+            })
+        })
+    }
+
+    /// The longest `kind` of period allowed, in seconds.
+    fn limit(&self, kind: ValidityKind) -> Option<u64> {
+        let explicit = match kind {
+            ValidityKind::CertificateLifetime => &self.max_certificate_lifetime,
+            ValidityKind::RotationInterval => &self.max_rotation_interval,
+            ValidityKind::CacheTtl => &self.max_cache_ttl,
+            ValidityKind::Duration => &None,
+        };
+        explicit
+            .as_deref()
+            .and_then(parse_duration)
+            .or_else(|| self.profile.and_then(|profile| profile.limit(kind)))
+    }
+
+    fn validate(&self) -> Result<(), String> {
+        let limits = [
+            &self.max_certificate_lifetime,
+            &self.max_rotation_interval,
+            &self.max_cache_ttl,
+        ];
+        for limit in limits.iter().copied().flatten() {
+            if parse_duration(limit).is_none() {
+                return Err(format!(
+                    "validity limit '{limit}' is not a duration like 90d, 12h or 30m"
+                ));
+            }
+        }
+        if self.profile.is_none() && limits.iter().all(|limit| limit.is_none()) {
+            return Err("validity constraint limits nothing".to_string());
+        }
+        Ok(())
+    }
+}
+
+/// Seconds in a duration such as `398d`, `12h`, `30m` or `45s`.
+fn parse_duration(value: &str) -> Option<u64> {
+    let value = value.trim();
+    let unit = match value.chars().last()? {
+        's' => 1,
+        'm' => 60,
+        'h' => 3_600,
+        'd' => SECONDS_PER_DAY,
+        _ => return None,
+    };
+    let count: u64 = value[..value.len() - 1].parse().ok()?;
+    count.checked_mul(unit)
+}
+
+/// `seconds` in the largest unit that divides it evenly: `398d`, `36h`, `90s`.
+fn format_duration(seconds: u64) -> String {
+    for (unit, size) in [("d", SECONDS_PER_DAY), ("h", 3_600), ("m", 60)] {
+        if seconds > 0 && seconds % size == 0 {
+            return format!("{}{unit}", seconds / size);
+        }
+    }
+    format!("{seconds}s")
+}
+
+impl SelectionConstraint {
+    fn check(&self, finding: &Finding, messages: &MessageCatalog) -> Option<String> {
+        let selection = finding.selection.as_ref()?;
+        let disallowed: Vec<_> = selection
+            .options
+            .iter()
+            .filter(|option| {
+                option.algorithm.as_deref().is_some_and(|algorithm| {
+                    !self
+                        .allowed
+                        .iter()
+                        .any(|a| a.eq_ignore_ascii_case(algorithm))
+                })
+            })
+            .collect();
+        if !selection.cases.contains(&disallowed.first()?.case) {
+            return None;
+        }
+
+        let options = disallowed
+            .iter()
+            .map(|option| {
+                messages.render(
+                    "selection-option",
+                    &[
+                        ("algorithm", option.algorithm.as_deref().unwrap_or_default()),
+                        ("case", &option.case),
+                    ],
+                )
+            })
+            .collect::<Vec<_>>()
+            .join(", ");
+        Some(messages.render(
+            "selection-disallowed",
+            &[
+                ("function", &selection.function),
+                ("selector", &selection.selector),
+                ("options", &options),
+                ("allowed", &self.allowed.join(", ")),
+            ],
+        ))
+    }
+}
+
+/// Whether any possible value of an argument is a literal nil.
+fn is_nil(value: &serde_json::Value) -> bool {
+    match value {
+        serde_json::Value::String(s) => matches!(s.as_str(), "nil" | "None" | "null"),
+        serde_json::Value::Array(items) => items.iter().any(is_nil),
+        _ => false,
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::output::{AlgorithmSelection, SelectionOption};
+    use crate::scanner::{
+        AeadScope, CrashPath, ErrorHandling, FailurePath, IterationTuning, KeyEncoding,
+        KeyExchange, LongLivedAead, PasswordStorage, RandomBias, SecretComparison, SecretMaterial,
+        UnauthenticatedMode, ValidityPeriod, ZeroFallback,
+    };
+    use std::collections::BTreeMap;
+
+    fn finding(full_name: &str, algorithm: Option<&str>, arg2: serde_json::Value) -> Finding {
+        Finding {
+            algorithm: algorithm.map(str::to_string),
+            ..Finding::call("pkg/kdf/kdf.go", 10, 2, full_name)
+                .with_package("pbkdf2")
+                .with_import_path("golang.org/x/crypto/pbkdf2")
+                .with_parameter("arg2", arg2)
+        }
+    }
+
+    fn parse(json: &str) -> Policy {
+        let policy: Policy = serde_json::from_str(json).unwrap();
+        policy.validate().unwrap();
+        policy
+    }
+
+    #[test]
+    fn test_rule_without_constraint_flags_every_match() {
+        let policy = parse(r#"{"rules": [{"id": "no-md5", "match": {"algorithm": "md5"}}]}"#);
+        let rule = &policy.rules[0];
+
+        let md5 = finding("crypto/md5.Sum", Some("MD5"), serde_json::json!(null));
+        assert_eq!(
+            rule.check(&md5).as_deref(),
+            Some("crypto/md5.Sum is not allowed")
+        );
+
+        let sha = finding(
+            "crypto/sha256.Sum256",
+            Some("SHA-256"),
+            serde_json::json!(null),
+        );
+        assert_eq!(rule.check(&sha), None);
+    }
+
+    #[test]
+    fn test_mock_findings_violate_nothing() {
+        let policy = parse(r#"{"rules": [{"id": "no-md5", "match": {"algorithm": "md5"}}]}"#);
+        let mut md5 = finding("crypto/md5.Sum", Some("MD5"), serde_json::json!(null));
+        assert_eq!(policy.violations(&md5).count(), 1);
+
+        md5.mock = Some("mockery".to_string());
+        assert_eq!(policy.violations(&md5).count(), 0);
+    }
+
+    #[test]
+    fn test_parameter_minimum() {
+        let policy = parse(
+            r#"{"rules": [{
+                "id": "pbkdf2-iterations",
+                "message": "PBKDF2 needs at least 600k iterations",
+                "match": {"function": "golang.org/x/crypto/pbkdf2.Key"},
+                "parameter": {"name": "arg2", "min": 600000}
+            }]}"#,
+        );
+        let rule = &policy.rules[0];
+
+        let weak = finding(
+            "golang.org/x/crypto/pbkdf2.Key",
+            None,
+            serde_json::json!([600000, 10000]),
+        );
+        assert_eq!(
+            rule.check(&weak).as_deref(),
+            Some("PBKDF2 needs at least 600k iterations (arg2 is 10000, minimum is 600000)")
+        );
+
+        let strong = finding(
+            "golang.org/x/crypto/pbkdf2.Key",
+            None,
+            serde_json::json!(600000),
+        );
+        assert_eq!(rule.check(&strong), None);
+    }
+
+    #[test]
+    fn test_parameter_category() {
+        let policy = parse(
+            r#"{"rules": [{
+                "id": "kdf-iterations",
+                "parameter": {"category": "iteration-count", "min": 600000}
+            }]}"#,
+        );
+        let rule = &policy.rules[0];
+
+        let mut weak = finding("crypto/pbkdf2.Key", None, serde_json::json!(10000));
+        assert_eq!(rule.check(&weak), None);
+        weak.parameter_categories =
+            BTreeMap::from([("arg2".to_string(), "iteration-count".to_string())]);
+        assert_eq!(
+            rule.check(&weak).as_deref(),
+            Some("arg2 is 10000, minimum is 600000")
+        );
+
+        let policy: Policy = serde_json::from_str(
+            r#"{"rules": [{"id": "both", "parameter": {"name": "arg2", "category": "key-size", "min": 16}}]}"#,
+        )
+        .unwrap();
+        assert!(policy.validate().is_err());
+    }
+
+    #[test]
+    fn test_moderate_policy() {
+        let policy = Policy::moderate().unwrap();
+
+        let md5 = finding("crypto/md5.Sum", Some("MD5"), serde_json::json!(null));
+        let ids: Vec<_> = policy.violations(&md5).map(|(id, _, _)| id).collect();
+        assert_eq!(ids, vec!["weak-hash-md5"]);
+
+        let mut pbkdf2 = finding(
+            "golang.org/x/crypto/pbkdf2.Key",
+            Some("PBKDF2"),
+            serde_json::json!(10000),
+        );
+        pbkdf2.parameter_categories =
+            BTreeMap::from([("arg2".to_string(), "iteration-count".to_string())]);
+        let violations: Vec<_> = policy.violations(&pbkdf2).collect();
+        assert_eq!(
+            violations,
+            vec![(
+                "pbkdf2-iterations",
+                Severity::Warning,
+                "PBKDF2 needs at least 100k iterations (arg2 is 10000, minimum is 100000)"
+                    .to_string()
+            )]
+        );
+
+        pbkdf2.parameters = BTreeMap::from([("arg2".to_string(), serde_json::json!(310000))]);
+        assert_eq!(policy.violations(&pbkdf2).count(), 0);
+    }
+
+    #[test]
+    fn test_unresolved_parameter() {
+        let policy = parse(
+            r#"{"rules": [{
+                "id": "pbkdf2-iterations",
+                "match": {"function": "golang.org/x/crypto/pbkdf2.Key"},
+                "parameter": {"name": "arg2", "min": 600000, "require_resolved": true}
+            }]}"#,
+        );
+        let unresolved = finding(
+            "golang.org/x/crypto/pbkdf2.Key",
+            None,
+            serde_json::json!({"source": "function_parameter", "value": null}),
+        );
+        assert_eq!(
+            policy.rules[0].check(&unresolved).as_deref(),
+            Some("arg2 could not be resolved")
+        );
+    }
+
+    #[test]
+    fn test_non_nil_associated_data_in_package() {
+        let policy = parse(
+            r#"{"rules": [{
+                "id": "token-aad",
+                "match": {"primitive": "aead", "operation": "encrypt", "paths": ["internal/token"]},
+                "parameter": {"name": "arg3", "non_nil": true}
+            }]}"#,
+        );
+        let rule = &policy.rules[0];
+
+        let mut seal = finding("gcm.Seal", None, serde_json::json!(null));
+        seal.file = "/src/app/internal/token/envelope.go".to_string();
+        seal.primitive = Some("aead".to_string());
+        seal.operation = Some("encrypt".to_string());
+        seal.parameters
+            .insert("arg3".to_string(), serde_json::json!("nil"));
+        seal.receiver_chain = vec![
+            "aes.NewCipher(key)".to_string(),
+            "cipher.NewGCM(block)".to_string(),
+        ];
+        assert_eq!(
+            rule.check(&seal).as_deref(),
+            Some("arg3 is nil; receiver built by aes.NewCipher(key) -> cipher.NewGCM(block)")
+        );
+
+        seal.parameters
+            .insert("arg3".to_string(), serde_json::json!(["header", "nil"]));
+        assert!(rule.check(&seal).is_some());
+
+        seal.parameters
+            .insert("arg3".to_string(), serde_json::json!("header"));
+        assert_eq!(rule.check(&seal), None);
+
+        seal.parameters
+            .insert("arg3".to_string(), serde_json::json!("nil"));
+        seal.file = "/src/app/internal/cache/store.go".to_string();
+        assert_eq!(rule.check(&seal), None);
+    }
+
+    #[test]
+    fn test_salt_quality() {
+        let policy = parse(
+            r#"{"rules": [{
+                "id": "kdf-salt",
+                "match": {"primitive": "kdf"},
+                "salt": {"min_length": 16}
+            }]}"#,
+        );
+        let rule = &policy.rules[0];
+        let mut kdf = finding(
+            "golang.org/x/crypto/pbkdf2.Key",
+            None,
+            serde_json::json!(600000),
+        );
+        kdf.primitive = Some("kdf".to_string());
+        let mut check = |origin, length: Option<usize>, expression: &str| {
+            kdf.salt = Some(ByteSource {
+                origin,
+                length,
+                available: None,
+                length_from: None,
+                minimum: None,
+                expression: expression.to_string(),
+            });
+            rule.check(&kdf)
+        };
+
+        assert_eq!(
+            check(ByteOrigin::Literal, Some(6), r#"[]byte("pepper")"#).as_deref(),
+            Some(r#"salt is a hard-coded literal ([]byte("pepper"))"#)
+        );
+        assert_eq!(
+            check(ByteOrigin::Empty, Some(0), "nil").as_deref(),
+            Some("salt is empty (nil)")
+        );
+        assert_eq!(
+            check(ByteOrigin::Unfilled, Some(16), "salt").as_deref(),
+            Some("salt buffer salt is never filled from crypto/rand")
+        );
+        assert_eq!(
+            check(ByteOrigin::Random, Some(8), "make([]byte, 8)").as_deref(),
+            Some("salt is 8 bytes (make([]byte, 8)), minimum is 16")
+        );
+        assert_eq!(
+            check(ByteOrigin::Random, Some(16), "make([]byte, 16)"),
+            None
+        );
+        assert_eq!(check(ByteOrigin::Stored, None, "user.Salt"), None);
+        assert_eq!(check(ByteOrigin::Untraced, None, "salt"), None);
+    }
+
+    #[test]
+    fn test_nonce_length_defaults_to_aead_nonce_size() {
+        let policy = parse(
+            r#"{"rules": [{
+                "id": "aead-nonce",
+                "match": {"function": "crypto/cipher.AEAD.Seal"},
+                "nonce": {}
+            }]}"#,
+        );
+        let rule = &policy.rules[0];
+        let mut seal = finding("crypto/cipher.AEAD.Seal", None, serde_json::json!(null));
+        let mut check = |origin, length: Option<usize>, expression: &str| {
+            seal.nonce = Some(ByteSource {
+                origin,
+                length,
+                available: None,
+                length_from: Some("chacha20poly1305.NonceSize".to_string()),
+                minimum: Some(24),
+                expression: expression.to_string(),
+            });
+            rule.check(&seal)
+        };
+
+        assert_eq!(
+            check(
+                ByteOrigin::Random,
+                Some(12),
+                "make([]byte, chacha20poly1305.NonceSize)"
+            )
+            .as_deref(),
+            Some("nonce is 12 bytes (chacha20poly1305.NonceSize), minimum is 24")
+        );
+        assert_eq!(
+            check(
+                ByteOrigin::Literal,
+                Some(24),
+                r#"[]byte("fixed-nonce-value-123456")"#
+            )
+            .as_deref(),
+            Some(
+                r#"nonce is a hard-coded literal ([]byte("fixed-nonce-value-123456")); it repeats on every message"#
+            )
+        );
+        assert_eq!(
+            check(
+                ByteOrigin::Random,
+                Some(24),
+                "make([]byte, chacha20poly1305.NonceSizeX)"
+            ),
+            None
+        );
+    }
+
+    #[test]
+    fn test_key_minimum_bits() {
+        let policy = parse(
+            r#"{"rules": [{
+                "id": "content-key-size",
+                "match": {"function": "crypto/aes.NewCipher"},
+                "key": {"min_bits": 256}
+            }]}"#,
+        );
+        let rule = &policy.rules[0];
+        let mut cipher = finding("crypto/aes.NewCipher", None, serde_json::json!(null));
+        cipher.key = Some(ByteSource {
+            origin: ByteOrigin::Random,
+            length: Some(16),
+            available: None,
+            length_from: None,
+            minimum: None,
+            expression: "GenerateJOSEKey()".to_string(),
+        });
+        assert_eq!(
+            rule.check(&cipher).as_deref(),
+            Some("key is 128-bit (GenerateJOSEKey()), minimum is 256-bit")
+        );
+
+        cipher.key.as_mut().unwrap().length = Some(32);
+        assert_eq!(rule.check(&cipher), None);
+    }
+
+    #[test]
+    fn test_static_derivation_inputs() {
+        let policy = parse(
+            r#"{"rules": [{
+                "id": "static-kdf",
+                "match": {"primitive": "kdf"},
+                "derivation": {"forbid_static": true}
+            }]}"#,
+        );
+        let rule = &policy.rules[0];
+        let source = |origin, expression: &str| ByteSource {
+            origin,
+            length: None,
+            available: None,
+            length_from: None,
+            minimum: None,
+            expression: expression.to_string(),
+        };
+        let mut kdf = finding(
+            "golang.org/x/crypto/pbkdf2.Key",
+            None,
+            serde_json::json!(600000),
+        );
+        kdf.primitive = Some("kdf".to_string());
+        kdf.secret = Some(source(ByteOrigin::Literal, "masterPassword"));
+        kdf.salt = Some(source(ByteOrigin::Literal, "[]byte(\"pepper\")"));
+        assert_eq!(
+            rule.check(&kdf).as_deref(),
+            Some("secret (masterPassword) and salt ([]byte(\"pepper\")) are constants; the derived key is hardcoded")
+        );
+
+        kdf.secret = Some(source(ByteOrigin::Untraced, "password"));
+        assert_eq!(rule.check(&kdf), None);
+        kdf.secret = Some(source(ByteOrigin::Literal, "masterPassword"));
+        kdf.salt = Some(source(ByteOrigin::Random, "salt"));
+        assert_eq!(rule.check(&kdf), None);
+    }
+
+    #[test]
+    fn test_clock_tuned_iterations_floor() {
+        let policy = parse(
+            r#"{"rules": [{
+                "id": "tuned-kdf",
+                "match": {"primitive": "kdf"},
+                "derivation": {"tuning_floor": 600000}
+            }]}"#,
+        );
+        let rule = &policy.rules[0];
+        let mut kdf = finding(
+            "golang.org/x/crypto/pbkdf2.Key",
+            None,
+            serde_json::json!(null),
+        );
+        kdf.primitive = Some("kdf".to_string());
+        assert_eq!(rule.check(&kdf), None);
+
+        kdf.iteration_tuning = Some(IterationTuning {
+            variable: "iterations".to_string(),
+            clock: "time.Since".to_string(),
+            floor: None,
+            floor_line: None,
+        });
+        assert_eq!(
+            rule.check(&kdf).as_deref(),
+            Some("iterations is tuned from time.Since with no floor; a fast or loaded machine can pick fewer than 600000")
+        );
+
+        let tuning = kdf.iteration_tuning.as_mut().unwrap();
+        tuning.floor = Some("100_000".to_string());
+        tuning.floor_line = Some(30);
+        assert_eq!(
+            rule.check(&kdf).as_deref(),
+            Some("iterations is tuned from time.Since with a floor of 100_000, minimum is 600000")
+        );
+        kdf.iteration_tuning.as_mut().unwrap().floor = Some("600_000".to_string());
+        assert_eq!(rule.check(&kdf), None);
+        kdf.iteration_tuning.as_mut().unwrap().floor = Some("minIterations".to_string());
+        assert_eq!(rule.check(&kdf), None);
+    }
+
+    #[test]
+    fn test_unencrypted_private_key_encoding() {
+        let policy = parse(
+            r#"{"rules": [{
+                "id": "encrypted-private-keys",
+                "match": {"finding_type": "key", "operation": "encode"},
+                "key_encoding": {"require_encryption": true}
+            }]}"#,
+        );
+        let rule = &policy.rules[0];
+        let mut marshal = finding(
+            "crypto/x509.MarshalPKCS8PrivateKey",
+            None,
+            serde_json::json!(null),
+        );
+        marshal.finding_type = Some("key".to_string());
+        marshal.operation = Some("encode".to_string());
+        marshal.key_encoding = Some(KeyEncoding {
+            encrypted: false,
+            destination: KeyDestination::File,
+            expression: "os.WriteFile(\"key.der\", der, 0600)".to_string(),
+        });
+        assert_eq!(
+            rule.check(&marshal).as_deref(),
+            Some("private key written to a file without encryption (os.WriteFile(\"key.der\", der, 0600))")
+        );
+
+        let encoding = marshal.key_encoding.as_mut().unwrap();
+        encoding.encrypted = true;
+        assert_eq!(rule.check(&marshal), None);
+
+        let encoding = marshal.key_encoding.as_mut().unwrap();
+        encoding.encrypted = false;
+        encoding.destination = KeyDestination::Pem;
+        assert_eq!(rule.check(&marshal), None);
+    }
+
+    #[test]
+    fn test_key_encoding_constraint_requiring_nothing_is_rejected() {
+        let policy: Policy = serde_json::from_str(
+            r#"{"rules": [{"id": "keys", "key_encoding": {"require_encryption": false}}]}"#,
+        )
+        .unwrap();
+        assert!(policy.validate().is_err());
+    }
+
+    #[test]
+    fn test_static_key_exchange() {
+        let policy = parse(
+            r#"{"rules": [{
+                "id": "forward-secrecy",
+                "match": {"finding_type": "key-agreement"},
+                "key_exchange": {"require_ephemeral": true}
+            }]}"#,
+        );
+        let rule = &policy.rules[0];
+        let mut exchange = finding("crypto/ecdh.PrivateKey.ECDH", None, serde_json::json!(null));
+        exchange.finding_type = Some("key-agreement".to_string());
+        exchange.key_exchange = Some(KeyExchange {
+            lifetime: KeyLifetime::Static,
+            private_key: "serverKey".to_string(),
+            origin: Some("ecdh.X25519().GenerateKey(rand.Reader)".to_string()),
+        });
+        assert_eq!(
+            rule.check(&exchange).as_deref(),
+            Some("static private key serverKey (ecdh.X25519().GenerateKey(rand.Reader)) is reused across key exchanges; generate an ephemeral key per exchange")
+        );
+
+        for lifetime in [KeyLifetime::Ephemeral, KeyLifetime::Untraced] {
+            exchange.key_exchange.as_mut().unwrap().lifetime = lifetime;
+            assert_eq!(rule.check(&exchange), None);
+        }
+    }
+
+    #[test]
+    fn test_key_exchange_constraint_requiring_nothing_is_rejected() {
+        let policy: Policy = serde_json::from_str(
+            r#"{"rules": [{"id": "ecdh", "key_exchange": {"require_ephemeral": false}}]}"#,
+        )
+        .unwrap();
+        assert!(policy.validate().is_err());
+    }
+
+    #[test]
+    fn test_long_lived_aead_without_rotation() {
+        let policy = parse(
+            r#"{"rules": [{
+                "id": "aead-rotation",
+                "aead_key": {"require_rotation": true}
+            }]}"#,
+        );
+        let rule = &policy.rules[0];
+        let mut seal = finding("crypto/cipher.AEAD.Seal", None, serde_json::json!(null));
+        assert_eq!(rule.check(&seal), None);
+
+        seal.long_lived_aead = Some(LongLivedAead {
+            aead: "tokenAEAD".to_string(),
+            scope: AeadScope::Global,
+            construction_line: Some(14),
+            construction: Some("tokenAEAD, _ = cipher.NewGCM(block)".to_string()),
+        });
+        assert_eq!(
+            rule.check(&seal).as_deref(),
+            Some("tokenAEAD (constructed at line 14) seals every message under one key and nothing rotates it; add a rekey path before 2^32 messages")
+        );
+
+        let aead = seal.long_lived_aead.as_mut().unwrap();
+        aead.construction_line = None;
+        aead.construction = None;
+        assert_eq!(
+            rule.check(&seal).as_deref(),
+            Some("tokenAEAD seals every message under one key and nothing rotates it; add a rekey path before 2^32 messages")
+        );
+    }
+
+    #[test]
+    fn test_unauthenticated_encryption_mode() {
+        let policy = parse(
+            r#"{"rules": [{
+                "id": "unauthenticated-encryption",
+                "encryption_mode": {"require_authentication": true}
+            }]}"#,
+        );
+        let rule = &policy.rules[0];
+        let mut mode = finding(
+            "crypto/cipher.NewCBCEncrypter",
+            None,
+            serde_json::json!(null),
+        );
+        assert_eq!(rule.check(&mode), None);
+
+        mode.unauthenticated_mode = Some(UnauthenticatedMode {
+            mode: "CBC".to_string(),
+            cipher: Some("AES".to_string()),
+            key_size: Some(256),
+            ciphertext: Some("out".to_string()),
+        });
+        assert_eq!(
+            rule.check(&mode).as_deref(),
+            Some("AES-256-CBC encryption with no MAC over the ciphertext; add an HMAC (encrypt-then-MAC) or use an AEAD such as AES-GCM")
+        );
+
+        let policy: Policy = serde_json::from_str(
+            r#"{"rules": [{"id": "r", "encryption_mode": {"require_authentication": false}}]}"#,
+        )
+        .unwrap();
+        assert!(policy.validate().is_err());
+    }
+
+    #[test]
+    fn test_random_bias() {
+        let policy = parse(
+            r#"{"rules": [{
+                "id": "uniform-random",
+                "random": {"forbid_bias": true}
+            }]}"#,
+        );
+        let rule = &policy.rules[0];
+        let mut draw = finding("crypto/rand.Int", None, serde_json::json!(null));
+        assert_eq!(rule.check(&draw), None);
+
+        draw.random_bias = Some(RandomBias {
+            kind: BiasKind::RandomBound,
+            expression: "big.NewInt(randomInt64)".to_string(),
+        });
+        assert_eq!(
+            rule.check(&draw).as_deref(),
+            Some("crypto/rand.Int draws below big.NewInt(randomInt64), which comes from another random value and skews the result toward small numbers")
+        );
+        draw.random_bias = Some(RandomBias {
+            kind: BiasKind::PredictableSecret,
+            expression: "token".to_string(),
+        });
+        assert_eq!(rule.check(&draw), None);
+
+        let policy: Policy =
+            serde_json::from_str(r#"{"rules": [{"id": "r", "random": {}}]}"#).unwrap();
+        assert!(policy.validate().is_err());
+    }
+
+    #[test]
+    fn test_validity_limits() {
+        let policy = parse(
+            r#"{"rules": [{
+                "id": "short-lived-certs",
+                "validity": {"profile": "cabf", "max_rotation_interval": "90d"}
+            }]}"#,
+        );
+        let rule = &policy.rules[0];
+        let mut issue = finding(
+            "crypto/x509.CreateCertificate",
+            None,
+            serde_json::json!(null),
+        );
+        assert_eq!(rule.check(&issue), None);
+
+        let period = |kind, days: u64, expression: &str| ValidityPeriod {
+            kind,
+            parameter: "NotAfter".to_string(),
+            seconds: days * 86_400,
+            expression: expression.to_string(),
+        };
+        issue.validity = vec![period(
+            ValidityKind::CertificateLifetime,
+            398,
+            "notBefore.AddDate(0, 0, 398)",
+        )];
+        assert_eq!(rule.check(&issue), None);
+        issue.validity = vec![period(
+            ValidityKind::CertificateLifetime,
+            825,
+            "time.Now().Add(certLifetime)",
+        )];
+        assert_eq!(
+            rule.check(&issue).as_deref(),
+            Some("crypto/x509.CreateCertificate sets a certificate lifetime of 825d (time.Now().Add(certLifetime)), longer than the 398d maximum")
+        );
+        issue.validity = vec![
+            period(ValidityKind::CacheTtl, 30, "30 * 24 * time.Hour"),
+            period(ValidityKind::RotationInterval, 365, "365 * day"),
+        ];
+        assert!(rule
+            .check(&issue)
+            .unwrap()
+            .contains("key rotation interval of 365d"));
+
+        assert_eq!(parse_duration("12h"), Some(43_200));
+        assert_eq!(parse_duration("12w"), None);
+        assert_eq!(format_duration(90), "90s");
+        assert_eq!(format_duration(129_600), "36h");
+        for invalid in [r#"{}"#, r#"{"max_cache_ttl": "one day"}"#] {
+            let policy: Policy = serde_json::from_str(&format!(
+                r#"{{"rules": [{{"id": "r", "validity": {invalid}}}]}}"#
+            ))
+            .unwrap();
+            assert!(policy.validate().is_err(), "{invalid}");
+        }
+    }
+
+    #[test]
+    fn test_password_storage() {
+        let policy = parse(
+            r#"{"rules": [{
+                "id": "password-storage",
+                "password_storage": {"forbid_fast_hash": true, "forbid_plaintext": true}
+            }]}"#,
+        );
+        let rule = &policy.rules[0];
+        let mut hash = finding("crypto/sha256.Sum256", None, serde_json::json!(null));
+        hash.password_storage = Some(PasswordStorage {
+            kind: PasswordStorageKind::FastHash,
+            password: "req.Password".to_string(),
+            expression: "[]byte(req.Password)".to_string(),
+        });
+        assert_eq!(
+            rule.check(&hash).as_deref(),
+            Some("password req.Password is hashed with crypto/sha256.Sum256, a fast hash; use a password KDF such as argon2id, scrypt or bcrypt")
+        );
+
+        let mut marshal = finding("encoding/json.Marshal", None, serde_json::json!(null));
+        marshal.password_storage = Some(PasswordStorage {
+            kind: PasswordStorageKind::Plaintext,
+            password: "a.Password".to_string(),
+            expression: "a".to_string(),
+        });
+        assert_eq!(
+            rule.check(&marshal).as_deref(),
+            Some("password a.Password is stored in plain text through encoding/json.Marshal (a)")
+        );
+        assert_eq!(
+            rule.check(&finding(
+                "crypto/sha256.Sum256",
+                None,
+                serde_json::json!(null)
+            )),
+            None
+        );
+    }
+
+    #[test]
+    fn test_password_storage_constraint_forbidding_nothing_is_rejected() {
+        let policy: Policy =
+            serde_json::from_str(r#"{"rules": [{"id": "passwords", "password_storage": {}}]}"#)
+                .unwrap();
+        assert!(policy.validate().is_err());
+    }
+
+    #[test]
+    fn test_secret_comparison() {
+        let policy = parse(
+            r#"{"rules": [{
+                "id": "constant-time",
+                "secret_comparison": {"require_constant_time": true}
+            }]}"#,
+        );
+        let rule = &policy.rules[0];
+        let mut equal = finding("bytes.Equal", None, serde_json::json!(null));
+        assert_eq!(rule.check(&equal), None);
+
+        equal.secret_comparison = Some(SecretComparison {
+            material: SecretMaterial::Mac,
+            operand: "expected".to_string(),
+            provenance: vec![
+                "expected".to_string(),
+                "mac.Sum(nil)".to_string(),
+                "hmac.New(sha256.New, key)".to_string(),
+            ],
+        });
+        assert_eq!(
+            rule.check(&equal).as_deref(),
+            Some("expected holds a MAC (from mac.Sum(nil) <- hmac.New(sha256.New, key)) and is compared with bytes.Equal, which is not constant time; use subtle.ConstantTimeCompare or hmac.Equal")
+        );
+    }
+
+    #[test]
+    fn test_policy_message_catalog() {
+        let dir = tempfile::tempdir().unwrap();
+        fs::create_dir(dir.path().join("i18n")).unwrap();
+        fs::write(
+            dir.path().join("i18n/de.json"),
+            r#"{"parameter-below-min": "{parameter} ist {value}, Minimum ist {min}"}"#,
+        )
+        .unwrap();
+        let path = dir.path().join("policy.json");
+        fs::write(
+            &path,
+            r#"{
+                "messages": "i18n/de.json",
+                "rules": [{
+                    "id": "min-iterations",
+                    "match": {"algorithm": "PBKDF2"},
+                    "parameter": {"name": "arg2", "min": 600000, "require_resolved": true}
+                }]
+            }"#,
+        )
+        .unwrap();
+        let policy = Policy::from_file(&path).unwrap();
+
+        let mut kdf = finding(
+            "golang.org/x/crypto/pbkdf2.Key",
+            Some("PBKDF2"),
+            serde_json::json!(1000),
+        );
+        let messages: Vec<_> = policy.violations(&kdf).map(|(_, _, m)| m).collect();
+        assert_eq!(messages, vec!["arg2 ist 1000, Minimum ist 600000"]);
+        assert!(!policy.rules[0].flags_unresolved(&kdf));
+
+        // Templates the catalog does not replace stay English
+        kdf.parameters
+            .insert("arg2".to_string(), serde_json::json!(null));
+        let messages: Vec<_> = policy.violations(&kdf).map(|(_, _, m)| m).collect();
+        assert_eq!(messages, vec!["arg2 could not be resolved"]);
+        assert!(policy.rules[0].flags_unresolved(&kdf));
+    }
+
+    #[test]
+    fn test_secret_comparison_constraint_requiring_nothing_is_rejected() {
+        let policy: Policy = serde_json::from_str(
+            r#"{"rules": [{"id": "compare", "secret_comparison": {"require_constant_time": false}}]}"#,
+        )
+        .unwrap();
+        assert!(policy.validate().is_err());
+    }
+
+    #[test]
+    fn test_constructor_failure_paths() {
+        let policy = parse(
+            r#"{"rules": [{
+                "id": "constructor-errors",
+                "match": {"function": "crypto/aes.NewCipher"},
+                "failure": {"nil_without_error": true}
+            }]}"#,
+        );
+        let rule = &policy.rules[0];
+        let mut cipher = finding("crypto/aes.NewCipher", None, serde_json::json!(null));
+        cipher.enclosing_function = Some("NewAES128".to_string());
+        cipher.failure_paths = vec![
+            FailurePath {
+                kind: FailureKind::Panic,
+                line: 12,
+                text: "panic(err)".to_string(),
+            },
+            FailurePath {
+                kind: FailureKind::NilWithoutError,
+                line: 14,
+                text: "return nil, nil".to_string(),
+            },
+        ];
+        assert_eq!(
+            rule.check(&cipher).as_deref(),
+            Some(
+                "NewAES128 returns nil without an error at line 14 (return nil, nil); \
+                 callers get a nil value"
+            )
+        );
+
+        cipher.failure_paths.pop();
+        assert_eq!(rule.check(&cipher), None);
+    }
+
+    #[test]
+    fn test_library_crash_paths() {
+        let policy = parse(
+            r#"{"rules": [{
+                "id": "library-crashes",
+                "match": {"function": "crypto/rand.Read", "paths": ["internal"]},
+                "crash": {"fatal": true}
+            }]}"#,
+        );
+        let rule = &policy.rules[0];
+        let mut read = finding("crypto/rand.Read", None, serde_json::json!(null));
+        read.file = "internal/random/seed.go".to_string();
+        read.enclosing_function = Some("NewMathRandInt".to_string());
+        read.crash_paths = vec![
+            CrashPath {
+                kind: CrashKind::Panic,
+                line: 13,
+                text: "panic(err)".to_string(),
+            },
+            CrashPath {
+                kind: CrashKind::Fatal,
+                line: 17,
+                text: r#"log.Fatalf("zero seed")"#.to_string(),
+            },
+        ];
+        assert_eq!(
+            rule.check(&read).as_deref(),
+            Some(
+                r#"NewMathRandInt exits the process at line 17 (log.Fatalf("zero seed")) instead of returning an error; a transient failure takes the service down"#
+            )
+        );
+
+        read.crash_paths.pop();
+        assert_eq!(rule.check(&read), None);
+
+        read.crash_paths = vec![CrashPath {
+            kind: CrashKind::Fatal,
+            line: 17,
+            text: "log.Fatal(err)".to_string(),
+        }];
+        read.file = "cmd/tool/seed.go".to_string();
+        assert_eq!(rule.check(&read), None);
+    }
+
+    #[test]
+    fn test_parameter_zero_fallback() {
+        let policy = parse(
+            r#"{"rules": [{
+                "id": "kdf-iterations",
+                "parameter": {"name": "arg2", "min": 600000}
+            }]}"#,
+        );
+        let rule = &policy.rules[0];
+        let mut key = finding(
+            "golang.org/x/crypto/pbkdf2.Key",
+            None,
+            serde_json::json!(null),
+        );
+        assert_eq!(rule.check(&key), None);
+
+        key.zero_fallbacks = vec![ZeroFallback {
+            argument: "arg2".to_string(),
+            position: 2,
+            expression: "cfg.Iterations".to_string(),
+            loader: "LoadConfig(path)".to_string(),
+            line: 8,
+            handling: ErrorHandling::Swallowed,
+            zero: Some("0".to_string()),
+        }];
+        assert_eq!(
+            rule.check(&key).as_deref(),
+            Some("arg2 is 0 when LoadConfig(path) fails (line 8): the error is checked but not returned and the zero value is used")
+        );
+
+        key.zero_fallbacks[0].zero = Some("nil".to_string());
+        assert_eq!(rule.check(&key), None);
+    }
+
+    #[test]
+    fn test_registry_selection_reported_once() {
+        let policy = parse(
+            r#"{"rules": [{
+                "id": "hash-registry",
+                "match": {"primitive": "hash"},
+                "selection": {"allowed": ["SHA-256", "SHA-512"]}
+            }]}"#,
+        );
+        let rule = &policy.rules[0];
+        let options = vec![
+            SelectionOption {
+                case: "sha256".to_string(),
+                algorithm: Some("SHA-256".to_string()),
+                line: 27,
+            },
+            SelectionOption {
+                case: "sha1".to_string(),
+                algorithm: Some("SHA-1".to_string()),
+                line: 29,
+            },
+        ];
+        let case = |case: &str| {
+            let mut hasher = finding("crypto/sha1.New", Some("SHA-1"), serde_json::json!(null));
+            hasher.primitive = Some("hash".to_string());
+            hasher.selection = Some(AlgorithmSelection {
+                function: "GetHasher".to_string(),
+                selector: "algorithm".to_string(),
+                cases: vec![case.to_string()],
+                options: options.clone(),
+            });
+            hasher
+        };
+
+        assert_eq!(rule.check(&case("sha256")), None);
+        assert_eq!(
+            rule.check(&case("sha1")).as_deref(),
+            Some("GetHasher(algorithm) can select SHA-1 (case sha1); allowed: SHA-256, SHA-512")
+        );
+    }
+
+    #[test]
+    fn test_constraint_without_bounds_is_rejected() {
+        let policy: Policy =
+            serde_json::from_str(r#"{"rules": [{"id": "empty", "parameter": {"name": "arg0"}}]}"#)
+                .unwrap();
+        assert!(policy.validate().is_err());
+
+        let policy: Policy =
+            serde_json::from_str(r#"{"rules": [{"id": "empty", "failure": {}}]}"#).unwrap();
+        assert!(policy.validate().is_err());
+
+        let policy: Policy =
+            serde_json::from_str(r#"{"rules": [{"id": "empty", "crash": {}}]}"#).unwrap();
+        assert!(policy.validate().is_err());
+    }
+}
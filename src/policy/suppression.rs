@@ -0,0 +1,353 @@
+use std::collections::BTreeMap;
+
+/// Marker for inline suppressions, e.g. `//argflow:ignore no-md5,min-iterations reason`.
+///
+/// Rule ids follow the marker as one comma-separated token; anything after it is free text.
+pub const SUPPRESSION_MARKER: &str = "argflow:ignore";
+
+/// Rewrites the rule ids of every inline suppression in `content` using `renames`.
+///
+/// Returns the new content and the number of suppressions changed, or `None` if nothing
+/// changed.
+pub fn rename_suppressed_rules(
+    content: &str,
+    renames: &BTreeMap<String, String>,
+) -> Option<(String, usize)> {
+    let mut changed = 0;
+    let mut lines: Vec<String> = Vec::new();
+
+    for line in content.split_inclusive('\n') {
+        match rename_line(line, renames) {
+            Some(renamed) => {
+                changed += 1;
+                lines.push(renamed);
+            }
+            None => lines.push(line.to_string()),
+        }
+    }
+
+    (changed > 0).then(|| (lines.concat(), changed))
+}
+
+fn rename_line(line: &str, renames: &BTreeMap<String, String>) -> Option<String> {
+    let (ids_start, ids_len) = rule_ids_span(line)?;
+    let ids = &line[ids_start..ids_start + ids_len];
+
+    let renamed: Vec<&str> = ids
+        .split(',')
+        .map(|id| renames.get(id).map_or(id, String::as_str))
+        .collect();
+    let renamed = renamed.join(",");
+    if renamed == ids {
+        return None;
+    }
+
+    Some(format!(
+        "{}{}{}",
+        &line[..ids_start],
+        renamed,
+        &line[ids_start + ids_len..]
+    ))
+}
+
+/// Byte offset and length of the comma-separated rule ids after the marker on `line`.
+fn rule_ids_span(line: &str) -> Option<(usize, usize)> {
+    let marker_end = line.find(SUPPRESSION_MARKER)? + SUPPRESSION_MARKER.len();
+    let rest = &line[marker_end..];
+    let ids_start = marker_end + (rest.len() - rest.trim_start().len());
+    let ids_len = line[ids_start..]
+        .find(char::is_whitespace)
+        .unwrap_or(line.len() - ids_start);
+    Some((ids_start, ids_len))
+}
+
+/// Placeholder written for the reason and owner when `argflow annotate` is not given one.
+pub const PLACEHOLDER: &str = "TODO";
+
+/// An inline suppression: the rule ids it names and its `key=value` fields.
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct Suppression {
+    pub rules: Vec<String>,
+    pub reason: Option<String>,
+    pub owner: Option<String>,
+    /// Ticket tracking the exception, e.g. `SEC-123` or an issue URL.
+    pub ticket: Option<String>,
+}
+
+/// Parses the suppression on `line`, if it has one.
+///
+/// Fields are `key=value` or `key="quoted value"`; other text after the rule ids is
+/// free-form and ignored. A field left at the [`PLACEHOLDER`] counts as missing.
+pub fn parse_suppression(line: &str) -> Option<Suppression> {
+    let (ids_start, ids_len) = rule_ids_span(line)?;
+    let mut suppression = Suppression {
+        rules: line[ids_start..ids_start + ids_len]
+            .split(',')
+            .filter(|id| !id.is_empty())
+            .map(str::to_string)
+            .collect(),
+        ..Suppression::default()
+    };
+
+    let mut rest = line[ids_start + ids_len..].trim_start();
+    while !rest.is_empty() {
+        let token_end = rest.find(char::is_whitespace).unwrap_or(rest.len());
+        let Some((key, value)) = rest[..token_end].split_once('=') else {
+            rest = rest[token_end..].trim_start();
+            continue;
+        };
+        let (value, consumed) = if value.starts_with('"') {
+            let start = key.len() + 1;
+            let (value, len) = unquote(&rest[start..]);
+            (value, start + len)
+        } else {
+            (value.to_string(), token_end)
+        };
+        let value = Some(value).filter(|v| !v.is_empty() && v != PLACEHOLDER);
+        match key {
+            "reason" => suppression.reason = value,
+            "owner" => suppression.owner = value,
+            "ticket" => suppression.ticket = value,
+            _ => {}
+        }
+        rest = rest[consumed..].trim_start();
+    }
+    Some(suppression)
+}
+
+/// The value of the quoted string at the start of `text` and the bytes it spans,
+/// undoing the escapes `quote_if_spaced` writes. An unterminated string runs to the end.
+fn unquote(text: &str) -> (String, usize) {
+    let mut value = String::new();
+    let mut escaped = false;
+    for (i, c) in text.char_indices().skip(1) {
+        match c {
+            _ if escaped => {
+                value.push(c);
+                escaped = false;
+            }
+            '\\' => escaped = true,
+            '"' => return (value, i + 1),
+            _ => value.push(c),
+        }
+    }
+    (value, text.len())
+}
+
+/// Inserts a suppression comment above each line in `rules_by_line` (1-based line to rule
+/// ids). The comment takes the line's indentation and is written as
+/// `<prefix>argflow:ignore <ids> reason=<reason> owner=<owner>`, followed by
+/// `ticket=<ticket>` when one is given.
+///
+/// A suppression already on the line above is extended with the missing rule ids rather
+/// than duplicated. Returns the new content and the number of suppressions written or
+/// extended, or `None` if nothing changed.
+pub fn insert_suppressions(
+    content: &str,
+    rules_by_line: &BTreeMap<usize, Vec<String>>,
+    comment_prefix: &str,
+    reason: &str,
+    owner: &str,
+    ticket: Option<&str>,
+) -> Option<(String, usize)> {
+    let mut lines: Vec<String> = content.split_inclusive('\n').map(str::to_string).collect();
+    let mut changed = 0;
+
+    // Bottom-up, so earlier insertions do not shift the lines still to be annotated
+    for (&line, rules) in rules_by_line.iter().rev() {
+        let Some(target) = line.checked_sub(1).filter(|&i| i < lines.len()) else {
+            continue;
+        };
+        if let Some(above) = target
+            .checked_sub(1)
+            .filter(|&i| lines[i].contains(SUPPRESSION_MARKER))
+        {
+            if let Some(extended) = add_rules(&lines[above], rules) {
+                lines[above] = extended;
+                changed += 1;
+            }
+            continue;
+        }
+
+        let indent: String = lines[target]
+            .chars()
+            .take_while(|c| *c == ' ' || *c == '\t')
+            .collect();
+        let newline = if lines[target].ends_with("\r\n") {
+            "\r\n"
+        } else {
+            "\n"
+        };
+        let ticket = ticket
+            .map(|ticket| format!(" ticket={}", quote_if_spaced(ticket)))
+            .unwrap_or_default();
+        lines.insert(
+            target,
+            format!(
+                "{indent}{comment_prefix}{SUPPRESSION_MARKER} {} reason={} owner={}{ticket}{newline}",
+                rules.join(","),
+                quote_if_spaced(reason),
+                quote_if_spaced(owner)
+            ),
+        );
+        changed += 1;
+    }
+
+    (changed > 0).then(|| (lines.concat(), changed))
+}
+
+/// Adds the ids in `rules` missing from the suppression on `line`.
+fn add_rules(line: &str, rules: &[String]) -> Option<String> {
+    let (ids_start, ids_len) = rule_ids_span(line)?;
+    let ids = &line[ids_start..ids_start + ids_len];
+
+    let mut merged: Vec<&str> = ids.split(',').filter(|id| !id.is_empty()).collect();
+    let before = merged.len();
+    for rule in rules {
+        if !merged.contains(&rule.as_str()) {
+            merged.push(rule);
+        }
+    }
+    if merged.len() == before {
+        return None;
+    }
+
+    Some(format!(
+        "{}{}{}",
+        &line[..ids_start],
+        merged.join(","),
+        &line[ids_start + ids_len..]
+    ))
+}
+
+fn quote_if_spaced(value: &str) -> String {
+    if value.contains(char::is_whitespace) {
+        format!("{value:?}")
+    } else {
+        value.to_string()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_rename_suppressed_rules() {
+        let renames = BTreeMap::from([("no-md5".to_string(), "weak-hash".to_string())]);
+        let content = "package main\n\n\
+            //argflow:ignore no-md5,min-iterations legacy checksum\n\
+            sum := md5.Sum(data)\n\
+            # argflow:ignore min-iterations\n";
+
+        let (renamed, changed) = rename_suppressed_rules(content, &renames).unwrap();
+        assert_eq!(changed, 1);
+        assert!(renamed.contains("//argflow:ignore weak-hash,min-iterations legacy checksum\n"));
+        assert!(renamed.contains("# argflow:ignore min-iterations\n"));
+        assert!(renamed.starts_with("package main\n\n"));
+    }
+
+    #[test]
+    fn test_no_change_without_matching_ids() {
+        let renames = BTreeMap::from([("no-md5".to_string(), "weak-hash".to_string())]);
+        assert!(rename_suppressed_rules("//argflow:ignore no-sha1\n", &renames).is_none());
+        assert!(rename_suppressed_rules("no-md5 without a marker\n", &renames).is_none());
+    }
+
+    fn rules(entries: &[(usize, &[&str])]) -> BTreeMap<usize, Vec<String>> {
+        entries
+            .iter()
+            .map(|(line, ids)| (*line, ids.iter().map(|id| id.to_string()).collect()))
+            .collect()
+    }
+
+    #[test]
+    fn test_insert_suppressions() {
+        let content = "package main\n\nfunc f() {\n\tsum := md5.Sum(data)\n\th := sha1.New()\n}\n";
+        let (annotated, changed) = insert_suppressions(
+            content,
+            &rules(&[(4, &["no-md5"]), (5, &["no-sha1", "weak-hash"])]),
+            "//",
+            PLACEHOLDER,
+            "@crypto team",
+            None,
+        )
+        .unwrap();
+
+        assert_eq!(changed, 2);
+        assert_eq!(
+            annotated,
+            "package main\n\nfunc f() {\n\
+             \t//argflow:ignore no-md5 reason=TODO owner=\"@crypto team\"\n\
+             \tsum := md5.Sum(data)\n\
+             \t//argflow:ignore no-sha1,weak-hash reason=TODO owner=\"@crypto team\"\n\
+             \th := sha1.New()\n}\n"
+        );
+    }
+
+    #[test]
+    fn test_parse_suppression_fields() {
+        let suppression = parse_suppression(
+            "\t//argflow:ignore no-md5,weak-hash legacy checksum reason=\"cache \\\"key\\\"\" owner=TODO ticket=https://jira.example.com/browse/SEC-42\n",
+        )
+        .unwrap();
+        assert_eq!(
+            suppression,
+            Suppression {
+                rules: vec!["no-md5".to_string(), "weak-hash".to_string()],
+                reason: Some("cache \"key\"".to_string()),
+                owner: None,
+                ticket: Some("https://jira.example.com/browse/SEC-42".to_string()),
+            }
+        );
+
+        assert!(parse_suppression("sum := md5.Sum(data)").is_none());
+        assert_eq!(
+            parse_suppression("# argflow:ignore no-md5").unwrap().ticket,
+            None
+        );
+
+        // What `annotate` writes reads back
+        let (annotated, _) = insert_suppressions(
+            "sum := md5.Sum(data)\n",
+            &rules(&[(1, &["no-md5"])]),
+            "//",
+            "legacy checksum",
+            "@crypto",
+            Some("SEC-42"),
+        )
+        .unwrap();
+        let written = parse_suppression(annotated.lines().next().unwrap()).unwrap();
+        assert_eq!(written.reason.as_deref(), Some("legacy checksum"));
+        assert_eq!(written.ticket.as_deref(), Some("SEC-42"));
+    }
+
+    #[test]
+    fn test_insert_suppressions_extends_existing_comment() {
+        let content = "    # argflow:ignore no-md5 legacy\n    h = hashlib.md5(data)\n";
+        let (annotated, changed) = insert_suppressions(
+            content,
+            &rules(&[(2, &["no-md5", "weak-hash"])]),
+            "# ",
+            PLACEHOLDER,
+            PLACEHOLDER,
+            None,
+        )
+        .unwrap();
+        assert_eq!(changed, 1);
+        assert_eq!(
+            annotated,
+            "    # argflow:ignore no-md5,weak-hash legacy\n    h = hashlib.md5(data)\n"
+        );
+
+        assert!(insert_suppressions(
+            &annotated,
+            &rules(&[(2, &["weak-hash"])]),
+            "# ",
+            PLACEHOLDER,
+            PLACEHOLDER,
+            None
+        )
+        .is_none());
+    }
+}
@@ -0,0 +1,178 @@
+//! Approved crypto wrappers: primitives the project must reach through its own API.
+
+use serde::{Deserialize, Serialize};
+
+use crate::output::Finding;
+
+use super::architecture::Wrapper;
+use super::messages::MessageCatalog;
+use super::rules::{in_directory, FindingSelector, Severity};
+
+/// Rule id of wrapper bypasses unless the entry names one.
+pub const WRAPPER_RULE: &str = "crypto-wrappers";
+
+/// A wrapper every matching sink call must go through, e.g.
+/// `{"wrapper": "example.com/app/internal/cryptoutil.Encrypt", "match": {"operation": "encrypt"},
+/// "allow": ["internal/cryptoutil"]}`.
+#[derive(Debug, Clone, Deserialize, Serialize)]
+pub struct WrapperPolicy {
+    #[serde(default = "default_rule")]
+    pub id: String,
+    #[serde(default)]
+    pub message: Option<String>,
+    #[serde(default)]
+    pub severity: Severity,
+    /// Remediation guide link, a template like the policy's `remediation_url`.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub remediation_url: Option<String>,
+    /// The approved wrapper as `import/path.Function`.
+    pub wrapper: String,
+    /// The primitives the wrapper centralizes.
+    #[serde(rename = "match", default)]
+    pub selector: FindingSelector,
+    /// Directories that may still call the primitives directly, matched like a rule's
+    /// `match.paths`.
+    #[serde(default)]
+    pub allow: Vec<String>,
+}
+
+fn default_rule() -> String {
+    WRAPPER_RULE.to_string()
+}
+
+impl WrapperPolicy {
+    /// Returns why `finding` bypasses the wrapper, or `None`.
+    pub fn check(&self, finding: &Finding) -> Option<String> {
+        self.check_with(finding, &MessageCatalog::default())
+    }
+
+    /// Like `check`, with messages from `messages`.
+    pub fn check_with(&self, finding: &Finding, messages: &MessageCatalog) -> Option<String> {
+        let wrapper = Wrapper::parse(&self.wrapper)?;
+        if finding.full_name == wrapper.full_name
+            || !self.selector.matches(finding)
+            || wrapper.contains(finding)
+            || self
+                .allow
+                .iter()
+                .any(|dir| in_directory(&finding.file, dir))
+        {
+            return None;
+        }
+
+        let detail = messages.render(
+            "wrapper-bypass",
+            &[
+                ("function", finding.full_name.as_str()),
+                ("wrapper", wrapper.full_name),
+            ],
+        );
+        Some(match &self.message {
+            Some(message) => messages.render(
+                "with-rule-message",
+                &[("message", message), ("detail", &detail)],
+            ),
+            None => detail,
+        })
+    }
+
+    pub(super) fn validate(&self) -> Result<(), String> {
+        if Wrapper::parse(&self.wrapper).is_none() {
+            return Err(format!(
+                "wrapper '{}' is not import/path.Function",
+                self.wrapper
+            ));
+        }
+        if !self.selector.paths.is_empty() {
+            return Err(
+                "wrapper entry restricts match.paths; list directories under allow".to_string(),
+            );
+        }
+        Ok(())
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn finding(file: &str, full_name: &str, enclosing: Option<&str>) -> Finding {
+        let (import_path, _) = full_name.rsplit_once('.').unwrap();
+        Finding {
+            enclosing_function: enclosing.map(str::to_string),
+            ..Finding::call(file, 14, 8, full_name)
+                .with_import_path(import_path)
+                .with_algorithm("AES")
+                .with_operation("encrypt")
+        }
+    }
+
+    fn policy() -> WrapperPolicy {
+        serde_json::from_str(
+            r#"{"wrapper": "example.com/app/internal/cryptoutil.Encrypt",
+                "match": {"operation": "encrypt"},
+                "allow": ["tools/migrate"]}"#,
+        )
+        .unwrap()
+    }
+
+    #[test]
+    fn test_direct_calls_outside_allowlist_bypass_wrapper() {
+        let policy = policy();
+        assert_eq!(policy.id, WRAPPER_RULE);
+
+        for allowed in [
+            finding(
+                "/app/internal/cryptoutil/seal.go",
+                "crypto/aes.NewCipher",
+                Some("Encrypt"),
+            ),
+            finding(
+                "/app/tools/migrate/rekey.go",
+                "crypto/aes.NewCipher",
+                Some("rekey"),
+            ),
+            finding(
+                "/app/pkg/api/store.go",
+                "example.com/app/internal/cryptoutil.Encrypt",
+                Some("Save"),
+            ),
+        ] {
+            assert_eq!(policy.check(&allowed), None, "{}", allowed.file);
+        }
+        let mut hashing = finding("/app/pkg/api/id.go", "crypto/sha256.Sum256", None);
+        hashing.operation = Some("hash".to_string());
+        assert_eq!(policy.check(&hashing), None);
+
+        // A helper next to the wrapper is not the wrapper
+        assert!(policy
+            .check(&finding(
+                "/app/internal/cryptoutil/keys.go",
+                "crypto/aes.NewCipher",
+                Some("newBlock"),
+            ))
+            .is_some());
+        assert_eq!(
+            policy
+                .check(&finding(
+                    "/app/pkg/api/store.go",
+                    "crypto/aes.NewCipher",
+                    Some("Save"),
+                ))
+                .unwrap(),
+            "crypto/aes.NewCipher is called directly instead of through example.com/app/internal/cryptoutil.Encrypt"
+        );
+    }
+
+    #[test]
+    fn test_invalid_entries_are_rejected() {
+        assert!(policy().validate().is_ok());
+        for entry in [
+            r#"{"wrapper": "Encrypt"}"#,
+            r#"{"wrapper": "example.com/app/internal/cryptoutil.Encrypt", "match": {"paths": ["pkg"]}}"#,
+        ] {
+            let entry: WrapperPolicy = serde_json::from_str(entry).unwrap();
+            assert!(entry.validate().is_err(), "{entry:?}");
+        }
+    }
+}
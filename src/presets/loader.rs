@@ -62,7 +62,7 @@ pub fn load_presets(names: &[String]) -> Result<Vec<PathBuf>> {
     names.iter().map(|name| load_preset(name)).collect()
 }
 
-#[allow(dead_code)]
+/// Reads the `preset.json` of the preset at `preset_path`.
 pub fn load_preset_metadata(preset_path: &Path) -> Result<PresetMetadata> {
     let metadata_path = preset_path.join("preset.json");
     let content = std::fs::read_to_string(&metadata_path).with_context(|| {
@@ -80,7 +80,8 @@ pub fn load_preset_metadata(preset_path: &Path) -> Result<PresetMetadata> {
     })
 }
 
-fn list_available_presets() -> Vec<String> {
+/// Names of the presets in the presets directory.
+pub fn list_available_presets() -> Vec<String> {
     let presets_dir = get_presets_dir();
 
     if !presets_dir.exists() {
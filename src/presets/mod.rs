@@ -1,3 +1,6 @@
 mod loader;
 
-pub use loader::{load_preset, load_presets, PresetMetadata};
+pub use loader::{
+    get_presets_dir, list_available_presets, load_preset, load_preset_metadata, load_presets,
+    PresetMetadata,
+};
@@ -0,0 +1,189 @@
+//! Zero-config quickstart scan for `argflow .`.
+
+use std::collections::BTreeMap;
+use std::fmt::Write as _;
+use std::path::{Path, PathBuf};
+
+use anyhow::Result;
+
+use crate::cli::{Args, Language};
+use crate::output::JsonOutput;
+use crate::policy::{self, GateOptions, Policy, Violation};
+use crate::presets;
+
+/// Where the quickstart writes the full report unless `--output-file` is given.
+pub const REPORT_FILE: &str = "report.json";
+
+/// Project manifests, in the order they decide a directory's language.
+const MANIFESTS: &[(&str, Language)] = &[
+    ("go.mod", Language::Go),
+    ("Cargo.toml", Language::Rust),
+    ("tsconfig.json", Language::Typescript),
+    ("package.json", Language::Javascript),
+    ("pyproject.toml", Language::Python),
+    ("setup.py", Language::Python),
+    ("requirements.txt", Language::Python),
+];
+
+/// Whether `args` ask for the quickstart: a scan of a path with no presets, rules or
+/// subcommand.
+pub fn applies(args: &Args) -> bool {
+    args.command.is_none()
+        && args.preset.is_empty()
+        && args.rules.is_none()
+        && (args.path.is_some() || args.target.is_some())
+}
+
+/// Fills in the quickstart defaults `args` leave open: every installed preset, the
+/// project's language, and `report.json` as the output file.
+pub fn configure(args: &mut Args) -> Result<()> {
+    let mut names = presets::list_available_presets();
+    if names.is_empty() {
+        anyhow::bail!(
+            "No presets found in {}. Use --rules <path> to scan with custom rules",
+            presets::get_presets_dir().display()
+        );
+    }
+    names.sort();
+    args.preset = names;
+
+    if args.language.is_none() {
+        args.language = args
+            .path
+            .as_deref()
+            .or(args.target.as_deref())
+            .filter(|path| path.is_dir())
+            .and_then(detect_project_language);
+    }
+    if args.output_file.is_none() {
+        args.output_file = Some(PathBuf::from(REPORT_FILE));
+    }
+    Ok(())
+}
+
+/// The language of the project in `dir`, from the manifest at its root.
+pub fn detect_project_language(dir: &Path) -> Option<Language> {
+    MANIFESTS
+        .iter()
+        .find(|(manifest, _)| dir.join(manifest).is_file())
+        .map(|(_, language)| *language)
+}
+
+/// What the quickstart found, for the text summary.
+#[derive(Debug, Clone)]
+pub struct Summary {
+    pub presets: Vec<String>,
+    pub findings: usize,
+    /// Findings per algorithm, `unknown` for those without one.
+    pub algorithms: BTreeMap<String, usize>,
+    /// Findings breaking the moderate thresholds; empty in inventory mode.
+    pub violations: Vec<Violation>,
+    pub report: PathBuf,
+}
+
+impl Summary {
+    /// Summarizes `report`, checking its findings against `policy` when one is given.
+    pub fn new(
+        report: &JsonOutput,
+        policy: Option<&Policy>,
+        root: &Path,
+        presets: Vec<String>,
+        report_file: PathBuf,
+    ) -> Self {
+        let mut algorithms = BTreeMap::new();
+        for finding in &report.findings {
+            let algorithm = finding.algorithm.as_deref().unwrap_or("unknown");
+            *algorithms.entry(algorithm.to_string()).or_insert(0) += 1;
+        }
+        let violations = policy.map_or_else(Vec::new, |policy| {
+            let options = GateOptions {
+                root: root.to_path_buf(),
+                ..GateOptions::default()
+            };
+            let mut violations = policy::evaluate(policy, &report.findings, &options).violations;
+            violations.sort_by(|a, b| {
+                b.severity
+                    .cmp(&a.severity)
+                    .then_with(|| (&a.file, a.line).cmp(&(&b.file, b.line)))
+            });
+            violations
+        });
+        Summary {
+            presets,
+            findings: report.findings.len(),
+            algorithms,
+            violations,
+            report: report_file,
+        }
+    }
+
+    pub fn render_text(&self) -> String {
+        let mut out = String::new();
+        let _ = writeln!(
+            out,
+            "{} crypto call(s) found with presets {}:",
+            self.findings,
+            self.presets.join(", ")
+        );
+        for (algorithm, count) in &self.algorithms {
+            let _ = writeln!(out, "  {algorithm}: {count}");
+        }
+        let _ = writeln!(
+            out,
+            "{} issue(s) at moderate thresholds:",
+            self.violations.len()
+        );
+        for violation in &self.violations {
+            let _ = writeln!(
+                out,
+                "  {} {}:{} [{}] {}",
+                violation.severity.as_str(),
+                violation.file,
+                violation.line,
+                violation.rule,
+                violation.message
+            );
+        }
+        let _ = writeln!(out, "Full report written to {}.", self.report.display());
+        let _ = writeln!(
+            out,
+            "Pick presets with --preset and gate on your own thresholds with `argflow gate --policy <file>`."
+        );
+        out
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use clap::Parser;
+    use tempfile::TempDir;
+
+    #[test]
+    fn test_quickstart_applies_to_bare_scans_only() {
+        let bare = Args::try_parse_from(["argflow", "."]).unwrap();
+        assert!(applies(&bare));
+        assert_eq!(bare.target.as_deref(), Some(Path::new(".")));
+
+        let preset = Args::try_parse_from(["argflow", ".", "--preset", "crypto"]).unwrap();
+        assert!(!applies(&preset));
+        assert!(!applies(&Args::try_parse_from(["argflow"]).unwrap()));
+        let gate =
+            Args::try_parse_from(["argflow", ".", "gate", "--policy", "policy.yaml"]).unwrap();
+        assert!(!applies(&gate));
+        assert!(Args::try_parse_from(["argflow", ".", "--path", "."]).is_err());
+    }
+
+    #[test]
+    fn test_detect_project_language() {
+        let dir = TempDir::new().unwrap();
+        assert_eq!(detect_project_language(dir.path()), None);
+        std::fs::write(dir.path().join("package.json"), "{}").unwrap();
+        assert_eq!(
+            detect_project_language(dir.path()),
+            Some(Language::Javascript)
+        );
+        std::fs::write(dir.path().join("go.mod"), "module example.com/app\n").unwrap();
+        assert_eq!(detect_project_language(dir.path()), Some(Language::Go));
+    }
+}
@@ -0,0 +1,574 @@
+//! Minimal reproductions of Go findings, for `argflow repro`.
+
+use std::collections::{BTreeMap, BTreeSet, HashMap};
+use std::fs;
+use std::path::{Path, PathBuf};
+
+use tree_sitter::{Node, Parser, Tree};
+
+use crate::discovery::languages::go::gomod::{self, GoMod};
+use crate::error::ReproError;
+use crate::output::Finding;
+use crate::policy::relative_path;
+use crate::utils::{extract_last_segment, unquote_string};
+
+/// Module path of generated reproductions.
+pub const REPRO_MODULE: &str = "example.com/argflow-repro";
+
+/// A reproduction ready to be written out.
+#[derive(Debug, Clone)]
+pub struct Repro {
+    pub go_mod: String,
+    pub main_go: String,
+    /// Line of the reproduced call in `main.go`.
+    pub line: usize,
+    /// Packages of the original module the extracted code imports. Their declarations
+    /// are not copied, so the reproduction needs them vendored by hand.
+    pub unresolved_imports: Vec<String>,
+}
+
+/// A top-level declaration in the finding's package.
+struct Unit<'t> {
+    file: usize,
+    node: Node<'t>,
+    names: Vec<String>,
+    /// Receiver type name for methods.
+    receiver: Option<String>,
+}
+
+#[derive(Clone)]
+struct Import {
+    alias: Option<String>,
+    path: String,
+}
+
+impl Import {
+    fn local_name(&self) -> String {
+        self.alias
+            .clone()
+            .unwrap_or_else(|| extract_last_segment(&self.path))
+    }
+
+    /// Blank and dot imports have effects that cannot be traced through selectors.
+    fn always_kept(&self) -> bool {
+        matches!(self.alias.as_deref(), Some("_" | "."))
+    }
+
+    fn render(&self) -> String {
+        match &self.alias {
+            Some(alias) => format!("{alias} \"{}\"", self.path),
+            None => format!("\"{}\"", self.path),
+        }
+    }
+}
+
+struct SourceFile {
+    path: PathBuf,
+    source: String,
+    tree: Tree,
+    imports: Vec<Import>,
+}
+
+/// Finds the finding `id` names: `FILE:LINE` or `FILE:LINE:COLUMN`, with `FILE`
+/// relative to `root` or any trailing part of the path.
+pub fn select<'a>(findings: &'a [Finding], id: &str, root: &Path) -> Option<&'a Finding> {
+    let (location, last) = id.rsplit_once(':')?;
+    let last: usize = last.parse().ok()?;
+    let (file, line, column) = match location.rsplit_once(':') {
+        Some((file, line)) if line.parse::<usize>().is_ok() => {
+            (file, line.parse().ok()?, Some(last))
+        }
+        _ => (location, last, None),
+    };
+
+    findings.iter().find(|f| {
+        let relative = relative_path(&f.file, root);
+        (relative == file || f.file == file || f.file.ends_with(&format!("/{file}")))
+            && f.line == line
+            && column.is_none_or(|c| f.column == c)
+    })
+}
+
+/// Extracts a reproduction of `finding` from the Go package it was found in.
+pub fn extract(finding: &Finding) -> Result<Repro, ReproError> {
+    let finding_path = PathBuf::from(&finding.file);
+    let dir = finding_path.parent().unwrap_or(Path::new("."));
+    let files = load_package(dir, &finding_path)?;
+
+    let mut units = Vec::new();
+    for (index, file) in files.iter().enumerate() {
+        collect_units(index, file, &mut units);
+    }
+
+    let finding_file = files
+        .iter()
+        .position(|f| f.path == finding_path)
+        .ok_or_else(|| ReproError::read_error(&finding_path, "not a Go source file"))?;
+    let row = finding.line.saturating_sub(1);
+    let start = units
+        .iter()
+        .position(|u| {
+            u.file == finding_file
+                && u.node.start_position().row <= row
+                && row <= u.node.end_position().row
+        })
+        .ok_or_else(|| ReproError::NoEnclosingDeclaration {
+            path: finding_path.clone(),
+            line: finding.line,
+        })?;
+
+    let (included, used_packages) = closure(&files, &units, start);
+
+    let go_mod_path = gomod::find_go_mod(dir);
+    let original = go_mod_path
+        .as_deref()
+        .and_then(|path| GoMod::from_file(path).ok())
+        .unwrap_or_default();
+
+    let mut imports: BTreeMap<String, Import> = BTreeMap::new();
+    for &unit in &included {
+        let file = &files[units[unit].file];
+        for import in &file.imports {
+            if import.always_kept()
+                || used_packages.contains(&(units[unit].file, import.local_name()))
+            {
+                imports.insert(import.render(), import.clone());
+            }
+        }
+    }
+
+    let unresolved_imports: Vec<String> = original
+        .module
+        .as_deref()
+        .map(|module| {
+            imports
+                .values()
+                .filter(|i| i.path == module || i.path.starts_with(&format!("{module}/")))
+                .map(|i| i.path.clone())
+                .collect::<BTreeSet<_>>()
+                .into_iter()
+                .collect()
+        })
+        .unwrap_or_default();
+
+    let (main_go, line) = render_main(
+        finding,
+        &files,
+        &units,
+        &included,
+        start,
+        &imports,
+        &unresolved_imports,
+    );
+    let go_mod = render_go_mod(&original, imports.values());
+
+    Ok(Repro {
+        go_mod,
+        main_go,
+        line,
+        unresolved_imports,
+    })
+}
+
+impl Repro {
+    /// Writes `go.mod`, `main.go` and `finding.json` (the original finding) into `dir`.
+    pub fn write(&self, dir: &Path, finding: &Finding) -> Result<(), ReproError> {
+        fs::create_dir_all(dir).map_err(|e| ReproError::write_error(dir, e.to_string()))?;
+        let finding_json = serde_json::to_string_pretty(finding)
+            .map_err(|e| ReproError::write_error(dir.join("finding.json"), e.to_string()))?;
+        for (name, content) in [
+            ("go.mod", self.go_mod.as_str()),
+            ("main.go", self.main_go.as_str()),
+            ("finding.json", finding_json.as_str()),
+        ] {
+            let path = dir.join(name);
+            fs::write(&path, content).map_err(|e| ReproError::write_error(&path, e.to_string()))?;
+        }
+        Ok(())
+    }
+}
+
+/// Parses the non-test Go files of the package in `dir`.
+fn load_package(dir: &Path, finding_path: &Path) -> Result<Vec<SourceFile>, ReproError> {
+    let mut paths: Vec<PathBuf> = fs::read_dir(dir)
+        .map_err(|e| ReproError::read_error(dir, e.to_string()))?
+        .filter_map(|entry| entry.ok().map(|e| e.path()))
+        .filter(|path| {
+            let name = path.file_name().and_then(|n| n.to_str()).unwrap_or("");
+            name.ends_with(".go") && !name.ends_with("_test.go")
+        })
+        .collect();
+    if !paths.iter().any(|p| p == finding_path) {
+        paths.push(finding_path.to_path_buf());
+    }
+    paths.sort();
+
+    let mut parser = Parser::new();
+    parser
+        .set_language(&tree_sitter_go::LANGUAGE.into())
+        .map_err(|_| ReproError::ParseError {
+            path: dir.to_path_buf(),
+        })?;
+
+    paths
+        .into_iter()
+        .map(|path| {
+            let source = fs::read_to_string(&path)
+                .map_err(|e| ReproError::read_error(&path, e.to_string()))?;
+            let tree = parser
+                .parse(&source, None)
+                .ok_or_else(|| ReproError::ParseError { path: path.clone() })?;
+            let imports = collect_imports(tree.root_node(), &source);
+            Ok(SourceFile {
+                path,
+                source,
+                tree,
+                imports,
+            })
+        })
+        .collect()
+}
+
+fn text<'s>(node: Node, source: &'s str) -> &'s str {
+    &source[node.byte_range()]
+}
+
+fn collect_imports(root: Node, source: &str) -> Vec<Import> {
+    let mut imports = Vec::new();
+    let mut stack = vec![root];
+    while let Some(node) = stack.pop() {
+        match node.kind() {
+            "import_spec" => {
+                if let Some(path) = node.child_by_field_name("path") {
+                    imports.push(Import {
+                        alias: node
+                            .child_by_field_name("name")
+                            .map(|n| text(n, source).to_string()),
+                        path: unquote_string(text(path, source)),
+                    });
+                }
+            }
+            "source_file" | "import_declaration" | "import_spec_list" => {
+                let mut cursor = node.walk();
+                stack.extend(node.children(&mut cursor));
+            }
+            _ => {}
+        }
+    }
+    imports
+}
+
+fn collect_units<'t>(file_index: usize, file: &'t SourceFile, units: &mut Vec<Unit<'t>>) {
+    let root = file.tree.root_node();
+    let mut cursor = root.walk();
+    for node in root.children(&mut cursor) {
+        let (names, receiver) = match node.kind() {
+            "function_declaration" => (field_text(node, "name", &file.source), None),
+            "method_declaration" => (
+                field_text(node, "name", &file.source),
+                node.child_by_field_name("receiver")
+                    .and_then(|r| first_descendant(r, "type_identifier"))
+                    .map(|t| text(t, &file.source).to_string()),
+            ),
+            "const_declaration" | "var_declaration" | "type_declaration" => {
+                (declared_names(node, &file.source), None)
+            }
+            _ => continue,
+        };
+        units.push(Unit {
+            file: file_index,
+            node,
+            names,
+            receiver,
+        });
+    }
+}
+
+fn field_text(node: Node, field: &str, source: &str) -> Vec<String> {
+    node.child_by_field_name(field)
+        .map(|n| vec![text(n, source).to_string()])
+        .unwrap_or_default()
+}
+
+/// Names introduced by a const, var or type declaration.
+fn declared_names(decl: Node, source: &str) -> Vec<String> {
+    let mut names = Vec::new();
+    let mut stack = vec![decl];
+    while let Some(node) = stack.pop() {
+        match node.kind() {
+            "const_spec" | "var_spec" | "type_spec" | "type_alias" => {
+                let mut cursor = node.walk();
+                names.extend(
+                    node.children_by_field_name("name", &mut cursor)
+                        .map(|n| text(n, source).to_string()),
+                );
+            }
+            _ => {
+                let mut cursor = node.walk();
+                stack.extend(node.children(&mut cursor));
+            }
+        }
+    }
+    names
+}
+
+fn first_descendant<'t>(node: Node<'t>, kind: &str) -> Option<Node<'t>> {
+    if node.kind() == kind {
+        return Some(node);
+    }
+    let mut cursor = node.walk();
+    let children: Vec<_> = node.children(&mut cursor).collect();
+    children.into_iter().find_map(|c| first_descendant(c, kind))
+}
+
+/// Declarations reachable from `start`, and the `(file, package name)` pairs they use.
+///
+/// Local variables that shadow package-level names are not tracked, so the result can
+/// include a declaration that is not strictly needed but never misses one.
+fn closure(
+    files: &[SourceFile],
+    units: &[Unit],
+    start: usize,
+) -> (BTreeSet<usize>, BTreeSet<(usize, String)>) {
+    let mut by_name: HashMap<&str, Vec<usize>> = HashMap::new();
+    for (index, unit) in units.iter().enumerate() {
+        if unit.receiver.is_none() {
+            for name in &unit.names {
+                by_name.entry(name).or_default().push(index);
+            }
+        }
+    }
+
+    let mut included = BTreeSet::new();
+    let mut packages = BTreeSet::new();
+    let mut fields = BTreeSet::new();
+    let mut pending = vec![start];
+
+    loop {
+        while let Some(index) = pending.pop() {
+            if !included.insert(index) {
+                continue;
+            }
+            let unit = &units[index];
+            let source = files[unit.file].source.as_str();
+            let mut stack = vec![unit.node];
+            while let Some(node) = stack.pop() {
+                match node.kind() {
+                    "identifier" | "type_identifier" => {
+                        if let Some(found) = by_name.get(text(node, source)) {
+                            pending.extend(found);
+                        }
+                    }
+                    "package_identifier" => {
+                        packages.insert((unit.file, text(node, source).to_string()));
+                    }
+                    "field_identifier" => {
+                        fields.insert(text(node, source).to_string());
+                    }
+                    "selector_expression" => {
+                        if let Some(operand) = node.child_by_field_name("operand") {
+                            if operand.kind() == "identifier" {
+                                packages.insert((unit.file, text(operand, source).to_string()));
+                            }
+                        }
+                    }
+                    _ => {}
+                }
+                let mut cursor = node.walk();
+                stack.extend(node.children(&mut cursor));
+            }
+        }
+
+        // Methods are reached through selectors, whose receiver type is unknown here;
+        // keep methods of included types that are called by name somewhere
+        let included_types: BTreeSet<&str> = included
+            .iter()
+            .flat_map(|&i| units[i].names.iter().map(String::as_str))
+            .collect();
+        pending.extend(units.iter().enumerate().filter_map(|(index, unit)| {
+            let receiver = unit.receiver.as_deref()?;
+            (!included.contains(&index)
+                && included_types.contains(receiver)
+                && unit.names.iter().any(|n| fields.contains(n)))
+            .then_some(index)
+        }));
+        if pending.is_empty() {
+            break;
+        }
+    }
+
+    (included, packages)
+}
+
+#[allow(clippy::too_many_arguments)]
+fn render_main(
+    finding: &Finding,
+    files: &[SourceFile],
+    units: &[Unit],
+    included: &BTreeSet<usize>,
+    start: usize,
+    imports: &BTreeMap<String, Import>,
+    unresolved_imports: &[String],
+) -> (String, usize) {
+    let mut out = String::new();
+    let origin = files[units[start].file]
+        .path
+        .file_name()
+        .map(|n| n.to_string_lossy().into_owned())
+        .unwrap_or_default();
+    out.push_str(&format!(
+        "// Reproduction of {} extracted by `argflow repro` from {}:{}.\n",
+        finding.full_name, origin, finding.line
+    ));
+    for path in unresolved_imports {
+        out.push_str(&format!(
+            "// {path} belongs to the original module and was not extracted; vendor the declarations used here.\n"
+        ));
+    }
+    out.push_str("package main\n");
+
+    if !imports.is_empty() {
+        out.push_str("\nimport (\n");
+        for rendered in imports.keys() {
+            out.push_str(&format!("\t{rendered}\n"));
+        }
+        out.push_str(")\n");
+    }
+
+    let mut line = 0;
+    let mut defines_main = false;
+    for &index in included {
+        let unit = &units[index];
+        let source = &files[unit.file].source;
+        if unit.receiver.is_none() && unit.names.iter().any(|n| n == "main") {
+            defines_main = true;
+        }
+        out.push('\n');
+        if index == start {
+            let unit_line = out.lines().count() + 1;
+            line = unit_line + finding.line - 1 - unit.node.start_position().row;
+        }
+        out.push_str(text(unit.node, source));
+        out.push('\n');
+    }
+    if !defines_main {
+        out.push_str("\nfunc main() {}\n");
+    }
+
+    (out, line)
+}
+
+fn render_go_mod<'a>(original: &GoMod, imports: impl Iterator<Item = &'a Import>) -> String {
+    let mut out = format!("module {REPRO_MODULE}\n");
+    if let Some(version) = &original.go_version {
+        out.push_str(&format!("\ngo {version}\n"));
+    }
+
+    let requires: BTreeSet<String> = imports
+        .filter_map(|import| {
+            original
+                .requires
+                .iter()
+                .filter(|r| {
+                    import.path == r.path || import.path.starts_with(&format!("{}/", r.path))
+                })
+                .max_by_key(|r| r.path.len())
+                .map(|r| format!("{} {}", r.path, r.version))
+        })
+        .collect();
+    if !requires.is_empty() {
+        out.push_str("\nrequire (\n");
+        for require in requires {
+            out.push_str(&format!("\t{require}\n"));
+        }
+        out.push_str(")\n");
+    }
+    out
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    fn finding(file: &str, line: usize) -> Finding {
+        Finding::call(file, line, 9, "golang.org/x/crypto/pbkdf2.Key")
+            .with_package("pbkdf2")
+            .with_import_path("golang.org/x/crypto/pbkdf2")
+            .with_algorithm("PBKDF2")
+            .with_raw_text("pbkdf2.Key(password, salt, Iterations, keyLen, sha256.New)")
+            .in_function("derive")
+    }
+
+    #[test]
+    fn test_select_by_location() {
+        let findings = [finding("/work/app/kdf/kdf.go", 12)];
+        let root = Path::new("/work/app");
+
+        assert!(select(&findings, "kdf/kdf.go:12", root).is_some());
+        assert!(select(&findings, "kdf.go:12:9", root).is_some());
+        assert!(select(&findings, "kdf/kdf.go:12:3", root).is_none());
+        assert!(select(&findings, "kdf/kdf.go:13", root).is_none());
+        assert!(select(&findings, "kdf/kdf.go", root).is_none());
+    }
+
+    #[test]
+    fn test_extract_follows_constant_chain() {
+        let temp_dir = TempDir::new().unwrap();
+        let root = temp_dir.path();
+        fs::write(
+            root.join("go.mod"),
+            "module example.com/app\n\ngo 1.22\n\nrequire (\n\tgolang.org/x/crypto v0.31.0\n\tgithub.com/unused/dep v1.0.0\n)\n",
+        )
+        .unwrap();
+        fs::create_dir(root.join("kdf")).unwrap();
+        fs::write(
+            root.join("kdf/config.go"),
+            "package kdf\n\nconst (\n\tbaseRounds = 1000\n\tIterations = baseRounds * 4\n)\n\nconst Unrelated = 7\n",
+        )
+        .unwrap();
+        let kdf_go = root.join("kdf/kdf.go");
+        fs::write(
+            &kdf_go,
+            r#"package kdf
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const keyLen = 32
+
+func derive(password, salt []byte) []byte {
+	return pbkdf2.Key(password, salt, Iterations, keyLen, sha256.New)
+}
+
+func describe() string {
+	return fmt.Sprint(Unrelated)
+}
+"#,
+        )
+        .unwrap();
+
+        let repro = extract(&finding(kdf_go.to_str().unwrap(), 13)).unwrap();
+
+        assert!(repro.main_go.contains("package main\n"));
+        assert!(repro.main_go.contains("baseRounds = 1000"));
+        assert!(repro.main_go.contains("const keyLen = 32"));
+        assert!(repro.main_go.contains("func main() {}"));
+        assert!(!repro.main_go.contains("Unrelated"));
+        assert!(!repro.main_go.contains("\"fmt\""));
+        assert!(repro.main_go.contains("\t\"crypto/sha256\"\n"));
+        assert_eq!(
+            repro.main_go.lines().nth(repro.line - 1).unwrap().trim(),
+            "return pbkdf2.Key(password, salt, Iterations, keyLen, sha256.New)"
+        );
+
+        assert!(repro.go_mod.contains("go 1.22\n"));
+        assert!(repro.go_mod.contains("\tgolang.org/x/crypto v0.31.0\n"));
+        assert!(!repro.go_mod.contains("github.com/unused/dep"));
+        assert!(repro.unresolved_imports.is_empty());
+    }
+}
@@ -0,0 +1,484 @@
+//! Long-lived AEADs whose key is never rotated.
+
+use serde::Serialize;
+use tree_sitter::Node;
+
+use super::callbacks;
+use super::receiver::find_declaration;
+use super::ImportMap;
+use crate::engine::Context;
+
+const FUNCTION_KINDS: &[&str] = &["function_declaration", "method_declaration", "func_literal"];
+
+const SEAL: &str = "crypto/cipher.AEAD.Seal";
+
+/// How many local aliases a receiver is followed through.
+const MAX_ALIASES: usize = 4;
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum AeadScope {
+    /// A package-level variable.
+    Global,
+    /// A field of a struct the process holds one instance of.
+    Singleton,
+}
+
+/// A package-level or singleton AEAD with no key-rotation path.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct LongLivedAead {
+    /// The variable, or `Type.field` for a struct field, e.g. `Sealer.aead`.
+    pub aead: String,
+    pub scope: AeadScope,
+    /// Line of the assignment constructing the AEAD, when it is in the same file.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub construction_line: Option<usize>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub construction: Option<String>,
+}
+
+/// The long-lived AEAD `call` seals with, if `function` under `import_path` is
+/// `AEAD.Seal` and nothing in the file rotates that AEAD.
+pub(super) fn go_long_lived_aead<'a>(
+    call: &Node<'a>,
+    import_path: Option<&str>,
+    function: &str,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Option<LongLivedAead> {
+    if format!("{}.{function}", import_path?) != SEAL {
+        return None;
+    }
+    let receiver = call
+        .child_by_field_name("function")?
+        .child_by_field_name("operand")?;
+    let (target, scope) = long_lived(unalias(receiver, call, ctx)?, call, ctx)?;
+
+    let root = file_root(*call);
+    let sites = assignments(root, &target, ctx);
+    let construction = sites.first().copied();
+    let constructing = construction.and_then(enclosing_function);
+    let rotated = sites.iter().any(|site| {
+        let function = enclosing_function(*site);
+        function.is_some()
+            && function != constructing
+            && function.is_some_and(|function| reachable(root, function, ctx, imports))
+    });
+    if rotated {
+        return None;
+    }
+
+    Some(LongLivedAead {
+        aead: target.label,
+        scope,
+        construction_line: construction.map(|site| site.start_position().row + 1),
+        construction: construction.map(|site| ctx.get_node_text(&site)),
+    })
+}
+
+/// What assignments to the AEAD look like: a variable name, or a field name on any
+/// receiver.
+struct Target {
+    name: String,
+    field: bool,
+    label: String,
+}
+
+/// The receiver `node` with local aliases (`aead := s.aead`) resolved.
+fn unalias<'a>(mut node: Node<'a>, call: &Node<'a>, ctx: &Context<'a>) -> Option<Node<'a>> {
+    for _ in 0..MAX_ALIASES {
+        if node.kind() != "identifier" {
+            return Some(node);
+        }
+        let declaration = find_declaration(call, &ctx.get_node_text(&node), ctx)?;
+        if enclosing_function(declaration.node).is_none() {
+            return Some(node);
+        }
+        // Parameters and locally constructed AEADs are not long-lived
+        node = declaration
+            .value
+            .filter(|value| matches!(value.kind(), "identifier" | "selector_expression"))?;
+    }
+    None
+}
+
+fn long_lived<'a>(
+    node: Node<'a>,
+    call: &Node<'a>,
+    ctx: &Context<'a>,
+) -> Option<(Target, AeadScope)> {
+    match node.kind() {
+        "identifier" => {
+            let name = ctx.get_node_text(&node);
+            let declaration = find_declaration(call, &name, ctx)?;
+            enclosing_function(declaration.node).is_none().then(|| {
+                (
+                    Target {
+                        name: name.clone(),
+                        field: false,
+                        label: name,
+                    },
+                    AeadScope::Global,
+                )
+            })
+        }
+        "selector_expression" => {
+            let operand = node.child_by_field_name("operand")?;
+            let field = ctx.get_field_text(&node, "field")?;
+            let root = file_root(node);
+            // `defaultSealer.aead` on a package-level instance
+            let global = operand.kind() == "identifier"
+                && find_declaration(call, &ctx.get_node_text(&operand), ctx)
+                    .is_some_and(|declaration| enclosing_function(declaration.node).is_none());
+            let type_name = receiver_type(*call, ctx);
+            let singleton = global
+                || set_once(root, &field, ctx)
+                || type_name
+                    .as_deref()
+                    .is_some_and(|type_name| package_instance(root, type_name, ctx));
+            singleton.then(|| {
+                let label = match &type_name {
+                    Some(type_name) if !global => format!("{type_name}.{field}"),
+                    _ => ctx.get_node_text(&node),
+                };
+                (
+                    Target {
+                        name: field,
+                        field: true,
+                        label,
+                    },
+                    AeadScope::Singleton,
+                )
+            })
+        }
+        _ => None,
+    }
+}
+
+/// Assignments of the AEAD in file order: `x = ...`, `s.x = ...`, `var x = ...` and
+/// `&T{x: ...}`.
+fn assignments<'a>(root: Node<'a>, target: &Target, ctx: &Context<'a>) -> Vec<Node<'a>> {
+    let mut sites = Vec::new();
+    walk(root, &mut |node| {
+        let assigns = match node.kind() {
+            "assignment_statement" => node
+                .child_by_field_name("left")
+                .map(|left| ctx.get_named_children(&left))
+                .unwrap_or_default()
+                .into_iter()
+                .any(|left| matches_target(left, target, ctx)),
+            "var_spec" if !target.field => {
+                let mut cursor = node.walk();
+                let named = node
+                    .children_by_field_name("name", &mut cursor)
+                    .any(|name| ctx.get_node_text(&name) == target.name);
+                named && node.child_by_field_name("value").is_some()
+            }
+            "keyed_element" if target.field => node.named_child(0).is_some_and(|key| {
+                let key = match key.kind() {
+                    "literal_element" => key.named_child(0).unwrap_or(key),
+                    _ => key,
+                };
+                ctx.get_node_text(&key) == target.name
+            }),
+            _ => false,
+        };
+        if assigns {
+            sites.push(node);
+        }
+    });
+    sites
+}
+
+fn matches_target<'a>(node: Node<'a>, target: &Target, ctx: &Context<'a>) -> bool {
+    match node.kind() {
+        "identifier" => !target.field && ctx.get_node_text(&node) == target.name,
+        "selector_expression" => {
+            target.field
+                && ctx.get_field_text(&node, "field").as_deref() == Some(target.name.as_str())
+        }
+        _ => false,
+    }
+}
+
+/// Whether `field` is assigned inside a function literal passed to a `Do` call, as
+/// `sync.Once` initialization does.
+fn set_once<'a>(root: Node<'a>, field: &str, ctx: &Context<'a>) -> bool {
+    let target = Target {
+        name: field.to_string(),
+        field: true,
+        label: String::new(),
+    };
+    assignments(root, &target, ctx).into_iter().any(|site| {
+        enclosing_function(site)
+            .filter(|function| function.kind() == "func_literal")
+            .and_then(|literal| literal.parent()?.parent())
+            .and_then(|call| call.child_by_field_name("function"))
+            .is_some_and(|function| ctx.get_field_text(&function, "field").as_deref() == Some("Do"))
+    })
+}
+
+/// Whether a package-level variable holds a `type_name`: `var std = &Sealer{}` or
+/// `var std Sealer`.
+fn package_instance<'a>(root: Node<'a>, type_name: &str, ctx: &Context<'a>) -> bool {
+    let mut cursor = root.walk();
+    let declarations: Vec<_> = root
+        .children(&mut cursor)
+        .filter(|child| child.kind() == "var_declaration")
+        .collect();
+    declarations.into_iter().any(|declaration| {
+        let mut found = false;
+        walk(declaration, &mut |node| {
+            found =
+                found || node.kind() == "type_identifier" && ctx.get_node_text(&node) == type_name;
+        });
+        found
+    })
+}
+
+/// The receiver type name of the method around `node`, e.g. `Sealer` for
+/// `func (s *Sealer) Seal(...)`.
+fn receiver_type<'a>(node: Node<'a>, ctx: &Context<'a>) -> Option<String> {
+    let mut current = node.parent();
+    while let Some(parent) = current {
+        if parent.kind() == "method_declaration" {
+            let receiver = parent.child_by_field_name("receiver")?.named_child(0)?;
+            let type_node = receiver.child_by_field_name("type")?;
+            let type_node = match type_node.kind() {
+                "pointer_type" => type_node.named_child(0)?,
+                _ => type_node,
+            };
+            return Some(ctx.get_node_text(&type_node));
+        }
+        current = parent.parent();
+    }
+    None
+}
+
+/// Whether the named function or method `function` is exported, called in the file, or
+/// registered as a callback.
+fn reachable<'a>(
+    root: Node<'a>,
+    function: Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> bool {
+    if function.kind() == "func_literal" {
+        return true;
+    }
+    let Some(name) = ctx.get_field_text(&function, "name") else {
+        return false;
+    };
+    if name == "init" {
+        return false;
+    }
+    if name.starts_with(|c: char| c.is_ascii_uppercase()) {
+        return true;
+    }
+    let mut called = false;
+    walk(root, &mut |node| {
+        if called || node.kind() != "call_expression" {
+            return;
+        }
+        called = node
+            .child_by_field_name("function")
+            .is_some_and(|callee| match callee.kind() {
+                "identifier" => ctx.get_node_text(&callee) == name,
+                "selector_expression" => {
+                    ctx.get_field_text(&callee, "field").as_deref() == Some(name.as_str())
+                }
+                _ => false,
+            });
+    });
+    called || callbacks::registration(root, function, ctx, imports).is_some()
+}
+
+fn enclosing_function(node: Node<'_>) -> Option<Node<'_>> {
+    let mut current = node.parent();
+    while let Some(parent) = current {
+        if FUNCTION_KINDS.contains(&parent.kind()) {
+            return Some(parent);
+        }
+        current = parent.parent();
+    }
+    None
+}
+
+fn file_root(node: Node<'_>) -> Node<'_> {
+    let mut root = node;
+    while let Some(parent) = root.parent() {
+        root = parent;
+    }
+    root
+}
+
+fn walk<'a>(root: Node<'a>, visit: &mut impl FnMut(Node<'a>)) {
+    let mut stack = vec![root];
+    while let Some(node) = stack.pop() {
+        visit(node);
+        let mut cursor = node.walk();
+        let children: Vec<_> = node.named_children(&mut cursor).collect();
+        stack.extend(children.into_iter().rev());
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::scanner::Scanner;
+    use std::collections::HashMap;
+
+    #[test]
+    fn test_long_lived_aeads_with_and_without_rotation() {
+        let source = r#"
+package session
+
+import (
+    "crypto/aes"
+    "crypto/cipher"
+    "sync"
+)
+
+var tokenAEAD cipher.AEAD
+
+func init() {
+    block, _ := aes.NewCipher(loadKey())
+    tokenAEAD, _ = cipher.NewGCM(block)
+}
+
+func sealToken(nonce, token []byte) []byte {
+    aead := tokenAEAD
+    return aead.Seal(nil, nonce, token, nil)
+}
+
+type Sealer struct {
+    aead cipher.AEAD
+    once sync.Once
+}
+
+func (s *Sealer) Seal(nonce, msg []byte) []byte {
+    s.once.Do(func() {
+        block, _ := aes.NewCipher(loadKey())
+        s.aead, _ = cipher.NewGCM(block)
+    })
+    return s.aead.Seal(nil, nonce, msg, nil)
+}
+
+type Rotating struct {
+    gcm cipher.AEAD
+}
+
+var sessions = &Rotating{}
+
+func (r *Rotating) setup(key []byte) {
+    block, _ := aes.NewCipher(key)
+    r.gcm, _ = cipher.NewGCM(block)
+}
+
+func (r *Rotating) Rekey(key []byte) {
+    block, _ := aes.NewCipher(key)
+    r.gcm, _ = cipher.NewGCM(block)
+}
+
+func (r *Rotating) Seal(nonce, msg []byte) []byte {
+    return r.gcm.Seal(nil, nonce, msg, nil)
+}
+
+func local(key, nonce, msg []byte) []byte {
+    block, _ := aes.NewCipher(key)
+    gcm, _ := cipher.NewGCM(block)
+    return gcm.Seal(nil, nonce, msg, nil)
+}
+"#;
+        let mut parser = tree_sitter::Parser::new();
+        parser
+            .set_language(&tree_sitter_go::LANGUAGE.into())
+            .unwrap();
+        let tree = parser.parse(source, None).unwrap();
+        let scanner = Scanner::with_mappings(HashMap::from([(
+            "crypto/cipher.aead".to_string(),
+            HashMap::from([("seal".to_string(), "aead_seal".to_string())]),
+        )]));
+        let result = scanner.scan_tree(&tree, source.as_bytes(), "session.go", "go");
+
+        let seals: Vec<_> = result
+            .calls
+            .iter()
+            .filter(|c| c.function_name == "Seal")
+            .map(|c| (c.line, c.long_lived_aead.clone()))
+            .collect();
+        assert_eq!(
+            seals,
+            vec![
+                (
+                    19,
+                    Some(LongLivedAead {
+                        aead: "tokenAEAD".to_string(),
+                        scope: AeadScope::Global,
+                        construction_line: Some(14),
+                        construction: Some("tokenAEAD, _ = cipher.NewGCM(block)".to_string()),
+                    })
+                ),
+                (
+                    32,
+                    Some(LongLivedAead {
+                        aead: "Sealer.aead".to_string(),
+                        scope: AeadScope::Singleton,
+                        construction_line: Some(30),
+                        construction: Some("s.aead, _ = cipher.NewGCM(block)".to_string()),
+                    })
+                ),
+                (52, None),
+                (58, None),
+            ]
+        );
+    }
+    #[test]
+    fn test_rotation_registered_as_cron_job() {
+        let source = r#"
+package session
+
+import (
+    "crypto/aes"
+    "crypto/cipher"
+
+    "github.com/robfig/cron"
+)
+
+var tokenAEAD cipher.AEAD
+
+func init() {
+    block, _ := aes.NewCipher(loadKey())
+    tokenAEAD, _ = cipher.NewGCM(block)
+    c := cron.New()
+    c.AddFunc("@daily", rekey)
+}
+
+func rekey() {
+    block, _ := aes.NewCipher(loadKey())
+    tokenAEAD, _ = cipher.NewGCM(block)
+}
+
+func sealToken(nonce, token []byte) []byte {
+    return tokenAEAD.Seal(nil, nonce, token, nil)
+}
+"#;
+        let mut parser = tree_sitter::Parser::new();
+        parser
+            .set_language(&tree_sitter_go::LANGUAGE.into())
+            .unwrap();
+        let tree = parser.parse(source, None).unwrap();
+        let scanner = Scanner::with_mappings(HashMap::from([(
+            "crypto/cipher.aead".to_string(),
+            HashMap::from([("seal".to_string(), "aead_seal".to_string())]),
+        )]));
+        let result = scanner.scan_tree(&tree, source.as_bytes(), "session.go", "go");
+
+        let seal = result
+            .calls
+            .iter()
+            .find(|c| c.function_name == "Seal")
+            .unwrap();
+        assert_eq!(seal.long_lived_aead, None);
+    }
+}
@@ -0,0 +1,314 @@
+//! Whether the algorithm and parameters of a Go crypto call are hardcoded, constant or
+//! configurable at runtime.
+
+use serde::Serialize;
+use tree_sitter::Node;
+
+use super::receiver::{callee, find_declaration};
+use super::{Finding, ImportMap};
+use crate::engine::Context;
+
+/// Calls, or package prefixes, whose results come from outside the program.
+const RUNTIME_SOURCES: &[&str] = &[
+    "os.Getenv",
+    "os.LookupEnv",
+    "flag.",
+    "github.com/spf13/pflag.",
+    "github.com/spf13/viper.",
+    "github.com/kelseyhightower/envconfig.",
+];
+
+/// Builtins that allocate buffers; their arguments size data, not crypto parameters.
+const ALLOCATIONS: &[&str] = &["make", "new"];
+
+/// How many declarations an argument is followed through.
+const MAX_DEPTH: usize = 4;
+
+/// How an algorithm or parameter is chosen, from least to most agile.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, PartialOrd, Ord, Hash, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum AgilityClass {
+    /// Written as a literal or a standard library name at the call.
+    HardcodedLiteral,
+    /// A named constant: one edit and a rebuild change every use.
+    CompileTimeConstant,
+    /// Read from the environment, a flag, a config library or a config struct.
+    RuntimeConfigurable,
+}
+
+/// A named constant of the scanned project, identified by its package's directory.
+#[derive(Debug, Clone, PartialEq, Eq, PartialOrd, Ord, Hash, Serialize)]
+pub struct ConstantRef {
+    /// Directory of the package declaring the constant.
+    pub package: String,
+    pub name: String,
+}
+
+/// The agility of one Go crypto call.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Agility {
+    pub algorithm: AgilityClass,
+    /// Classified arguments by position; data arguments are left out.
+    pub arguments: Vec<(usize, AgilityClass)>,
+    /// Project constants each argument reads, by position.
+    pub constants: Vec<(usize, ConstantRef)>,
+}
+
+/// Agility of the Go call `call`, found as `finding`.
+pub(super) fn go_agility<'a>(
+    call: &Node<'a>,
+    finding: &Finding,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Agility {
+    let algorithm = if finding.selection.is_some() {
+        AgilityClass::RuntimeConfigurable
+    } else {
+        AgilityClass::HardcodedLiteral
+    };
+
+    let args = call
+        .child_by_field_name("arguments")
+        .map(|args| ctx.get_named_children(&args))
+        .unwrap_or_default();
+    let arguments = args
+        .iter()
+        .enumerate()
+        .filter_map(|(i, arg)| Some((i, classify(*arg, call, ctx, imports, 0)?)))
+        .collect();
+    let mut constants = Vec::new();
+    for (i, arg) in args.into_iter().enumerate() {
+        let mut read = Vec::new();
+        constants_in(arg, call, ctx, imports, 0, &mut read);
+        read.sort();
+        read.dedup();
+        constants.extend(read.into_iter().map(|constant| (i, constant)));
+    }
+
+    Agility {
+        algorithm,
+        arguments,
+        constants,
+    }
+}
+
+/// Collects the project constants `node` reads, following variables to their values.
+fn constants_in<'a>(
+    node: Node<'a>,
+    call: &Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+    depth: usize,
+    out: &mut Vec<ConstantRef>,
+) {
+    match node.kind() {
+        "identifier" => {
+            let name = ctx.get_node_text(&node);
+            let package = match find_declaration(call, &name, ctx) {
+                Some(declaration) if declaration.node.kind() != "const_spec" => {
+                    if let Some(value) = declaration.value.filter(|_| depth < MAX_DEPTH) {
+                        constants_in(value, call, ctx, imports, depth + 1, out);
+                    }
+                    return;
+                }
+                Some(_) => ctx.package_dir(),
+                // Declared in another file of the package
+                None => ctx
+                    .find_cross_file_constant(&name)
+                    .and_then(|_| ctx.package_dir()),
+            };
+            if let Some(package) = package {
+                out.push(ConstantRef { package, name });
+            }
+        }
+        "selector_expression" => {
+            let Some(operand) = node.child_by_field_name("operand") else {
+                return;
+            };
+            let operand_name = ctx.get_node_text(&operand);
+            if operand.kind() != "identifier" || imports.resolve(&operand_name).is_none() {
+                return;
+            }
+            let Some(name) = ctx.get_field_text(&node, "field") else {
+                return;
+            };
+            if ctx.find_imported_constant(&operand_name, &name).is_some() {
+                if let Some(package) = ctx.imported_package_dir(&operand_name) {
+                    out.push(ConstantRef { package, name });
+                }
+            }
+        }
+        "call_expression" => {
+            let allocates = node
+                .child_by_field_name("function")
+                .is_some_and(|function| {
+                    ALLOCATIONS.contains(&ctx.get_node_text(&function).as_str())
+                });
+            if let Some(args) = node.child_by_field_name("arguments").filter(|_| !allocates) {
+                constants_in(args, call, ctx, imports, depth, out);
+            }
+        }
+        _ => {
+            for child in ctx.get_named_children(&node) {
+                constants_in(child, call, ctx, imports, depth, out);
+            }
+        }
+    }
+}
+
+fn classify<'a>(
+    node: Node<'a>,
+    call: &Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+    depth: usize,
+) -> Option<AgilityClass> {
+    match node.kind() {
+        "int_literal"
+        | "float_literal"
+        | "interpreted_string_literal"
+        | "raw_string_literal"
+        | "true"
+        | "false" => Some(AgilityClass::HardcodedLiteral),
+        "identifier" => {
+            let name = ctx.get_node_text(&node);
+            let Some(declaration) = find_declaration(call, &name, ctx) else {
+                // Declared in another file of the package
+                return ctx
+                    .find_cross_file_constant(&name)
+                    .map(|_| AgilityClass::CompileTimeConstant);
+            };
+            if declaration.node.kind() == "const_spec" {
+                return Some(AgilityClass::CompileTimeConstant);
+            }
+            match declaration.value {
+                Some(value) if depth < MAX_DEPTH => classify(value, call, ctx, imports, depth + 1),
+                _ => None,
+            }
+        }
+        "selector_expression" => {
+            let operand = node.child_by_field_name("operand")?;
+            let operand_name = ctx.get_node_text(&operand);
+            if operand.kind() == "identifier" && imports.resolve(&operand_name).is_some() {
+                let name = ctx.get_field_text(&node, "field")?;
+                return Some(match ctx.find_imported_constant(&operand_name, &name) {
+                    Some(_) => AgilityClass::CompileTimeConstant,
+                    None => AgilityClass::HardcodedLiteral,
+                });
+            }
+            // `cfg.Iterations`: a field of a value built at runtime
+            Some(AgilityClass::RuntimeConfigurable)
+        }
+        "call_expression" => {
+            let function = node.child_by_field_name("function")?;
+            if ALLOCATIONS.contains(&ctx.get_node_text(&function).as_str()) {
+                return None;
+            }
+            if callee(node, ctx, imports)
+                .is_some_and(|name| RUNTIME_SOURCES.iter().any(|s| name.starts_with(s)))
+            {
+                return Some(AgilityClass::RuntimeConfigurable);
+            }
+            let args = node.child_by_field_name("arguments")?;
+            most_agile(args, call, ctx, imports, depth)
+        }
+        _ => most_agile(node, call, ctx, imports, depth),
+    }
+}
+
+/// The most agile class among the children of `node`, e.g. `base * 1000` is as agile as
+/// `base`.
+fn most_agile<'a>(
+    node: Node<'a>,
+    call: &Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+    depth: usize,
+) -> Option<AgilityClass> {
+    let mut cursor = node.walk();
+    let class = node
+        .named_children(&mut cursor)
+        .filter_map(|child| classify(child, call, ctx, imports, depth))
+        .max();
+    class
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::scanner::Scanner;
+    use std::collections::HashMap;
+
+    #[test]
+    fn test_agility_of_arguments() {
+        let source = r#"
+package kdf
+
+import (
+    "crypto/sha256"
+    "flag"
+    "os"
+    "strconv"
+
+    "golang.org/x/crypto/pbkdf2"
+)
+
+const keyLen = 32
+
+var rounds = flag.Int("rounds", 4096, "PBKDF2 iterations")
+
+func derive(password []byte) []byte {
+    salt := make([]byte, 16)
+    iterations, _ := strconv.Atoi(os.Getenv("KDF_ITERATIONS"))
+    pbkdf2.Key(password, salt, *rounds, keyLen, sha256.New)
+    return pbkdf2.Key(password, salt, iterations, 64, sha256.New)
+}
+"#;
+        let mut parser = tree_sitter::Parser::new();
+        parser
+            .set_language(&tree_sitter_go::LANGUAGE.into())
+            .unwrap();
+        let tree = parser.parse(source, None).unwrap();
+        let scanner = Scanner::with_mappings(HashMap::from([(
+            "golang.org/x/crypto/pbkdf2".to_string(),
+            HashMap::from([("key".to_string(), "pbkdf2".to_string())]),
+        )]));
+        let result = scanner.scan_tree(&tree, source.as_bytes(), "kdf.go", "go");
+
+        let agility: Vec<_> = result
+            .calls
+            .iter()
+            .map(|c| c.agility.clone().unwrap())
+            .collect();
+        assert_eq!(agility[0].algorithm, AgilityClass::HardcodedLiteral);
+        assert_eq!(
+            agility[0].arguments,
+            vec![
+                (2, AgilityClass::RuntimeConfigurable),
+                (3, AgilityClass::CompileTimeConstant),
+                (4, AgilityClass::HardcodedLiteral),
+            ]
+        );
+        assert_eq!(
+            agility[1].arguments,
+            vec![
+                (2, AgilityClass::RuntimeConfigurable),
+                (3, AgilityClass::HardcodedLiteral),
+                (4, AgilityClass::HardcodedLiteral),
+            ]
+        );
+
+        // `keyLen` is the only named constant; `rounds` is a flag variable
+        assert_eq!(
+            agility[0].constants,
+            vec![(
+                3,
+                ConstantRef {
+                    package: String::new(),
+                    name: "keyLen".to_string(),
+                }
+            )]
+        );
+        assert!(agility[1].constants.is_empty());
+    }
+}
@@ -0,0 +1,253 @@
+//! Secrets declared in source with `//argflow:secret`, or as sources and sinks in a
+//! rules file.
+
+use std::collections::HashMap;
+
+use serde::Deserialize;
+use tree_sitter::Node;
+
+use crate::engine::Context;
+
+/// Marker comment declaring a secret carrier.
+pub const SECRET_MARKER: &str = "argflow:secret";
+
+/// Secret sources and sinks declared in a rules file, by `import/path.Function` or
+/// `import/path.Type.Method`.
+#[derive(Debug, Clone, Default, Deserialize)]
+pub struct DeclaredSecrets {
+    /// Package-level functions returning a secret.
+    #[serde(default)]
+    pub sources: Vec<String>,
+    /// Calls persisting or logging their arguments from the given index on.
+    #[serde(default)]
+    pub sinks: HashMap<String, usize>,
+}
+
+impl DeclaredSecrets {
+    pub fn extend(&mut self, other: DeclaredSecrets) {
+        for source in other.sources {
+            if !self.sources.contains(&source) {
+                self.sources.push(source);
+            }
+        }
+        self.sinks.extend(other.sinks);
+    }
+
+    pub(super) fn is_source(&self, name: &str) -> bool {
+        self.sources.iter().any(|source| source == name)
+    }
+
+    /// Index of the first argument the sink `name` persists.
+    pub(super) fn sink(&self, name: &str) -> Option<usize> {
+        self.sinks.get(name).copied()
+    }
+}
+
+/// Whether the declaration `node` is marked secret.
+pub(super) fn is_secret<'a>(node: Node<'a>, ctx: &Context<'a>) -> bool {
+    let source = ctx.source_code();
+    let start = line_start(source, node.start_byte());
+    let end = source[start..]
+        .iter()
+        .position(|&b| b == b'\n')
+        .map_or(source.len(), |offset| start + offset);
+
+    // `Token string //argflow:secret`; `//` in a string before the comment is skipped
+    let line = String::from_utf8_lossy(&source[start..end]);
+    if line.split("//").skip(1).any(is_marker) {
+        return true;
+    }
+    // `//argflow:secret` alone on the line above
+    if start == 0 {
+        return false;
+    }
+    let above = line_start(source, start - 1);
+    String::from_utf8_lossy(&source[above..start - 1])
+        .trim()
+        .strip_prefix("//")
+        .is_some_and(is_marker)
+}
+
+/// Whether a struct type in the file of `node` declares a marked field named `field`.
+pub(super) fn is_secret_field<'a>(node: Node<'a>, field: &str, ctx: &Context<'a>) -> bool {
+    let mut root = node;
+    while let Some(parent) = root.parent() {
+        root = parent;
+    }
+    let mut stack = vec![root];
+    while let Some(current) = stack.pop() {
+        if current.kind() == "field_declaration" {
+            let mut cursor = current.walk();
+            let named = current
+                .children_by_field_name("name", &mut cursor)
+                .any(|name| ctx.get_node_text(&name) == field);
+            if named && is_secret(current, ctx) {
+                return true;
+            }
+            continue;
+        }
+        let mut cursor = current.walk();
+        stack.extend(current.named_children(&mut cursor));
+    }
+    false
+}
+
+fn is_marker(comment: &str) -> bool {
+    comment
+        .trim_start()
+        .strip_prefix(SECRET_MARKER)
+        .is_some_and(|rest| rest.is_empty() || rest.starts_with(char::is_whitespace))
+}
+
+fn line_start(source: &[u8], byte: usize) -> usize {
+    source[..byte]
+        .iter()
+        .rposition(|&b| b == b'\n')
+        .map_or(0, |newline| newline + 1)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::scanner::{PasswordStorageKind, Scanner, SecretMaterial};
+    use std::collections::HashMap;
+
+    #[test]
+    fn test_marker() {
+        assert!(is_marker("argflow:secret"));
+        assert!(is_marker(" argflow:secret session token"));
+        assert!(!is_marker("argflow:secrets"));
+        assert!(!is_marker("see argflow:secret"));
+    }
+
+    #[test]
+    fn test_declared_secrets() {
+        let source = r#"
+package session
+
+import (
+    "bytes"
+    "encoding/json"
+)
+
+type Session struct {
+    User  string
+    Token string `json:"token"` //argflow:secret
+}
+
+//argflow:secret
+var signingKey = loadKey()
+
+func check(presented []byte) bool {
+    return bytes.Equal(presented, signingKey)
+}
+
+func save(s Session, name string) ([]byte, error) {
+    json.Marshal(name)
+    return json.Marshal(s.Token)
+}
+"#;
+        let mut parser = tree_sitter::Parser::new();
+        parser
+            .set_language(&tree_sitter_go::LANGUAGE.into())
+            .unwrap();
+        let tree = parser.parse(source, None).unwrap();
+        let scanner = Scanner::with_mappings(HashMap::from([
+            (
+                "bytes".to_string(),
+                HashMap::from([("equal".to_string(), "secret_compare".to_string())]),
+            ),
+            (
+                "encoding/json".to_string(),
+                HashMap::from([("marshal".to_string(), "password_persist".to_string())]),
+            ),
+        ]));
+        let result = scanner.scan_tree(&tree, source.as_bytes(), "session.go", "go");
+
+        let comparison = result
+            .calls
+            .iter()
+            .find_map(|c| c.secret_comparison.as_ref())
+            .unwrap();
+        assert_eq!(comparison.material, SecretMaterial::Declared);
+        assert_eq!(comparison.operand, "signingKey");
+
+        let stored: Vec<_> = result
+            .calls
+            .iter()
+            .filter_map(|c| c.password_storage.as_ref())
+            .collect();
+        assert_eq!(stored.len(), 1);
+        assert_eq!(stored[0].kind, PasswordStorageKind::Plaintext);
+        assert_eq!(stored[0].password, "s.Token");
+    }
+
+    #[test]
+    fn test_secrets_declared_in_rules() {
+        let source = r#"
+package session
+
+import (
+    "bytes"
+    "crypto/sha256"
+
+    "example.com/app/internal/audit"
+    "example.com/app/internal/vault"
+)
+
+func check(presented []byte) bool {
+    key := vault.Get("signing")
+    sha256.Sum256(vault.Get("token"))
+    audit.Record("rotated", key)
+    audit.Record("checked", presented)
+    return bytes.Equal(presented, key)
+}
+"#;
+        let mut parser = tree_sitter::Parser::new();
+        parser
+            .set_language(&tree_sitter_go::LANGUAGE.into())
+            .unwrap();
+        let tree = parser.parse(source, None).unwrap();
+        let scanner = Scanner::with_mappings(HashMap::from([
+            (
+                "bytes".to_string(),
+                HashMap::from([("equal".to_string(), "secret_compare".to_string())]),
+            ),
+            (
+                "crypto/sha256".to_string(),
+                HashMap::from([("sum256".to_string(), "sha256".to_string())]),
+            ),
+            (
+                "example.com/app/internal/audit".to_string(),
+                HashMap::from([("record".to_string(), "password_persist".to_string())]),
+            ),
+        ]))
+        .with_secrets(DeclaredSecrets {
+            sources: vec!["example.com/app/internal/vault.Get".to_string()],
+            sinks: HashMap::from([("example.com/app/internal/audit.Record".to_string(), 1)]),
+        });
+        let result = scanner.scan_tree(&tree, source.as_bytes(), "session.go", "go");
+
+        let comparison = result
+            .calls
+            .iter()
+            .find_map(|c| c.secret_comparison.as_ref())
+            .unwrap();
+        assert_eq!(comparison.material, SecretMaterial::Declared);
+        assert_eq!(
+            comparison.provenance,
+            vec!["key".to_string(), "vault.Get(\"signing\")".to_string()]
+        );
+
+        // Hashing a declared secret is sound, and the second audit record holds none
+        let stored: Vec<_> = result
+            .calls
+            .iter()
+            .filter_map(|c| Some((c.line, c.password_storage.as_ref()?)))
+            .collect();
+        assert_eq!(stored.len(), 1);
+        assert_eq!(stored[0].0, 15);
+        assert_eq!(stored[0].1.kind, PasswordStorageKind::Plaintext);
+        assert_eq!(stored[0].1.password, "vault.Get(\"signing\")");
+    }
+}
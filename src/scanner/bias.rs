@@ -0,0 +1,604 @@
+//! Random numbers that are not uniform over their range, or `math/rand` values used as
+//! secrets.
+
+use serde::Serialize;
+use tree_sitter::Node;
+
+use super::annotation;
+use super::receiver::{callee, find_declaration};
+use super::ImportMap;
+use crate::engine::Context;
+
+const FUNCTION_KINDS: &[&str] = &["function_declaration", "method_declaration", "func_literal"];
+
+const CRYPTO_RAND: &str = "crypto/rand";
+
+const MATH_RAND: &[&str] = &["math/rand", "math/rand/v2"];
+
+/// Functions returning a random value, or filling their first argument for `Read`.
+const RANDOM_FUNCTIONS: &[&str] = &[
+    "Read", "Int", "Int31", "Int63", "Int64", "Int32", "Uint32", "Uint64", "Intn", "Int31n",
+    "Int63n", "IntN", "Int32N", "Int64N", "Uint32N", "Uint64N", "UintN", "N", "Float64", "Perm",
+];
+
+/// Functions drawing from `[0, n)` and the index of `n`.
+const BOUNDED: &[(&str, &str, usize)] = &[
+    ("crypto/rand", "Int", 1),
+    ("math/rand", "Intn", 0),
+    ("math/rand", "Int31n", 0),
+    ("math/rand", "Int63n", 0),
+    ("math/rand/v2", "IntN", 0),
+    ("math/rand/v2", "Int32N", 0),
+    ("math/rand/v2", "Int64N", 0),
+    ("math/rand/v2", "Uint32N", 0),
+    ("math/rand/v2", "Uint64N", 0),
+    ("math/rand/v2", "UintN", 0),
+    ("math/rand/v2", "N", 0),
+];
+
+/// Words of a name marking a secret, compared case-insensitively.
+const SECRET_WORDS: &[&str] = &[
+    "token",
+    "secret",
+    "password",
+    "passwd",
+    "passphrase",
+    "pwd",
+    "otp",
+    "pin",
+    "nonce",
+    "salt",
+    "iv",
+    "key",
+    "apikey",
+    "csrf",
+    "session",
+    "credential",
+];
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum BiasKind {
+    /// A random value reduced with `%` by a modulus that is not a power of two.
+    Modulo,
+    /// A bounded draw whose bound comes from another random value.
+    RandomBound,
+    /// A `math/rand` value used as a secret.
+    PredictableSecret,
+}
+
+/// A random number that is skewed toward part of its range, or predictable where it
+/// must be secret.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct RandomBias {
+    pub kind: BiasKind,
+    /// The `%` expression, the bound argument, or the secret the value ends up in, e.g.
+    /// `int(b[0]) % len(charset)`, `big.NewInt(max)` or `token`.
+    pub expression: String,
+}
+
+/// How the random value `call` returns is biased or predictable, if `function` under
+/// `import_path` is a `crypto/rand` or `math/rand` draw.
+pub(super) fn go_random_bias<'a>(
+    call: &Node<'a>,
+    import_path: Option<&str>,
+    function: &str,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Option<RandomBias> {
+    let import_path = import_path?;
+    if !is_random(import_path, function) {
+        return None;
+    }
+    let scope = enclosing_function(*call)?;
+
+    if MATH_RAND.contains(&import_path) {
+        if let Some(secret) = predictable_secret(*call, scope, ctx) {
+            return Some(RandomBias {
+                kind: BiasKind::PredictableSecret,
+                expression: secret,
+            });
+        }
+    }
+
+    if let Some((.., index)) = BOUNDED
+        .iter()
+        .find(|(package, name, _)| *package == import_path && *name == function)
+    {
+        let bound = arguments(*call).get(*index).copied();
+        if let Some(bound) = bound.filter(|bound| from_random(*bound, *call, scope, ctx, imports)) {
+            return Some(RandomBias {
+                kind: BiasKind::RandomBound,
+                expression: ctx.get_node_text(&bound),
+            });
+        }
+    }
+
+    let names = derived_names(
+        scope,
+        random_value(*call, function, ctx),
+        call.end_byte(),
+        usize::MAX,
+        ctx,
+    );
+    modulo(*call, scope, &names, ctx).map(|expression| RandomBias {
+        kind: BiasKind::Modulo,
+        expression,
+    })
+}
+
+fn is_random(import_path: &str, function: &str) -> bool {
+    (import_path == CRYPTO_RAND && matches!(function, "Read" | "Int"))
+        || (MATH_RAND.contains(&import_path) && RANDOM_FUNCTIONS.contains(&function))
+}
+
+/// The variable holding the value `call` draws: the buffer `Read` fills, or the variable
+/// the result is assigned to.
+fn random_value<'a>(call: Node<'a>, function: &str, ctx: &Context<'a>) -> Vec<String> {
+    let name = if function == "Read" {
+        arguments(call)
+            .first()
+            .filter(|buffer| buffer.kind() == "identifier")
+            .map(|buffer| ctx.get_node_text(buffer))
+    } else {
+        assigned_variable(call, ctx)
+    };
+    name.into_iter().collect()
+}
+
+/// `names` and the variables declared from them in `scope` between `from` and `to`, in
+/// source order: `v` in `v := binary.BigEndian.Uint32(b)` when `b` is in `names`.
+fn derived_names<'a>(
+    scope: Node<'a>,
+    mut names: Vec<String>,
+    from: usize,
+    to: usize,
+    ctx: &Context<'a>,
+) -> Vec<String> {
+    if names.is_empty() {
+        return names;
+    }
+    walk(scope, &mut |node| {
+        if !matches!(
+            node.kind(),
+            "short_var_declaration" | "assignment_statement"
+        ) || node.start_byte() < from
+            || node.end_byte() > to
+        {
+            return;
+        }
+        let (Some(left), Some(right)) = (
+            node.child_by_field_name("left"),
+            node.child_by_field_name("right"),
+        ) else {
+            return;
+        };
+        let targets = named_children(left);
+        let values = named_children(right);
+        for (index, value) in values.iter().enumerate() {
+            if !mentions(*value, &names, ctx) {
+                continue;
+            }
+            // `n, err := f(b)`: a single call's first result carries the value
+            let target = if values.len() == 1 {
+                targets.first()
+            } else {
+                targets.get(index)
+            };
+            if let Some(target) = target.filter(|t| t.kind() == "identifier") {
+                let name = ctx.get_node_text(target);
+                if name != "_" && !names.contains(&name) {
+                    names.push(name);
+                }
+            }
+        }
+    });
+    names
+}
+
+/// Whether `bound` uses a random value drawn earlier in `scope` than `call`.
+fn from_random<'a>(
+    bound: Node<'a>,
+    call: Node<'a>,
+    scope: Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> bool {
+    let mut seeds = Vec::new();
+    let mut inline = false;
+    walk(scope, &mut |node| {
+        if node.kind() != "call_expression" || node == call {
+            return;
+        }
+        let Some(name) = callee(node, ctx, imports) else {
+            return;
+        };
+        let Some((package, function)) = name.rsplit_once('.') else {
+            return;
+        };
+        if !is_random(package, function) {
+            return;
+        }
+        if node.start_byte() >= bound.start_byte() && node.end_byte() <= bound.end_byte() {
+            inline = true;
+        } else if node.end_byte() <= call.start_byte() {
+            seeds.extend(random_value(node, function, ctx));
+        }
+    });
+    inline
+        || mentions(
+            bound,
+            &derived_names(scope, seeds, 0, call.start_byte(), ctx),
+            ctx,
+        )
+}
+
+/// The first `%` in `scope` reducing `call` or a variable in `names` by something other
+/// than a power of two.
+fn modulo<'a>(
+    call: Node<'a>,
+    scope: Node<'a>,
+    names: &[String],
+    ctx: &Context<'a>,
+) -> Option<String> {
+    let mut found = None;
+    walk(scope, &mut |node| {
+        if found.is_some() || node.kind() != "binary_expression" {
+            return;
+        }
+        if ctx.get_field_text(&node, "operator").as_deref() != Some("%") {
+            return;
+        }
+        let (Some(left), Some(right)) = (
+            node.child_by_field_name("left"),
+            node.child_by_field_name("right"),
+        ) else {
+            return;
+        };
+        let reduces =
+            if left.start_byte() <= call.start_byte() && call.end_byte() <= left.end_byte() {
+                true
+            } else {
+                node.start_byte() >= call.end_byte() && mentions(left, names, ctx)
+            };
+        if reduces && !is_power_of_two(&ctx.get_node_text(&right)) {
+            found = Some(ctx.get_node_text(&node));
+        }
+    });
+    found
+}
+
+fn is_power_of_two(literal: &str) -> bool {
+    let literal = literal.replace('_', "");
+    let value = match literal
+        .strip_prefix("0x")
+        .or_else(|| literal.strip_prefix("0X"))
+    {
+        Some(hex) => u64::from_str_radix(hex, 16).ok(),
+        None => literal.parse::<u64>().ok(),
+    };
+    value.is_some_and(u64::is_power_of_two)
+}
+
+/// The secret a `math/rand` value ends up in: the variable it is assigned to, or the
+/// function that returns it.
+fn predictable_secret<'a>(call: Node<'a>, scope: Node<'a>, ctx: &Context<'a>) -> Option<String> {
+    let statement = enclosing_statement(call)?;
+    let function = scope
+        .child_by_field_name("name")
+        .map(|name| ctx.get_node_text(&name))
+        .filter(|name| is_secret_name(name));
+    let targets = match statement.kind() {
+        "return_statement" => return function,
+        "short_var_declaration" | "assignment_statement" => {
+            named_children(statement.child_by_field_name("left")?)
+        }
+        "var_spec" => {
+            let mut cursor = statement.walk();
+            let names: Vec<_> = statement
+                .children_by_field_name("name", &mut cursor)
+                .collect();
+            names
+        }
+        _ => return None,
+    };
+    for target in targets {
+        // `b[i] = ...` and `s.token = ...` store into `b` and `token`
+        let variable = match target.kind() {
+            "index_expression" => target.child_by_field_name("operand"),
+            _ => Some(target),
+        };
+        let Some(variable) = variable else {
+            continue;
+        };
+        let name = match variable.kind() {
+            "selector_expression" => ctx.get_field_text(&variable, "field"),
+            "identifier" => Some(ctx.get_node_text(&variable)),
+            _ => None,
+        };
+        let Some(name) = name.filter(|name| name != "_") else {
+            continue;
+        };
+        let marked = variable.kind() == "identifier"
+            && find_declaration(&variable, &name, ctx)
+                .is_some_and(|declaration| annotation::is_secret(declaration.node, ctx));
+        if is_secret_name(&name) || marked {
+            return Some(name);
+        }
+        if function.is_some() && returned(scope, &name, ctx) {
+            return function;
+        }
+    }
+    None
+}
+
+/// Whether a `return` in `scope` mentions `name`.
+fn returned<'a>(scope: Node<'a>, name: &str, ctx: &Context<'a>) -> bool {
+    let names = [name.to_string()];
+    let mut found = false;
+    walk(scope, &mut |node| {
+        found |= node.kind() == "return_statement" && mentions(node, &names, ctx);
+    });
+    found
+}
+
+/// Whether the words of `name`, split at `_` and lower-to-upper case changes, include
+/// one marking a secret: `apiKey`, `csrf_token`, `OTP`.
+fn is_secret_name(name: &str) -> bool {
+    let mut words = Vec::new();
+    let mut word = String::new();
+    let mut previous_lower = false;
+    for c in name.chars() {
+        if c == '_' || (c.is_uppercase() && previous_lower) {
+            if !word.is_empty() {
+                words.push(std::mem::take(&mut word));
+            }
+        }
+        if c != '_' {
+            word.extend(c.to_lowercase());
+        }
+        previous_lower = c.is_lowercase() || c.is_ascii_digit();
+    }
+    words.push(word);
+    words
+        .iter()
+        .any(|word| SECRET_WORDS.contains(&word.as_str()))
+}
+
+/// Whether an identifier in `node` is one of `names`.
+fn mentions<'a>(node: Node<'a>, names: &[String], ctx: &Context<'a>) -> bool {
+    if names.is_empty() {
+        return false;
+    }
+    let mut found = false;
+    walk(node, &mut |current| {
+        found |= current.kind() == "identifier" && names.contains(&ctx.get_node_text(&current));
+    });
+    found
+}
+
+/// `n` in `n, err := rand.Int(rand.Reader, max)` or `var n = ...`.
+fn assigned_variable<'a>(call: Node<'a>, ctx: &Context<'a>) -> Option<String> {
+    let values = call.parent()?;
+    let statement = values.parent()?;
+    let index = named_children(values)
+        .iter()
+        .position(|value| *value == call)?;
+    let name = match statement.kind() {
+        "short_var_declaration" | "assignment_statement" => {
+            statement.child_by_field_name("left")?.named_child(index)?
+        }
+        "var_spec" => {
+            let mut cursor = statement.walk();
+            let name = statement
+                .children_by_field_name("name", &mut cursor)
+                .nth(index);
+            name?
+        }
+        _ => return None,
+    };
+    (name.kind() == "identifier").then(|| ctx.get_node_text(&name))
+}
+
+/// The statement `node` is part of, stopping at its function.
+fn enclosing_statement(node: Node<'_>) -> Option<Node<'_>> {
+    let mut current = node.parent();
+    while let Some(parent) = current {
+        if FUNCTION_KINDS.contains(&parent.kind()) || parent.kind() == "block" {
+            return None;
+        }
+        if matches!(
+            parent.kind(),
+            "return_statement" | "short_var_declaration" | "assignment_statement" | "var_spec"
+        ) {
+            return Some(parent);
+        }
+        current = parent.parent();
+    }
+    None
+}
+
+fn arguments(call: Node<'_>) -> Vec<Node<'_>> {
+    call.child_by_field_name("arguments")
+        .map(named_children)
+        .unwrap_or_default()
+}
+
+fn named_children(node: Node<'_>) -> Vec<Node<'_>> {
+    let mut cursor = node.walk();
+    let nodes: Vec<_> = node.named_children(&mut cursor).collect();
+    nodes
+}
+
+fn enclosing_function(node: Node<'_>) -> Option<Node<'_>> {
+    let mut current = node.parent();
+    while let Some(parent) = current {
+        if FUNCTION_KINDS.contains(&parent.kind()) {
+            return Some(parent);
+        }
+        current = parent.parent();
+    }
+    None
+}
+
+fn walk<'a>(root: Node<'a>, visit: &mut impl FnMut(Node<'a>)) {
+    let mut stack = vec![root];
+    while let Some(node) = stack.pop() {
+        visit(node);
+        let mut cursor = node.walk();
+        let children: Vec<_> = node.named_children(&mut cursor).collect();
+        stack.extend(children.into_iter().rev());
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::scanner::Scanner;
+    use std::collections::HashMap;
+
+    fn biases(source: &str) -> Vec<(String, Option<RandomBias>)> {
+        let mut parser = tree_sitter::Parser::new();
+        parser
+            .set_language(&tree_sitter_go::LANGUAGE.into())
+            .unwrap();
+        let tree = parser.parse(source, None).unwrap();
+        let functions: HashMap<String, String> = RANDOM_FUNCTIONS
+            .iter()
+            .map(|name| (name.to_lowercase(), "random".to_string()))
+            .collect();
+        let scanner = Scanner::with_mappings(HashMap::from([
+            (CRYPTO_RAND.to_string(), functions.clone()),
+            ("math/rand".to_string(), functions),
+        ]));
+        let result = scanner.scan_tree(&tree, source.as_bytes(), "random.go", "go");
+        result
+            .calls
+            .into_iter()
+            .map(|call| (call.function_name, call.random_bias))
+            .collect()
+    }
+
+    fn bias(kind: BiasKind, expression: &str) -> Option<RandomBias> {
+        Some(RandomBias {
+            kind,
+            expression: expression.to_string(),
+        })
+    }
+
+    #[test]
+    fn test_secret_names() {
+        for name in [
+            "token",
+            "apiKey",
+            "csrf_token",
+            "OTP",
+            "sessionID",
+            "resetPin",
+        ] {
+            assert!(is_secret_name(name), "{name}");
+        }
+        for name in ["monkey", "jitter", "index", "keyboardLayout", "spinner"] {
+            assert!(!is_secret_name(name), "{name}");
+        }
+    }
+
+    #[test]
+    fn test_modulo_and_random_bounds() {
+        let found = biases(
+            r#"
+package code
+
+import (
+    "crypto/rand"
+    "encoding/binary"
+    "math/big"
+)
+
+const charset = "0123456789"
+
+func pick() byte {
+    b := make([]byte, 4)
+    rand.Read(b)
+    v := binary.BigEndian.Uint32(b)
+    return charset[v%uint32(len(charset))]
+}
+
+func nibble() byte {
+    b := make([]byte, 1)
+    rand.Read(b)
+    return b[0] % 16
+}
+
+func nested() (*big.Int, error) {
+    n, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+    if err != nil {
+        return nil, err
+    }
+    randomInt64 := n.Int64()
+    return rand.Int(rand.Reader, big.NewInt(randomInt64))
+}
+"#,
+        );
+        assert_eq!(
+            found,
+            vec![
+                (
+                    "Read".to_string(),
+                    bias(BiasKind::Modulo, "v%uint32(len(charset))")
+                ),
+                ("Read".to_string(), None),
+                ("Int".to_string(), None),
+                (
+                    "Int".to_string(),
+                    bias(BiasKind::RandomBound, "big.NewInt(randomInt64)")
+                ),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_math_rand_secrets() {
+        let found = biases(
+            r#"
+package code
+
+import "math/rand"
+
+const letters = "abcdefghijklmnopqrstuvwxyz"
+
+func generateToken(n int) string {
+    b := make([]byte, n)
+    for i := range b {
+        b[i] = letters[rand.Intn(len(letters))]
+    }
+    return string(b)
+}
+
+func issue() {
+    otp := rand.Int63n(1000000)
+    send(otp)
+}
+
+func backoff() int {
+    jitter := rand.Intn(100)
+    return jitter
+}
+"#,
+        );
+        assert_eq!(
+            found,
+            vec![
+                (
+                    "Intn".to_string(),
+                    bias(BiasKind::PredictableSecret, "generateToken")
+                ),
+                (
+                    "Int63n".to_string(),
+                    bias(BiasKind::PredictableSecret, "otp")
+                ),
+                ("Intn".to_string(), None),
+            ]
+        );
+    }
+}
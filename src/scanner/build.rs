@@ -0,0 +1,105 @@
+//! Build constraint detection for Go source files.
+
+use std::path::Path;
+
+const KNOWN_GOOS: &[&str] = &[
+    "aix",
+    "android",
+    "darwin",
+    "dragonfly",
+    "freebsd",
+    "hurd",
+    "illumos",
+    "ios",
+    "js",
+    "linux",
+    "nacl",
+    "netbsd",
+    "openbsd",
+    "plan9",
+    "solaris",
+    "wasip1",
+    "windows",
+    "zos",
+];
+
+const KNOWN_GOARCH: &[&str] = &[
+    "386", "amd64", "arm", "arm64", "loong64", "mips", "mips64", "mips64le", "mipsle", "ppc64",
+    "ppc64le", "riscv64", "s390x", "wasm",
+];
+
+/// Returns the build constraint of a Go file, or `None` if it is built in every configuration.
+///
+/// An explicit `//go:build` line wins; otherwise the constraint implied by the file name
+/// suffix (e.g. `key_linux_arm64.go` -> `linux && arm64`) is used.
+pub fn go_build_constraint(source: &str, file_path: &str) -> Option<String> {
+    go_build_directive(source).or_else(|| file_name_constraint(file_path))
+}
+
+fn go_build_directive(source: &str) -> Option<String> {
+    for line in source.lines() {
+        let trimmed = line.trim();
+        if trimmed.is_empty() {
+            continue;
+        }
+        if let Some(expr) = trimmed.strip_prefix("//go:build") {
+            let expr = expr.trim();
+            return (!expr.is_empty()).then(|| expr.to_string());
+        }
+        // Constraints must appear before the package clause, among other comments only
+        if !trimmed.starts_with("//") {
+            return None;
+        }
+    }
+    None
+}
+
+fn file_name_constraint(file_path: &str) -> Option<String> {
+    let stem = Path::new(file_path).file_stem()?.to_str()?;
+    let stem = stem.strip_suffix("_test").unwrap_or(stem);
+    let parts: Vec<&str> = stem.split('_').collect();
+
+    match parts.as_slice() {
+        [_, .., goos, goarch] if KNOWN_GOOS.contains(goos) && KNOWN_GOARCH.contains(goarch) => {
+            Some(format!("{goos} && {goarch}"))
+        }
+        [_, .., last] if KNOWN_GOOS.contains(last) || KNOWN_GOARCH.contains(last) => {
+            Some(last.to_string())
+        }
+        _ => None,
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_go_build_directive() {
+        let source = "// Copyright\n\n//go:build linux && !cgo\n\npackage keys\n";
+        assert_eq!(
+            go_build_constraint(source, "keys.go"),
+            Some("linux && !cgo".to_string())
+        );
+    }
+
+    #[test]
+    fn test_directive_after_package_is_ignored() {
+        let source = "package keys\n\n//go:build linux\n";
+        assert_eq!(go_build_constraint(source, "keys.go"), None);
+    }
+
+    #[test]
+    fn test_file_name_constraints() {
+        assert_eq!(
+            go_build_constraint("package keys", "pkg/keys_windows.go"),
+            Some("windows".to_string())
+        );
+        assert_eq!(
+            go_build_constraint("package keys", "pkg/keys_linux_arm64_test.go"),
+            Some("linux && arm64".to_string())
+        );
+        assert_eq!(go_build_constraint("package keys", "pkg/keys.go"), None);
+        assert_eq!(go_build_constraint("package keys", "pkg/linux.go"), None);
+    }
+}
@@ -0,0 +1,508 @@
+//! Go functions that run because a framework calls them back, such as HTTP handlers and
+//! cron jobs.
+
+use serde::Serialize;
+use tree_sitter::Node;
+
+use super::receiver::{callee, go_receiver_type};
+use super::ImportMap;
+use crate::engine::Context;
+use crate::utils::unquote_string;
+
+const FUNCTION_KINDS: &[&str] = &["function_declaration", "method_declaration", "func_literal"];
+
+/// Registrars, the index of their first handler argument (later arguments are handlers
+/// too, as with variadic middleware chains) and what they register.
+const REGISTRARS: &[(&str, usize, CallbackKind)] = &[
+    ("net/http.HandleFunc", 1, CallbackKind::HttpHandler),
+    ("net/http.Handle", 1, CallbackKind::HttpHandler),
+    ("net/http.HandlerFunc", 0, CallbackKind::HttpHandler),
+    ("net/http.ServeMux.HandleFunc", 1, CallbackKind::HttpHandler),
+    ("net/http.ServeMux.Handle", 1, CallbackKind::HttpHandler),
+    (
+        "github.com/gorilla/mux.Router.HandleFunc",
+        1,
+        CallbackKind::HttpHandler,
+    ),
+    (
+        "github.com/go-chi/chi/v5.Mux.Get",
+        1,
+        CallbackKind::HttpHandler,
+    ),
+    (
+        "github.com/go-chi/chi/v5.Mux.Post",
+        1,
+        CallbackKind::HttpHandler,
+    ),
+    (
+        "github.com/go-chi/chi/v5.Mux.Put",
+        1,
+        CallbackKind::HttpHandler,
+    ),
+    (
+        "github.com/go-chi/chi/v5.Mux.Patch",
+        1,
+        CallbackKind::HttpHandler,
+    ),
+    (
+        "github.com/go-chi/chi/v5.Mux.Delete",
+        1,
+        CallbackKind::HttpHandler,
+    ),
+    (
+        "github.com/go-chi/chi/v5.Mux.HandleFunc",
+        1,
+        CallbackKind::HttpHandler,
+    ),
+    (
+        "github.com/gin-gonic/gin.Engine.GET",
+        1,
+        CallbackKind::HttpHandler,
+    ),
+    (
+        "github.com/gin-gonic/gin.Engine.POST",
+        1,
+        CallbackKind::HttpHandler,
+    ),
+    (
+        "github.com/gin-gonic/gin.Engine.PUT",
+        1,
+        CallbackKind::HttpHandler,
+    ),
+    (
+        "github.com/gin-gonic/gin.Engine.PATCH",
+        1,
+        CallbackKind::HttpHandler,
+    ),
+    (
+        "github.com/gin-gonic/gin.Engine.DELETE",
+        1,
+        CallbackKind::HttpHandler,
+    ),
+    (
+        "github.com/robfig/cron/v3.Cron.AddFunc",
+        1,
+        CallbackKind::Scheduled,
+    ),
+    (
+        "github.com/robfig/cron.Cron.AddFunc",
+        1,
+        CallbackKind::Scheduled,
+    ),
+    ("time.AfterFunc", 1, CallbackKind::Scheduled),
+    (
+        "golang.org/x/sync/errgroup.Group.Go",
+        0,
+        CallbackKind::Worker,
+    ),
+    ("sync.WaitGroup.Go", 0, CallbackKind::Worker),
+    (
+        "github.com/panjf2000/ants/v2.Submit",
+        0,
+        CallbackKind::Worker,
+    ),
+    (
+        "github.com/panjf2000/ants/v2.Pool.Submit",
+        0,
+        CallbackKind::Worker,
+    ),
+    (
+        "github.com/alitto/pond.WorkerPool.Submit",
+        0,
+        CallbackKind::Worker,
+    ),
+];
+
+/// How many functions are followed back through callers before giving up.
+const MAX_FUNCTIONS: usize = 16;
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum CallbackKind {
+    /// An HTTP handler on a mux or router.
+    HttpHandler,
+    /// A cron job or timer callback.
+    Scheduled,
+    /// A task handed to a goroutine group or worker pool.
+    Worker,
+}
+
+/// The registration through which a framework calls the function enclosing a finding.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct CallbackRegistration {
+    pub kind: CallbackKind,
+    /// The registrar, e.g. `net/http.ServeMux.HandleFunc`.
+    pub registrar: String,
+    /// The registered function, e.g. `handleLogin` or `Server.rotate`; `func literal` for
+    /// an anonymous handler.
+    pub handler: String,
+    /// The route or schedule it is registered under, when it is a string literal.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub pattern: Option<String>,
+    /// Line of the registering call.
+    pub line: usize,
+}
+
+/// The registration making `call` reachable, if its enclosing function or one of that
+/// function's callers in the file is a registered callback.
+pub(super) fn go_registered_callback<'a>(
+    call: &Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Option<CallbackRegistration> {
+    let root = file_root(*call);
+    let mut pending: Vec<Node<'a>> = enclosing_function(*call).into_iter().collect();
+    let mut seen = Vec::new();
+    while let Some(function) = pending.pop() {
+        if seen.len() >= MAX_FUNCTIONS || seen.contains(&function.id()) {
+            continue;
+        }
+        seen.push(function.id());
+        if let Some(registration) = registration(root, function, ctx, imports) {
+            return Some(registration);
+        }
+        pending.extend(callers(root, function, ctx));
+    }
+    None
+}
+
+/// How `function` is registered with a framework in the file rooted at `root`, if it is.
+pub(super) fn registration<'a>(
+    root: Node<'a>,
+    function: Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Option<CallbackRegistration> {
+    // `http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) { ... })`
+    if function.kind() == "func_literal" {
+        let arguments = function.parent().filter(|p| p.kind() == "argument_list");
+        if let Some(call) = arguments.and_then(|arguments| arguments.parent()) {
+            if let Some(registration) = registered(call, function, "func literal", ctx, imports) {
+                return Some(registration);
+            }
+        }
+    }
+
+    let name = handler_name(function, ctx)?;
+    let method = function.kind() == "method_declaration";
+    let mut found = None;
+    walk(root, &mut |node| {
+        if found.is_some() || node.kind() != "call_expression" {
+            return;
+        }
+        let Some(arguments) = node.child_by_field_name("arguments") else {
+            return;
+        };
+        let mut cursor = arguments.walk();
+        let handler =
+            arguments
+                .named_children(&mut cursor)
+                .find(|argument| match argument.kind() {
+                    "identifier" => !method && ctx.get_node_text(argument) == name,
+                    // `s.rotate` as a method value
+                    "selector_expression" => {
+                        method
+                            && ctx.get_field_text(argument, "field").as_deref()
+                                == Some(name.as_str())
+                    }
+                    _ => false,
+                });
+        found = handler.and_then(|handler| {
+            registered(
+                node,
+                handler,
+                &display_name(function, &name, ctx),
+                ctx,
+                imports,
+            )
+        });
+    });
+    found
+}
+
+/// A registration if `call` is a registrar and `handler` is one of its handler arguments.
+fn registered<'a>(
+    call: Node<'a>,
+    handler: Node<'a>,
+    handler_label: &str,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Option<CallbackRegistration> {
+    let (registrar, first_handler, kind) = registrar(call, ctx, imports)?;
+    let arguments = call.child_by_field_name("arguments")?;
+    let mut cursor = arguments.walk();
+    let position = arguments
+        .named_children(&mut cursor)
+        .position(|argument| argument.id() == handler.id())?;
+    if position < first_handler {
+        return None;
+    }
+    let pattern = (first_handler > 0)
+        .then(|| arguments.named_child(0))
+        .flatten()
+        .filter(|first| {
+            matches!(
+                first.kind(),
+                "interpreted_string_literal" | "raw_string_literal"
+            )
+        })
+        .map(|first| unquote_string(&ctx.get_node_text(&first)));
+    Some(CallbackRegistration {
+        kind,
+        registrar: registrar.to_string(),
+        handler: handler_label.to_string(),
+        pattern,
+        line: call.start_position().row + 1,
+    })
+}
+
+/// The registrar entry `call` invokes: a package function, or a method resolved through
+/// its receiver's type.
+fn registrar<'a>(
+    call: Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Option<(&'static str, usize, CallbackKind)> {
+    let function = call.child_by_field_name("function")?;
+    if function.kind() != "selector_expression" {
+        return None;
+    }
+    let method = ctx.get_field_text(&function, "field")?;
+    let suffix = format!(".{method}");
+    if !REGISTRARS.iter().any(|(name, ..)| name.ends_with(&suffix)) {
+        return None;
+    }
+    let name = callee(call, ctx, imports).or_else(|| {
+        let operand = ctx.get_field_text(&function, "operand")?;
+        let receiver = go_receiver_type(&call, &operand, ctx, imports)?;
+        Some(format!("{}{suffix}", receiver.type_name))
+    })?;
+    REGISTRARS
+        .iter()
+        .find(|(registrar, ..)| *registrar == name)
+        .copied()
+}
+
+/// The name a function is handed to a registrar by: its own name, or for a function
+/// literal the variable it is assigned to.
+fn handler_name<'a>(function: Node<'a>, ctx: &Context<'a>) -> Option<String> {
+    if function.kind() != "func_literal" {
+        return ctx.get_field_text(&function, "name");
+    }
+    // `h := func(...) { ... }`
+    let values = function
+        .parent()
+        .filter(|p| p.kind() == "expression_list")?;
+    let declaration = values
+        .parent()
+        .filter(|p| matches!(p.kind(), "short_var_declaration" | "assignment_statement"))?;
+    let left = declaration.child_by_field_name("left")?;
+    let mut cursor = values.walk();
+    let index = values
+        .named_children(&mut cursor)
+        .position(|value| value.id() == function.id())?;
+    let name = left.named_child(index)?;
+    (name.kind() == "identifier").then(|| ctx.get_node_text(&name))
+}
+
+/// `name`, or `Type.name` for a method.
+fn display_name<'a>(function: Node<'a>, name: &str, ctx: &Context<'a>) -> String {
+    if function.kind() == "func_literal" {
+        return "func literal".to_string();
+    }
+    let receiver_type = function
+        .child_by_field_name("receiver")
+        .and_then(|receiver| receiver.named_child(0))
+        .and_then(|parameter| parameter.child_by_field_name("type"))
+        .map(|type_node| match type_node.kind() {
+            "pointer_type" => type_node.named_child(0).unwrap_or(type_node),
+            _ => type_node,
+        });
+    match receiver_type {
+        Some(type_node) => format!("{}.{name}", ctx.get_node_text(&type_node)),
+        None => name.to_string(),
+    }
+}
+
+/// Functions in the file that call `function`; a function literal runs as part of the
+/// function it is written in.
+fn callers<'a>(root: Node<'a>, function: Node<'a>, ctx: &Context<'a>) -> Vec<Node<'a>> {
+    if function.kind() == "func_literal" {
+        return enclosing_function(function).into_iter().collect();
+    }
+    let Some(name) = ctx.get_field_text(&function, "name") else {
+        return Vec::new();
+    };
+    let method = function.kind() == "method_declaration";
+    let mut callers = Vec::new();
+    walk(root, &mut |node| {
+        if node.kind() != "call_expression" {
+            return;
+        }
+        let calls =
+            node.child_by_field_name("function")
+                .is_some_and(|callee| match callee.kind() {
+                    "identifier" => !method && ctx.get_node_text(&callee) == name,
+                    "selector_expression" => {
+                        method
+                            && ctx.get_field_text(&callee, "field").as_deref()
+                                == Some(name.as_str())
+                    }
+                    _ => false,
+                });
+        if calls {
+            callers.extend(enclosing_function(node));
+        }
+    });
+    callers
+}
+
+fn enclosing_function(node: Node<'_>) -> Option<Node<'_>> {
+    let mut current = node.parent();
+    while let Some(parent) = current {
+        if FUNCTION_KINDS.contains(&parent.kind()) {
+            return Some(parent);
+        }
+        current = parent.parent();
+    }
+    None
+}
+
+fn file_root(node: Node<'_>) -> Node<'_> {
+    let mut root = node;
+    while let Some(parent) = root.parent() {
+        root = parent;
+    }
+    root
+}
+
+fn walk<'a>(root: Node<'a>, visit: &mut impl FnMut(Node<'a>)) {
+    let mut stack = vec![root];
+    while let Some(node) = stack.pop() {
+        visit(node);
+        let mut cursor = node.walk();
+        let children: Vec<_> = node.named_children(&mut cursor).collect();
+        stack.extend(children.into_iter().rev());
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::scanner::Scanner;
+    use std::collections::HashMap;
+
+    #[test]
+    fn test_registered_handlers_jobs_and_tasks() {
+        let source = r#"
+package server
+
+import (
+    "crypto/sha256"
+    "net/http"
+
+    "github.com/robfig/cron"
+    "golang.org/x/sync/errgroup"
+)
+
+type Server struct {
+    mux *http.ServeMux
+}
+
+func (s *Server) routes() {
+    s.mux.HandleFunc("/login", s.handleLogin)
+    http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+        sha256.Sum256(nil)
+    })
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+    digest(nil)
+}
+
+func digest(b []byte) [32]byte {
+    return sha256.Sum256(b)
+}
+
+func schedule() {
+    c := cron.New()
+    c.AddFunc("@hourly", rotate)
+    var g errgroup.Group
+    g.Go(func() error {
+        sha256.Sum256(nil)
+        return nil
+    })
+}
+
+func rotate() {
+    sha256.Sum256(nil)
+}
+
+func unused() {
+    sha256.Sum256(nil)
+}
+"#;
+        let mut parser = tree_sitter::Parser::new();
+        parser
+            .set_language(&tree_sitter_go::LANGUAGE.into())
+            .unwrap();
+        let tree = parser.parse(source, None).unwrap();
+        let scanner = Scanner::with_mappings(HashMap::from([(
+            "crypto/sha256".to_string(),
+            HashMap::from([("sum256".to_string(), "sha256".to_string())]),
+        )]));
+        let result = scanner.scan_tree(&tree, source.as_bytes(), "server.go", "go");
+
+        let callbacks: Vec<_> = result
+            .calls
+            .iter()
+            .map(|call| (call.line, call.callback.clone()))
+            .collect();
+        assert_eq!(
+            callbacks,
+            vec![
+                (
+                    19,
+                    Some(CallbackRegistration {
+                        kind: CallbackKind::HttpHandler,
+                        registrar: "net/http.HandleFunc".to_string(),
+                        handler: "func literal".to_string(),
+                        pattern: Some("/health".to_string()),
+                        line: 18,
+                    })
+                ),
+                (
+                    28,
+                    Some(CallbackRegistration {
+                        kind: CallbackKind::HttpHandler,
+                        registrar: "net/http.ServeMux.HandleFunc".to_string(),
+                        handler: "Server.handleLogin".to_string(),
+                        pattern: Some("/login".to_string()),
+                        line: 17,
+                    })
+                ),
+                (
+                    36,
+                    Some(CallbackRegistration {
+                        kind: CallbackKind::Worker,
+                        registrar: "golang.org/x/sync/errgroup.Group.Go".to_string(),
+                        handler: "func literal".to_string(),
+                        pattern: None,
+                        line: 35,
+                    })
+                ),
+                (
+                    42,
+                    Some(CallbackRegistration {
+                        kind: CallbackKind::Scheduled,
+                        registrar: "github.com/robfig/cron.Cron.AddFunc".to_string(),
+                        handler: "rotate".to_string(),
+                        pattern: Some("@hourly".to_string()),
+                        line: 33,
+                    })
+                ),
+                (46, None),
+            ]
+        );
+    }
+}
@@ -0,0 +1,362 @@
+//! MACs, derived keys and declared secrets compared in variable time with `bytes.Equal`,
+//! `==` and the like.
+
+use serde::Serialize;
+use tree_sitter::Node;
+
+use super::annotation::{self, DeclaredSecrets};
+use super::receiver::{callee, find_declaration};
+use super::ImportMap;
+use crate::engine::Context;
+
+/// Calls comparing their first two arguments byte by byte.
+const COMPARISONS: &[&str] = &["bytes.Equal", "bytes.Compare", "reflect.DeepEqual"];
+
+/// Pseudo-package comparison operators are matched under.
+pub const OPERATOR_PACKAGE: &str = "builtin";
+
+/// Calls returning derived or agreed key material.
+const KEY_SOURCES: &[&str] = &[
+    "golang.org/x/crypto/pbkdf2.Key",
+    "crypto/pbkdf2.Key",
+    "golang.org/x/crypto/scrypt.Key",
+    "golang.org/x/crypto/argon2.Key",
+    "golang.org/x/crypto/argon2.IDKey",
+    "golang.org/x/crypto/hkdf.Extract",
+    "crypto/hkdf.Key",
+    "crypto/hkdf.Extract",
+    "golang.org/x/crypto/curve25519.X25519",
+];
+
+const MAC_CONSTRUCTOR: &str = "crypto/hmac.New";
+
+/// Calls a secret is followed through because their result still holds it.
+const PASSTHROUGH: &[&str] = &["fmt.Sprintf", "fmt.Sprint"];
+
+/// How many declarations an operand is followed through.
+const MAX_DEPTH: usize = 4;
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum SecretMaterial {
+    /// An HMAC sum.
+    Mac,
+    /// Derived or agreed key material.
+    Key,
+    /// A value marked `//argflow:secret` or returned by a declared source.
+    Declared,
+}
+
+impl SecretMaterial {
+    pub fn as_str(self) -> &'static str {
+        match self {
+            SecretMaterial::Mac => "a MAC",
+            SecretMaterial::Key => "key material",
+            SecretMaterial::Declared => "a declared secret",
+        }
+    }
+}
+
+/// A secret operand of a comparison that does not run in constant time.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct SecretComparison {
+    pub material: SecretMaterial,
+    /// The compared operand holding the secret, e.g. `expected`.
+    pub operand: String,
+    /// The operand and the expressions it was traced through, ending at the call that
+    /// produced the secret: `expected`, `mac.Sum(nil)`, `hmac.New(sha256.New, key)`.
+    pub provenance: Vec<String>,
+}
+
+/// Whether `function` under `import_path` is only a sink when a secret reaches it.
+pub(super) fn is_comparison_sink(import_path: Option<&str>, function: &str) -> bool {
+    import_path.is_some_and(|path| {
+        path == OPERATOR_PACKAGE || COMPARISONS.contains(&format!("{path}.{function}").as_str())
+    })
+}
+
+/// The secret operand of `call` if `function` under `import_path` is a comparison.
+pub(super) fn go_secret_comparison<'a>(
+    call: &Node<'a>,
+    import_path: Option<&str>,
+    function: &str,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+    secrets: &DeclaredSecrets,
+) -> Option<SecretComparison> {
+    let name = format!("{}.{function}", import_path?);
+    if !COMPARISONS.contains(&name.as_str()) {
+        return None;
+    }
+    let args = ctx.get_named_children(&call.child_by_field_name("arguments")?);
+    args.into_iter()
+        .take(2)
+        .find_map(|operand| secret_operand(operand, call, ctx, imports, secrets))
+}
+
+/// The operator and secret operand of an `==` or `!=` expression.
+pub(super) fn go_operator_comparison<'a>(
+    node: &Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+    secrets: &DeclaredSecrets,
+) -> Option<(String, SecretComparison)> {
+    if node.kind() != "binary_expression" {
+        return None;
+    }
+    let operator = ctx.get_node_text(&node.child_by_field_name("operator")?);
+    if operator != "==" && operator != "!=" {
+        return None;
+    }
+    let sides = [
+        node.child_by_field_name("left")?,
+        node.child_by_field_name("right")?,
+    ];
+    // `mac == nil` compares no bytes
+    if sides.iter().any(|side| side.kind() == "nil") {
+        return None;
+    }
+    let comparison = sides
+        .into_iter()
+        .find_map(|operand| secret_operand(operand, node, ctx, imports, secrets))?;
+    Some((operator, comparison))
+}
+
+fn secret_operand<'a>(
+    operand: Node<'a>,
+    anchor: &Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+    secrets: &DeclaredSecrets,
+) -> Option<SecretComparison> {
+    let (material, mut provenance) = secret_in(operand, anchor, ctx, imports, secrets, 0)?;
+    let text = ctx.get_node_text(&operand);
+    provenance.insert(0, text.clone());
+    Some(SecretComparison {
+        material,
+        operand: text,
+        provenance,
+    })
+}
+
+/// The material `node` holds and the expressions below it the trace went through.
+fn secret_in<'a>(
+    node: Node<'a>,
+    anchor: &Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+    secrets: &DeclaredSecrets,
+    depth: usize,
+) -> Option<(SecretMaterial, Vec<String>)> {
+    let follow = |child: Node<'a>| secret_in(child, anchor, ctx, imports, secrets, depth);
+    match node.kind() {
+        "identifier" => {
+            if depth >= MAX_DEPTH {
+                return None;
+            }
+            let declaration = find_declaration(anchor, &ctx.get_node_text(&node), ctx)?;
+            if annotation::is_secret(declaration.node, ctx) {
+                return Some((SecretMaterial::Declared, Vec::new()));
+            }
+            let value = declaration.value?;
+            let (material, mut chain) = secret_in(value, anchor, ctx, imports, secrets, depth + 1)?;
+            chain.insert(0, ctx.get_node_text(&value));
+            Some((material, chain))
+        }
+        "selector_expression" => {
+            let field = ctx.get_field_text(&node, "field")?;
+            annotation::is_secret_field(node, &field, ctx)
+                .then_some((SecretMaterial::Declared, Vec::new()))
+        }
+        "parenthesized_expression" => follow(node.named_child(0)?),
+        "slice_expression" | "type_conversion_expression" => {
+            follow(node.child_by_field_name("operand")?)
+        }
+        "call_expression" => {
+            let function = node.child_by_field_name("function")?;
+            let mut args = ctx.get_named_children(&node.child_by_field_name("arguments")?);
+            match function.kind() {
+                // `string(mac)`, `[]byte(key)`
+                "identifier" if ctx.get_node_text(&function) == "string" => {
+                    follow(args.into_iter().next()?)
+                }
+                "slice_type" | "parenthesized_type" => follow(args.into_iter().next()?),
+                "selector_expression" => {
+                    if let Some(name) = callee(node, ctx, imports) {
+                        if KEY_SOURCES.contains(&name.as_str()) {
+                            return Some((SecretMaterial::Key, Vec::new()));
+                        }
+                        if secrets.is_source(&name) {
+                            return Some((SecretMaterial::Declared, Vec::new()));
+                        }
+                        if PASSTHROUGH.contains(&name.as_str()) {
+                            return args.into_iter().find_map(follow);
+                        }
+                    }
+                    match ctx.get_field_text(&function, "field")?.as_str() {
+                        // `hex.EncodeToString(mac)`, `base64.StdEncoding.EncodeToString(mac)`
+                        "EncodeToString" if !args.is_empty() => follow(args.remove(0)),
+                        "Sum" => {
+                            let receiver = function.child_by_field_name("operand")?;
+                            mac_sum(receiver, anchor, ctx, imports, depth)
+                        }
+                        _ => None,
+                    }
+                }
+                _ => None,
+            }
+        }
+        _ => None,
+    }
+}
+
+/// The chain of a `Sum` call on `receiver` when the receiver is built by `hmac.New`.
+fn mac_sum<'a>(
+    receiver: Node<'a>,
+    anchor: &Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+    depth: usize,
+) -> Option<(SecretMaterial, Vec<String>)> {
+    let (constructor, chain) = match receiver.kind() {
+        "identifier" => {
+            let (value, text) = declared_value(receiver, anchor, ctx, depth)?;
+            (value, vec![text])
+        }
+        "call_expression" => (receiver, Vec::new()),
+        _ => return None,
+    };
+    (callee(constructor, ctx, imports).as_deref() == Some(MAC_CONSTRUCTOR))
+        .then_some((SecretMaterial::Mac, chain))
+}
+
+/// The value assigned to the identifier `node` and its text.
+fn declared_value<'a>(
+    node: Node<'a>,
+    anchor: &Node<'a>,
+    ctx: &Context<'a>,
+    depth: usize,
+) -> Option<(Node<'a>, String)> {
+    if depth >= MAX_DEPTH {
+        return None;
+    }
+    let value = find_declaration(anchor, &ctx.get_node_text(&node), ctx)?.value?;
+    Some((value, ctx.get_node_text(&value)))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::scanner::Scanner;
+    use std::collections::HashMap;
+
+    #[test]
+    fn test_secret_comparisons() {
+        let source = r#"
+package auth
+
+import (
+    "bytes"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+
+    "golang.org/x/crypto/argon2"
+)
+
+func verify(key, msg, sig []byte, header string) bool {
+    mac := hmac.New(sha256.New, key)
+    mac.Write(msg)
+    expected := mac.Sum(nil)
+    if hex.EncodeToString(expected) == header {
+        return true
+    }
+    if hmac.Equal(sig, expected) {
+        return true
+    }
+    return bytes.Equal(sig, expected)
+}
+
+func login(password, salt, stored []byte, name string) bool {
+    if name == "admin" || bytes.Equal(salt, stored) {
+        return false
+    }
+    derived := argon2.IDKey(password, salt, 1, 64*1024, 4, 32)
+    return bytes.Equal(derived, stored)
+}
+"#;
+        let mut parser = tree_sitter::Parser::new();
+        parser
+            .set_language(&tree_sitter_go::LANGUAGE.into())
+            .unwrap();
+        let tree = parser.parse(source, None).unwrap();
+        let scanner = Scanner::with_mappings(HashMap::from([
+            (
+                "bytes".to_string(),
+                HashMap::from([("equal".to_string(), "secret_compare".to_string())]),
+            ),
+            (
+                "builtin".to_string(),
+                HashMap::from([("==".to_string(), "secret_compare".to_string())]),
+            ),
+        ]));
+        let result = scanner.scan_tree(&tree, source.as_bytes(), "auth.go", "go");
+
+        // `hmac.Equal` is constant time, and neither the name check nor the salt
+        // comparison involves a secret
+        let compared: Vec<_> = result
+            .calls
+            .iter()
+            .map(|c| {
+                (
+                    c.line,
+                    c.function_name.as_str(),
+                    c.secret_comparison.clone(),
+                )
+            })
+            .collect();
+        assert_eq!(
+            compared,
+            vec![
+                (
+                    17,
+                    "==",
+                    Some(SecretComparison {
+                        material: SecretMaterial::Mac,
+                        operand: "hex.EncodeToString(expected)".to_string(),
+                        provenance: vec![
+                            "hex.EncodeToString(expected)".to_string(),
+                            "mac.Sum(nil)".to_string(),
+                            "hmac.New(sha256.New, key)".to_string(),
+                        ],
+                    })
+                ),
+                (
+                    23,
+                    "Equal",
+                    Some(SecretComparison {
+                        material: SecretMaterial::Mac,
+                        operand: "expected".to_string(),
+                        provenance: vec![
+                            "expected".to_string(),
+                            "mac.Sum(nil)".to_string(),
+                            "hmac.New(sha256.New, key)".to_string(),
+                        ],
+                    })
+                ),
+                (
+                    31,
+                    "Equal",
+                    Some(SecretComparison {
+                        material: SecretMaterial::Key,
+                        operand: "derived".to_string(),
+                        provenance: vec![
+                            "derived".to_string(),
+                            "argon2.IDKey(password, salt, 1, 64*1024, 4, 32)".to_string(),
+                        ],
+                    })
+                ),
+            ]
+        );
+    }
+}
@@ -0,0 +1,155 @@
+//! Panics and fatal exits in library code around crypto calls.
+
+use serde::Serialize;
+use tree_sitter::Node;
+
+use super::receiver::callee;
+use super::ImportMap;
+use crate::engine::Context;
+
+const FUNCTION_KINDS: &[&str] = &["function_declaration", "method_declaration", "func_literal"];
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum CrashKind {
+    /// `panic(...)` or `log.Panic*`: unwinds unless a caller recovers.
+    Panic,
+    /// `log.Fatal*`: exits the process, skipping deferred calls.
+    Fatal,
+}
+
+/// A statement in the function enclosing a crypto call that brings the process down.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct CrashPath {
+    pub kind: CrashKind,
+    pub line: usize,
+    pub text: String,
+}
+
+/// Panics and fatal exits of the function enclosing `call`, outside package `main`.
+pub(super) fn go_crash_paths<'a>(
+    call: &Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Vec<CrashPath> {
+    let mut scope = call.parent();
+    while let Some(current) = scope {
+        if FUNCTION_KINDS.contains(&current.kind()) {
+            break;
+        }
+        scope = current.parent();
+    }
+    let Some(body) = scope.and_then(|function| function.child_by_field_name("body")) else {
+        return Vec::new();
+    };
+    if package_name(*call, ctx).as_deref() == Some("main") {
+        return Vec::new();
+    }
+
+    let mut paths = Vec::new();
+    let mut stack = vec![body];
+    while let Some(node) = stack.pop() {
+        if node.kind() == "func_literal" {
+            continue;
+        }
+        if let Some(kind) = crash_kind(node, ctx, imports) {
+            paths.push(CrashPath {
+                kind,
+                line: node.start_position().row + 1,
+                text: ctx.get_node_text(&node),
+            });
+        }
+        let mut cursor = node.walk();
+        stack.extend(node.named_children(&mut cursor));
+    }
+    paths.sort_by_key(|path| path.line);
+    paths
+}
+
+pub(super) fn crash_kind<'a>(
+    node: Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Option<CrashKind> {
+    if node.kind() != "call_expression" {
+        return None;
+    }
+    let function = node.child_by_field_name("function")?;
+    if function.kind() == "identifier" {
+        return (ctx.get_node_text(&function) == "panic").then_some(CrashKind::Panic);
+    }
+    match callee(node, ctx, imports)?.as_str() {
+        "log.Panic" | "log.Panicf" | "log.Panicln" => Some(CrashKind::Panic),
+        "log.Fatal" | "log.Fatalf" | "log.Fatalln" => Some(CrashKind::Fatal),
+        _ => None,
+    }
+}
+
+/// The name in the package clause of the file containing `node`.
+fn package_name<'a>(node: Node<'a>, ctx: &Context<'a>) -> Option<String> {
+    let mut root = node;
+    while let Some(parent) = root.parent() {
+        root = parent;
+    }
+    let mut cursor = root.walk();
+    let clause = root
+        .named_children(&mut cursor)
+        .find(|child| child.kind() == "package_clause")?;
+    let mut cursor = clause.walk();
+    let name = clause
+        .named_children(&mut cursor)
+        .find(|child| child.kind() == "package_identifier")?;
+    Some(ctx.get_node_text(&name))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::scanner::Scanner;
+    use std::collections::HashMap;
+    use tree_sitter::Parser;
+
+    fn crash_paths(package: &str, body: &str) -> Vec<(CrashKind, usize)> {
+        let source = format!(
+            "package {package}\n\nimport (\n\tcrand \"crypto/rand\"\n\t\"encoding/binary\"\n\t\"log\"\n\tmrand \"math/rand\"\n)\n\n{body}\n"
+        );
+        let mut parser = Parser::new();
+        parser
+            .set_language(&tree_sitter_go::LANGUAGE.into())
+            .unwrap();
+        let tree = parser.parse(&source, None).unwrap();
+        let mappings = HashMap::from([(
+            "crypto/rand".to_string(),
+            HashMap::from([("read".to_string(), "random".to_string())]),
+        )]);
+        let calls = Scanner::with_mappings(mappings)
+            .scan_tree(&tree, source.as_bytes(), "random.go", "go")
+            .calls;
+        calls[0]
+            .crash_paths
+            .iter()
+            .map(|path| (path.kind, path.line))
+            .collect()
+    }
+
+    #[test]
+    fn test_library_panics_and_fatal_exits() {
+        let body = r#"func NewMathRandInt() *mrand.Rand {
+	var b [8]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	seed := int64(binary.LittleEndian.Uint64(b[:]))
+	if seed == 0 {
+		log.Fatalf("zero seed")
+	}
+	go func() { panic("unrelated") }()
+	return mrand.New(mrand.NewSource(seed))
+}"#;
+        assert_eq!(
+            crash_paths("random", body),
+            vec![(CrashKind::Panic, 13), (CrashKind::Fatal, 17)]
+        );
+        assert!(crash_paths("main", body).is_empty());
+    }
+}
@@ -0,0 +1,164 @@
+//! How much work replacing a Go crypto call is likely to take, from how its arguments
+//! reach it.
+
+use serde::Serialize;
+use tree_sitter::Node;
+
+use super::receiver::find_declaration;
+use super::{Finding, ImportMap};
+use crate::engine::Context;
+
+/// How many declarations an argument is followed through.
+const MAX_DEPTH: usize = 4;
+
+/// Parameter types that carry the data a call processes rather than how it processes it.
+const DATA_TYPES: &[&str] = &["slice_type", "array_type", "pointer_type"];
+
+/// Remediation effort, from least to most work.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, PartialOrd, Ord, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum RemediationEffort {
+    /// Every argument is a literal or a constant of the calling package.
+    LiteralChange,
+    /// An argument is a constant declared in another package of the module.
+    CrossPackageConstant,
+    /// An argument is a parameter of the enclosing function, so its callers change too.
+    SignatureChange,
+    /// The call goes through a constructed receiver or an algorithm registry.
+    InterfaceMigration,
+}
+
+/// Effort to change the Go call `call`, found as `finding`.
+pub(super) fn go_remediation_effort<'a>(
+    call: &Node<'a>,
+    finding: &Finding,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> RemediationEffort {
+    if !finding.receiver_chain.is_empty() || finding.selection.is_some() {
+        return RemediationEffort::InterfaceMigration;
+    }
+
+    let Some(args) = call.child_by_field_name("arguments") else {
+        return RemediationEffort::LiteralChange;
+    };
+    let mut cursor = args.walk();
+    let effort = args
+        .named_children(&mut cursor)
+        .map(|arg| argument_effort(arg, call, ctx, imports, 0))
+        .max()
+        .unwrap_or(RemediationEffort::LiteralChange);
+    effort
+}
+
+fn argument_effort<'a>(
+    node: Node<'a>,
+    call: &Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+    depth: usize,
+) -> RemediationEffort {
+    match node.kind() {
+        "identifier" => {
+            let Some(declaration) = find_declaration(call, &ctx.get_node_text(&node), ctx) else {
+                // Not declared in the function: a constant or variable of the package
+                return RemediationEffort::LiteralChange;
+            };
+            if declaration.node.kind() == "parameter_declaration" {
+                return match declaration.type_node {
+                    Some(data) if is_data_type(data, ctx) => RemediationEffort::LiteralChange,
+                    _ => RemediationEffort::SignatureChange,
+                };
+            }
+            match declaration.value {
+                Some(value) if depth < MAX_DEPTH => {
+                    argument_effort(value, call, ctx, imports, depth + 1)
+                }
+                _ => RemediationEffort::LiteralChange,
+            }
+        }
+        "selector_expression" => {
+            let package = node
+                .child_by_field_name("operand")
+                .filter(|operand| operand.kind() == "identifier")
+                .map(|operand| ctx.get_node_text(&operand));
+            let name = ctx.get_field_text(&node, "field");
+            match (package, name) {
+                // Only constants of indexed module packages; `sha256.New` or
+                // `tls.VersionTLS12` are swapped in place like literals
+                (Some(package), Some(name))
+                    if imports.resolve(&package).is_some()
+                        && ctx.find_imported_constant(&package, &name).is_some() =>
+                {
+                    RemediationEffort::CrossPackageConstant
+                }
+                _ => RemediationEffort::LiteralChange,
+            }
+        }
+        _ => {
+            let mut cursor = node.walk();
+            let effort = node
+                .named_children(&mut cursor)
+                .map(|child| argument_effort(child, call, ctx, imports, depth))
+                .max()
+                .unwrap_or(RemediationEffort::LiteralChange);
+            effort
+        }
+    }
+}
+
+fn is_data_type<'a>(type_node: Node<'a>, ctx: &Context<'a>) -> bool {
+    DATA_TYPES.contains(&type_node.kind()) || ctx.get_node_text(&type_node) == "string"
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::scanner::Scanner;
+    use std::collections::HashMap;
+
+    fn efforts(source: &str) -> Vec<Option<RemediationEffort>> {
+        let mut parser = tree_sitter::Parser::new();
+        parser
+            .set_language(&tree_sitter_go::LANGUAGE.into())
+            .unwrap();
+        let tree = parser.parse(source, None).unwrap();
+        let scanner = Scanner::with_mappings(HashMap::from([(
+            "golang.org/x/crypto/pbkdf2".to_string(),
+            HashMap::from([("key".to_string(), "pbkdf2".to_string())]),
+        )]));
+        let result = scanner.scan_tree(&tree, source.as_bytes(), "kdf.go", "go");
+        result.calls.iter().map(|c| c.remediation_effort).collect()
+    }
+
+    #[test]
+    fn test_effort_from_argument_provenance() {
+        let source = r#"
+package kdf
+
+import (
+    "crypto/sha256"
+
+    "golang.org/x/crypto/pbkdf2"
+)
+
+const iterations = 4096
+
+func fixed(password, salt []byte) []byte {
+    n := iterations
+    return pbkdf2.Key(password, salt, n, 32, sha256.New)
+}
+
+func tunable(password, salt []byte, rounds int) []byte {
+    return pbkdf2.Key(password, salt, rounds, 32, sha256.New)
+}
+"#;
+        assert_eq!(
+            efforts(source),
+            vec![
+                Some(RemediationEffort::LiteralChange),
+                Some(RemediationEffort::SignatureChange),
+            ]
+        );
+    }
+}
@@ -0,0 +1,185 @@
+//! Failure paths of Go constructors that swallow or escalate errors.
+
+use serde::Serialize;
+use tree_sitter::Node;
+
+use crate::engine::Context;
+
+const FUNCTION_KINDS: &[&str] = &["function_declaration", "method_declaration", "func_literal"];
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum FailureKind {
+    /// `return nil, nil`: no value and no error.
+    NilWithoutError,
+    /// `panic(...)` instead of returning the error.
+    Panic,
+}
+
+/// A failure path of the function enclosing a crypto call.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct FailurePath {
+    pub kind: FailureKind,
+    pub line: usize,
+    pub text: String,
+}
+
+/// Failure paths of the constructor enclosing `call`, if it returns `(T, error)`.
+pub(super) fn go_failure_paths<'a>(call: &Node<'a>, ctx: &Context<'a>) -> Vec<FailurePath> {
+    let mut scope = call.parent();
+    while let Some(current) = scope {
+        if FUNCTION_KINDS.contains(&current.kind()) {
+            break;
+        }
+        scope = current.parent();
+    }
+    let Some(function) = scope else {
+        return Vec::new();
+    };
+    if !returns_value_and_error(function, ctx) {
+        return Vec::new();
+    }
+    let Some(body) = function.child_by_field_name("body") else {
+        return Vec::new();
+    };
+
+    let mut paths = Vec::new();
+    let mut stack = vec![body];
+    while let Some(node) = stack.pop() {
+        // Nested closures fail on their own behalf
+        if node.kind() == "func_literal" {
+            continue;
+        }
+        let kind = match node.kind() {
+            "return_statement" if returns_only_nil(node) => Some(FailureKind::NilWithoutError),
+            "call_expression" if is_panic(node, ctx) => Some(FailureKind::Panic),
+            _ => None,
+        };
+        if let Some(kind) = kind {
+            paths.push(FailurePath {
+                kind,
+                line: node.start_position().row + 1,
+                text: ctx.get_node_text(&node),
+            });
+        }
+        let mut cursor = node.walk();
+        stack.extend(node.named_children(&mut cursor));
+    }
+    paths.sort_by_key(|path| path.line);
+    paths
+}
+
+/// Whether the results are at least one value followed by `error`.
+fn returns_value_and_error<'a>(function: Node<'a>, ctx: &Context<'a>) -> bool {
+    let Some(result) = function.child_by_field_name("result") else {
+        return false;
+    };
+    if result.kind() != "parameter_list" {
+        return false;
+    }
+    let mut cursor = result.walk();
+    let results: Vec<_> = result.named_children(&mut cursor).collect();
+    // `(a, b T, err error)` declares several values in one parameter_declaration
+    let count: usize = results
+        .iter()
+        .map(|declaration| {
+            let mut cursor = declaration.walk();
+            declaration
+                .children_by_field_name("name", &mut cursor)
+                .count()
+                .max(1)
+        })
+        .sum();
+    let last_type = results
+        .last()
+        .and_then(|declaration| declaration.child_by_field_name("type"));
+    count >= 2 && last_type.is_some_and(|t| ctx.get_node_text(&t) == "error")
+}
+
+fn returns_only_nil(node: Node) -> bool {
+    let Some(values) = node.named_child(0) else {
+        return false;
+    };
+    let mut cursor = values.walk();
+    let values: Vec<_> = values.named_children(&mut cursor).collect();
+    values.len() >= 2 && values.iter().all(|value| value.kind() == "nil")
+}
+
+fn is_panic<'a>(call: Node<'a>, ctx: &Context<'a>) -> bool {
+    call.child_by_field_name("function")
+        .is_some_and(|f| f.kind() == "identifier" && ctx.get_node_text(&f) == "panic")
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::scanner::Scanner;
+    use std::collections::HashMap;
+    use tree_sitter::Parser;
+
+    fn failure_paths(body: &str) -> Vec<(FailureKind, usize)> {
+        let source = format!("package cipher\n\nimport \"crypto/aes\"\n\n{body}\n");
+        let mut parser = Parser::new();
+        parser
+            .set_language(&tree_sitter_go::LANGUAGE.into())
+            .unwrap();
+        let tree = parser.parse(&source, None).unwrap();
+        let mappings = HashMap::from([(
+            "crypto/aes".to_string(),
+            HashMap::from([("newcipher".to_string(), "aes".to_string())]),
+        )]);
+        let calls = Scanner::with_mappings(mappings)
+            .scan_tree(&tree, source.as_bytes(), "aes.go", "go")
+            .calls;
+        calls[0]
+            .failure_paths
+            .iter()
+            .map(|path| (path.kind, path.line))
+            .collect()
+    }
+
+    #[test]
+    fn test_nil_without_error_and_panic() {
+        let paths = failure_paths(
+            r#"func NewAES128(key []byte) (cipher.Block, error) {
+	if len(key) < 16 {
+		return nil, nil
+	}
+	if len(key) > 32 {
+		panic("key too long")
+	}
+	return aes.NewCipher(key[:16])
+}"#,
+        );
+        assert_eq!(
+            paths,
+            vec![(FailureKind::NilWithoutError, 7), (FailureKind::Panic, 10)]
+        );
+    }
+
+    #[test]
+    fn test_non_constructors_and_closures_are_ignored() {
+        let paths = failure_paths(
+            r#"func mustBlock(key []byte) cipher.Block {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err)
+	}
+	return block
+}"#,
+        );
+        assert!(paths.is_empty());
+
+        let paths = failure_paths(
+            r#"func NewBlock(key []byte) (cipher.Block, error) {
+	defer func() {
+		if r := recover(); r != nil {
+			panic(r)
+		}
+	}()
+	return aes.NewCipher(key)
+}"#,
+        );
+        assert!(paths.is_empty());
+    }
+}
@@ -0,0 +1,417 @@
+//! Zero values of a failed loader, such as a `Config` whose error was only logged,
+//! reaching crypto calls.
+
+use serde::Serialize;
+use tree_sitter::Node;
+
+use super::crash::crash_kind;
+use super::receiver::callee;
+use super::ImportMap;
+use crate::engine::Context;
+
+/// Loaders whose first result is an integer, so their zero value is `0`.
+const INTEGER_PARSERS: &[&str] = &["strconv.Atoi", "strconv.ParseInt", "strconv.ParseUint"];
+
+/// Calls ending an error branch besides `panic` and `log.Fatal*`.
+const EXITS: &[&str] = &["os.Exit", "runtime.Goexit"];
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum ErrorHandling {
+    /// The error is assigned to `_`.
+    Ignored,
+    /// No check of the error precedes the call.
+    Unchecked,
+    /// The error is checked, but the failing branch falls through to the call.
+    Swallowed,
+}
+
+impl ErrorHandling {
+    pub fn describe(&self) -> &'static str {
+        match self {
+            ErrorHandling::Ignored => "is discarded",
+            ErrorHandling::Unchecked => "is not checked before the call",
+            ErrorHandling::Swallowed => "is checked but not returned",
+        }
+    }
+}
+
+/// An argument that is the zero value of a loader's result when the loader fails.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct ZeroFallback {
+    /// Argument name, positional (`arg2`) until the sink's roles rename it.
+    pub argument: String,
+    #[serde(skip)]
+    pub position: usize,
+    /// The argument as written, e.g. `cfg.Iterations`.
+    pub expression: String,
+    /// The call the variable is assigned from, e.g. `LoadKDFConfig(path)`.
+    pub loader: String,
+    /// Line of the assignment.
+    pub line: usize,
+    pub handling: ErrorHandling,
+    /// The zero value as Go source (`0`, `""`, `nil`, `false`), when the type is known.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub zero: Option<String>,
+}
+
+/// Arguments of `call` that fall back to a zero value when their loader fails.
+pub(super) fn go_zero_fallbacks<'a>(
+    call: &Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Vec<ZeroFallback> {
+    let Some(arguments) = call.child_by_field_name("arguments") else {
+        return Vec::new();
+    };
+    let Some(body) = enclosing_body(*call) else {
+        return Vec::new();
+    };
+
+    let mut cursor = arguments.walk();
+    arguments
+        .named_children(&mut cursor)
+        .enumerate()
+        .filter_map(|(position, argument)| {
+            let (variable, field) = match argument.kind() {
+                "identifier" => (ctx.get_node_text(&argument), None),
+                "selector_expression" => {
+                    let mut operand = argument.child_by_field_name("operand")?;
+                    while operand.kind() == "selector_expression" {
+                        operand = operand.child_by_field_name("operand")?;
+                    }
+                    let field = argument.child_by_field_name("field")?;
+                    (ctx.get_node_text(&operand), Some(ctx.get_node_text(&field)))
+                }
+                _ => return None,
+            };
+            if imports.get(&variable).is_some() {
+                return None;
+            }
+
+            let assignment = last_assignment(body, &variable, call.start_byte(), ctx)?;
+            let left = assignment.child_by_field_name("left")?;
+            let right = assignment.child_by_field_name("right")?;
+            let loader = single_call(right)?;
+            let mut targets = left.walk();
+            let names: Vec<String> = left
+                .named_children(&mut targets)
+                .map(|name| ctx.get_node_text(&name))
+                .collect();
+            let error = names.last().filter(|_| names.len() >= 2)?;
+            if names.first() != Some(&variable) || !is_error_name(error) {
+                return None;
+            }
+
+            let handling = if error == "_" {
+                ErrorHandling::Ignored
+            } else {
+                error_handling(body, error, assignment.end_byte(), *call, ctx, imports)?
+            };
+            let zero = match field {
+                Some(field) => field_zero(*call, &field, ctx),
+                None => callee(loader, ctx, imports)
+                    .filter(|name| INTEGER_PARSERS.contains(&name.as_str()))
+                    .map(|_| "0".to_string()),
+            };
+            Some(ZeroFallback {
+                argument: format!("arg{position}"),
+                position,
+                expression: ctx.get_node_text(&argument),
+                loader: ctx.get_node_text(&loader),
+                line: assignment.start_position().row + 1,
+                handling,
+                zero,
+            })
+        })
+        .collect()
+}
+
+fn enclosing_body(node: Node) -> Option<Node> {
+    let mut scope = node.parent();
+    while let Some(current) = scope {
+        if matches!(
+            current.kind(),
+            "function_declaration" | "method_declaration" | "func_literal"
+        ) {
+            return current.child_by_field_name("body");
+        }
+        scope = current.parent();
+    }
+    None
+}
+
+fn is_error_name(name: &str) -> bool {
+    name == "_" || name.to_ascii_lowercase().contains("err")
+}
+
+/// The last assignment to `name` in `body` starting before `before`, outside nested
+/// function literals.
+fn last_assignment<'a>(
+    body: Node<'a>,
+    name: &str,
+    before: usize,
+    ctx: &Context<'a>,
+) -> Option<Node<'a>> {
+    let mut found: Option<Node<'a>> = None;
+    let mut stack = vec![body];
+    while let Some(node) = stack.pop() {
+        if node.start_byte() >= before || node.kind() == "func_literal" {
+            continue;
+        }
+        if matches!(
+            node.kind(),
+            "short_var_declaration" | "assignment_statement"
+        ) {
+            let assigns = node.child_by_field_name("left").is_some_and(|left| {
+                let mut cursor = left.walk();
+                let assigns = left
+                    .named_children(&mut cursor)
+                    .any(|target| ctx.get_node_text(&target) == name);
+                assigns
+            });
+            if assigns && found.is_none_or(|f| node.start_byte() > f.start_byte()) {
+                found = Some(node);
+            }
+        }
+        let mut cursor = node.walk();
+        stack.extend(node.named_children(&mut cursor));
+    }
+    found
+}
+
+/// The call an expression list consists of, as in `cfg, err := Load()`.
+fn single_call(right: Node) -> Option<Node> {
+    let value = match right.kind() {
+        "expression_list" if right.named_child_count() == 1 => right.named_child(0)?,
+        "expression_list" => return None,
+        _ => right,
+    };
+    (value.kind() == "call_expression").then_some(value)
+}
+
+/// How the error `error`, assigned before `after`, is handled on the way to `call`;
+/// `None` when every failing path leaves before the call.
+fn error_handling<'a>(
+    body: Node<'a>,
+    error: &str,
+    after: usize,
+    call: Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Option<ErrorHandling> {
+    let failing = format!("{error}!=nil");
+    let succeeding = format!("{error}==nil");
+    let mut stack = vec![body];
+    let mut checks = Vec::new();
+    while let Some(node) = stack.pop() {
+        if node.kind() == "func_literal"
+            || (node.start_byte() >= call.start_byte() && !contains(node, call))
+        {
+            continue;
+        }
+        if node.kind() == "if_statement" && node.start_byte() >= after {
+            if let Some(condition) = node.child_by_field_name("condition") {
+                let condition: String = ctx
+                    .get_node_text(&condition)
+                    .chars()
+                    .filter(|c| !c.is_whitespace())
+                    .collect();
+                if condition == failing || condition == succeeding {
+                    checks.push((node, condition == failing));
+                }
+            }
+        }
+        let mut cursor = node.walk();
+        stack.extend(node.named_children(&mut cursor));
+    }
+    checks.sort_by_key(|(node, _)| node.start_byte());
+
+    let Some((check, failing)) = checks.first() else {
+        return Some(ErrorHandling::Unchecked);
+    };
+    let consequence = check.child_by_field_name("consequence")?;
+    let alternative = check.child_by_field_name("alternative");
+    if *failing {
+        if alternative.is_some_and(|branch| contains(branch, call)) {
+            return None;
+        }
+        if !contains(consequence, call) && terminates(consequence, ctx, imports) {
+            return None;
+        }
+        Some(ErrorHandling::Swallowed)
+    } else {
+        // `if err == nil { use(cfg) }`
+        if contains(consequence, call) {
+            return None;
+        }
+        Some(ErrorHandling::Swallowed)
+    }
+}
+
+fn contains(outer: Node, inner: Node) -> bool {
+    outer.start_byte() <= inner.start_byte() && inner.end_byte() <= outer.end_byte()
+}
+
+/// Whether `block` ends the enclosing path: returns, jumps away, exits or panics.
+fn terminates<'a>(block: Node<'a>, ctx: &Context<'a>, imports: &ImportMap) -> bool {
+    let mut cursor = block.walk();
+    let terminates = block
+        .named_children(&mut cursor)
+        .any(|statement| match statement.kind() {
+            "return_statement" | "break_statement" | "continue_statement" | "goto_statement" => {
+                true
+            }
+            "expression_statement" => statement.named_child(0).is_some_and(|call| {
+                crash_kind(call, ctx, imports).is_some()
+                    || callee(call, ctx, imports).is_some_and(|name| EXITS.contains(&name.as_str()))
+            }),
+            _ => false,
+        });
+    terminates
+}
+
+/// Zero value of the type a struct in the file of `node` declares `field` with.
+fn field_zero<'a>(node: Node<'a>, field: &str, ctx: &Context<'a>) -> Option<String> {
+    let mut root = node;
+    while let Some(parent) = root.parent() {
+        root = parent;
+    }
+    let mut stack = vec![root];
+    while let Some(current) = stack.pop() {
+        if current.kind() == "field_declaration" {
+            let mut cursor = current.walk();
+            let named = current
+                .children_by_field_name("name", &mut cursor)
+                .any(|name| ctx.get_node_text(&name) == field);
+            if named {
+                return zero_of(current.child_by_field_name("type")?, ctx);
+            }
+            continue;
+        }
+        let mut cursor = current.walk();
+        stack.extend(current.named_children(&mut cursor));
+    }
+    None
+}
+
+fn zero_of<'a>(type_node: Node<'a>, ctx: &Context<'a>) -> Option<String> {
+    let zero = match type_node.kind() {
+        "pointer_type" | "slice_type" | "map_type" | "channel_type" | "function_type"
+        | "interface_type" => "nil",
+        "type_identifier" => match ctx.get_node_text(&type_node).as_str() {
+            "string" => "\"\"",
+            "bool" => "false",
+            "int" | "int8" | "int16" | "int32" | "int64" | "uint" | "uint8" | "uint16"
+            | "uint32" | "uint64" | "uintptr" | "byte" | "rune" | "float32" | "float64" => "0",
+            _ => return None,
+        },
+        _ => return None,
+    };
+    Some(zero.to_string())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::scanner::Scanner;
+    use std::collections::HashMap;
+    use tree_sitter::Parser;
+
+    const HEADER: &str = "package kdf\n\nimport (\n\t\"crypto/sha256\"\n\t\"log\"\n\t\"strconv\"\n\n\t\"golang.org/x/crypto/pbkdf2\"\n)\n\ntype Config struct {\n\tIterations int\n\tKeyLen     int\n}\n\n";
+
+    fn fallbacks(body: &str) -> Vec<(String, ErrorHandling, Option<String>)> {
+        let source = format!("{HEADER}{body}\n");
+        let mut parser = Parser::new();
+        parser
+            .set_language(&tree_sitter_go::LANGUAGE.into())
+            .unwrap();
+        let tree = parser.parse(&source, None).unwrap();
+        let mappings = HashMap::from([(
+            "golang.org/x/crypto/pbkdf2".to_string(),
+            HashMap::from([("key".to_string(), "pbkdf2".to_string())]),
+        )]);
+        let calls = Scanner::with_mappings(mappings)
+            .scan_tree(&tree, source.as_bytes(), "kdf.go", "go")
+            .calls;
+        calls[0]
+            .zero_fallbacks
+            .iter()
+            .map(|f| (f.expression.clone(), f.handling, f.zero.clone()))
+            .collect()
+    }
+
+    #[test]
+    fn test_logged_and_ignored_errors_fall_back_to_zero() {
+        let logged = fallbacks(
+            r#"func Derive(pw, salt []byte) []byte {
+	cfg, err := LoadConfig("kdf.yaml")
+	if err != nil {
+		err = fmt.Errorf("load kdf config: %w", err)
+		log.Printf("%v", err)
+	}
+	return pbkdf2.Key(pw, salt, cfg.Iterations, cfg.KeyLen, sha256.New)
+}"#,
+        );
+        assert_eq!(
+            logged,
+            vec![
+                (
+                    "cfg.Iterations".to_string(),
+                    ErrorHandling::Swallowed,
+                    Some("0".to_string())
+                ),
+                (
+                    "cfg.KeyLen".to_string(),
+                    ErrorHandling::Swallowed,
+                    Some("0".to_string())
+                ),
+            ]
+        );
+
+        let ignored = fallbacks(
+            r#"func Derive(pw, salt []byte, iter string) []byte {
+	n, _ := strconv.Atoi(iter)
+	return pbkdf2.Key(pw, salt, n, 32, sha256.New)
+}"#,
+        );
+        assert_eq!(
+            ignored,
+            vec![(
+                "n".to_string(),
+                ErrorHandling::Ignored,
+                Some("0".to_string())
+            )]
+        );
+    }
+
+    #[test]
+    fn test_returned_errors_and_defaults_are_not_fallbacks() {
+        for body in [
+            r#"func Derive(pw, salt []byte) ([]byte, error) {
+	cfg, err := LoadConfig("kdf.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("load kdf config: %w", err)
+	}
+	return pbkdf2.Key(pw, salt, cfg.Iterations, cfg.KeyLen, sha256.New), nil
+}"#,
+            r#"func Derive(pw, salt []byte) []byte {
+	cfg, err := LoadConfig("kdf.yaml")
+	if err != nil {
+		log.Printf("using defaults: %v", err)
+		cfg = DefaultConfig()
+	}
+	return pbkdf2.Key(pw, salt, cfg.Iterations, cfg.KeyLen, sha256.New)
+}"#,
+            r#"func Derive(pw, salt []byte) []byte {
+	cfg, err := LoadConfig("kdf.yaml")
+	if err != nil {
+		log.Fatalf("load kdf config: %v", err)
+	}
+	return pbkdf2.Key(pw, salt, cfg.Iterations, cfg.KeyLen, sha256.New)
+}"#,
+        ] {
+            assert!(fallbacks(body).is_empty(), "{body}");
+        }
+    }
+}
@@ -0,0 +1,472 @@
+//! Where Go code sends the private keys it encodes, and whether they are encrypted.
+
+use serde::Serialize;
+use tree_sitter::Node;
+
+use super::receiver::{callee, find_declaration};
+use super::ImportMap;
+use crate::engine::Context;
+use crate::utils::unquote_string;
+
+const FUNCTION_KINDS: &[&str] = &["function_declaration", "method_declaration", "func_literal"];
+
+const MARSHALERS: &[&str] = &[
+    "crypto/x509.MarshalPKCS8PrivateKey",
+    "crypto/x509.MarshalPKCS1PrivateKey",
+    "crypto/x509.MarshalECPrivateKey",
+];
+
+const PEM_ENCODE: &str = "encoding/pem.Encode";
+const PEM_ENCODE_TO_MEMORY: &str = "encoding/pem.EncodeToMemory";
+const ENCRYPT_PEM_BLOCK: &str = "crypto/x509.EncryptPEMBlock";
+
+/// Functions that write their argument at the given index to a file.
+const FILE_WRITERS: &[(&str, usize)] = &[("os.WriteFile", 1), ("io/ioutil.WriteFile", 1)];
+
+/// Functions returning an `*os.File` to write to.
+const FILE_OPENERS: &[&str] = &["os.Create", "os.OpenFile"];
+
+/// How many declarations a PEM block argument is followed through.
+const MAX_DEPTH: usize = 4;
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum KeyDestination {
+    /// Written with `os.WriteFile` or to a file from `os.Create`/`os.OpenFile`.
+    File,
+    /// Returned to the caller.
+    Returned,
+    /// Written to an `http.ResponseWriter`.
+    Response,
+    /// Wrapped in a PEM block; the `pem.Encode` call reports where it goes.
+    Pem,
+    /// Kept in the function or passed somewhere the trace does not follow.
+    Untraced,
+}
+
+/// A private key encoded by a Go call, and where the encoded bytes go.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct KeyEncoding {
+    pub encrypted: bool,
+    pub destination: KeyDestination,
+    /// The expression the encoded key leaves through, e.g. `os.WriteFile("key.pem", der, 0600)`.
+    pub expression: String,
+}
+
+/// Key encoding for `call` if `function` under `import_path` marshals a private key or
+/// PEM-encodes a private key block.
+pub(super) fn go_key_encoding<'a>(
+    call: &Node<'a>,
+    import_path: Option<&str>,
+    function: &str,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Option<KeyEncoding> {
+    let name = format!("{}.{function}", import_path?);
+    if MARSHALERS.contains(&name.as_str()) {
+        return Some(follow_output(*call, ctx, imports));
+    }
+
+    let arguments = call.child_by_field_name("arguments")?;
+    let encoding = match name.as_str() {
+        PEM_ENCODE => {
+            let encrypted = private_key_block(arguments.named_child(1)?, ctx, imports, 0)?;
+            let destination = writer_destination(arguments.named_child(0)?, ctx, imports);
+            KeyEncoding {
+                encrypted,
+                destination,
+                expression: ctx.get_node_text(call),
+            }
+        }
+        PEM_ENCODE_TO_MEMORY => {
+            let encrypted = private_key_block(arguments.named_child(0)?, ctx, imports, 0)?;
+            KeyEncoding {
+                encrypted,
+                ..follow_output(*call, ctx, imports)
+            }
+        }
+        _ => return None,
+    };
+    Some(encoding)
+}
+
+/// Where the result of `call` goes: straight into a return or another call, or through
+/// the variable it is assigned to. The first use that leaves the function wins.
+fn follow_output<'a>(call: Node<'a>, ctx: &Context<'a>, imports: &ImportMap) -> KeyEncoding {
+    if let Some(encoding) = consumer(call, ctx, imports) {
+        return encoding;
+    }
+    let untraced = KeyEncoding {
+        encrypted: false,
+        destination: KeyDestination::Untraced,
+        expression: ctx.get_node_text(&call),
+    };
+
+    // `der, err := x509.MarshalPKCS8PrivateKey(key)`: the first name holds the key
+    let Some(name) = assigned_name(call, ctx) else {
+        return untraced;
+    };
+    let Some(body) = enclosing_function(call).and_then(|f| f.child_by_field_name("body")) else {
+        return untraced;
+    };
+
+    let mut uses = Vec::new();
+    let mut stack = vec![body];
+    while let Some(node) = stack.pop() {
+        if node.kind() == "identifier"
+            && node.start_byte() > call.end_byte()
+            && ctx.get_node_text(&node) == name
+        {
+            uses.push(node);
+        }
+        let mut cursor = node.walk();
+        stack.extend(node.named_children(&mut cursor));
+    }
+    uses.sort_by_key(|node| node.start_byte());
+    uses.into_iter()
+        .find_map(|node| consumer(node, ctx, imports))
+        .unwrap_or(untraced)
+}
+
+/// What directly consumes `node`: a `return`, a PEM block's `Bytes`, or a call that
+/// encrypts or writes it.
+fn consumer<'a>(node: Node<'a>, ctx: &Context<'a>, imports: &ImportMap) -> Option<KeyEncoding> {
+    let mut parent = node.parent()?;
+    // `[]byte(pemData)` and `(der)` pass the same bytes
+    while matches!(
+        parent.kind(),
+        "parenthesized_expression" | "type_conversion_expression" | "literal_element"
+    ) || (parent.kind() == "call_expression"
+        && parent
+            .child_by_field_name("function")
+            .is_some_and(|f| f.kind() == "slice_type"))
+    {
+        parent = parent.parent()?;
+    }
+    let encoding = |encrypted, destination, at: Node<'a>| KeyEncoding {
+        encrypted,
+        destination,
+        expression: ctx.get_node_text(&at),
+    };
+
+    match parent.kind() {
+        "return_statement" => Some(encoding(false, KeyDestination::Returned, parent)),
+        "expression_list" if parent.parent()?.kind() == "return_statement" => {
+            Some(encoding(false, KeyDestination::Returned, parent.parent()?))
+        }
+        "keyed_element" if keyed_element_key(parent, ctx).as_deref() == Some("Bytes") => {
+            Some(encoding(false, KeyDestination::Pem, parent))
+        }
+        "argument_list" => {
+            let call = parent.parent()?;
+            let mut cursor = parent.walk();
+            let index = parent
+                .named_children(&mut cursor)
+                .position(|arg| arg.byte_range().contains(&node.start_byte()))?;
+            let name = callee(call, ctx, imports).unwrap_or_default();
+            if name == ENCRYPT_PEM_BLOCK {
+                return Some(encoding(true, KeyDestination::Pem, call));
+            }
+            if FILE_WRITERS.contains(&(name.as_str(), index)) {
+                return Some(encoding(false, KeyDestination::File, call));
+            }
+            // `f.Write(der)` / `w.Write(pemBytes)`
+            let function = call.child_by_field_name("function")?;
+            let is_write = function.kind() == "selector_expression"
+                && function
+                    .child_by_field_name("field")
+                    .is_some_and(|f| ctx.get_node_text(&f) == "Write");
+            if !is_write {
+                return None;
+            }
+            let writer = function.child_by_field_name("operand")?;
+            match writer_destination(writer, ctx, imports) {
+                KeyDestination::Untraced => None,
+                destination => Some(encoding(false, destination, call)),
+            }
+        }
+        _ => None,
+    }
+}
+
+/// Whether `writer` is a file or an HTTP response: an `os.Create`/`os.OpenFile` result or
+/// a parameter typed `http.ResponseWriter`.
+fn writer_destination<'a>(
+    writer: Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> KeyDestination {
+    let opened = |value: Node<'a>| {
+        callee(value, ctx, imports).is_some_and(|name| FILE_OPENERS.contains(&name.as_str()))
+    };
+    if opened(writer) {
+        return KeyDestination::File;
+    }
+    if writer.kind() != "identifier" {
+        return KeyDestination::Untraced;
+    }
+    let Some(declaration) = find_declaration(&writer, &ctx.get_node_text(&writer), ctx) else {
+        return KeyDestination::Untraced;
+    };
+    if declaration
+        .type_node
+        .is_some_and(|t| ctx.get_node_text(&t).ends_with("ResponseWriter"))
+    {
+        return KeyDestination::Response;
+    }
+    match declaration.value {
+        Some(value) if opened(value) => KeyDestination::File,
+        _ => KeyDestination::Untraced,
+    }
+}
+
+/// For a PEM block argument that holds a private key, whether it is encrypted; `None` if
+/// the block is not (known to be) a private key.
+fn private_key_block<'a>(
+    block: Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+    depth: usize,
+) -> Option<bool> {
+    match block.kind() {
+        "unary_expression" | "parenthesized_expression" => {
+            let operand = block
+                .child_by_field_name("operand")
+                .or_else(|| block.named_child(0))?;
+            private_key_block(operand, ctx, imports, depth)
+        }
+        "composite_literal" => literal_block(block, ctx, imports),
+        // `x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", der, pass, alg)`
+        "call_expression" if callee(block, ctx, imports).as_deref() == Some(ENCRYPT_PEM_BLOCK) => {
+            let block_type = block
+                .child_by_field_name("arguments")
+                .and_then(|args| args.named_child(1))
+                .map(|t| unquote_string(&ctx.get_node_text(&t)))?;
+            block_type.ends_with("PRIVATE KEY").then_some(true)
+        }
+        "identifier" if depth < MAX_DEPTH => {
+            let declaration = find_declaration(&block, &ctx.get_node_text(&block), ctx)?;
+            private_key_block(declaration.value?, ctx, imports, depth + 1)
+        }
+        _ => None,
+    }
+}
+
+/// `pem.Block{Type: "...", Headers: ..., Bytes: ...}`: a private key if the type says so
+/// or the bytes come straight from a private key marshaler.
+fn literal_block<'a>(literal: Node<'a>, ctx: &Context<'a>, imports: &ImportMap) -> Option<bool> {
+    let body = literal.child_by_field_name("body")?;
+    let mut block_type = None;
+    let mut headers = false;
+    let mut marshaled = false;
+    let mut cursor = body.walk();
+    for element in body.named_children(&mut cursor) {
+        if element.kind() != "keyed_element" {
+            continue;
+        }
+        let Some(value) = keyed_element_value(element) else {
+            continue;
+        };
+        match keyed_element_key(element, ctx).as_deref() {
+            Some("Type") => block_type = Some(unquote_string(&ctx.get_node_text(&value))),
+            // RFC 1421 `Proc-Type: 4,ENCRYPTED`
+            Some("Headers") => headers = ctx.get_node_text(&value).contains("ENCRYPTED"),
+            Some("Bytes") => marshaled = from_marshaler(value, ctx, imports),
+            _ => {}
+        }
+    }
+
+    let encrypted = headers
+        || block_type
+            .as_deref()
+            .is_some_and(|t| t.starts_with("ENCRYPTED"));
+    let private = marshaled
+        || block_type
+            .as_deref()
+            .is_some_and(|t| t.ends_with("PRIVATE KEY"));
+    private.then_some(encrypted)
+}
+
+/// Whether `value` is, or is a variable assigned from, a private key marshaler call.
+fn from_marshaler<'a>(value: Node<'a>, ctx: &Context<'a>, imports: &ImportMap) -> bool {
+    let value = match value.kind() {
+        "identifier" => {
+            match find_declaration(&value, &ctx.get_node_text(&value), ctx).and_then(|d| d.value) {
+                Some(value) => value,
+                None => return false,
+            }
+        }
+        _ => value,
+    };
+    callee(value, ctx, imports).is_some_and(|name| MARSHALERS.contains(&name.as_str()))
+}
+
+fn keyed_element_key<'a>(element: Node<'a>, ctx: &Context<'a>) -> Option<String> {
+    let key = element.named_child(0)?;
+    let key = match key.kind() {
+        "literal_element" => key.named_child(0)?,
+        _ => key,
+    };
+    Some(ctx.get_node_text(&key))
+}
+
+fn keyed_element_value(element: Node) -> Option<Node> {
+    let value = element.named_child(element.named_child_count().checked_sub(1)?)?;
+    match value.kind() {
+        "literal_element" => value.named_child(0),
+        _ => Some(value),
+    }
+}
+
+/// The variable the first result of `call` is assigned to, e.g. `der` in
+/// `der, err := x509.MarshalPKCS8PrivateKey(key)`.
+fn assigned_name<'a>(call: Node<'a>, ctx: &Context<'a>) -> Option<String> {
+    let values = call.parent()?;
+    let statement = match values.kind() {
+        "expression_list" => values.parent()?,
+        _ => values,
+    };
+    if !matches!(
+        statement.kind(),
+        "short_var_declaration" | "assignment_statement" | "var_spec"
+    ) {
+        return None;
+    }
+    let left = statement
+        .child_by_field_name("left")
+        .or_else(|| statement.child_by_field_name("name"))?;
+    let target = match left.kind() {
+        "expression_list" => left.named_child(0)?,
+        _ => left,
+    };
+    (target.kind() == "identifier").then(|| ctx.get_node_text(&target))
+}
+
+fn enclosing_function(node: Node) -> Option<Node> {
+    let mut current = node.parent();
+    while let Some(scope) = current {
+        if FUNCTION_KINDS.contains(&scope.kind()) {
+            return Some(scope);
+        }
+        current = scope.parent();
+    }
+    None
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::scanner::Scanner;
+    use std::collections::HashMap;
+    use tree_sitter::Parser;
+
+    fn key_encodings(body: &str) -> Vec<Option<KeyEncoding>> {
+        let source = format!(
+            "package keys\n\nimport (\n\t\"crypto/rand\"\n\t\"crypto/x509\"\n\t\"encoding/pem\"\n\t\"net/http\"\n\t\"os\"\n)\n\n{body}\n"
+        );
+        let mut parser = Parser::new();
+        parser
+            .set_language(&tree_sitter_go::LANGUAGE.into())
+            .unwrap();
+        let tree = parser.parse(&source, None).unwrap();
+        let mappings = HashMap::from([
+            (
+                "crypto/x509".to_string(),
+                HashMap::from([(
+                    "marshalpkcs8privatekey".to_string(),
+                    "private_key_marshal".to_string(),
+                )]),
+            ),
+            (
+                "encoding/pem".to_string(),
+                HashMap::from([
+                    ("encode".to_string(), "pem_encode".to_string()),
+                    ("encodetomemory".to_string(), "pem_encode".to_string()),
+                ]),
+            ),
+        ]);
+        Scanner::with_mappings(mappings)
+            .scan_tree(&tree, source.as_bytes(), "keys.go", "go")
+            .calls
+            .into_iter()
+            .map(|call| call.key_encoding)
+            .collect()
+    }
+
+    fn destination(encoding: &Option<KeyEncoding>) -> Option<(KeyDestination, bool)> {
+        encoding.as_ref().map(|e| (e.destination, e.encrypted))
+    }
+
+    #[test]
+    fn test_marshaled_key_written_to_file() {
+        let encodings = key_encodings(
+            r#"func save(key any) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile("key.der", der, 0600)
+}"#,
+        );
+        assert_eq!(
+            destination(&encodings[0]),
+            Some((KeyDestination::File, false))
+        );
+        assert_eq!(
+            encodings[0].as_ref().unwrap().expression,
+            r#"os.WriteFile("key.der", der, 0600)"#
+        );
+    }
+
+    #[test]
+    fn test_pem_encoded_key_returned_and_served() {
+        let encodings = key_encodings(
+            r#"func export(key any) []byte {
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func serve(w http.ResponseWriter, der []byte) {
+	pem.Encode(w, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: der})
+}"#,
+        );
+        assert_eq!(
+            destination(&encodings[0]),
+            Some((KeyDestination::Pem, false))
+        );
+        assert_eq!(
+            destination(&encodings[1]),
+            Some((KeyDestination::Returned, false))
+        );
+        assert_eq!(
+            destination(&encodings[2]),
+            Some((KeyDestination::Response, false))
+        );
+    }
+
+    #[test]
+    fn test_encrypted_and_public_blocks() {
+        let encodings = key_encodings(
+            r#"func save(der, pass []byte) error {
+	f, err := os.Create("key.pem")
+	if err != nil {
+		return err
+	}
+	block, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", der, pass, x509.PEMCipherAES256)
+	if err != nil {
+		return err
+	}
+	return pem.Encode(f, block)
+}
+
+func cert(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}"#,
+        );
+        assert_eq!(
+            destination(&encodings[0]),
+            Some((KeyDestination::File, true))
+        );
+        assert_eq!(encodings[1], None);
+    }
+}
@@ -0,0 +1,237 @@
+//! Whether the private key of a Go key exchange is generated per exchange or reused.
+
+use serde::Serialize;
+use tree_sitter::Node;
+
+use super::provenance::filled_from_rand;
+use super::receiver::find_declaration;
+use super::ImportMap;
+use crate::engine::Context;
+
+const FUNCTION_KINDS: &[&str] = &["function_declaration", "method_declaration", "func_literal"];
+
+/// Where the private key of each exchange is: the receiver, or an argument index.
+const EXCHANGES: &[(&str, Option<usize>)] = &[
+    ("crypto/ecdh.PrivateKey.ECDH", None),
+    ("golang.org/x/crypto/curve25519.X25519", Some(0)),
+    ("golang.org/x/crypto/curve25519.ScalarMult", Some(1)),
+    ("golang.org/x/crypto/nacl/box.Seal", Some(4)),
+    ("golang.org/x/crypto/nacl/box.Open", Some(4)),
+    ("golang.org/x/crypto/nacl/box.Precompute", Some(2)),
+];
+
+/// How many declarations a key is followed through.
+const MAX_DEPTH: usize = 4;
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum KeyLifetime {
+    /// Generated in the function performing the exchange.
+    Ephemeral,
+    /// Held in a package-level variable and reused by every exchange.
+    Static,
+    /// A parameter, struct field or loaded key the trace does not follow.
+    Untraced,
+}
+
+/// The private key of a key exchange and how long it lives.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct KeyExchange {
+    pub lifetime: KeyLifetime,
+    /// The private key operand, e.g. `serverKey`.
+    pub private_key: String,
+    /// Where the key was created or assigned, e.g. `ecdh.X25519().GenerateKey(rand.Reader)`.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub origin: Option<String>,
+}
+
+/// Private key lifetime for `call` if `function` under `import_path` is a key exchange.
+pub(super) fn go_key_exchange<'a>(
+    call: &Node<'a>,
+    import_path: Option<&str>,
+    function: &str,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Option<KeyExchange> {
+    let name = format!("{}.{function}", import_path?);
+    let (_, index) = EXCHANGES.iter().find(|(sink, _)| *sink == name)?;
+    let key = match index {
+        Some(index) => call.child_by_field_name("arguments")?.named_child(*index)?,
+        None => call
+            .child_by_field_name("function")?
+            .child_by_field_name("operand")?,
+    };
+    let (lifetime, origin) = lifetime(key, call, ctx, imports, 0);
+    Some(KeyExchange {
+        lifetime,
+        private_key: ctx.get_node_text(&key),
+        origin,
+    })
+}
+
+fn lifetime<'a>(
+    key: Node<'a>,
+    call: &Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+    depth: usize,
+) -> (KeyLifetime, Option<String>) {
+    let untraced = (KeyLifetime::Untraced, None);
+    match key.kind() {
+        // `&priv`, `*priv`, `priv[:]`
+        "unary_expression" | "slice_expression" => match key.child_by_field_name("operand") {
+            Some(operand) => lifetime(operand, call, ctx, imports, depth),
+            None => untraced,
+        },
+        "identifier" => {
+            let name = ctx.get_node_text(&key);
+            let Some(declaration) = find_declaration(call, &name, ctx) else {
+                return untraced;
+            };
+            if is_package_level(declaration.node) {
+                let origin = declaration
+                    .value
+                    .or_else(|| assignment(ctx.tree().root_node(), &name, ctx))
+                    .map(|value| ctx.get_node_text(&value));
+                return (KeyLifetime::Static, origin);
+            }
+            if declaration.node.kind() == "parameter_declaration" {
+                return untraced;
+            }
+            if declaration.type_node.is_some()
+                && filled_from_rand(&declaration.node, call, &name, ctx, imports)
+            {
+                return (
+                    KeyLifetime::Ephemeral,
+                    Some(ctx.get_node_text(&declaration.node)),
+                );
+            }
+            match declaration.value {
+                Some(value) if is_generated(value, ctx) => {
+                    (KeyLifetime::Ephemeral, Some(ctx.get_node_text(&value)))
+                }
+                Some(value) if depth < MAX_DEPTH => lifetime(value, call, ctx, imports, depth + 1),
+                _ => untraced,
+            }
+        }
+        _ => untraced,
+    }
+}
+
+/// `ecdh.X25519().GenerateKey(rand.Reader)`, `box.GenerateKey(rand.Reader)`.
+fn is_generated<'a>(value: Node<'a>, ctx: &Context<'a>) -> bool {
+    value.kind() == "call_expression"
+        && value
+            .child_by_field_name("function")
+            .and_then(|function| ctx.get_field_text(&function, "field"))
+            .is_some_and(|method| method == "GenerateKey")
+}
+
+fn is_package_level(declaration: Node<'_>) -> bool {
+    let mut current = declaration.parent();
+    while let Some(node) = current {
+        if FUNCTION_KINDS.contains(&node.kind()) {
+            return false;
+        }
+        current = node.parent();
+    }
+    true
+}
+
+/// The value first assigned to the package-level `name`, e.g. in `init`.
+fn assignment<'a>(root: Node<'a>, name: &str, ctx: &Context<'a>) -> Option<Node<'a>> {
+    let mut stack = vec![root];
+    while let Some(node) = stack.pop() {
+        if node.kind() == "assignment_statement" {
+            let left = node
+                .child_by_field_name("left")
+                .map(|left| ctx.get_named_children(&left))
+                .unwrap_or_default();
+            if let Some(index) = left.iter().position(|n| ctx.get_node_text(n) == name) {
+                if let Some(value) = node
+                    .child_by_field_name("right")
+                    .and_then(|right| right.named_child(index))
+                {
+                    return Some(value);
+                }
+            }
+        }
+        let mut cursor = node.walk();
+        let children: Vec<_> = node.named_children(&mut cursor).collect();
+        stack.extend(children.into_iter().rev());
+    }
+    None
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::scanner::Scanner;
+    use std::collections::HashMap;
+
+    #[test]
+    fn test_static_and_ephemeral_exchange_keys() {
+        let source = r#"
+package handshake
+
+import (
+    "crypto/ecdh"
+    "crypto/rand"
+
+    "golang.org/x/crypto/curve25519"
+)
+
+var serverKey *ecdh.PrivateKey
+
+func init() {
+    serverKey, _ = ecdh.X25519().GenerateKey(rand.Reader)
+}
+
+func static(peer *ecdh.PublicKey) ([]byte, error) {
+    return serverKey.ECDH(peer)
+}
+
+func ephemeral(peer []byte) ([]byte, error) {
+    var scalar [32]byte
+    rand.Read(scalar[:])
+    return curve25519.X25519(scalar[:], peer)
+}
+
+func given(priv, peer []byte) ([]byte, error) {
+    return curve25519.X25519(priv, peer)
+}
+"#;
+        let mut parser = tree_sitter::Parser::new();
+        parser
+            .set_language(&tree_sitter_go::LANGUAGE.into())
+            .unwrap();
+        let tree = parser.parse(source, None).unwrap();
+        let scanner = Scanner::with_mappings(HashMap::from([
+            (
+                "crypto/ecdh.privatekey".to_string(),
+                HashMap::from([("ecdh".to_string(), "ecdh_exchange".to_string())]),
+            ),
+            (
+                "golang.org/x/crypto/curve25519".to_string(),
+                HashMap::from([("x25519".to_string(), "x25519_exchange".to_string())]),
+            ),
+        ]));
+        let result = scanner.scan_tree(&tree, source.as_bytes(), "handshake.go", "go");
+
+        let exchanges: Vec<_> = result
+            .calls
+            .iter()
+            .map(|c| c.key_exchange.clone().unwrap())
+            .collect();
+        assert_eq!(
+            exchanges[0],
+            KeyExchange {
+                lifetime: KeyLifetime::Static,
+                private_key: "serverKey".to_string(),
+                origin: Some("ecdh.X25519().GenerateKey(rand.Reader)".to_string()),
+            }
+        );
+        assert_eq!(exchanges[1].lifetime, KeyLifetime::Ephemeral);
+        assert_eq!(exchanges[2].lifetime, KeyLifetime::Untraced);
+    }
+}
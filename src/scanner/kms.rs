@@ -0,0 +1,321 @@
+//! Cloud KMS calls: the key a Go program hands its data to, instead of holding one.
+
+use serde::Serialize;
+use tree_sitter::Node;
+
+use super::receiver::find_declaration;
+use crate::engine::Context;
+use crate::utils::unquote_string;
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum KmsProvider {
+    Aws,
+    Gcp,
+    Azure,
+}
+
+/// Where a client call names its key.
+#[derive(Clone, Copy)]
+enum KeyOperand {
+    /// A field of the request literal, first match wins.
+    Field(&'static [&'static str]),
+    /// A positional argument.
+    Argument(usize),
+}
+
+const CLIENTS: &[(&str, KmsProvider, KeyOperand)] = &[
+    (
+        "github.com/aws/aws-sdk-go-v2/service/kms.Client",
+        KmsProvider::Aws,
+        KeyOperand::Field(&["KeyId", "DestinationKeyId"]),
+    ),
+    (
+        "github.com/aws/aws-sdk-go/service/kms.KMS",
+        KmsProvider::Aws,
+        KeyOperand::Field(&["KeyId", "DestinationKeyId"]),
+    ),
+    (
+        "cloud.google.com/go/kms/apiv1.KeyManagementClient",
+        KmsProvider::Gcp,
+        KeyOperand::Field(&["Name"]),
+    ),
+    (
+        "github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azkeys.Client",
+        KmsProvider::Azure,
+        KeyOperand::Argument(1),
+    ),
+];
+
+const OPERATIONS: &[&str] = &[
+    "Encrypt",
+    "Decrypt",
+    "ReEncrypt",
+    "GenerateDataKey",
+    "GenerateDataKeyWithoutPlaintext",
+    "GenerateDataKeyPair",
+    "AsymmetricDecrypt",
+    "RawEncrypt",
+    "RawDecrypt",
+    "WrapKey",
+    "UnwrapKey",
+];
+
+/// Request fields naming the data key spec or the algorithm the KMS key runs.
+const KEY_SPEC_FIELDS: &[&str] = &["KeySpec", "KeyPairSpec", "EncryptionAlgorithm", "Algorithm"];
+
+/// Single-argument helpers taking the address of a value, e.g. `aws.String("alias/app")`.
+const POINTER_HELPERS: &[&str] = &["String", "Ptr", "Int32"];
+
+/// A call encrypting, decrypting or generating a data key under a cloud KMS key.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct KmsOperation {
+    pub provider: KmsProvider,
+    /// The key ARN, alias, resource name or Key Vault key name, or the expression holding it.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub key_id: Option<String>,
+    /// The data key spec or algorithm, e.g. `AES_256` or `azkeys.EncryptionAlgorithmRSAOAEP256`.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub key_spec: Option<String>,
+}
+
+/// The KMS key and key spec for `call` if `function` under `import_path` is a KMS client call.
+pub(super) fn go_kms_operation<'a>(
+    call: &Node<'a>,
+    import_path: Option<&str>,
+    function: &str,
+    ctx: &Context<'a>,
+) -> Option<KmsOperation> {
+    let import_path = import_path?;
+    let (_, provider, operand) = CLIENTS.iter().find(|(client, ..)| *client == import_path)?;
+    let operation = function.strip_suffix("WithContext").unwrap_or(function);
+    if !OPERATIONS.contains(&operation) {
+        return None;
+    }
+
+    let arguments = call.child_by_field_name("arguments")?;
+    let mut cursor = arguments.walk();
+    let requests: Vec<Node> = arguments
+        .named_children(&mut cursor)
+        .filter_map(|argument| request_literal(argument, call, ctx))
+        .collect();
+    let key_id = match operand {
+        KeyOperand::Field(fields) => requests
+            .iter()
+            .find_map(|request| field(*request, fields, ctx)),
+        KeyOperand::Argument(index) => arguments.named_child(*index),
+    };
+    let key_spec = requests
+        .iter()
+        .find_map(|request| field(*request, KEY_SPEC_FIELDS, ctx));
+    Some(KmsOperation {
+        provider: *provider,
+        key_id: key_id.map(|key| value_text(key, call, ctx)),
+        key_spec: key_spec.map(|spec| value_text(spec, call, ctx)),
+    })
+}
+
+/// The composite literal a request argument is, through `&` and a local variable.
+fn request_literal<'a>(argument: Node<'a>, call: &Node<'a>, ctx: &Context<'a>) -> Option<Node<'a>> {
+    match argument.kind() {
+        "composite_literal" => Some(argument),
+        "unary_expression" => request_literal(argument.child_by_field_name("operand")?, call, ctx),
+        "identifier" => {
+            let value = find_declaration(call, &ctx.get_node_text(&argument), ctx)?.value?;
+            match value.kind() {
+                "composite_literal" => Some(value),
+                "unary_expression" => value
+                    .child_by_field_name("operand")
+                    .filter(|operand| operand.kind() == "composite_literal"),
+                _ => None,
+            }
+        }
+        _ => None,
+    }
+}
+
+/// The value of the first of `names` set in `literal`.
+fn field<'a>(literal: Node<'a>, names: &[&str], ctx: &Context<'a>) -> Option<Node<'a>> {
+    let body = literal.child_by_field_name("body")?;
+    let mut cursor = body.walk();
+    let elements: Vec<Node> = body
+        .named_children(&mut cursor)
+        .filter(|element| element.kind() == "keyed_element")
+        .collect();
+    names.iter().find_map(|name| {
+        elements.iter().find_map(|element| {
+            let key = literal_element(element.named_child(0)?);
+            let value = literal_element(element.named_child(1)?);
+            (ctx.get_node_text(&key) == *name).then_some(value)
+        })
+    })
+}
+
+/// The expression inside a `literal_element` wrapper.
+fn literal_element(node: Node) -> Node {
+    match node.kind() {
+        "literal_element" => node.named_child(0).unwrap_or(node),
+        _ => node,
+    }
+}
+
+/// A key or spec expression as written, unwrapping pointer helpers and following
+/// variables and constants initialized from a string literal.
+fn value_text<'a>(node: Node<'a>, call: &Node<'a>, ctx: &Context<'a>) -> String {
+    match node.kind() {
+        "interpreted_string_literal" | "raw_string_literal" => {
+            unquote_string(&ctx.get_node_text(&node))
+        }
+        "call_expression" => {
+            let helper = node
+                .child_by_field_name("function")
+                .and_then(|function| function.child_by_field_name("field"))
+                .map(|name| ctx.get_node_text(&name));
+            let argument = node
+                .child_by_field_name("arguments")
+                .filter(|arguments| arguments.named_child_count() == 1)
+                .and_then(|arguments| arguments.named_child(0));
+            match (helper, argument) {
+                (Some(helper), Some(argument)) if POINTER_HELPERS.contains(&helper.as_str()) => {
+                    value_text(argument, call, ctx)
+                }
+                _ => ctx.get_node_text(&node),
+            }
+        }
+        "identifier" => {
+            let name = ctx.get_node_text(&node);
+            match find_declaration(call, &name, ctx).and_then(|declaration| declaration.value) {
+                Some(value)
+                    if matches!(
+                        value.kind(),
+                        "interpreted_string_literal" | "raw_string_literal"
+                    ) =>
+                {
+                    unquote_string(&ctx.get_node_text(&value))
+                }
+                _ => name,
+            }
+        }
+        _ => ctx.get_node_text(&node),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::scanner::Scanner;
+    use std::collections::HashMap;
+
+    fn kms(source: &str) -> Vec<Option<KmsOperation>> {
+        let mut parser = tree_sitter::Parser::new();
+        parser
+            .set_language(&tree_sitter_go::LANGUAGE.into())
+            .unwrap();
+        let tree = parser.parse(source, None).unwrap();
+        let mappings = CLIENTS
+            .iter()
+            .map(|(client, ..)| {
+                let operations = OPERATIONS
+                    .iter()
+                    .map(|operation| (operation.to_lowercase(), "kms".to_string()))
+                    .collect();
+                (client.to_lowercase(), operations)
+            })
+            .collect::<HashMap<_, _>>();
+        let result = Scanner::with_mappings(mappings).scan_tree(
+            &tree,
+            source.as_bytes(),
+            "envelope.go",
+            "go",
+        );
+        result.calls.into_iter().map(|call| call.kms).collect()
+    }
+
+    #[test]
+    fn test_aws_v2_data_key() {
+        let found = kms(r#"
+package main
+
+import (
+    "context"
+
+    "github.com/aws/aws-sdk-go-v2/aws"
+    "github.com/aws/aws-sdk-go-v2/service/kms"
+    "github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+const keyAlias = "alias/payments"
+
+func dataKey(ctx context.Context, cfg aws.Config) {
+    client := kms.NewFromConfig(cfg)
+    input := &kms.GenerateDataKeyInput{
+        KeyId:   aws.String(keyAlias),
+        KeySpec: types.DataKeySpecAes256,
+    }
+    client.GenerateDataKey(ctx, input)
+}
+"#);
+        assert_eq!(found.len(), 1);
+        assert_eq!(
+            found[0],
+            Some(KmsOperation {
+                provider: KmsProvider::Aws,
+                key_id: Some("alias/payments".to_string()),
+                key_spec: Some("types.DataKeySpecAes256".to_string()),
+            })
+        );
+    }
+
+    #[test]
+    fn test_azure_key_name_argument() {
+        let found = kms(r#"
+package main
+
+import (
+    "context"
+
+    "github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+    "github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azkeys"
+)
+
+func wrap(ctx context.Context, client *azkeys.Client, dek []byte) {
+    client.WrapKey(ctx, "tenant-kek", "", azkeys.KeyOperationParameters{
+        Algorithm: to.Ptr(azkeys.EncryptionAlgorithmRSAOAEP256),
+        Value:     dek,
+    }, nil)
+}
+"#);
+        assert_eq!(found.len(), 1);
+        let operation = found[0].as_ref().unwrap();
+        assert_eq!(operation.provider, KmsProvider::Azure);
+        assert_eq!(operation.key_id.as_deref(), Some("tenant-kek"));
+        assert_eq!(
+            operation.key_spec.as_deref(),
+            Some("azkeys.EncryptionAlgorithmRSAOAEP256")
+        );
+    }
+
+    #[test]
+    fn test_gcp_key_from_config_is_kept_as_expression() {
+        let found = kms(r#"
+package main
+
+import (
+    "context"
+
+    kms "cloud.google.com/go/kms/apiv1"
+    "cloud.google.com/go/kms/apiv1/kmspb"
+)
+
+func seal(ctx context.Context, cfg Config, plaintext []byte) {
+    client, _ := kms.NewKeyManagementClient(ctx)
+    client.Encrypt(ctx, &kmspb.EncryptRequest{Name: cfg.KeyName, Plaintext: plaintext})
+}
+"#);
+        assert_eq!(found.len(), 1);
+        let operation = found[0].as_ref().unwrap();
+        assert_eq!(operation.provider, KmsProvider::Gcp);
+        assert_eq!(operation.key_id.as_deref(), Some("cfg.KeyName"));
+        assert_eq!(operation.key_spec, None);
+    }
+}
@@ -1,13 +1,64 @@
+mod aead_lifetime;
+mod agility;
+mod annotation;
+mod bias;
+mod build;
+mod callbacks;
+mod comparison;
+mod crash;
+mod effort;
+mod failure;
+mod fallback;
+mod generated;
 mod imports;
-
-use std::collections::HashMap;
+mod key_encoding;
+mod key_exchange;
+mod kms;
+mod nonce;
+mod password;
+mod pkcs11;
+mod provenance;
+mod receiver;
+mod selection;
+mod tuning;
+mod unauthenticated;
+mod validity;
+
+use std::cell::RefCell;
+use std::collections::{HashMap, HashSet};
+use std::rc::Rc;
+use std::sync::Arc;
 use tracing::{debug, trace, warn};
 use tree_sitter::{Node, Tree};
 
-use crate::engine::{Context, NodeCategory, Resolver, Value};
+use crate::engine::{
+    Context, FileCache, ImportEquivalences, NodeCategory, Resolver, Value, ValueOverrides,
+    DEFAULT_MAX_DERIVATION_DEPTH,
+};
 use crate::query::QueryEngine;
 use crate::utils::{extract_last_segment, unquote_string};
+pub use aead_lifetime::{AeadScope, LongLivedAead};
+pub use agility::{Agility, AgilityClass, ConstantRef};
+pub use annotation::{DeclaredSecrets, SECRET_MARKER};
+pub use bias::{BiasKind, RandomBias};
+pub use callbacks::{CallbackKind, CallbackRegistration};
+pub use comparison::{SecretComparison, SecretMaterial};
+pub use crash::{CrashKind, CrashPath};
+pub use effort::RemediationEffort;
+pub use failure::{FailureKind, FailurePath};
+pub use fallback::{ErrorHandling, ZeroFallback};
 pub use imports::ImportMap;
+pub use key_encoding::{KeyDestination, KeyEncoding};
+pub use key_exchange::{KeyExchange, KeyLifetime};
+pub use kms::{KmsOperation, KmsProvider};
+pub use nonce::NonceCounter;
+pub use password::{PasswordStorage, PasswordStorageKind};
+pub use pkcs11::Pkcs11Operation;
+pub use provenance::{key_sizes, secret_argument, ByteOrigin, ByteSource};
+pub use selection::{Selection, DEFAULT_CASE};
+pub use tuning::IterationTuning;
+pub use unauthenticated::UnauthenticatedMode;
+pub use validity::{ValidityKind, ValidityPeriod};
 
 /// Trait for matching function calls to preset patterns.
 ///
@@ -128,6 +179,59 @@ pub struct Finding {
     pub arguments: Vec<Value>,
     pub raw_text: String,
     pub language: String,
+    /// Name of the function or method containing the call, if any.
+    pub enclosing_function: Option<String>,
+    /// `return nil, nil` and `panic` paths of the enclosing `(T, error)` constructor.
+    pub failure_paths: Vec<FailurePath>,
+    /// `panic` and `log.Fatal` calls of the enclosing function, outside package `main`.
+    pub crash_paths: Vec<CrashPath>,
+    /// Arguments that are a loader's zero value when its error is ignored or swallowed.
+    pub zero_fallbacks: Vec<ZeroFallback>,
+    /// The registry case returning this call, when a dispatcher switches on a parameter.
+    pub selection: Option<Selection>,
+    /// Calls that constructed the receiver of a method call, outermost last.
+    pub receiver_chain: Vec<String>,
+    /// The constructor call or composite literal that built the receiver of a method call.
+    pub receiver_construction: Option<ReceiverConstruction>,
+    /// Where the password or input key material comes from, for key-derivation calls.
+    pub secret: Option<ByteSource>,
+    /// Where the salt comes from, for key-derivation calls.
+    pub salt: Option<ByteSource>,
+    /// Where the key comes from, for cipher and MAC constructors.
+    pub key: Option<ByteSource>,
+    /// Where the nonce comes from, for AEAD `Seal` and `Open` calls.
+    pub nonce: Option<ByteSource>,
+    /// Where an encoded private key goes, for key marshaling and PEM encoding calls.
+    pub key_encoding: Option<KeyEncoding>,
+    /// Whether the private key of a key exchange is ephemeral or reused across sessions.
+    pub key_exchange: Option<KeyExchange>,
+    /// The incremented counter an AEAD nonce is encoded from, and whether it is bounded.
+    pub nonce_counter: Option<NonceCounter>,
+    /// A password reaching a fast hash or persistence call.
+    pub password_storage: Option<PasswordStorage>,
+    /// A secret operand of a comparison that does not run in constant time.
+    pub secret_comparison: Option<SecretComparison>,
+    /// A KDF work factor tuned at runtime from wall-clock timing, and its enforced floor.
+    pub iteration_tuning: Option<IterationTuning>,
+    /// A package-level or singleton AEAD sealing under a key nothing rotates.
+    pub long_lived_aead: Option<LongLivedAead>,
+    /// The cloud KMS key and key spec a KMS client call encrypts or generates data keys under.
+    pub kms: Option<KmsOperation>,
+    /// The mechanisms and key template of a PKCS#11 call, and the algorithm they name.
+    pub pkcs11: Option<Pkcs11Operation>,
+    /// A CBC, CTR, OFB or CFB mode whose ciphertext no MAC in the same function covers.
+    pub unauthenticated_mode: Option<UnauthenticatedMode>,
+    /// A random number reduced to a range with bias, or a `math/rand` value used as a secret.
+    pub random_bias: Option<RandomBias>,
+    /// Certificate lifetimes, key rotation intervals and key cache TTLs the call sets.
+    pub validity: Vec<ValidityPeriod>,
+    /// The HTTP route, cron schedule or worker pool a framework calls the enclosing
+    /// function through.
+    pub callback: Option<CallbackRegistration>,
+    /// Estimated work to replace the call, from how its arguments reach it.
+    pub remediation_effort: Option<RemediationEffort>,
+    /// Whether the algorithm and tunable arguments are hardcoded, constants or configurable.
+    pub agility: Option<Agility>,
 }
 
 impl Finding {
@@ -139,6 +243,16 @@ impl Finding {
     }
 }
 
+/// How the receiver of a method sink was built: `jose.NewEncrypter(enc, rcpt, opts)` or
+/// `&tls.Config{MinVersion: ...}`.
+#[derive(Debug, Clone)]
+pub struct ReceiverConstruction {
+    /// Constructor as `import/path.Function`, or the literal's type as `import/path.Type`.
+    pub name: String,
+    /// Constructor arguments in order, or literal fields by name.
+    pub arguments: Vec<(Option<String>, Value)>,
+}
+
 #[derive(Debug, Clone)]
 pub struct ConfigField {
     pub field_name: String,
@@ -171,21 +285,38 @@ impl ConfigFinding {
     }
 }
 
+/// The module that owns a scanned file, with its package URL.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct ModuleRef {
+    pub path: String,
+    pub version: Option<String>,
+    pub purl: String,
+}
+
 #[derive(Debug, Clone, Default)]
 pub struct ScanResult {
     pub file_path: String,
+    /// Module that owns the file, when it could be determined.
+    pub module: Option<ModuleRef>,
     pub calls: Vec<Finding>,
     pub configs: Vec<ConfigFinding>,
     pub errors: Vec<String>,
+    /// Build constraint the file is compiled under (Go `//go:build` or file name suffix).
+    pub build_constraint: Option<String>,
+    /// Generator of the file when it is a generated mock, e.g. `mockgen`.
+    pub mock: Option<String>,
 }
 
 impl ScanResult {
     pub fn new(file_path: String) -> Self {
         Self {
             file_path,
+            module: None,
             calls: Vec::new(),
             configs: Vec::new(),
             errors: Vec::new(),
+            build_constraint: None,
+            mock: None,
         }
     }
 
@@ -221,6 +352,16 @@ pub struct Scanner {
     matcher: Box<dyn CallMatcher>,
     query_engine: QueryEngine,
     struct_fields: StructFieldsMap,
+    /// Lowercased function names under any mapping; method calls with one of these
+    /// names get their Go receiver type inferred when the operand is not a package.
+    mapped_functions: HashSet<String>,
+    /// Forks reported under their upstream import path.
+    import_equivalences: ImportEquivalences,
+    max_derivation_depth: usize,
+    /// Values resolved for package-level names instead of their declarations.
+    overrides: Arc<ValueOverrides>,
+    /// Secret sources and sinks declared in the rules.
+    secrets: Arc<DeclaredSecrets>,
 }
 
 impl Scanner {
@@ -230,6 +371,11 @@ impl Scanner {
             matcher: Box::new(PatternMatcher::new(vec![])),
             query_engine: QueryEngine::new(),
             struct_fields: HashMap::new(),
+            mapped_functions: HashSet::new(),
+            import_equivalences: ImportEquivalences::default(),
+            max_derivation_depth: DEFAULT_MAX_DERIVATION_DEPTH,
+            overrides: Arc::default(),
+            secrets: Arc::default(),
         }
     }
 
@@ -239,6 +385,11 @@ impl Scanner {
             matcher: Box::new(PatternMatcher::new(vec![])),
             query_engine: QueryEngine::new(),
             struct_fields: HashMap::new(),
+            mapped_functions: HashSet::new(),
+            import_equivalences: ImportEquivalences::default(),
+            max_derivation_depth: DEFAULT_MAX_DERIVATION_DEPTH,
+            overrides: Arc::default(),
+            secrets: Arc::default(),
         }
     }
 
@@ -252,12 +403,7 @@ impl Scanner {
     }
 
     pub fn with_mappings(mappings: MappingsMap) -> Self {
-        Self {
-            resolver: Resolver::new(),
-            matcher: Box::new(MappingMatcher::new(mappings)),
-            query_engine: QueryEngine::new(),
-            struct_fields: HashMap::new(),
-        }
+        Self::with_mappings_and_struct_fields(mappings, HashMap::new())
     }
 
     pub fn with_struct_fields(mut self, struct_fields: StructFieldsMap) -> Self {
@@ -265,15 +411,50 @@ impl Scanner {
         self
     }
 
+    pub fn with_import_equivalences(mut self, equivalences: ImportEquivalences) -> Self {
+        self.import_equivalences = equivalences;
+        self
+    }
+
+    /// Limits how many declarations an argument is followed through before it is
+    /// reported unresolved with source `derivation_limit`.
+    pub fn with_max_derivation_depth(mut self, depth: usize) -> Self {
+        self.max_derivation_depth = depth;
+        self
+    }
+
+    /// Resolves the overridden package-level names to the given values, for what-if
+    /// simulation.
+    pub fn with_overrides(mut self, overrides: ValueOverrides) -> Self {
+        self.overrides = Arc::new(overrides);
+        self
+    }
+
+    /// Treats the declared sources and sinks like values marked `//argflow:secret` and
+    /// the built-in persistence calls.
+    pub fn with_secrets(mut self, secrets: DeclaredSecrets) -> Self {
+        self.secrets = Arc::new(secrets);
+        self
+    }
+
     pub fn with_mappings_and_struct_fields(
         mappings: MappingsMap,
         struct_fields: StructFieldsMap,
     ) -> Self {
+        let mapped_functions = mappings
+            .values()
+            .flat_map(|functions| functions.keys().cloned())
+            .collect();
         Self {
             resolver: Resolver::new(),
             matcher: Box::new(MappingMatcher::new(mappings)),
             query_engine: QueryEngine::new(),
             struct_fields,
+            mapped_functions,
+            import_equivalences: ImportEquivalences::default(),
+            max_derivation_depth: DEFAULT_MAX_DERIVATION_DEPTH,
+            overrides: Arc::default(),
+            secrets: Arc::default(),
         }
     }
 
@@ -283,24 +464,78 @@ impl Scanner {
         source: &'a [u8],
         file_path: &str,
         language: &str,
+    ) -> ScanResult {
+        self.scan(tree, source, file_path, language, None)
+    }
+
+    /// Like [`Scanner::scan_tree`], but resolves constants from other files and
+    /// imported packages through a shared project index.
+    pub fn scan_tree_with_cache<'a>(
+        &self,
+        tree: &'a Tree,
+        source: &'a [u8],
+        file_path: &str,
+        language: &str,
+        file_cache: Rc<RefCell<FileCache>>,
+    ) -> ScanResult {
+        self.scan(tree, source, file_path, language, Some(file_cache))
+    }
+
+    fn scan<'a>(
+        &self,
+        tree: &'a Tree,
+        source: &'a [u8],
+        file_path: &str,
+        language: &str,
+        file_cache: Option<Rc<RefCell<FileCache>>>,
     ) -> ScanResult {
         trace!(file_path, language, "scanning tree");
 
         let source_str = std::str::from_utf8(source).unwrap_or("");
-        let ctx = Context::new(
-            tree,
-            source,
-            file_path.to_string(),
-            language.to_string(),
-            HashMap::new(),
-        );
 
         let imports = self.extract_imports_via_query(tree, source_str, language);
         trace!(import_count = imports.len(), "extracted imports");
 
+        let ctx = match file_cache {
+            Some(cache) => Context::with_file_cache(
+                tree,
+                source,
+                file_path.to_string(),
+                language.to_string(),
+                HashMap::new(),
+                cache,
+            )
+            .with_imports(
+                imports
+                    .iter()
+                    .map(|(alias, path)| (alias.clone(), path.clone()))
+                    .collect(),
+            ),
+            None => Context::new(
+                tree,
+                source,
+                file_path.to_string(),
+                language.to_string(),
+                HashMap::new(),
+            ),
+        }
+        .with_max_derivation_depth(self.max_derivation_depth)
+        .with_overrides(Arc::clone(&self.overrides));
         let mut result = ScanResult::new(file_path.to_string());
+        if language == "go" {
+            result.build_constraint = build::go_build_constraint(source_str, file_path);
+            result.mock = generated::go_mock_generator(source_str).map(String::from);
+        }
         self.traverse_node(tree.root_node(), &ctx, &imports, &mut result);
 
+        // Matching above is best-effort on a tree with error nodes; record where
+        // parsing went wrong so the file is reported as partially analyzed.
+        if tree.root_node().has_error() {
+            for error in collect_syntax_errors(tree.root_node()) {
+                result.add_error(error);
+            }
+        }
+
         debug!(
             file_path,
             calls = result.call_count(),
@@ -344,7 +579,7 @@ impl Scanner {
                     let short_name = alias_opt
                         .map(|s| s.to_string())
                         .unwrap_or_else(|| extract_last_segment(&p));
-                    imports.insert(short_name, p);
+                    imports.insert(short_name, self.import_equivalences.canonical(&p));
                 }
                 _ => {}
             }
@@ -362,7 +597,151 @@ impl Scanner {
     ) {
         // Detect function calls
         if ctx.is_node_category(node.kind(), NodeCategory::CallExpression) {
-            if let Some(call) = self.process_call_node(&node, ctx, imports) {
+            if let Some(mut call) = self.process_call_node(&node, ctx, imports) {
+                if self.is_match(&call) {
+                    if ctx.language() == "go" {
+                        let import_path = call.import_path.as_deref();
+                        call.secret = provenance::go_secret_source(
+                            &node,
+                            import_path,
+                            &call.function_name,
+                            ctx,
+                            imports,
+                        );
+                        call.salt = provenance::go_salt_source(
+                            &node,
+                            import_path,
+                            &call.function_name,
+                            ctx,
+                            imports,
+                        );
+                        call.key = provenance::go_key_source(
+                            &node,
+                            import_path,
+                            &call.function_name,
+                            ctx,
+                            imports,
+                        );
+                        call.nonce = provenance::go_nonce_source(
+                            &node,
+                            import_path,
+                            &call.function_name,
+                            call.package.as_deref(),
+                            ctx,
+                            imports,
+                        );
+                        call.key_encoding = key_encoding::go_key_encoding(
+                            &node,
+                            import_path,
+                            &call.function_name,
+                            ctx,
+                            imports,
+                        );
+                        call.key_exchange = key_exchange::go_key_exchange(
+                            &node,
+                            import_path,
+                            &call.function_name,
+                            ctx,
+                            imports,
+                        );
+                        call.nonce_counter = nonce::go_nonce_counter(
+                            &node,
+                            import_path,
+                            &call.function_name,
+                            ctx,
+                            imports,
+                        );
+                        call.password_storage = password::go_password_storage(
+                            &node,
+                            import_path,
+                            &call.function_name,
+                            ctx,
+                            imports,
+                            &self.secrets,
+                        );
+                        call.secret_comparison = comparison::go_secret_comparison(
+                            &node,
+                            import_path,
+                            &call.function_name,
+                            ctx,
+                            imports,
+                            &self.secrets,
+                        );
+                        call.iteration_tuning = tuning::go_iteration_tuning(
+                            &node,
+                            import_path,
+                            &call.function_name,
+                            ctx,
+                            imports,
+                        );
+                        call.long_lived_aead = aead_lifetime::go_long_lived_aead(
+                            &node,
+                            import_path,
+                            &call.function_name,
+                            ctx,
+                            imports,
+                        );
+                        call.kms =
+                            kms::go_kms_operation(&node, import_path, &call.function_name, ctx);
+                        call.pkcs11 = pkcs11::go_pkcs11_operation(
+                            &node,
+                            import_path,
+                            &call.function_name,
+                            ctx,
+                        );
+                        call.unauthenticated_mode = unauthenticated::go_unauthenticated_mode(
+                            &node,
+                            import_path,
+                            &call.function_name,
+                            ctx,
+                            imports,
+                        );
+                        call.random_bias = bias::go_random_bias(
+                            &node,
+                            import_path,
+                            &call.function_name,
+                            ctx,
+                            imports,
+                        );
+                        call.validity = validity::go_validity(
+                            &node,
+                            import_path,
+                            &call.function_name,
+                            ctx,
+                            imports,
+                        );
+                        call.callback = callbacks::go_registered_callback(&node, ctx, imports);
+                        // Marshaling and SQL writes are only crypto-relevant for passwords,
+                        // byte comparisons only for secrets, timers only for key rotation
+                        if call.password_storage.is_none()
+                            && password::is_persistence_sink(
+                                import_path,
+                                &call.function_name,
+                                &self.secrets,
+                            )
+                            || call.secret_comparison.is_none()
+                                && comparison::is_comparison_sink(import_path, &call.function_name)
+                            || call.validity.is_empty()
+                                && validity::is_schedule_sink(import_path, &call.function_name)
+                        {
+                            return self.traverse_children(node, ctx, imports, result);
+                        }
+                        call.failure_paths = failure::go_failure_paths(&node, ctx);
+                        call.crash_paths = crash::go_crash_paths(&node, ctx, imports);
+                        call.zero_fallbacks = fallback::go_zero_fallbacks(&node, ctx, imports);
+                        call.selection = selection::go_selection(&node, ctx);
+                        call.remediation_effort =
+                            Some(effort::go_remediation_effort(&node, &call, ctx, imports));
+                        call.agility = Some(agility::go_agility(&node, &call, ctx, imports));
+                    }
+                    result.add_call(call);
+                }
+            }
+        }
+
+        // Detect `==` and `!=` on secrets (Go)
+        if ctx.language() == "go" {
+            if let Some(call) = self.process_operator_comparison(&node, ctx, imports) {
                 if self.is_match(&call) {
                     result.add_call(call);
                 }
@@ -376,6 +755,16 @@ impl Scanner {
             }
         }
 
+        self.traverse_children(node, ctx, imports, result);
+    }
+
+    fn traverse_children<'a>(
+        &self,
+        node: Node<'a>,
+        ctx: &Context<'a>,
+        imports: &ImportMap,
+        result: &mut ScanResult,
+    ) {
         let mut cursor = node.walk();
         for child in node.children(&mut cursor) {
             self.traverse_node(child, ctx, imports, result);
@@ -581,7 +970,26 @@ impl Scanner {
         let arguments = self.extract_arguments(node, ctx);
         let raw_text = ctx.get_node_text(node);
 
-        let import_path = package.as_ref().and_then(|pkg| imports.resolve(pkg));
+        let mut import_path = package.as_ref().and_then(|pkg| imports.resolve(pkg));
+        let mut receiver_chain = Vec::new();
+        let mut receiver_construction = None;
+        if import_path.is_none()
+            && ctx.language() == "go"
+            && self
+                .mapped_functions
+                .contains(&function_name.to_lowercase())
+        {
+            if let Some(receiver) = package
+                .as_deref()
+                .and_then(|operand| receiver::go_receiver_type(node, operand, ctx, imports))
+            {
+                receiver_construction = receiver.construction.and_then(|built| {
+                    self.receiver_construction(&built, &receiver.type_name, ctx, imports)
+                });
+                import_path = Some(receiver.type_name);
+                receiver_chain = receiver.chain;
+            }
+        }
 
         let start = node.start_position();
 
@@ -595,6 +1003,113 @@ impl Scanner {
             arguments,
             raw_text,
             language: ctx.language().to_string(),
+            enclosing_function: enclosing_function_name(node, ctx),
+            failure_paths: Vec::new(),
+            crash_paths: Vec::new(),
+            zero_fallbacks: Vec::new(),
+            selection: None,
+            receiver_chain,
+            receiver_construction,
+            secret: None,
+            salt: None,
+            key: None,
+            nonce: None,
+            key_encoding: None,
+            key_exchange: None,
+            nonce_counter: None,
+            password_storage: None,
+            secret_comparison: None,
+            iteration_tuning: None,
+            long_lived_aead: None,
+            kms: None,
+            pkcs11: None,
+            unauthenticated_mode: None,
+            random_bias: None,
+            validity: Vec::new(),
+            callback: None,
+            remediation_effort: None,
+            agility: None,
+        })
+    }
+
+    /// A finding for an `==` or `!=` expression comparing a secret, named after the operator.
+    fn process_operator_comparison<'a>(
+        &self,
+        node: &Node<'a>,
+        ctx: &Context<'a>,
+        imports: &ImportMap,
+    ) -> Option<Finding> {
+        let (operator, comparison) =
+            comparison::go_operator_comparison(node, ctx, imports, &self.secrets)?;
+        let start = node.start_position();
+        Some(Finding {
+            file_path: ctx.file_path().to_string(),
+            line: start.row + 1,
+            column: start.column + 1,
+            function_name: operator,
+            package: None,
+            import_path: Some(comparison::OPERATOR_PACKAGE.to_string()),
+            arguments: Vec::new(),
+            raw_text: ctx.get_node_text(node),
+            language: ctx.language().to_string(),
+            enclosing_function: enclosing_function_name(node, ctx),
+            failure_paths: Vec::new(),
+            crash_paths: Vec::new(),
+            zero_fallbacks: Vec::new(),
+            selection: None,
+            receiver_chain: Vec::new(),
+            receiver_construction: None,
+            secret: None,
+            salt: None,
+            key: None,
+            nonce: None,
+            key_encoding: None,
+            key_exchange: None,
+            nonce_counter: None,
+            password_storage: None,
+            secret_comparison: Some(comparison),
+            iteration_tuning: None,
+            long_lived_aead: None,
+            kms: None,
+            pkcs11: None,
+            unauthenticated_mode: None,
+            random_bias: None,
+            validity: Vec::new(),
+            callback: None,
+            remediation_effort: None,
+            agility: None,
+        })
+    }
+
+    fn receiver_construction<'a>(
+        &self,
+        node: &Node<'a>,
+        type_name: &str,
+        ctx: &Context<'a>,
+        imports: &ImportMap,
+    ) -> Option<ReceiverConstruction> {
+        if node.kind() == "call_expression" {
+            return Some(ReceiverConstruction {
+                name: receiver::callee(*node, ctx, imports)?,
+                arguments: self
+                    .extract_arguments(node, ctx)
+                    .into_iter()
+                    .map(|value| (None, value))
+                    .collect(),
+            });
+        }
+
+        let body = self.find_struct_body(node)?;
+        let mut cursor = body.walk();
+        let arguments = body
+            .named_children(&mut cursor)
+            .filter(|child| child.kind() == "keyed_element")
+            .filter_map(|child| self.extract_keyed_field(&child, ctx, None))
+            .map(|field| (Some(field.field_name), field.value))
+            .collect();
+        Some(ReceiverConstruction {
+            name: type_name.to_string(),
+            arguments,
         })
     }
 
@@ -698,6 +1213,63 @@ impl Default for Scanner {
     }
 }
 
+/// Declaration kinds that introduce a named function or method, across supported languages.
+const FUNCTION_DECLARATION_KINDS: &[&str] = &[
+    "function_declaration",
+    "method_declaration",
+    "function_definition",
+    "function_item",
+    "method_definition",
+];
+
+fn enclosing_function_name<'a>(node: &Node<'a>, ctx: &Context<'a>) -> Option<String> {
+    let mut current = node.parent();
+    while let Some(parent) = current {
+        if FUNCTION_DECLARATION_KINDS.contains(&parent.kind()) {
+            return parent
+                .child_by_field_name("name")
+                .map(|name| ctx.get_node_text(&name));
+        }
+        current = parent.parent();
+    }
+    None
+}
+
+/// Upper bound on syntax errors reported per file; one broken construct tends to cascade.
+const MAX_SYNTAX_ERRORS: usize = 5;
+
+fn collect_syntax_errors(root: Node) -> Vec<String> {
+    let mut errors = Vec::new();
+    let mut stack = vec![root];
+
+    while let Some(node) = stack.pop() {
+        if errors.len() >= MAX_SYNTAX_ERRORS {
+            break;
+        }
+        if node.is_error() || node.is_missing() {
+            let position = node.start_position();
+            let what = if node.is_missing() {
+                format!("missing {}", node.kind())
+            } else {
+                "syntax error".to_string()
+            };
+            errors.push(format!(
+                "{what} at line {}, column {}",
+                position.row + 1,
+                position.column + 1
+            ));
+            continue;
+        }
+        if node.has_error() {
+            let mut cursor = node.walk();
+            let children: Vec<_> = node.children(&mut cursor).collect();
+            stack.extend(children.into_iter().rev());
+        }
+    }
+
+    errors
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
@@ -732,6 +1304,29 @@ func main() { pbkdf2.Key() }"#;
         assert_eq!(result.call_count(), 1);
     }
 
+    #[test]
+    fn test_scan_records_syntax_errors() {
+        let scanner = Scanner::new().with_patterns(test_patterns());
+        let source = r#"package main
+func main() { pbkdf2.Key() }
+func broken( {
+"#;
+        let tree = parse_go(source);
+        let result = scanner.scan_tree(&tree, source.as_bytes(), "test.go", "go");
+        assert_eq!(result.call_count(), 1);
+        assert!(result.has_errors());
+    }
+
+    #[test]
+    fn test_scan_clean_file_has_no_errors() {
+        let scanner = Scanner::new().with_patterns(test_patterns());
+        let source = r#"package main
+func main() { pbkdf2.Key() }"#;
+        let tree = parse_go(source);
+        let result = scanner.scan_tree(&tree, source.as_bytes(), "test.go", "go");
+        assert!(!result.has_errors());
+    }
+
     #[test]
     fn test_finding_full_name() {
         let call = Finding {
@@ -744,6 +1339,32 @@ func main() { pbkdf2.Key() }"#;
             arguments: vec![],
             raw_text: "pbkdf2.Key(...)".to_string(),
             language: "go".to_string(),
+            enclosing_function: None,
+            failure_paths: Vec::new(),
+            crash_paths: Vec::new(),
+            zero_fallbacks: Vec::new(),
+            selection: None,
+            receiver_chain: Vec::new(),
+            receiver_construction: None,
+            secret: None,
+            salt: None,
+            key: None,
+            nonce: None,
+            key_encoding: None,
+            key_exchange: None,
+            nonce_counter: None,
+            password_storage: None,
+            secret_comparison: None,
+            iteration_tuning: None,
+            long_lived_aead: None,
+            kms: None,
+            pkcs11: None,
+            unauthenticated_mode: None,
+            random_bias: None,
+            validity: Vec::new(),
+            callback: None,
+            remediation_effort: None,
+            agility: None,
         };
         assert_eq!(call.full_name(), "pbkdf2.Key");
     }
@@ -760,6 +1381,32 @@ func main() { pbkdf2.Key() }"#;
             arguments: vec![],
             raw_text: "encrypt(...)".to_string(),
             language: "go".to_string(),
+            enclosing_function: None,
+            failure_paths: Vec::new(),
+            crash_paths: Vec::new(),
+            zero_fallbacks: Vec::new(),
+            selection: None,
+            receiver_chain: Vec::new(),
+            receiver_construction: None,
+            secret: None,
+            salt: None,
+            key: None,
+            nonce: None,
+            key_encoding: None,
+            key_exchange: None,
+            nonce_counter: None,
+            password_storage: None,
+            secret_comparison: None,
+            iteration_tuning: None,
+            long_lived_aead: None,
+            kms: None,
+            pkcs11: None,
+            unauthenticated_mode: None,
+            random_bias: None,
+            validity: Vec::new(),
+            callback: None,
+            remediation_effort: None,
+            agility: None,
         };
         assert_eq!(call.full_name(), "encrypt");
     }
@@ -780,6 +1427,32 @@ func main() { pbkdf2.Key() }"#;
             arguments: vec![],
             raw_text: "test()".to_string(),
             language: "go".to_string(),
+            enclosing_function: None,
+            failure_paths: Vec::new(),
+            crash_paths: Vec::new(),
+            zero_fallbacks: Vec::new(),
+            selection: None,
+            receiver_chain: Vec::new(),
+            receiver_construction: None,
+            secret: None,
+            salt: None,
+            key: None,
+            nonce: None,
+            key_encoding: None,
+            key_exchange: None,
+            nonce_counter: None,
+            password_storage: None,
+            secret_comparison: None,
+            iteration_tuning: None,
+            long_lived_aead: None,
+            kms: None,
+            pkcs11: None,
+            unauthenticated_mode: None,
+            random_bias: None,
+            validity: Vec::new(),
+            callback: None,
+            remediation_effort: None,
+            agility: None,
         });
         assert_eq!(result.call_count(), 1);
 
@@ -915,6 +1588,33 @@ func main() {
         );
     }
 
+    #[test]
+    fn test_import_tracking_go_fork_reports_upstream_path() {
+        let source = r#"
+package main
+
+import "example.com/app/internal/thirdparty/pbkdf2"
+
+func main() {
+    key := pbkdf2.Key(password, salt, 10000, 32, sha256.New)
+}
+"#;
+        let tree = parse_go(source);
+        let scanner = Scanner::new()
+            .with_patterns(test_patterns())
+            .with_import_equivalences(ImportEquivalences::new(vec![(
+                "internal/thirdparty/pbkdf2".to_string(),
+                "golang.org/x/crypto/pbkdf2".to_string(),
+            )]));
+        let result = scanner.scan_tree(&tree, source.as_bytes(), "test.go", "go");
+
+        assert_eq!(result.call_count(), 1);
+        assert_eq!(
+            result.calls[0].import_path,
+            Some("golang.org/x/crypto/pbkdf2".to_string())
+        );
+    }
+
     #[test]
     fn test_import_tracking_go_aliased() {
         let source = r#"
@@ -978,6 +1678,143 @@ func main() {
         );
     }
 
+    #[test]
+    fn test_go_receiver_type_inferred_for_mapped_methods() {
+        let source = r#"
+package main
+
+import (
+    "net/http"
+)
+
+func serve(other *http.Server) {
+    srv := &http.Server{Addr: ":443"}
+    srv.ListenAndServeTLS("cert.pem", "key.pem")
+    other.ServeTLS(nil, "", "")
+    client.ListenAndServeTLS("", "")
+}
+"#;
+        let tree = parse_go(source);
+        let mappings = HashMap::from([(
+            "net/http.server".to_string(),
+            HashMap::from([
+                (
+                    "listenandservetls".to_string(),
+                    "server_tls_http_listen".to_string(),
+                ),
+                ("servetls".to_string(), "server_tls_http_listen".to_string()),
+            ]),
+        )]);
+        let scanner = Scanner::with_mappings(mappings);
+        let result = scanner.scan_tree(&tree, source.as_bytes(), "test.go", "go");
+
+        let receivers: Vec<_> = result
+            .calls
+            .iter()
+            .map(|c| (c.package.as_deref(), c.import_path.as_deref()))
+            .collect();
+        assert_eq!(
+            receivers,
+            vec![
+                (Some("srv"), Some("net/http.Server")),
+                (Some("other"), Some("net/http.Server")),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_go_aead_receiver_records_construction_chain() {
+        let source = r#"
+package token
+
+import (
+    "crypto/aes"
+    "crypto/cipher"
+)
+
+func seal(key, nonce, payload []byte) []byte {
+    block, _ := aes.NewCipher(key)
+    gcm, err := cipher.NewGCM(block)
+    return gcm.Seal(nil, nonce, payload, nil)
+}
+"#;
+        let tree = parse_go(source);
+        let mappings = HashMap::from([(
+            "crypto/cipher.aead".to_string(),
+            HashMap::from([("seal".to_string(), "aead_seal".to_string())]),
+        )]);
+        let scanner = Scanner::with_mappings(mappings);
+        let result = scanner.scan_tree(&tree, source.as_bytes(), "token.go", "go");
+
+        assert_eq!(result.call_count(), 1);
+        let call = &result.calls[0];
+        assert_eq!(call.import_path.as_deref(), Some("crypto/cipher.AEAD"));
+        assert_eq!(
+            call.receiver_chain,
+            vec!["aes.NewCipher(key)", "cipher.NewGCM(block)"]
+        );
+    }
+
+    #[test]
+    fn test_go_third_party_method_sink_records_receiver_construction() {
+        let source = r#"
+package token
+
+import (
+    "crypto/tls"
+
+    jose "github.com/go-jose/go-jose/v4"
+)
+
+func encrypt(key []byte, payload, aad []byte) {
+    enc, _ := jose.NewEncrypter(jose.A128GCM, jose.Recipient{Algorithm: jose.DIRECT, Key: key}, nil)
+    enc.EncryptWithAuthData(payload, aad)
+}
+
+func serve(l net.Listener) {
+    base := &tls.Config{MinVersion: tls.VersionTLS10}
+    cfg := base.Clone()
+    cfg.BuildNameToCertificate()
+}
+"#;
+        let tree = parse_go(source);
+        let mappings = HashMap::from([
+            (
+                "github.com/go-jose/go-jose/v4.encrypter".to_string(),
+                HashMap::from([(
+                    "encryptwithauthdata".to_string(),
+                    "jose_encrypt".to_string(),
+                )]),
+            ),
+            (
+                "crypto/tls.config".to_string(),
+                HashMap::from([(
+                    "buildnametocertificate".to_string(),
+                    "tls_config".to_string(),
+                )]),
+            ),
+        ]);
+        let scanner = Scanner::with_mappings(mappings);
+        let result = scanner.scan_tree(&tree, source.as_bytes(), "token.go", "go");
+
+        assert_eq!(result.call_count(), 2);
+        let encrypt = &result.calls[0];
+        assert_eq!(
+            encrypt.import_path.as_deref(),
+            Some("github.com/go-jose/go-jose/v4.Encrypter")
+        );
+        let built = encrypt.receiver_construction.as_ref().unwrap();
+        assert_eq!(built.name, "github.com/go-jose/go-jose/v4.NewEncrypter");
+        assert_eq!(built.arguments.len(), 3);
+
+        let config = &result.calls[1];
+        assert_eq!(config.import_path.as_deref(), Some("crypto/tls.Config"));
+        assert_eq!(config.receiver_chain, vec!["base.Clone()"]);
+        let built = config.receiver_construction.as_ref().unwrap();
+        assert_eq!(built.name, "crypto/tls.Config");
+        assert_eq!(built.arguments[0].0.as_deref(), Some("MinVersion"));
+    }
+
     #[test]
     fn test_import_tracking_python() {
         let source = r#"
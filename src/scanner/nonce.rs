@@ -0,0 +1,395 @@
+//! Counter nonces of Go AEAD calls, and whether anything stops the counter wrapping.
+
+use serde::Serialize;
+use tree_sitter::Node;
+
+use super::receiver::find_declaration;
+use super::ImportMap;
+use crate::engine::Context;
+
+const FUNCTION_KINDS: &[&str] = &["function_declaration", "method_declaration", "func_literal"];
+
+/// AEAD calls and the index of their nonce argument.
+const NONCE_ARGUMENTS: &[(&str, usize)] = &[
+    ("crypto/cipher.AEAD.Seal", 1),
+    ("crypto/cipher.AEAD.Open", 1),
+];
+
+const BINARY: &str = "encoding/binary";
+
+/// `encoding/binary` byte order methods writing a counter, and its width in bits.
+const ENCODERS: &[(&str, u32)] = &[
+    ("PutUint32", 32),
+    ("PutUint64", 64),
+    ("AppendUint32", 32),
+    ("AppendUint64", 64),
+];
+
+const ATOMIC_ADDS: &[&str] = &["sync/atomic.AddUint32", "sync/atomic.AddUint64"];
+
+const COMPARISONS: &[&str] = &["==", "!=", "<", "<=", ">", ">="];
+
+/// A nonce filled from an incremented counter.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct NonceCounter {
+    /// The counter expression, e.g. `s.seq`.
+    pub counter: String,
+    /// Bits of the counter written into the nonce; it wraps after `2^bits` messages.
+    pub bits: u32,
+    /// Line of the counter's field or variable declaration.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub declaration_line: Option<usize>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub declaration: Option<String>,
+    /// Whether the counter is compared against a bound or reset for key rotation.
+    pub bounded: bool,
+}
+
+/// The counter behind the nonce of `call` if `function` under `import_path` is an AEAD
+/// call whose nonce is encoded from an incremented counter.
+pub(super) fn go_nonce_counter<'a>(
+    call: &Node<'a>,
+    import_path: Option<&str>,
+    function: &str,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Option<NonceCounter> {
+    let name = format!("{}.{function}", import_path?);
+    let (_, index) = NONCE_ARGUMENTS.iter().find(|(sink, _)| *sink == name)?;
+    let nonce = root_identifier(call.child_by_field_name("arguments")?.named_child(*index)?)?;
+    let nonce = ctx.get_node_text(&nonce);
+
+    let (counter, bits) = encoded_counter(call, &nonce, ctx, imports)?;
+    let root = file_root(*call);
+    let key = counter_key(counter, ctx);
+    if !incremented(root, &key, ctx, imports) {
+        return None;
+    }
+
+    let declaration = declaration(counter, call, &key, ctx);
+    Some(NonceCounter {
+        counter: ctx.get_node_text(&counter),
+        bits,
+        declaration_line: declaration.map(|node| node.start_position().row + 1),
+        declaration: declaration.map(|node| ctx.get_node_text(&node)),
+        bounded: bounded(root, &key, ctx),
+    })
+}
+
+/// The counter encoded into `nonce` before `call` in its function: the second argument
+/// of a `Put` call on the nonce, or of the `Append` call declaring it.
+fn encoded_counter<'a>(
+    call: &Node<'a>,
+    nonce: &str,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Option<(Node<'a>, u32)> {
+    if let Some(value) = find_declaration(call, nonce, ctx).and_then(|d| d.value) {
+        if let Some((encoder, bits)) = encoder(value, ctx, imports) {
+            if encoder.starts_with("Append") {
+                return Some((unconvert(arguments(value, ctx).get(1).copied()?), bits));
+            }
+        }
+    }
+
+    let function = enclosing_function(*call)?;
+    let mut found = None;
+    walk(function, &mut |node| {
+        if node.start_byte() >= call.start_byte() || node.kind() != "call_expression" {
+            return;
+        }
+        let Some((encoder, bits)) = encoder(node, ctx, imports) else {
+            return;
+        };
+        let args = arguments(node, ctx);
+        let writes_nonce = args
+            .first()
+            .and_then(|arg| root_identifier(*arg))
+            .is_some_and(|arg| ctx.get_node_text(&arg) == nonce);
+        if encoder.starts_with("Put") && writes_nonce {
+            if let Some(counter) = args.get(1) {
+                found = Some((unconvert(*counter), bits));
+            }
+        }
+    });
+    found
+}
+
+/// `binary.BigEndian.PutUint64` -> `("PutUint64", 64)`.
+fn encoder<'a>(
+    call: Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Option<(&'static str, u32)> {
+    if call.kind() != "call_expression" {
+        return None;
+    }
+    let function = call.child_by_field_name("function")?;
+    let method = ctx.get_field_text(&function, "field")?;
+    let order = function.child_by_field_name("operand")?;
+    let package = ctx.get_node_text(&order.child_by_field_name("operand")?);
+    if imports.resolve(&package)? != BINARY {
+        return None;
+    }
+    ENCODERS
+        .iter()
+        .find(|(name, _)| *name == method)
+        .map(|&(name, bits)| (name, bits))
+}
+
+/// Whether the counter is incremented: `c++`, `c += 1`, `atomic.AddUint64(&c, 1)` or
+/// `c.Add(1)` on an atomic integer.
+fn incremented<'a>(root: Node<'a>, key: &str, ctx: &Context<'a>, imports: &ImportMap) -> bool {
+    let mut found = false;
+    walk(root, &mut |node| {
+        found |= match node.kind() {
+            "inc_statement" => node
+                .named_child(0)
+                .is_some_and(|operand| counter_key(operand, ctx) == key),
+            "assignment_statement" => {
+                ctx.get_field_text(&node, "operator").as_deref() == Some("+=")
+                    && node
+                        .child_by_field_name("left")
+                        .and_then(|left| left.named_child(0))
+                        .is_some_and(|left| counter_key(left, ctx) == key)
+            }
+            "call_expression" => {
+                let args = arguments(node, ctx);
+                let atomic_add = super::receiver::callee(node, ctx, imports)
+                    .is_some_and(|name| ATOMIC_ADDS.contains(&name.as_str()))
+                    && args
+                        .first()
+                        .is_some_and(|arg| counter_key(unaddress(*arg), ctx) == key);
+                let method_add = node
+                    .child_by_field_name("function")
+                    .is_some_and(|function| {
+                        ctx.get_field_text(&function, "field").as_deref() == Some("Add")
+                            && function
+                                .child_by_field_name("operand")
+                                .is_some_and(|operand| counter_key(operand, ctx) == key)
+                    });
+                atomic_add || method_add
+            }
+            _ => false,
+        };
+    });
+    found
+}
+
+/// Whether the counter is compared against anything, or reset to zero.
+fn bounded<'a>(root: Node<'a>, key: &str, ctx: &Context<'a>) -> bool {
+    let mut found = false;
+    walk(root, &mut |node| {
+        found |= match node.kind() {
+            "binary_expression" => {
+                ctx.get_field_text(&node, "operator")
+                    .is_some_and(|op| COMPARISONS.contains(&op.as_str()))
+                    && ["left", "right"].iter().any(|side| {
+                        node.child_by_field_name(side)
+                            .is_some_and(|operand| counter_key(unconvert(operand), ctx) == key)
+                    })
+            }
+            "assignment_statement" => {
+                ctx.get_field_text(&node, "operator").as_deref() == Some("=")
+                    && node
+                        .child_by_field_name("left")
+                        .and_then(|left| left.named_child(0))
+                        .is_some_and(|left| counter_key(left, ctx) == key)
+                    && node
+                        .child_by_field_name("right")
+                        .and_then(|right| right.named_child(0))
+                        .is_some_and(|right| ctx.get_node_text(&right) == "0")
+            }
+            _ => false,
+        };
+    });
+    found
+}
+
+/// The struct field or variable declaring the counter.
+fn declaration<'a>(
+    counter: Node<'a>,
+    call: &Node<'a>,
+    key: &str,
+    ctx: &Context<'a>,
+) -> Option<Node<'a>> {
+    if counter.kind() == "identifier" {
+        return find_declaration(call, key, ctx).map(|d| d.node);
+    }
+    let mut found = None;
+    walk(file_root(*call), &mut |node| {
+        if found.is_none()
+            && node.kind() == "field_declaration"
+            && ctx
+                .get_field_text(&node, "name")
+                .is_some_and(|name| name == key)
+        {
+            found = Some(node);
+        }
+    });
+    found
+}
+
+/// `s.seq` and `c.seq` are the same field; an identifier is itself.
+fn counter_key<'a>(counter: Node<'a>, ctx: &Context<'a>) -> String {
+    match counter.kind() {
+        "selector_expression" => ctx.get_field_text(&counter, "field").unwrap_or_default(),
+        "parenthesized_expression" => counter
+            .named_child(0)
+            .map(|inner| counter_key(inner, ctx))
+            .unwrap_or_default(),
+        _ => ctx.get_node_text(&counter),
+    }
+}
+
+/// `nonce[4:]`, `&nonce` and `nonce` -> `nonce`.
+fn root_identifier(node: Node<'_>) -> Option<Node<'_>> {
+    match node.kind() {
+        "identifier" => Some(node),
+        "slice_expression" | "unary_expression" => {
+            root_identifier(node.child_by_field_name("operand")?)
+        }
+        _ => None,
+    }
+}
+
+/// `uint64(c.seq)` -> `c.seq`.
+fn unconvert(node: Node<'_>) -> Node<'_> {
+    if node.kind() == "call_expression"
+        && node
+            .child_by_field_name("function")
+            .is_some_and(|function| function.kind() == "identifier")
+    {
+        if let Some(argument) = node
+            .child_by_field_name("arguments")
+            .and_then(|args| args.named_child(0))
+        {
+            return argument;
+        }
+    }
+    node
+}
+
+/// `&c.seq` -> `c.seq`.
+fn unaddress(node: Node<'_>) -> Node<'_> {
+    match node.kind() {
+        "unary_expression" => node.child_by_field_name("operand").unwrap_or(node),
+        _ => node,
+    }
+}
+
+fn arguments<'a>(call: Node<'a>, ctx: &Context<'a>) -> Vec<Node<'a>> {
+    call.child_by_field_name("arguments")
+        .map(|args| ctx.get_named_children(&args))
+        .unwrap_or_default()
+}
+
+fn enclosing_function(node: Node<'_>) -> Option<Node<'_>> {
+    let mut current = node.parent();
+    while let Some(parent) = current {
+        if FUNCTION_KINDS.contains(&parent.kind()) {
+            return Some(parent);
+        }
+        current = parent.parent();
+    }
+    None
+}
+
+fn file_root(node: Node<'_>) -> Node<'_> {
+    let mut root = node;
+    while let Some(parent) = root.parent() {
+        root = parent;
+    }
+    root
+}
+
+fn walk<'a>(root: Node<'a>, visit: &mut impl FnMut(Node<'a>)) {
+    let mut stack = vec![root];
+    while let Some(node) = stack.pop() {
+        visit(node);
+        let mut cursor = node.walk();
+        let children: Vec<_> = node.named_children(&mut cursor).collect();
+        stack.extend(children.into_iter().rev());
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::scanner::Scanner;
+    use std::collections::HashMap;
+
+    #[test]
+    fn test_counter_nonces_with_and_without_rotation() {
+        let source = r#"
+package channel
+
+import (
+    "crypto/cipher"
+    "encoding/binary"
+    "math"
+)
+
+type sealer struct {
+    seq uint64
+}
+
+func (s *sealer) seal(aead cipher.AEAD, payload []byte) []byte {
+    nonce := make([]byte, aead.NonceSize())
+    binary.BigEndian.PutUint64(nonce[4:], s.seq)
+    s.seq++
+    return aead.Seal(nil, nonce, payload, nil)
+}
+
+type rotating struct {
+    count uint32
+}
+
+func (r *rotating) seal(aead cipher.AEAD, payload []byte) []byte {
+    if r.count == math.MaxUint32 {
+        r.rekey()
+    }
+    r.count++
+    nonce := binary.LittleEndian.AppendUint32(make([]byte, 8), r.count)
+    return aead.Seal(nil, nonce, payload, nil)
+}
+
+func (r *rotating) rekey() {
+    r.count = 0
+}
+
+func random(aead cipher.AEAD, nonce, payload []byte) []byte {
+    return aead.Seal(nil, nonce, payload, nil)
+}
+"#;
+        let mut parser = tree_sitter::Parser::new();
+        parser
+            .set_language(&tree_sitter_go::LANGUAGE.into())
+            .unwrap();
+        let tree = parser.parse(source, None).unwrap();
+        let scanner = Scanner::with_mappings(HashMap::from([(
+            "crypto/cipher.aead".to_string(),
+            HashMap::from([("seal".to_string(), "aead_seal".to_string())]),
+        )]));
+        let result = scanner.scan_tree(&tree, source.as_bytes(), "channel.go", "go");
+
+        let counters: Vec<_> = result
+            .calls
+            .iter()
+            .map(|c| c.nonce_counter.clone())
+            .collect();
+        assert_eq!(
+            counters[0],
+            Some(NonceCounter {
+                counter: "s.seq".to_string(),
+                bits: 64,
+                declaration_line: Some(11),
+                declaration: Some("seq uint64".to_string()),
+                bounded: false,
+            })
+        );
+        let rotating = counters[1].as_ref().unwrap();
+        assert_eq!((rotating.bits, rotating.bounded), (32, true));
+        assert_eq!(counters[2], None);
+    }
+}
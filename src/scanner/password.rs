@@ -0,0 +1,382 @@
+//! Passwords, recognized by name and traced through local data flow, stored in plain
+//! text or behind a fast hash instead of a KDF.
+
+use serde::Serialize;
+use tree_sitter::Node;
+
+use super::annotation::{self, DeclaredSecrets};
+use super::receiver::{callee, find_declaration};
+use super::ImportMap;
+use crate::engine::Context;
+
+/// One-shot hashes: fast to brute-force, so never a way to store a password.
+const FAST_HASHES: &[&str] = &[
+    "crypto/md5.Sum",
+    "crypto/sha1.Sum",
+    "crypto/sha256.Sum224",
+    "crypto/sha256.Sum256",
+    "crypto/sha512.Sum384",
+    "crypto/sha512.Sum512",
+    "crypto/sha512.Sum512_256",
+    "golang.org/x/crypto/sha3.Sum256",
+    "golang.org/x/crypto/sha3.Sum512",
+];
+
+/// Calls that persist or serialize their arguments from the given index on.
+const PERSISTENCE: &[(&str, usize)] = &[
+    ("encoding/json.Marshal", 0),
+    ("encoding/json.MarshalIndent", 0),
+    ("encoding/xml.Marshal", 0),
+    ("encoding/xml.MarshalIndent", 0),
+    ("encoding/gob.Encoder.Encode", 0),
+    ("os.WriteFile", 1),
+    ("database/sql.DB.Exec", 0),
+    ("database/sql.DB.ExecContext", 1),
+    ("database/sql.Tx.Exec", 0),
+    ("database/sql.Tx.ExecContext", 1),
+];
+
+/// Calls a password is followed through because their result still holds it.
+const PASSTHROUGH: &[&str] = &["fmt.Sprintf", "fmt.Sprint", "strings.Join"];
+
+/// Name fragments marking a password.
+const LABELS: &[&str] = &["password", "passwd", "passphrase", "pwd"];
+
+/// Whole names marking a password.
+const SHORT_LABELS: &[&str] = &["pw", "pass"];
+
+/// Name fragments marking something derived from a password rather than the password.
+const NOT_PASSWORD: &[&str] = &["hash", "digest", "salt", "file", "path"];
+
+/// How many declarations an argument is followed through.
+const MAX_DEPTH: usize = 4;
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum PasswordStorageKind {
+    /// Hashed with a one-shot hash such as SHA-256 instead of a password KDF.
+    FastHash,
+    /// Serialized, written to a file or stored in a database as is.
+    Plaintext,
+}
+
+/// A password reaching a hash or persistence call.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct PasswordStorage {
+    pub kind: PasswordStorageKind,
+    /// The password-labeled value, e.g. `req.Password`.
+    pub password: String,
+    /// The argument it reaches the call through, e.g. `[]byte(req.Password)`.
+    pub expression: String,
+}
+
+/// Whether `function` under `import_path` is only a sink when a password reaches it.
+pub(super) fn is_persistence_sink(
+    import_path: Option<&str>,
+    function: &str,
+    secrets: &DeclaredSecrets,
+) -> bool {
+    import_path.is_some_and(|path| {
+        let name = format!("{path}.{function}");
+        PERSISTENCE.iter().any(|(sink, _)| *sink == name) || secrets.sink(&name).is_some()
+    })
+}
+
+/// The password reaching `call` if `function` under `import_path` is a fast hash, a
+/// persistence call or a declared sink.
+pub(super) fn go_password_storage<'a>(
+    call: &Node<'a>,
+    import_path: Option<&str>,
+    function: &str,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+    secrets: &DeclaredSecrets,
+) -> Option<PasswordStorage> {
+    let name = format!("{}.{function}", import_path?);
+    let (kind, arguments) = if FAST_HASHES.contains(&name.as_str()) {
+        (PasswordStorageKind::FastHash, 0..1)
+    } else {
+        let first = PERSISTENCE
+            .iter()
+            .find(|(sink, _)| *sink == name)
+            .map(|(_, first)| *first)
+            .or_else(|| secrets.sink(&name))?;
+        (PasswordStorageKind::Plaintext, first..usize::MAX)
+    };
+
+    let declared = kind == PasswordStorageKind::Plaintext;
+
+    let args = ctx.get_named_children(&call.child_by_field_name("arguments")?);
+    args.into_iter()
+        .enumerate()
+        .filter(|(index, _)| arguments.contains(index))
+        .find_map(|(_, argument)| {
+            let password = password_in(argument, call, declared, secrets, ctx, imports, 0)?;
+            Some(PasswordStorage {
+                kind,
+                password,
+                expression: ctx.get_node_text(&argument),
+            })
+        })
+}
+
+/// The password in `node`; `declared` counts values marked `//argflow:secret` and the
+/// results of declared sources as well.
+fn password_in<'a>(
+    node: Node<'a>,
+    call: &Node<'a>,
+    declared: bool,
+    secrets: &DeclaredSecrets,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+    depth: usize,
+) -> Option<String> {
+    let follow = |child: Node<'a>| password_in(child, call, declared, secrets, ctx, imports, depth);
+    match node.kind() {
+        "identifier" => {
+            let name = ctx.get_node_text(&node);
+            if is_password(&name) {
+                return Some(name);
+            }
+            let declaration = find_declaration(call, &name, ctx)?;
+            if declared && annotation::is_secret(declaration.node, ctx) {
+                return Some(name);
+            }
+            if let Some(field) = declaration
+                .type_node
+                .and_then(|type_node| password_field(type_node, declared, ctx))
+            {
+                return Some(format!("{name}.{field}"));
+            }
+            let value = declaration.value.filter(|_| depth < MAX_DEPTH)?;
+            password_in(value, call, declared, secrets, ctx, imports, depth + 1)
+        }
+        "selector_expression" => {
+            let field = ctx.get_field_text(&node, "field")?;
+            (is_password(&field) || declared && annotation::is_secret_field(node, &field, ctx))
+                .then(|| ctx.get_node_text(&node))
+        }
+        "unary_expression" | "slice_expression" => follow(node.child_by_field_name("operand")?),
+        "parenthesized_expression" => follow(node.named_child(0)?),
+        "binary_expression" => ["left", "right"]
+            .iter()
+            .filter_map(|side| node.child_by_field_name(side))
+            .find_map(follow),
+        "type_conversion_expression" => follow(node.child_by_field_name("operand")?),
+        "call_expression" => {
+            let function = node.child_by_field_name("function")?;
+            let passes_through = match function.kind() {
+                // `[]byte(password)`, `string(pw)`, `append(salt, password...)`
+                "identifier" => {
+                    matches!(ctx.get_node_text(&function).as_str(), "string" | "append")
+                }
+                "slice_type" | "parenthesized_type" => true,
+                _ => {
+                    let name = callee(node, ctx, imports);
+                    // `vault.Get("db")` with `internal/vault.Get` a declared source
+                    if declared && name.as_deref().is_some_and(|name| secrets.is_source(name)) {
+                        return Some(ctx.get_node_text(&node));
+                    }
+                    name.is_some_and(|name| PASSTHROUGH.contains(&name.as_str()))
+                }
+            };
+            if !passes_through {
+                return None;
+            }
+            ctx.get_named_children(&node.child_by_field_name("arguments")?)
+                .into_iter()
+                .find_map(follow)
+        }
+        "composite_literal" => {
+            let body = node.child_by_field_name("body")?;
+            composite_values(body, ctx).into_iter().find_map(follow)
+        }
+        _ => None,
+    }
+}
+
+/// Values of a composite literal body, keyed or not.
+fn composite_values<'a>(body: Node<'a>, ctx: &Context<'a>) -> Vec<Node<'a>> {
+    ctx.get_named_children(&body)
+        .into_iter()
+        .filter_map(|element| match element.kind() {
+            "keyed_element" => element.named_child(1),
+            _ => Some(element),
+        })
+        // Newer grammars wrap each side of a keyed element in a `literal_element`
+        .map(|value| match value.kind() {
+            "literal_element" => value.named_child(0).unwrap_or(value),
+            _ => value,
+        })
+        .collect()
+}
+
+/// A password field of the struct type `type_node` declares, when the type is declared
+/// in the same file and the field is not excluded from encoding. With `declared`, fields
+/// marked `//argflow:secret` count.
+fn password_field<'a>(type_node: Node<'a>, declared: bool, ctx: &Context<'a>) -> Option<String> {
+    let type_node = match type_node.kind() {
+        "pointer_type" => type_node.named_child(0)?,
+        _ => type_node,
+    };
+    if type_node.kind() != "type_identifier" {
+        return None;
+    }
+    let type_name = ctx.get_node_text(&type_node);
+
+    let mut root = type_node;
+    while let Some(parent) = root.parent() {
+        root = parent;
+    }
+    let mut stack = vec![root];
+    while let Some(node) = stack.pop() {
+        if node.kind() == "type_spec"
+            && ctx.get_field_text(&node, "name").as_deref() == Some(type_name.as_str())
+        {
+            let fields = node
+                .child_by_field_name("type")
+                .filter(|t| t.kind() == "struct_type")?
+                .named_child(0)?;
+            return ctx
+                .get_named_children(&fields)
+                .into_iter()
+                .filter(|field| {
+                    ctx.get_field_text(field, "tag")
+                        .is_none_or(|tag| !tag.contains(":\"-\""))
+                })
+                .find_map(|field| {
+                    let name = ctx.get_field_text(&field, "name")?;
+                    (is_password(&name) || declared && annotation::is_secret(field, ctx))
+                        .then_some(name)
+                });
+        }
+        let mut cursor = node.walk();
+        let children: Vec<_> = node.named_children(&mut cursor).collect();
+        stack.extend(children.into_iter().rev());
+    }
+    None
+}
+
+fn is_password(name: &str) -> bool {
+    let lower = name.to_lowercase();
+    (LABELS.iter().any(|label| lower.contains(label)) || SHORT_LABELS.contains(&lower.as_str()))
+        && !NOT_PASSWORD.iter().any(|other| lower.contains(other))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::scanner::Scanner;
+    use std::collections::HashMap;
+
+    #[test]
+    fn test_password_labels() {
+        for name in [
+            "password",
+            "Passwd",
+            "req_password",
+            "pwd",
+            "pw",
+            "Passphrase",
+        ] {
+            assert!(is_password(name), "{name}");
+        }
+        for name in [
+            "passwordHash",
+            "hashedPassword",
+            "pwdSalt",
+            "passwordFile",
+            "path",
+        ] {
+            assert!(!is_password(name), "{name}");
+        }
+    }
+
+    #[test]
+    fn test_passwords_reaching_hashes_and_storage() {
+        let source = r#"
+package users
+
+import (
+    "crypto/sha256"
+    "database/sql"
+    "encoding/json"
+
+    "golang.org/x/crypto/bcrypt"
+)
+
+type account struct {
+    Name     string
+    Password string
+}
+
+type public struct {
+    Name     string
+    Password string `json:"-"`
+}
+
+func register(db *sql.DB, name, password string) error {
+    sum := sha256.Sum256([]byte(password))
+    hash, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+    db.Exec("INSERT INTO users VALUES (?, ?)", name, hash)
+    _, err := db.Exec("INSERT INTO legacy VALUES (?, ?)", name, sum[:])
+    return err
+}
+
+func export(a account, p public) {
+    json.Marshal(a)
+    json.Marshal(p)
+    json.Marshal(map[string]string{"user": a.Name})
+}
+"#;
+        let mut parser = tree_sitter::Parser::new();
+        parser
+            .set_language(&tree_sitter_go::LANGUAGE.into())
+            .unwrap();
+        let tree = parser.parse(source, None).unwrap();
+        let scanner = Scanner::with_mappings(HashMap::from([
+            (
+                "crypto/sha256".to_string(),
+                HashMap::from([("sum256".to_string(), "sha256".to_string())]),
+            ),
+            (
+                "database/sql.db".to_string(),
+                HashMap::from([("exec".to_string(), "password_persist".to_string())]),
+            ),
+            (
+                "encoding/json".to_string(),
+                HashMap::from([("marshal".to_string(), "password_persist".to_string())]),
+            ),
+        ]));
+        let result = scanner.scan_tree(&tree, source.as_bytes(), "users.go", "go");
+
+        // The SQL writes store a bcrypt hash and a SHA-256 sum, and `p` hides its password
+        // from JSON, so only the hash call and the export of `a` are reported
+        let stored: Vec<_> = result
+            .calls
+            .iter()
+            .map(|c| (c.line, c.password_storage.clone()))
+            .collect();
+        assert_eq!(
+            stored,
+            vec![
+                (
+                    23,
+                    Some(PasswordStorage {
+                        kind: PasswordStorageKind::FastHash,
+                        password: "password".to_string(),
+                        expression: "[]byte(password)".to_string(),
+                    })
+                ),
+                (
+                    31,
+                    Some(PasswordStorage {
+                        kind: PasswordStorageKind::Plaintext,
+                        password: "a.Password".to_string(),
+                        expression: "a".to_string(),
+                    })
+                ),
+            ]
+        );
+    }
+}
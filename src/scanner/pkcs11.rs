@@ -0,0 +1,336 @@
+//! PKCS#11 calls: the mechanism and key template a Go program hands an HSM.
+
+use std::collections::BTreeMap;
+
+use serde::Serialize;
+use tree_sitter::Node;
+
+use super::receiver::find_declaration;
+use crate::engine::Context;
+use crate::utils::unquote_string;
+
+const PKCS11_CONTEXT: &str = "github.com/miekg/pkcs11.Ctx";
+const CRYPTO11_CONTEXT: &str = "github.com/ThalesIgnite/crypto11.Context";
+
+const OPERATIONS: &[&str] = &[
+    "EncryptInit",
+    "DecryptInit",
+    "SignInit",
+    "VerifyInit",
+    "DigestInit",
+    "GenerateKey",
+    "GenerateKeyPair",
+    "WrapKey",
+    "UnwrapKey",
+    "DeriveKey",
+];
+
+/// Mechanism constants to the algorithm they run.
+const MECHANISMS: &[(&str, &str)] = &[
+    ("CKM_AES_KEY_GEN", "AES"),
+    ("CKM_AES_GCM", "AES-GCM"),
+    ("CKM_AES_CBC", "AES-CBC"),
+    ("CKM_AES_CBC_PAD", "AES-CBC"),
+    ("CKM_AES_CTR", "AES-CTR"),
+    ("CKM_AES_ECB", "AES-ECB"),
+    ("CKM_AES_CMAC", "AES-CMAC"),
+    ("CKM_AES_KEY_WRAP", "AES-KW"),
+    ("CKM_AES_KEY_WRAP_PAD", "AES-KWP"),
+    ("CKM_DES3_KEY_GEN", "3DES"),
+    ("CKM_DES3_CBC", "3DES-CBC"),
+    ("CKM_DES3_ECB", "3DES-ECB"),
+    ("CKM_DES_CBC", "DES-CBC"),
+    ("CKM_DES_ECB", "DES-ECB"),
+    ("CKM_RSA_PKCS_KEY_PAIR_GEN", "RSA"),
+    ("CKM_RSA_PKCS", "RSA-PKCS1v15"),
+    ("CKM_SHA1_RSA_PKCS", "RSA-PKCS1v15"),
+    ("CKM_SHA256_RSA_PKCS", "RSA-PKCS1v15"),
+    ("CKM_SHA384_RSA_PKCS", "RSA-PKCS1v15"),
+    ("CKM_SHA512_RSA_PKCS", "RSA-PKCS1v15"),
+    ("CKM_RSA_PKCS_OAEP", "RSA-OAEP"),
+    ("CKM_RSA_PKCS_PSS", "RSA-PSS"),
+    ("CKM_SHA256_RSA_PKCS_PSS", "RSA-PSS"),
+    ("CKM_SHA384_RSA_PKCS_PSS", "RSA-PSS"),
+    ("CKM_SHA512_RSA_PKCS_PSS", "RSA-PSS"),
+    ("CKM_EC_KEY_PAIR_GEN", "EC"),
+    ("CKM_ECDSA", "ECDSA"),
+    ("CKM_ECDSA_SHA1", "ECDSA"),
+    ("CKM_ECDSA_SHA256", "ECDSA"),
+    ("CKM_ECDSA_SHA384", "ECDSA"),
+    ("CKM_ECDSA_SHA512", "ECDSA"),
+    ("CKM_ECDH1_DERIVE", "ECDH"),
+    ("CKM_GENERIC_SECRET_KEY_GEN", "generic-secret"),
+    ("CKM_MD5", "MD5"),
+    ("CKM_SHA_1", "SHA-1"),
+    ("CKM_SHA256", "SHA-256"),
+    ("CKM_SHA384", "SHA-384"),
+    ("CKM_SHA512", "SHA-512"),
+    ("CKM_SHA_1_HMAC", "HMAC-SHA1"),
+    ("CKM_SHA256_HMAC", "HMAC-SHA256"),
+    ("CKM_SHA384_HMAC", "HMAC-SHA384"),
+    ("CKM_SHA512_HMAC", "HMAC-SHA512"),
+];
+
+/// `CKA_KEY_TYPE` values to the algorithm of the key.
+const KEY_TYPES: &[(&str, &str)] = &[
+    ("CKK_AES", "AES"),
+    ("CKK_DES3", "3DES"),
+    ("CKK_DES", "DES"),
+    ("CKK_RSA", "RSA"),
+    ("CKK_EC", "EC"),
+    ("CKK_GENERIC_SECRET", "generic-secret"),
+];
+
+/// `crypto11` symmetric ciphers to the algorithm of the secret key.
+const CRYPTO11_CIPHERS: &[(&str, &str)] = &[
+    ("CipherAES", "AES"),
+    ("CipherDES3", "3DES"),
+    ("CipherGeneric", "generic-secret"),
+];
+
+/// How many declarations a mechanism or template is followed through.
+const MAX_DEPTH: usize = 4;
+
+/// The mechanisms and key template of a PKCS#11 call.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct Pkcs11Operation {
+    /// Mechanism constants passed to the call, e.g. `CKM_AES_GCM`.
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub mechanisms: Vec<String>,
+    /// The algorithm the mechanism, key type or cipher names, e.g. `AES-GCM`.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub algorithm: Option<String>,
+    /// Key template attributes to their values, e.g. `CKA_KEY_TYPE` to `CKK_AES`.
+    #[serde(skip_serializing_if = "BTreeMap::is_empty")]
+    pub attributes: BTreeMap<String, String>,
+}
+
+/// Mechanisms and template for `call` if `function` under `import_path` is a PKCS#11
+/// operation.
+pub(super) fn go_pkcs11_operation<'a>(
+    call: &Node<'a>,
+    import_path: Option<&str>,
+    function: &str,
+    ctx: &Context<'a>,
+) -> Option<Pkcs11Operation> {
+    let arguments = call.child_by_field_name("arguments")?;
+    match import_path? {
+        PKCS11_CONTEXT if OPERATIONS.contains(&function) => {
+            let mut mechanisms = Vec::new();
+            let mut attributes = BTreeMap::new();
+            let mut cursor = arguments.walk();
+            for argument in arguments.named_children(&mut cursor) {
+                for constructor in constructors(argument, call, ctx, 0) {
+                    let Some(name) = constructor_name(constructor, ctx) else {
+                        continue;
+                    };
+                    let Some(args) = constructor.child_by_field_name("arguments") else {
+                        continue;
+                    };
+                    match name.as_str() {
+                        "NewMechanism" => {
+                            if let Some(mechanism) = args.named_child(0) {
+                                mechanisms.push(value_text(mechanism, call, ctx));
+                            }
+                        }
+                        "NewAttribute" => {
+                            if let (Some(kind), Some(value)) =
+                                (args.named_child(0), args.named_child(1))
+                            {
+                                attributes.insert(
+                                    value_text(kind, call, ctx),
+                                    value_text(value, call, ctx),
+                                );
+                            }
+                        }
+                        _ => {}
+                    }
+                }
+            }
+            let algorithm = mechanisms
+                .iter()
+                .find_map(|mechanism| lookup(MECHANISMS, mechanism))
+                .or_else(|| {
+                    let key_type = attributes.get("CKA_KEY_TYPE")?;
+                    lookup(KEY_TYPES, key_type)
+                });
+            Some(Pkcs11Operation {
+                mechanisms,
+                algorithm,
+                attributes,
+            })
+        }
+        CRYPTO11_CONTEXT if function.starts_with("GenerateSecretKey") => {
+            let count = arguments.named_child_count();
+            let cipher = arguments.named_child(count.checked_sub(1)?)?;
+            Some(Pkcs11Operation {
+                mechanisms: Vec::new(),
+                algorithm: lookup(CRYPTO11_CIPHERS, &value_text(cipher, call, ctx)),
+                attributes: BTreeMap::new(),
+            })
+        }
+        _ => None,
+    }
+}
+
+fn lookup(table: &[(&str, &str)], name: &str) -> Option<String> {
+    table
+        .iter()
+        .find(|(constant, _)| *constant == name)
+        .map(|(_, algorithm)| algorithm.to_string())
+}
+
+/// `NewMechanism` and `NewAttribute` calls under an argument, following the variables
+/// it names to their values.
+fn constructors<'a>(
+    node: Node<'a>,
+    call: &Node<'a>,
+    ctx: &Context<'a>,
+    depth: usize,
+) -> Vec<Node<'a>> {
+    if node.kind() == "identifier" {
+        if depth >= MAX_DEPTH {
+            return Vec::new();
+        }
+        return find_declaration(call, &ctx.get_node_text(&node), ctx)
+            .and_then(|declaration| declaration.value)
+            .map(|value| constructors(value, call, ctx, depth + 1))
+            .unwrap_or_default();
+    }
+    if node.kind() == "call_expression" && constructor_name(node, ctx).is_some() {
+        return vec![node];
+    }
+    let mut found = Vec::new();
+    let mut cursor = node.walk();
+    for child in node.named_children(&mut cursor) {
+        found.extend(constructors(child, call, ctx, depth));
+    }
+    found
+}
+
+/// `NewMechanism` or `NewAttribute` if `node` calls one.
+fn constructor_name<'a>(node: Node<'a>, ctx: &Context<'a>) -> Option<String> {
+    let function = node.child_by_field_name("function")?;
+    let name = match function.kind() {
+        "selector_expression" => ctx.get_node_text(&function.child_by_field_name("field")?),
+        _ => ctx.get_node_text(&function),
+    };
+    matches!(name.as_str(), "NewMechanism" | "NewAttribute").then_some(name)
+}
+
+/// A constant as its bare name (`pkcs11.CKM_AES_GCM` is `CKM_AES_GCM`), a string literal
+/// unquoted, anything else as written.
+fn value_text<'a>(node: Node<'a>, call: &Node<'a>, ctx: &Context<'a>) -> String {
+    match node.kind() {
+        "selector_expression" => node
+            .child_by_field_name("field")
+            .map(|field| ctx.get_node_text(&field))
+            .unwrap_or_else(|| ctx.get_node_text(&node)),
+        "interpreted_string_literal" | "raw_string_literal" => {
+            unquote_string(&ctx.get_node_text(&node))
+        }
+        "identifier" => {
+            let name = ctx.get_node_text(&node);
+            match find_declaration(call, &name, ctx).and_then(|declaration| declaration.value) {
+                Some(value) if matches!(value.kind(), "selector_expression" | "int_literal") => {
+                    value_text(value, call, ctx)
+                }
+                _ => name,
+            }
+        }
+        _ => ctx.get_node_text(&node),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::scanner::Scanner;
+    use std::collections::HashMap;
+
+    fn pkcs11(source: &str) -> Vec<(String, Option<Pkcs11Operation>)> {
+        let mut parser = tree_sitter::Parser::new();
+        parser
+            .set_language(&tree_sitter_go::LANGUAGE.into())
+            .unwrap();
+        let tree = parser.parse(source, None).unwrap();
+        let operations = OPERATIONS
+            .iter()
+            .map(|operation| (operation.to_lowercase(), "pkcs11".to_string()))
+            .collect();
+        let scanner = Scanner::with_mappings(HashMap::from([
+            (PKCS11_CONTEXT.to_lowercase(), operations),
+            (
+                CRYPTO11_CONTEXT.to_lowercase(),
+                HashMap::from([("generatesecretkey".to_string(), "crypto11".to_string())]),
+            ),
+        ]));
+        let result = scanner.scan_tree(&tree, source.as_bytes(), "hsm.go", "go");
+        result
+            .calls
+            .into_iter()
+            .map(|call| (call.function_name, call.pkcs11))
+            .collect()
+    }
+
+    #[test]
+    fn test_mechanism_and_template_resolved() {
+        let found = pkcs11(
+            r#"
+package hsm
+
+import "github.com/miekg/pkcs11"
+
+func generate(p *pkcs11.Ctx, session pkcs11.SessionHandle) {
+    mech := []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_KEY_GEN, nil)}
+    template := []*pkcs11.Attribute{
+        pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_AES),
+        pkcs11.NewAttribute(pkcs11.CKA_VALUE_LEN, 32),
+        pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, false),
+    }
+    p.GenerateKey(session, mech, template)
+}
+
+func seal(p *pkcs11.Ctx, session pkcs11.SessionHandle, key pkcs11.ObjectHandle, params []byte) {
+    p.EncryptInit(session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_GCM, params)}, key)
+}
+"#,
+        );
+        assert_eq!(found.len(), 2);
+        assert_eq!(
+            found[0].1,
+            Some(Pkcs11Operation {
+                mechanisms: vec!["CKM_AES_KEY_GEN".to_string()],
+                algorithm: Some("AES".to_string()),
+                attributes: BTreeMap::from([
+                    ("CKA_EXTRACTABLE".to_string(), "false".to_string()),
+                    ("CKA_KEY_TYPE".to_string(), "CKK_AES".to_string()),
+                    ("CKA_VALUE_LEN".to_string(), "32".to_string()),
+                ]),
+            })
+        );
+        let seal = found[1].1.as_ref().unwrap();
+        assert_eq!(seal.mechanisms, vec!["CKM_AES_GCM".to_string()]);
+        assert_eq!(seal.algorithm.as_deref(), Some("AES-GCM"));
+    }
+
+    #[test]
+    fn test_crypto11_secret_key_cipher() {
+        let found = pkcs11(
+            r#"
+package hsm
+
+import "github.com/ThalesIgnite/crypto11"
+
+func generate(ctx *crypto11.Context, id []byte) {
+    ctx.GenerateSecretKey(id, 256, crypto11.CipherAES)
+}
+"#,
+        );
+        assert_eq!(found.len(), 1);
+        assert_eq!(
+            found[0].1.as_ref().unwrap().algorithm.as_deref(),
+            Some("AES")
+        );
+    }
+}
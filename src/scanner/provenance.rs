@@ -0,0 +1,879 @@
+//! Provenance of byte arguments to Go crypto calls: KDF secrets and salts, cipher keys,
+//! and AEAD nonces.
+
+use serde::Serialize;
+use tree_sitter::Node;
+
+use super::receiver::{callee, find_declaration, go_receiver_type};
+use super::ImportMap;
+use crate::engine::Context;
+use crate::utils::unquote_string;
+
+/// KDF functions and the indexes of their secret (password or input key material) and
+/// salt arguments.
+const KDF_SINKS: &[(&str, usize, usize)] = &[
+    ("golang.org/x/crypto/pbkdf2.Key", 0, 1),
+    ("crypto/pbkdf2.Key", 1, 2),
+    ("golang.org/x/crypto/scrypt.Key", 0, 1),
+    ("golang.org/x/crypto/argon2.Key", 0, 1),
+    ("golang.org/x/crypto/argon2.IDKey", 0, 1),
+    ("golang.org/x/crypto/hkdf.New", 1, 2),
+    ("golang.org/x/crypto/hkdf.Extract", 1, 2),
+    ("crypto/hkdf.Key", 1, 2),
+    ("crypto/hkdf.Extract", 1, 2),
+];
+
+/// Cipher constructors, the index of their key argument and the key sizes in bytes they
+/// accept (empty: any).
+const KEY_SINKS: &[(&str, usize, &[usize])] = &[
+    ("crypto/aes.NewCipher", 0, &[16, 24, 32]),
+    ("crypto/des.NewCipher", 0, &[8]),
+    ("crypto/des.NewTripleDESCipher", 0, &[24]),
+    ("crypto/hmac.New", 1, &[]),
+    ("golang.org/x/crypto/chacha20poly1305.New", 0, &[32]),
+    ("golang.org/x/crypto/chacha20poly1305.NewX", 0, &[32]),
+];
+
+/// KDFs taking a random salt and its minimum length in bytes (NIST SP 800-132 for
+/// PBKDF2, and the same for the memory-hard KDFs).
+const SALT_MINIMUMS: &[(&str, usize)] = &[
+    ("golang.org/x/crypto/pbkdf2.Key", 16),
+    ("crypto/pbkdf2.Key", 16),
+    ("golang.org/x/crypto/scrypt.Key", 16),
+    ("golang.org/x/crypto/argon2.Key", 16),
+    ("golang.org/x/crypto/argon2.IDKey", 16),
+];
+
+/// AEAD methods and the index of their nonce argument.
+const NONCE_SINKS: &[(&str, usize)] = &[
+    ("crypto/cipher.AEAD.Seal", 1),
+    ("crypto/cipher.AEAD.Open", 1),
+];
+
+/// AEAD constructors and the nonce size in bytes of the AEAD they build.
+const NONCE_SIZES: &[(&str, usize)] = &[
+    ("crypto/cipher.NewGCM", 12),
+    ("crypto/cipher.NewGCMWithTagSize", 12),
+    ("golang.org/x/crypto/chacha20poly1305.New", 12),
+    ("golang.org/x/crypto/chacha20poly1305.NewX", 24),
+];
+
+/// Size constants of packages outside the scanned tree.
+const SIZE_CONSTANTS: &[(&str, usize)] = &[
+    ("crypto/aes.BlockSize", 16),
+    ("golang.org/x/crypto/chacha20poly1305.KeySize", 32),
+    ("golang.org/x/crypto/chacha20poly1305.NonceSize", 12),
+    ("golang.org/x/crypto/chacha20poly1305.NonceSizeX", 24),
+];
+
+/// Packages whose `DecodeString` is taken to read bytes back from storage.
+const DECODERS: &[&str] = &["encoding/hex", "encoding/base64", "encoding/base32"];
+
+const RAND_PACKAGE: &str = "crypto/rand";
+
+/// How many declarations an argument is followed through.
+const MAX_DEPTH: usize = 6;
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum ByteOrigin {
+    /// `nil`, `""` or a zero-length buffer.
+    Empty,
+    /// Bytes written in the source.
+    Literal,
+    /// A buffer that is allocated but never filled from `crypto/rand`.
+    Unfilled,
+    /// Read from `crypto/rand`.
+    Random,
+    /// A struct field or decoded value, assumed to hold persisted random bytes.
+    Stored,
+    /// A function parameter or the result of a call the trace does not follow.
+    Untraced,
+}
+
+/// Where the bytes of a salt or key argument come from.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct ByteSource {
+    pub origin: ByteOrigin,
+    /// Length in bytes the call receives, when known.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub length: Option<usize>,
+    /// Length of the buffer the argument was sliced from, when that differs.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub available: Option<usize>,
+    /// The constant or call the length was resolved from, e.g. `saltSize` or
+    /// `gcm.NonceSize()`; absent for literal lengths.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub length_from: Option<String>,
+    /// Length the primitive needs: 16 bytes for a PBKDF2, scrypt or Argon2 salt, the
+    /// AEAD's nonce size for a nonce.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub minimum: Option<usize>,
+    /// The expression the trace ended at.
+    pub expression: String,
+}
+
+/// Key sizes in bytes `function` (`import/path.Name`) accepts; empty means any size.
+pub fn key_sizes(function: &str) -> Option<&'static [usize]> {
+    KEY_SINKS
+        .iter()
+        .find(|(sink, _, _)| *sink == function)
+        .map(|(_, _, sizes)| *sizes)
+}
+
+/// Index of the secret argument of `function` (`import/path.Name`): a KDF's password or
+/// input key material, or a cipher constructor's key.
+pub fn secret_argument(function: &str) -> Option<usize> {
+    KDF_SINKS
+        .iter()
+        .find(|(sink, _, _)| *sink == function)
+        .map(|(_, secret, _)| *secret)
+        .or_else(|| {
+            KEY_SINKS
+                .iter()
+                .find(|(sink, _, _)| *sink == function)
+                .map(|(_, key, _)| *key)
+        })
+}
+
+/// Salt provenance for `call` if `function` under `import_path` is a known KDF.
+pub(super) fn go_salt_source<'a>(
+    call: &Node<'a>,
+    import_path: Option<&str>,
+    function: &str,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Option<ByteSource> {
+    let name = format!("{}.{function}", import_path?);
+    let (_, _, index) = KDF_SINKS.iter().find(|(sink, _, _)| *sink == name)?;
+    let salt = trace_argument(call, *index, ctx, imports)?;
+    Some(ByteSource {
+        minimum: SALT_MINIMUMS
+            .iter()
+            .find(|(sink, _)| *sink == name)
+            .map(|(_, minimum)| *minimum),
+        ..salt
+    })
+}
+
+/// Secret provenance for `call` if `function` under `import_path` is a known KDF.
+pub(super) fn go_secret_source<'a>(
+    call: &Node<'a>,
+    import_path: Option<&str>,
+    function: &str,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Option<ByteSource> {
+    let name = format!("{}.{function}", import_path?);
+    let (_, index, _) = KDF_SINKS.iter().find(|(sink, _, _)| *sink == name)?;
+    trace_argument(call, *index, ctx, imports)
+}
+
+/// Key provenance for `call` if `function` under `import_path` is a known cipher.
+pub(super) fn go_key_source<'a>(
+    call: &Node<'a>,
+    import_path: Option<&str>,
+    function: &str,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Option<ByteSource> {
+    let name = format!("{}.{function}", import_path?);
+    let (_, index, _) = KEY_SINKS.iter().find(|(sink, _, _)| *sink == name)?;
+    trace_argument(call, *index, ctx, imports)
+}
+
+/// Nonce provenance for `call` if `function` under `import_path` is an AEAD `Seal` or
+/// `Open`; `receiver` names the AEAD, whose constructor gives the nonce size.
+pub(super) fn go_nonce_source<'a>(
+    call: &Node<'a>,
+    import_path: Option<&str>,
+    function: &str,
+    receiver: Option<&str>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Option<ByteSource> {
+    let name = format!("{}.{function}", import_path?);
+    let (_, index) = NONCE_SINKS.iter().find(|(sink, _)| *sink == name)?;
+    let nonce = trace_argument(call, *index, ctx, imports)?;
+    Some(ByteSource {
+        minimum: receiver.and_then(|aead| nonce_size(*call, aead, ctx, imports)),
+        ..nonce
+    })
+}
+
+fn trace_argument<'a>(
+    call: &Node<'a>,
+    index: usize,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Option<ByteSource> {
+    let argument = call.child_by_field_name("arguments")?.named_child(index)?;
+    Some(trace(argument, call, ctx, imports, 0))
+}
+
+fn trace<'a>(
+    node: Node<'a>,
+    sink: &Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+    depth: usize,
+) -> ByteSource {
+    let source = |origin, length| ByteSource {
+        origin,
+        length,
+        available: None,
+        length_from: None,
+        minimum: None,
+        expression: ctx.get_node_text(&node),
+    };
+
+    match node.kind() {
+        "nil" => source(ByteOrigin::Empty, Some(0)),
+        "interpreted_string_literal" | "raw_string_literal" => {
+            let length = unquote_string(&ctx.get_node_text(&node)).len();
+            literal(source, length)
+        }
+        "composite_literal" => {
+            let length = node
+                .child_by_field_name("body")
+                .map_or(0, |body| body.named_child_count());
+            literal(source, length)
+        }
+        "slice_expression" => trace_slice(node, sink, ctx, imports, depth),
+        "parenthesized_expression" | "type_conversion_expression" => {
+            let inner = node
+                .child_by_field_name("operand")
+                .or_else(|| node.named_child(node.named_child_count().saturating_sub(1)));
+            match inner {
+                Some(inner) if inner != node => trace(inner, sink, ctx, imports, depth),
+                _ => source(ByteOrigin::Untraced, None),
+            }
+        }
+        // `x.Salt`: a field of a loaded record, unless `x` is a package; `config.Salt`
+        // is a literal if it resolves to a constant there
+        "selector_expression" => {
+            let operand = node
+                .child_by_field_name("operand")
+                .map(|o| ctx.get_node_text(&o))
+                .unwrap_or_default();
+            if imports.resolve(&operand).is_none() {
+                return source(ByteOrigin::Stored, None);
+            }
+            let constant = node
+                .child_by_field_name("field")
+                .and_then(|field| ctx.find_imported_constant(&operand, &ctx.get_node_text(&field)));
+            match constant.as_ref().and_then(|value| value.as_string()) {
+                Some(value) => literal(source, value.len()),
+                None => source(ByteOrigin::Untraced, None),
+            }
+        }
+        "call_expression" => trace_call(node, sink, ctx, imports, depth),
+        "identifier" if depth < MAX_DEPTH => trace_identifier(node, sink, ctx, imports, depth),
+        _ => source(ByteOrigin::Untraced, None),
+    }
+}
+
+fn literal(source: impl Fn(ByteOrigin, Option<usize>) -> ByteSource, length: usize) -> ByteSource {
+    let origin = if length == 0 {
+        ByteOrigin::Empty
+    } else {
+        ByteOrigin::Literal
+    };
+    source(origin, Some(length))
+}
+
+fn trace_call<'a>(
+    call: Node<'a>,
+    sink: &Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+    depth: usize,
+) -> ByteSource {
+    let source = |origin, length| ByteSource {
+        origin,
+        length,
+        available: None,
+        length_from: None,
+        minimum: None,
+        expression: ctx.get_node_text(&call),
+    };
+    let function = call.child_by_field_name("function");
+    let arguments = call.child_by_field_name("arguments");
+
+    // `[]byte(s)` parses as a call whose function is a type
+    if function.is_some_and(|f| f.kind() == "slice_type") {
+        if let Some(operand) = arguments.and_then(|args| args.named_child(0)) {
+            return trace(operand, sink, ctx, imports, depth);
+        }
+    }
+    // A bare `make([]byte, n)` is all zeros
+    if function.is_some_and(|f| ctx.get_node_text(&f) == "make") {
+        let size = arguments.and_then(|args| args.named_child(1));
+        let length = size.and_then(|size| resolve_length(size, ctx, imports, depth));
+        let traced = match length {
+            Some(0) => source(ByteOrigin::Empty, Some(0)),
+            length => source(ByteOrigin::Unfilled, length),
+        };
+        return ByteSource {
+            length_from: size.and_then(|size| length_from(size, length, ctx)),
+            ..traced
+        };
+    }
+
+    if callee(call, ctx, imports).is_some_and(|name| name.starts_with(&format!("{RAND_PACKAGE}.")))
+    {
+        return source(ByteOrigin::Random, None);
+    }
+    if function.is_some_and(|f| is_decode(f, ctx, imports)) {
+        return source(ByteOrigin::Stored, None);
+    }
+    // `newKey()`: a helper in the same file, followed through its last return
+    if let Some(returned) = function
+        .filter(|f| f.kind() == "identifier" && depth < MAX_DEPTH)
+        .and_then(|f| returned_value(call, &ctx.get_node_text(&f), ctx))
+    {
+        let statement = returned.parent().unwrap_or(returned);
+        return trace(returned, &statement, ctx, imports, depth + 1);
+    }
+    source(ByteOrigin::Untraced, None)
+}
+
+/// `key[:16]`: the sliced length, keeping the full buffer's length when they differ.
+fn trace_slice<'a>(
+    node: Node<'a>,
+    sink: &Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+    depth: usize,
+) -> ByteSource {
+    let Some(operand) = node.child_by_field_name("operand") else {
+        return ByteSource {
+            origin: ByteOrigin::Untraced,
+            length: None,
+            available: None,
+            length_from: None,
+            minimum: None,
+            expression: ctx.get_node_text(&node),
+        };
+    };
+    let buffer = trace(operand, sink, ctx, imports, depth);
+
+    // Bounds must be absent or resolvable; `key[:n]` of a parameter `n` has an unknown
+    // length
+    let bound = |field| match node.child_by_field_name(field) {
+        None => Some(None),
+        Some(bound) => resolve_length(bound, ctx, imports, depth).map(Some),
+    };
+    let (Some(start), Some(end)) = (bound("start"), bound("end")) else {
+        return ByteSource {
+            length: None,
+            length_from: None,
+            ..buffer
+        };
+    };
+    let length = end
+        .or(buffer.length)
+        .map(|end| end.saturating_sub(start.unwrap_or(0)));
+    let available = buffer
+        .available
+        .or(buffer.length)
+        .filter(|available| Some(*available) != length);
+    let length_from = match node.child_by_field_name("end") {
+        Some(bound) => length_from(bound, end, ctx),
+        None => buffer.length_from.clone(),
+    };
+    ByteSource {
+        length,
+        available,
+        length_from,
+        minimum: None,
+        expression: if available.is_some() {
+            ctx.get_node_text(&node)
+        } else {
+            buffer.expression
+        },
+        origin: buffer.origin,
+    }
+}
+
+/// First expression of the last `return` in the top-level function `name` of the file
+/// containing `node`.
+fn returned_value<'a>(node: Node<'a>, name: &str, ctx: &Context<'a>) -> Option<Node<'a>> {
+    let mut root = node;
+    while let Some(parent) = root.parent() {
+        root = parent;
+    }
+    let mut cursor = root.walk();
+    let function = root.children(&mut cursor).find(|child| {
+        child.kind() == "function_declaration"
+            && child
+                .child_by_field_name("name")
+                .is_some_and(|n| ctx.get_node_text(&n) == name)
+    })?;
+
+    let mut last: Option<Node<'a>> = None;
+    let mut stack = vec![function.child_by_field_name("body")?];
+    while let Some(current) = stack.pop() {
+        if current.kind() == "func_literal" {
+            continue;
+        }
+        if current.kind() == "return_statement"
+            && last.is_none_or(|l| current.start_byte() > l.start_byte())
+        {
+            last = Some(current);
+        }
+        let mut cursor = current.walk();
+        stack.extend(current.named_children(&mut cursor));
+    }
+    let values = last?.named_child(0)?;
+    match values.kind() {
+        "expression_list" => values.named_child(0),
+        _ => Some(values),
+    }
+}
+
+/// `hex.DecodeString` or `base64.StdEncoding.DecodeString`.
+fn is_decode<'a>(function: Node<'a>, ctx: &Context<'a>, imports: &ImportMap) -> bool {
+    if function.kind() != "selector_expression"
+        || function
+            .child_by_field_name("field")
+            .is_none_or(|f| ctx.get_node_text(&f) != "DecodeString")
+    {
+        return false;
+    }
+    let mut operand = function.child_by_field_name("operand");
+    while let Some(node) = operand.filter(|o| o.kind() == "selector_expression") {
+        operand = node.child_by_field_name("operand");
+    }
+    operand
+        .and_then(|o| imports.resolve(&ctx.get_node_text(&o)))
+        .is_some_and(|path| DECODERS.contains(&path.as_str()))
+}
+
+fn trace_identifier<'a>(
+    node: Node<'a>,
+    sink: &Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+    depth: usize,
+) -> ByteSource {
+    let name = ctx.get_node_text(&node);
+    let untraced = || ByteSource {
+        origin: ByteOrigin::Untraced,
+        length: None,
+        available: None,
+        length_from: None,
+        minimum: None,
+        expression: name.clone(),
+    };
+    let Some(declaration) = find_declaration(&node, &name, ctx) else {
+        // A string constant declared in another file of the package
+        return match ctx.find_cross_file_constant(&name) {
+            Some(value) if value.as_string().is_some() => ByteSource {
+                origin: ByteOrigin::Literal,
+                length: value.as_string().map(str::len),
+                available: None,
+                length_from: None,
+                minimum: None,
+                expression: name.clone(),
+            },
+            _ => untraced(),
+        };
+    };
+    if declaration.node.kind() == "parameter_declaration" {
+        return untraced();
+    }
+
+    let traced = match (declaration.type_node, declaration.value) {
+        // `var salt [16]byte`
+        (Some(type_node), _) => {
+            let size = type_node.child_by_field_name("length");
+            let length = size.and_then(|size| resolve_length(size, ctx, imports, depth));
+            ByteSource {
+                origin: ByteOrigin::Unfilled,
+                length,
+                available: None,
+                length_from: size.and_then(|size| length_from(size, length, ctx)),
+                minimum: None,
+                expression: name.clone(),
+            }
+        }
+        (None, Some(value)) => trace(value, sink, ctx, imports, depth + 1),
+        (None, None) => return untraced(),
+    };
+
+    // A buffer counts as random once `rand.Read` or `io.ReadFull(rand.Reader, ...)` fills
+    // it before the consuming call
+    if traced.origin == ByteOrigin::Unfilled
+        && filled_from_rand(&declaration.node, sink, &name, ctx, imports)
+    {
+        return ByteSource {
+            origin: ByteOrigin::Random,
+            ..traced
+        };
+    }
+    traced
+}
+
+/// Whether a call between `declaration` and `sink` fills `name` from `crypto/rand`.
+pub(super) fn filled_from_rand<'a>(
+    declaration: &Node<'a>,
+    sink: &Node<'a>,
+    name: &str,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> bool {
+    let targets = |arg: Option<Node<'a>>| {
+        arg.is_some_and(|arg| {
+            let text = ctx.get_node_text(&arg);
+            text == name || text.starts_with(&format!("{name}["))
+        })
+    };
+    let is_rand_reader = |arg: Option<Node<'a>>| {
+        arg.is_some_and(|arg| {
+            arg.kind() == "selector_expression"
+                && arg
+                    .child_by_field_name("operand")
+                    .and_then(|o| imports.resolve(&ctx.get_node_text(&o)))
+                    .is_some_and(|path| path == RAND_PACKAGE)
+        })
+    };
+
+    // Search the innermost node spanning both, skipping subtrees outside the gap
+    let mut scope = declaration.parent();
+    while let Some(node) = scope.filter(|n| n.end_byte() < sink.end_byte()) {
+        scope = node.parent();
+    }
+    let Some(scope) = scope else {
+        return false;
+    };
+
+    let mut stack = vec![scope];
+    while let Some(node) = stack.pop() {
+        if node.end_byte() <= declaration.end_byte() || node.start_byte() >= sink.start_byte() {
+            continue;
+        }
+        if node.kind() == "call_expression" && node.start_byte() >= declaration.end_byte() {
+            let args = node.child_by_field_name("arguments");
+            let arg = |i| args.and_then(|a| a.named_child(i));
+            match callee(node, ctx, imports).as_deref() {
+                Some("crypto/rand.Read") if targets(arg(0)) => return true,
+                Some("io.ReadFull") if is_rand_reader(arg(0)) && targets(arg(1)) => return true,
+                _ => {}
+            }
+        }
+        let mut cursor = node.walk();
+        stack.extend(node.named_children(&mut cursor));
+    }
+    false
+}
+
+/// A byte length written as an integer literal, a constant, a size constant such as
+/// `chacha20poly1305.NonceSizeX`, or the `NonceSize()` of an AEAD built in the file.
+fn resolve_length<'a>(
+    node: Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+    depth: usize,
+) -> Option<usize> {
+    match node.kind() {
+        "int_literal" => int_literal(node, ctx),
+        "parenthesized_expression" => resolve_length(node.named_child(0)?, ctx, imports, depth),
+        "identifier" if depth < MAX_DEPTH => {
+            let name = ctx.get_node_text(&node);
+            match find_declaration(&node, &name, ctx) {
+                Some(declaration) if declaration.node.kind() != "parameter_declaration" => {
+                    resolve_length(declaration.value?, ctx, imports, depth + 1)
+                }
+                Some(_) => None,
+                None => usize::try_from(ctx.find_cross_file_constant(&name)?.as_int()?).ok(),
+            }
+        }
+        "selector_expression" => {
+            let operand = ctx.get_node_text(&node.child_by_field_name("operand")?);
+            let field = ctx.get_node_text(&node.child_by_field_name("field")?);
+            let constant = format!("{}.{field}", imports.resolve(&operand)?);
+            match SIZE_CONSTANTS.iter().find(|(name, _)| *name == constant) {
+                Some((_, size)) => Some(*size),
+                None => {
+                    usize::try_from(ctx.find_imported_constant(&operand, &field)?.as_int()?).ok()
+                }
+            }
+        }
+        // `gcm.NonceSize()`
+        "call_expression" => {
+            let function = node.child_by_field_name("function")?;
+            let method = ctx.get_node_text(&function.child_by_field_name("field")?);
+            let aead = ctx.get_node_text(&function.child_by_field_name("operand")?);
+            (method == "NonceSize")
+                .then(|| nonce_size(node, &aead, ctx, imports))
+                .flatten()
+        }
+        _ => None,
+    }
+}
+
+/// The expression a resolved `length` came from, unless it is written as a literal.
+fn length_from<'a>(size: Node<'a>, length: Option<usize>, ctx: &Context<'a>) -> Option<String> {
+    (length.is_some() && size.kind() != "int_literal").then(|| ctx.get_node_text(&size))
+}
+
+/// Nonce size of the AEAD `aead`, when the constructor that built it is known.
+fn nonce_size<'a>(
+    node: Node<'a>,
+    aead: &str,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Option<usize> {
+    let construction = go_receiver_type(&node, aead, ctx, imports)?.construction?;
+    let constructor = callee(construction, ctx, imports)?;
+    NONCE_SIZES
+        .iter()
+        .find(|(name, _)| *name == constructor)
+        .map(|(_, size)| *size)
+}
+
+fn int_literal<'a>(node: Node<'a>, ctx: &Context<'a>) -> Option<usize> {
+    (node.kind() == "int_literal")
+        .then(|| ctx.get_node_text(&node).replace('_', "").parse().ok())
+        .flatten()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::scanner::Scanner;
+    use std::collections::HashMap;
+    use tree_sitter::Parser;
+
+    fn scan(body: &str) -> Vec<crate::scanner::Finding> {
+        let source = format!(
+            "package kdf\n\nimport (\n\t\"crypto/aes\"\n\t\"crypto/cipher\"\n\t\"crypto/rand\"\n\t\"encoding/hex\"\n\t\"io\"\n\n\t\"golang.org/x/crypto/chacha20poly1305\"\n\t\"golang.org/x/crypto/pbkdf2\"\n)\n\n{body}\n"
+        );
+        let mut parser = Parser::new();
+        parser
+            .set_language(&tree_sitter_go::LANGUAGE.into())
+            .unwrap();
+        let tree = parser.parse(&source, None).unwrap();
+        let mappings = HashMap::from([
+            (
+                "golang.org/x/crypto/pbkdf2".to_string(),
+                HashMap::from([("key".to_string(), "pbkdf2".to_string())]),
+            ),
+            (
+                "crypto/aes".to_string(),
+                HashMap::from([("newcipher".to_string(), "aes".to_string())]),
+            ),
+            (
+                "crypto/cipher.aead".to_string(),
+                HashMap::from([("seal".to_string(), "aead_seal".to_string())]),
+            ),
+        ]);
+        Scanner::with_mappings(mappings)
+            .scan_tree(&tree, source.as_bytes(), "kdf.go", "go")
+            .calls
+    }
+
+    fn salts(body: &str) -> Vec<(ByteOrigin, Option<usize>)> {
+        scan(body)
+            .iter()
+            .map(|call| {
+                let salt = call.salt.as_ref().unwrap();
+                (salt.origin, salt.length)
+            })
+            .collect()
+    }
+
+    #[test]
+    fn test_literal_and_empty_salts() {
+        let found = salts(
+            r#"var static = []byte("pepper")
+
+func derive(pw []byte) {
+	pbkdf2.Key(pw, static, 600000, 32, nil)
+	pbkdf2.Key(pw, nil, 600000, 32, nil)
+	pbkdf2.Key(pw, []byte{1, 2, 3, 4}, 600000, 32, nil)
+	pbkdf2.Key(pw, make([]byte, 16), 600000, 32, nil)
+}"#,
+        );
+        assert_eq!(
+            found,
+            vec![
+                (ByteOrigin::Literal, Some(6)),
+                (ByteOrigin::Empty, Some(0)),
+                (ByteOrigin::Literal, Some(4)),
+                (ByteOrigin::Unfilled, Some(16)),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_random_stored_and_untraced_salts() {
+        let found = salts(
+            r#"func derive(pw, given []byte, user User) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		panic(err)
+	}
+	pbkdf2.Key(pw, salt, 600000, 32, nil)
+
+	var short [8]byte
+	io.ReadFull(rand.Reader, short[:])
+	pbkdf2.Key(pw, short[:], 600000, 32, nil)
+
+	pbkdf2.Key(pw, user.Salt, 600000, 32, nil)
+	decoded, _ := hex.DecodeString(user.SaltHex)
+	pbkdf2.Key(pw, decoded, 600000, 32, nil)
+	pbkdf2.Key(pw, given, 600000, 32, nil)
+}"#,
+        );
+        assert_eq!(
+            found,
+            vec![
+                (ByteOrigin::Random, Some(16)),
+                (ByteOrigin::Random, Some(8)),
+                (ByteOrigin::Stored, None),
+                (ByteOrigin::Stored, None),
+                (ByteOrigin::Untraced, None),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_constant_secret_and_salt() {
+        let calls = scan(
+            r#"const masterPassword = "correct horse"
+
+func derive(user User) {
+	const pepper = "pepper"
+	pbkdf2.Key([]byte(masterPassword), []byte(pepper), 600000, 32, nil)
+	pbkdf2.Key(user.Password, []byte(pepper), 600000, 32, nil)
+}"#,
+        );
+        let inputs: Vec<_> = calls
+            .iter()
+            .map(|call| {
+                let secret = call.secret.as_ref().unwrap();
+                let salt = call.salt.as_ref().unwrap();
+                (secret.origin, secret.length, salt.origin, salt.length)
+            })
+            .collect();
+        assert_eq!(
+            inputs,
+            vec![
+                (ByteOrigin::Literal, Some(13), ByteOrigin::Literal, Some(6)),
+                (ByteOrigin::Stored, None, ByteOrigin::Literal, Some(6)),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_lengths_resolve_through_constants() {
+        let found: Vec<_> = scan(
+            r#"const saltSize = 8
+
+func derive(pw []byte) {
+	salt := make([]byte, saltSize)
+	rand.Read(salt)
+	pbkdf2.Key(pw, salt, 600000, 32, nil)
+}"#,
+        )
+        .into_iter()
+        .map(|call| call.salt.unwrap())
+        .collect();
+        assert_eq!(
+            found,
+            vec![ByteSource {
+                origin: ByteOrigin::Random,
+                length: Some(8),
+                available: None,
+                length_from: Some("saltSize".to_string()),
+                minimum: Some(16),
+                expression: "make([]byte, saltSize)".to_string(),
+            }]
+        );
+    }
+
+    #[test]
+    fn test_nonce_minimum_from_aead_constructor() {
+        let nonces: Vec<_> = scan(
+            r#"func seal(key, msg []byte) {
+	block, _ := aes.NewCipher(key)
+	gcm, _ := cipher.NewGCM(block)
+	nonce := make([]byte, gcm.NonceSize())
+	io.ReadFull(rand.Reader, nonce)
+	gcm.Seal(nil, nonce, msg, nil)
+
+	x, _ := chacha20poly1305.NewX(key)
+	short := make([]byte, chacha20poly1305.NonceSize)
+	rand.Read(short)
+	x.Seal(nil, short, msg, nil)
+	x.Seal(nil, []byte("fixed-nonce-value-123456"), msg, nil)
+}"#,
+        )
+        .into_iter()
+        .filter_map(|call| call.nonce)
+        .map(|nonce| (nonce.origin, nonce.length, nonce.length_from, nonce.minimum))
+        .collect();
+        assert_eq!(
+            nonces,
+            vec![
+                (
+                    ByteOrigin::Random,
+                    Some(12),
+                    Some("gcm.NonceSize()".to_string()),
+                    Some(12)
+                ),
+                (
+                    ByteOrigin::Random,
+                    Some(12),
+                    Some("chacha20poly1305.NonceSize".to_string()),
+                    Some(24)
+                ),
+                (ByteOrigin::Literal, Some(24), None, Some(24)),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_key_truncation_through_helper() {
+        let calls = scan(
+            r#"func GenerateJOSEKey() []byte {
+	k := make([]byte, 32)
+	if _, err := rand.Read(k); err != nil {
+		panic(err)
+	}
+	return k
+}
+
+func seal() {
+	key := GenerateJOSEKey()
+	aes.NewCipher(key[:16])
+	aes.NewCipher(key)
+}"#,
+        );
+        let keys: Vec<_> = calls.iter().map(|call| call.key.clone().unwrap()).collect();
+        assert_eq!(
+            keys,
+            vec![
+                ByteSource {
+                    origin: ByteOrigin::Random,
+                    length: Some(16),
+                    available: Some(32),
+                    length_from: None,
+                    minimum: None,
+                    expression: "key[:16]".to_string(),
+                },
+                ByteSource {
+                    origin: ByteOrigin::Random,
+                    length: Some(32),
+                    available: None,
+                    length_from: None,
+                    minimum: None,
+                    expression: "make([]byte, 32)".to_string(),
+                },
+            ]
+        );
+        assert_eq!(key_sizes("crypto/aes.NewCipher"), Some(&[16, 24, 32][..]));
+    }
+}
@@ -0,0 +1,409 @@
+//! Receiver types for Go method calls, recovered from the declaration of the operand.
+
+use tree_sitter::Node;
+
+use super::ImportMap;
+use crate::engine::Context;
+
+const FUNCTION_KINDS: &[&str] = &["function_declaration", "method_declaration", "func_literal"];
+
+/// Functions whose first result is worth tracking as a receiver but is not named by the
+/// `NewFoo` convention, e.g. an interface or a `New` returning the package's main type.
+const CONSTRUCTORS: &[(&str, &str)] = &[
+    ("crypto/cipher.NewGCM", "crypto/cipher.AEAD"),
+    ("crypto/cipher.NewGCMWithNonceSize", "crypto/cipher.AEAD"),
+    ("crypto/cipher.NewGCMWithTagSize", "crypto/cipher.AEAD"),
+    ("crypto/cipher.NewGCMWithRandomNonce", "crypto/cipher.AEAD"),
+    (
+        "golang.org/x/crypto/chacha20poly1305.New",
+        "crypto/cipher.AEAD",
+    ),
+    (
+        "golang.org/x/crypto/chacha20poly1305.NewX",
+        "crypto/cipher.AEAD",
+    ),
+    (
+        "github.com/aws/aws-sdk-go-v2/service/kms.NewFromConfig",
+        "github.com/aws/aws-sdk-go-v2/service/kms.Client",
+    ),
+    (
+        "github.com/aws/aws-sdk-go/service/kms.New",
+        "github.com/aws/aws-sdk-go/service/kms.KMS",
+    ),
+    ("github.com/miekg/pkcs11.New", "github.com/miekg/pkcs11.Ctx"),
+    (
+        "github.com/ThalesIgnite/crypto11.Configure",
+        "github.com/ThalesIgnite/crypto11.Context",
+    ),
+    (
+        "github.com/ThalesIgnite/crypto11.ConfigureFromFile",
+        "github.com/ThalesIgnite/crypto11.Context",
+    ),
+    (
+        "github.com/go-chi/chi/v5.NewRouter",
+        "github.com/go-chi/chi/v5.Mux",
+    ),
+    (
+        "github.com/gin-gonic/gin.Default",
+        "github.com/gin-gonic/gin.Engine",
+    ),
+    (
+        "github.com/gin-gonic/gin.New",
+        "github.com/gin-gonic/gin.Engine",
+    ),
+    (
+        "github.com/robfig/cron/v3.New",
+        "github.com/robfig/cron/v3.Cron",
+    ),
+    ("github.com/robfig/cron.New", "github.com/robfig/cron.Cron"),
+    (
+        "golang.org/x/sync/errgroup.WithContext",
+        "golang.org/x/sync/errgroup.Group",
+    ),
+    (
+        "github.com/alitto/pond.New",
+        "github.com/alitto/pond.WorkerPool",
+    ),
+];
+
+/// Methods returning a copy of their receiver, e.g. `(*tls.Config).Clone`.
+const COPY_METHODS: &[&str] = &["Clone"];
+
+/// How far back a constructor chain is followed through first arguments.
+const MAX_CHAIN: usize = 4;
+
+/// A receiver's resolved type and, when it came from a constructor, the calls that built
+/// it, outermost last (`aes.NewCipher(key)`, `cipher.NewGCM(block)`).
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub(super) struct Receiver<'a> {
+    pub type_name: String,
+    pub chain: Vec<String>,
+    /// The constructor call or composite literal the receiver was built by, if any.
+    pub construction: Option<Node<'a>>,
+}
+
+/// What declares a variable: its explicit type, or the expression assigned to it.
+pub(super) struct Declaration<'a> {
+    /// The declaring node, e.g. a `short_var_declaration` or `parameter_declaration`.
+    pub node: Node<'a>,
+    pub type_node: Option<Node<'a>>,
+    pub value: Option<Node<'a>>,
+}
+
+/// The `import/path.Type` of the variable `name` used as a receiver in `call`.
+pub(super) fn go_receiver_type<'a>(
+    call: &Node<'a>,
+    name: &str,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Option<Receiver<'a>> {
+    receiver_type(call, name, ctx, imports, 0)
+}
+
+fn receiver_type<'a>(
+    call: &Node<'a>,
+    name: &str,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+    depth: usize,
+) -> Option<Receiver<'a>> {
+    if let Some((operand, field)) = name.rsplit_once('.') {
+        if operand.is_empty()
+            || !name
+                .chars()
+                .all(|c| c.is_alphanumeric() || c == '_' || c == '.')
+        {
+            return None;
+        }
+        return Some(Receiver {
+            type_name: field_type(*call, field, ctx, imports)?,
+            chain: Vec::new(),
+            construction: None,
+        });
+    }
+    let declaration = find_declaration(call, name, ctx)?;
+    if let Some(type_node) = declaration.type_node {
+        return Some(Receiver {
+            type_name: type_name(type_node, ctx, imports)?,
+            chain: Vec::new(),
+            construction: None,
+        });
+    }
+
+    let value = declaration.value?;
+    if let Some((type_name, literal)) = literal_type(value, ctx, imports) {
+        return Some(Receiver {
+            type_name,
+            chain: Vec::new(),
+            construction: Some(literal),
+        });
+    }
+    // `cfg := base.Clone()`: the copy has the type and construction of `base`
+    if let Some(operand) = copied_receiver(value, ctx, imports) {
+        if depth >= MAX_CHAIN {
+            return None;
+        }
+        let mut receiver = receiver_type(&value, &operand, ctx, imports, depth + 1)?;
+        receiver.chain.push(ctx.get_node_text(&value));
+        return Some(receiver);
+    }
+
+    // `aead := defaultAEAD`: an alias has the type of what it names
+    if matches!(value.kind(), "identifier" | "selector_expression") {
+        if depth >= MAX_CHAIN {
+            return None;
+        }
+        return receiver_type(&value, &ctx.get_node_text(&value), ctx, imports, depth + 1);
+    }
+
+    let constructor = callee(value, ctx, imports)?;
+    let type_name = match CONSTRUCTORS.iter().find(|(name, _)| *name == constructor) {
+        Some((_, type_name)) => type_name.to_string(),
+        None => constructed_type(&constructor)?,
+    };
+    Some(Receiver {
+        type_name,
+        chain: construction_chain(value, ctx),
+        construction: Some(value),
+    })
+}
+
+/// `import/path.Foo` for a `import/path.NewFoo` constructor.
+fn constructed_type(constructor: &str) -> Option<String> {
+    let (package, function) = constructor.rsplit_once('.')?;
+    let type_name = function.strip_prefix("New")?;
+    type_name
+        .starts_with(|c: char| c.is_ascii_uppercase())
+        .then(|| format!("{package}.{type_name}"))
+}
+
+/// The receiver variable of a copy method call such as `base.Clone()`.
+fn copied_receiver<'a>(value: Node<'a>, ctx: &Context<'a>, imports: &ImportMap) -> Option<String> {
+    if value.kind() != "call_expression" {
+        return None;
+    }
+    let function = value.child_by_field_name("function")?;
+    if function.kind() != "selector_expression" {
+        return None;
+    }
+    let method = ctx.get_node_text(&function.child_by_field_name("field")?);
+    let operand = function.child_by_field_name("operand")?;
+    let operand_name = ctx.get_node_text(&operand);
+    (COPY_METHODS.contains(&method.as_str())
+        && operand.kind() == "identifier"
+        && imports.resolve(&operand_name).is_none())
+    .then_some(operand_name)
+}
+
+/// Innermost declaration of `name` visible at `node`: enclosing functions outward, then
+/// package-level `var` and `const` declarations.
+pub(super) fn find_declaration<'a>(
+    node: &Node<'a>,
+    name: &str,
+    ctx: &Context<'a>,
+) -> Option<Declaration<'a>> {
+    if !name.chars().all(|c| c.is_alphanumeric() || c == '_') {
+        return None;
+    }
+
+    let mut root = *node;
+    let mut scope = node.parent();
+    while let Some(current) = scope {
+        if FUNCTION_KINDS.contains(&current.kind()) {
+            if let Some(found) = last_declaration(current, name, node.start_byte(), ctx) {
+                return Some(found);
+            }
+        }
+        root = current;
+        scope = current.parent();
+    }
+
+    let mut cursor = root.walk();
+    let found = root
+        .children(&mut cursor)
+        .filter(|child| matches!(child.kind(), "var_declaration" | "const_declaration"))
+        .find_map(|decl| last_declaration(decl, name, usize::MAX, ctx));
+    found
+}
+
+/// The last declaration of `name` under `node` that starts before `before`. Nested
+/// function literals are skipped; their declarations are not in scope.
+fn last_declaration<'a>(
+    node: Node<'a>,
+    name: &str,
+    before: usize,
+    ctx: &Context<'a>,
+) -> Option<Declaration<'a>> {
+    let mut found: Option<(usize, Declaration<'a>)> = None;
+    let mut stack = vec![node];
+    let mut visited_root = false;
+
+    while let Some(current) = stack.pop() {
+        if current.start_byte() >= before {
+            continue;
+        }
+        if visited_root && current.kind() == "func_literal" {
+            continue;
+        }
+        visited_root = true;
+
+        if let Some(declaration) = declaration(current, name, ctx) {
+            let position = current.start_byte();
+            if found.as_ref().is_none_or(|(p, _)| position >= *p) {
+                found = Some((position, declaration));
+            }
+        }
+
+        let mut cursor = current.walk();
+        stack.extend(current.named_children(&mut cursor));
+    }
+    found.map(|(_, declaration)| declaration)
+}
+
+fn declaration<'a>(node: Node<'a>, name: &str, ctx: &Context<'a>) -> Option<Declaration<'a>> {
+    let (index, values) = match node.kind() {
+        // func f(srv *http.Server) / var srv http.Server / var srv = &http.Server{}
+        "parameter_declaration" | "var_spec" => {
+            let mut cursor = node.walk();
+            let index = node
+                .children_by_field_name("name", &mut cursor)
+                .position(|n| ctx.get_node_text(&n) == name)?;
+            if let Some(type_node) = node.child_by_field_name("type") {
+                return Some(Declaration {
+                    node,
+                    type_node: Some(type_node),
+                    value: None,
+                });
+            }
+            (index, node.child_by_field_name("value")?)
+        }
+        // const salt = "pepper": the type of a constant says nothing about its bytes
+        "const_spec" => {
+            let mut cursor = node.walk();
+            let index = node
+                .children_by_field_name("name", &mut cursor)
+                .position(|n| ctx.get_node_text(&n) == name)?;
+            (index, node.child_by_field_name("value")?)
+        }
+        // srv := &http.Server{...} / aead, err := cipher.NewGCM(block)
+        "short_var_declaration" | "assignment_statement" => {
+            let left = node.child_by_field_name("left")?;
+            let mut cursor = left.walk();
+            let index = left
+                .named_children(&mut cursor)
+                .position(|n| n.kind() == "identifier" && ctx.get_node_text(&n) == name)?;
+            (index, node.child_by_field_name("right")?)
+        }
+        _ => return None,
+    };
+
+    // In `a, err := f()` only `a` lines up with a value; the call's first result is `a`
+    let value = values.named_child(index)?;
+    Some(Declaration {
+        node,
+        type_node: None,
+        value: Some(value),
+    })
+}
+
+/// Type of a composite literal, optionally behind `&`, and the literal itself.
+fn literal_type<'a>(
+    value: Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Option<(String, Node<'a>)> {
+    let literal = match value.kind() {
+        "unary_expression" => value.child_by_field_name("operand")?,
+        _ => value,
+    };
+    if literal.kind() != "composite_literal" {
+        return None;
+    }
+    let type_name = type_name(literal.child_by_field_name("type")?, ctx, imports)?;
+    Some((type_name, literal))
+}
+
+fn type_name<'a>(node: Node<'a>, ctx: &Context<'a>, imports: &ImportMap) -> Option<String> {
+    match node.kind() {
+        "pointer_type" => type_name(node.named_child(0)?, ctx, imports),
+        "qualified_type" => {
+            let package = ctx.get_node_text(&node.child_by_field_name("package")?);
+            let name = ctx.get_node_text(&node.child_by_field_name("name")?);
+            Some(format!("{}.{name}", imports.resolve(&package)?))
+        }
+        _ => None,
+    }
+}
+
+/// The type a struct type in the file of `node` declares its field `field` with.
+fn field_type<'a>(
+    node: Node<'a>,
+    field: &str,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Option<String> {
+    let mut root = node;
+    while let Some(parent) = root.parent() {
+        root = parent;
+    }
+    let mut stack = vec![root];
+    while let Some(current) = stack.pop() {
+        if current.kind() == "field_declaration" {
+            let mut cursor = current.walk();
+            let named = current
+                .children_by_field_name("name", &mut cursor)
+                .any(|name| ctx.get_node_text(&name) == field);
+            if let Some(type_name) = current
+                .child_by_field_name("type")
+                .filter(|_| named)
+                .and_then(|type_node| type_name(type_node, ctx, imports))
+            {
+                return Some(type_name);
+            }
+            continue;
+        }
+        let mut cursor = current.walk();
+        stack.extend(current.named_children(&mut cursor));
+    }
+    None
+}
+
+/// `import/path.Function` of a call to a package-level function.
+pub(super) fn callee<'a>(call: Node<'a>, ctx: &Context<'a>, imports: &ImportMap) -> Option<String> {
+    if call.kind() != "call_expression" {
+        return None;
+    }
+    let function = call.child_by_field_name("function")?;
+    if function.kind() != "selector_expression" {
+        return None;
+    }
+    let package = ctx.get_node_text(&function.child_by_field_name("operand")?);
+    let name = ctx.get_node_text(&function.child_by_field_name("field")?);
+    Some(format!("{}.{name}", imports.resolve(&package)?))
+}
+
+/// Source text of `call` and of the calls that produced its first argument, followed
+/// back through local assignments: `aes.NewCipher(key)`, then `cipher.NewGCM(block)`.
+fn construction_chain<'a>(call: Node<'a>, ctx: &Context<'a>) -> Vec<String> {
+    let mut chain = vec![ctx.get_node_text(&call)];
+    let mut current = call;
+    while chain.len() < MAX_CHAIN {
+        let Some(argument) = current
+            .child_by_field_name("arguments")
+            .and_then(|args| args.named_child(0))
+            .filter(|arg| arg.kind() == "identifier")
+        else {
+            break;
+        };
+        let Some(value) = find_declaration(&current, &ctx.get_node_text(&argument), ctx)
+            .and_then(|declaration| declaration.value)
+            .filter(|value| value.kind() == "call_expression")
+        else {
+            break;
+        };
+        chain.push(ctx.get_node_text(&value));
+        current = value;
+    }
+    chain.reverse();
+    chain
+}
@@ -0,0 +1,152 @@
+//! Algorithm selection through registry functions that switch on a parameter.
+
+use serde::Serialize;
+use tree_sitter::Node;
+
+use super::receiver::find_declaration;
+use crate::engine::Context;
+use crate::utils::unquote_string;
+
+/// Case label used for the `default` clause.
+pub const DEFAULT_CASE: &str = "default";
+
+/// The dispatcher case that returns a crypto call.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct Selection {
+    /// The dispatcher, e.g. `GetHasher`.
+    pub function: String,
+    /// The parameter switched on, e.g. `algorithm`.
+    pub selector: String,
+    /// Labels of the case returning this call; `default` for the default clause.
+    pub cases: Vec<String>,
+}
+
+/// The selection `call` belongs to if it is returned from a `switch` on a parameter of
+/// the enclosing function.
+pub(super) fn go_selection<'a>(call: &Node<'a>, ctx: &Context<'a>) -> Option<Selection> {
+    let values = call.parent().filter(|p| p.kind() == "expression_list")?;
+    let statement = values.parent().filter(|p| p.kind() == "return_statement")?;
+    if values.named_child(0)?.id() != call.id() {
+        return None;
+    }
+
+    let clause = statement.parent()?;
+    let cases = match clause.kind() {
+        "expression_case" => {
+            let labels = clause.child_by_field_name("value")?;
+            let mut cursor = labels.walk();
+            labels
+                .named_children(&mut cursor)
+                .map(|label| unquote_string(&ctx.get_node_text(&label)))
+                .collect()
+        }
+        "default_case" => vec![DEFAULT_CASE.to_string()],
+        _ => return None,
+    };
+
+    let switch = clause
+        .parent()
+        .filter(|p| p.kind() == "expression_switch_statement")?;
+    let selector = switch
+        .child_by_field_name("value")
+        .filter(|v| v.kind() == "identifier")?;
+    let selector = ctx.get_node_text(&selector);
+    let declaration = find_declaration(&switch, &selector, ctx)?;
+    if declaration.node.kind() != "parameter_declaration" {
+        return None;
+    }
+
+    let function = declaration
+        .node
+        .parent()
+        .and_then(|parameters| parameters.parent())
+        .and_then(|function| function.child_by_field_name("name"))?;
+    Some(Selection {
+        function: ctx.get_node_text(&function),
+        selector,
+        cases,
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::scanner::Scanner;
+    use std::collections::HashMap;
+    use tree_sitter::Parser;
+
+    fn selections(body: &str) -> Vec<Option<Selection>> {
+        let source = format!(
+            "package hash\n\nimport (\n\t\"crypto/sha256\"\n\t\"crypto/sha512\"\n\t\"hash\"\n)\n\n{body}\n"
+        );
+        let mut parser = Parser::new();
+        parser
+            .set_language(&tree_sitter_go::LANGUAGE.into())
+            .unwrap();
+        let tree = parser.parse(&source, None).unwrap();
+        let mappings = HashMap::from([
+            (
+                "crypto/sha256".to_string(),
+                HashMap::from([("new".to_string(), "sha256".to_string())]),
+            ),
+            (
+                "crypto/sha512".to_string(),
+                HashMap::from([("new".to_string(), "sha512".to_string())]),
+            ),
+        ]);
+        Scanner::with_mappings(mappings)
+            .scan_tree(&tree, source.as_bytes(), "sha.go", "go")
+            .calls
+            .into_iter()
+            .map(|call| call.selection)
+            .collect()
+    }
+
+    #[test]
+    fn test_switch_on_parameter() {
+        let found = selections(
+            r#"func GetHasher(algorithm string) hash.Hash {
+	switch algorithm {
+	case "sha256", "SHA-256":
+		return sha256.New()
+	case "sha512":
+		return sha512.New()
+	default:
+		return sha256.New()
+	}
+}"#,
+        );
+        let cases: Vec<_> = found
+            .iter()
+            .map(|selection| {
+                let selection = selection.as_ref().unwrap();
+                assert_eq!(selection.function, "GetHasher");
+                assert_eq!(selection.selector, "algorithm");
+                selection.cases.clone()
+            })
+            .collect();
+        assert_eq!(
+            cases,
+            vec![
+                vec!["sha256".to_string(), "SHA-256".to_string()],
+                vec!["sha512".to_string()],
+                vec!["default".to_string()],
+            ]
+        );
+    }
+
+    #[test]
+    fn test_switch_on_local_is_not_a_registry() {
+        let found = selections(
+            r#"func Hasher() hash.Hash {
+	algorithm := "sha512"
+	switch algorithm {
+	case "sha512":
+		return sha512.New()
+	}
+	return sha256.New()
+}"#,
+        );
+        assert_eq!(found, vec![None, None]);
+    }
+}
@@ -0,0 +1,399 @@
+//! KDF work factors tuned from wall-clock measurements without a floor.
+
+use serde::Serialize;
+use tree_sitter::Node;
+
+use super::receiver::callee;
+use super::ImportMap;
+use crate::engine::Context;
+
+const FUNCTION_KINDS: &[&str] = &["function_declaration", "method_declaration", "func_literal"];
+
+/// KDFs and the index of their work factor: iterations, scrypt's N, argon2's time, or
+/// bcrypt's cost.
+const WORK_FACTORS: &[(&str, usize)] = &[
+    ("golang.org/x/crypto/pbkdf2.Key", 2),
+    ("crypto/pbkdf2.Key", 3),
+    ("golang.org/x/crypto/scrypt.Key", 2),
+    ("golang.org/x/crypto/argon2.Key", 2),
+    ("golang.org/x/crypto/argon2.IDKey", 2),
+    ("golang.org/x/crypto/bcrypt.GenerateFromPassword", 1),
+];
+
+const CLOCKS: &[&str] = &["time.Now", "time.Since", "time.Until"];
+
+const TUNING_OPERATORS: &[&str] = &["=", "*=", "+=", "<<=", "-=", "/=", ">>="];
+
+/// A KDF work factor chosen by timing derivations on the running machine.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct IterationTuning {
+    /// The tuned variable, e.g. `iterations`.
+    pub variable: String,
+    /// The clock call the tuning measures with, e.g. `time.Since`.
+    pub clock: String,
+    /// The minimum the tuned value is raised to, as written, e.g. `600000`.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub floor: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub floor_line: Option<usize>,
+}
+
+/// How the work factor of `call` is tuned, if `function` under `import_path` is a KDF
+/// called from a function that calibrates it against the clock.
+pub(super) fn go_iteration_tuning<'a>(
+    call: &Node<'a>,
+    import_path: Option<&str>,
+    function: &str,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Option<IterationTuning> {
+    let name = format!("{}.{function}", import_path?);
+    let (_, index) = WORK_FACTORS.iter().find(|(sink, _)| *sink == name)?;
+    let work_factor = call.child_by_field_name("arguments")?.named_child(*index)?;
+    let enclosing = enclosing_function(*call)?;
+
+    let clock = clock_call(enclosing, ctx, imports)?;
+    let measured = measured_variables(enclosing, ctx, imports);
+    let key = variable_key(unconvert(work_factor), ctx);
+    let variable = if timed_loop_changes(enclosing, &key, &measured, ctx, imports) {
+        ctx.get_node_text(&unconvert(work_factor))
+    } else {
+        scaled_variable(enclosing, &measured, ctx, imports)?
+    };
+
+    let variable_key = variable.rsplit('.').next().unwrap_or(&variable).to_string();
+    let floor = floor(enclosing, &variable_key, ctx)
+        .or_else(|| floor(file_root(enclosing), &variable_key, ctx));
+    Some(IterationTuning {
+        variable,
+        clock,
+        floor: floor.map(|node| ctx.get_node_text(&node)),
+        floor_line: floor.map(|node| node.start_position().row + 1),
+    })
+}
+
+/// The first clock call in `function`.
+fn clock_call<'a>(function: Node<'a>, ctx: &Context<'a>, imports: &ImportMap) -> Option<String> {
+    let mut found = None;
+    walk(function, &mut |node| {
+        if found.is_none() && is_clock(node, ctx, imports) {
+            found = callee(node, ctx, imports);
+        }
+    });
+    found
+}
+
+fn is_clock<'a>(node: Node<'a>, ctx: &Context<'a>, imports: &ImportMap) -> bool {
+    callee(node, ctx, imports).is_some_and(|name| CLOCKS.contains(&name.as_str()))
+}
+
+/// Variables assigned from the clock: `start := time.Now()`, `elapsed := time.Since(start)`.
+fn measured_variables<'a>(
+    function: Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Vec<String> {
+    let mut measured = Vec::new();
+    walk(function, &mut |node| {
+        if !matches!(
+            node.kind(),
+            "short_var_declaration" | "assignment_statement"
+        ) {
+            return;
+        }
+        let Some(right) = node.child_by_field_name("right") else {
+            return;
+        };
+        if contains(right, &mut |n| is_clock(n, ctx, imports)) {
+            if let Some(left) = node.child_by_field_name("left") {
+                measured.extend(
+                    ctx.get_named_children(&left)
+                        .iter()
+                        .map(|name| variable_key(*name, ctx)),
+                );
+            }
+        }
+    });
+    measured
+}
+
+/// Whether `key` is changed inside a loop that reads the clock or a measured duration.
+fn timed_loop_changes<'a>(
+    function: Node<'a>,
+    key: &str,
+    measured: &[String],
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> bool {
+    let mut found = false;
+    walk(function, &mut |node| {
+        if found || node.kind() != "for_statement" {
+            return;
+        }
+        let timed = contains(node, &mut |n| {
+            is_clock(n, ctx, imports)
+                || n.kind() == "identifier" && measured.contains(&ctx.get_node_text(&n))
+        });
+        found = timed && contains(node, &mut |n| assigns(n, key, ctx));
+    });
+    found
+}
+
+/// A variable assigned from an expression of a measured duration, e.g.
+/// `n := probe * int(target / elapsed)`.
+fn scaled_variable<'a>(
+    function: Node<'a>,
+    measured: &[String],
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Option<String> {
+    let mut found = None;
+    walk(function, &mut |node| {
+        if found.is_some()
+            || !matches!(
+                node.kind(),
+                "short_var_declaration" | "assignment_statement"
+            )
+        {
+            return;
+        }
+        let (Some(left), Some(right)) = (
+            node.child_by_field_name("left"),
+            node.child_by_field_name("right"),
+        ) else {
+            return;
+        };
+        // The measurement itself is not a tuned value
+        if contains(right, &mut |n| is_clock(n, ctx, imports)) {
+            return;
+        }
+        let scaled = contains(right, &mut |n| {
+            n.kind() == "identifier" && measured.contains(&ctx.get_node_text(&n))
+        });
+        if scaled {
+            found = left
+                .named_child(0)
+                .map(|variable| ctx.get_node_text(&variable));
+        }
+    });
+    found
+}
+
+/// Whether `node` changes `key`: `key *= 2`, `key = key << 1`, `key++`.
+fn assigns<'a>(node: Node<'a>, key: &str, ctx: &Context<'a>) -> bool {
+    match node.kind() {
+        "inc_statement" => node
+            .named_child(0)
+            .is_some_and(|operand| variable_key(operand, ctx) == key),
+        "assignment_statement" => {
+            ctx.get_field_text(&node, "operator")
+                .is_some_and(|op| TUNING_OPERATORS.contains(&op.as_str()))
+                && node
+                    .child_by_field_name("left")
+                    .and_then(|left| left.named_child(0))
+                    .is_some_and(|left| variable_key(left, ctx) == key)
+        }
+        _ => false,
+    }
+}
+
+/// The minimum `key` is raised to under `root`: `min` in `if key < min { key = min }` or
+/// in `key = max(key, min)`.
+fn floor<'a>(root: Node<'a>, key: &str, ctx: &Context<'a>) -> Option<Node<'a>> {
+    let mut found = None;
+    walk(root, &mut |node| {
+        if found.is_some() {
+            return;
+        }
+        found = match node.kind() {
+            "if_statement" => if_floor(node, key, ctx),
+            "assignment_statement" => max_floor(node, key, ctx),
+            _ => None,
+        };
+    });
+    found
+}
+
+fn if_floor<'a>(node: Node<'a>, key: &str, ctx: &Context<'a>) -> Option<Node<'a>> {
+    let condition = node.child_by_field_name("condition")?;
+    if condition.kind() != "binary_expression" {
+        return None;
+    }
+    let operator = ctx.get_field_text(&condition, "operator")?;
+    let (left, right) = (
+        condition.child_by_field_name("left")?,
+        condition.child_by_field_name("right")?,
+    );
+    let is_key = |side: Node<'a>| variable_key(unconvert(side), ctx) == key;
+    let minimum = match operator.as_str() {
+        "<" | "<=" if is_key(left) => right,
+        ">" | ">=" if is_key(right) => left,
+        _ => return None,
+    };
+    let consequence = node.child_by_field_name("consequence")?;
+    contains(consequence, &mut |n| assigns(n, key, ctx)).then_some(minimum)
+}
+
+fn max_floor<'a>(node: Node<'a>, key: &str, ctx: &Context<'a>) -> Option<Node<'a>> {
+    if ctx.get_field_text(&node, "operator").as_deref() != Some("=") {
+        return None;
+    }
+    let left = node.child_by_field_name("left")?.named_child(0)?;
+    let value = node.child_by_field_name("right")?.named_child(0)?;
+    if variable_key(left, ctx) != key || value.kind() != "call_expression" {
+        return None;
+    }
+    let function = value.child_by_field_name("function")?;
+    if ctx.get_node_text(&function) != "max" {
+        return None;
+    }
+    let args = ctx.get_named_children(&value.child_by_field_name("arguments")?);
+    match args.as_slice() {
+        [a, b] if variable_key(unconvert(*a), ctx) == key => Some(*b),
+        [a, b] if variable_key(unconvert(*b), ctx) == key => Some(*a),
+        _ => None,
+    }
+}
+
+/// `c.iterations` and `cfg.iterations` are the same field; an identifier is itself.
+fn variable_key<'a>(node: Node<'a>, ctx: &Context<'a>) -> String {
+    match node.kind() {
+        "selector_expression" => ctx.get_field_text(&node, "field").unwrap_or_default(),
+        "parenthesized_expression" => node
+            .named_child(0)
+            .map(|inner| variable_key(inner, ctx))
+            .unwrap_or_default(),
+        _ => ctx.get_node_text(&node),
+    }
+}
+
+/// `uint32(n)` -> `n`.
+fn unconvert(node: Node<'_>) -> Node<'_> {
+    if node.kind() == "call_expression"
+        && node
+            .child_by_field_name("function")
+            .is_some_and(|function| function.kind() == "identifier")
+    {
+        if let Some(argument) = node
+            .child_by_field_name("arguments")
+            .and_then(|args| args.named_child(0))
+        {
+            return argument;
+        }
+    }
+    node
+}
+
+fn enclosing_function(node: Node<'_>) -> Option<Node<'_>> {
+    let mut current = node.parent();
+    while let Some(parent) = current {
+        if FUNCTION_KINDS.contains(&parent.kind()) {
+            return Some(parent);
+        }
+        current = parent.parent();
+    }
+    None
+}
+
+fn file_root(node: Node<'_>) -> Node<'_> {
+    let mut root = node;
+    while let Some(parent) = root.parent() {
+        root = parent;
+    }
+    root
+}
+
+fn contains<'a>(root: Node<'a>, matches: &mut impl FnMut(Node<'a>) -> bool) -> bool {
+    let mut found = false;
+    walk(root, &mut |node| found = found || matches(node));
+    found
+}
+
+fn walk<'a>(root: Node<'a>, visit: &mut impl FnMut(Node<'a>)) {
+    let mut stack = vec![root];
+    while let Some(node) = stack.pop() {
+        visit(node);
+        let mut cursor = node.walk();
+        let children: Vec<_> = node.named_children(&mut cursor).collect();
+        stack.extend(children.into_iter().rev());
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::scanner::Scanner;
+    use std::collections::HashMap;
+
+    #[test]
+    fn test_clock_tuned_iterations_with_and_without_floor() {
+        let source = r#"
+package kdf
+
+import (
+    "crypto/sha256"
+    "time"
+
+    "golang.org/x/crypto/pbkdf2"
+)
+
+const minIterations = 600000
+
+func calibrate(pw, salt []byte) int {
+    iterations := 10000
+    for {
+        start := time.Now()
+        pbkdf2.Key(pw, salt, iterations, 32, sha256.New)
+        if time.Since(start) > 100*time.Millisecond {
+            break
+        }
+        iterations *= 2
+    }
+    if iterations < minIterations {
+        iterations = minIterations
+    }
+    return iterations
+}
+
+func scale(pw, salt []byte) int {
+    start := time.Now()
+    pbkdf2.Key(pw, salt, 1000, 32, sha256.New)
+    elapsed := time.Since(start)
+    n := int(1000 * (250 * time.Millisecond) / elapsed)
+    return n
+}
+
+func derive(pw, salt []byte, iterations int) []byte {
+    return pbkdf2.Key(pw, salt, iterations, 32, sha256.New)
+}
+"#;
+        let mut parser = tree_sitter::Parser::new();
+        parser
+            .set_language(&tree_sitter_go::LANGUAGE.into())
+            .unwrap();
+        let tree = parser.parse(source, None).unwrap();
+        let scanner = Scanner::with_mappings(HashMap::from([(
+            "golang.org/x/crypto/pbkdf2".to_string(),
+            HashMap::from([("key".to_string(), "pbkdf2".to_string())]),
+        )]));
+        let result = scanner.scan_tree(&tree, source.as_bytes(), "kdf.go", "go");
+
+        let tunings: Vec<_> = result
+            .calls
+            .iter()
+            .map(|c| c.iteration_tuning.clone())
+            .collect();
+        assert_eq!(
+            tunings[0],
+            Some(IterationTuning {
+                variable: "iterations".to_string(),
+                clock: "time.Now".to_string(),
+                floor: Some("minIterations".to_string()),
+                floor_line: Some(23),
+            })
+        );
+        let scaled = tunings[1].as_ref().unwrap();
+        assert_eq!((scaled.variable.as_str(), &scaled.floor), ("n", &None));
+        assert_eq!(tunings[2], None);
+    }
+}
@@ -0,0 +1,377 @@
+//! Unauthenticated Go encryption: CBC, CTR, OFB and CFB modes with no MAC over the
+//! ciphertext.
+
+use serde::Serialize;
+use tree_sitter::Node;
+
+use super::provenance::{go_key_source, key_sizes};
+use super::receiver::{callee, find_declaration};
+use super::ImportMap;
+use crate::engine::Context;
+
+const FUNCTION_KINDS: &[&str] = &["function_declaration", "method_declaration", "func_literal"];
+
+const CIPHER_PACKAGE: &str = "crypto/cipher";
+
+/// Mode constructors, the mode, and whether the mode decrypts (`None`: either way, as
+/// CTR and OFB do; their destination is taken as the ciphertext).
+const MODES: &[(&str, &str, Option<bool>)] = &[
+    ("NewCBCEncrypter", "CBC", Some(false)),
+    ("NewCBCDecrypter", "CBC", Some(true)),
+    ("NewCTR", "CTR", None),
+    ("NewOFB", "OFB", None),
+    ("NewCFBEncrypter", "CFB", Some(false)),
+    ("NewCFBDecrypter", "CFB", Some(true)),
+];
+
+/// Block cipher constructors and the cipher they build.
+const BLOCK_CIPHERS: &[(&str, &str)] = &[
+    ("crypto/aes.NewCipher", "AES"),
+    ("crypto/des.NewCipher", "DES"),
+    ("crypto/des.NewTripleDESCipher", "3DES"),
+    ("golang.org/x/crypto/blowfish.NewCipher", "Blowfish"),
+    ("golang.org/x/crypto/twofish.NewCipher", "Twofish"),
+    ("golang.org/x/crypto/cast5.NewCipher", "CAST5"),
+];
+
+/// Methods running a mode over a buffer: `(dst, src)`.
+const MODE_METHODS: &[&str] = &["CryptBlocks", "XORKeyStream"];
+
+const MAC_CONSTRUCTORS: &[&str] = &["crypto/hmac.New", "golang.org/x/crypto/poly1305.New"];
+
+/// One-shot MACs and the index of their message argument.
+const MAC_FUNCTIONS: &[(&str, usize)] = &[("golang.org/x/crypto/poly1305.Sum", 1)];
+
+/// A CBC, CTR, OFB or CFB mode whose ciphertext no MAC covers.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct UnauthenticatedMode {
+    /// `CBC`, `CTR`, `OFB` or `CFB`.
+    pub mode: String,
+    /// The block cipher, e.g. `AES`, when its constructor is in view.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub cipher: Option<String>,
+    /// Key size in bits, when the key's length is known.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub key_size: Option<usize>,
+    /// The buffer holding the ciphertext, when the mode runs in the same function.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub ciphertext: Option<String>,
+}
+
+/// The mode, cipher and key size of `call` if `function` under `import_path` builds a
+/// CBC, CTR, OFB or CFB mode that its enclosing function does not authenticate.
+pub(super) fn go_unauthenticated_mode<'a>(
+    call: &Node<'a>,
+    import_path: Option<&str>,
+    function: &str,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Option<UnauthenticatedMode> {
+    if import_path? != CIPHER_PACKAGE {
+        return None;
+    }
+    let (_, mode, decrypts) = MODES.iter().find(|(name, ..)| *name == function)?;
+    let scope = enclosing_function(*call);
+
+    let ciphertexts: Vec<String> = scope
+        .map(|scope| mode_runs(*call, scope, ctx))
+        .unwrap_or_default()
+        .into_iter()
+        .flat_map(|run| {
+            let arguments = arguments(run);
+            let buffers = match decrypts {
+                Some(true) => vec![arguments.get(1)],
+                _ => vec![arguments.first()],
+            };
+            buffers
+                .into_iter()
+                .flatten()
+                .filter_map(|argument| buffer(*argument, ctx))
+                .collect::<Vec<_>>()
+        })
+        .collect();
+
+    let macs = scope.map_or_else(Vec::new, |scope| mac_inputs(scope, ctx, imports));
+    let authenticated = if ciphertexts.is_empty() {
+        !macs.is_empty()
+    } else {
+        macs.iter()
+            .any(|input| input.as_ref().is_some_and(|i| ciphertexts.contains(i)))
+    };
+    if authenticated {
+        return None;
+    }
+
+    let (cipher, key_size) = block_cipher(*call, ctx, imports).unwrap_or_default();
+    Some(UnauthenticatedMode {
+        mode: mode.to_string(),
+        cipher,
+        key_size,
+        ciphertext: ciphertexts.into_iter().next(),
+    })
+}
+
+/// The cipher behind the mode's block argument and its key size in bits.
+fn block_cipher<'a>(
+    call: Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Option<(Option<String>, Option<usize>)> {
+    let block = arguments(call).first().copied()?;
+    let constructor = match block.kind() {
+        "identifier" => find_declaration(&call, &ctx.get_node_text(&block), ctx)?.value?,
+        _ => block,
+    };
+    let name = callee(constructor, ctx, imports)?;
+    let (_, cipher) = BLOCK_CIPHERS.iter().find(|(sink, _)| *sink == name)?;
+    let (package, function) = name.rsplit_once('.')?;
+    let length = go_key_source(&constructor, Some(package), function, ctx, imports)
+        .and_then(|source| source.length)
+        .or_else(|| match key_sizes(&name)? {
+            [size] => Some(*size),
+            _ => None,
+        });
+    Some((Some(cipher.to_string()), length.map(|bytes| bytes * 8)))
+}
+
+/// `CryptBlocks` and `XORKeyStream` calls on the mode `call` builds, in `scope`.
+fn mode_runs<'a>(call: Node<'a>, scope: Node<'a>, ctx: &Context<'a>) -> Vec<Node<'a>> {
+    // cipher.NewCTR(block, iv).XORKeyStream(dst, src)
+    if let Some(run) = call
+        .parent()
+        .filter(|parent| parent.kind() == "selector_expression")
+        .and_then(|selector| selector.parent())
+        .filter(|run| run.kind() == "call_expression")
+    {
+        return vec![run];
+    }
+
+    let Some(variable) = assigned_variable(call, ctx) else {
+        return Vec::new();
+    };
+    let mut runs = Vec::new();
+    walk(scope, &mut |node| {
+        if node.kind() != "call_expression" || node.start_byte() < call.end_byte() {
+            return;
+        }
+        let Some(function) = node.child_by_field_name("function") else {
+            return;
+        };
+        let operand = ctx.get_field_text(&function, "operand");
+        let method = ctx.get_field_text(&function, "field");
+        if operand.as_deref() == Some(variable.as_str())
+            && method.is_some_and(|method| MODE_METHODS.contains(&method.as_str()))
+        {
+            runs.push(node);
+        }
+    });
+    runs
+}
+
+/// `mode` in `mode := cipher.NewCBCEncrypter(block, iv)` or `var mode = ...`.
+fn assigned_variable<'a>(call: Node<'a>, ctx: &Context<'a>) -> Option<String> {
+    let values = call.parent()?;
+    let statement = values.parent()?;
+    let mut cursor = values.walk();
+    let index = values
+        .named_children(&mut cursor)
+        .position(|value| value == call)?;
+    let name = match statement.kind() {
+        "short_var_declaration" | "assignment_statement" => {
+            statement.child_by_field_name("left")?.named_child(index)?
+        }
+        "var_spec" => {
+            let mut cursor = statement.walk();
+            let name = statement
+                .children_by_field_name("name", &mut cursor)
+                .nth(index);
+            name?
+        }
+        _ => return None,
+    };
+    Some(ctx.get_node_text(&name))
+}
+
+/// The buffers written into a MAC in `scope`: arguments of `Write` on a variable built by
+/// a MAC constructor, and messages of one-shot MACs. `None` marks a MAC fed something
+/// other than a buffer.
+fn mac_inputs<'a>(scope: Node<'a>, ctx: &Context<'a>, imports: &ImportMap) -> Vec<Option<String>> {
+    let mut inputs = Vec::new();
+    walk(scope, &mut |node| {
+        if node.kind() != "call_expression" {
+            return;
+        }
+        let arguments = arguments(node);
+        if let Some(name) = callee(node, ctx, imports) {
+            if let Some((_, index)) = MAC_FUNCTIONS.iter().find(|(sink, _)| *sink == name) {
+                inputs.push(arguments.get(*index).and_then(|m| buffer(*m, ctx)));
+            }
+            return;
+        }
+        let Some(function) = node.child_by_field_name("function") else {
+            return;
+        };
+        if ctx.get_field_text(&function, "field").as_deref() != Some("Write") {
+            return;
+        }
+        let is_mac = function
+            .child_by_field_name("operand")
+            .filter(|operand| operand.kind() == "identifier")
+            .and_then(|operand| find_declaration(&node, &ctx.get_node_text(&operand), ctx))
+            .and_then(|declaration| declaration.value)
+            .and_then(|value| callee(value, ctx, imports))
+            .is_some_and(|constructor| MAC_CONSTRUCTORS.contains(&constructor.as_str()));
+        if is_mac {
+            inputs.push(arguments.first().and_then(|m| buffer(*m, ctx)));
+        }
+    });
+    inputs
+}
+
+/// The variable or field a buffer argument slices, e.g. `out` for `out[aes.BlockSize:]`.
+fn buffer<'a>(node: Node<'a>, ctx: &Context<'a>) -> Option<String> {
+    match node.kind() {
+        "identifier" | "selector_expression" => Some(ctx.get_node_text(&node)),
+        "slice_expression" | "unary_expression" => {
+            buffer(node.child_by_field_name("operand")?, ctx)
+        }
+        "parenthesized_expression" => buffer(node.named_child(0)?, ctx),
+        _ => None,
+    }
+}
+
+fn arguments(call: Node<'_>) -> Vec<Node<'_>> {
+    let Some(arguments) = call.child_by_field_name("arguments") else {
+        return Vec::new();
+    };
+    let mut cursor = arguments.walk();
+    let nodes: Vec<_> = arguments.named_children(&mut cursor).collect();
+    nodes
+}
+
+fn enclosing_function(node: Node<'_>) -> Option<Node<'_>> {
+    let mut current = node.parent();
+    while let Some(parent) = current {
+        if FUNCTION_KINDS.contains(&parent.kind()) {
+            return Some(parent);
+        }
+        current = parent.parent();
+    }
+    None
+}
+
+fn walk<'a>(root: Node<'a>, visit: &mut impl FnMut(Node<'a>)) {
+    let mut stack = vec![root];
+    while let Some(node) = stack.pop() {
+        visit(node);
+        let mut cursor = node.walk();
+        let children: Vec<_> = node.named_children(&mut cursor).collect();
+        stack.extend(children.into_iter().rev());
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::scanner::Scanner;
+    use std::collections::HashMap;
+
+    fn modes(source: &str) -> Vec<Option<UnauthenticatedMode>> {
+        let mut parser = tree_sitter::Parser::new();
+        parser
+            .set_language(&tree_sitter_go::LANGUAGE.into())
+            .unwrap();
+        let tree = parser.parse(source, None).unwrap();
+        let functions = MODES
+            .iter()
+            .map(|(name, ..)| (name.to_lowercase(), "cipher_mode".to_string()))
+            .collect();
+        let scanner =
+            Scanner::with_mappings(HashMap::from([(CIPHER_PACKAGE.to_string(), functions)]));
+        let result = scanner.scan_tree(&tree, source.as_bytes(), "modes.go", "go");
+        result
+            .calls
+            .into_iter()
+            .map(|call| call.unauthenticated_mode)
+            .collect()
+    }
+
+    #[test]
+    fn test_cbc_without_mac() {
+        let found = modes(
+            r#"
+package box
+
+import (
+    "crypto/aes"
+    "crypto/cipher"
+    "crypto/rand"
+)
+
+func encrypt(plaintext []byte) []byte {
+    key := make([]byte, 32)
+    rand.Read(key)
+    block, _ := aes.NewCipher(key)
+    out := make([]byte, aes.BlockSize+len(plaintext))
+    iv := out[:aes.BlockSize]
+    mode := cipher.NewCBCEncrypter(block, iv)
+    mode.CryptBlocks(out[aes.BlockSize:], plaintext)
+    return out
+}
+"#,
+        );
+        assert_eq!(
+            found,
+            vec![Some(UnauthenticatedMode {
+                mode: "CBC".to_string(),
+                cipher: Some("AES".to_string()),
+                key_size: Some(256),
+                ciphertext: Some("out".to_string()),
+            })]
+        );
+    }
+
+    #[test]
+    fn test_encrypt_then_mac_is_authenticated() {
+        let found = modes(
+            r#"
+package box
+
+import (
+    "crypto/aes"
+    "crypto/cipher"
+    "crypto/hmac"
+    "crypto/sha256"
+)
+
+func seal(encKey, macKey, iv, plaintext []byte) []byte {
+    block, _ := aes.NewCipher(encKey)
+    ciphertext := make([]byte, len(plaintext))
+    cipher.NewCTR(block, iv).XORKeyStream(ciphertext, plaintext)
+    mac := hmac.New(sha256.New, macKey)
+    mac.Write(iv)
+    mac.Write(ciphertext)
+    return mac.Sum(ciphertext)
+}
+
+func sealIV(encKey, macKey, iv, plaintext []byte) []byte {
+    block, _ := aes.NewCipher(encKey)
+    mac := hmac.New(sha256.New, macKey)
+    mac.Write(iv)
+    stream := cipher.NewCTR(block, iv)
+    ct := make([]byte, len(plaintext))
+    stream.XORKeyStream(ct, plaintext)
+    return append(mac.Sum(nil), ct...)
+}
+"#,
+        );
+        assert_eq!(found.len(), 2);
+        assert_eq!(found[0], None);
+        // The MAC covers only the IV
+        let mode = found[1].as_ref().unwrap();
+        assert_eq!(mode.mode, "CTR");
+        assert_eq!(mode.cipher.as_deref(), Some("AES"));
+        assert_eq!(mode.key_size, None);
+        assert_eq!(mode.ciphertext.as_deref(), Some("ct"));
+    }
+}
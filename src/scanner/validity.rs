@@ -0,0 +1,661 @@
+//! Certificate lifetimes, key rotation intervals and key cache TTLs, evaluated to seconds
+//! from their `time.Duration` expressions.
+
+use serde::Serialize;
+use tree_sitter::Node;
+
+use super::receiver::{callee, find_declaration};
+use super::ImportMap;
+use crate::engine::Context;
+
+const FUNCTION_KINDS: &[&str] = &["function_declaration", "method_declaration", "func_literal"];
+
+const CREATE_CERTIFICATE: &str = "crypto/x509.CreateCertificate";
+
+/// Timers whose first argument is the interval.
+const SCHEDULES: &[&str] = &[
+    "time.NewTicker",
+    "time.Tick",
+    "time.NewTimer",
+    "time.AfterFunc",
+];
+
+/// Options setting how often a cached JWK set is refetched.
+const CACHE_REFRESH: &[&str] = &[
+    "github.com/lestrrat-go/jwx/v2/jwk.WithRefreshInterval",
+    "github.com/lestrrat-go/jwx/v2/jwk.WithMinRefreshInterval",
+    "github.com/lestrrat-go/jwx/jwk.WithRefreshInterval",
+    "github.com/lestrrat-go/jwx/jwk.WithMinRefreshInterval",
+];
+
+const ROTATION_WORDS: &[&str] = &["rotate", "rotation", "rekey", "renew", "rollover"];
+const CACHE_WORDS: &[&str] = &["ttl", "cache", "refresh"];
+const CERTIFICATE_WORDS: &[&str] = &["cert", "notafter", "validity", "lifetime"];
+
+/// `time` unit constants in nanoseconds.
+const UNITS: &[(&str, i128)] = &[
+    ("Nanosecond", 1),
+    ("Microsecond", 1_000),
+    ("Millisecond", 1_000_000),
+    ("Second", 1_000_000_000),
+    ("Minute", 60_000_000_000),
+    ("Hour", 3_600_000_000_000),
+];
+
+const NANOS_PER_SECOND: i128 = 1_000_000_000;
+const SECONDS_PER_DAY: i128 = 86_400;
+
+/// Named constants are followed this many declarations deep.
+const MAX_DEPTH: usize = 8;
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum ValidityKind {
+    /// How long an issued certificate is valid.
+    CertificateLifetime,
+    /// How often keys are rotated.
+    RotationInterval,
+    /// How long fetched or derived keys are cached.
+    CacheTtl,
+    /// A duration passed to a sink whose name says nothing more.
+    Duration,
+}
+
+impl ValidityKind {
+    pub fn as_str(self) -> &'static str {
+        match self {
+            ValidityKind::CertificateLifetime => "certificate lifetime",
+            ValidityKind::RotationInterval => "key rotation interval",
+            ValidityKind::CacheTtl => "key cache TTL",
+            ValidityKind::Duration => "duration",
+        }
+    }
+}
+
+/// A validity period or interval a call sets, resolved to seconds.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct ValidityPeriod {
+    pub kind: ValidityKind,
+    /// The argument (`arg0`) or template field (`NotAfter`) the period is set through.
+    pub parameter: String,
+    pub seconds: u64,
+    /// The expression as written, e.g. `time.Now().Add(certLifetime)`.
+    pub expression: String,
+}
+
+/// A value while evaluating a duration expression: an untyped number, or a
+/// `time.Duration` in nanoseconds.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+enum Quantity {
+    Number(i128),
+    Duration(i128),
+}
+
+/// Whether `function` under `import_path` is a timer, reported only when it schedules
+/// rotation.
+pub(super) fn is_schedule_sink(import_path: Option<&str>, function: &str) -> bool {
+    import_path.is_some_and(|path| SCHEDULES.contains(&format!("{path}.{function}").as_str()))
+}
+
+/// The validity periods `call` of `function` under `import_path` sets.
+pub(super) fn go_validity<'a>(
+    call: &Node<'a>,
+    import_path: Option<&str>,
+    function: &str,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Vec<ValidityPeriod> {
+    let name = match import_path {
+        Some(path) => format!("{path}.{function}"),
+        None => function.to_string(),
+    };
+    let arguments = arguments(*call);
+
+    if name == CREATE_CERTIFICATE {
+        return arguments
+            .get(1)
+            .and_then(|template| certificate_lifetime(*call, *template, ctx, imports))
+            .into_iter()
+            .collect();
+    }
+    let fixed = if SCHEDULES.contains(&name.as_str()) {
+        if !schedules_rotation(*call, ctx) {
+            return Vec::new();
+        }
+        Some(ValidityKind::RotationInterval)
+    } else if CACHE_REFRESH.contains(&name.as_str()) {
+        Some(ValidityKind::CacheTtl)
+    } else {
+        None
+    };
+    if let Some(kind) = fixed {
+        return arguments
+            .first()
+            .and_then(|argument| period(kind, "arg0", *argument, ctx, imports))
+            .into_iter()
+            .collect();
+    }
+
+    let kind = kind_of(function);
+    arguments
+        .iter()
+        .enumerate()
+        .filter_map(|(i, argument)| period(kind, &format!("arg{i}"), *argument, ctx, imports))
+        .collect()
+}
+
+fn period<'a>(
+    kind: ValidityKind,
+    parameter: &str,
+    argument: Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Option<ValidityPeriod> {
+    let Quantity::Duration(nanos) = evaluate(argument, ctx, imports, 0)? else {
+        return None;
+    };
+    Some(ValidityPeriod {
+        kind,
+        parameter: parameter.to_string(),
+        seconds: seconds(nanos)?,
+        expression: ctx.get_node_text(&argument),
+    })
+}
+
+fn seconds(nanos: i128) -> Option<u64> {
+    u64::try_from(nanos / NANOS_PER_SECOND).ok()
+}
+
+/// What a duration passed to `function` most likely sets, from its name.
+fn kind_of(function: &str) -> ValidityKind {
+    let lower = function.to_lowercase();
+    let mentions = |words: &[&str]| words.iter().any(|word| lower.contains(word));
+    if mentions(ROTATION_WORDS) {
+        ValidityKind::RotationInterval
+    } else if mentions(CACHE_WORDS) {
+        ValidityKind::CacheTtl
+    } else if mentions(CERTIFICATE_WORDS) {
+        ValidityKind::CertificateLifetime
+    } else {
+        ValidityKind::Duration
+    }
+}
+
+/// The lifetime the certificate `template` of `call` is issued with.
+fn certificate_lifetime<'a>(
+    call: Node<'a>,
+    template: Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+) -> Option<ValidityPeriod> {
+    let template = unaddress(template);
+    let not_after = match template.kind() {
+        "composite_literal" => keyed_field(template, "NotAfter", ctx),
+        "identifier" => {
+            let name = ctx.get_node_text(&template);
+            field_assignment(call, &name, "NotAfter", ctx).or_else(|| {
+                find_declaration(&template, &name, ctx)
+                    .and_then(|declaration| declaration.value)
+                    .map(unaddress)
+                    .filter(|value| value.kind() == "composite_literal")
+                    .and_then(|literal| keyed_field(literal, "NotAfter", ctx))
+            })
+        }
+        _ => None,
+    }?;
+    let nanos = added_duration(not_after, ctx, imports, 0)?;
+    Some(ValidityPeriod {
+        kind: ValidityKind::CertificateLifetime,
+        parameter: "NotAfter".to_string(),
+        seconds: seconds(nanos)?,
+        expression: ctx.get_node_text(&not_after),
+    })
+}
+
+/// The duration a `time.Time` expression adds to its base: `start.Add(d)` or
+/// `start.AddDate(years, months, days)`, with a year as 365 days and a month as 30.
+fn added_duration<'a>(
+    value: Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+    depth: usize,
+) -> Option<i128> {
+    if depth > MAX_DEPTH {
+        return None;
+    }
+    match value.kind() {
+        "call_expression" => {
+            let function = value.child_by_field_name("function")?;
+            if function.kind() != "selector_expression" {
+                return None;
+            }
+            let method = ctx.get_node_text(&function.child_by_field_name("field")?);
+            let arguments = arguments(value);
+            match method.as_str() {
+                "Add" => match evaluate(*arguments.first()?, ctx, imports, 0)? {
+                    Quantity::Duration(nanos) => Some(nanos),
+                    Quantity::Number(_) => None,
+                },
+                "AddDate" => {
+                    let mut days = 0i128;
+                    for (argument, scale) in arguments.iter().zip([365, 30, 1]) {
+                        let Quantity::Number(n) = evaluate(*argument, ctx, imports, 0)? else {
+                            return None;
+                        };
+                        days = days.checked_add(n.checked_mul(scale)?)?;
+                    }
+                    days.checked_mul(SECONDS_PER_DAY * NANOS_PER_SECOND)
+                }
+                _ => None,
+            }
+        }
+        "identifier" => {
+            let value = find_declaration(&value, &ctx.get_node_text(&value), ctx)?.value?;
+            added_duration(value, ctx, imports, depth + 1)
+        }
+        "parenthesized_expression" => added_duration(value.named_child(0)?, ctx, imports, depth),
+        _ => None,
+    }
+}
+
+/// Evaluates a constant integer or `time.Duration` expression.
+fn evaluate<'a>(
+    node: Node<'a>,
+    ctx: &Context<'a>,
+    imports: &ImportMap,
+    depth: usize,
+) -> Option<Quantity> {
+    if depth > MAX_DEPTH {
+        return None;
+    }
+    match node.kind() {
+        "int_literal" => parse_int(&ctx.get_node_text(&node)).map(Quantity::Number),
+        "parenthesized_expression" => evaluate(node.named_child(0)?, ctx, imports, depth),
+        "selector_expression" => {
+            let operand = node.child_by_field_name("operand")?;
+            let package = imports.resolve(&ctx.get_node_text(&operand))?;
+            if package != "time" {
+                return None;
+            }
+            let field = ctx.get_node_text(&node.child_by_field_name("field")?);
+            UNITS
+                .iter()
+                .find(|(unit, _)| *unit == field)
+                .map(|(_, nanos)| Quantity::Duration(*nanos))
+        }
+        // time.Duration(n) is a count still to be scaled by a unit: time.Duration(n) * time.Hour
+        "call_expression" => {
+            if callee(node, ctx, imports)? != "time.Duration" {
+                return None;
+            }
+            evaluate(*arguments(node).first()?, ctx, imports, depth)
+        }
+        "binary_expression" => {
+            let left = evaluate(node.child_by_field_name("left")?, ctx, imports, depth)?;
+            let right = evaluate(node.child_by_field_name("right")?, ctx, imports, depth)?;
+            let operator = ctx.get_node_text(&node.child_by_field_name("operator")?);
+            combine(&operator, left, right)
+        }
+        "identifier" => {
+            let value = find_declaration(&node, &ctx.get_node_text(&node), ctx)?.value?;
+            evaluate(value, ctx, imports, depth + 1)
+        }
+        _ => None,
+    }
+}
+
+fn combine(operator: &str, left: Quantity, right: Quantity) -> Option<Quantity> {
+    use Quantity::{Duration, Number};
+    match (operator, left, right) {
+        ("*", Number(a), Number(b)) => a.checked_mul(b).map(Number),
+        ("*", Number(a), Duration(b)) | ("*", Duration(a), Number(b)) => {
+            a.checked_mul(b).map(Duration)
+        }
+        ("+", Number(a), Number(b)) => a.checked_add(b).map(Number),
+        ("+", Duration(a), Duration(b)) => a.checked_add(b).map(Duration),
+        ("-", Number(a), Number(b)) => a.checked_sub(b).map(Number),
+        ("-", Duration(a), Duration(b)) => a.checked_sub(b).map(Duration),
+        ("/", Number(a), Number(b)) => a.checked_div(b).map(Number),
+        ("/", Duration(a), Number(b)) => a.checked_div(b).map(Duration),
+        ("/", Duration(a), Duration(b)) => a.checked_div(b).map(Number),
+        ("<<", Number(a), Number(b)) => u32::try_from(b)
+            .ok()
+            .and_then(|shift| a.checked_shl(shift))
+            .map(Number),
+        _ => None,
+    }
+}
+
+fn parse_int(literal: &str) -> Option<i128> {
+    let digits = literal.replace('_', "");
+    let lower = digits.to_lowercase();
+    if let Some(hex) = lower.strip_prefix("0x") {
+        i128::from_str_radix(hex, 16).ok()
+    } else if let Some(octal) = lower.strip_prefix("0o") {
+        i128::from_str_radix(octal, 8).ok()
+    } else if let Some(binary) = lower.strip_prefix("0b") {
+        i128::from_str_radix(binary, 2).ok()
+    } else {
+        lower.parse().ok()
+    }
+}
+
+/// Whether the timer `call` drives rotation: the enclosing function is named for it, or
+/// calls or passes a function that is.
+fn schedules_rotation<'a>(call: Node<'a>, ctx: &Context<'a>) -> bool {
+    let is_rotation = |name: &str| {
+        let lower = name.to_lowercase();
+        ROTATION_WORDS.iter().any(|word| lower.contains(word))
+    };
+    if arguments(call)
+        .iter()
+        .skip(1)
+        .any(|argument| is_rotation(&ctx.get_node_text(argument)))
+    {
+        return true;
+    }
+    let Some(function) = enclosing_function(call) else {
+        return false;
+    };
+    if function
+        .child_by_field_name("name")
+        .is_some_and(|name| is_rotation(&ctx.get_node_text(&name)))
+    {
+        return true;
+    }
+    let mut found = false;
+    walk(function, &mut |node| {
+        if !found && node.kind() == "call_expression" {
+            found = node
+                .child_by_field_name("function")
+                .is_some_and(|callee| is_rotation(&ctx.get_node_text(&callee)));
+        }
+    });
+    found
+}
+
+/// The value of the last `name.field = value` before `call` in its function.
+fn field_assignment<'a>(
+    call: Node<'a>,
+    name: &str,
+    field: &str,
+    ctx: &Context<'a>,
+) -> Option<Node<'a>> {
+    let function = enclosing_function(call)?;
+    let mut found = None;
+    walk(function, &mut |node| {
+        if node.kind() != "assignment_statement" || node.start_byte() >= call.start_byte() {
+            return;
+        }
+        let (Some(left), Some(right)) = (
+            node.child_by_field_name("left")
+                .and_then(|left| left.named_child(0)),
+            node.child_by_field_name("right")
+                .and_then(|right| right.named_child(0)),
+        ) else {
+            return;
+        };
+        let assigns = left.kind() == "selector_expression"
+            && left
+                .child_by_field_name("operand")
+                .is_some_and(|operand| ctx.get_node_text(&operand) == name)
+            && left
+                .child_by_field_name("field")
+                .is_some_and(|f| ctx.get_node_text(&f) == field);
+        if assigns {
+            found = Some(right);
+        }
+    });
+    found
+}
+
+/// The value of `field` in a keyed composite literal.
+fn keyed_field<'a>(literal: Node<'a>, field: &str, ctx: &Context<'a>) -> Option<Node<'a>> {
+    let body = literal.child_by_field_name("body")?;
+    named_children(body)
+        .into_iter()
+        .filter(|element| element.kind() == "keyed_element")
+        .find(|element| {
+            element
+                .named_child(0)
+                .is_some_and(|key| ctx.get_node_text(&key) == field)
+        })
+        .and_then(|element| element.named_child(1))
+        .map(unwrap_element)
+}
+
+/// Go wraps keys and values of composite literals in `literal_element`.
+fn unwrap_element(node: Node<'_>) -> Node<'_> {
+    match node.kind() {
+        "literal_element" => node.named_child(0).unwrap_or(node),
+        _ => node,
+    }
+}
+
+/// `x` for `&x`.
+fn unaddress(node: Node<'_>) -> Node<'_> {
+    match node.kind() {
+        "unary_expression" => node.child_by_field_name("operand").unwrap_or(node),
+        _ => node,
+    }
+}
+
+fn arguments(call: Node<'_>) -> Vec<Node<'_>> {
+    call.child_by_field_name("arguments")
+        .map(named_children)
+        .unwrap_or_default()
+}
+
+fn named_children(node: Node<'_>) -> Vec<Node<'_>> {
+    let mut cursor = node.walk();
+    let children: Vec<_> = node.named_children(&mut cursor).collect();
+    children
+}
+
+fn enclosing_function(node: Node<'_>) -> Option<Node<'_>> {
+    let mut current = node.parent();
+    while let Some(parent) = current {
+        if FUNCTION_KINDS.contains(&parent.kind()) {
+            return Some(parent);
+        }
+        current = parent.parent();
+    }
+    None
+}
+
+fn walk<'a>(root: Node<'a>, visit: &mut impl FnMut(Node<'a>)) {
+    let mut stack = vec![root];
+    while let Some(node) = stack.pop() {
+        visit(node);
+        let mut cursor = node.walk();
+        let children: Vec<_> = node.named_children(&mut cursor).collect();
+        stack.extend(children.into_iter().rev());
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::scanner::Scanner;
+    use std::collections::HashMap;
+
+    fn periods(source: &str) -> Vec<(String, Vec<ValidityPeriod>)> {
+        let mut parser = tree_sitter::Parser::new();
+        parser
+            .set_language(&tree_sitter_go::LANGUAGE.into())
+            .unwrap();
+        let tree = parser.parse(source, None).unwrap();
+        let scanner = Scanner::with_mappings(HashMap::from([
+            (
+                "crypto/x509".to_string(),
+                HashMap::from([("createcertificate".to_string(), "cert".to_string())]),
+            ),
+            (
+                "time".to_string(),
+                HashMap::from([
+                    ("newticker".to_string(), "rotate".to_string()),
+                    ("afterfunc".to_string(), "rotate".to_string()),
+                ]),
+            ),
+            (
+                "example.com/pki".to_string(),
+                HashMap::from([("issuecert".to_string(), "cert".to_string())]),
+            ),
+        ]));
+        let result = scanner.scan_tree(&tree, source.as_bytes(), "certs.go", "go");
+        result
+            .calls
+            .into_iter()
+            .map(|call| (call.function_name, call.validity))
+            .collect()
+    }
+
+    fn period(kind: ValidityKind, parameter: &str, days: u64, expression: &str) -> ValidityPeriod {
+        ValidityPeriod {
+            kind,
+            parameter: parameter.to_string(),
+            seconds: days * 86_400,
+            expression: expression.to_string(),
+        }
+    }
+
+    #[test]
+    fn test_certificate_lifetimes() {
+        let found = periods(
+            r#"
+package certs
+
+import (
+    "crypto/x509"
+    "time"
+)
+
+const certLifetime = 825 * 24 * time.Hour
+
+func literal(pub, priv any) {
+    template := &x509.Certificate{
+        NotBefore: time.Now(),
+        NotAfter:  time.Now().Add(certLifetime),
+    }
+    x509.CreateCertificate(nil, template, template, pub, priv)
+}
+
+func assigned(pub, priv any) {
+    var template x509.Certificate
+    notBefore := time.Now()
+    template.NotAfter = notBefore.AddDate(1, 0, 0)
+    x509.CreateCertificate(nil, &template, &template, pub, priv)
+}
+
+func unknown(pub, priv any, until time.Time) {
+    x509.CreateCertificate(nil, &x509.Certificate{NotAfter: until}, nil, pub, priv)
+}
+"#,
+        );
+        assert_eq!(
+            found,
+            vec![
+                (
+                    "CreateCertificate".to_string(),
+                    vec![period(
+                        ValidityKind::CertificateLifetime,
+                        "NotAfter",
+                        825,
+                        "time.Now().Add(certLifetime)"
+                    )]
+                ),
+                (
+                    "CreateCertificate".to_string(),
+                    vec![period(
+                        ValidityKind::CertificateLifetime,
+                        "NotAfter",
+                        365,
+                        "notBefore.AddDate(1, 0, 0)"
+                    )]
+                ),
+                ("CreateCertificate".to_string(), Vec::new()),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_rotation_timers_and_helpers() {
+        let found = periods(
+            r#"
+package certs
+
+import (
+    "time"
+
+    "example.com/pki"
+)
+
+const day = 24 * time.Hour
+
+func (k *Keyring) run() {
+    ticker := time.NewTicker(time.Duration(30) * day)
+    for range ticker.C {
+        k.rotateKeys()
+    }
+}
+
+func poll() {
+    ticker := time.NewTicker(5 * time.Second)
+    for range ticker.C {
+        refreshMetrics()
+    }
+}
+
+func schedule(k *Keyring) {
+    time.AfterFunc(90*day, k.Rekey)
+    pki.IssueCert("api", 398*day)
+}
+"#,
+        );
+        assert_eq!(
+            found,
+            vec![
+                (
+                    "NewTicker".to_string(),
+                    vec![period(
+                        ValidityKind::RotationInterval,
+                        "arg0",
+                        30,
+                        "time.Duration(30) * day"
+                    )]
+                ),
+                (
+                    "AfterFunc".to_string(),
+                    vec![period(ValidityKind::RotationInterval, "arg0", 90, "90*day")]
+                ),
+                (
+                    "IssueCert".to_string(),
+                    vec![period(
+                        ValidityKind::CertificateLifetime,
+                        "arg1",
+                        398,
+                        "398*day"
+                    )]
+                ),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_duration_arithmetic() {
+        use Quantity::{Duration, Number};
+        let hour = Duration(3_600_000_000_000);
+        assert_eq!(
+            combine("*", Number(24), hour),
+            Some(Duration(86_400_000_000_000))
+        );
+        assert_eq!(
+            combine("/", hour, Number(60)),
+            Some(Duration(60_000_000_000))
+        );
+        assert_eq!(combine("*", hour, hour), None);
+        assert_eq!(combine("+", hour, Number(1)), None);
+        assert_eq!(parse_int("1_000"), Some(1000));
+        assert_eq!(parse_int("0x10"), Some(16));
+    }
+}
@@ -0,0 +1,495 @@
+//! JSON Schemas for argflow's config files and JSON report.
+
+pub struct Schema {
+    pub name: &'static str,
+    pub description: &'static str,
+    pub content: &'static str,
+}
+
+pub const SCHEMAS: &[Schema] = &[
+    Schema {
+        name: "findings",
+        description: "JSON report written by --format json",
+        content: include_str!("../schemas/findings.schema.json"),
+    },
+    Schema {
+        name: "policy",
+        description: "Gate policy file (gate --policy)",
+        content: include_str!("../schemas/policy.schema.json"),
+    },
+    Schema {
+        name: "notify",
+        description: "Notification config (--notify)",
+        content: include_str!("../schemas/notify.schema.json"),
+    },
+    Schema {
+        name: "architecture",
+        description: "Reference architecture spec (architecture --spec)",
+        content: include_str!("../schemas/architecture.schema.json"),
+    },
+];
+
+pub const SCHEMA_NAMES: [&str; 4] = ["findings", "policy", "notify", "architecture"];
+
+pub fn find(name: &str) -> Option<&'static Schema> {
+    SCHEMAS.iter().find(|schema| schema.name == name)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::collections::{BTreeMap, BTreeSet};
+
+    use serde_json::Value;
+
+    use crate::attestation::ToolInfo;
+    use crate::output::{
+        AlgorithmSelection, AnalysisStatus, CatalogVersion, ConstantUsage, CryptoOperation,
+        DuplicateOperation, Finding, FindingAgility, GeneratorSetting, GeneratorSettingKind,
+        JsonOutput, KeyMismatch, ModuleScan, NonceOverflow, PackageAgility, PackageStatus,
+        ScanManifest, SelectionOption, WrapperLink, WrapperRole, WrapperSite,
+    };
+    use crate::scanner::{
+        AeadScope, AgilityClass, BiasKind, ByteOrigin, ByteSource, CallbackKind,
+        CallbackRegistration, ConstantRef, CrashKind, CrashPath, ErrorHandling, FailureKind,
+        FailurePath, IterationTuning, KeyDestination, KeyEncoding, KeyExchange, KeyLifetime,
+        KmsOperation, KmsProvider, LongLivedAead, NonceCounter, PasswordStorage,
+        PasswordStorageKind, Pkcs11Operation, RandomBias, RemediationEffort, SecretComparison,
+        SecretMaterial, UnauthenticatedMode, ValidityKind, ValidityPeriod, ZeroFallback,
+    };
+    use crate::vcs::{Blame, Repository};
+
+    fn parse(name: &str) -> Value {
+        serde_json::from_str(find(name).unwrap().content).unwrap()
+    }
+
+    /// Keys in `value` the schema object at `pointer` does not declare, and required
+    /// keys `value` is missing.
+    fn drift(schema: &Value, pointer: &str, value: &Value) -> (Vec<String>, Vec<String>) {
+        let definition = schema.pointer(pointer).unwrap();
+        let declared: BTreeSet<&str> = definition["properties"]
+            .as_object()
+            .unwrap()
+            .keys()
+            .map(String::as_str)
+            .collect();
+        let present: BTreeSet<&str> = value
+            .as_object()
+            .unwrap()
+            .keys()
+            .map(String::as_str)
+            .collect();
+        let undeclared = present
+            .difference(&declared)
+            .map(|k| k.to_string())
+            .collect();
+        let missing = definition["required"]
+            .as_array()
+            .into_iter()
+            .flatten()
+            .filter_map(Value::as_str)
+            .filter(|k| !present.contains(k))
+            .map(str::to_string)
+            .collect();
+        (undeclared, missing)
+    }
+
+    fn finding() -> Finding {
+        Finding {
+            file: "main.go".to_string(),
+            line: 10,
+            column: 2,
+            function: "Key".to_string(),
+            package: Some("pbkdf2".to_string()),
+            import_path: Some("golang.org/x/crypto/pbkdf2".to_string()),
+            full_name: "golang.org/x/crypto/pbkdf2.Key".to_string(),
+            algorithm: Some("PBKDF2".to_string()),
+            finding_type: Some("kdf".to_string()),
+            operation: Some("derive".to_string()),
+            primitive: Some("kdf".to_string()),
+            parameters: BTreeMap::new(),
+            parameter_status: BTreeMap::new(),
+            parameter_categories: BTreeMap::from([(
+                "arg2".to_string(),
+                "iteration-count".to_string(),
+            )]),
+            raw_text: "pbkdf2.Key(pw, salt, 4096, 32, sha256.New)".to_string(),
+            enclosing_function: Some("derive".to_string()),
+            build_constraint: Some("linux".to_string()),
+            mock: Some("mockgen".to_string()),
+            module: Some("golang.org/x/crypto".to_string()),
+            module_version: Some("v0.31.0".to_string()),
+            purl: Some("pkg:golang/golang.org/x/crypto@v0.31.0".to_string()),
+            advisories: Vec::new(),
+            configurations: Vec::new(),
+            failure_paths: vec![FailurePath {
+                kind: FailureKind::NilWithoutError,
+                line: 14,
+                text: "return nil, nil".to_string(),
+            }],
+            crash_paths: vec![CrashPath {
+                kind: CrashKind::Fatal,
+                line: 16,
+                text: "log.Fatal(err)".to_string(),
+            }],
+            zero_fallbacks: vec![ZeroFallback {
+                argument: "iterations".to_string(),
+                position: 2,
+                expression: "cfg.Iterations".to_string(),
+                loader: "LoadConfig(path)".to_string(),
+                line: 8,
+                handling: ErrorHandling::Swallowed,
+                zero: Some("0".to_string()),
+            }],
+            selection: Some(AlgorithmSelection {
+                function: "DeriveKey".to_string(),
+                selector: "kdf".to_string(),
+                cases: vec!["pbkdf2".to_string()],
+                options: vec![SelectionOption {
+                    case: "pbkdf2".to_string(),
+                    algorithm: Some("PBKDF2".to_string()),
+                    line: 12,
+                }],
+            }),
+            receiver_chain: vec!["aes.NewCipher(key)".to_string()],
+            receiver_parameters: BTreeMap::from([(
+                "arg0".to_string(),
+                Value::String("key".to_string()),
+            )]),
+            secret: Some(ByteSource {
+                origin: ByteOrigin::Stored,
+                length: None,
+                available: None,
+                length_from: None,
+                minimum: None,
+                expression: "user.Password".to_string(),
+            }),
+            salt: Some(ByteSource {
+                origin: ByteOrigin::Random,
+                length: Some(16),
+                available: None,
+                length_from: Some("saltSize".to_string()),
+                minimum: Some(16),
+                expression: "make([]byte, saltSize)".to_string(),
+            }),
+            key: Some(ByteSource {
+                origin: ByteOrigin::Random,
+                length: Some(16),
+                available: Some(32),
+                length_from: None,
+                minimum: None,
+                expression: "key[:16]".to_string(),
+            }),
+            nonce: Some(ByteSource {
+                origin: ByteOrigin::Random,
+                length: Some(12),
+                available: None,
+                length_from: Some("gcm.NonceSize()".to_string()),
+                minimum: Some(12),
+                expression: "make([]byte, gcm.NonceSize())".to_string(),
+            }),
+            key_encoding: Some(KeyEncoding {
+                encrypted: false,
+                destination: KeyDestination::File,
+                expression: "os.WriteFile(\"key.der\", der, 0600)".to_string(),
+            }),
+            key_exchange: Some(KeyExchange {
+                lifetime: KeyLifetime::Static,
+                private_key: "serverKey".to_string(),
+                origin: Some("ecdh.X25519().GenerateKey(rand.Reader)".to_string()),
+            }),
+            nonce_counter: Some(NonceCounter {
+                counter: "s.seq".to_string(),
+                bits: 64,
+                declaration_line: Some(11),
+                declaration: Some("seq uint64".to_string()),
+                bounded: false,
+            }),
+            password_storage: Some(PasswordStorage {
+                kind: PasswordStorageKind::FastHash,
+                password: "req.Password".to_string(),
+                expression: "[]byte(req.Password)".to_string(),
+            }),
+            secret_comparison: Some(SecretComparison {
+                material: SecretMaterial::Key,
+                operand: "derived".to_string(),
+                provenance: vec!["derived".to_string(), "pbkdf2.Key(...)".to_string()],
+            }),
+            iteration_tuning: Some(IterationTuning {
+                variable: "iterations".to_string(),
+                clock: "time.Since".to_string(),
+                floor: Some("minIterations".to_string()),
+                floor_line: Some(22),
+            }),
+            long_lived_aead: Some(LongLivedAead {
+                aead: "Sealer.aead".to_string(),
+                scope: AeadScope::Singleton,
+                construction_line: Some(30),
+                construction: Some("s.aead, _ = cipher.NewGCM(block)".to_string()),
+            }),
+            kms: Some(KmsOperation {
+                provider: KmsProvider::Aws,
+                key_id: Some("alias/payments".to_string()),
+                key_spec: Some("AES_256".to_string()),
+            }),
+            pkcs11: Some(Pkcs11Operation {
+                mechanisms: vec!["CKM_AES_GCM".to_string()],
+                algorithm: Some("AES-GCM".to_string()),
+                attributes: BTreeMap::from([("CKA_KEY_TYPE".to_string(), "CKK_AES".to_string())]),
+            }),
+            unauthenticated_mode: Some(UnauthenticatedMode {
+                mode: "CBC".to_string(),
+                cipher: Some("AES".to_string()),
+                key_size: Some(256),
+                ciphertext: Some("out".to_string()),
+            }),
+            random_bias: Some(RandomBias {
+                kind: BiasKind::Modulo,
+                expression: "int(b[0]) % 10".to_string(),
+            }),
+            validity: vec![ValidityPeriod {
+                kind: ValidityKind::CertificateLifetime,
+                parameter: "NotAfter".to_string(),
+                seconds: 34_387_200,
+                expression: "time.Now().Add(398 * 24 * time.Hour)".to_string(),
+            }],
+            callback: Some(CallbackRegistration {
+                kind: CallbackKind::HttpHandler,
+                registrar: "net/http.ServeMux.HandleFunc".to_string(),
+                handler: "Server.handleLogin".to_string(),
+                pattern: Some("/login".to_string()),
+                line: 12,
+            }),
+            remediation_effort: Some(RemediationEffort::SignatureChange),
+            agility: Some(FindingAgility {
+                algorithm: AgilityClass::HardcodedLiteral,
+                parameters: BTreeMap::from([(
+                    "arg2".to_string(),
+                    AgilityClass::CompileTimeConstant,
+                )]),
+                constants: BTreeMap::from([(
+                    "arg2".to_string(),
+                    vec![ConstantRef {
+                        package: "config".to_string(),
+                        name: "PBKDF2Iterations".to_string(),
+                    }],
+                )]),
+            }),
+            wrapper: Some(WrapperLink {
+                role: WrapperRole::Definition,
+                sites: vec![WrapperSite {
+                    file: "api/login.go".to_string(),
+                    line: 30,
+                    column: 12,
+                    function: "example.com/app/internal/kdf.Derive".to_string(),
+                }],
+            }),
+            blame: Some(Blame {
+                commit: "3f786850e387550fdab836ed7e6dc881de23001b".to_string(),
+                author: "Jane Doe".to_string(),
+                email: Some("jane@example.com".to_string()),
+                committed_at: 1_600_000_000,
+                age_days: 400,
+            }),
+        }
+    }
+
+    #[test]
+    fn test_schemas_are_valid_json_with_matching_ids() {
+        assert_eq!(SCHEMAS.len(), SCHEMA_NAMES.len());
+        for name in SCHEMA_NAMES {
+            let schema = parse(name);
+            let id = schema["$id"].as_str().unwrap();
+            assert!(id.ends_with(&format!("/{name}.schema.json")), "{id}");
+        }
+    }
+
+    #[test]
+    fn test_findings_schema_matches_report() {
+        let schema = parse("findings");
+
+        let report = JsonOutput {
+            go_version: Some("1.22".to_string()),
+            analysis_status: Some(AnalysisStatus::Ok),
+            files_scanned: 1,
+            total_findings: 1,
+            total_configs: 0,
+            findings: vec![finding()],
+            configs: Vec::new(),
+            packages: vec![PackageStatus {
+                package: "app".to_string(),
+                status: AnalysisStatus::Partial,
+                files_analyzed: 1,
+                files_failed: 1,
+                errors: vec!["syntax error".to_string()],
+            }],
+            vulnerabilities: Vec::new(),
+            fips: None,
+            key_mismatches: KeyMismatch::detect(&[finding()]),
+            nonce_overflows: NonceOverflow::detect(&[finding()]),
+            agility: PackageAgility::scorecard(&[finding()]),
+            constant_usage: ConstantUsage::index(&[finding()]),
+            operations: CryptoOperation::group(&[
+                finding(),
+                Finding {
+                    line: 9,
+                    ..finding()
+                },
+            ]),
+            duplicate_operations: DuplicateOperation::detect(&[
+                finding(),
+                Finding {
+                    line: 9,
+                    ..finding()
+                },
+                Finding {
+                    file: "other.go".to_string(),
+                    ..finding()
+                },
+                Finding {
+                    file: "other.go".to_string(),
+                    line: 9,
+                    ..finding()
+                },
+            ]),
+            generator_settings: vec![GeneratorSetting {
+                generator: "sqlc".to_string(),
+                source: "sqlc.yaml:5".to_string(),
+                name: "sslmode".to_string(),
+                value: "disable".to_string(),
+                kind: GeneratorSettingKind::TlsMode,
+            }],
+            modules: vec![ModuleScan {
+                dir: "tests/fixtures/kdf".to_string(),
+                module: Some("example.com/fixtures/kdf".to_string()),
+                findings: 1,
+            }],
+            manifest: Some(ScanManifest {
+                tool: ToolInfo::current(),
+                arguments: vec!["--preset".to_string(), "crypto".to_string()],
+                settings: BTreeMap::from([("language".to_string(), "go".to_string())]),
+                config_hash: "0f1e".to_string(),
+                commit: Some("0123456789abcdef".to_string()),
+                go_toolchain: Some("go1.22.5".to_string()),
+                catalogs: vec![CatalogVersion {
+                    name: "crypto".to_string(),
+                    version: Some("1.0.0".to_string()),
+                    sha256: "9a8b".to_string(),
+                }],
+                modules: BTreeMap::from([(
+                    "golang.org/x/crypto".to_string(),
+                    "v0.21.0".to_string(),
+                )]),
+            }),
+            repository: Some(Repository {
+                vcs: "git".to_string(),
+                remote: Some("https://github.com/acme/app.git".to_string()),
+                branch: Some("main".to_string()),
+                commit: Some("0123456789abcdef".to_string()),
+            }),
+        };
+        let value = serde_json::to_value(&report).unwrap();
+
+        for (pointer, value) in [
+            ("", &value),
+            ("/$defs/finding", &value["findings"][0]),
+            ("/$defs/packageStatus", &value["packages"][0]),
+            (
+                "/$defs/failurePath",
+                &value["findings"][0]["failure_paths"][0],
+            ),
+            ("/$defs/crashPath", &value["findings"][0]["crash_paths"][0]),
+            (
+                "/$defs/zeroFallback",
+                &value["findings"][0]["zero_fallbacks"][0],
+            ),
+            ("/$defs/selection", &value["findings"][0]["selection"]),
+            (
+                "/$defs/selectionOption",
+                &value["findings"][0]["selection"]["options"][0],
+            ),
+            ("/$defs/byteSource", &value["findings"][0]["secret"]),
+            ("/$defs/byteSource", &value["findings"][0]["salt"]),
+            ("/$defs/byteSource", &value["findings"][0]["key"]),
+            ("/$defs/byteSource", &value["findings"][0]["nonce"]),
+            ("/$defs/keyEncoding", &value["findings"][0]["key_encoding"]),
+            ("/$defs/keyExchange", &value["findings"][0]["key_exchange"]),
+            ("/$defs/keyMismatch", &value["key_mismatches"][0]),
+            (
+                "/$defs/nonceCounter",
+                &value["findings"][0]["nonce_counter"],
+            ),
+            (
+                "/$defs/passwordStorage",
+                &value["findings"][0]["password_storage"],
+            ),
+            (
+                "/$defs/secretComparison",
+                &value["findings"][0]["secret_comparison"],
+            ),
+            (
+                "/$defs/iterationTuning",
+                &value["findings"][0]["iteration_tuning"],
+            ),
+            (
+                "/$defs/longLivedAead",
+                &value["findings"][0]["long_lived_aead"],
+            ),
+            ("/$defs/kmsOperation", &value["findings"][0]["kms"]),
+            ("/$defs/pkcs11Operation", &value["findings"][0]["pkcs11"]),
+            (
+                "/$defs/unauthenticatedMode",
+                &value["findings"][0]["unauthenticated_mode"],
+            ),
+            ("/$defs/randomBias", &value["findings"][0]["random_bias"]),
+            (
+                "/$defs/validityPeriod",
+                &value["findings"][0]["validity"][0],
+            ),
+            (
+                "/$defs/callbackRegistration",
+                &value["findings"][0]["callback"],
+            ),
+            ("/$defs/nonceOverflow", &value["nonce_overflows"][0]),
+            ("/$defs/findingAgility", &value["findings"][0]["agility"]),
+            ("/$defs/packageAgility", &value["agility"][0]),
+            (
+                "/$defs/constantRef",
+                &value["findings"][0]["agility"]["constants"]["arg2"][0],
+            ),
+            ("/$defs/constantUsage", &value["constant_usage"][0]),
+            (
+                "/$defs/constantSink",
+                &value["constant_usage"][0]["sinks"][0],
+            ),
+            ("/$defs/cryptoOperation", &value["operations"][0]),
+            ("/$defs/operationStep", &value["operations"][0]["steps"][0]),
+            (
+                "/$defs/duplicateOperation",
+                &value["duplicate_operations"][0],
+            ),
+            (
+                "/$defs/implementation",
+                &value["duplicate_operations"][0]["implementations"][0],
+            ),
+            ("/$defs/generatorSetting", &value["generator_settings"][0]),
+            ("/$defs/moduleScan", &value["modules"][0]),
+            ("/$defs/scanManifest", &value["manifest"]),
+            ("/$defs/catalogVersion", &value["manifest"]["catalogs"][0]),
+            ("/$defs/wrapperLink", &value["findings"][0]["wrapper"]),
+            (
+                "/$defs/wrapperSite",
+                &value["findings"][0]["wrapper"]["sites"][0],
+            ),
+            ("/$defs/blame", &value["findings"][0]["blame"]),
+            ("/$defs/repository", &value["repository"]),
+        ] {
+            let (undeclared, missing) = drift(&schema, pointer, value);
+            assert!(
+                undeclared.is_empty(),
+                "{pointer}: undeclared {undeclared:?}"
+            );
+            assert!(missing.is_empty(), "{pointer}: missing {missing:?}");
+        }
+    }
+}
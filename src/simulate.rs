@@ -0,0 +1,243 @@
+//! What-if simulation of constant changes, for `argflow simulate`.
+
+use std::collections::{BTreeMap, HashSet};
+use std::fmt::Write as _;
+
+use serde::Serialize;
+use serde_json::Value;
+
+use crate::output::{Finding, Redactions};
+use crate::policy::{self, GateOptions, Policy, Violation};
+
+/// An argument whose resolved value the overrides change.
+#[derive(Debug, Clone, Serialize)]
+pub struct ParameterChange {
+    pub file: String,
+    pub line: usize,
+    pub column: usize,
+    pub function: String,
+    pub parameter: String,
+    pub before: Value,
+    pub after: Value,
+}
+
+/// Policy violations of the project as written against those with the overrides.
+#[derive(Debug, Clone, Serialize)]
+pub struct PolicyDelta {
+    /// Whether the gate passes as written.
+    pub passed_before: bool,
+    /// Whether the gate would pass with the overrides.
+    pub passed_after: bool,
+    pub cleared: Vec<Violation>,
+    pub introduced: Vec<Violation>,
+    /// Violations the overrides do not affect.
+    pub remaining: usize,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct SimulationReport {
+    /// `package.Name` to the value substituted for it, as given.
+    pub overrides: BTreeMap<String, String>,
+    pub changes: Vec<ParameterChange>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub policy: Option<PolicyDelta>,
+}
+
+impl SimulationReport {
+    /// Compares the findings of the project as written with those of the simulated scan.
+    /// Findings are matched by location and call; arguments are compared by name.
+    pub fn compare(
+        overrides: &[(String, String)],
+        actual: &[Finding],
+        simulated: &[Finding],
+    ) -> Self {
+        let key = |f: &Finding| (f.file.clone(), f.line, f.column, f.full_name.clone());
+        let actual: BTreeMap<_, &Finding> = actual.iter().map(|f| (key(f), f)).collect();
+
+        let mut changes = Vec::new();
+        for after in simulated {
+            let Some(before) = actual.get(&key(after)) else {
+                continue;
+            };
+            for (parameter, value) in &after.parameters {
+                let previous = before.parameters.get(parameter).unwrap_or(&Value::Null);
+                if previous != value {
+                    changes.push(ParameterChange {
+                        file: after.file.clone(),
+                        line: after.line,
+                        column: after.column,
+                        function: after.full_name.clone(),
+                        parameter: parameter.clone(),
+                        before: previous.clone(),
+                        after: value.clone(),
+                    });
+                }
+            }
+        }
+
+        SimulationReport {
+            overrides: overrides.iter().cloned().collect(),
+            changes,
+            policy: None,
+        }
+    }
+
+    /// Evaluates `policy` against both scans and records the violations that differ.
+    /// Violations are matched by fingerprint, which does not depend on argument values.
+    pub fn evaluate_policy(
+        &mut self,
+        policy: &Policy,
+        actual: &[Finding],
+        simulated: &[Finding],
+        options: &GateOptions,
+    ) {
+        let before = policy::evaluate(policy, actual, options);
+        let after = policy::evaluate(policy, simulated, options);
+        let fingerprints = |violations: &[Violation]| -> HashSet<String> {
+            violations.iter().map(|v| v.fingerprint.clone()).collect()
+        };
+        let (was, now) = (
+            fingerprints(&before.violations),
+            fingerprints(&after.violations),
+        );
+
+        self.policy = Some(PolicyDelta {
+            passed_before: before.passed,
+            passed_after: after.passed,
+            remaining: after
+                .violations
+                .iter()
+                .filter(|v| was.contains(&v.fingerprint))
+                .count(),
+            cleared: before
+                .violations
+                .into_iter()
+                .filter(|v| !now.contains(&v.fingerprint))
+                .collect(),
+            introduced: after
+                .violations
+                .into_iter()
+                .filter(|v| !was.contains(&v.fingerprint))
+                .collect(),
+        });
+    }
+
+    /// Redacts hardcoded secrets from the compared values and violation messages.
+    pub fn redact(&mut self, redactions: &Redactions) {
+        for change in &mut self.changes {
+            redactions.redact_value(&mut change.before);
+            redactions.redact_value(&mut change.after);
+        }
+        if let Some(delta) = &mut self.policy {
+            for violation in delta.cleared.iter_mut().chain(&mut delta.introduced) {
+                violation.redact(redactions);
+            }
+        }
+    }
+
+    pub fn render_text(&self) -> String {
+        let mut out = String::new();
+        let overrides: Vec<_> = self
+            .overrides
+            .iter()
+            .map(|(name, value)| format!("{name}={value}"))
+            .collect();
+        let _ = writeln!(out, "argflow simulate: {}", overrides.join(", "));
+
+        if self.changes.is_empty() {
+            let _ = writeln!(out, "\nNo resolved argument changes.");
+        } else {
+            let _ = writeln!(out, "\nChanged arguments:");
+            for change in &self.changes {
+                let _ = writeln!(
+                    out,
+                    "  {}:{}:{} {} {}: {} -> {}",
+                    change.file,
+                    change.line,
+                    change.column,
+                    change.function,
+                    change.parameter,
+                    change.before,
+                    change.after
+                );
+            }
+        }
+
+        if let Some(delta) = &self.policy {
+            let verdict = |passed: bool| if passed { "PASSED" } else { "FAILED" };
+            let _ = writeln!(
+                out,
+                "\nGate: {} -> {} ({} cleared, {} introduced, {} remaining)",
+                verdict(delta.passed_before),
+                verdict(delta.passed_after),
+                delta.cleared.len(),
+                delta.introduced.len(),
+                delta.remaining
+            );
+            for (marker, violations) in [("-", &delta.cleared), ("+", &delta.introduced)] {
+                for violation in violations {
+                    let _ = writeln!(
+                        out,
+                        "  {marker} [{}] {}: {}:{}:{} {}",
+                        violation.severity.as_str(),
+                        violation.rule,
+                        violation.file,
+                        violation.line,
+                        violation.column,
+                        violation.function
+                    );
+                }
+            }
+        }
+        out
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use serde_json::json;
+
+    fn pbkdf2(file: &str, iterations: i64) -> Finding {
+        Finding::call(file, 12, 9, "golang.org/x/crypto/pbkdf2.Key")
+            .with_package("pbkdf2")
+            .with_import_path("golang.org/x/crypto/pbkdf2")
+            .with_algorithm("PBKDF2")
+            .with_parameter("arg2", json!(iterations))
+            .with_raw_text("pbkdf2.Key(pw, salt, config.PBKDF2Iterations, 32, sha256.New)")
+    }
+
+    #[test]
+    fn test_compare_and_policy_delta() {
+        let overrides = [("config.PBKDF2Iterations".to_string(), "600000".to_string())];
+        let actual = [
+            pbkdf2("auth/login.go", 10000),
+            pbkdf2("auth/legacy.go", 4096),
+        ];
+        let simulated = [
+            pbkdf2("auth/login.go", 600000),
+            pbkdf2("auth/legacy.go", 4096),
+        ];
+
+        let mut report = SimulationReport::compare(&overrides, &actual, &simulated);
+        assert_eq!(report.changes.len(), 1);
+        assert_eq!(report.changes[0].file, "auth/login.go");
+        assert_eq!(report.changes[0].before, json!(10000));
+        assert_eq!(report.changes[0].after, json!(600000));
+
+        let policy: Policy = serde_json::from_value(json!({
+            "rules": [{"id": "pbkdf2-iterations", "severity": "error",
+                       "match": {"function": "golang.org/x/crypto/pbkdf2.Key"},
+                       "parameter": {"name": "arg2", "min": 600000}}]
+        }))
+        .unwrap();
+        report.evaluate_policy(&policy, &actual, &simulated, &GateOptions::default());
+
+        let delta = report.policy.as_ref().unwrap();
+        assert!(!delta.passed_before && !delta.passed_after);
+        assert_eq!(delta.cleared.len(), 1);
+        assert_eq!(delta.cleared[0].file, "auth/login.go");
+        assert!(delta.introduced.is_empty());
+        assert_eq!(delta.remaining, 1);
+    }
+}
@@ -0,0 +1,511 @@
+//! Sink catalogs checked against the packages they describe, for `argflow sinks verify`.
+
+use std::collections::HashMap;
+use std::fmt::Write as _;
+use std::fs;
+use std::path::{Path, PathBuf};
+
+use serde::Serialize;
+use tree_sitter::{Node, Parser};
+
+use crate::discovery::languages::go::gomod::{find_go_mod, GoMod, Require};
+use crate::discovery::languages::go::GoVersion;
+
+/// The versions of the packages a Go module builds against.
+#[derive(Debug, Clone, Default)]
+pub struct PackageVersions {
+    /// Go version of the standard library.
+    pub go_version: Option<GoVersion>,
+    pub requires: Vec<Require>,
+}
+
+/// Where a package's source comes from.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum PackageOrigin<'a> {
+    Stdlib,
+    Module(&'a Require),
+}
+
+impl PackageVersions {
+    /// Versions from the nearest `go.mod` at or above `start`; `go_version` overrides its
+    /// `go` directive.
+    pub fn detect(start: &Path, go_version: Option<GoVersion>) -> Self {
+        let go_mod = find_go_mod(start).and_then(|path| GoMod::from_file(&path).ok());
+        PackageVersions {
+            go_version: go_version.or(go_mod.as_ref().and_then(|m| m.go_version)),
+            requires: go_mod.map(|m| m.requires).unwrap_or_default(),
+        }
+    }
+
+    /// The origin of `import_path` and the path of its package below the origin's root.
+    /// Module paths match case-insensitively, as sink catalogs are keyed lowercased.
+    pub fn origin(&self, import_path: &str) -> Option<(PackageOrigin<'_>, String)> {
+        let first = import_path.split('/').next().unwrap_or_default();
+        if !first.contains('.') {
+            return Some((PackageOrigin::Stdlib, import_path.to_string()));
+        }
+        let lower = import_path.to_lowercase();
+        self.requires
+            .iter()
+            .filter_map(|require| {
+                let module = require.path.to_lowercase();
+                let subpath = lower.strip_prefix(&module)?;
+                match subpath.strip_prefix('/') {
+                    Some(subpath) => Some((require, subpath.to_string())),
+                    None if subpath.is_empty() => Some((require, String::new())),
+                    None => None,
+                }
+            })
+            .max_by_key(|(require, _)| require.path.len())
+            .map(|(require, subpath)| (PackageOrigin::Module(require), subpath))
+    }
+
+    /// The version of the package a sink key (`import/path` or `import/path.Type`)
+    /// belongs to: `go1.24` for the standard library, the required module version
+    /// otherwise.
+    pub fn version_of(&self, key: &str) -> Option<String> {
+        let (origin, _) = split_key(key)
+            .into_iter()
+            .find_map(|(import_path, _)| self.origin(import_path))?;
+        match origin {
+            PackageOrigin::Stdlib => self.go_version.map(|version| format!("go{version}")),
+            PackageOrigin::Module(require) => Some(require.version.clone()),
+        }
+    }
+}
+
+/// The ways to read a sink key: a package, or a package and the receiver type after the
+/// last `.` of its final path element.
+fn split_key(key: &str) -> Vec<(&str, Option<&str>)> {
+    let mut readings = vec![(key, None)];
+    let last = key.rfind('/').map_or(0, |slash| slash + 1);
+    if let Some(dot) = key[last..].rfind('.') {
+        let (package, receiver) = key.split_at(last + dot);
+        readings.push((package, Some(&receiver[1..])));
+    }
+    readings
+}
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum DriftKind {
+    /// The module is required but has no such package.
+    PackageNotFound,
+    /// The package no longer declares the function or method.
+    FunctionNotFound,
+    /// The catalog names more or fewer arguments than the signature has.
+    ParameterCount,
+}
+
+/// A sink whose catalog roles do not fit the signature in the version in use.
+#[derive(Debug, Clone, Serialize)]
+pub struct SignatureDrift {
+    /// `import/path.Function` or `import/path.Type.Method`, lowercased as catalogs key it.
+    pub sink: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub version: Option<String>,
+    pub kind: DriftKind,
+    pub roles: Vec<String>,
+    /// Parameter names the loaded package declares, `_` for unnamed ones.
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub parameters: Vec<String>,
+    pub message: String,
+}
+
+#[derive(Debug, Clone, Default, Serialize)]
+pub struct SinkVerification {
+    /// Sinks whose package was found and compared.
+    pub checked: usize,
+    /// Sinks of packages that are neither in the standard library nor required.
+    pub skipped: usize,
+    pub drift: Vec<SignatureDrift>,
+}
+
+impl SinkVerification {
+    /// Checks the positional `roles` of every sink, keyed by lowercased import path and
+    /// function as `RulesClassifier::get_parameter_roles` returns them. `locate` returns
+    /// the source root of an origin: `GOROOT/src` or the module's directory.
+    pub fn verify(
+        roles: &HashMap<String, HashMap<String, Vec<String>>>,
+        versions: &PackageVersions,
+        locate: impl Fn(PackageOrigin) -> Option<PathBuf>,
+    ) -> Self {
+        let mut roots: HashMap<Option<String>, Option<PathBuf>> = HashMap::new();
+        let mut declarations: HashMap<PathBuf, Vec<Declaration>> = HashMap::new();
+        let mut verification = SinkVerification::default();
+
+        let mut keys: Vec<&String> = roles.keys().collect();
+        keys.sort();
+        for key in keys {
+            // Rust sinks share the catalogs; Python and JavaScript modules read as standard
+            // library paths and are skipped when GOROOT has no such package
+            if key.contains("::") {
+                continue;
+            }
+            let mut required = false;
+            let package = split_key(key)
+                .into_iter()
+                .find_map(|(import_path, receiver)| {
+                    let (origin, subpath) = versions.origin(import_path)?;
+                    let module = match origin {
+                        PackageOrigin::Stdlib => None,
+                        PackageOrigin::Module(require) => Some(require.path.clone()),
+                    };
+                    required |= module.is_some();
+                    let root = roots
+                        .entry(module)
+                        .or_insert_with(|| locate(origin))
+                        .clone()?;
+                    Some((find_dir(&root, &subpath)?, receiver))
+                });
+            let functions = &roles[key];
+            let (dir, receiver) = match package {
+                Some((dir, receiver)) => (Some(dir), receiver),
+                None if required => (None, None),
+                None => {
+                    verification.skipped += functions.len();
+                    continue;
+                }
+            };
+            let version = versions.version_of(key);
+
+            let mut names: Vec<&String> = functions.keys().collect();
+            names.sort();
+            for function in names {
+                let roles = &functions[function];
+                let sink = format!("{key}.{function}");
+                verification.checked += 1;
+                let Some(dir) = &dir else {
+                    verification.drift.push(SignatureDrift {
+                        message: format!(
+                            "{sink}: package not found in {}",
+                            version.as_deref().unwrap_or("the required module")
+                        ),
+                        sink,
+                        version: version.clone(),
+                        kind: DriftKind::PackageNotFound,
+                        roles: roles.clone(),
+                        parameters: Vec::new(),
+                    });
+                    continue;
+                };
+                let declared = declarations
+                    .entry(dir.clone())
+                    .or_insert_with(|| declarations_in(dir));
+                let found = declared.iter().find(|declaration| {
+                    declaration.name.eq_ignore_ascii_case(function)
+                        && declaration
+                            .receiver
+                            .as_deref()
+                            .map(str::to_lowercase)
+                            .as_deref()
+                            == receiver
+                });
+                let in_version = version
+                    .as_deref()
+                    .map(|version| format!(" in {version}"))
+                    .unwrap_or_default();
+                match found {
+                    None => verification.drift.push(SignatureDrift {
+                        message: format!("{sink}: not declared{in_version}"),
+                        sink,
+                        version: version.clone(),
+                        kind: DriftKind::FunctionNotFound,
+                        roles: roles.clone(),
+                        parameters: Vec::new(),
+                    }),
+                    Some(declaration) if declaration.parameters.len() != roles.len() => {
+                        verification.drift.push(SignatureDrift {
+                            message: format!(
+                                "{sink}: catalog names {} argument(s) ({}) but the signature{in_version} has {} ({}); add a parameter_versions entry",
+                                roles.len(),
+                                roles.join(", "),
+                                declaration.parameters.len(),
+                                declaration.parameters.join(", "),
+                            ),
+                            sink,
+                            version: version.clone(),
+                            kind: DriftKind::ParameterCount,
+                            roles: roles.clone(),
+                            parameters: declaration.parameters.clone(),
+                        })
+                    }
+                    Some(_) => {}
+                }
+            }
+        }
+        verification
+    }
+
+    pub fn render_text(&self) -> String {
+        let mut out = String::new();
+        let _ = writeln!(
+            out,
+            "Checked {} sink signature(s), {} skipped (package not in use), {} drifted",
+            self.checked,
+            self.skipped,
+            self.drift.len()
+        );
+        for drift in &self.drift {
+            let _ = writeln!(out, "  {}", drift.message);
+        }
+        out
+    }
+}
+
+/// A function or method declared in a package.
+#[derive(Debug, Clone, PartialEq, Eq)]
+struct Declaration {
+    name: String,
+    /// Receiver type of a method, without `*` or type arguments.
+    receiver: Option<String>,
+    parameters: Vec<String>,
+}
+
+/// `root/subpath`, matching each path element case-insensitively.
+fn find_dir(root: &Path, subpath: &str) -> Option<PathBuf> {
+    let mut dir = root.to_path_buf();
+    for element in subpath.split('/').filter(|element| !element.is_empty()) {
+        let exact = dir.join(element);
+        dir = if exact.is_dir() {
+            exact
+        } else {
+            fs::read_dir(&dir)
+                .ok()?
+                .filter_map(|entry| entry.ok())
+                .map(|entry| entry.path())
+                .find(|path| {
+                    path.is_dir()
+                        && path
+                            .file_name()
+                            .and_then(|name| name.to_str())
+                            .is_some_and(|name| name.eq_ignore_ascii_case(element))
+                })?
+        };
+    }
+    Some(dir)
+}
+
+/// Functions and methods declared by the non-test Go files of `dir`.
+fn declarations_in(dir: &Path) -> Vec<Declaration> {
+    let mut parser = Parser::new();
+    if parser
+        .set_language(&tree_sitter_go::LANGUAGE.into())
+        .is_err()
+    {
+        return Vec::new();
+    }
+    let Ok(entries) = fs::read_dir(dir) else {
+        return Vec::new();
+    };
+    let mut files: Vec<PathBuf> = entries
+        .filter_map(|entry| entry.ok())
+        .map(|entry| entry.path())
+        .filter(|path| {
+            path.is_file()
+                && path
+                    .file_name()
+                    .and_then(|name| name.to_str())
+                    .is_some_and(|name| name.ends_with(".go") && !name.ends_with("_test.go"))
+        })
+        .collect();
+    files.sort();
+
+    let mut declarations = Vec::new();
+    for file in files {
+        let Ok(source) = fs::read(&file) else {
+            continue;
+        };
+        let Some(tree) = parser.parse(&source, None) else {
+            continue;
+        };
+        let root = tree.root_node();
+        let mut cursor = root.walk();
+        for node in root.named_children(&mut cursor) {
+            if let Some(declaration) = declaration(node, &source) {
+                declarations.push(declaration);
+            }
+        }
+    }
+    declarations
+}
+
+fn declaration(node: Node, source: &[u8]) -> Option<Declaration> {
+    let text = |node: Node| node.utf8_text(source).unwrap_or_default().to_string();
+    let receiver = match node.kind() {
+        "function_declaration" => None,
+        "method_declaration" => {
+            let receiver = node.child_by_field_name("receiver")?.named_child(0)?;
+            let receiver_type = text(receiver.child_by_field_name("type")?);
+            let receiver_type = receiver_type.trim_start_matches('*');
+            Some(
+                receiver_type
+                    .split('[')
+                    .next()
+                    .unwrap_or(receiver_type)
+                    .trim()
+                    .to_string(),
+            )
+        }
+        _ => return None,
+    };
+    let list = node.child_by_field_name("parameters")?;
+    let mut cursor = list.walk();
+    let mut parameters = Vec::new();
+    for parameter in list.named_children(&mut cursor) {
+        match parameter.kind() {
+            "parameter_declaration" => {
+                let mut names_cursor = parameter.walk();
+                let names: Vec<String> = parameter
+                    .children_by_field_name("name", &mut names_cursor)
+                    .map(text)
+                    .collect();
+                if names.is_empty() {
+                    parameters.push("_".to_string());
+                } else {
+                    parameters.extend(names);
+                }
+            }
+            "variadic_parameter_declaration" => parameters.push(
+                parameter
+                    .child_by_field_name("name")
+                    .map_or_else(|| "_".to_string(), text),
+            ),
+            _ => {}
+        }
+    }
+    Some(Declaration {
+        name: text(node.child_by_field_name("name")?),
+        receiver,
+        parameters,
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn versions() -> PackageVersions {
+        PackageVersions {
+            go_version: Some(GoVersion::new(1, 24, 0)),
+            requires: vec![
+                Require {
+                    path: "github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azkeys"
+                        .to_string(),
+                    version: "v1.3.0".to_string(),
+                },
+                Require {
+                    path: "github.com/miekg/pkcs11".to_string(),
+                    version: "v1.1.1".to_string(),
+                },
+            ],
+        }
+    }
+
+    #[test]
+    fn test_version_of_sink_keys() {
+        let versions = versions();
+        assert_eq!(
+            versions.version_of("crypto/pbkdf2").as_deref(),
+            Some("go1.24")
+        );
+        assert_eq!(versions.version_of("hash.hash").as_deref(), Some("go1.24"));
+        assert_eq!(
+            versions
+                .version_of("github.com/azure/azure-sdk-for-go/sdk/security/keyvault/azkeys.client")
+                .as_deref(),
+            Some("v1.3.0")
+        );
+        assert_eq!(
+            versions
+                .version_of("github.com/miekg/pkcs11.ctx")
+                .as_deref(),
+            Some("v1.1.1")
+        );
+        assert_eq!(versions.version_of("golang.org/x/crypto/scrypt"), None);
+    }
+
+    #[test]
+    fn test_verify_reports_drifted_signatures() {
+        let root = tempfile::tempdir().unwrap();
+        let module = root.path().join("pkcs11");
+        fs::create_dir_all(&module).unwrap();
+        fs::write(
+            module.join("pkcs11.go"),
+            r#"package pkcs11
+
+type Ctx struct{}
+
+func (c *Ctx) EncryptInit(sh SessionHandle, m []*Mechanism, o ObjectHandle) error { return nil }
+
+func (c *Ctx) DigestInit(sh SessionHandle, m []*Mechanism, opts ...Option) error { return nil }
+
+func New(module string) *Ctx { return nil }
+"#,
+        )
+        .unwrap();
+        fs::write(
+            module.join("pkcs11_test.go"),
+            "package pkcs11\n\nfunc New() {}\n",
+        )
+        .unwrap();
+
+        let roles: HashMap<String, HashMap<String, Vec<String>>> = HashMap::from([
+            (
+                "github.com/miekg/pkcs11.ctx".to_string(),
+                HashMap::from([
+                    (
+                        "encryptinit".to_string(),
+                        vec!["session".into(), "mechanism".into(), "key".into()],
+                    ),
+                    (
+                        "digestinit".to_string(),
+                        vec!["session".into(), "mechanism".into()],
+                    ),
+                    ("signinit".to_string(), vec!["session".into()]),
+                ]),
+            ),
+            (
+                "github.com/miekg/pkcs11".to_string(),
+                HashMap::from([("new".to_string(), vec!["module".into()])]),
+            ),
+            (
+                "golang.org/x/crypto/scrypt".to_string(),
+                HashMap::from([("key".to_string(), vec!["password".into()])]),
+            ),
+            (
+                "hashlib".to_string(),
+                HashMap::from([("pbkdf2_hmac".to_string(), vec!["hash_name".into()])]),
+            ),
+        ]);
+        let stdlib = root.path().join("goroot");
+        fs::create_dir_all(&stdlib).unwrap();
+        let verification = SinkVerification::verify(&roles, &versions(), |origin| match origin {
+            PackageOrigin::Stdlib => Some(stdlib.clone()),
+            PackageOrigin::Module(_) => Some(module.clone()),
+        });
+
+        assert_eq!(verification.checked, 4);
+        assert_eq!(verification.skipped, 2);
+        let drift: Vec<_> = verification
+            .drift
+            .iter()
+            .map(|drift| (drift.sink.as_str(), drift.kind))
+            .collect();
+        assert_eq!(
+            drift,
+            vec![
+                (
+                    "github.com/miekg/pkcs11.ctx.digestinit",
+                    DriftKind::ParameterCount
+                ),
+                (
+                    "github.com/miekg/pkcs11.ctx.signinit",
+                    DriftKind::FunctionNotFound
+                ),
+            ]
+        );
+        assert_eq!(verification.drift[0].parameters, vec!["sh", "m", "opts"]);
+        assert!(verification.drift[0].message.contains("in v1.1.1"));
+    }
+}
@@ -0,0 +1,277 @@
+//! OpenTelemetry traces and metrics for scan runs, exported over OTLP/HTTP.
+
+mod otlp;
+
+use std::cell::RefCell;
+use std::time::SystemTime;
+
+use tracing::debug;
+
+use crate::error::TelemetryError;
+
+pub use otlp::{export, metrics_payload, traces_payload};
+
+pub const DEFAULT_SERVICE_NAME: &str = "argflow";
+
+/// Where and how to export, following the standard `OTEL_*` environment variables.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct OtlpConfig {
+    /// Base URL; `/v1/traces` and `/v1/metrics` are appended.
+    pub endpoint: String,
+    pub headers: Vec<(String, String)>,
+    pub service_name: String,
+}
+
+impl OtlpConfig {
+    /// Resolves the exporter config. `endpoint` (from `--otlp-endpoint`) takes precedence
+    /// over `OTEL_EXPORTER_OTLP_ENDPOINT`; returns `None` when neither is set.
+    pub fn resolve(
+        endpoint: Option<&str>,
+        env: &dyn Fn(&str) -> Option<String>,
+    ) -> Result<Option<Self>, TelemetryError> {
+        let Some(endpoint) = endpoint
+            .map(str::to_string)
+            .or_else(|| env("OTEL_EXPORTER_OTLP_ENDPOINT"))
+            .filter(|e| !e.is_empty())
+        else {
+            return Ok(None);
+        };
+
+        let headers = match env("OTEL_EXPORTER_OTLP_HEADERS") {
+            Some(value) => parse_headers(&value)?,
+            None => Vec::new(),
+        };
+        let service_name = env("OTEL_SERVICE_NAME")
+            .filter(|s| !s.is_empty())
+            .unwrap_or_else(|| DEFAULT_SERVICE_NAME.to_string());
+
+        Ok(Some(Self {
+            endpoint: endpoint.trim_end_matches('/').to_string(),
+            headers,
+            service_name,
+        }))
+    }
+}
+
+/// Parses `OTEL_EXPORTER_OTLP_HEADERS`: comma-separated `key=value` pairs.
+fn parse_headers(value: &str) -> Result<Vec<(String, String)>, TelemetryError> {
+    value
+        .split(',')
+        .map(str::trim)
+        .filter(|pair| !pair.is_empty())
+        .map(|pair| {
+            pair.split_once('=')
+                .map(|(k, v)| (k.trim().to_string(), v.trim().to_string()))
+                .filter(|(k, _)| !k.is_empty())
+                .ok_or_else(|| TelemetryError::InvalidHeader {
+                    header: pair.to_string(),
+                })
+        })
+        .collect()
+}
+
+#[derive(Debug, Clone)]
+pub struct PhaseSpan {
+    pub name: String,
+    pub start: SystemTime,
+    pub end: SystemTime,
+    /// Index of the enclosing phase; `None` for phases directly under the run.
+    pub parent: Option<usize>,
+}
+
+#[derive(Debug, Clone, Copy, PartialEq)]
+pub enum MetricValue {
+    Int(i64),
+    Double(f64),
+}
+
+#[derive(Debug, Clone)]
+pub struct Metric {
+    pub name: &'static str,
+    pub unit: &'static str,
+    pub description: &'static str,
+    pub value: MetricValue,
+}
+
+/// Collects phase timings and metrics for one run. A disabled recorder only runs the
+/// phases it is handed, so call sites need no checks.
+#[derive(Debug)]
+pub struct Telemetry {
+    enabled: bool,
+    started: SystemTime,
+    attributes: RefCell<Vec<(String, String)>>,
+    phases: RefCell<Vec<PhaseSpan>>,
+    /// Phases currently running, innermost last.
+    open: RefCell<Vec<usize>>,
+    metrics: RefCell<Vec<Metric>>,
+}
+
+impl Telemetry {
+    pub fn new(enabled: bool) -> Self {
+        Self {
+            enabled,
+            started: SystemTime::now(),
+            attributes: RefCell::new(Vec::new()),
+            phases: RefCell::new(Vec::new()),
+            open: RefCell::new(Vec::new()),
+            metrics: RefCell::new(Vec::new()),
+        }
+    }
+
+    pub fn disabled() -> Self {
+        Self::new(false)
+    }
+
+    pub fn is_enabled(&self) -> bool {
+        self.enabled
+    }
+
+    pub fn started(&self) -> SystemTime {
+        self.started
+    }
+
+    /// Runs `f` as the named phase, recording its start and end time. Phases started
+    /// inside `f` become its children.
+    pub fn phase<T>(&self, name: &str, f: impl FnOnce() -> T) -> T {
+        if !self.enabled {
+            return f();
+        }
+        let start = SystemTime::now();
+        let index = {
+            let mut phases = self.phases.borrow_mut();
+            phases.push(PhaseSpan {
+                name: name.to_string(),
+                start,
+                end: start,
+                parent: self.open.borrow().last().copied(),
+            });
+            phases.len() - 1
+        };
+        self.open.borrow_mut().push(index);
+        let result = f();
+        self.open.borrow_mut().pop();
+        self.phases.borrow_mut()[index].end = SystemTime::now();
+        result
+    }
+
+    /// Sets an attribute on the run span, e.g. the language or preset.
+    pub fn set_attribute(&self, key: &str, value: impl Into<String>) {
+        if self.enabled {
+            self.attributes
+                .borrow_mut()
+                .push((key.to_string(), value.into()));
+        }
+    }
+
+    pub fn count(&self, name: &'static str, description: &'static str, value: usize) {
+        self.record(Metric {
+            name,
+            unit: "1",
+            description,
+            value: MetricValue::Int(i64::try_from(value).unwrap_or(i64::MAX)),
+        });
+    }
+
+    /// Records `hits / (hits + misses)`; nothing is recorded when there were no lookups.
+    pub fn hit_rate(&self, name: &'static str, description: &'static str, hits: u64, misses: u64) {
+        let lookups = hits + misses;
+        if lookups == 0 {
+            debug!(metric = name, "no cache lookups, skipping hit rate");
+            return;
+        }
+        self.record(Metric {
+            name,
+            unit: "1",
+            description,
+            value: MetricValue::Double(hits as f64 / lookups as f64),
+        });
+    }
+
+    fn record(&self, metric: Metric) {
+        if self.enabled {
+            self.metrics.borrow_mut().push(metric);
+        }
+    }
+
+    pub fn attributes(&self) -> Vec<(String, String)> {
+        self.attributes.borrow().clone()
+    }
+
+    pub fn phases(&self) -> Vec<PhaseSpan> {
+        self.phases.borrow().clone()
+    }
+
+    pub fn metrics(&self) -> Vec<Metric> {
+        self.metrics.borrow().clone()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_resolve_prefers_flag_and_reads_env() {
+        let env = |key: &str| match key {
+            "OTEL_EXPORTER_OTLP_ENDPOINT" => Some("http://env:4318".to_string()),
+            "OTEL_EXPORTER_OTLP_HEADERS" => {
+                Some("authorization=Bearer x, x-team=crypto".to_string())
+            }
+            _ => None,
+        };
+
+        let config = OtlpConfig::resolve(Some("http://collector:4318/"), &env)
+            .unwrap()
+            .unwrap();
+        assert_eq!(config.endpoint, "http://collector:4318");
+        assert_eq!(config.service_name, DEFAULT_SERVICE_NAME);
+        assert_eq!(
+            config.headers,
+            vec![
+                ("authorization".to_string(), "Bearer x".to_string()),
+                ("x-team".to_string(), "crypto".to_string()),
+            ]
+        );
+
+        let config = OtlpConfig::resolve(None, &env).unwrap().unwrap();
+        assert_eq!(config.endpoint, "http://env:4318");
+    }
+
+    #[test]
+    fn test_resolve_without_endpoint_is_disabled() {
+        assert!(OtlpConfig::resolve(None, &|_| None).unwrap().is_none());
+        assert!(parse_headers("novalue").is_err());
+    }
+
+    #[test]
+    fn test_disabled_recorder_only_runs_phases() {
+        let telemetry = Telemetry::disabled();
+        assert_eq!(telemetry.phase("scan", || 42), 42);
+        telemetry.count("argflow.findings", "", 3);
+        assert!(telemetry.phases().is_empty());
+        assert!(telemetry.metrics().is_empty());
+    }
+
+    #[test]
+    fn test_nested_phases() {
+        let telemetry = Telemetry::new(true);
+        telemetry.phase("scan", || {
+            telemetry.phase("discover", || ());
+            telemetry.phase("match", || ());
+        });
+        telemetry.phase("output", || ());
+
+        let parents: Vec<_> = telemetry.phases().iter().map(|p| p.parent).collect();
+        assert_eq!(parents, vec![None, Some(0), Some(0), None]);
+    }
+
+    #[test]
+    fn test_hit_rate() {
+        let telemetry = Telemetry::new(true);
+        telemetry.hit_rate("argflow.cache.hit_rate", "", 0, 0);
+        telemetry.hit_rate("argflow.cache.hit_rate", "", 3, 1);
+        let metrics = telemetry.metrics();
+        assert_eq!(metrics.len(), 1);
+        assert_eq!(metrics[0].value, MetricValue::Double(0.75));
+    }
+}
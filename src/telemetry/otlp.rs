@@ -0,0 +1,263 @@
+use std::time::{Duration, SystemTime, UNIX_EPOCH};
+
+use serde_json::{json, Value};
+use sha2::{Digest, Sha256};
+use tracing::debug;
+
+use super::{Metric, MetricValue, OtlpConfig, Telemetry};
+use crate::error::TelemetryError;
+
+const EXPORT_TIMEOUT: Duration = Duration::from_secs(10);
+
+const SCOPE_NAME: &str = "argflow";
+const RUN_SPAN_NAME: &str = "argflow.run";
+
+/// OTLP span kind `SPAN_KIND_INTERNAL`.
+const SPAN_KIND_INTERNAL: u8 = 1;
+
+/// Posts the run's trace and metrics to the collector.
+pub fn export(telemetry: &Telemetry, config: &OtlpConfig) -> Result<(), TelemetryError> {
+    let client = reqwest::blocking::Client::builder()
+        .timeout(EXPORT_TIMEOUT)
+        .build()
+        .map_err(|e| TelemetryError::export_error(&config.endpoint, e.to_string()))?;
+    let ended = SystemTime::now();
+
+    post(
+        &client,
+        config,
+        "traces",
+        &traces_payload(telemetry, config, ended),
+    )?;
+    post(
+        &client,
+        config,
+        "metrics",
+        &metrics_payload(telemetry, config, ended),
+    )?;
+    Ok(())
+}
+
+fn post(
+    client: &reqwest::blocking::Client,
+    config: &OtlpConfig,
+    signal: &str,
+    payload: &Value,
+) -> Result<(), TelemetryError> {
+    let url = format!("{}/v1/{signal}", config.endpoint);
+    let mut request = client
+        .post(&url)
+        .header(reqwest::header::CONTENT_TYPE, "application/json")
+        .body(payload.to_string());
+    for (key, value) in &config.headers {
+        request = request.header(key, value);
+    }
+    let response = request
+        .send()
+        .map_err(|e| TelemetryError::export_error(&url, e.without_url().to_string()))?;
+    let status = response.status();
+    if !status.is_success() {
+        return Err(TelemetryError::export_error(&url, format!("HTTP {status}")));
+    }
+    debug!(url, "exported telemetry");
+    Ok(())
+}
+
+/// `ExportTraceServiceRequest` with one run span and a child span per phase.
+pub fn traces_payload(telemetry: &Telemetry, config: &OtlpConfig, ended: SystemTime) -> Value {
+    let trace_id = trace_id(telemetry.started());
+    let run_span_id = span_id(&trace_id, 0);
+
+    let mut spans = vec![json!({
+        "traceId": trace_id,
+        "spanId": run_span_id,
+        "name": RUN_SPAN_NAME,
+        "kind": SPAN_KIND_INTERNAL,
+        "startTimeUnixNano": unix_nanos(telemetry.started()),
+        "endTimeUnixNano": unix_nanos(ended),
+        "attributes": attributes(&telemetry.attributes()),
+    })];
+    for (index, phase) in telemetry.phases().iter().enumerate() {
+        spans.push(json!({
+            "traceId": trace_id,
+            "spanId": span_id(&trace_id, index + 1),
+            "parentSpanId": phase
+                .parent
+                .map_or_else(|| run_span_id.clone(), |parent| span_id(&trace_id, parent + 1)),
+            "name": phase.name,
+            "kind": SPAN_KIND_INTERNAL,
+            "startTimeUnixNano": unix_nanos(phase.start),
+            "endTimeUnixNano": unix_nanos(phase.end),
+        }));
+    }
+
+    json!({
+        "resourceSpans": [{
+            "resource": resource(config),
+            "scopeSpans": [{ "scope": scope(), "spans": spans }],
+        }],
+    })
+}
+
+/// `ExportMetricsServiceRequest` with the recorded metrics plus one
+/// `argflow.phase.duration` data point per phase, all as gauges.
+pub fn metrics_payload(telemetry: &Telemetry, config: &OtlpConfig, ended: SystemTime) -> Value {
+    let time = unix_nanos(ended);
+    let run_attributes = attributes(&telemetry.attributes());
+
+    let mut metrics: Vec<Value> = telemetry
+        .metrics()
+        .iter()
+        .map(|metric| gauge(metric, &time, &run_attributes))
+        .collect();
+
+    let durations: Vec<Value> = telemetry
+        .phases()
+        .iter()
+        .map(|phase| {
+            let seconds = phase
+                .end
+                .duration_since(phase.start)
+                .unwrap_or_default()
+                .as_secs_f64();
+            json!({
+                "timeUnixNano": time,
+                "asDouble": seconds,
+                "attributes": attributes(&[("phase".to_string(), phase.name.clone())]),
+            })
+        })
+        .collect();
+    if !durations.is_empty() {
+        metrics.push(json!({
+            "name": "argflow.phase.duration",
+            "unit": "s",
+            "description": "Wall time of each scan phase",
+            "gauge": { "dataPoints": durations },
+        }));
+    }
+
+    json!({
+        "resourceMetrics": [{
+            "resource": resource(config),
+            "scopeMetrics": [{ "scope": scope(), "metrics": metrics }],
+        }],
+    })
+}
+
+fn gauge(metric: &Metric, time: &str, run_attributes: &Value) -> Value {
+    let mut point = json!({ "timeUnixNano": time, "attributes": run_attributes });
+    match metric.value {
+        // OTLP/JSON encodes 64-bit integers as strings
+        MetricValue::Int(value) => point["asInt"] = json!(value.to_string()),
+        MetricValue::Double(value) => point["asDouble"] = json!(value),
+    }
+    json!({
+        "name": metric.name,
+        "unit": metric.unit,
+        "description": metric.description,
+        "gauge": { "dataPoints": [point] },
+    })
+}
+
+fn resource(config: &OtlpConfig) -> Value {
+    json!({
+        "attributes": attributes(&[
+            ("service.name".to_string(), config.service_name.clone()),
+            ("service.version".to_string(), env!("CARGO_PKG_VERSION").to_string()),
+        ]),
+    })
+}
+
+fn scope() -> Value {
+    json!({ "name": SCOPE_NAME, "version": env!("CARGO_PKG_VERSION") })
+}
+
+fn attributes(pairs: &[(String, String)]) -> Value {
+    pairs
+        .iter()
+        .map(|(key, value)| json!({ "key": key, "value": { "stringValue": value } }))
+        .collect()
+}
+
+fn unix_nanos(time: SystemTime) -> String {
+    time.duration_since(UNIX_EPOCH)
+        .unwrap_or_default()
+        .as_nanos()
+        .to_string()
+}
+
+/// 16-byte trace id, hex encoded as OTLP/JSON requires. Derived from the start time and
+/// process id, which is unique enough for one process exporting one trace.
+fn trace_id(started: SystemTime) -> String {
+    let seed = format!("{}:{}", unix_nanos(started), std::process::id());
+    hex_prefix(&Sha256::digest(seed.as_bytes()), 16)
+}
+
+fn span_id(trace_id: &str, index: usize) -> String {
+    let seed = format!("{trace_id}:{index}");
+    hex_prefix(&Sha256::digest(seed.as_bytes()), 8)
+}
+
+fn hex_prefix(bytes: &[u8], len: usize) -> String {
+    bytes[..len].iter().map(|b| format!("{b:02x}")).collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn config() -> OtlpConfig {
+        OtlpConfig {
+            endpoint: "http://localhost:4318".to_string(),
+            headers: Vec::new(),
+            service_name: "argflow".to_string(),
+        }
+    }
+
+    fn telemetry() -> Telemetry {
+        let telemetry = Telemetry::new(true);
+        telemetry.set_attribute("argflow.language", "go");
+        telemetry.phase("scan", || {
+            telemetry.phase("discover", || ());
+        });
+        telemetry.count("argflow.findings", "Crypto API calls found", 7);
+        telemetry
+    }
+
+    #[test]
+    fn test_traces_payload_nests_phases_under_run_span() {
+        let payload = traces_payload(&telemetry(), &config(), SystemTime::now());
+        let spans = payload["resourceSpans"][0]["scopeSpans"][0]["spans"]
+            .as_array()
+            .unwrap();
+
+        assert_eq!(spans.len(), 3);
+        assert_eq!(spans[0]["name"], RUN_SPAN_NAME);
+        assert_eq!(spans[0]["traceId"].as_str().unwrap().len(), 32);
+        assert_eq!(spans[1]["name"], "scan");
+        assert_eq!(spans[1]["parentSpanId"], spans[0]["spanId"]);
+        assert_eq!(spans[2]["name"], "discover");
+        assert_eq!(spans[2]["parentSpanId"], spans[1]["spanId"]);
+        assert_eq!(spans[0]["attributes"][0]["value"]["stringValue"], "go");
+    }
+
+    #[test]
+    fn test_metrics_payload() {
+        let payload = metrics_payload(&telemetry(), &config(), SystemTime::now());
+        let metrics = payload["resourceMetrics"][0]["scopeMetrics"][0]["metrics"]
+            .as_array()
+            .unwrap();
+
+        assert_eq!(metrics[0]["name"], "argflow.findings");
+        assert_eq!(metrics[0]["gauge"]["dataPoints"][0]["asInt"], "7");
+        assert_eq!(metrics[1]["name"], "argflow.phase.duration");
+        assert_eq!(
+            metrics[1]["gauge"]["dataPoints"].as_array().unwrap().len(),
+            2
+        );
+        assert_eq!(
+            payload["resourceMetrics"][0]["resource"]["attributes"][0]["key"],
+            "service.name"
+        );
+    }
+}
@@ -0,0 +1,46 @@
+use std::path::Path;
+use std::process::Command;
+
+const GIT_COMMAND: &str = "git";
+
+/// Runs git in the directory containing `path` and returns its stdout, or stderr on failure.
+pub fn run_git(path: &Path, args: &[&str]) -> Result<String, String> {
+    let output = Command::new(GIT_COMMAND)
+        .args(args)
+        .current_dir(repo_dir(path))
+        .output()
+        .map_err(|e| e.to_string())?;
+
+    if !output.status.success() {
+        return Err(String::from_utf8_lossy(&output.stderr).trim().to_string());
+    }
+
+    Ok(String::from_utf8_lossy(&output.stdout).to_string())
+}
+
+/// The commit checked out in the repository containing `path`, if any.
+pub fn head_commit(path: &Path) -> Option<String> {
+    run_git(path, &["rev-parse", "HEAD"])
+        .ok()
+        .map(|sha| sha.trim().to_string())
+        .filter(|sha| !sha.is_empty())
+}
+
+/// Author of the checked-out commit as `Name <email>`, if any.
+pub fn head_author(path: &Path) -> Option<String> {
+    run_git(path, &["log", "-1", "--format=%an <%ae>"])
+        .ok()
+        .map(|author| author.trim().to_string())
+        .filter(|author| !author.is_empty())
+}
+
+/// Directory to run git in for `path`, which may be a file.
+fn repo_dir(path: &Path) -> &Path {
+    if path.is_dir() {
+        path
+    } else {
+        path.parent()
+            .filter(|p| !p.as_os_str().is_empty())
+            .unwrap_or(Path::new("."))
+    }
+}
@@ -1,3 +1,4 @@
+pub mod git;
 mod string;
 
 pub use string::{extract_last_segment, unquote_string};
@@ -0,0 +1,307 @@
+//! Merging of govulncheck `-json` results into the scan report.
+
+use std::collections::HashMap;
+use std::path::Path;
+use std::process::Command;
+
+use serde::Deserialize;
+use tracing::debug;
+
+use crate::error::VulnDbError;
+use crate::output::{AdvisoryMatch, AdvisoryRef, Finding, JsonOutput, Vulnerability};
+
+use super::osv::Advisory;
+
+const GOVULNCHECK_COMMAND: &str = "govulncheck";
+
+/// One message of the govulncheck JSON stream; each carries exactly one field.
+#[derive(Debug, Deserialize)]
+struct Message {
+    osv: Option<Advisory>,
+    finding: Option<GovulncheckFinding>,
+}
+
+#[derive(Debug, Clone, Deserialize)]
+pub struct GovulncheckFinding {
+    pub osv: String,
+    pub fixed_version: Option<String>,
+    /// The vulnerable symbol first, followed by its callers up to the entry point.
+    #[serde(default)]
+    pub trace: Vec<Frame>,
+}
+
+#[derive(Debug, Clone, Default, Deserialize)]
+pub struct Frame {
+    pub module: String,
+    pub version: Option<String>,
+    pub package: Option<String>,
+    pub function: Option<String>,
+    pub receiver: Option<String>,
+    pub position: Option<Position>,
+}
+
+#[derive(Debug, Clone, Default, Deserialize)]
+pub struct Position {
+    pub filename: String,
+    pub line: usize,
+}
+
+#[derive(Debug, Default)]
+pub struct GovulncheckOutput {
+    pub advisories: Vec<Advisory>,
+    pub findings: Vec<GovulncheckFinding>,
+}
+
+impl GovulncheckOutput {
+    /// Parses the output of `govulncheck -json`, a stream of JSON objects.
+    pub fn parse(content: &str, source: &Path) -> Result<Self, VulnDbError> {
+        let mut output = Self::default();
+        for message in serde_json::Deserializer::from_str(content).into_iter::<Message>() {
+            let message = message.map_err(|e| VulnDbError::parse_error(source, e.to_string()))?;
+            output.advisories.extend(message.osv);
+            output.findings.extend(message.finding);
+        }
+        debug!(
+            advisories = output.advisories.len(),
+            findings = output.findings.len(),
+            "parsed govulncheck output"
+        );
+        Ok(output)
+    }
+
+    pub fn from_file(path: &Path) -> Result<Self, VulnDbError> {
+        let content = std::fs::read_to_string(path)
+            .map_err(|e| VulnDbError::read_error(path, e.to_string()))?;
+        Self::parse(&content, path)
+    }
+
+    /// Runs `govulncheck -json ./...` in `dir`.
+    pub fn run(dir: &Path) -> Result<Self, VulnDbError> {
+        let output = Command::new(GOVULNCHECK_COMMAND)
+            .args(["-json", "./..."])
+            .current_dir(dir)
+            .output()
+            .map_err(|e| VulnDbError::govulncheck_error(dir, e.to_string()))?;
+        if !output.status.success() {
+            return Err(VulnDbError::govulncheck_error(
+                dir,
+                String::from_utf8_lossy(&output.stderr).trim(),
+            ));
+        }
+        Self::parse(&String::from_utf8_lossy(&output.stdout), dir)
+    }
+
+    /// Adds govulncheck's vulnerabilities to the report and marks the findings whose
+    /// call sites lie on a crypto-related vulnerable call path.
+    pub fn merge_into(&self, report: &mut JsonOutput) {
+        let advisories: HashMap<&str, &Advisory> =
+            self.advisories.iter().map(|a| (a.id.as_str(), a)).collect();
+
+        for finding in &self.findings {
+            let Some(advisory) = advisories.get(finding.osv.as_str()) else {
+                continue;
+            };
+            if !advisory.is_crypto_related() {
+                continue;
+            }
+            for target in report
+                .findings
+                .iter_mut()
+                .filter(|f| finding.passes_through(f))
+            {
+                attach_reachable(target, advisory, finding.fixed_version.as_deref());
+            }
+        }
+
+        report.vulnerabilities = self.vulnerabilities(&advisories);
+    }
+
+    /// One entry per advisory, keeping the most precise finding govulncheck reported.
+    fn vulnerabilities(&self, advisories: &HashMap<&str, &Advisory>) -> Vec<Vulnerability> {
+        let mut best: HashMap<&str, &GovulncheckFinding> = HashMap::new();
+        for finding in &self.findings {
+            let current = best.entry(finding.osv.as_str()).or_insert(finding);
+            if finding.precision() > current.precision() {
+                *current = finding;
+            }
+        }
+
+        let mut vulnerabilities: Vec<Vulnerability> = best
+            .into_values()
+            .map(|finding| {
+                let advisory = advisories.get(finding.osv.as_str());
+                let vulnerable = finding.trace.first();
+                Vulnerability {
+                    id: finding.osv.clone(),
+                    aliases: advisory.map(|a| a.aliases.clone()).unwrap_or_default(),
+                    summary: advisory.map(|a| a.summary.clone()).unwrap_or_default(),
+                    module: vulnerable.map(|f| f.module.clone()).unwrap_or_default(),
+                    version: vulnerable.and_then(|f| f.version.clone()),
+                    fixed: finding.fixed_version.clone(),
+                    symbol: vulnerable.and_then(Frame::symbol),
+                    trace: finding.trace.iter().filter_map(Frame::describe).collect(),
+                    crypto: advisory.is_some_and(|a| a.is_crypto_related()),
+                }
+            })
+            .collect();
+        vulnerabilities.sort_by(|a, b| a.id.cmp(&b.id));
+        vulnerabilities
+    }
+}
+
+impl GovulncheckFinding {
+    /// 0 for module-level, 1 for package-level and 2 for symbol-level findings.
+    fn precision(&self) -> u8 {
+        match self.trace.first() {
+            Some(frame) if frame.function.is_some() => 2,
+            Some(frame) if frame.package.is_some() => 1,
+            _ => 0,
+        }
+    }
+
+    /// True if the finding's call site appears in the trace.
+    fn passes_through(&self, finding: &Finding) -> bool {
+        self.trace.iter().any(|frame| {
+            frame.position.as_ref().is_some_and(|position| {
+                position.line == finding.line && same_file(&position.filename, &finding.file)
+            })
+        })
+    }
+}
+
+impl Frame {
+    /// `package.Function` or `package.Receiver.Method`.
+    fn symbol(&self) -> Option<String> {
+        let function = self.function.as_deref()?;
+        let package = self.package.as_deref().unwrap_or(&self.module);
+        Some(match self.receiver.as_deref().filter(|r| !r.is_empty()) {
+            Some(receiver) => format!("{package}.{}.{function}", receiver.trim_start_matches('*')),
+            None => format!("{package}.{function}"),
+        })
+    }
+
+    fn describe(&self) -> Option<String> {
+        let symbol = self.symbol()?;
+        Some(match &self.position {
+            Some(position) => format!("{symbol} ({}:{})", position.filename, position.line),
+            None => symbol,
+        })
+    }
+}
+
+/// govulncheck reports positions relative to the module root, argflow as scanned.
+fn same_file(govulncheck: &str, argflow: &str) -> bool {
+    let govulncheck = govulncheck.trim_start_matches("./");
+    argflow == govulncheck || argflow.ends_with(&format!("/{govulncheck}"))
+}
+
+fn attach_reachable(finding: &mut Finding, advisory: &Advisory, fixed: Option<&str>) {
+    if let Some(existing) = finding.advisories.iter_mut().find(|r| r.id == advisory.id) {
+        existing.relation = AdvisoryMatch::Reachable;
+        return;
+    }
+    finding.advisories.push(AdvisoryRef {
+        id: advisory.id.clone(),
+        aliases: advisory.aliases.clone(),
+        summary: advisory.summary.clone(),
+        fixed: fixed.map(str::to_string),
+        url: advisory.url().map(str::to_string),
+        relation: AdvisoryMatch::Reachable,
+    });
+    finding.advisories.sort_by(|a, b| a.id.cmp(&b.id));
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    const OUTPUT: &str = r#"
+{"config": {"protocol_version": "v1.0.0", "scanner_name": "govulncheck"}}
+{"progress": {"message": "Scanning your code and 46 packages across 1 dependent module for known vulnerabilities..."}}
+{"osv": {"id": "GO-2023-1840", "aliases": ["CVE-2023-29403"], "summary": "Timing side channel in crypto/rsa",
+  "affected": [{"package": {"name": "stdlib"}, "ecosystem_specific": {"imports": [{"path": "crypto/rsa"}]}}]}}
+{"osv": {"id": "GO-2024-2600", "summary": "Memory exhaustion in net/http"}}
+{"finding": {"osv": "GO-2023-1840", "fixed_version": "v1.20.5",
+  "trace": [{"module": "stdlib", "version": "v1.20.3"}]}}
+{"finding": {"osv": "GO-2023-1840", "fixed_version": "v1.20.5", "trace": [
+  {"module": "stdlib", "version": "v1.20.3", "package": "crypto/rsa", "function": "DecryptPKCS1v15"},
+  {"module": "example.com/app", "package": "example.com/app/auth", "function": "Decrypt",
+   "position": {"filename": "auth/decrypt.go", "line": 14}}
+]}}
+{"finding": {"osv": "GO-2024-2600", "fixed_version": "v1.22.1", "trace": [
+  {"module": "stdlib", "version": "v1.20.3", "package": "net/http", "function": "ServeMux", "receiver": "*Server"}
+]}}
+"#;
+
+    fn finding(file: &str, line: usize) -> Finding {
+        Finding::call(file, line, 9, "rsa.DecryptPKCS1v15")
+            .with_package("rsa")
+            .with_import_path("crypto/rsa")
+    }
+
+    fn report(findings: Vec<Finding>) -> JsonOutput {
+        JsonOutput {
+            go_version: None,
+            analysis_status: None,
+            files_scanned: 1,
+            total_findings: findings.len(),
+            total_configs: 0,
+            findings,
+            configs: Vec::new(),
+            packages: Vec::new(),
+            vulnerabilities: Vec::new(),
+            fips: None,
+            key_mismatches: Vec::new(),
+            nonce_overflows: Vec::new(),
+            agility: Vec::new(),
+            constant_usage: Vec::new(),
+            operations: Vec::new(),
+            duplicate_operations: Vec::new(),
+            generator_settings: Vec::new(),
+            modules: Vec::new(),
+            manifest: None,
+            repository: None,
+        }
+    }
+
+    #[test]
+    fn test_parse_stream() {
+        let output = GovulncheckOutput::parse(OUTPUT, Path::new("govulncheck.json")).unwrap();
+        assert_eq!(output.advisories.len(), 2);
+        assert_eq!(output.findings.len(), 3);
+        assert_eq!(output.findings[1].precision(), 2);
+    }
+
+    #[test]
+    fn test_merge_marks_call_sites_on_trace() {
+        let output = GovulncheckOutput::parse(OUTPUT, Path::new("govulncheck.json")).unwrap();
+        let mut report = report(vec![
+            finding("/work/app/auth/decrypt.go", 14),
+            finding("/work/app/auth/legacy.go", 14),
+        ]);
+        output.merge_into(&mut report);
+
+        let advisory = &report.findings[0].advisories[0];
+        assert_eq!(advisory.id, "GO-2023-1840");
+        assert_eq!(advisory.relation, AdvisoryMatch::Reachable);
+        assert_eq!(advisory.fixed.as_deref(), Some("v1.20.5"));
+        assert!(report.findings[1].advisories.is_empty());
+    }
+
+    #[test]
+    fn test_merge_lists_all_vulnerabilities() {
+        let output = GovulncheckOutput::parse(OUTPUT, Path::new("govulncheck.json")).unwrap();
+        let mut report = report(Vec::new());
+        output.merge_into(&mut report);
+
+        assert_eq!(report.vulnerabilities.len(), 2);
+        let rsa = &report.vulnerabilities[0];
+        assert_eq!(rsa.symbol.as_deref(), Some("crypto/rsa.DecryptPKCS1v15"));
+        assert_eq!(rsa.trace.len(), 2);
+        assert!(rsa.crypto);
+
+        let http = &report.vulnerabilities[1];
+        assert_eq!(http.symbol.as_deref(), Some("net/http.Server.ServeMux"));
+        assert!(!http.crypto);
+    }
+}
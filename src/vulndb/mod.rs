@@ -0,0 +1,225 @@
+//! Correlation of findings with a local copy of the Go vulnerability database.
+
+mod govulncheck;
+mod osv;
+mod semver;
+
+use std::ffi::OsStr;
+use std::fs;
+use std::path::Path;
+
+use tracing::debug;
+use walkdir::WalkDir;
+
+use crate::error::VulnDbError;
+use crate::output::{AdvisoryMatch, AdvisoryRef, Finding};
+
+pub use govulncheck::{Frame, GovulncheckFinding, GovulncheckOutput};
+pub use osv::{Advisory, Affected, AffectedImport};
+pub use semver::Semver;
+
+/// OSV package name the Go database uses for the standard library.
+pub const STDLIB_PACKAGE: &str = "stdlib";
+
+#[derive(Debug, Default)]
+pub struct VulnDb {
+    advisories: Vec<Advisory>,
+}
+
+impl VulnDb {
+    pub fn new(advisories: Vec<Advisory>) -> Self {
+        Self { advisories }
+    }
+
+    /// Loads advisories from an OSV JSON file (one advisory or an array) or a directory of them.
+    ///
+    /// In directories, files that are not advisories (such as the database's `index/`
+    /// files) are skipped.
+    pub fn load(path: &Path) -> Result<Self, VulnDbError> {
+        if path.is_file() {
+            return Ok(Self::new(parse_file(path)?));
+        }
+
+        let mut advisories = Vec::new();
+        for entry in WalkDir::new(path).sort_by_file_name() {
+            let entry = entry.map_err(|e| VulnDbError::read_error(path, e.to_string()))?;
+            let file = entry.path();
+            if !entry.file_type().is_file() || file.extension() != Some(OsStr::new("json")) {
+                continue;
+            }
+            match parse_file(file) {
+                Ok(parsed) => advisories.extend(parsed),
+                Err(e) => debug!(error = %e, "skipping non-advisory file"),
+            }
+        }
+        debug!(count = advisories.len(), "loaded advisories");
+        Ok(Self::new(advisories))
+    }
+
+    pub fn len(&self) -> usize {
+        self.advisories.len()
+    }
+
+    pub fn is_empty(&self) -> bool {
+        self.advisories.is_empty()
+    }
+
+    /// Attaches matching crypto-related advisories to each finding.
+    ///
+    /// A finding matches when its module version is affected (`module`), or when the
+    /// advisory names the API it calls at an affected version (`api`). Standard library
+    /// calls are checked against `go_version`.
+    pub fn annotate(&self, findings: &mut [Finding], go_version: Option<&str>) {
+        let go_version = go_version.and_then(Semver::parse);
+
+        for finding in findings.iter_mut() {
+            let module_version = finding.module_version.as_deref().and_then(Semver::parse);
+            let mut refs: Vec<AdvisoryRef> = Vec::new();
+
+            for advisory in self.advisories.iter().filter(|a| a.is_crypto_related()) {
+                for affected in &advisory.affected {
+                    let is_stdlib = affected.package.name == STDLIB_PACKAGE;
+                    let version = if is_stdlib {
+                        go_version.as_ref()
+                    } else {
+                        module_version.as_ref()
+                    };
+                    let Some(version) = version.filter(|v| affected.affects(v)) else {
+                        continue;
+                    };
+
+                    let relation = if calls_affected_api(finding, affected) {
+                        AdvisoryMatch::Api
+                    } else if !is_stdlib
+                        && finding.module.as_deref() == Some(affected.package.name.as_str())
+                    {
+                        AdvisoryMatch::Module
+                    } else {
+                        continue;
+                    };
+
+                    if let Some(existing) = refs.iter_mut().find(|r| r.id == advisory.id) {
+                        // An API match is more specific than a module match
+                        if relation == AdvisoryMatch::Api {
+                            existing.relation = relation;
+                        }
+                        continue;
+                    }
+                    refs.push(AdvisoryRef {
+                        id: advisory.id.clone(),
+                        aliases: advisory.aliases.clone(),
+                        summary: advisory.summary.clone(),
+                        fixed: affected.fixed_after(version),
+                        url: advisory.url().map(str::to_string),
+                        relation,
+                    });
+                }
+            }
+
+            refs.sort_by(|a, b| a.id.cmp(&b.id));
+            finding.advisories = refs;
+        }
+    }
+}
+
+fn parse_file(path: &Path) -> Result<Vec<Advisory>, VulnDbError> {
+    let content =
+        fs::read_to_string(path).map_err(|e| VulnDbError::read_error(path, e.to_string()))?;
+    if content.trim_start().starts_with('[') {
+        serde_json::from_str(&content)
+    } else {
+        serde_json::from_str(&content).map(|advisory| vec![advisory])
+    }
+    .map_err(|e| VulnDbError::parse_error(path, e.to_string()))
+}
+
+/// True if the finding calls a package (and symbol, when listed) named by the advisory.
+fn calls_affected_api(finding: &Finding, affected: &Affected) -> bool {
+    let Some(import_path) = finding.import_path.as_deref() else {
+        return false;
+    };
+    affected
+        .ecosystem_specific
+        .iter()
+        .flat_map(|e| e.imports.iter())
+        .filter(|import| import.path == import_path)
+        .any(|import| {
+            import.symbols.is_empty()
+                || import.symbols.iter().any(|symbol| {
+                    // Methods are listed as `Type.Method`
+                    symbol == &finding.function
+                        || symbol.rsplit('.').next() == Some(finding.function.as_str())
+                })
+        })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn finding(import_path: &str, function: &str, module: Option<(&str, &str)>) -> Finding {
+        Finding {
+            module: module.map(|(m, _)| m.to_string()),
+            module_version: module.map(|(_, v)| v.to_string()),
+            ..Finding::call("vendor/x.go", 1, 1, &format!("{import_path}.{function}"))
+                .with_import_path(import_path)
+        }
+    }
+
+    fn db() -> VulnDb {
+        VulnDb::new(
+            serde_json::from_str(
+                r#"[
+                {
+                    "id": "GO-2023-1840",
+                    "summary": "Timing side channel in crypto/rsa",
+                    "affected": [{
+                        "package": {"name": "stdlib", "ecosystem": "Go"},
+                        "ranges": [{"type": "SEMVER", "events": [{"introduced": "1.20.0"}, {"fixed": "1.20.5"}]}],
+                        "ecosystem_specific": {"imports": [{"path": "crypto/rsa", "symbols": ["DecryptPKCS1v15"]}]}
+                    }]
+                },
+                {
+                    "id": "GO-2021-0227",
+                    "aliases": ["CVE-2020-29652"],
+                    "summary": "Panic on crafted authentication request in golang.org/x/crypto/ssh",
+                    "affected": [{
+                        "package": {"name": "golang.org/x/crypto", "ecosystem": "Go"},
+                        "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "0.0.0-20201216223049-8b5274cf687f"}]}],
+                        "ecosystem_specific": {"imports": [{"path": "golang.org/x/crypto/ssh"}]}
+                    }]
+                }
+            ]"#,
+            )
+            .unwrap(),
+        )
+    }
+
+    #[test]
+    fn test_stdlib_api_match_uses_go_version() {
+        let mut findings = [finding("crypto/rsa", "DecryptPKCS1v15", None)];
+
+        db().annotate(&mut findings, Some("1.20.3"));
+        assert_eq!(findings[0].advisories.len(), 1);
+        assert_eq!(findings[0].advisories[0].relation, AdvisoryMatch::Api);
+        assert_eq!(findings[0].advisories[0].fixed.as_deref(), Some("1.20.5"));
+
+        db().annotate(&mut findings, Some("1.21"));
+        assert!(findings[0].advisories.is_empty());
+    }
+
+    #[test]
+    fn test_dependency_module_match() {
+        let mut findings = [finding(
+            "golang.org/x/crypto/pbkdf2",
+            "Key",
+            Some(("golang.org/x/crypto", "v0.0.0-20200622213623-75b288015ac9")),
+        )];
+
+        db().annotate(&mut findings, None);
+        let advisory = &findings[0].advisories[0];
+        assert_eq!(advisory.id, "GO-2021-0227");
+        assert_eq!(advisory.relation, AdvisoryMatch::Module);
+        assert_eq!(advisory.aliases, vec!["CVE-2020-29652"]);
+    }
+}
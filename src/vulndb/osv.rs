@@ -0,0 +1,242 @@
+use serde::Deserialize;
+
+use super::semver::Semver;
+
+/// The subset of the OSV schema used by the Go vulnerability database.
+#[derive(Debug, Clone, Deserialize)]
+pub struct Advisory {
+    pub id: String,
+    #[serde(default)]
+    pub aliases: Vec<String>,
+    #[serde(default)]
+    pub summary: String,
+    #[serde(default)]
+    pub details: String,
+    #[serde(default)]
+    pub affected: Vec<Affected>,
+    #[serde(default)]
+    pub database_specific: Option<DatabaseSpecific>,
+}
+
+#[derive(Debug, Clone, Deserialize)]
+pub struct Affected {
+    pub package: AffectedPackage,
+    #[serde(default)]
+    pub ranges: Vec<Range>,
+    #[serde(default)]
+    pub ecosystem_specific: Option<EcosystemSpecific>,
+}
+
+#[derive(Debug, Clone, Deserialize)]
+pub struct AffectedPackage {
+    pub name: String,
+    #[serde(default)]
+    pub ecosystem: String,
+}
+
+#[derive(Debug, Clone, Deserialize)]
+pub struct Range {
+    #[serde(rename = "type")]
+    pub kind: String,
+    #[serde(default)]
+    pub events: Vec<RangeEvent>,
+}
+
+#[derive(Debug, Clone, Default, Deserialize)]
+pub struct RangeEvent {
+    pub introduced: Option<String>,
+    pub fixed: Option<String>,
+}
+
+#[derive(Debug, Clone, Default, Deserialize)]
+pub struct EcosystemSpecific {
+    #[serde(default)]
+    pub imports: Vec<AffectedImport>,
+}
+
+#[derive(Debug, Clone, Deserialize)]
+pub struct AffectedImport {
+    pub path: String,
+    #[serde(default)]
+    pub symbols: Vec<String>,
+}
+
+#[derive(Debug, Clone, Default, Deserialize)]
+pub struct DatabaseSpecific {
+    pub url: Option<String>,
+}
+
+/// Words that mark an advisory as crypto-related when its affected imports do not.
+const CRYPTO_KEYWORDS: &[&str] = &[
+    "crypto",
+    "cipher",
+    "tls",
+    "x509",
+    "certificate",
+    "signature",
+    "rsa",
+    "ecdsa",
+    "ed25519",
+    "aes",
+    "hmac",
+    "nonce",
+    "padding oracle",
+    "timing",
+    "key exchange",
+    "ssh",
+    "random",
+];
+
+impl Advisory {
+    pub fn is_crypto_related(&self) -> bool {
+        let touches_crypto_package = self.imports().any(|import| {
+            import.path.starts_with("crypto/")
+                || import.path.contains("/crypto/")
+                || import.path.ends_with("/crypto")
+        });
+        if touches_crypto_package {
+            return true;
+        }
+
+        let text = format!("{} {}", self.summary, self.details).to_lowercase();
+        let words: Vec<&str> = text
+            .split(|c: char| !c.is_ascii_alphanumeric())
+            .filter(|w| !w.is_empty())
+            .collect();
+        CRYPTO_KEYWORDS.iter().any(|keyword| {
+            if keyword.contains(' ') {
+                text.contains(keyword)
+            } else {
+                // Prefix match so "cryptographic" counts but "traversal" does not match "rsa"
+                words.iter().any(|word| word.starts_with(keyword))
+            }
+        })
+    }
+
+    pub fn imports(&self) -> impl Iterator<Item = &AffectedImport> {
+        self.affected
+            .iter()
+            .filter_map(|a| a.ecosystem_specific.as_ref())
+            .flat_map(|e| e.imports.iter())
+    }
+
+    pub fn url(&self) -> Option<&str> {
+        self.database_specific.as_ref()?.url.as_deref()
+    }
+}
+
+impl Affected {
+    /// True if `version` falls in any of the affected ranges.
+    pub fn affects(&self, version: &Semver) -> bool {
+        self.ranges
+            .iter()
+            .filter(|range| range.kind == "SEMVER")
+            .any(|range| range.contains(version))
+    }
+
+    /// The earliest fixed version above `version`, if any.
+    pub fn fixed_after(&self, version: &Semver) -> Option<String> {
+        self.ranges
+            .iter()
+            .flat_map(|range| range.events.iter())
+            .filter_map(|event| event.fixed.as_deref())
+            .filter_map(|fixed| Some((Semver::parse(fixed)?, fixed)))
+            .filter(|(fixed, _)| fixed > version)
+            .min_by(|(a, _), (b, _)| a.cmp(b))
+            .map(|(_, fixed)| fixed.to_string())
+    }
+}
+
+impl Range {
+    /// Walks the events in order; a version is affected when the last boundary at or below it
+    /// is an `introduced` event.
+    fn contains(&self, version: &Semver) -> bool {
+        // `introduced: "0"` means every version, including pre-releases of 0.0.0
+        let boundary = |v: &str| match v {
+            "0" => Some(None),
+            v => Semver::parse(v).map(Some),
+        };
+        let mut boundaries: Vec<(Option<Semver>, bool)> = self
+            .events
+            .iter()
+            .filter_map(|event| match (&event.introduced, &event.fixed) {
+                (Some(introduced), _) => Some((boundary(introduced)?, true)),
+                (None, Some(fixed)) => Some((boundary(fixed)?, false)),
+                (None, None) => None,
+            })
+            .collect();
+        boundaries.sort_by(|a, b| a.0.cmp(&b.0));
+
+        boundaries
+            .iter()
+            .take_while(|(boundary, _)| boundary.as_ref().is_none_or(|b| b <= version))
+            .last()
+            .is_some_and(|(_, introduced)| *introduced)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn advisory() -> Advisory {
+        serde_json::from_str(
+            r#"{
+                "id": "GO-2022-0969",
+                "aliases": ["CVE-2022-27191"],
+                "summary": "Denial of service via crafted Signer in golang.org/x/crypto/ssh",
+                "affected": [{
+                    "package": {"name": "golang.org/x/crypto", "ecosystem": "Go"},
+                    "ranges": [{"type": "SEMVER", "events": [
+                        {"introduced": "0"}, {"fixed": "0.0.0-20220314234659-1baeb1ce4c0b"}
+                    ]}],
+                    "ecosystem_specific": {"imports": [
+                        {"path": "golang.org/x/crypto/ssh", "symbols": ["ServerConn.handleAuthentication"]}
+                    ]}
+                }]
+            }"#,
+        )
+        .unwrap()
+    }
+
+    #[test]
+    fn test_affected_ranges() {
+        let advisory = advisory();
+        let affected = &advisory.affected[0];
+
+        let old = Semver::parse("v0.0.0-20210921155107-089bfa567519").unwrap();
+        let new = Semver::parse("v0.1.0").unwrap();
+        assert!(affected.affects(&old));
+        assert!(!affected.affects(&new));
+        assert_eq!(
+            affected.fixed_after(&old).as_deref(),
+            Some("0.0.0-20220314234659-1baeb1ce4c0b")
+        );
+    }
+
+    #[test]
+    fn test_reintroduced_range() {
+        let range: Range = serde_json::from_str(
+            r#"{"type": "SEMVER", "events": [
+                {"introduced": "0"}, {"fixed": "1.2.0"}, {"introduced": "1.4.0"}, {"fixed": "1.4.3"}
+            ]}"#,
+        )
+        .unwrap();
+        let v = |s| Semver::parse(s).unwrap();
+        assert!(range.contains(&v("1.1.9")));
+        assert!(!range.contains(&v("1.3.0")));
+        assert!(range.contains(&v("1.4.1")));
+        assert!(!range.contains(&v("1.4.3")));
+    }
+
+    #[test]
+    fn test_crypto_related() {
+        assert!(advisory().is_crypto_related());
+
+        let unrelated: Advisory = serde_json::from_str(
+            r#"{"id": "GO-2023-0001", "summary": "Path traversal in archive extraction"}"#,
+        )
+        .unwrap();
+        assert!(!unrelated.is_crypto_related());
+    }
+}
@@ -0,0 +1,96 @@
+use std::cmp::Ordering;
+
+/// A semantic version as used by Go modules and OSV `SEMVER` ranges.
+///
+/// The leading `v` is optional, so module versions (`v1.2.3`) and Go vulndb
+/// range events (`1.2.3`) compare directly. Build metadata is ignored.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Semver {
+    pub major: u64,
+    pub minor: u64,
+    pub patch: u64,
+    pub prerelease: Vec<String>,
+}
+
+impl Semver {
+    pub fn parse(input: &str) -> Option<Self> {
+        let input = input.trim();
+        let input = input.strip_prefix('v').unwrap_or(input);
+        let input = input.split('+').next()?;
+        let (core, prerelease) = match input.split_once('-') {
+            Some((core, pre)) => (core, pre.split('.').map(str::to_string).collect()),
+            None => (input, Vec::new()),
+        };
+
+        let mut parts = core.split('.');
+        let major = parts.next()?.parse().ok()?;
+        let minor = parts.next().map_or(Some(0), |p| p.parse().ok())?;
+        let patch = parts.next().map_or(Some(0), |p| p.parse().ok())?;
+        if parts.next().is_some() {
+            return None;
+        }
+
+        Some(Self {
+            major,
+            minor,
+            patch,
+            prerelease,
+        })
+    }
+}
+
+impl Ord for Semver {
+    fn cmp(&self, other: &Self) -> Ordering {
+        (self.major, self.minor, self.patch)
+            .cmp(&(other.major, other.minor, other.patch))
+            .then_with(|| compare_prerelease(&self.prerelease, &other.prerelease))
+    }
+}
+
+impl PartialOrd for Semver {
+    fn partial_cmp(&self, other: &Self) -> Option<Ordering> {
+        Some(self.cmp(other))
+    }
+}
+
+/// A release sorts after all of its pre-releases; identifiers compare numerically when both are numbers.
+fn compare_prerelease(a: &[String], b: &[String]) -> Ordering {
+    match (a.is_empty(), b.is_empty()) {
+        (true, true) => return Ordering::Equal,
+        (true, false) => return Ordering::Greater,
+        (false, true) => return Ordering::Less,
+        (false, false) => {}
+    }
+
+    for (x, y) in a.iter().zip(b) {
+        let ordering = match (x.parse::<u64>(), y.parse::<u64>()) {
+            (Ok(x), Ok(y)) => x.cmp(&y),
+            (Ok(_), Err(_)) => Ordering::Less,
+            (Err(_), Ok(_)) => Ordering::Greater,
+            (Err(_), Err(_)) => x.cmp(y),
+        };
+        if ordering != Ordering::Equal {
+            return ordering;
+        }
+    }
+    a.len().cmp(&b.len())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn v(s: &str) -> Semver {
+        Semver::parse(s).unwrap()
+    }
+
+    #[test]
+    fn test_ordering() {
+        assert!(v("v1.2.3") < v("1.10.0"));
+        assert!(v("1.21.0-rc.1") < v("1.21.0"));
+        assert!(v("v0.0.0-20210921155107-089bfa567519") < v("v0.1.0"));
+        assert_eq!(v("v1.2.3+incompatible"), v("1.2.3"));
+        assert_eq!(v("0"), v("0.0.0"));
+        assert!(Semver::parse("latest").is_none());
+    }
+}
@@ -0,0 +1,118 @@
+use serde::Serialize;
+
+use super::{VulnRecord, VulnSeverity};
+
+/// A finding of DefectDojo's Generic Findings Import.
+#[derive(Debug, Serialize)]
+struct DojoFinding<'a> {
+    title: &'a str,
+    description: String,
+    severity: VulnSeverity,
+    cwe: u32,
+    file_path: &'a str,
+    line: usize,
+    /// Deduplicates re-imports of the same violation.
+    unique_id_from_tool: &'a str,
+    vuln_id_from_tool: &'a str,
+    static_finding: bool,
+    dynamic_finding: bool,
+    active: bool,
+    risk_accepted: bool,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    mitigation: Option<&'a str>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    references: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    component_name: Option<&'a str>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    component_version: Option<&'a str>,
+}
+
+#[derive(Debug, Serialize)]
+struct DojoImport<'a> {
+    findings: Vec<DojoFinding<'a>>,
+}
+
+/// `records` as DefectDojo Generic Findings Import JSON, for the "Generic Findings Import"
+/// scan type of `import-scan` and `reimport-scan`.
+pub fn render_defectdojo(records: &[VulnRecord]) -> serde_json::Result<String> {
+    let findings = records
+        .iter()
+        .map(|record| {
+            let mut description = format!(
+                "{}\n\n**Location:** `{}:{}` in `{}`",
+                record.description, record.file, record.line, record.function
+            );
+            if let Some(owner) = &record.owner {
+                description.push_str(&format!("\n\n**Owner:** {owner}"));
+            }
+            DojoFinding {
+                title: &record.title,
+                description,
+                severity: record.severity,
+                cwe: record.cwe,
+                file_path: &record.file,
+                line: record.line,
+                unique_id_from_tool: &record.fingerprint,
+                vuln_id_from_tool: &record.rule,
+                static_finding: true,
+                dynamic_finding: false,
+                active: !record.accepted,
+                risk_accepted: record.accepted,
+                mitigation: record.mitigation.as_deref(),
+                references: (!record.references.is_empty()).then(|| record.references.join("\n")),
+                component_name: record.component.as_deref(),
+                component_version: record.component_version.as_deref(),
+            }
+        })
+        .collect();
+    serde_json::to_string_pretty(&DojoImport { findings })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use serde_json::json;
+
+    #[test]
+    fn test_generic_findings_import() {
+        let record = VulnRecord {
+            title: "no-md5: crypto/md5.Sum is not allowed".to_string(),
+            description: "crypto/md5.Sum is not allowed".to_string(),
+            severity: VulnSeverity::High,
+            rule: "no-md5".to_string(),
+            file: "internal/cache/key.go".to_string(),
+            line: 12,
+            function: "Sum".to_string(),
+            fingerprint: "3f2a9c".to_string(),
+            cwe: 327,
+            accepted: true,
+            mitigation: Some("See https://wiki.example.com/crypto/no-md5".to_string()),
+            references: Vec::new(),
+            component: Some("example.com/app".to_string()),
+            component_version: None,
+            owner: Some("@platform".to_string()),
+        };
+        let rendered: serde_json::Value =
+            serde_json::from_str(&render_defectdojo(&[record]).unwrap()).unwrap();
+        assert_eq!(
+            rendered,
+            json!({"findings": [{
+                "title": "no-md5: crypto/md5.Sum is not allowed",
+                "description": "crypto/md5.Sum is not allowed\n\n**Location:** `internal/cache/key.go:12` in `Sum`\n\n**Owner:** @platform",
+                "severity": "High",
+                "cwe": 327,
+                "file_path": "internal/cache/key.go",
+                "line": 12,
+                "unique_id_from_tool": "3f2a9c",
+                "vuln_id_from_tool": "no-md5",
+                "static_finding": true,
+                "dynamic_finding": false,
+                "active": false,
+                "risk_accepted": true,
+                "mitigation": "See https://wiki.example.com/crypto/no-md5",
+                "component_name": "example.com/app"
+            }]})
+        );
+    }
+}
@@ -0,0 +1,162 @@
+//! Findings exported to vulnerability managers such as DefectDojo.
+
+mod defectdojo;
+
+use std::path::Path;
+
+use serde::Serialize;
+
+use crate::output::Finding;
+use crate::policy::{fingerprint, relative_path, GateReport, Severity, ViolationStatus};
+
+pub use defectdojo::render_defectdojo;
+
+/// Name the records carry as the reporting tool.
+pub const TOOL_NAME: &str = "argflow";
+
+/// CWE-327, Use of a Broken or Risky Cryptographic Algorithm, which covers the calls the
+/// sink catalogs describe.
+pub const DEFAULT_CWE: u32 = 327;
+
+/// Severity scale shared by vulnerability managers.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, PartialOrd, Ord, Serialize)]
+pub enum VulnSeverity {
+    Info,
+    Low,
+    Medium,
+    High,
+    Critical,
+}
+
+impl From<Severity> for VulnSeverity {
+    fn from(severity: Severity) -> Self {
+        match severity {
+            Severity::Info => VulnSeverity::Low,
+            Severity::Warning => VulnSeverity::Medium,
+            Severity::Error => VulnSeverity::High,
+        }
+    }
+}
+
+/// One entry for a vulnerability manager.
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct VulnRecord {
+    pub title: String,
+    pub description: String,
+    pub severity: VulnSeverity,
+    /// Policy rule id, or the sink's full name when exporting raw findings.
+    pub rule: String,
+    /// Path relative to the scan root.
+    pub file: String,
+    pub line: usize,
+    pub function: String,
+    /// Stable across scans, for deduplication on re-import.
+    pub fingerprint: String,
+    pub cwe: u32,
+    /// Baselined or suppressed in the repository; managers record these as accepted risks.
+    pub accepted: bool,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub mitigation: Option<String>,
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub references: Vec<String>,
+    /// Module owning the file and its version, for managers that track components.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub component: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub component_version: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub owner: Option<String>,
+}
+
+/// Records for the violations of a gate report. Violations outside the diff are left
+/// out, as the gate does not judge them.
+pub fn records_from_gate(
+    report: &GateReport,
+    findings: &[Finding],
+    root: &Path,
+) -> Vec<VulnRecord> {
+    report
+        .violations
+        .iter()
+        .filter(|violation| violation.status != ViolationStatus::OutsideDiff)
+        .map(|violation| {
+            let finding = findings.iter().find(|finding| {
+                finding.line == violation.line
+                    && relative_path(&finding.file, root) == violation.file
+            });
+            VulnRecord {
+                title: format!("{}: {}", violation.rule, violation.message),
+                description: violation.message.clone(),
+                severity: violation.severity.into(),
+                rule: violation.rule.clone(),
+                file: violation.file.clone(),
+                line: violation.line,
+                function: violation.function.clone(),
+                fingerprint: violation.fingerprint.clone(),
+                cwe: DEFAULT_CWE,
+                accepted: matches!(
+                    violation.status,
+                    ViolationStatus::Baselined | ViolationStatus::Suppressed
+                ),
+                mitigation: violation
+                    .remediation_url
+                    .as_ref()
+                    .map(|url| format!("See {url}")),
+                references: finding.map(advisory_references).unwrap_or_default(),
+                component: finding.and_then(|finding| finding.module.clone()),
+                component_version: finding.and_then(|finding| finding.module_version.clone()),
+                owner: violation.owner.clone(),
+            }
+        })
+        .collect()
+}
+
+/// Informational records for every finding, when no policy judges them.
+pub fn records_from_findings(findings: &[Finding], root: &Path) -> Vec<VulnRecord> {
+    findings
+        .iter()
+        .map(|finding| {
+            let file = relative_path(&finding.file, root);
+            let call = match &finding.algorithm {
+                Some(algorithm) => format!("{} ({algorithm})", finding.full_name),
+                None => finding.full_name.clone(),
+            };
+            VulnRecord {
+                title: format!("Crypto call {call}"),
+                description: format!("`{}` in {}", finding.raw_text, finding.function),
+                severity: VulnSeverity::Info,
+                rule: finding.full_name.clone(),
+                fingerprint: fingerprint(&finding.full_name, &file, finding),
+                file,
+                line: finding.line,
+                function: finding.function.clone(),
+                cwe: DEFAULT_CWE,
+                accepted: false,
+                mitigation: None,
+                references: advisory_references(finding),
+                component: finding.module.clone(),
+                component_version: finding.module_version.clone(),
+                owner: None,
+            }
+        })
+        .collect()
+}
+
+pub fn render_generic(records: &[VulnRecord]) -> serde_json::Result<String> {
+    serde_json::to_string_pretty(&serde_json::json!({
+        "tool": TOOL_NAME,
+        "version": env!("CARGO_PKG_VERSION"),
+        "records": records,
+    }))
+}
+
+fn advisory_references(finding: &Finding) -> Vec<String> {
+    finding
+        .advisories
+        .iter()
+        .map(|advisory| match &advisory.url {
+            Some(url) => format!("{} {url}", advisory.id),
+            None => advisory.id.clone(),
+        })
+        .collect()
+}
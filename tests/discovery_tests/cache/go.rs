@@ -10,7 +10,7 @@ use argflow::discovery::loader::PackageLoader;
 #[test]
 fn test_go_cache_functionality() {
     let test_app_path = get_test_fixture_path("go", Some("discovery-test-app"));
-    let loader = GoPackageLoader;
+    let loader = GoPackageLoader::default();
     let mut cache1 = DiscoveryCache::default();
     let mut cache2 = DiscoveryCache::default();
 
@@ -11,7 +11,7 @@ use argflow::discovery::SourceType;
 #[test]
 fn test_go_dependency_discovery() {
     let test_app_path = get_test_fixture_path("go", Some("discovery-test-app"));
-    let loader = GoPackageLoader;
+    let loader = GoPackageLoader::default();
     let mut cache = DiscoveryCache::default();
     let dep_files = loader
         .load_dependencies(&test_app_path, &mut cache)
@@ -70,7 +70,7 @@ fn test_go_vendor_as_dependency() {
         .write_all(b"module test\n")
         .unwrap();
 
-    let loader = GoPackageLoader;
+    let loader = GoPackageLoader::default();
     let mut cache = DiscoveryCache::default();
 
     let dep_files = loader
@@ -100,7 +100,7 @@ fn test_go_vendor_as_dependency() {
 #[test]
 fn test_go_stdlib_files_included() {
     let test_app_path = get_test_fixture_path("go", Some("discovery-test-app"));
-    let loader = GoPackageLoader;
+    let loader = GoPackageLoader::default();
     let mut cache = DiscoveryCache::default();
 
     let dep_files = loader
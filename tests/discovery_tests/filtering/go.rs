@@ -11,7 +11,7 @@ use argflow::discovery::loader::PackageLoader;
 #[test]
 fn test_go_crypto_filter() {
     let test_app_path = get_test_fixture_path("go", Some("discovery-test-app"));
-    let loader = GoPackageLoader;
+    let loader = GoPackageLoader::default();
     let filter = GoImportFilter::from_bundled().expect("Failed to create filter");
 
     let all_files = loader
@@ -33,7 +33,7 @@ fn test_go_crypto_filter() {
 #[test]
 fn test_go_crypto_filter_exact_count() {
     let test_app_path = get_test_fixture_path("go", Some("discovery-test-app"));
-    let loader = GoPackageLoader;
+    let loader = GoPackageLoader::default();
     let filter = GoImportFilter::from_bundled().expect("Failed to create filter");
 
     let all_files = loader
@@ -13,7 +13,7 @@ use argflow::discovery::loader::PackageLoader;
 #[test]
 fn test_go_user_and_dependencies() {
     let test_app_path = get_test_fixture_path("go", Some("discovery-test-app"));
-    let loader = GoPackageLoader;
+    let loader = GoPackageLoader::default();
     let filter = GoImportFilter::from_bundled().expect("Failed to create filter");
 
     let user_files = loader
@@ -57,7 +57,7 @@ fn test_go_user_and_dependencies() {
 #[test]
 fn test_go_go_jose_imported() {
     let test_app_path = get_test_fixture_path("go", Some("discovery-test-app"));
-    let loader = GoPackageLoader;
+    let loader = GoPackageLoader::default();
     let filter = GoImportFilter::from_bundled().expect("Failed to create filter");
 
     let user_files = loader
@@ -115,7 +115,7 @@ fn test_go_go_jose_imported() {
 #[test]
 fn test_go_dependencies_included_in_scan() {
     let test_app_path = get_test_fixture_path("go", Some("discovery-test-app"));
-    let loader = GoPackageLoader;
+    let loader = GoPackageLoader::default();
     let filter = GoImportFilter::from_bundled().expect("Failed to create filter");
     let mut cache = DiscoveryCache::default();
 
@@ -232,7 +232,7 @@ fn test_go_dependencies_included_in_scan() {
 #[test]
 fn test_go_no_unnecessary_skipping() {
     let test_app_path = get_test_fixture_path("go", Some("discovery-test-app"));
-    let loader = GoPackageLoader;
+    let loader = GoPackageLoader::default();
     let filter = GoImportFilter::from_bundled().expect("Failed to create filter");
     let mut cache = DiscoveryCache::default();
 
@@ -12,7 +12,7 @@ use argflow::discovery::loader::PackageLoader;
 #[test]
 fn test_go_source_type_tagging() {
     let test_app_path = get_test_fixture_path("go", Some("discovery-test-app"));
-    let loader = GoPackageLoader;
+    let loader = GoPackageLoader::default();
     let mut cache = DiscoveryCache::default();
 
     let user_files = loader
@@ -38,7 +38,7 @@ fn test_go_source_type_tagging() {
 #[test]
 fn test_go_source_type_counts() {
     let test_app_path = get_test_fixture_path("go", Some("discovery-test-app"));
-    let loader = GoPackageLoader;
+    let loader = GoPackageLoader::default();
     let mut cache = DiscoveryCache::default();
 
     let user_files = loader
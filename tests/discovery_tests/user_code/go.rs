@@ -9,7 +9,7 @@ use argflow::discovery::loader::PackageLoader;
 #[test]
 fn test_go_user_code_discovery() {
     let test_app_path = get_test_fixture_path("go", Some("discovery-test-app"));
-    let loader = GoPackageLoader;
+    let loader = GoPackageLoader::default();
     let files = loader
         .load_user_code(&test_app_path)
         .expect("Failed to load user code");
@@ -65,7 +65,7 @@ fn test_go_excluded_directories() {
         .write_all(b"package dep")
         .unwrap();
 
-    let loader = GoPackageLoader;
+    let loader = GoPackageLoader::default();
     let user_files = loader
         .load_user_code(root)
         .expect("Failed to load user code");
@@ -3,6 +3,10 @@
 //! Tests crypto detection and parameter resolution for Go code.
 //! Fixtures: tests/fixtures/go/
 
+use std::cell::RefCell;
+use std::rc::Rc;
+
+use argflow::engine::{index_file, FileCache};
 use argflow::scanner::Scanner;
 
 use crate::fixtures::{get_test_fixture_path, test_patterns};
@@ -172,6 +176,44 @@ fn test_go_cross_file_constants_kdf() {
     // Note: Cross-file constant resolution requires Identifier strategy
 }
 
+#[test]
+fn test_go_cross_file_constants_with_package_index() {
+    let project = get_test_fixture_path("go", None).join("cross-file-constants");
+    let config_path = project.join("config/constants.go");
+    let config_source = std::fs::read_to_string(&config_path).unwrap();
+    let config_tree = parse_go(&config_source);
+
+    let mut cache = FileCache::new();
+    let config_file = config_path.to_string_lossy().to_string();
+    cache.add_file(
+        config_file.clone(),
+        index_file(&config_tree, config_source.as_bytes(), &config_file, "go"),
+    );
+    cache.register_package(
+        "github.com/example/cross-file-constants/config".to_string(),
+        project.join("config").to_string_lossy().to_string(),
+    );
+
+    let kdf_path = project.join("crypto/kdf.go");
+    let kdf_source = std::fs::read_to_string(&kdf_path).unwrap();
+    let kdf_tree = parse_go(&kdf_source);
+    let result = create_scanner().scan_tree_with_cache(
+        &kdf_tree,
+        kdf_source.as_bytes(),
+        &kdf_path.to_string_lossy(),
+        "go",
+        Rc::new(RefCell::new(cache)),
+    );
+
+    let iterations: Vec<_> = result
+        .calls
+        .iter()
+        .filter(|c| c.function_name == "Key")
+        .map(|c| c.arguments[2].int_values.clone())
+        .collect();
+    assert!(iterations.contains(&vec![100000]));
+}
+
 // =============================================================================
 // Inline tests for Go-specific resolution behaviors
 // =============================================================================